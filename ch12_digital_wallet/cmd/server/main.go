@@ -5,21 +5,27 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
 	"github.com/nathanyu/digital-wallet/internal/engine"
 	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	walletgrpc "github.com/nathanyu/digital-wallet/internal/grpc"
 	"github.com/nathanyu/digital-wallet/internal/handler"
 	"github.com/nathanyu/digital-wallet/internal/middleware"
 	"github.com/nathanyu/digital-wallet/internal/queue"
 	"github.com/nathanyu/digital-wallet/internal/telemetry"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
 )
 
 const serviceName = "digital-wallet"
@@ -28,9 +34,73 @@ const serviceName = "digital-wallet"
 type Config struct {
 	Port           int
 	MetricsPort    int
+	GRPCPort       int // 0 disables the gRPC API (see internal/grpc)
 	NATSUrl        string
 	EventStorePath string
 	GinMode        string
+	// ReplayMode is what replay does with a corrupt or malformed event store
+	// line: "strict" refuses to start, "skip" skips it and reports it
+	ReplayMode string
+	// EventStoreReplicaPath, if set, is a second log every batch is
+	// synchronously written to and read from when the primary is corrupt
+	EventStoreReplicaPath string
+	// MaxSegmentBytes splits the event log into files of about this size
+	// (0 = one file)
+	MaxSegmentBytes int64
+	// EventCodec encodes new events: "json" or the smaller, faster "binary".
+	// Events already stored in the other codec are still read.
+	EventCodec string
+	// BalancesCacheTTL bounds how stale the all-balances endpoint may be
+	BalancesCacheTTL time.Duration
+	// MaxTransferAmount caps a single transfer in cents (0 = no maximum)
+	MaxTransferAmount int64
+	// SnapshotEveryEvents and SnapshotInterval trigger engine state snapshots (0 = off)
+	SnapshotEveryEvents int
+	SnapshotInterval    time.Duration
+	// MaxMemoLength caps a transfer memo in characters (0 = no limit)
+	MaxMemoLength int
+	// MaxAccounts caps the number of distinct accounts (0 = no limit)
+	MaxAccounts int
+	// AccountTrimSpace, AccountFoldCase, AccountMaxLength and AccountPattern
+	// set the account naming policy: IDs are trimmed and lower-cased as
+	// configured, then must be at most AccountMaxLength characters (0 = no
+	// limit) and match AccountPattern in full (empty = any characters).
+	// Transfers, account init and balance queries all apply it.
+	AccountTrimSpace bool
+	AccountFoldCase  bool
+	AccountMaxLength int
+	AccountPattern   string
+	// MaxScheduledTransfers caps pending future-dated transfers (0 = no limit)
+	MaxScheduledTransfers int
+	// ApprovalThreshold holds transfers above this many cents for a second approval (0 = off)
+	ApprovalThreshold int64
+	// FeeFlat and FeeBasisPoints charge a fee on every transfer: cents plus
+	// hundredths of a percent of the amount (both 0 = no fees), paid by the
+	// sender on top of the amount and credited to FeeAccount
+	FeeFlat        int64
+	FeeBasisPoints int64
+	FeeAccount     string
+	// OutcomeCacheSize is how many transaction results duplicates are answered from (0 = off)
+	OutcomeCacheSize int
+	// IdempotencyWindow is how many processed transaction IDs are remembered to reject duplicates (0 = all)
+	IdempotencyWindow int
+	// PriorityLane enables the priority command subject for urgent transfers
+	PriorityLane bool
+	// JetStreamCommands sends commands through a persistent JetStream stream
+	// instead of core NATS request/reply; not combinable with PriorityLane
+	JetStreamCommands bool
+	// DeadLetterLogPath is where dead-lettered commands are recorded (empty = off)
+	DeadLetterLogPath string
+	// MaxDeliveries is how often a JetStream command is attempted before it is dead-lettered
+	MaxDeliveries int
+	// ReadyRequiresNATS makes /readyz fail while NATS is disconnected
+	ReadyRequiresNATS bool
+	// ReadModelPendingMsgs and ReadModelPendingBytes bound the read model's
+	// event subscription buffer (0 = NATS default)
+	ReadModelPendingMsgs  int
+	ReadModelPendingBytes int
+	// ReadModelHistoryLength is how many balance changes the history endpoint keeps per account (0 = off)
+	ReadModelHistoryLength int
 }
 
 func main() {
@@ -62,57 +132,122 @@ func main() {
 	}
 	defer natsClient.Close()
 	log.Println("Connected to NATS")
+	if cfg.JetStreamCommands {
+		if cfg.PriorityLane {
+			log.Fatalf("The priority lane is not supported with JetStream commands")
+		}
+		if err := natsClient.EnableJetStream(); err != nil {
+			log.Fatalf("Failed to set up JetStream commands: %v", err)
+		}
+		log.Printf("Commands go through JetStream stream %s", engine.CommandStream)
+	}
+	var deadLetters *queue.DeadLetterLog
+	if cfg.DeadLetterLogPath != "" {
+		deadLetters, err = queue.NewDeadLetterLog(cfg.DeadLetterLogPath, natsClient)
+		if err != nil {
+			log.Fatalf("Failed to open dead-letter log: %v", err)
+		}
+		defer deadLetters.Close()
+		if err := deadLetters.Start(); err != nil {
+			log.Fatalf("Failed to start dead-letter log: %v", err)
+		}
+		log.Printf("Recording dead-lettered commands in %s", cfg.DeadLetterLogPath)
+	}
 
 	// 2. Initialize Event Store
 	log.Printf("Initializing event store at %s...", cfg.EventStorePath)
-	eventStore, err := eventstore.NewEventStore(cfg.EventStorePath)
+	codec, err := domain.CodecByName(cfg.EventCodec)
+	if err != nil {
+		log.Fatalf("Invalid event codec: %v", err)
+	}
+	eventStore, err := eventstore.NewEventStoreWithOptions(cfg.EventStorePath, eventstore.Options{
+		ReplayMode:      eventstore.ReplayMode(cfg.ReplayMode),
+		ReplicaPath:     cfg.EventStoreReplicaPath,
+		MaxSegmentBytes: cfg.MaxSegmentBytes,
+		Codec:           codec,
+	})
 	if err != nil {
 		log.Fatalf("Failed to initialize event store: %v", err)
 	}
+	if corrupt := eventStore.CorruptLines(); len(corrupt) > 0 {
+		log.Printf("WARNING: skipped %d corrupt event store lines:", len(corrupt))
+		for _, line := range corrupt {
+			log.Printf("  %s", line)
+		}
+	}
 	defer eventStore.Close()
+	if cfg.EventStoreReplicaPath != "" {
+		log.Printf("Replicating event store to %s", cfg.EventStoreReplicaPath)
+	}
 	log.Println("Event store initialized")
 
 	// 3. Initialize Wallet Engine (State Machine)
 	walletEngine := engine.NewWalletEngine(eventStore, natsClient.GetConn())
+	walletEngine.SetMaxTransferAmount(cfg.MaxTransferAmount)
+	walletEngine.SetMaxMemoLength(cfg.MaxMemoLength)
+	walletEngine.SetMaxAccounts(cfg.MaxAccounts)
+	accountPolicy, err := cfg.accountPolicy()
+	if err != nil {
+		log.Fatalf("Invalid account policy: %v", err)
+	}
+	walletEngine.SetAccountPolicy(accountPolicy)
+	walletEngine.SetMaxScheduledTransfers(cfg.MaxScheduledTransfers)
+	walletEngine.SetApprovalThreshold(cfg.ApprovalThreshold)
+	if err := walletEngine.SetFeePolicy(engine.FeePolicy{
+		Account:     cfg.FeeAccount,
+		Flat:        cfg.FeeFlat,
+		BasisPoints: cfg.FeeBasisPoints,
+	}); err != nil {
+		log.Fatalf("Invalid fee policy: %v", err)
+	}
+	walletEngine.SetOutcomeCacheSize(cfg.OutcomeCacheSize)
+	walletEngine.SetIdempotencyWindow(cfg.IdempotencyWindow)
+	if err := walletEngine.SetSnapshotPolicy(cfg.SnapshotEveryEvents, cfg.SnapshotInterval); err != nil {
+		log.Fatalf("Invalid snapshot policy: %v", err)
+	}
+	if cfg.PriorityLane {
+		walletEngine.EnablePriorityLane(engine.DefaultLaneBuffer)
+	}
+	if cfg.JetStreamCommands {
+		walletEngine.EnableJetStream()
+	}
+	walletEngine.SetMaxDeliveries(cfg.MaxDeliveries)
 
 	// 4. Initialize CQRS Read Model
 	readModel := cqrs.NewReadModel(natsClient.GetConn())
+	readModel.SetSnapshotTTL(cfg.BalancesCacheTTL)
+	readModel.SetHistoryLength(cfg.ReadModelHistoryLength)
+	if err := readModel.SetPendingLimits(cfg.ReadModelPendingMsgs, cfg.ReadModelPendingBytes); err != nil {
+		log.Fatalf("Invalid read model pending limits: %v", err)
+	}
+	// Dropped events are replayed from the event store instead of being lost
+	natsClient.OnSlowConsumer(readModel.HandleSlowConsumer)
 
 	// 5. Register read model as event handler for direct updates
-	walletEngine.RegisterEventHandler(readModel.HandleEventDirect)
+	// (the NATS subscription delivers the same events; duplicates are dropped by sequence)
+	walletEngine.RegisterEventHandler(readModel.HandleSequencedEvent)
 
-	// 6. Replay events to rebuild state
-	log.Println("Replaying events to rebuild state...")
-	if err := walletEngine.InitializeFromEventStore(); err != nil {
-		log.Fatalf("Failed to initialize wallet engine: %v", err)
-	}
-	if err := readModel.InitializeFromEventStore(eventStore); err != nil {
-		log.Fatalf("Failed to initialize read model: %v", err)
+	// 6. Initialize HTTP handler; balance and transfer endpoints answer 503
+	// until replay completes and both components have started
+	h := handler.NewHandler(natsClient, readModel, walletEngine)
+	h.EnableEventStream(eventStore, natsClient)
+	h.EnableEventExport(eventStore)
+	if deadLetters != nil {
+		h.EnableDeadLetters(deadLetters)
 	}
-
-	// 7. Start the wallet engine
-	if err := walletEngine.Start(); err != nil {
-		log.Fatalf("Failed to start wallet engine: %v", err)
+	h.SetReady(false)
+	if cfg.ReadyRequiresNATS {
+		h.SetBrokerStatus(natsClient.Status())
 	}
-	defer walletEngine.Stop()
 
-	// 8. Start the read model (subscribe to events via NATS)
-	if err := readModel.Start(engine.EventSubject); err != nil {
-		log.Fatalf("Failed to start read model: %v", err)
-	}
-	defer readModel.Stop()
-
-	// 9. Initialize HTTP handler
-	h := handler.NewHandler(natsClient, readModel, walletEngine)
-
-	// 10. Setup Gin router with middleware
+	// 7. Setup Gin router with middleware
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(middleware.Tracing())
 	router.Use(middleware.Metrics())
 	handler.SetupRoutes(router, h)
 
-	// 11. Start HTTP server
+	// 8. Start HTTP server
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
 		Handler:      router,
@@ -120,7 +255,7 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	// 12. Start metrics server (separate port for Prometheus scraping)
+	// 9. Start metrics server (separate port for Prometheus scraping)
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", promhttp.Handler())
 	metricsSrv := &http.Server{
@@ -128,6 +263,22 @@ func main() {
 		Handler: metricsMux,
 	}
 
+	// 9b. gRPC API, sharing the handler's validation and readiness gate
+	var grpcSrv *grpc.Server
+	if cfg.GRPCPort > 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC: %v", err)
+		}
+		grpcSrv = walletgrpc.NewGRPCServer(walletgrpc.NewServer(h, readModel, walletEngine))
+		go func() {
+			log.Printf("gRPC server listening on port %d", cfg.GRPCPort)
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatalf("gRPC server error: %v", err)
+			}
+		}()
+	}
+
 	// Start servers in goroutines
 	go func() {
 		log.Printf("HTTP server listening on port %d", cfg.Port)
@@ -143,6 +294,31 @@ func main() {
 		}
 	}()
 
+	// 10. Replay events to rebuild state
+	log.Println("Replaying events to rebuild state...")
+	if err := walletEngine.InitializeFromEventStore(); err != nil {
+		log.Fatalf("Failed to initialize wallet engine: %v", err)
+	}
+	if err := readModel.InitializeFromEventStore(eventStore); err != nil {
+		log.Fatalf("Failed to initialize read model: %v", err)
+	}
+
+	// 11. Start the wallet engine
+	if err := walletEngine.Start(); err != nil {
+		log.Fatalf("Failed to start wallet engine: %v", err)
+	}
+	defer walletEngine.Stop()
+
+	// 12. Start the read model (subscribe to events via NATS)
+	if err := readModel.Start(engine.EventSubject); err != nil {
+		log.Fatalf("Failed to start read model: %v", err)
+	}
+	defer readModel.Stop()
+
+	// 13. Open the readiness gate
+	h.SetReady(true)
+	log.Println("Service ready")
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -160,6 +336,9 @@ func main() {
 	if err := metricsSrv.Shutdown(ctx); err != nil {
 		log.Printf("Metrics server forced to shutdown: %v", err)
 	}
+	if grpcSrv != nil {
+		grpcSrv.GracefulStop()
+	}
 
 	log.Println("Service stopped")
 }
@@ -169,15 +348,65 @@ func parseFlags() *Config {
 
 	flag.IntVar(&cfg.Port, "port", getEnvInt("PORT", 8080), "HTTP server port")
 	flag.IntVar(&cfg.MetricsPort, "metrics-port", getEnvInt("METRICS_PORT", 9090), "Metrics server port")
+	flag.IntVar(&cfg.GRPCPort, "grpc-port", getEnvInt("GRPC_PORT", 50051), "gRPC server port (0 disables the gRPC API)")
 	flag.StringVar(&cfg.NATSUrl, "nats-url", getEnv("NATS_URL", "nats://localhost:4222"), "NATS server URL")
 	flag.StringVar(&cfg.EventStorePath, "event-store", getEnv("EVENT_STORE_PATH", "data/events.log"), "Event store file path")
+	flag.StringVar(&cfg.ReplayMode, "replay-mode", getEnv("REPLAY_MODE", string(eventstore.ReplayStrict)), "Corrupt or malformed event store lines on replay: strict (refuse to start) or skip (start without them and report them)")
+	flag.StringVar(&cfg.EventStoreReplicaPath, "event-store-replica", getEnv("EVENT_STORE_REPLICA_PATH", ""), "Replica event store file path (empty = no replica)")
+	flag.StringVar(&cfg.EventCodec, "event-codec", getEnv("EVENT_CODEC", domain.JSONCodec.Name()), "Encoding of new events in the event store: json or binary")
+	flag.Int64Var(&cfg.MaxSegmentBytes, "max-segment-bytes", int64(getEnvInt("EVENT_STORE_MAX_SEGMENT_BYTES", 0)), "Start a new event store segment file past this many bytes (0 = one file)")
 	flag.StringVar(&cfg.GinMode, "gin-mode", getEnv("GIN_MODE", "release"), "Gin mode (debug/release)")
+	flag.Int64Var(&cfg.MaxTransferAmount, "max-transfer-amount", int64(getEnvInt("MAX_TRANSFER_AMOUNT", 0)), "Largest amount in cents a single transfer may move (0 = no maximum)")
+	flag.IntVar(&cfg.MaxMemoLength, "max-memo-length", getEnvInt("MAX_MEMO_LENGTH", engine.DefaultMaxMemoLength), "Longest transfer memo in characters (0 = no limit)")
+	flag.IntVar(&cfg.MaxAccounts, "max-accounts", getEnvInt("MAX_ACCOUNTS", 0), "Most distinct accounts; transfers to new accounts fail once reached (0 = no limit)")
+	flag.BoolVar(&cfg.AccountTrimSpace, "account-trim-space", getEnvBool("ACCOUNT_TRIM_SPACE", true), "Trim surrounding whitespace from account IDs")
+	flag.BoolVar(&cfg.AccountFoldCase, "account-fold-case", getEnvBool("ACCOUNT_FOLD_CASE", false), "Lower-case account IDs so they are case-insensitive")
+	flag.IntVar(&cfg.AccountMaxLength, "account-max-length", getEnvInt("ACCOUNT_MAX_LENGTH", domain.DefaultMaxAccountLength), "Longest account ID in characters (0 = no limit)")
+	flag.StringVar(&cfg.AccountPattern, "account-pattern", getEnv("ACCOUNT_PATTERN", ""), "Regular expression a whole account ID must match, e.g. [a-z0-9_.-]+ (empty allows any)")
+	flag.Int64Var(&cfg.ApprovalThreshold, "approval-threshold", int64(getEnvInt("APPROVAL_THRESHOLD", 0)), "Transfers above this many cents wait for approval (0 = no approvals)")
+	flag.Int64Var(&cfg.FeeFlat, "fee-flat", int64(getEnvInt("FEE_FLAT", 0)), "Flat fee in cents charged on every transfer (0 = none)")
+	flag.Int64Var(&cfg.FeeBasisPoints, "fee-basis-points", int64(getEnvInt("FEE_BASIS_POINTS", 0)), "Percentage fee on every transfer in hundredths of a percent, e.g. 150 = 1.5% (0 = none)")
+	flag.StringVar(&cfg.FeeAccount, "fee-account", getEnv("FEE_ACCOUNT", engine.DefaultFeeAccount), "Account transfer fees are credited to")
+	flag.IntVar(&cfg.MaxScheduledTransfers, "max-scheduled-transfers", getEnvInt("MAX_SCHEDULED_TRANSFERS", engine.DefaultMaxScheduledTransfers), "Most future-dated transfers pending at once (0 = no limit)")
+	flag.IntVar(&cfg.OutcomeCacheSize, "outcome-cache-size", getEnvInt("OUTCOME_CACHE_SIZE", engine.DefaultOutcomeCacheSize), "Transaction results remembered to answer duplicates with the original outcome (0 disables)")
+	flag.IntVar(&cfg.IdempotencyWindow, "idempotency-window", getEnvInt("IDEMPOTENCY_WINDOW", engine.DefaultIdempotencyWindow), "Processed transaction IDs remembered to reject duplicates; older IDs are forgotten first (0 keeps all)")
+	flag.IntVar(&cfg.SnapshotEveryEvents, "snapshot-every-events", getEnvInt("SNAPSHOT_EVERY_EVENTS", 0), "Snapshot engine state after this many events (0 disables)")
+	flag.DurationVar(&cfg.SnapshotInterval, "snapshot-interval", getEnvDuration("SNAPSHOT_INTERVAL", 0), "Snapshot engine state when this much time has passed since the last snapshot (0 disables)")
+	flag.BoolVar(&cfg.PriorityLane, "priority-lane", getEnvBool("PRIORITY_LANE", false), "Consume the priority command subject for urgent transfers")
+	flag.BoolVar(&cfg.JetStreamCommands, "jetstream-commands", getEnvBool("JETSTREAM_COMMANDS", false), "Send commands through a persistent JetStream stream so none are lost while the engine is down (requires JetStream on the NATS server)")
+	flag.StringVar(&cfg.DeadLetterLogPath, "dead-letter-log", getEnv("DEAD_LETTER_LOG_PATH", "data/deadletter.log"), "File recording commands the engine dead-lettered (empty disables)")
+	flag.IntVar(&cfg.MaxDeliveries, "max-deliveries", getEnvInt("MAX_DELIVERIES", engine.DefaultMaxDeliveries), "Attempts of a JetStream command before it is dead-lettered")
+	flag.BoolVar(&cfg.ReadyRequiresNATS, "ready-requires-nats", getEnvBool("READY_REQUIRES_NATS", true), "Report not-ready on /readyz while NATS is disconnected")
+	flag.IntVar(&cfg.ReadModelPendingMsgs, "read-model-pending-msgs", getEnvInt("READ_MODEL_PENDING_MSGS", 0), "Events the read model subscription may buffer before NATS drops them (0 = NATS default)")
+	flag.IntVar(&cfg.ReadModelPendingBytes, "read-model-pending-bytes", getEnvInt("READ_MODEL_PENDING_BYTES", 0), "Bytes the read model subscription may buffer before NATS drops events (0 = NATS default)")
+	flag.IntVar(&cfg.ReadModelHistoryLength, "read-model-history-length", getEnvInt("READ_MODEL_HISTORY_LENGTH", cqrs.DefaultHistoryLength), "Balance changes kept per account for the history endpoint; older ones are dropped (0 disables)")
+	flag.DurationVar(&cfg.BalancesCacheTTL, "balances-cache-ttl", getEnvDuration("BALANCES_CACHE_TTL", time.Second), "TTL of the cached all-balances snapshot (0 disables)")
 
 	flag.Parse()
 
 	return cfg
 }
 
+// accountPolicy builds the account naming policy from the configuration
+func (cfg *Config) accountPolicy() (domain.AccountPolicy, error) {
+	policy := domain.AccountPolicy{
+		TrimSpace: cfg.AccountTrimSpace,
+		FoldCase:  cfg.AccountFoldCase,
+		MaxLength: cfg.AccountMaxLength,
+	}
+	if cfg.AccountMaxLength < 0 {
+		return policy, fmt.Errorf("account max length must not be negative, got %d", cfg.AccountMaxLength)
+	}
+	if cfg.AccountPattern != "" {
+		pattern, err := regexp.Compile("^(?:" + cfg.AccountPattern + ")$")
+		if err != nil {
+			return policy, fmt.Errorf("account pattern: %w", err)
+		}
+		policy.Pattern = pattern
+	}
+	return policy, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -194,3 +423,21 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,96 @@
+package cqrs
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// balanceSnapshot is an immutable copy of all default currency balances
+// taken at a point in time
+type balanceSnapshot struct {
+	balances map[string]int64
+	total    int64
+	takenAt  time.Time
+}
+
+// balanceCache serves the "all balances" query from a periodically refreshed
+// snapshot, so frequent polling doesn't copy the full map under the read lock
+// on every request. A zero TTL disables caching.
+type balanceCache struct {
+	ttl       atomic.Int64 // time.Duration
+	current   atomic.Pointer[balanceSnapshot]
+	refreshMu sync.Mutex // serializes refreshes so concurrent misses copy the map once
+}
+
+// SetSnapshotTTL configures how long an all-balances snapshot may be served
+// before it is rebuilt. Zero disables the cache.
+func (r *ReadModel) SetSnapshotTTL(ttl time.Duration) {
+	r.cache.ttl.Store(int64(ttl))
+	r.cache.current.Store(nil)
+}
+
+// SetClock overrides the read model's time source (for testing)
+func (r *ReadModel) SetClock(now func() time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.now = now
+}
+
+// GetBalancesSnapshot returns all balances and their total, served from the
+// cached snapshot when it is younger than the TTL. The returned map is shared
+// between callers and must not be modified.
+func (r *ReadModel) GetBalancesSnapshot() (map[string]int64, int64) {
+	ttl := time.Duration(r.cache.ttl.Load())
+	if ttl <= 0 {
+		snap := r.takeSnapshot()
+		return snap.balances, snap.total
+	}
+
+	now := r.clock()
+	if snap := r.cache.current.Load(); snap != nil && now.Sub(snap.takenAt) < ttl {
+		return snap.balances, snap.total
+	}
+
+	r.cache.refreshMu.Lock()
+	defer r.cache.refreshMu.Unlock()
+
+	// Another caller may have refreshed while we waited
+	if snap := r.cache.current.Load(); snap != nil && now.Sub(snap.takenAt) < ttl {
+		return snap.balances, snap.total
+	}
+
+	snap := r.takeSnapshot()
+	r.cache.current.Store(snap)
+	return snap.balances, snap.total
+}
+
+// invalidateSnapshot drops the cached snapshot so the next read rebuilds it
+func (r *ReadModel) invalidateSnapshot() {
+	r.cache.current.Store(nil)
+}
+
+// takeSnapshot copies balances and their total under a single read lock
+func (r *ReadModel) takeSnapshot() *balanceSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snap := &balanceSnapshot{
+		balances: make(map[string]int64, len(r.balances[domain.DefaultCurrency])),
+		takenAt:  r.now(),
+	}
+	for k, v := range r.balances[domain.DefaultCurrency] {
+		snap.balances[k] = v
+		snap.total += v
+	}
+	return snap
+}
+
+// clock returns the current time from the read model's time source
+func (r *ReadModel) clock() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.now()
+}
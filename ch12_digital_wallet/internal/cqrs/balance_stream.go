@@ -0,0 +1,127 @@
+package cqrs
+
+import (
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// BalanceSubscriberBuffer is how many updates a balance subscriber may fall
+// behind before it is dropped
+const BalanceSubscriberBuffer = 64
+
+// BalanceUpdate is the balance of an account in one currency, pushed to its
+// subscribers after every change. The first update of a subscription is the
+// balance at the time of subscribing and has no transaction.
+type BalanceUpdate struct {
+	Account  string `json:"account"`
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+	// The change that led to Balance; Amount is negative when money left
+	// the account
+	TransactionID string `json:"transaction_id,omitempty"`
+	Type          string `json:"type,omitempty"`
+	Amount        int64  `json:"amount,omitempty"`
+}
+
+// balanceSubscriber receives the balance updates of one account and currency
+type balanceSubscriber struct {
+	currency string
+	updates  chan BalanceUpdate
+}
+
+// balanceSubscribers fans balance changes out to the subscribers of each
+// account. Guarded by r.mu.
+type balanceSubscribers map[string]map[*balanceSubscriber]struct{}
+
+// SubscribeBalance subscribes to the balance of account in currency. The
+// returned channel first yields the current balance, then one update per
+// change, in order. A subscriber that falls BalanceSubscriberBuffer updates
+// behind is dropped and its channel closed, so a slow client never holds up
+// the read model. unsubscribe closes the channel too and is safe to call
+// after a drop.
+func (r *ReadModel) SubscribeBalance(account, currency string) (updates <-chan BalanceUpdate, unsubscribe func()) {
+	currency = domain.CurrencyOrDefault(currency)
+	sub := &balanceSubscriber{
+		currency: currency,
+		updates:  make(chan BalanceUpdate, BalanceSubscriberBuffer+1),
+	}
+
+	// Registering under the write lock orders the current balance before
+	// any change applied later
+	r.mu.Lock()
+	sub.updates <- BalanceUpdate{Account: account, Currency: currency, Balance: r.balances[currency][account]}
+	if r.subscribers == nil {
+		r.subscribers = make(balanceSubscribers)
+	}
+	if r.subscribers[account] == nil {
+		r.subscribers[account] = make(map[*balanceSubscriber]struct{})
+	}
+	r.subscribers[account][sub] = struct{}{}
+	r.mu.Unlock()
+	telemetry.BalanceSubscribers.Inc()
+
+	return sub.updates, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.removeSubscriberLocked(account, sub)
+	}
+}
+
+// removeSubscriberLocked unregisters sub and closes its channel, if still
+// registered. Caller must hold the write lock.
+func (r *ReadModel) removeSubscriberLocked(account string, sub *balanceSubscriber) {
+	if _, ok := r.subscribers[account][sub]; !ok {
+		return
+	}
+	delete(r.subscribers[account], sub)
+	if len(r.subscribers[account]) == 0 {
+		delete(r.subscribers, account)
+	}
+	close(sub.updates)
+	telemetry.BalanceSubscribers.Dec()
+}
+
+// notifyBalance pushes the balance change of event to the subscribers of
+// its account. Caller must hold the write lock and have applied the balance.
+func (r *ReadModel) notifyBalance(event domain.Event) {
+	if len(r.subscribers) == 0 {
+		return
+	}
+
+	var account, currency string
+	var amount int64
+	switch ev := event.(type) {
+	case domain.MoneyDeducted:
+		account, currency, amount = ev.Account, ev.Currency, -ev.Amount
+	case domain.MoneyCredited:
+		account, currency, amount = ev.Account, ev.Currency, ev.Amount
+	case domain.MoneyDeposited:
+		account, currency, amount = ev.Account, ev.Currency, ev.Amount
+	case domain.MoneyWithdrawn:
+		account, currency, amount = ev.Account, ev.Currency, -ev.Amount
+	default:
+		return
+	}
+	currency = domain.CurrencyOrDefault(currency)
+
+	update := BalanceUpdate{
+		Account:       account,
+		Currency:      currency,
+		Balance:       r.balances[currency][account],
+		TransactionID: event.GetTransactionID(),
+		Type:          event.GetType(),
+		Amount:        amount,
+	}
+	for sub := range r.subscribers[account] {
+		if sub.currency != currency {
+			continue
+		}
+		select {
+		case sub.updates <- update:
+		default:
+			// Slow client: drop it rather than wait
+			r.removeSubscriberLocked(account, sub)
+			telemetry.BalanceSubscribersDroppedTotal.Inc()
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cqrs
+
+import "github.com/nathanyu/digital-wallet/internal/domain"
+
+// DefaultHistoryLength is how many entries the read model keeps per account
+// until SetHistoryLength changes it
+const DefaultHistoryLength = 1000
+
+// HistoryEntry is one balance change of an account
+type HistoryEntry struct {
+	TransactionID string `json:"transaction_id"`
+	Type          string `json:"type"`
+	Amount        int64  `json:"amount"` // Signed: negative when money left the account
+	Currency      string `json:"currency"`
+	Balance       int64  `json:"balance"` // Balance in Currency after the change
+	// Counterparty is the other account of a transfer; empty for deposits
+	// and withdrawals
+	Counterparty string `json:"counterparty,omitempty"`
+	Reverses     string `json:"reverses,omitempty"`
+	Memo         string `json:"memo,omitempty"`
+	// Fee marks the fee leg of a transfer
+	Fee bool `json:"fee,omitempty"`
+}
+
+// accountHistory keeps the latest balance changes of each account, oldest
+// first, dropping the oldest beyond length. Guarded by r.mu.
+type accountHistory struct {
+	length  int // 0 disables history
+	entries map[string][]HistoryEntry
+}
+
+func newAccountHistory(length int) accountHistory {
+	return accountHistory{length: length, entries: make(map[string][]HistoryEntry)}
+}
+
+// record appends a balance change of account
+func (h *accountHistory) record(account string, entry HistoryEntry) {
+	if h.length == 0 {
+		return
+	}
+	entries := append(h.entries[account], entry)
+	if len(entries) > h.length {
+		entries = entries[len(entries)-h.length:]
+	}
+	h.entries[account] = entries
+}
+
+// link sets the counterparty of both sides of a transfer once its credit is
+// applied. The debit is among the latest entries of the sender, since the
+// two events of a transfer are applied back to back.
+func (h *accountHistory) link(txnID, from, to string) {
+	link := func(account, counterparty, eventType string) {
+		entries := h.entries[account]
+		for i := len(entries) - 1; i >= 0 && i >= len(entries)-2; i-- {
+			if entries[i].TransactionID == txnID && entries[i].Type == eventType {
+				entries[i].Counterparty = counterparty
+				return
+			}
+		}
+	}
+	link(from, to, domain.EventTypeMoneyDeducted)
+	link(to, from, domain.EventTypeMoneyCredited)
+}
+
+// recordHistory adds the balance change of event to the history of its
+// account. Caller must hold the write lock and have applied the balance.
+func (r *ReadModel) recordHistory(event domain.Event) {
+	entry := func(account, currency string, amount int64, reverses, memo string, fee bool) HistoryEntry {
+		currency = domain.CurrencyOrDefault(currency)
+		return HistoryEntry{
+			TransactionID: event.GetTransactionID(),
+			Type:          event.GetType(),
+			Amount:        amount,
+			Currency:      currency,
+			Balance:       r.balances[currency][account],
+			Reverses:      reverses,
+			Memo:          memo,
+			Fee:           fee,
+		}
+	}
+
+	switch ev := event.(type) {
+	case domain.MoneyDeducted:
+		r.history.record(ev.Account, entry(ev.Account, ev.Currency, -ev.Amount, ev.Reverses, ev.Memo, ev.Fee))
+		r.lastDebit = ev
+	case domain.MoneyCredited:
+		r.history.record(ev.Account, entry(ev.Account, ev.Currency, ev.Amount, ev.Reverses, ev.Memo, ev.Fee))
+		if r.lastDebit.TransactionID == ev.TransactionID {
+			r.history.link(ev.TransactionID, r.lastDebit.Account, ev.Account)
+		}
+	case domain.MoneyDeposited:
+		r.history.record(ev.Account, entry(ev.Account, ev.Currency, ev.Amount, "", ev.Memo, false))
+	case domain.MoneyWithdrawn:
+		r.history.record(ev.Account, entry(ev.Account, ev.Currency, -ev.Amount, "", ev.Memo, false))
+	}
+}
+
+// SetHistoryLength sets how many balance changes are kept per account; 0
+// disables history. Shrinking it drops the oldest entries at once; growing
+// it only keeps more from now on.
+func (r *ReadModel) SetHistoryLength(length int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if length < 0 {
+		length = 0
+	}
+	if length == 0 {
+		r.history = newAccountHistory(0)
+		return
+	}
+	r.history.length = length
+	for account, entries := range r.history.entries {
+		if len(entries) > length {
+			r.history.entries[account] = entries[len(entries)-length:]
+		}
+	}
+}
+
+// GetHistory returns up to limit of account's retained balance changes,
+// newest first, skipping the offset newest ones. A limit of 0 returns all
+// of them.
+func (r *ReadModel) GetHistory(account string, limit, offset int) []HistoryEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := r.history.entries[account]
+	if offset < 0 {
+		offset = 0
+	}
+	n := len(entries) - offset
+	if n <= 0 {
+		return []HistoryEntry{}
+	}
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	result := make([]HistoryEntry, n)
+	for i := range result {
+		result[i] = entries[len(entries)-1-offset-i]
+	}
+	return result
+}
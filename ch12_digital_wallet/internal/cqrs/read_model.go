@@ -5,20 +5,54 @@ import (
 	"encoding/json"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nathanyu/digital-wallet/internal/domain"
-	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
 )
 
+// EventSource is the event store the read model replays from to fill gaps.
+// *eventstore.EventStore is the production implementation.
+type EventSource interface {
+	LoadSince(afterSeq uint64) ([]domain.SequencedEvent, error)
+}
+
 // ReadModel provides a read-only view of wallet balances (CQRS pattern)
 type ReadModel struct {
-	// Read-only balances map
-	balances map[string]int64
+	// Read-only balances, by currency and then account
+	balances map[string]map[string]int64
+	// Frozen accounts, for the balance endpoint
+	frozen map[string]bool
 	mu       sync.RWMutex
 
+	// Sequence of the last applied event; anything after it is replayed
+	// from source when a gap is detected (at-least-once, deduplicated by sequence)
+	lastSeq uint64
+	source  EventSource
+
+	// Recent balance changes per account (see history.go); lastDebit is
+	// the latest MoneyDeducted, to pair a transfer's two sides
+	history   accountHistory
+	lastDebit domain.MoneyDeducted
+
+	// Balance stream subscribers by account (see balance_stream.go)
+	subscribers balanceSubscribers
+
+	// Cached all-balances snapshot (see balance_cache.go)
+	cache balanceCache
+	now   func() time.Time
+
 	natsConn     *nats.Conn
 	subscription *nats.Subscription
+	subMu        sync.Mutex
+
+	// Slow consumer handling (see slow_consumer.go); limits of 0 keep the NATS defaults
+	pendingMsgs  int
+	pendingBytes int
+	droppedSeen  int // drops already counted for the current subscription
+	resyncing    atomic.Bool
 
 	ctx      context.Context
 	cancel   context.CancelFunc
@@ -29,142 +63,264 @@ type ReadModel struct {
 func NewReadModel(natsConn *nats.Conn) *ReadModel {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &ReadModel{
-		balances: make(map[string]int64),
+		balances: make(map[string]map[string]int64),
+		frozen:   make(map[string]bool),
+		history:  newAccountHistory(DefaultHistoryLength),
+		now:      time.Now,
 		natsConn: natsConn,
 		ctx:      ctx,
 		cancel:   cancel,
 	}
 }
 
-// InitializeFromEventStore replays all events to rebuild the read model
-func (r *ReadModel) InitializeFromEventStore(store *eventstore.EventStore) error {
-	events, err := store.LoadAll()
-	if err != nil {
-		return err
-	}
-
+// InitializeFromEventStore replays all events to rebuild the read model.
+// The store is kept as the source for replaying gaps later on.
+func (r *ReadModel) InitializeFromEventStore(store EventSource) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for _, event := range events {
-		r.applyEvent(event)
+	r.source = store
+	replayed, err := r.replayLocked()
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Read model initialized with %d events, %d accounts", len(events), len(r.balances))
+	log.Printf("Read model initialized with %d events, %d accounts", replayed, len(r.balances[domain.DefaultCurrency]))
 	return nil
 }
 
-// Start subscribes to the event stream
+// Start subscribes to the event stream, then replays anything persisted since
+// the last applied event. Subscribing first means no event can fall between
+// the replay and the live stream; overlaps are dropped by sequence.
+// Start may be called again after Stop to reconnect.
 func (r *ReadModel) Start(eventSubject string) error {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
 	sub, err := r.natsConn.Subscribe(eventSubject, r.handleEvent)
 	if err != nil {
 		return err
 	}
-
 	r.subscription = sub
+	r.droppedSeen = 0
+	if err := r.applyPendingLimits(sub); err != nil {
+		log.Printf("Read model failed to set pending limits: %v", err)
+	}
+
+	if err := r.Resync(); err != nil {
+		log.Printf("Read model resync on start failed: %v", err)
+	}
+
 	log.Printf("Read model started, listening for events on: %s", eventSubject)
 	return nil
 }
 
-// Stop gracefully stops the read model
+// Stop unsubscribes from the event stream
 func (r *ReadModel) Stop() error {
-	var err error
-	r.stopOnce.Do(func() {
-		r.cancel()
-		if r.subscription != nil {
-			err = r.subscription.Unsubscribe()
-		}
-	})
+	r.stopOnce.Do(r.cancel)
+
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	if r.subscription == nil {
+		return nil
+	}
+	err := r.subscription.Unsubscribe()
+	r.subscription = nil
 	return err
 }
 
 // handleEvent processes events from NATS
 func (r *ReadModel) handleEvent(msg *nats.Msg) {
-	event, err := domain.DeserializeEvent(msg.Data)
+	event, err := domain.DeserializeSequencedEvent(msg.Data)
 	if err != nil {
 		log.Printf("Failed to deserialize event in read model: %v", err)
 		return
 	}
 
-	r.mu.Lock()
-	r.applyEvent(event)
-	r.mu.Unlock()
+	r.HandleSequencedEvent(event)
 }
 
-// HandleEventDirect processes an event directly (for direct engine integration)
+// HandleEventDirect processes an unsequenced event directly, bypassing gap detection
 func (r *ReadModel) HandleEventDirect(event domain.Event) {
 	r.mu.Lock()
 	r.applyEvent(event)
 	r.mu.Unlock()
 }
 
+// HandleSequencedEvent applies an event exactly once by sequence. Events at or
+// below the last applied sequence are duplicates and are dropped; an event
+// beyond the next expected sequence triggers a replay of the gap first.
+func (r *ReadModel) HandleSequencedEvent(event domain.SequencedEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.Sequence == 0 {
+		r.applyEvent(event.Event)
+		return
+	}
+	if event.Sequence <= r.lastSeq {
+		return
+	}
+	if event.Sequence > r.lastSeq+1 && r.source != nil {
+		log.Printf("Read model gap detected: last applied %d, received %d; replaying", r.lastSeq, event.Sequence)
+		if _, err := r.replayLocked(); err != nil {
+			log.Printf("Read model gap replay failed: %v", err)
+		}
+		if event.Sequence <= r.lastSeq {
+			return
+		}
+	}
+
+	r.applyEvent(event.Event)
+	r.lastSeq = event.Sequence
+}
+
+// Resync replays any events persisted after the last applied sequence
+func (r *ReadModel) Resync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replayed, err := r.replayLocked()
+	if err != nil {
+		return err
+	}
+	if replayed > 0 {
+		log.Printf("Read model resynced %d events, now at sequence %d", replayed, r.lastSeq)
+	}
+	return nil
+}
+
+// LastSequence returns the sequence of the last applied event
+func (r *ReadModel) LastSequence() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastSeq
+}
+
+// replayLocked applies every event in source after lastSeq.
+// Caller must hold the write lock.
+func (r *ReadModel) replayLocked() (int, error) {
+	if r.source == nil {
+		return 0, nil
+	}
+
+	events, err := r.source.LoadSince(r.lastSeq)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		r.applyEvent(event.Event)
+		r.lastSeq = event.Sequence
+	}
+	telemetry.ReadModelReplayedEventsTotal.Add(float64(len(events)))
+	return len(events), nil
+}
+
 // applyEvent updates the read model based on an event
 // This method is NOT thread-safe; caller must hold the lock
 func (r *ReadModel) applyEvent(event domain.Event) {
 	switch ev := event.(type) {
 	case domain.MoneyDeducted:
-		r.balances[ev.Account] -= ev.Amount
+		r.addBalance(ev.Account, ev.Currency, -ev.Amount)
 	case domain.MoneyCredited:
-		r.balances[ev.Account] += ev.Amount
+		r.addBalance(ev.Account, ev.Currency, ev.Amount)
+	case domain.MoneyDeposited:
+		r.addBalance(ev.Account, ev.Currency, ev.Amount)
+	case domain.MoneyWithdrawn:
+		r.addBalance(ev.Account, ev.Currency, -ev.Amount)
 	case domain.TransactionFailed:
 		// No state change for failed transactions
+	case domain.AccountFrozen:
+		r.frozen[ev.Account] = true
+	case domain.AccountUnfrozen:
+		delete(r.frozen, ev.Account)
+	}
+	r.recordHistory(event)
+	r.notifyBalance(event)
+}
+
+// addBalance adds delta to account's balance in currency.
+// Caller must hold the lock.
+func (r *ReadModel) addBalance(account, currency string, delta int64) {
+	currency = domain.CurrencyOrDefault(currency)
+	balances, ok := r.balances[currency]
+	if !ok {
+		balances = make(map[string]int64)
+		r.balances[currency] = balances
 	}
+	balances[account] += delta
 }
 
-// GetBalance returns the current balance for an account
-func (r *ReadModel) GetBalance(account string) (int64, bool) {
+// GetBalance returns the current balance for an account in currency
+func (r *ReadModel) GetBalance(account, currency string) (int64, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	balance, exists := r.balances[account]
+	balance, exists := r.balances[currency][account]
 	return balance, exists
 }
 
-// GetAllBalances returns a copy of all balances
+// IsFrozen reports whether account is frozen
+func (r *ReadModel) IsFrozen(account string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.frozen[account]
+}
+
+// GetAllBalances returns a copy of all default currency balances
 func (r *ReadModel) GetAllBalances() map[string]int64 {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	result := make(map[string]int64, len(r.balances))
-	for k, v := range r.balances {
+	balances := r.balances[domain.DefaultCurrency]
+	result := make(map[string]int64, len(balances))
+	for k, v := range balances {
 		result[k] = v
 	}
 	return result
 }
 
-// GetTotalBalance returns the sum of all account balances
+// GetTotalBalance returns the sum of all default currency balances
 func (r *ReadModel) GetTotalBalance() int64 {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	var total int64
-	for _, balance := range r.balances {
+	for _, balance := range r.balances[domain.DefaultCurrency] {
 		total += balance
 	}
 	return total
 }
 
-// SetBalance sets the balance for an account (for initialization/testing)
+// SetBalance sets the default currency balance for an account (for initialization/testing)
 func (r *ReadModel) SetBalance(account string, balance int64) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.balances[account] = balance
+	r.addBalance(account, domain.DefaultCurrency, balance-r.balances[domain.DefaultCurrency][account])
+	// Account initialization should be visible immediately
+	r.invalidateSnapshot()
 }
 
 // BalanceResponse is the JSON response for balance queries
 type BalanceResponse struct {
-	Account string `json:"account"`
-	Balance int64  `json:"balance"`
-	Exists  bool   `json:"exists"`
+	Account  string `json:"account"`
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+	Exists   bool   `json:"exists"`
+	Frozen   bool   `json:"frozen"`
 }
 
-// ToJSON returns the balance as a JSON response
+// ToJSON returns the default currency balance as a JSON response
 func (r *ReadModel) ToJSON(account string) []byte {
-	balance, exists := r.GetBalance(account)
+	balance, exists := r.GetBalance(account, domain.DefaultCurrency)
 	resp := BalanceResponse{
-		Account: account,
-		Balance: balance,
-		Exists:  exists,
+		Account:  account,
+		Currency: domain.DefaultCurrency,
+		Balance:  balance,
+		Exists:   exists,
+		Frozen:   r.IsFrozen(account),
 	}
 	data, _ := json.Marshal(resp)
 	return data
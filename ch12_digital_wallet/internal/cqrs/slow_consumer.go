@@ -0,0 +1,85 @@
+package cqrs
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"github.com/nats-io/nats.go"
+)
+
+// SetPendingLimits bounds how many messages and bytes the event subscription
+// may buffer before NATS drops further events and reports a slow consumer;
+// 0 keeps the NATS default for that limit. Takes effect on the next Start.
+func (r *ReadModel) SetPendingLimits(msgs, bytes int) error {
+	if msgs < 0 || bytes < 0 {
+		return fmt.Errorf("pending limits cannot be negative")
+	}
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+	r.pendingMsgs = msgs
+	r.pendingBytes = bytes
+	return nil
+}
+
+// applyPendingLimits sets the configured limits on a new subscription.
+// Caller must hold subMu.
+func (r *ReadModel) applyPendingLimits(sub *nats.Subscription) error {
+	if r.pendingMsgs == 0 && r.pendingBytes == 0 {
+		return nil
+	}
+	msgs, bytes, err := sub.PendingLimits()
+	if err != nil {
+		return err
+	}
+	if r.pendingMsgs > 0 {
+		msgs = r.pendingMsgs
+	}
+	if r.pendingBytes > 0 {
+		bytes = r.pendingBytes
+	}
+	return sub.SetPendingLimits(msgs, bytes)
+}
+
+// HandleSlowConsumer is the NATS slow consumer callback (see
+// queue.NATSClient.OnSlowConsumer). Drops on the read model's subscription
+// trigger a background ResyncAfterDrops; other subscriptions are ignored.
+func (r *ReadModel) HandleSlowConsumer(sub *nats.Subscription) {
+	r.subMu.Lock()
+	ours := sub != nil && sub == r.subscription
+	r.subMu.Unlock()
+	if !ours {
+		return
+	}
+
+	// Several reports while a resync runs need only one more pass
+	if !r.resyncing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer r.resyncing.Store(false)
+		if err := r.ResyncAfterDrops(); err != nil {
+			log.Printf("Read model resync after dropped events failed: %v", err)
+		}
+	}()
+}
+
+// ResyncAfterDrops records that the event subscription dropped messages and
+// replays everything persisted since the last applied event. Without it a
+// drop at the tail of the stream would go unnoticed until the next event
+// revealed the gap.
+func (r *ReadModel) ResyncAfterDrops() error {
+	telemetry.ReadModelSlowConsumerTotal.Inc()
+
+	r.subMu.Lock()
+	if r.subscription != nil {
+		if dropped, err := r.subscription.Dropped(); err == nil && dropped > r.droppedSeen {
+			telemetry.ReadModelDroppedEventsTotal.Add(float64(dropped - r.droppedSeen))
+			log.Printf("Read model subscription dropped %d events", dropped-r.droppedSeen)
+			r.droppedSeen = dropped
+		}
+	}
+	r.subMu.Unlock()
+
+	return r.Resync()
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxAccountLength is the account ID length cap of DefaultAccountPolicy
+const DefaultMaxAccountLength = 64
+
+// ErrInvalidAccount is wrapped by every AccountPolicy rejection
+var ErrInvalidAccount = errors.New("invalid account")
+
+// AccountPolicy is the naming scheme for account IDs. Balances are keyed by
+// the exact string, so every ID entering the system goes through Normalize
+// first; otherwise "Alice", "alice" and " alice " would be three accounts.
+//
+// Normalization happens before validation: whitespace is trimmed when
+// TrimSpace is set, then the ID is lower-cased when FoldCase is set. The
+// result must be non-empty, at most MaxLength characters (0 = no limit) and
+// match Pattern when one is set.
+//
+// Accounts already in the event store are not rewritten: changing the policy
+// on a running wallet can make existing accounts unreachable under their old
+// spelling.
+type AccountPolicy struct {
+	TrimSpace bool
+	FoldCase  bool
+	MaxLength int
+	// Pattern restricts the allowed characters, e.g. ^[a-z0-9_.-]+$ (anchor
+	// it, or any ID containing a match passes); nil allows any
+	Pattern *regexp.Regexp
+}
+
+// DefaultAccountPolicy trims surrounding whitespace and caps the length,
+// keeping case and allowing any characters
+func DefaultAccountPolicy() AccountPolicy {
+	return AccountPolicy{TrimSpace: true, MaxLength: DefaultMaxAccountLength}
+}
+
+// Normalize returns the canonical form of an account ID, or an error
+// wrapping ErrInvalidAccount when the policy does not accept it
+func (p AccountPolicy) Normalize(account string) (string, error) {
+	if p.TrimSpace {
+		account = strings.TrimSpace(account)
+	}
+	if p.FoldCase {
+		account = strings.ToLower(account)
+	}
+
+	if account == "" {
+		return "", fmt.Errorf("%w: account is empty", ErrInvalidAccount)
+	}
+	if p.MaxLength > 0 && utf8.RuneCountInString(account) > p.MaxLength {
+		return "", fmt.Errorf("%w: account exceeds %d characters", ErrInvalidAccount, p.MaxLength)
+	}
+	if p.Pattern != nil && !p.Pattern.MatchString(account) {
+		return "", fmt.Errorf("%w: account %q does not match %s", ErrInvalidAccount, account, p.Pattern)
+	}
+	return account, nil
+}
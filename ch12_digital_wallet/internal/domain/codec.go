@@ -0,0 +1,239 @@
+package domain
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Codec encodes sequenced events for storage. Encode stamps the record
+// with the current time; Decode returns that timestamp alongside the event.
+type Codec interface {
+	// Name identifies the codec, e.g. in event store segment headers
+	Name() string
+	Encode(se SequencedEvent) ([]byte, error)
+	Decode(data []byte) (SequencedEvent, time.Time, error)
+}
+
+var (
+	// JSONCodec is the JSON envelope of SerializeSequencedEvent
+	JSONCodec Codec = jsonCodec{}
+	// BinaryCodec is a compact binary encoding, see binaryCodec
+	BinaryCodec Codec = binaryCodec{}
+)
+
+// CodecByName returns the codec called name ("json" or "binary")
+func CodecByName(name string) (Codec, error) {
+	for _, c := range []Codec{JSONCodec, BinaryCodec} {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown event codec %q", name)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Encode(se SequencedEvent) ([]byte, error) {
+	return SerializeSequencedEvent(se)
+}
+
+func (jsonCodec) Decode(data []byte) (SequencedEvent, time.Time, error) {
+	return deserializeEnvelope(data)
+}
+
+// binaryCodec writes the event type, sequence and timestamp followed by the
+// event's fields in declaration order: strings length-prefixed, integers as
+// varints, times in their MarshalBinary form. A record starts with its field
+// count, so records written before a field was appended to an event still
+// decode, with the new field zero; fields must therefore only ever be added
+// at the end of an event struct.
+type binaryCodec struct{}
+
+// eventTypes maps event type names to their structs for binaryCodec
+var eventTypes = func() map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+	for _, e := range []Event{
+		MoneyDeducted{}, MoneyCredited{}, TransactionFailed{},
+		MoneyDeposited{}, MoneyWithdrawn{},
+		MinimumBalanceSet{}, OverdraftLimitSet{},
+		AccountFrozen{}, AccountUnfrozen{},
+		TransferScheduled{}, ScheduledTransferCanceled{},
+		TransferPendingApproval{}, TransferRejected{},
+	} {
+		types[e.GetType()] = reflect.TypeOf(e)
+	}
+	return types
+}()
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var errTruncated = errors.New("truncated binary event")
+
+func (binaryCodec) Name() string { return "binary" }
+
+func (binaryCodec) Encode(se SequencedEvent) ([]byte, error) {
+	v := reflect.ValueOf(se.Event)
+	if _, ok := eventTypes[se.Event.GetType()]; !ok || v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unknown event type: %s", se.Event.GetType())
+	}
+
+	buf := make([]byte, 0, 128)
+	buf = appendString(buf, se.Event.GetType())
+	buf = binary.AppendUvarint(buf, se.Sequence)
+	buf = binary.AppendVarint(buf, time.Now().UTC().UnixNano())
+	buf = binary.AppendUvarint(buf, uint64(v.NumField()))
+	for i := 0; i < v.NumField(); i++ {
+		var err error
+		if buf, err = appendField(buf, v.Field(i)); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", v.Type().Name(), v.Type().Field(i).Name, err)
+		}
+	}
+	return buf, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendField(buf []byte, f reflect.Value) ([]byte, error) {
+	if f.Type() == timeType {
+		data, err := f.Interface().(time.Time).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return appendString(buf, string(data)), nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		return appendString(buf, f.String()), nil
+	case reflect.Int, reflect.Int64:
+		return binary.AppendVarint(buf, f.Int()), nil
+	case reflect.Uint64:
+		return binary.AppendUvarint(buf, f.Uint()), nil
+	case reflect.Bool:
+		if f.Bool() {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	default:
+		return nil, fmt.Errorf("unsupported field type %s", f.Type())
+	}
+}
+
+func (binaryCodec) Decode(data []byte) (SequencedEvent, time.Time, error) {
+	d := binaryDecoder{data: data}
+	typ := d.string()
+	seq := d.uvarint()
+	at := time.Unix(0, d.varint()).UTC()
+	fields := d.uvarint()
+	if d.err != nil {
+		return SequencedEvent{}, time.Time{}, d.err
+	}
+
+	t, ok := eventTypes[typ]
+	if !ok {
+		return SequencedEvent{}, time.Time{}, fmt.Errorf("unknown event type: %s", typ)
+	}
+	if fields > uint64(t.NumField()) {
+		return SequencedEvent{}, time.Time{}, fmt.Errorf("%s has %d fields, record has %d", typ, t.NumField(), fields)
+	}
+	v := reflect.New(t).Elem()
+	for i := 0; i < int(fields); i++ {
+		d.field(v.Field(i))
+	}
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%d trailing bytes after %s", len(d.data), typ)
+	}
+	if d.err != nil {
+		return SequencedEvent{}, time.Time{}, d.err
+	}
+
+	// As with JSON, events written without a currency get DefaultCurrency
+	if f := v.FieldByName("Currency"); f.IsValid() && f.Kind() == reflect.String {
+		f.SetString(CurrencyOrDefault(f.String()))
+	}
+	return SequencedEvent{Sequence: seq, Event: v.Interface().(Event)}, at, nil
+}
+
+// binaryDecoder reads binaryCodec fields, keeping the first error
+type binaryDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *binaryDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = errTruncated
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *binaryDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = errTruncated
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *binaryDecoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.data)) {
+		d.err = errTruncated
+		return ""
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
+
+func (d *binaryDecoder) field(f reflect.Value) {
+	if f.Type() == timeType {
+		var t time.Time
+		if data := d.string(); d.err == nil {
+			d.err = t.UnmarshalBinary([]byte(data))
+		}
+		f.Set(reflect.ValueOf(t))
+		return
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(d.string())
+	case reflect.Int, reflect.Int64:
+		f.SetInt(d.varint())
+	case reflect.Uint64:
+		f.SetUint(d.uvarint())
+	case reflect.Bool:
+		if d.err == nil && len(d.data) == 0 {
+			d.err = errTruncated
+		}
+		if d.err == nil {
+			f.SetBool(d.data[0] != 0)
+			d.data = d.data[1:]
+		}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unsupported field type %s", f.Type())
+		}
+	}
+}
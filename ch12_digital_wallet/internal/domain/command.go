@@ -1,9 +1,96 @@
 package domain
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// DefaultCurrency is the currency of commands and events that name none,
+// including every event written before balances had currencies
+const DefaultCurrency = "USD"
+
+// CurrencyOrDefault returns currency, or DefaultCurrency when it is empty
+func CurrencyOrDefault(currency string) string {
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
+
+// TransferMode selects how a transfer's amount is determined
+type TransferMode string
+
+const (
+	// TransferModeExact transfers Amount (the default)
+	TransferModeExact TransferMode = "exact"
+	// TransferModeAll transfers the source account's entire balance
+	TransferModeAll TransferMode = "all"
+	// TransferModePercent transfers Percent% of the source balance, rounded down to the cent
+	TransferModePercent TransferMode = "percent"
+)
+
 // TransferCommand represents a transfer request from the API
 type TransferCommand struct {
+	TransactionID string       `json:"transaction_id"`
+	FromAccount   string       `json:"from_account"`
+	ToAccount     string       `json:"to_account"`
+	Amount        int64        `json:"amount"`             // Amount in cents to avoid floating point issues
+	Currency      string       `json:"currency,omitempty"` // Both accounts' currency; empty means DefaultCurrency
+	Mode          TransferMode `json:"mode,omitempty"`     // Empty means exact
+	Percent       int64        `json:"percent,omitempty"`  // 1-100, percent mode only
+	Memo          string       `json:"memo,omitempty"`     // Free-text annotation, e.g. "invoice #123"
+	ExecuteAt     time.Time    `json:"execute_at"`         // Run at this time instead of now; zero or past means now
+}
+
+// UnmarshalJSON also accepts scheduled_at, ExecuteAt's name before, so
+// commands queued by older producers keep their due time
+func (c *TransferCommand) UnmarshalJSON(data []byte) error {
+	type plain TransferCommand
+	aux := struct {
+		*plain
+		ScheduledAt time.Time `json:"scheduled_at"`
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if c.ExecuteAt.IsZero() {
+		c.ExecuteAt = aux.ScheduledAt
+	}
+	return nil
+}
+
+// DepositCommand adds money to an account from outside the wallet
+type DepositCommand struct {
 	TransactionID string `json:"transaction_id"`
-	FromAccount   string `json:"from_account"`
-	ToAccount     string `json:"to_account"`
-	Amount        int64  `json:"amount"` // Amount in cents to avoid floating point issues
+	Account       string `json:"account"`
+	Amount        int64  `json:"amount"`             // Amount in cents
+	Currency      string `json:"currency,omitempty"` // Empty means DefaultCurrency
+	Memo          string `json:"memo,omitempty"`
+}
+
+// WithdrawCommand takes money out of the wallet from an account
+type WithdrawCommand struct {
+	TransactionID string `json:"transaction_id"`
+	Account       string `json:"account"`
+	Amount        int64  `json:"amount"`             // Amount in cents
+	Currency      string `json:"currency,omitempty"` // Empty means DefaultCurrency
+	Memo          string `json:"memo,omitempty"`
+}
+
+// FreezeCommand blocks money from leaving an account until it is unfrozen
+type FreezeCommand struct {
+	Account string `json:"account"`
+	Reason  string `json:"reason,omitempty"` // Why, for the audit trail, e.g. "fraud review"
+}
+
+// UnfreezeCommand lifts a freeze
+type UnfreezeCommand struct {
+	Account string `json:"account"`
+}
+
+// ReverseCommand undoes a completed transfer by moving its amount back from
+// the receiver to the sender
+type ReverseCommand struct {
+	TransactionID         string `json:"transaction_id"`          // The reversal's own ID
+	OriginalTransactionID string `json:"original_transaction_id"` // The transfer to reverse
 }
@@ -11,6 +11,20 @@ const (
 	EventTypeMoneyDeducted     = "MoneyDeducted"
 	EventTypeMoneyCredited     = "MoneyCredited"
 	EventTypeTransactionFailed = "TransactionFailed"
+	EventTypeMinimumBalanceSet = "MinimumBalanceSet"
+	EventTypeOverdraftLimitSet = "OverdraftLimitSet"
+
+	EventTypeTransferScheduled         = "TransferScheduled"
+	EventTypeScheduledTransferCanceled = "ScheduledTransferCanceled"
+
+	EventTypeTransferPendingApproval = "TransferPendingApproval"
+	EventTypeTransferRejected        = "TransferRejected"
+
+	EventTypeMoneyDeposited = "MoneyDeposited"
+	EventTypeMoneyWithdrawn = "MoneyWithdrawn"
+
+	EventTypeAccountFrozen   = "AccountFrozen"
+	EventTypeAccountUnfrozen = "AccountUnfrozen"
 )
 
 // Event is the base interface for all events
@@ -22,15 +36,27 @@ type Event interface {
 // EventEnvelope wraps an event with metadata for serialization
 type EventEnvelope struct {
 	Type      string          `json:"type"`
+	Sequence  uint64          `json:"seq,omitempty"`
 	Timestamp time.Time       `json:"timestamp"`
 	Data      json.RawMessage `json:"data"`
 }
 
+// SequencedEvent pairs an event with its global position in the event store.
+// Sequences start at 1 and increase by one per persisted event; 0 means unsequenced.
+type SequencedEvent struct {
+	Sequence uint64
+	Event    Event
+}
+
 // MoneyDeducted represents a successful deduction from an account
 type MoneyDeducted struct {
 	TransactionID string `json:"transaction_id"`
 	Account       string `json:"account"`
 	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Memo          string `json:"memo,omitempty"`
+	Reverses      string `json:"reverses,omitempty"` // Set on a reversal: the transfer it undoes
+	Fee           bool   `json:"fee,omitempty"`      // Set on the fee leg of a transfer (see engine.FeePolicy)
 }
 
 func (e MoneyDeducted) GetType() string          { return EventTypeMoneyDeducted }
@@ -41,6 +67,10 @@ type MoneyCredited struct {
 	TransactionID string `json:"transaction_id"`
 	Account       string `json:"account"`
 	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Memo          string `json:"memo,omitempty"`
+	Reverses      string `json:"reverses,omitempty"` // Set on a reversal: the transfer it undoes
+	Fee           bool   `json:"fee,omitempty"`      // Set on the fee leg of a transfer (see engine.FeePolicy)
 }
 
 func (e MoneyCredited) GetType() string          { return EventTypeMoneyCredited }
@@ -51,20 +81,172 @@ type TransactionFailed struct {
 	TransactionID string `json:"transaction_id"`
 	FromAccount   string `json:"from_account"`
 	Reason        string `json:"reason"`
+	Memo          string `json:"memo,omitempty"`
 }
 
 func (e TransactionFailed) GetType() string          { return EventTypeTransactionFailed }
 func (e TransactionFailed) GetTransactionID() string { return e.TransactionID }
 
+// MoneyDeposited represents money paid into an account from outside the wallet
+type MoneyDeposited struct {
+	TransactionID string `json:"transaction_id"`
+	Account       string `json:"account"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Memo          string `json:"memo,omitempty"`
+}
+
+func (e MoneyDeposited) GetType() string          { return EventTypeMoneyDeposited }
+func (e MoneyDeposited) GetTransactionID() string { return e.TransactionID }
+
+// MoneyWithdrawn represents money paid out of an account to outside the wallet
+type MoneyWithdrawn struct {
+	TransactionID string `json:"transaction_id"`
+	Account       string `json:"account"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Memo          string `json:"memo,omitempty"`
+}
+
+func (e MoneyWithdrawn) GetType() string          { return EventTypeMoneyWithdrawn }
+func (e MoneyWithdrawn) GetTransactionID() string { return e.TransactionID }
+
+// MinimumBalanceSet is a configuration event recording the balance an account
+// may not be drawn below. It belongs to no transaction.
+type MinimumBalanceSet struct {
+	Account string `json:"account"`
+	Floor   int64  `json:"floor"`
+}
+
+func (e MinimumBalanceSet) GetType() string          { return EventTypeMinimumBalanceSet }
+func (e MinimumBalanceSet) GetTransactionID() string { return "" }
+
+// OverdraftLimitSet is a configuration event recording how far an account may
+// go below zero. It belongs to no transaction.
+type OverdraftLimitSet struct {
+	Account string `json:"account"`
+	Limit   int64  `json:"limit"`
+}
+
+func (e OverdraftLimitSet) GetType() string          { return EventTypeOverdraftLimitSet }
+func (e OverdraftLimitSet) GetTransactionID() string { return "" }
+
+// AccountFrozen is a configuration event recording that money may not leave
+// an account until an AccountUnfrozen. It belongs to no transaction.
+type AccountFrozen struct {
+	Account string `json:"account"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+func (e AccountFrozen) GetType() string          { return EventTypeAccountFrozen }
+func (e AccountFrozen) GetTransactionID() string { return "" }
+
+// AccountUnfrozen is a configuration event lifting an account's freeze
+type AccountUnfrozen struct {
+	Account string `json:"account"`
+}
+
+func (e AccountUnfrozen) GetType() string          { return EventTypeAccountUnfrozen }
+func (e AccountUnfrozen) GetTransactionID() string { return "" }
+
+// TransferScheduled records a future-dated transfer accepted for execution
+// at DueAt. It carries the whole command so the transfer can be rebuilt on
+// replay; the usual MoneyDeducted/MoneyCredited or TransactionFailed events
+// follow when it runs.
+type TransferScheduled struct {
+	TransactionID string       `json:"transaction_id"`
+	FromAccount   string       `json:"from_account"`
+	ToAccount     string       `json:"to_account"`
+	Amount        int64        `json:"amount"`
+	Currency      string       `json:"currency,omitempty"`
+	Mode          TransferMode `json:"mode,omitempty"`
+	Percent       int64        `json:"percent,omitempty"`
+	Memo          string       `json:"memo,omitempty"`
+	DueAt         time.Time    `json:"due_at"`
+}
+
+func (e TransferScheduled) GetType() string          { return EventTypeTransferScheduled }
+func (e TransferScheduled) GetTransactionID() string { return e.TransactionID }
+
+// Command rebuilds the transfer command to execute when the transfer is due
+func (e TransferScheduled) Command() TransferCommand {
+	return TransferCommand{
+		TransactionID: e.TransactionID,
+		FromAccount:   e.FromAccount,
+		ToAccount:     e.ToAccount,
+		Amount:        e.Amount,
+		Currency:      e.Currency,
+		Mode:          e.Mode,
+		Percent:       e.Percent,
+		Memo:          e.Memo,
+		ExecuteAt:     e.DueAt,
+	}
+}
+
+// ScheduledTransferCanceled records that a pending scheduled transfer was
+// withdrawn before it ran
+type ScheduledTransferCanceled struct {
+	TransactionID string `json:"transaction_id"`
+}
+
+func (e ScheduledTransferCanceled) GetType() string          { return EventTypeScheduledTransferCanceled }
+func (e ScheduledTransferCanceled) GetTransactionID() string { return e.TransactionID }
+
+// TransferPendingApproval records a transfer above the approval threshold
+// that passed every check and now waits for a second approval. Amount is the
+// resolved amount, held from FromAccount until the transfer is approved or
+// rejected; approval emits the usual MoneyDeducted/MoneyCredited events.
+type TransferPendingApproval struct {
+	TransactionID string `json:"transaction_id"`
+	FromAccount   string `json:"from_account"`
+	ToAccount     string `json:"to_account"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency,omitempty"`
+	Memo          string `json:"memo,omitempty"`
+}
+
+func (e TransferPendingApproval) GetType() string          { return EventTypeTransferPendingApproval }
+func (e TransferPendingApproval) GetTransactionID() string { return e.TransactionID }
+
+// Command rebuilds the transfer command to execute once it is approved
+func (e TransferPendingApproval) Command() TransferCommand {
+	return TransferCommand{
+		TransactionID: e.TransactionID,
+		FromAccount:   e.FromAccount,
+		ToAccount:     e.ToAccount,
+		Amount:        e.Amount,
+		Currency:      e.Currency,
+		Memo:          e.Memo,
+	}
+}
+
+// TransferRejected records that a transfer pending approval was rejected
+// and its held funds released
+type TransferRejected struct {
+	TransactionID string `json:"transaction_id"`
+	FromAccount   string `json:"from_account"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+func (e TransferRejected) GetType() string          { return EventTypeTransferRejected }
+func (e TransferRejected) GetTransactionID() string { return e.TransactionID }
+
 // SerializeEvent converts an event to JSON bytes with envelope
 func SerializeEvent(event Event) ([]byte, error) {
-	data, err := json.Marshal(event)
+	return SerializeSequencedEvent(SequencedEvent{Event: event})
+}
+
+// SerializeSequencedEvent converts an event to JSON bytes with an envelope
+// carrying its global sequence number
+func SerializeSequencedEvent(se SequencedEvent) ([]byte, error) {
+	data, err := json.Marshal(se.Event)
 	if err != nil {
 		return nil, err
 	}
 
 	envelope := EventEnvelope{
-		Type:      event.GetType(),
+		Type:      se.Event.GetType(),
+		Sequence:  se.Sequence,
 		Timestamp: time.Now().UTC(),
 		Data:      data,
 	}
@@ -74,9 +256,27 @@ func SerializeEvent(event Event) ([]byte, error) {
 
 // DeserializeEvent converts JSON bytes back to an Event
 func DeserializeEvent(data []byte) (Event, error) {
+	se, err := DeserializeSequencedEvent(data)
+	if err != nil {
+		return nil, err
+	}
+	return se.Event, nil
+}
+
+// DeserializeSequencedEvent converts JSON bytes back to an Event and its
+// sequence number (0 if the envelope has none). Events written without a
+// currency get DefaultCurrency.
+func DeserializeSequencedEvent(data []byte) (SequencedEvent, error) {
+	se, _, err := deserializeEnvelope(data)
+	return se, err
+}
+
+// deserializeEnvelope is DeserializeSequencedEvent, also returning the
+// envelope's timestamp
+func deserializeEnvelope(data []byte) (SequencedEvent, time.Time, error) {
 	var envelope EventEnvelope
 	if err := json.Unmarshal(data, &envelope); err != nil {
-		return nil, err
+		return SequencedEvent{}, time.Time{}, err
 	}
 
 	var event Event
@@ -84,24 +284,90 @@ func DeserializeEvent(data []byte) (Event, error) {
 	case EventTypeMoneyDeducted:
 		var e MoneyDeducted
 		if err := json.Unmarshal(envelope.Data, &e); err != nil {
-			return nil, err
+			return SequencedEvent{}, time.Time{}, err
 		}
+		e.Currency = CurrencyOrDefault(e.Currency)
 		event = e
 	case EventTypeMoneyCredited:
 		var e MoneyCredited
 		if err := json.Unmarshal(envelope.Data, &e); err != nil {
-			return nil, err
+			return SequencedEvent{}, time.Time{}, err
 		}
+		e.Currency = CurrencyOrDefault(e.Currency)
 		event = e
 	case EventTypeTransactionFailed:
 		var e TransactionFailed
 		if err := json.Unmarshal(envelope.Data, &e); err != nil {
-			return nil, err
+			return SequencedEvent{}, time.Time{}, err
+		}
+		event = e
+	case EventTypeMoneyDeposited:
+		var e MoneyDeposited
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		e.Currency = CurrencyOrDefault(e.Currency)
+		event = e
+	case EventTypeMoneyWithdrawn:
+		var e MoneyWithdrawn
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		e.Currency = CurrencyOrDefault(e.Currency)
+		event = e
+	case EventTypeMinimumBalanceSet:
+		var e MinimumBalanceSet
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		event = e
+	case EventTypeOverdraftLimitSet:
+		var e OverdraftLimitSet
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		event = e
+	case EventTypeAccountFrozen:
+		var e AccountFrozen
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		event = e
+	case EventTypeAccountUnfrozen:
+		var e AccountUnfrozen
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		event = e
+	case EventTypeTransferScheduled:
+		var e TransferScheduled
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		e.Currency = CurrencyOrDefault(e.Currency)
+		event = e
+	case EventTypeScheduledTransferCanceled:
+		var e ScheduledTransferCanceled
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		event = e
+	case EventTypeTransferPendingApproval:
+		var e TransferPendingApproval
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
+		}
+		e.Currency = CurrencyOrDefault(e.Currency)
+		event = e
+	case EventTypeTransferRejected:
+		var e TransferRejected
+		if err := json.Unmarshal(envelope.Data, &e); err != nil {
+			return SequencedEvent{}, time.Time{}, err
 		}
 		event = e
 	default:
-		return nil, fmt.Errorf("unknown event type: %s", envelope.Type)
+		return SequencedEvent{}, time.Time{}, fmt.Errorf("unknown event type: %s", envelope.Type)
 	}
 
-	return event, nil
+	return SequencedEvent{Sequence: envelope.Sequence, Event: event}, envelope.Timestamp, nil
 }
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// ErrApprovalNotFound is returned when approving or rejecting a transfer
+// that is not pending approval: unknown, already approved or already rejected
+var ErrApprovalNotFound = errors.New("transfer pending approval not found")
+
+// SetApprovalThreshold makes transfers moving more than threshold wait for a
+// second approval: they emit TransferPendingApproval instead of moving money
+// and hold the amount until ApproveTransfer or RejectTransfer.
+// 0 (the default) disables approvals.
+func (e *WalletEngine) SetApprovalThreshold(threshold int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if threshold < 0 {
+		threshold = 0
+	}
+	e.approvalThreshold = threshold
+}
+
+// ApprovalThreshold returns the amount above which transfers need approval,
+// or 0 when approvals are disabled
+func (e *WalletEngine) ApprovalThreshold() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.approvalThreshold
+}
+
+// PendingApprovals returns the transfers waiting for approval, ordered by
+// transaction ID
+func (e *WalletEngine) PendingApprovals() []domain.TransferPendingApproval {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	pending := make([]domain.TransferPendingApproval, 0, len(e.pendingApprovals))
+	for _, p := range e.pendingApprovals {
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].TransactionID < pending[j].TransactionID
+	})
+	return pending
+}
+
+// HeldAmount returns how much of account's balance in currency is held by
+// transfers pending approval
+func (e *WalletEngine) HeldAmount(account, currency string) int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.heldLocked(account, currency, "")
+}
+
+// heldLocked sums the amounts held from account's currency wallet by pending
+// approvals other than except. Caller must hold e.mu.
+func (e *WalletEngine) heldLocked(account, currency, except string) int64 {
+	var held int64
+	for id, p := range e.pendingApprovals {
+		if p.FromAccount == account && domain.CurrencyOrDefault(p.Currency) == currency && id != except {
+			held += p.Amount + e.feeLocked(account, p.Amount)
+		}
+	}
+	return held
+}
+
+// awaitingApprovalLocked reports whether txnID is pending approval.
+// Caller must hold e.mu.
+func (e *WalletEngine) awaitingApprovalLocked(txnID string) bool {
+	_, ok := e.pendingApprovals[txnID]
+	return ok
+}
+
+// ApproveTransfer executes a transfer pending approval. Its hold is released
+// and it runs through the usual checks, so it emits MoneyDeducted and
+// MoneyCredited, or TransactionFailed if it can no longer go through (e.g.
+// a minimum balance was raised in the meantime).
+func (e *WalletEngine) ApproveTransfer(ctx context.Context, txnID string) ([]domain.Event, error) {
+	e.mu.RLock()
+	p, ok := e.pendingApprovals[txnID]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, ErrApprovalNotFound
+	}
+	// processCommand checks again under writeMu in case of a concurrent decision
+	return e.processCommand(ctx, p.Command(), true)
+}
+
+// RejectTransfer cancels a transfer pending approval and releases its hold.
+// The rejection is persisted as a TransferRejected event and the transaction
+// ID counts as used afterwards.
+func (e *WalletEngine) RejectTransfer(txnID, reason string) error {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	if e.IsStandby() {
+		return ErrStandby
+	}
+
+	e.mu.RLock()
+	p, pending := e.pendingApprovals[txnID]
+	e.mu.RUnlock()
+	if !pending {
+		return ErrApprovalNotFound
+	}
+
+	if !e.allowWrite() {
+		telemetry.DegradedRejectionsTotal.Inc()
+		return ErrDegraded
+	}
+
+	event := domain.TransferRejected{TransactionID: txnID, FromAccount: p.FromAccount, Reason: reason}
+	sequenced, err := e.eventStore.AppendSequenced([]domain.Event{event})
+	e.recordPersistResult(err)
+	if err != nil {
+		return fmt.Errorf("failed to persist events: %w", err)
+	}
+	telemetry.EventsStoredTotal.WithLabelValues(event.GetType()).Inc()
+
+	e.mu.Lock()
+	e.applyEvent(event)
+	if n := len(sequenced); n > 0 {
+		e.lastSeq = sequenced[n-1].Sequence
+	}
+	snap := e.snapshotDueLocked(1)
+	e.mu.Unlock()
+	e.writeSnapshot(snap)
+
+	e.notifyEventHandlers(sequenced)
+	e.publishEvents(sequenced)
+	return nil
+}
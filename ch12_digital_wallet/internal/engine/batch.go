@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"github.com/nats-io/nats.go"
+)
+
+// MaxBatchSize is the most transfers a single batch may carry
+const MaxBatchSize = 1000
+
+// ErrBatchTooLarge is returned for a batch of more than MaxBatchSize transfers
+var ErrBatchTooLarge = fmt.Errorf("batch exceeds %d transfers", MaxBatchSize)
+
+// BatchResponse answers a batch of transfer commands. Results holds one
+// CommandResponse per command, in order; a transfer that failed has Success
+// false and its reason in Error. When the batch could not be processed at
+// all, Success is false, Error and Code say why, and Results is empty.
+type BatchResponse struct {
+	Success bool              `json:"success"`
+	Error   string            `json:"error,omitempty"`
+	Code    string            `json:"code,omitempty"`
+	Results []CommandResponse `json:"results,omitempty"`
+}
+
+// isBatch reports whether a command message holds a batch: a JSON array of
+// transfer commands rather than a single one
+func isBatch(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '['
+}
+
+// serveBatch processes a batch command message and answers it with a
+// BatchResponse. redeliver is as for serveCommand.
+func (e *WalletEngine) serveBatch(ctx context.Context, msg *nats.Msg, redeliver bool) error {
+	var cmds []domain.TransferCommand
+	if err := json.Unmarshal(msg.Data, &cmds); err != nil {
+		log.Printf("Failed to unmarshal batch: %v", err)
+		e.deadLetter(msg, "invalid batch format: "+err.Error(), 1)
+		respond(msg, BatchResponse{Error: "invalid batch format"})
+		return nil
+	}
+
+	results, err := e.ProcessBatch(ctx, cmds)
+	if err != nil {
+		log.Printf("Failed to process batch: %v", err)
+		if redeliver {
+			return err
+		}
+		resp := BatchResponse{Error: err.Error()}
+		switch {
+		case errors.Is(err, ErrDegraded):
+			resp.Code = CodeDegraded
+		case errors.Is(err, ErrStandby):
+			resp.Code = CodeStandby
+		default:
+			e.deadLetter(msg, err.Error(), 1)
+		}
+		respond(msg, resp)
+		return nil
+	}
+
+	resp := BatchResponse{Success: true, Results: make([]CommandResponse, len(results))}
+	for i, events := range results {
+		if len(events) == 0 {
+			resp.Results[i] = e.duplicateResponse(cmds[i].TransactionID)
+			continue
+		}
+		resp.Results[i] = successResponse(events)
+		if failed, ok := events[0].(domain.TransactionFailed); ok {
+			resp.Results[i].Success = false
+			resp.Results[i].Error = failed.Reason
+		}
+	}
+	respond(msg, resp)
+	return nil
+}
+
+// ProcessBatch executes transfer commands in order as one write: each is
+// checked against the state the ones before it left, and the events of all
+// of them are persisted with a single append. It returns each command's
+// events; an empty slice is a duplicate, of an earlier transaction or of
+// one before it in the batch. A transfer that fails, e.g. for insufficient
+// funds, does not stop the rest. When the append fails, none of the batch
+// is applied and the error is returned.
+//
+// e.mu is held for the whole batch, the append included, so readers never
+// see balances the event log does not have yet.
+func (e *WalletEngine) ProcessBatch(ctx context.Context, cmds []domain.TransferCommand) ([][]domain.Event, error) {
+	if len(cmds) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	if e.IsStandby() {
+		return nil, ErrStandby
+	}
+	if !e.allowWrite() {
+		telemetry.DegradedRejectionsTotal.Inc()
+		return nil, ErrDegraded
+	}
+
+	e.mu.Lock()
+	undo := newBatchUndo()
+	results := make([][]domain.Event, len(cmds))
+	var all []domain.Event
+	for i, cmd := range cmds {
+		events, err := e.executeLocked(ctx, cmd, false)
+		if err != nil {
+			undo.revertLocked(e)
+			e.mu.Unlock()
+			return nil, err
+		}
+		// Applied now so the next command sees them, a repeated ID included
+		for _, event := range events {
+			undo.saveLocked(e, event)
+			e.applyEvent(event)
+		}
+		results[i] = events
+		all = append(all, events...)
+	}
+
+	sequenced, err := e.persist(ctx, all, e.now)
+	if err != nil {
+		undo.revertLocked(e)
+		e.mu.Unlock()
+		return nil, err
+	}
+	if n := len(sequenced); n > 0 {
+		e.lastSeq = sequenced[n-1].Sequence
+	}
+	snap := e.snapshotDueLocked(len(all))
+	e.mu.Unlock()
+	e.writeSnapshot(snap)
+
+	e.notifyEventHandlers(sequenced)
+	e.publishEvents(sequenced)
+
+	for i, events := range results {
+		e.recordTransferMetrics(events, transferredAmount(events, cmds[i].Amount))
+	}
+	e.updateBalanceMetrics()
+
+	return results, nil
+}
+
+// walletKey identifies one wallet, an account's balance in a currency
+type walletKey struct {
+	account  string
+	currency string
+}
+
+// walletBefore is a wallet's state before a batch touched it
+type walletBefore struct {
+	balance int64
+	existed bool
+}
+
+// batchUndo remembers the state a batch's events changed, so it can be put
+// back when the batch cannot be persisted. A transaction ID that produces
+// events was neither processed, scheduled nor awaiting approval before (it
+// would be a duplicate), so undoing its events only has to forget it.
+// Older IDs the window evicted to make room stay forgotten.
+type batchUndo struct {
+	wallets     map[walletKey]walletBefore
+	newAccounts map[string]bool
+	// Transaction IDs the batch used, with the outcome recorded for them before
+	txns map[string]*TransactionOutcome
+}
+
+func newBatchUndo() *batchUndo {
+	return &batchUndo{
+		wallets:     make(map[walletKey]walletBefore),
+		newAccounts: make(map[string]bool),
+		txns:        make(map[string]*TransactionOutcome),
+	}
+}
+
+// saveLocked records what applying event is about to change.
+// Caller must hold e.mu.
+func (u *batchUndo) saveLocked(e *WalletEngine, event domain.Event) {
+	switch ev := event.(type) {
+	case domain.MoneyDeducted:
+		u.saveWalletLocked(e, ev.Account, ev.Currency)
+	case domain.MoneyCredited:
+		u.saveWalletLocked(e, ev.Account, ev.Currency)
+	}
+
+	txnID := event.GetTransactionID()
+	if _, seen := u.txns[txnID]; seen {
+		return
+	}
+	if outcome, ok := e.outcomes.entries[txnID]; ok {
+		u.txns[txnID] = &outcome
+	} else {
+		u.txns[txnID] = nil
+	}
+}
+
+func (u *batchUndo) saveWalletLocked(e *WalletEngine, account, currency string) {
+	key := walletKey{account: account, currency: domain.CurrencyOrDefault(currency)}
+	if _, seen := u.wallets[key]; seen {
+		return
+	}
+	wallets, ok := e.balances[account]
+	if !ok {
+		u.newAccounts[account] = true
+	}
+	balance, existed := wallets[key.currency]
+	u.wallets[key] = walletBefore{balance: balance, existed: existed}
+}
+
+// revertLocked puts back the state saved since newBatchUndo.
+// Caller must hold e.mu.
+func (u *batchUndo) revertLocked(e *WalletEngine) {
+	for key, before := range u.wallets {
+		if before.existed {
+			e.balances[key.account][key.currency] = before.balance
+		} else {
+			delete(e.balances[key.account], key.currency)
+		}
+	}
+	for account := range u.newAccounts {
+		delete(e.balances, account)
+	}
+	for txnID, outcome := range u.txns {
+		e.processedTxns.remove(txnID)
+		e.unscheduleLocked(txnID)
+		delete(e.pendingApprovals, txnID)
+		if outcome != nil {
+			e.outcomes.record(txnID, *outcome)
+		} else {
+			e.outcomes.remove(txnID)
+		}
+	}
+}
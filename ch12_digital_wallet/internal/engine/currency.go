@@ -0,0 +1,69 @@
+package engine
+
+import "github.com/nathanyu/digital-wallet/internal/domain"
+
+// Balances are kept per account and currency, e.balances[account][currency]:
+// each pair is a separate wallet. A deposit can open a wallet in any
+// currency, and a transfer to an account without wallets opens one in the
+// transfer's currency. A transfer between accounts that already hold money
+// in other currencies but not in the transfer's fails with "currency
+// mismatch", since it would need a conversion. Minimum balances apply to
+// the DefaultCurrency wallet.
+
+// balanceLocked returns account's balance in currency. Caller must hold e.mu.
+func (e *WalletEngine) balanceLocked(account, currency string) int64 {
+	return e.balances[account][currency]
+}
+
+// addBalanceLocked adds delta to account's balance in currency, opening the
+// wallet if needed. Caller must hold e.mu.
+func (e *WalletEngine) addBalanceLocked(account, currency string, delta int64) {
+	wallets, ok := e.balances[account]
+	if !ok {
+		wallets = make(map[string]int64)
+		e.balances[account] = wallets
+	}
+	wallets[domain.CurrencyOrDefault(currency)] += delta
+}
+
+// currencyMismatchLocked reports whether account holds wallets but none in
+// currency. Caller must hold e.mu.
+func (e *WalletEngine) currencyMismatchLocked(account, currency string) bool {
+	wallets := e.balances[account]
+	if len(wallets) == 0 {
+		return false
+	}
+	_, ok := wallets[currency]
+	return !ok
+}
+
+// balancesInLocked returns a copy of the balances of every account with a
+// wallet in currency. Caller must hold e.mu.
+func (e *WalletEngine) balancesInLocked(currency string) map[string]int64 {
+	result := make(map[string]int64, len(e.balances))
+	for account, wallets := range e.balances {
+		if balance, ok := wallets[currency]; ok {
+			result[account] = balance
+		}
+	}
+	return result
+}
+
+// GetCurrencyBalances returns a copy of the balances held in currency, by account
+func (e *WalletEngine) GetCurrencyBalances(currency string) map[string]int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.balancesInLocked(currency)
+}
+
+// GetAccountBalances returns a copy of account's balances, by currency
+func (e *WalletEngine) GetAccountBalances(account string) map[string]int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	result := make(map[string]int64, len(e.balances[account]))
+	for currency, balance := range e.balances[account] {
+		result[currency] = balance
+	}
+	return result
+}
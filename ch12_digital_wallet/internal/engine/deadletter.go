@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// DeadLetterSubject receives commands the engine gave up on, with the
+	// reason in DeadLetterReasonHeader
+	DeadLetterSubject = "wallet.commands.dlq"
+	// DeadLetterReasonHeader says why a command was dead-lettered
+	DeadLetterReasonHeader = "Wallet-Dead-Letter-Reason"
+	// DeadLetterSubjectHeader is the subject the command was sent on
+	DeadLetterSubjectHeader = "Wallet-Original-Subject"
+	// DeadLetterDeliveriesHeader is how often the command was attempted
+	DeadLetterDeliveriesHeader = "Wallet-Deliveries"
+
+	// DefaultMaxDeliveries is how often a JetStream command is attempted
+	// before it is dead-lettered, until SetMaxDeliveries changes it
+	DefaultMaxDeliveries = 5
+)
+
+// A command is dead-lettered when it cannot be decoded, and when its events
+// cannot be persisted: at once over core NATS, where the caller gets the
+// error and nothing retries, and after the maximum number of deliveries with
+// JetStream. Degraded and standby rejections over core NATS are answered but
+// not dead-lettered; callers are expected to retry those. Either way the
+// engine moves on to the next command.
+
+// SetMaxDeliveries sets how often a JetStream command is attempted before it
+// is dead-lettered; values below 1 mean 1
+func (e *WalletEngine) SetMaxDeliveries(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if n < 1 {
+		n = 1
+	}
+	e.maxDeliveries = n
+}
+
+// MaxDeliveries returns how often a JetStream command is attempted before it
+// is dead-lettered
+func (e *WalletEngine) MaxDeliveries() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.maxDeliveries
+}
+
+// deadLetter republishes msg's command on DeadLetterSubject with reason
+func (e *WalletEngine) deadLetter(msg *nats.Msg, reason string, deliveries uint64) {
+	telemetry.DeadLettersTotal.Inc()
+	if e.natsConn == nil {
+		log.Printf("Dead-lettering command (no NATS connection, dropped): %s", reason)
+		return
+	}
+
+	dlq := nats.NewMsg(DeadLetterSubject)
+	dlq.Data = msg.Data
+	dlq.Header.Set(DeadLetterReasonHeader, reason)
+	dlq.Header.Set(DeadLetterSubjectHeader, msg.Subject)
+	dlq.Header.Set(DeadLetterDeliveriesHeader, strconv.FormatUint(deliveries, 10))
+	if err := e.natsConn.PublishMsg(dlq); err != nil {
+		log.Printf("Failed to dead-letter command (%s): %v", reason, err)
+		return
+	}
+	log.Printf("Dead-lettered command on %s: %s", msg.Subject, reason)
+}
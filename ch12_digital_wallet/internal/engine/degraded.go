@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+const (
+	// CodeDegraded marks a command rejected because the engine is read-only
+	CodeDegraded = "DEGRADED"
+
+	defaultDegradedThreshold     = 3
+	defaultDegradedProbeInterval = 5 * time.Second
+)
+
+// ErrDegraded is returned for commands rejected while the event store is failing
+var ErrDegraded = errors.New("wallet engine degraded: event store unavailable, transfers are temporarily rejected")
+
+// persistHealth tracks event store write failures.
+//
+// The engine enters degraded (read-only) mode immediately on a disk-full error,
+// or after `threshold` consecutive write failures of any other kind. While
+// degraded, commands are rejected without touching the event store, except for
+// one probe command every `probeInterval`; a successful probe write recovers
+// the engine automatically.
+type persistHealth struct {
+	mu                  sync.Mutex
+	threshold           int
+	probeInterval       time.Duration
+	consecutiveFailures int
+	degraded            bool
+	lastAttempt         time.Time
+}
+
+// SetDegradedPolicy configures how many consecutive write failures put the
+// engine into degraded mode and how often a probe write is attempted while degraded
+func (e *WalletEngine) SetDegradedPolicy(threshold int, probeInterval time.Duration) {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+	if threshold < 1 {
+		threshold = 1
+	}
+	e.health.threshold = threshold
+	e.health.probeInterval = probeInterval
+}
+
+// IsDegraded reports whether the engine is rejecting writes
+func (e *WalletEngine) IsDegraded() bool {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+	return e.health.degraded
+}
+
+// allowWrite reports whether a command may attempt a write right now
+func (e *WalletEngine) allowWrite() bool {
+	now := e.clock()
+
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+
+	if !e.health.degraded {
+		return true
+	}
+	if now.Sub(e.health.lastAttempt) < e.health.probeInterval {
+		return false
+	}
+	e.health.lastAttempt = now
+	return true
+}
+
+// recordPersistResult updates the write health after an AppendBatch call
+func (e *WalletEngine) recordPersistResult(err error) {
+	e.recordPersistResultAt(err, e.clock())
+}
+
+// recordPersistResultAt is recordPersistResult for a write that finished at
+// now, for callers holding e.mu, which clock takes
+func (e *WalletEngine) recordPersistResultAt(err error, now time.Time) {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+
+	if err == nil {
+		e.health.consecutiveFailures = 0
+		if e.health.degraded {
+			e.health.degraded = false
+			telemetry.EngineDegraded.Set(0)
+			log.Printf("Event store writes recovered, leaving degraded mode")
+		}
+		return
+	}
+
+	telemetry.EventStoreWriteFailuresTotal.Inc()
+	e.health.consecutiveFailures++
+	e.health.lastAttempt = now
+
+	if !e.health.degraded && (isDiskFull(err) || e.health.consecutiveFailures >= e.health.threshold) {
+		e.health.degraded = true
+		telemetry.EngineDegraded.Set(1)
+		log.Printf("Event store write failing (%d consecutive): %v; entering degraded mode", e.health.consecutiveFailures, err)
+	}
+}
+
+// clock returns the current time from the engine's time source
+func (e *WalletEngine) clock() time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.now()
+}
+
+// isDiskFull reports whether err is caused by the disk running out of space
+func isDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"unicode/utf8"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// Deposit pays money into an account from outside the wallet. It persists
+// and applies the resulting MoneyDeposited, or TransactionFailed when the
+// command is invalid, like ProcessCommand does for transfers.
+func (e *WalletEngine) Deposit(ctx context.Context, cmd domain.DepositCommand) ([]domain.Event, error) {
+	return e.processCash(ctx, func() ([]domain.Event, error) { return e.ExecuteDeposit(cmd), nil })
+}
+
+// Withdraw pays money out of an account. Like a transfer it fails for
+// insufficient funds, counting funds held for approvals as unavailable and
+// the overdraft limit as available, when it would draw the account below
+// its minimum balance, and from a frozen account.
+func (e *WalletEngine) Withdraw(ctx context.Context, cmd domain.WithdrawCommand) ([]domain.Event, error) {
+	return e.processCash(ctx, func() ([]domain.Event, error) { return e.ExecuteWithdraw(cmd), nil })
+}
+
+// processCash runs a deposit, withdrawal or reversal through the write path
+func (e *WalletEngine) processCash(ctx context.Context, execute func() ([]domain.Event, error)) ([]domain.Event, error) {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	if e.IsStandby() {
+		return nil, ErrStandby
+	}
+	if !e.allowWrite() {
+		telemetry.DegradedRejectionsTotal.Inc()
+		return nil, ErrDegraded
+	}
+
+	events, err := execute()
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return events, nil
+	}
+	if err := e.commit(ctx, events); err != nil {
+		return nil, err
+	}
+	e.updateBalanceMetrics()
+	return events, nil
+}
+
+// ExecuteDeposit generates the events of a deposit without modifying state
+func (e *WalletEngine) ExecuteDeposit(cmd domain.DepositCommand) []domain.Event {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.usedTxnIDLocked(cmd.TransactionID) {
+		return []domain.Event{}
+	}
+
+	account, reason := e.checkCashLocked(cmd.Account, cmd.Amount, cmd.Memo)
+	if reason == "" {
+		if _, exists := e.balances[account]; !exists && e.maxAccounts > 0 && len(e.balances) >= e.maxAccounts {
+			reason = "account limit reached"
+		}
+	}
+	if reason != "" {
+		return []domain.Event{domain.TransactionFailed{
+			TransactionID: cmd.TransactionID, FromAccount: cmd.Account, Reason: reason, Memo: cmd.Memo,
+		}}
+	}
+
+	return []domain.Event{domain.MoneyDeposited{
+		TransactionID: cmd.TransactionID, Account: account, Amount: cmd.Amount,
+		Currency: domain.CurrencyOrDefault(cmd.Currency), Memo: cmd.Memo,
+	}}
+}
+
+// ExecuteWithdraw generates the events of a withdrawal without modifying state
+func (e *WalletEngine) ExecuteWithdraw(cmd domain.WithdrawCommand) []domain.Event {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.usedTxnIDLocked(cmd.TransactionID) {
+		return []domain.Event{}
+	}
+
+	currency := domain.CurrencyOrDefault(cmd.Currency)
+	account, reason := e.checkCashLocked(cmd.Account, cmd.Amount, cmd.Memo)
+	if reason == "" {
+		available := e.balanceLocked(account, currency) - e.heldLocked(account, currency, "")
+		if e.frozen[account] {
+			reason = "account frozen"
+		} else if available-cmd.Amount < -e.overdraftLocked(account, currency) {
+			reason = "insufficient funds"
+		} else if floor := e.minBalances[account]; floor > 0 && currency == domain.DefaultCurrency && available-cmd.Amount < floor {
+			reason = "below minimum balance"
+		}
+	}
+	if reason != "" {
+		return []domain.Event{domain.TransactionFailed{
+			TransactionID: cmd.TransactionID, FromAccount: cmd.Account, Reason: reason, Memo: cmd.Memo,
+		}}
+	}
+
+	return []domain.Event{domain.MoneyWithdrawn{
+		TransactionID: cmd.TransactionID, Account: account, Amount: cmd.Amount, Currency: currency, Memo: cmd.Memo,
+	}}
+}
+
+// usedTxnIDLocked reports whether txnID was processed or belongs to a
+// transfer still scheduled or awaiting approval. Caller must hold e.mu.
+func (e *WalletEngine) usedTxnIDLocked(txnID string) bool {
+	_, scheduled := e.scheduled[txnID]
+	if e.processedTxns.contains(txnID) || scheduled || e.awaitingApprovalLocked(txnID) {
+		log.Printf("Transaction %s already processed, skipping", txnID)
+		telemetry.DuplicateTransactionsTotal.Inc()
+		return true
+	}
+	return false
+}
+
+// checkCashLocked validates the parts deposits and withdrawals share and
+// returns the normalized account, or a failure reason. Caller must hold e.mu.
+func (e *WalletEngine) checkCashLocked(account string, amount int64, memo string) (string, string) {
+	if amount <= 0 {
+		return "", "amount must be positive"
+	}
+	if e.maxMemoLength > 0 && utf8.RuneCountInString(memo) > e.maxMemoLength {
+		return "", "memo too long"
+	}
+	account, err := e.accountPolicy.Normalize(account)
+	if err != nil {
+		return "", err.Error()
+	}
+	return account, ""
+}
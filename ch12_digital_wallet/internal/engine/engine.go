@@ -3,14 +3,15 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nathanyu/digital-wallet/internal/domain"
-	"github.com/nathanyu/digital-wallet/internal/eventstore"
 	"github.com/nathanyu/digital-wallet/internal/telemetry"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -22,42 +23,202 @@ const (
 	EventSubject   = "wallet.events"
 )
 
+// DefaultMaxMemoLength is the memo length cap until SetMaxMemoLength changes it
+const DefaultMaxMemoLength = 256
+
+// EventLog is the append-only persistence the engine writes events to and
+// replays them from. *eventstore.EventStore is the production implementation.
+type EventLog interface {
+	AppendSequenced(events []domain.Event) ([]domain.SequencedEvent, error)
+	LoadAll() ([]domain.Event, error)
+}
+
 // WalletEngine is the deterministic state machine for processing wallet commands
 type WalletEngine struct {
 	// Current state: account -> balance (in cents)
-	balances map[string]int64
-	// Track processed transactions for idempotency
-	processedTxns map[string]bool
+	balances map[string]map[string]int64 // account -> currency -> balance (see currency.go)
+	// Recently processed transactions, for idempotency (see idempotency.go)
+	processedTxns txnWindow
+	// Recent transaction results returned to duplicates (see outcomes.go)
+	outcomes outcomeCache
 
-	eventStore    *eventstore.EventStore
+	eventStore    EventLog
 	natsConn      *nats.Conn
 	subscription  *nats.Subscription
 	eventHandlers []EventHandler
 
+	// Warm standby (see standby.go): applies tailed events, rejects commands
+	standby bool
+	tailSub *nats.Subscription
+	lastSeq uint64 // sequence of the last applied event
+
+	// Event store write health (see degraded.go)
+	health persistHealth
+	now    func() time.Time
+
+	// Largest amount a single transfer may move (0 = no maximum)
+	maxTransferAmount int64
+	// Longest memo a transfer may carry, in characters (0 = no limit)
+	maxMemoLength int
+	// Most distinct accounts a transfer may bring into existence (0 = no limit)
+	maxAccounts int
+	// Normalization and validation of transfer account IDs
+	accountPolicy domain.AccountPolicy
+	// Per-account balance floors (see minimum_balance.go); absent means 0
+	minBalances map[string]int64
+	// Per-account overdraft limits (see overdraft.go); absent means 0
+	overdraftLimits map[string]int64
+	// Fee charged on transfers (see fee.go)
+	fees FeePolicy
+	// Accounts money may not leave (see freeze.go)
+	frozen map[string]bool
+
+	// Future-dated transfers waiting to run, and the timer wheel that finds
+	// the due ones (see scheduled.go)
+	scheduled        map[string]domain.TransferScheduled
+	wheel            *timerWheel
+	maxScheduled     int
+	schedulerStarted bool
+
+	// Transfers above the approval threshold waiting for a second approval
+	// (see approval.go); their amounts are held from the source account
+	pendingApprovals  map[string]domain.TransferPendingApproval
+	approvalThreshold int64
+
+	// Automatic state snapshots (see snapshot.go)
+	snapshots snapshotPolicy
+	// Progress of InitializeFromEventStore (see replay.go)
+	replay replayProgress
+
+	// Priority command lane (see priority.go); 0 means disabled
+	laneBuffer  int
+	prioritySub *nats.Subscription
+
+	// Consume commands from a JetStream stream (see jetstream.go)
+	jetStream bool
+	// Attempts of a JetStream command before it is dead-lettered (see deadletter.go)
+	maxDeliveries int
+
 	mu       sync.RWMutex
+	writeMu  sync.Mutex // serializes ProcessCommand: check, persist and apply happen as one step
 	wg       sync.WaitGroup
 	ctx      context.Context
 	cancel   context.CancelFunc
 	stopOnce sync.Once
 }
 
-// EventHandler is a function that handles events (for CQRS)
-type EventHandler func(event domain.Event)
+// EventHandler is a function that handles events (for CQRS).
+// Events carry their event store sequence so subscribers can detect gaps.
+type EventHandler func(event domain.SequencedEvent)
 
 // NewWalletEngine creates a new wallet engine
-func NewWalletEngine(eventStore *eventstore.EventStore, natsConn *nats.Conn) *WalletEngine {
+func NewWalletEngine(eventStore EventLog, natsConn *nats.Conn) *WalletEngine {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &WalletEngine{
-		balances:      make(map[string]int64),
-		processedTxns: make(map[string]bool),
-		eventStore:    eventStore,
-		natsConn:      natsConn,
-		eventHandlers: make([]EventHandler, 0),
-		ctx:           ctx,
-		cancel:        cancel,
+		balances:         make(map[string]map[string]int64),
+		processedTxns:    newTxnWindow(DefaultIdempotencyWindow),
+		outcomes:         newOutcomeCache(DefaultOutcomeCacheSize),
+		minBalances:      make(map[string]int64),
+		overdraftLimits:  make(map[string]int64),
+		frozen:           make(map[string]bool),
+		scheduled:        make(map[string]domain.TransferScheduled),
+		wheel:            newTimerWheel(scheduleTickInterval, scheduleWheelSlots),
+		maxScheduled:     DefaultMaxScheduledTransfers,
+		pendingApprovals: make(map[string]domain.TransferPendingApproval),
+		maxMemoLength:    DefaultMaxMemoLength,
+		maxDeliveries:    DefaultMaxDeliveries,
+		accountPolicy:    domain.DefaultAccountPolicy(),
+		eventStore:       eventStore,
+		natsConn:         natsConn,
+		eventHandlers:    make([]EventHandler, 0),
+		health: persistHealth{
+			threshold:     defaultDegradedThreshold,
+			probeInterval: defaultDegradedProbeInterval,
+		},
+		now:    time.Now,
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
+// SetClock overrides the engine's time source (for testing)
+func (e *WalletEngine) SetClock(now func() time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now = now
+}
+
+// SetMaxTransferAmount caps the amount a single transfer may move;
+// 0 (the default) means no maximum
+func (e *WalletEngine) SetMaxTransferAmount(max int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if max < 0 {
+		max = 0
+	}
+	e.maxTransferAmount = max
+}
+
+// MaxTransferAmount returns the single-transfer cap, or 0 when there is none
+func (e *WalletEngine) MaxTransferAmount() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.maxTransferAmount
+}
+
+// SetMaxMemoLength caps the length of a transfer memo in characters;
+// 0 means no limit
+func (e *WalletEngine) SetMaxMemoLength(max int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if max < 0 {
+		max = 0
+	}
+	e.maxMemoLength = max
+}
+
+// MaxMemoLength returns the memo length cap, or 0 when there is none
+func (e *WalletEngine) MaxMemoLength() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.maxMemoLength
+}
+
+// SetMaxAccounts caps the number of distinct accounts: once reached, a
+// transfer to an account the engine has never seen fails, while existing
+// accounts keep transacting. 0 (the default) means no limit.
+func (e *WalletEngine) SetMaxAccounts(max int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if max < 0 {
+		max = 0
+	}
+	e.maxAccounts = max
+}
+
+// MaxAccounts returns the account cap, or 0 when there is none
+func (e *WalletEngine) MaxAccounts() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.maxAccounts
+}
+
+// SetAccountPolicy sets how transfer account IDs are normalized and which
+// are accepted; commands naming an invalid account fail
+func (e *WalletEngine) SetAccountPolicy(policy domain.AccountPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.accountPolicy = policy
+}
+
+// AccountPolicy returns the account naming policy, for normalizing account
+// IDs the same way at the API boundary
+func (e *WalletEngine) AccountPolicy() domain.AccountPolicy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.accountPolicy
+}
+
 // RegisterEventHandler registers a handler to receive events
 func (e *WalletEngine) RegisterEventHandler(handler EventHandler) {
 	e.mu.Lock()
@@ -65,26 +226,88 @@ func (e *WalletEngine) RegisterEventHandler(handler EventHandler) {
 	e.eventHandlers = append(e.eventHandlers, handler)
 }
 
-// InitializeFromEventStore replays all events from the event store to rebuild state
+// InitializeFromEventStore replays all events from the event store to rebuild state.
+// ReplayProgress follows it while it runs.
 func (e *WalletEngine) InitializeFromEventStore() error {
+	e.replay.start()
+	applied := 0
+	defer func() { e.replay.finish(applied) }()
+
+	// Stores that expose sequences let the engine remember where replay ended
+	if source, ok := e.eventStore.(sequencedLog); ok {
+		// Only events after the latest snapshot need replaying
+		snap, err := e.loadSnapshot()
+		if err != nil {
+			return fmt.Errorf("failed to load snapshot: %w", err)
+		}
+		var fromSeq uint64
+		if snap != nil {
+			fromSeq = snap.Sequence
+		}
+		events, err := source.LoadSince(fromSeq)
+		if err != nil {
+			return fmt.Errorf("failed to load events: %w", err)
+		}
+		e.replay.loaded(len(events))
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		if snap != nil {
+			e.installSnapshotLocked(snap)
+		}
+		for _, event := range events {
+			e.applyEvent(event.Event)
+			e.lastSeq = event.Sequence
+			applied++
+			e.replay.advance(applied)
+		}
+
+		log.Printf("Wallet engine initialized with %d events, %d accounts", len(events), len(e.balances))
+		return nil
+	}
+
 	events, err := e.eventStore.LoadAll()
 	if err != nil {
 		return fmt.Errorf("failed to load events: %w", err)
 	}
+	e.replay.loaded(len(events))
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	for _, event := range events {
 		e.applyEvent(event)
+		applied++
+		e.replay.advance(applied)
 	}
 
 	log.Printf("Wallet engine initialized with %d events, %d accounts", len(events), len(e.balances))
 	return nil
 }
 
-// Start begins processing commands from NATS
+// Start begins processing commands from NATS.
+// A standby engine tails published events instead until it is promoted.
 func (e *WalletEngine) Start() error {
+	if e.IsStandby() {
+		return e.startTailing()
+	}
+	if err := e.subscribeCommands(); err != nil {
+		return err
+	}
+	e.startScheduler()
+	return nil
+}
+
+// subscribeCommands starts consuming CommandSubject
+func (e *WalletEngine) subscribeCommands() error {
+	if e.JetStreamEnabled() {
+		return e.subscribeStream()
+	}
+	if e.PriorityLaneEnabled() {
+		return e.subscribeLanes()
+	}
+
 	sub, err := e.natsConn.Subscribe(CommandSubject, e.handleCommand)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to commands: %w", err)
@@ -104,6 +327,12 @@ func (e *WalletEngine) Stop() error {
 		if e.subscription != nil {
 			err = e.subscription.Unsubscribe()
 		}
+		if e.prioritySub != nil {
+			e.prioritySub.Unsubscribe()
+		}
+		if e.tailSub != nil {
+			e.tailSub.Unsubscribe()
+		}
 
 		e.wg.Wait()
 	})
@@ -112,6 +341,13 @@ func (e *WalletEngine) Stop() error {
 
 // handleCommand processes a single command from NATS
 func (e *WalletEngine) handleCommand(msg *nats.Msg) {
+	e.serveCommand(msg, false)
+}
+
+// serveCommand processes a command message and answers it. With redeliver
+// set, a command that could not be processed (the write path failed) is not
+// answered; its error is returned so the message can be delivered again.
+func (e *WalletEngine) serveCommand(msg *nats.Msg, redeliver bool) error {
 	e.wg.Add(1)
 	defer e.wg.Done()
 
@@ -125,20 +361,26 @@ func (e *WalletEngine) handleCommand(msg *nats.Msg) {
 			trace.WithSpanKind(trace.SpanKindConsumer),
 			trace.WithAttributes(
 				attribute.String("messaging.system", "nats"),
-				attribute.String("messaging.destination", CommandSubject),
+				attribute.String("messaging.destination", msg.Subject),
 			),
 		)
 		defer span.End()
 	}
 
 	// Record NATS message received
-	telemetry.NATSMessagesReceived.WithLabelValues(CommandSubject).Inc()
+	telemetry.NATSMessagesReceived.WithLabelValues(msg.Subject).Inc()
+
+	// A JSON array is a batch of transfers (see batch.go)
+	if isBatch(msg.Data) {
+		return e.serveBatch(ctx, msg, redeliver)
+	}
 
 	var cmd domain.TransferCommand
 	if err := json.Unmarshal(msg.Data, &cmd); err != nil {
 		log.Printf("Failed to unmarshal command: %v", err)
+		e.deadLetter(msg, "invalid command format: "+err.Error(), 1)
 		e.respondError(msg, "invalid command format")
-		return
+		return nil
 	}
 
 	// Add command attributes to span
@@ -152,33 +394,95 @@ func (e *WalletEngine) handleCommand(msg *nats.Msg) {
 	}
 
 	// Process the command
-	events, err := e.ExecuteWithContext(ctx, cmd)
+	events, err := e.ProcessCommand(ctx, cmd)
 	if err != nil {
-		log.Printf("Failed to execute command: %v", err)
+		log.Printf("Failed to process command: %v", err)
 		if span := trace.SpanFromContext(ctx); span.IsRecording() {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, err.Error())
 		}
+		if redeliver {
+			return err
+		}
+		if errors.Is(err, ErrDegraded) {
+			e.respondErrorCode(msg, CodeDegraded, err.Error())
+			return nil
+		}
+		if errors.Is(err, ErrStandby) {
+			e.respondErrorCode(msg, CodeStandby, err.Error())
+			return nil
+		}
+		e.deadLetter(msg, err.Error(), 1)
 		e.respondError(msg, err.Error())
-		return
+		return nil
 	}
 
-	// Persist events
-	persistStart := time.Now()
-	if err := e.eventStore.AppendBatch(events); err != nil {
-		log.Printf("Failed to persist events: %v", err)
-		if span := trace.SpanFromContext(ctx); span.IsRecording() {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, "failed to persist events")
-		}
-		e.respondError(msg, "failed to persist events")
-		return
+	// Record transfer metrics
+	telemetry.TransferProcessingDuration.Observe(time.Since(start).Seconds())
+
+	// A duplicate is answered with the original result when it is remembered
+	if len(events) == 0 {
+		e.respondDuplicate(msg, cmd.TransactionID)
+		return nil
 	}
-	telemetry.EventStoreWriteDuration.Observe(time.Since(persistStart).Seconds())
 
-	// Record event metrics
-	for _, event := range events {
-		telemetry.EventsStoredTotal.WithLabelValues(event.GetType()).Inc()
+	// Respond with success
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.SetStatus(codes.Ok, "")
+		span.SetAttributes(attribute.Int("events_count", len(events)))
+	}
+	e.respondSuccess(msg, events)
+	return nil
+}
+
+// ProcessCommand executes a command, persists the resulting events, applies
+// them to the engine state and fans them out to event handlers and NATS.
+// It is the write path behind handleCommand.
+func (e *WalletEngine) ProcessCommand(ctx context.Context, cmd domain.TransferCommand) ([]domain.Event, error) {
+	return e.processCommand(ctx, cmd, false)
+}
+
+// processCommand is ProcessCommand; approving runs cmd as the approval of
+// the pending transfer with its ID (see ApproveTransfer)
+func (e *WalletEngine) processCommand(ctx context.Context, cmd domain.TransferCommand, approving bool) ([]domain.Event, error) {
+	// Without this a duplicate arriving while the original is being persisted
+	// would pass the idempotency check before the original's events are applied
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	if e.IsStandby() {
+		return nil, ErrStandby
+	}
+
+	// Reject writes while the event store is failing (a probe is let through periodically)
+	if !e.allowWrite() {
+		telemetry.DegradedRejectionsTotal.Inc()
+		return nil, ErrDegraded
+	}
+
+	events, err := e.execute(ctx, cmd, approving)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.commit(ctx, events); err != nil {
+		return nil, err
+	}
+
+	e.recordTransferMetrics(events, transferredAmount(events, cmd.Amount))
+
+	// Update balance metrics
+	e.updateBalanceMetrics()
+
+	return events, nil
+}
+
+// commit persists events produced by a command, applies them to the engine
+// state and fans them out to event handlers and NATS. Caller must hold
+// e.writeMu.
+func (e *WalletEngine) commit(ctx context.Context, events []domain.Event) error {
+	sequenced, err := e.persist(ctx, events, e.clock)
+	if err != nil {
+		return err
 	}
 
 	// Apply events to update state
@@ -186,27 +490,42 @@ func (e *WalletEngine) handleCommand(msg *nats.Msg) {
 	for _, event := range events {
 		e.applyEvent(event)
 	}
+	if n := len(sequenced); n > 0 {
+		e.lastSeq = sequenced[n-1].Sequence
+	}
+	snap := e.snapshotDueLocked(len(events))
 	e.mu.Unlock()
+	e.writeSnapshot(snap)
 
 	// Notify event handlers (for CQRS)
-	e.notifyEventHandlers(events)
+	e.notifyEventHandlers(sequenced)
 
 	// Publish events to NATS for other subscribers
-	e.publishEvents(events)
-
-	// Record transfer metrics
-	telemetry.TransferProcessingDuration.Observe(time.Since(start).Seconds())
-	e.recordTransferMetrics(events, cmd.Amount)
+	e.publishEvents(sequenced)
+	return nil
+}
 
-	// Update balance metrics
-	e.updateBalanceMetrics()
+// persist appends events to the event store, tracking its write health with
+// the time from now: e.clock, or e.now when the caller holds e.mu
+func (e *WalletEngine) persist(ctx context.Context, events []domain.Event, now func() time.Time) ([]domain.SequencedEvent, error) {
+	persistStart := time.Now()
+	sequenced, err := e.eventStore.AppendSequenced(events)
+	e.recordPersistResultAt(err, now())
+	if err != nil {
+		log.Printf("Failed to persist events: %v", err)
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to persist events")
+		}
+		return nil, fmt.Errorf("failed to persist events: %w", err)
+	}
+	telemetry.EventStoreWriteDuration.Observe(time.Since(persistStart).Seconds())
 
-	// Respond with success
-	if span := trace.SpanFromContext(ctx); span.IsRecording() {
-		span.SetStatus(codes.Ok, "")
-		span.SetAttributes(attribute.Int("events_count", len(events)))
+	// Record event metrics
+	for _, event := range events {
+		telemetry.EventsStoredTotal.WithLabelValues(event.GetType()).Inc()
 	}
-	e.respondSuccess(msg, events)
+	return sequenced, nil
 }
 
 // Execute processes a command and generates events without modifying state
@@ -216,6 +535,12 @@ func (e *WalletEngine) Execute(cmd domain.TransferCommand) ([]domain.Event, erro
 
 // ExecuteWithContext processes a command with tracing context
 func (e *WalletEngine) ExecuteWithContext(ctx context.Context, cmd domain.TransferCommand) ([]domain.Event, error) {
+	return e.execute(ctx, cmd, false)
+}
+
+// execute is ExecuteWithContext; approving executes the pending approval
+// with cmd's ID, releasing its hold and skipping the approval threshold
+func (e *WalletEngine) execute(ctx context.Context, cmd domain.TransferCommand, approving bool) ([]domain.Event, error) {
 	// Start tracing span
 	if telemetry.Tracer != nil {
 		var span trace.Span
@@ -230,9 +555,20 @@ func (e *WalletEngine) ExecuteWithContext(ctx context.Context, cmd domain.Transf
 
 	e.mu.RLock()
 	defer e.mu.RUnlock()
+	return e.executeLocked(ctx, cmd, approving)
+}
 
-	// Check for idempotency
-	if e.processedTxns[cmd.TransactionID] {
+// executeLocked is execute without the span. Caller must hold e.mu.
+func (e *WalletEngine) executeLocked(ctx context.Context, cmd domain.TransferCommand, approving bool) ([]domain.Event, error) {
+	if approving {
+		if _, ok := e.pendingApprovals[cmd.TransactionID]; !ok {
+			return nil, ErrApprovalNotFound
+		}
+	}
+
+	// Check for idempotency; a pending scheduled transfer only runs from the
+	// scheduler and a transfer awaiting approval only through ApproveTransfer
+	if e.processedTxns.contains(cmd.TransactionID) || e.isPendingDuplicateLocked(cmd) || (!approving && e.awaitingApprovalLocked(cmd.TransactionID)) {
 		log.Printf("Transaction %s already processed, skipping", cmd.TransactionID)
 		telemetry.DuplicateTransactionsTotal.Inc()
 		if span := trace.SpanFromContext(ctx); span.IsRecording() {
@@ -241,13 +577,105 @@ func (e *WalletEngine) ExecuteWithContext(ctx context.Context, cmd domain.Transf
 		return []domain.Event{}, nil
 	}
 
-	// Validate command
-	if cmd.Amount <= 0 {
+	if e.maxMemoLength > 0 && utf8.RuneCountInString(cmd.Memo) > e.maxMemoLength {
+		return []domain.Event{
+			domain.TransactionFailed{
+				TransactionID: cmd.TransactionID,
+				FromAccount:   cmd.FromAccount,
+				Reason:        "memo too long",
+			},
+		}, nil
+	}
+
+	// Commands may come straight from NATS, so the naming policy is applied
+	// here as well as in the API
+	from, err := e.accountPolicy.Normalize(cmd.FromAccount)
+	if err == nil {
+		cmd.ToAccount, err = e.accountPolicy.Normalize(cmd.ToAccount)
+	}
+	if err != nil {
 		return []domain.Event{
 			domain.TransactionFailed{
 				TransactionID: cmd.TransactionID,
 				FromAccount:   cmd.FromAccount,
-				Reason:        "amount must be positive",
+				Reason:        err.Error(),
+				Memo:          cmd.Memo,
+			},
+		}, nil
+	}
+	cmd.FromAccount = from
+	cmd.Currency = domain.CurrencyOrDefault(cmd.Currency)
+
+	// Checked before scheduling too, and again when a scheduled transfer runs
+	if e.frozen[cmd.FromAccount] {
+		return []domain.Event{
+			domain.TransactionFailed{
+				TransactionID: cmd.TransactionID,
+				FromAccount:   cmd.FromAccount,
+				Reason:        "account frozen",
+				Memo:          cmd.Memo,
+			},
+		}, nil
+	}
+
+	// Future-dated transfers are only recorded now; balance checks happen when they run
+	if cmd.ExecuteAt.After(e.now()) {
+		return []domain.Event{e.scheduleLocked(cmd)}, nil
+	}
+
+	// Money only moves between wallets of the same currency
+	if e.currencyMismatchLocked(cmd.FromAccount, cmd.Currency) || e.currencyMismatchLocked(cmd.ToAccount, cmd.Currency) {
+		return []domain.Event{
+			domain.TransactionFailed{
+				TransactionID: cmd.TransactionID,
+				FromAccount:   cmd.FromAccount,
+				Reason:        "currency mismatch",
+				Memo:          cmd.Memo,
+			},
+		}, nil
+	}
+
+	// Resolve the amount against the current balance. ProcessCommand holds
+	// writeMu, so the balance cannot change before these events are applied.
+	// Funds held for other transfers awaiting approval are not available.
+	fromBalance := e.balanceLocked(cmd.FromAccount, cmd.Currency) - e.heldLocked(cmd.FromAccount, cmd.Currency, cmd.TransactionID)
+	amount, reason := resolveTransferAmount(cmd, fromBalance)
+	if reason != "" {
+		return []domain.Event{
+			domain.TransactionFailed{
+				TransactionID: cmd.TransactionID,
+				FromAccount:   cmd.FromAccount,
+				Reason:        reason,
+				Memo:          cmd.Memo,
+			},
+		}, nil
+	}
+
+	// The fee comes on top of the amount; an "all" transfer leaves room for it
+	fee := e.feeLocked(cmd.FromAccount, amount)
+	if fee > 0 && cmd.Mode == domain.TransferModeAll {
+		amount = e.fees.maxAmount(amount)
+		if amount <= 0 {
+			return []domain.Event{
+				domain.TransactionFailed{
+					TransactionID: cmd.TransactionID,
+					FromAccount:   cmd.FromAccount,
+					Reason:        "insufficient funds",
+					Memo:          cmd.Memo,
+				},
+			}, nil
+		}
+		fee = e.feeLocked(cmd.FromAccount, amount)
+	}
+
+	// Checked on the resolved amount, so "all" and "percent" transfers are capped too
+	if e.maxTransferAmount > 0 && amount > e.maxTransferAmount {
+		return []domain.Event{
+			domain.TransactionFailed{
+				TransactionID: cmd.TransactionID,
+				FromAccount:   cmd.FromAccount,
+				Reason:        "amount exceeds maximum",
+				Memo:          cmd.Memo,
 			},
 		}, nil
 	}
@@ -258,13 +686,26 @@ func (e *WalletEngine) ExecuteWithContext(ctx context.Context, cmd domain.Transf
 				TransactionID: cmd.TransactionID,
 				FromAccount:   cmd.FromAccount,
 				Reason:        "cannot transfer to same account",
+				Memo:          cmd.Memo,
+			},
+		}, nil
+	}
+
+	// Only the credit can create an account: a debit from an unknown account
+	// fails for insufficient funds below
+	if _, exists := e.balances[cmd.ToAccount]; !exists && e.maxAccounts > 0 && len(e.balances) >= e.maxAccounts {
+		return []domain.Event{
+			domain.TransactionFailed{
+				TransactionID: cmd.TransactionID,
+				FromAccount:   cmd.FromAccount,
+				Reason:        "account limit reached",
+				Memo:          cmd.Memo,
 			},
 		}, nil
 	}
 
-	// Check balance
-	fromBalance := e.balances[cmd.FromAccount]
-	if fromBalance < cmd.Amount {
+	// Check balance; an overdraft limit lets the account go that far below zero
+	if fromBalance-amount-fee < -e.overdraftLocked(cmd.FromAccount, cmd.Currency) {
 		if span := trace.SpanFromContext(ctx); span.IsRecording() {
 			span.SetAttributes(
 				attribute.String("failure_reason", "insufficient_funds"),
@@ -276,6 +717,36 @@ func (e *WalletEngine) ExecuteWithContext(ctx context.Context, cmd domain.Transf
 				TransactionID: cmd.TransactionID,
 				FromAccount:   cmd.FromAccount,
 				Reason:        "insufficient funds",
+				Memo:          cmd.Memo,
+			},
+		}, nil
+	}
+
+	// The floor is checked after funds: a transfer the balance cannot cover
+	// at all is still reported as insufficient funds
+	if floor := e.minBalances[cmd.FromAccount]; floor > 0 && cmd.Currency == domain.DefaultCurrency && fromBalance-amount-fee < floor {
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.SetAttributes(attribute.String("failure_reason", "below_minimum_balance"))
+		}
+		return []domain.Event{
+			domain.TransactionFailed{
+				TransactionID: cmd.TransactionID,
+				FromAccount:   cmd.FromAccount,
+				Reason:        "below minimum balance",
+				Memo:          cmd.Memo,
+			},
+		}, nil
+	}
+
+	if !approving && e.approvalThreshold > 0 && amount > e.approvalThreshold {
+		return []domain.Event{
+			domain.TransferPendingApproval{
+				TransactionID: cmd.TransactionID,
+				FromAccount:   cmd.FromAccount,
+				ToAccount:     cmd.ToAccount,
+				Amount:        amount,
+				Currency:      cmd.Currency,
+				Memo:          cmd.Memo,
 			},
 		}, nil
 	}
@@ -285,14 +756,21 @@ func (e *WalletEngine) ExecuteWithContext(ctx context.Context, cmd domain.Transf
 		domain.MoneyDeducted{
 			TransactionID: cmd.TransactionID,
 			Account:       cmd.FromAccount,
-			Amount:        cmd.Amount,
+			Amount:        amount,
+			Currency:      cmd.Currency,
+			Memo:          cmd.Memo,
 		},
 		domain.MoneyCredited{
 			TransactionID: cmd.TransactionID,
 			Account:       cmd.ToAccount,
-			Amount:        cmd.Amount,
+			Amount:        amount,
+			Currency:      cmd.Currency,
+			Memo:          cmd.Memo,
 		},
 	}
+	if fee > 0 {
+		events = append(events, feeEvents(cmd, e.fees.Account, fee)...)
+	}
 
 	if span := trace.SpanFromContext(ctx); span.IsRecording() {
 		span.SetAttributes(attribute.Bool("success", true))
@@ -301,11 +779,54 @@ func (e *WalletEngine) ExecuteWithContext(ctx context.Context, cmd domain.Transf
 	return events, nil
 }
 
+// resolveTransferAmount works out how much a command moves given the source
+// balance. It returns a failure reason instead when the command is invalid.
+func resolveTransferAmount(cmd domain.TransferCommand, balance int64) (int64, string) {
+	switch cmd.Mode {
+	case "", domain.TransferModeExact:
+		if cmd.Amount <= 0 {
+			return 0, "amount must be positive"
+		}
+		return cmd.Amount, ""
+	case domain.TransferModeAll:
+		if balance <= 0 {
+			return 0, "insufficient funds"
+		}
+		return balance, ""
+	case domain.TransferModePercent:
+		if cmd.Percent < 1 || cmd.Percent > 100 {
+			return 0, "percent must be between 1 and 100"
+		}
+		// Round down so the transfer never exceeds the requested share
+		amount := balance/100*cmd.Percent + balance%100*cmd.Percent/100
+		if amount <= 0 {
+			return 0, "insufficient funds"
+		}
+		return amount, ""
+	default:
+		return 0, fmt.Sprintf("unknown transfer mode %q", cmd.Mode)
+	}
+}
+
+// transferredAmount returns the amount actually deducted, or fallback when the transfer failed
+func transferredAmount(events []domain.Event, fallback int64) int64 {
+	for _, event := range events {
+		if ev, ok := event.(domain.MoneyDeducted); ok {
+			return ev.Amount
+		}
+	}
+	return fallback
+}
+
 // recordTransferMetrics records metrics for a transfer
 func (e *WalletEngine) recordTransferMetrics(events []domain.Event, amount int64) {
 	for _, event := range events {
 		switch event.(type) {
 		case domain.MoneyDeducted:
+			// The fee leg belongs to the same transfer
+			if event.(domain.MoneyDeducted).Fee {
+				continue
+			}
 			telemetry.TransfersTotal.WithLabelValues("success").Inc()
 			telemetry.TransferAmount.WithLabelValues("success").Observe(float64(amount))
 		case domain.TransactionFailed:
@@ -325,26 +846,74 @@ func (e *WalletEngine) updateBalanceMetrics() {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
+	// Gauges have no currency label; they track the default currency.
+	// Overdrawn accounts report their negative balance, so the total stays
+	// the net amount that transfers conserve.
 	var total int64
-	for account, balance := range e.balances {
+	var overdrawn int
+	for account, balance := range e.balancesInLocked(domain.DefaultCurrency) {
 		telemetry.AccountBalanceGauge.WithLabelValues(account).Set(float64(balance))
 		total += balance
+		if balance < 0 {
+			overdrawn++
+		}
 	}
 	telemetry.TotalBalanceGauge.Set(float64(total))
+	telemetry.OverdrawnAccounts.Set(float64(overdrawn))
 	telemetry.AccountCount.Set(float64(len(e.balances)))
 }
 
 // applyEvent updates the internal state based on an event
 // This method is NOT thread-safe; caller must hold the lock
 func (e *WalletEngine) applyEvent(event domain.Event) {
+	e.recordOutcomeLocked(event)
 	switch ev := event.(type) {
 	case domain.MoneyDeducted:
-		e.balances[ev.Account] -= ev.Amount
-		e.processedTxns[ev.TransactionID] = true
+		e.addBalanceLocked(ev.Account, ev.Currency, -ev.Amount)
+		e.processedTxns.add(ev.TransactionID)
+		e.unscheduleLocked(ev.TransactionID)
+		delete(e.pendingApprovals, ev.TransactionID)
 	case domain.MoneyCredited:
-		e.balances[ev.Account] += ev.Amount
+		e.addBalanceLocked(ev.Account, ev.Currency, ev.Amount)
+	case domain.MoneyDeposited:
+		e.addBalanceLocked(ev.Account, ev.Currency, ev.Amount)
+		e.processedTxns.add(ev.TransactionID)
+	case domain.MoneyWithdrawn:
+		e.addBalanceLocked(ev.Account, ev.Currency, -ev.Amount)
+		e.processedTxns.add(ev.TransactionID)
 	case domain.TransactionFailed:
-		e.processedTxns[ev.TransactionID] = true
+		e.processedTxns.add(ev.TransactionID)
+		e.unscheduleLocked(ev.TransactionID)
+		delete(e.pendingApprovals, ev.TransactionID)
+	case domain.TransferScheduled:
+		e.scheduled[ev.TransactionID] = ev
+		e.wheel.add(ev.TransactionID, ev.DueAt)
+	case domain.ScheduledTransferCanceled:
+		e.processedTxns.add(ev.TransactionID)
+		e.unscheduleLocked(ev.TransactionID)
+	case domain.TransferPendingApproval:
+		// A due scheduled transfer can end up here; it no longer waits for its due time
+		e.unscheduleLocked(ev.TransactionID)
+		e.pendingApprovals[ev.TransactionID] = ev
+	case domain.TransferRejected:
+		e.processedTxns.add(ev.TransactionID)
+		delete(e.pendingApprovals, ev.TransactionID)
+	case domain.MinimumBalanceSet:
+		if ev.Floor == 0 {
+			delete(e.minBalances, ev.Account)
+		} else {
+			e.minBalances[ev.Account] = ev.Floor
+		}
+	case domain.OverdraftLimitSet:
+		if ev.Limit == 0 {
+			delete(e.overdraftLimits, ev.Account)
+		} else {
+			e.overdraftLimits[ev.Account] = ev.Limit
+		}
+	case domain.AccountFrozen:
+		e.frozen[ev.Account] = true
+	case domain.AccountUnfrozen:
+		delete(e.frozen, ev.Account)
 	}
 }
 
@@ -358,7 +927,7 @@ func (e *WalletEngine) ApplyEvents(events []domain.Event) {
 }
 
 // notifyEventHandlers sends events to all registered handlers
-func (e *WalletEngine) notifyEventHandlers(events []domain.Event) {
+func (e *WalletEngine) notifyEventHandlers(events []domain.SequencedEvent) {
 	e.mu.RLock()
 	handlers := make([]EventHandler, len(e.eventHandlers))
 	copy(handlers, e.eventHandlers)
@@ -372,9 +941,12 @@ func (e *WalletEngine) notifyEventHandlers(events []domain.Event) {
 }
 
 // publishEvents publishes events to NATS for other subscribers
-func (e *WalletEngine) publishEvents(events []domain.Event) {
+func (e *WalletEngine) publishEvents(events []domain.SequencedEvent) {
+	if e.natsConn == nil {
+		return
+	}
 	for _, event := range events {
-		data, err := domain.SerializeEvent(event)
+		data, err := domain.SerializeSequencedEvent(event)
 		if err != nil {
 			log.Printf("Failed to serialize event for publishing: %v", err)
 			continue
@@ -388,74 +960,103 @@ func (e *WalletEngine) publishEvents(events []domain.Event) {
 
 // CommandResponse represents the response to a command
 type CommandResponse struct {
-	Success bool     `json:"success"`
-	Error   string   `json:"error,omitempty"`
-	Events  []string `json:"events,omitempty"`
+	Success   bool     `json:"success"`
+	Error     string   `json:"error,omitempty"`
+	Code      string   `json:"code,omitempty"`
+	Events    []string `json:"events,omitempty"`
+	Amount    int64    `json:"amount,omitempty"`    // Amount moved, resolved for all/percent transfers
+	Fee       int64    `json:"fee,omitempty"`       // Fee charged on top of Amount
+	Duplicate bool     `json:"duplicate,omitempty"` // The transaction was already processed; the rest describes the original result
 }
 
 func (e *WalletEngine) respondSuccess(msg *nats.Msg, events []domain.Event) {
+	respond(msg, successResponse(events))
+}
+
+// respondDuplicate answers a duplicate command with the original outcome of
+// txnID, or an empty success when it is no longer remembered
+func (e *WalletEngine) respondDuplicate(msg *nats.Msg, txnID string) {
+	respond(msg, e.duplicateResponse(txnID))
+}
+
+// successResponse describes a command that produced events
+func successResponse(events []domain.Event) CommandResponse {
 	eventTypes := make([]string, len(events))
 	for i, ev := range events {
 		eventTypes[i] = ev.GetType()
 	}
 
-	resp := CommandResponse{
+	return CommandResponse{
 		Success: true,
 		Events:  eventTypes,
+		Amount:  transferredAmount(events, 0),
+		Fee:     chargedFee(events),
 	}
+}
 
-	data, _ := json.Marshal(resp)
-	if msg.Reply != "" {
-		msg.Respond(data)
+// duplicateResponse describes a duplicate of txnID
+func (e *WalletEngine) duplicateResponse(txnID string) CommandResponse {
+	resp := CommandResponse{Success: true, Duplicate: true}
+	if outcome, ok := e.Outcome(txnID); ok {
+		resp.Success = outcome.Success()
+		resp.Error = outcome.Reason
+		resp.Events = make([]string, len(outcome.Events))
+		for i, ev := range outcome.Events {
+			resp.Events[i] = ev.GetType()
+		}
+		resp.Amount = transferredAmount(outcome.Events, 0)
+		resp.Fee = chargedFee(outcome.Events)
 	}
+	return resp
 }
 
 func (e *WalletEngine) respondError(msg *nats.Msg, errMsg string) {
-	resp := CommandResponse{
+	e.respondErrorCode(msg, "", errMsg)
+}
+
+func (e *WalletEngine) respondErrorCode(msg *nats.Msg, code, errMsg string) {
+	respond(msg, CommandResponse{
 		Success: false,
 		Error:   errMsg,
-	}
+		Code:    code,
+	})
+}
 
+// respond sends resp to the reply subject of msg, if it has one
+func respond(msg *nats.Msg, resp any) {
 	data, _ := json.Marshal(resp)
 	if msg.Reply != "" {
 		msg.Respond(data)
 	}
 }
 
-// GetBalance returns the current balance for an account (for testing)
-func (e *WalletEngine) GetBalance(account string) int64 {
+// GetBalance returns the current balance of an account in currency (for testing)
+func (e *WalletEngine) GetBalance(account, currency string) int64 {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
-	return e.balances[account]
+	return e.balanceLocked(account, currency)
 }
 
-// SetBalance sets the balance for an account (for testing/initialization)
+// SetBalance sets the default currency balance for an account (for testing/initialization)
 func (e *WalletEngine) SetBalance(account string, balance int64) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	e.balances[account] = balance
+	e.addBalanceLocked(account, domain.DefaultCurrency, balance-e.balanceLocked(account, domain.DefaultCurrency))
 }
 
-// GetAllBalances returns a copy of all balances (for testing)
+// GetAllBalances returns a copy of all default currency balances (for testing)
 func (e *WalletEngine) GetAllBalances() map[string]int64 {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-
-	result := make(map[string]int64, len(e.balances))
-	for k, v := range e.balances {
-		result[k] = v
-	}
-	return result
+	return e.GetCurrencyBalances(domain.DefaultCurrency)
 }
 
-// GetTotalBalance returns the sum of all account balances
+// GetTotalBalance returns the sum of all default currency balances
 func (e *WalletEngine) GetTotalBalance() int64 {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
 	var total int64
-	for _, balance := range e.balances {
-		total += balance
+	for _, wallets := range e.balances {
+		total += wallets[domain.DefaultCurrency]
 	}
 	return total
 }
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// DefaultFeeAccount is where fees are credited unless FeePolicy.Account says otherwise
+const DefaultFeeAccount = "fees"
+
+// FeePolicy charges a fee on every successful transfer: Flat cents plus
+// BasisPoints hundredths of a percent of the amount, rounded down. The fee
+// is taken from the sender on top of the amount, so the funds checks cover
+// both, and credited to Account. It is recorded as a second MoneyDeducted
+// and MoneyCredited pair with the transfer's ID and Fee set, so every debit
+// still has a credit of the same amount. The zero policy charges nothing.
+type FeePolicy struct {
+	Account     string
+	Flat        int64
+	BasisPoints int64
+}
+
+// Fee returns the fee the policy charges on a transfer of amount
+func (p FeePolicy) Fee(amount int64) int64 {
+	if p.Flat == 0 && p.BasisPoints == 0 {
+		return 0
+	}
+	return p.Flat + amount/10000*p.BasisPoints + amount%10000*p.BasisPoints/10000
+}
+
+// maxAmount returns the largest amount that can be sent from total funds
+// with its fee on top, or 0 when not even the fee is covered
+func (p FeePolicy) maxAmount(total int64) int64 {
+	lo, hi := int64(0), total
+	for lo < hi {
+		mid := hi - (hi-lo)/2
+		if mid+p.Fee(mid) <= total {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+// SetFeePolicy sets the fee charged on transfers; an empty Account means
+// DefaultFeeAccount. The fee account pays no fee on its own transfers.
+// Deposits, withdrawals and reversals are free, and a reversal does not
+// refund the original's fee.
+func (e *WalletEngine) SetFeePolicy(policy FeePolicy) error {
+	if policy.Flat < 0 || policy.BasisPoints < 0 {
+		return fmt.Errorf("fees cannot be negative")
+	}
+	if policy.BasisPoints > 10000 {
+		return fmt.Errorf("percentage fee cannot exceed 10000 basis points")
+	}
+	if policy.Account == "" {
+		policy.Account = DefaultFeeAccount
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	account, err := e.accountPolicy.Normalize(policy.Account)
+	if err != nil {
+		return fmt.Errorf("invalid fee account: %w", err)
+	}
+	policy.Account = account
+	e.fees = policy
+	return nil
+}
+
+// FeePolicy returns the fee charged on transfers
+func (e *WalletEngine) FeePolicy() FeePolicy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.fees
+}
+
+// feeLocked returns the fee from pays on a transfer of amount.
+// Caller must hold e.mu.
+func (e *WalletEngine) feeLocked(from string, amount int64) int64 {
+	if from == e.fees.Account {
+		return 0
+	}
+	return e.fees.Fee(amount)
+}
+
+// chargedFee returns the fee a transfer's events charged, or 0
+func chargedFee(events []domain.Event) int64 {
+	for _, event := range events {
+		if ev, ok := event.(domain.MoneyDeducted); ok && ev.Fee {
+			return ev.Amount
+		}
+	}
+	return 0
+}
+
+// feeEvents returns the fee leg of a transfer
+func feeEvents(cmd domain.TransferCommand, account string, fee int64) []domain.Event {
+	return []domain.Event{
+		domain.MoneyDeducted{
+			TransactionID: cmd.TransactionID,
+			Account:       cmd.FromAccount,
+			Amount:        fee,
+			Currency:      cmd.Currency,
+			Fee:           true,
+		},
+		domain.MoneyCredited{
+			TransactionID: cmd.TransactionID,
+			Account:       account,
+			Amount:        fee,
+			Currency:      cmd.Currency,
+			Fee:           true,
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// DefaultIdempotencyWindow is how many processed transaction IDs the engine
+// remembers for deduplication until SetIdempotencyWindow changes it
+const DefaultIdempotencyWindow = 1000000
+
+// txnWindow remembers the most recently processed transaction IDs, forgetting
+// the oldest first once it holds size of them. A duplicate inside the window
+// produces no events; one older than the window is processed again.
+// Guarded by e.mu.
+type txnWindow struct {
+	size  int // 0 means no limit
+	ids   map[string]struct{}
+	order []string // processing order, oldest first
+}
+
+func newTxnWindow(size int) txnWindow {
+	return txnWindow{size: size, ids: make(map[string]struct{})}
+}
+
+// contains reports whether txnID is in the window
+func (w *txnWindow) contains(txnID string) bool {
+	_, ok := w.ids[txnID]
+	return ok
+}
+
+// add records txnID as processed, evicting the oldest IDs beyond the size
+func (w *txnWindow) add(txnID string) {
+	if _, ok := w.ids[txnID]; ok {
+		return
+	}
+	w.ids[txnID] = struct{}{}
+	w.order = append(w.order, txnID)
+	w.trim()
+	telemetry.IdempotencyCacheSize.Set(float64(len(w.ids)))
+}
+
+// remove forgets txnID, undoing a recent add (see batchUndo)
+func (w *txnWindow) remove(txnID string) {
+	if _, ok := w.ids[txnID]; !ok {
+		return
+	}
+	delete(w.ids, txnID)
+	for i := len(w.order) - 1; i >= 0; i-- {
+		if w.order[i] == txnID {
+			w.order = append(w.order[:i], w.order[i+1:]...)
+			break
+		}
+	}
+	telemetry.IdempotencyCacheSize.Set(float64(len(w.ids)))
+}
+
+// trim evicts the oldest IDs until the window fits its size
+func (w *txnWindow) trim() {
+	if w.size == 0 {
+		return
+	}
+	for len(w.order) > w.size {
+		delete(w.ids, w.order[0])
+		w.order[0] = ""
+		w.order = w.order[1:]
+		telemetry.IdempotencyEvictionsTotal.Inc()
+	}
+}
+
+// set returns a copy of the window for a snapshot
+func (w *txnWindow) set() map[string]bool {
+	result := make(map[string]bool, len(w.ids))
+	for id := range w.ids {
+		result[id] = true
+	}
+	return result
+}
+
+// restore replaces the window with the IDs of a snapshot. A snapshot keeps no
+// order, so they are added sorted, which for the time-ordered IDs the API
+// generates is oldest first.
+func (w *txnWindow) restore(processed map[string]bool) {
+	ids := make([]string, 0, len(processed))
+	for id, ok := range processed {
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	*w = newTxnWindow(w.size)
+	for _, id := range ids {
+		w.add(id)
+	}
+}
+
+// SetIdempotencyWindow sets how many processed transaction IDs are remembered
+// to reject duplicates; 0 remembers all of them. Shrinking the window
+// forgets the oldest IDs at once.
+func (e *WalletEngine) SetIdempotencyWindow(size int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	e.processedTxns.size = size
+	e.processedTxns.trim()
+	telemetry.IdempotencyCacheSize.Set(float64(len(e.processedTxns.ids)))
+}
+
+// ProcessedTransactions returns how many transaction IDs are currently
+// remembered for deduplication
+func (e *WalletEngine) ProcessedTransactions() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.processedTxns.ids)
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// StreamCommandSubject carries commands in JetStream mode. It is separate
+	// from CommandSubject so the stream never captures core NATS requests.
+	StreamCommandSubject = "wallet.jetstream.commands"
+	// CommandStream is the JetStream stream that persists commands
+	CommandStream = "WALLET_COMMANDS"
+	// CommandConsumer is the engine's durable consumer on CommandStream
+	CommandConsumer = "wallet-engine"
+	// ReplyHeader carries the inbox a JetStream command is answered on: the
+	// reply subject of a JetStream message is taken by its ack
+	ReplyHeader = "Wallet-Reply-To"
+
+	// streamRedeliveryDelay is how long a command that could not be
+	// processed waits before JetStream delivers it again
+	streamRedeliveryDelay = time.Second
+)
+
+// EnsureCommandStream creates CommandStream unless it exists. Commands are
+// kept on disk until the engine acknowledges them.
+func EnsureCommandStream(js nats.JetStreamContext) error {
+	_, err := js.StreamInfo(CommandStream)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return fmt.Errorf("failed to look up command stream: %w", err)
+	}
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:      CommandStream,
+		Subjects:  []string{StreamCommandSubject},
+		Storage:   nats.FileStorage,
+		Retention: nats.WorkQueuePolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create command stream: %w", err)
+	}
+	return nil
+}
+
+// EnableJetStream makes the engine consume commands from CommandStream
+// instead of core NATS: a command published while the engine is down waits
+// in the stream, and is acknowledged only once its events are persisted, so
+// a restart resumes from the first unacknowledged command. Duplicates from
+// redelivery are dropped by transaction ID as usual. The priority lane is
+// not available in this mode. Must be called before Start.
+func (e *WalletEngine) EnableJetStream() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.jetStream = true
+}
+
+// JetStreamEnabled reports whether the engine consumes commands from JetStream
+func (e *WalletEngine) JetStreamEnabled() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.jetStream
+}
+
+// subscribeStream consumes CommandStream through the durable CommandConsumer.
+// The consumer is created here rather than by the subscription, so that
+// unsubscribing on Stop keeps it and its position for the next start.
+func (e *WalletEngine) subscribeStream() error {
+	if e.PriorityLaneEnabled() {
+		return fmt.Errorf("the priority lane is not supported with JetStream commands")
+	}
+	js, err := e.natsConn.JetStream()
+	if err != nil {
+		return fmt.Errorf("failed to open JetStream: %w", err)
+	}
+	if err := EnsureCommandStream(js); err != nil {
+		return err
+	}
+
+	if _, err := js.ConsumerInfo(CommandStream, CommandConsumer); errors.Is(err, nats.ErrConsumerNotFound) {
+		_, err = js.AddConsumer(CommandStream, &nats.ConsumerConfig{
+			Durable:        CommandConsumer,
+			DeliverSubject: nats.NewInbox(),
+			DeliverPolicy:  nats.DeliverAllPolicy,
+			AckPolicy:      nats.AckExplicitPolicy,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create command consumer: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to look up command consumer: %w", err)
+	}
+
+	sub, err := js.Subscribe(StreamCommandSubject, e.handleStreamCommand,
+		nats.Bind(CommandStream, CommandConsumer), nats.ManualAck())
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to command stream: %w", err)
+	}
+
+	e.subscription = sub
+	log.Printf("Wallet engine started, consuming stream %s (%s)", CommandStream, StreamCommandSubject)
+	return nil
+}
+
+// handleStreamCommand processes a command from CommandStream, answers it on
+// the inbox in ReplyHeader and acknowledges it. When its events could not be
+// persisted it asks for redelivery, until the command has been delivered
+// MaxDeliveries times and is dead-lettered instead.
+func (e *WalletEngine) handleStreamCommand(msg *nats.Msg) {
+	cmdMsg := &nats.Msg{
+		Subject: msg.Subject,
+		Reply:   msg.Header.Get(ReplyHeader),
+		Header:  msg.Header,
+		Data:    msg.Data,
+		Sub:     msg.Sub,
+	}
+	if err := e.serveCommand(cmdMsg, true); err != nil {
+		var deliveries uint64 = 1
+		if meta, metaErr := msg.Metadata(); metaErr == nil {
+			deliveries = meta.NumDelivered
+		}
+		if deliveries >= uint64(e.MaxDeliveries()) {
+			e.deadLetter(cmdMsg, err.Error(), deliveries)
+			e.respondError(cmdMsg, err.Error())
+			if err := msg.Term(); err != nil {
+				log.Printf("Failed to terminate command: %v", err)
+			}
+			return
+		}
+		log.Printf("Command not processed, redelivering in %s: %v", streamRedeliveryDelay, err)
+		if err := msg.NakWithDelay(streamRedeliveryDelay); err != nil {
+			log.Printf("Failed to nak command: %v", err)
+		}
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		log.Printf("Failed to ack command: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// SetMinimumBalance sets the balance below which transfers may not take
+// account, e.g. for a reserve account. Transfers that would breach the floor
+// fail with "below minimum balance". The default floor is 0: balances cannot
+// go negative. The floor is persisted as a MinimumBalanceSet event, so it is
+// restored on replay and mirrored by standbys; setting 0 removes it.
+func (e *WalletEngine) SetMinimumBalance(account string, floor int64) error {
+	if account == "" {
+		return fmt.Errorf("account is required")
+	}
+	if floor < 0 {
+		return fmt.Errorf("minimum balance cannot be negative, got %d", floor)
+	}
+	return e.persistConfigEvent(domain.MinimumBalanceSet{Account: account, Floor: floor})
+}
+
+// persistConfigEvent appends a configuration event, such as a minimum
+// balance or overdraft limit, through the write path and applies it
+func (e *WalletEngine) persistConfigEvent(event domain.Event) error {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	if e.IsStandby() {
+		return ErrStandby
+	}
+	if !e.allowWrite() {
+		telemetry.DegradedRejectionsTotal.Inc()
+		return ErrDegraded
+	}
+
+	sequenced, err := e.eventStore.AppendSequenced([]domain.Event{event})
+	e.recordPersistResult(err)
+	if err != nil {
+		return fmt.Errorf("failed to persist events: %w", err)
+	}
+	telemetry.EventsStoredTotal.WithLabelValues(event.GetType()).Inc()
+
+	e.mu.Lock()
+	e.applyEvent(event)
+	if n := len(sequenced); n > 0 {
+		e.lastSeq = sequenced[n-1].Sequence
+	}
+	snap := e.snapshotDueLocked(1)
+	e.mu.Unlock()
+	e.writeSnapshot(snap)
+
+	e.notifyEventHandlers(sequenced)
+	e.publishEvents(sequenced)
+	return nil
+}
+
+// MinimumBalance returns the floor configured for account (0 if none)
+func (e *WalletEngine) MinimumBalance(account string) int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.minBalances[account]
+}
@@ -0,0 +1,123 @@
+package engine
+
+import "github.com/nathanyu/digital-wallet/internal/domain"
+
+// DefaultOutcomeCacheSize is how many transaction outcomes the engine
+// remembers until SetOutcomeCacheSize changes it
+const DefaultOutcomeCacheSize = 10000
+
+// TransactionOutcome is the recorded result of a processed transaction, so a
+// duplicate submission can be answered with what happened the first time
+type TransactionOutcome struct {
+	Events []domain.Event
+	// Reason is the failure reason; empty when the transaction succeeded
+	Reason string
+}
+
+// Success reports whether the transaction originally succeeded
+func (o TransactionOutcome) Success() bool {
+	return o.Reason == ""
+}
+
+// outcomeCache keeps the most recent transaction outcomes, evicting the
+// oldest transaction first. Guarded by e.mu.
+type outcomeCache struct {
+	size    int // 0 disables the cache
+	entries map[string]TransactionOutcome
+	order   []string // insertion order, oldest first
+}
+
+func newOutcomeCache(size int) outcomeCache {
+	return outcomeCache{size: size, entries: make(map[string]TransactionOutcome)}
+}
+
+// record stores the outcome of txnID, replacing any earlier one
+func (c *outcomeCache) record(txnID string, outcome TransactionOutcome) {
+	if c.size == 0 || txnID == "" {
+		return
+	}
+	if _, ok := c.entries[txnID]; !ok {
+		for len(c.order) >= c.size {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, txnID)
+	}
+	c.entries[txnID] = outcome
+}
+
+// remove forgets the outcome of txnID
+func (c *outcomeCache) remove(txnID string) {
+	if _, ok := c.entries[txnID]; !ok {
+		return
+	}
+	delete(c.entries, txnID)
+	for i := len(c.order) - 1; i >= 0; i-- {
+		if c.order[i] == txnID {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// appendEvent adds event to an outcome already recorded for its transaction
+func (c *outcomeCache) appendEvent(event domain.Event) {
+	outcome, ok := c.entries[event.GetTransactionID()]
+	if !ok {
+		return
+	}
+	outcome.Events = append(outcome.Events, event)
+	c.entries[event.GetTransactionID()] = outcome
+}
+
+// recordOutcomeLocked updates the outcome cache for an applied event.
+// Caller must hold e.mu.
+func (e *WalletEngine) recordOutcomeLocked(event domain.Event) {
+	switch ev := event.(type) {
+	case domain.MoneyDeducted:
+		// The fee leg follows the transfer's own pair
+		if ev.Fee {
+			e.outcomes.appendEvent(ev)
+		} else {
+			e.outcomes.record(ev.TransactionID, TransactionOutcome{Events: []domain.Event{ev}})
+		}
+	case domain.MoneyDeposited, domain.MoneyWithdrawn, domain.TransferScheduled, domain.TransferPendingApproval:
+		e.outcomes.record(ev.GetTransactionID(), TransactionOutcome{Events: []domain.Event{ev}})
+	case domain.MoneyCredited:
+		e.outcomes.appendEvent(ev)
+	case domain.TransactionFailed:
+		e.outcomes.record(ev.TransactionID, TransactionOutcome{Events: []domain.Event{ev}, Reason: ev.Reason})
+	case domain.ScheduledTransferCanceled:
+		e.outcomes.record(ev.TransactionID, TransactionOutcome{Events: []domain.Event{ev}, Reason: "scheduled transfer canceled"})
+	case domain.TransferRejected:
+		e.outcomes.record(ev.TransactionID, TransactionOutcome{Events: []domain.Event{ev}, Reason: "transfer rejected"})
+	}
+}
+
+// SetOutcomeCacheSize sets how many transaction outcomes are remembered for
+// answering duplicates; 0 disables it, and duplicates then get an empty
+// success. Changing the size drops the outcomes remembered so far. The cache
+// is rebuilt from events on replay, but not from snapshots: transactions
+// before the latest snapshot are still deduplicated, only without their
+// original outcome.
+func (e *WalletEngine) SetOutcomeCacheSize(size int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if size < 0 {
+		size = 0
+	}
+	e.outcomes = newOutcomeCache(size)
+}
+
+// Outcome returns the original result of a processed transaction, if it is
+// still remembered
+func (e *WalletEngine) Outcome(txnID string) (TransactionOutcome, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	outcome, ok := e.outcomes.entries[txnID]
+	if !ok {
+		return TransactionOutcome{}, false
+	}
+	outcome.Events = append([]domain.Event(nil), outcome.Events...)
+	return outcome, true
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// SetOverdraftLimit lets account go negative by up to limit, e.g. for a
+// credit line: a transfer or withdrawal succeeds while the balance after it
+// stays at or above -limit. The default limit is 0: balances cannot go
+// negative. Like the minimum balance it applies to the default currency
+// only, and is persisted as an OverdraftLimitSet event so it is restored on
+// replay and mirrored by standbys; setting 0 removes it. An account with a
+// minimum balance is still held to that floor.
+func (e *WalletEngine) SetOverdraftLimit(account string, limit int64) error {
+	if account == "" {
+		return fmt.Errorf("account is required")
+	}
+	if limit < 0 {
+		return fmt.Errorf("overdraft limit cannot be negative, got %d", limit)
+	}
+	return e.persistConfigEvent(domain.OverdraftLimitSet{Account: account, Limit: limit})
+}
+
+// OverdraftLimit returns the overdraft limit configured for account (0 if none)
+func (e *WalletEngine) OverdraftLimit(account string) int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.overdraftLimits[account]
+}
+
+// overdraftLocked returns how far account may go below zero in currency.
+// Caller must hold e.mu.
+func (e *WalletEngine) overdraftLocked(account, currency string) int64 {
+	if currency != domain.DefaultCurrency {
+		return 0
+	}
+	return e.overdraftLimits[account]
+}
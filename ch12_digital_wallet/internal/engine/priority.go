@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/nats-io/nats.go"
+)
+
+// PriorityCommandSubject carries urgent commands that jump ahead of the
+// backlog on CommandSubject
+const PriorityCommandSubject = "wallet.commands.priority"
+
+// DefaultLaneBuffer is how many commands each lane holds before NATS treats
+// the engine as a slow consumer and drops further messages
+const DefaultLaneBuffer = 4096
+
+// EnablePriorityLane makes the engine also consume PriorityCommandSubject.
+// Both subjects then feed one processing goroutine that always drains the
+// priority lane before taking the next normal command; within a lane commands
+// are still processed in arrival order. Must be called before Start.
+func (e *WalletEngine) EnablePriorityLane(buffer int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if buffer < 1 {
+		buffer = DefaultLaneBuffer
+	}
+	e.laneBuffer = buffer
+}
+
+// PriorityLaneEnabled reports whether the engine consumes PriorityCommandSubject
+func (e *WalletEngine) PriorityLaneEnabled() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.laneBuffer > 0
+}
+
+// subscribeLanes consumes both command subjects into buffered lanes
+func (e *WalletEngine) subscribeLanes() error {
+	e.mu.RLock()
+	buffer := e.laneBuffer
+	e.mu.RUnlock()
+
+	normal := make(chan *nats.Msg, buffer)
+	priority := make(chan *nats.Msg, buffer)
+
+	sub, err := e.natsConn.ChanSubscribe(CommandSubject, normal)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to commands: %w", err)
+	}
+	prioritySub, err := e.natsConn.ChanSubscribe(PriorityCommandSubject, priority)
+	if err != nil {
+		sub.Unsubscribe()
+		return fmt.Errorf("failed to subscribe to priority commands: %w", err)
+	}
+
+	e.subscription = sub
+	e.prioritySub = prioritySub
+	e.wg.Add(1)
+	go e.drainLanes(normal, priority)
+
+	log.Printf("Wallet engine started, listening on subjects: %s, %s (priority)", CommandSubject, PriorityCommandSubject)
+	return nil
+}
+
+// drainLanes processes commands one at a time until the engine stops,
+// checking the priority lane before every normal command
+func (e *WalletEngine) drainLanes(normal, priority <-chan *nats.Msg) {
+	defer e.wg.Done()
+	for {
+		select {
+		case msg := <-priority:
+			e.handleCommand(msg)
+			continue
+		default:
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return
+		case msg := <-priority:
+			e.handleCommand(msg)
+		case msg := <-normal:
+			e.handleCommand(msg)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// replayReportEvery is how many events InitializeFromEventStore applies
+// between progress updates
+const replayReportEvery = 1000
+
+// ReplayProgress is how far InitializeFromEventStore has got rebuilding the
+// engine state from the event store
+type ReplayProgress struct {
+	Replaying bool
+	Applied   uint64 // Events applied so far
+	Total     uint64 // Events to apply; 0 while they are still being loaded
+}
+
+// String describes the progress for a health check, e.g. "replaying (1000/5000 events)"
+func (p ReplayProgress) String() string {
+	if p.Total == 0 {
+		return "replaying (loading events)"
+	}
+	return fmt.Sprintf("replaying (%d/%d events)", p.Applied, p.Total)
+}
+
+// replayProgress tracks ReplayProgress in atomics, so it can be read while
+// the replay holds e.mu
+type replayProgress struct {
+	replaying atomic.Bool
+	applied   atomic.Uint64
+	total     atomic.Uint64
+}
+
+// start resets the progress for a new replay
+func (p *replayProgress) start() {
+	p.applied.Store(0)
+	p.total.Store(0)
+	p.replaying.Store(true)
+	telemetry.ReplayEventsTotal.Set(0)
+	telemetry.ReplayEventsExpected.Set(0)
+}
+
+// loaded records how many events are about to be applied
+func (p *replayProgress) loaded(total int) {
+	p.total.Store(uint64(total))
+	telemetry.ReplayEventsExpected.Set(float64(total))
+}
+
+// advance records applied events, publishing every replayReportEvery of them
+func (p *replayProgress) advance(applied int) {
+	if applied%replayReportEvery == 0 {
+		p.report(applied)
+	}
+}
+
+// finish records the final count and ends the replay
+func (p *replayProgress) finish(applied int) {
+	p.report(applied)
+	p.replaying.Store(false)
+}
+
+func (p *replayProgress) report(applied int) {
+	p.applied.Store(uint64(applied))
+	telemetry.ReplayEventsTotal.Set(float64(applied))
+}
+
+// ReplayProgress reports how far InitializeFromEventStore has got. It can
+// be called while the replay runs, which is what it is for.
+func (e *WalletEngine) ReplayProgress() ReplayProgress {
+	return ReplayProgress{
+		Replaying: e.replay.replaying.Load(),
+		Applied:   e.replay.applied.Load(),
+		Total:     e.replay.total.Load(),
+	}
+}
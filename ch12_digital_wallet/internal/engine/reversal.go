@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// Reverse undoes a completed transfer: it debits the original receiver and
+// credits the original sender with MoneyDeducted and MoneyCredited events
+// that carry the reversal's own transaction ID and link back through
+// Reverses. It fails when the original was never seen, failed, was not a
+// transfer, has not run yet or was already reversed, and when the receiver
+// no longer has the funds. The receiver's minimum balance does not block it.
+// The original's fee, if any, is not refunded.
+//
+// The original is looked up by reading the event store, so reversals scan
+// the whole log; they are meant to be occasional.
+func (e *WalletEngine) Reverse(ctx context.Context, cmd domain.ReverseCommand) ([]domain.Event, error) {
+	return e.processCash(ctx, func() ([]domain.Event, error) {
+		original, err := e.findTransfer(cmd.OriginalTransactionID)
+		if err != nil {
+			return nil, err
+		}
+		return e.executeReverse(cmd, original), nil
+	})
+}
+
+// transferRecord is what the event store holds about a transaction that a
+// reversal refers to
+type transferRecord struct {
+	seen       bool   // any event carries the transaction ID
+	failed     bool   // it ended in TransactionFailed
+	from, to   string // set when it moved money between accounts
+	amount     int64
+	currency   string
+	reverses   string // set when it is itself a reversal
+	reversedBy string // the reversal that undid it, if any
+}
+
+// findTransfer reads the event store for what happened to txnID. The caller
+// must hold e.writeMu so no events are appended during the scan.
+func (e *WalletEngine) findTransfer(txnID string) (transferRecord, error) {
+	var record transferRecord
+	if txnID == "" {
+		return record, nil
+	}
+	events, err := e.eventStore.LoadAll()
+	if err != nil {
+		return record, fmt.Errorf("failed to read event store: %w", err)
+	}
+	for _, event := range events {
+		if ev, ok := event.(domain.MoneyDeducted); ok && ev.Reverses == txnID {
+			record.reversedBy = ev.TransactionID
+		}
+		if event.GetTransactionID() != txnID {
+			continue
+		}
+		record.seen = true
+		switch ev := event.(type) {
+		case domain.MoneyDeducted:
+			if !ev.Fee {
+				record.from, record.amount, record.currency, record.reverses = ev.Account, ev.Amount, ev.Currency, ev.Reverses
+			}
+		case domain.MoneyCredited:
+			if !ev.Fee {
+				record.to = ev.Account
+			}
+		case domain.TransactionFailed:
+			record.failed = true
+		}
+	}
+	return record, nil
+}
+
+// executeReverse generates the events of a reversal of original without
+// modifying state
+func (e *WalletEngine) executeReverse(cmd domain.ReverseCommand, original transferRecord) []domain.Event {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.usedTxnIDLocked(cmd.TransactionID) {
+		return []domain.Event{}
+	}
+
+	var reason string
+	_, scheduled := e.scheduled[cmd.OriginalTransactionID]
+	switch {
+	case cmd.OriginalTransactionID == "":
+		reason = "original transaction ID required"
+	case scheduled || e.awaitingApprovalLocked(cmd.OriginalTransactionID):
+		reason = "original transfer not completed"
+	case !original.seen:
+		reason = "original transaction not found"
+	case original.failed:
+		reason = "original transaction failed"
+	case original.from == "" || original.to == "":
+		reason = "original transaction is not a transfer"
+	case original.reverses != "":
+		reason = "cannot reverse a reversal"
+	case original.reversedBy != "":
+		reason = "transfer already reversed"
+	default:
+		available := e.balanceLocked(original.to, original.currency) - e.heldLocked(original.to, original.currency, "")
+		if available < original.amount {
+			reason = "insufficient funds"
+		}
+	}
+	if reason != "" {
+		return []domain.Event{domain.TransactionFailed{
+			TransactionID: cmd.TransactionID, FromAccount: original.to, Reason: reason,
+		}}
+	}
+
+	return []domain.Event{
+		domain.MoneyDeducted{
+			TransactionID: cmd.TransactionID, Account: original.to, Amount: original.amount,
+			Currency: original.currency, Reverses: cmd.OriginalTransactionID,
+		},
+		domain.MoneyCredited{
+			TransactionID: cmd.TransactionID, Account: original.from, Amount: original.amount,
+			Currency: original.currency, Reverses: cmd.OriginalTransactionID,
+		},
+	}
+}
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// DefaultMaxScheduledTransfers bounds the pending queue until
+// SetMaxScheduledTransfers changes it
+const DefaultMaxScheduledTransfers = 10000
+
+// scheduleTickInterval is how often the scheduler looks for due transfers,
+// and the width of one timer wheel slot
+const scheduleTickInterval = time.Second
+
+// scheduleWheelSlots is the timer wheel's size: one revolution covers an hour
+// of due times, and transfers due further out wait for later revolutions
+const scheduleWheelSlots = 3600
+
+// ErrScheduledTransferNotFound is returned when canceling a transfer that is
+// not pending: unknown, already run or already canceled
+var ErrScheduledTransferNotFound = errors.New("scheduled transfer not found")
+
+// SetMaxScheduledTransfers caps how many future-dated transfers may be
+// pending at once; further ones fail with "too many scheduled transfers".
+// 0 means no limit.
+func (e *WalletEngine) SetMaxScheduledTransfers(max int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if max < 0 {
+		max = 0
+	}
+	e.maxScheduled = max
+}
+
+// MaxScheduledTransfers returns the pending queue cap, or 0 when there is none
+func (e *WalletEngine) MaxScheduledTransfers() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.maxScheduled
+}
+
+// ScheduledTransfers returns the pending transfers, earliest due first
+func (e *WalletEngine) ScheduledTransfers() []domain.TransferScheduled {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	pending := make([]domain.TransferScheduled, 0, len(e.scheduled))
+	for _, p := range e.scheduled {
+		pending = append(pending, p)
+	}
+	sortPending(pending)
+	return pending
+}
+
+// sortPending orders pending transfers by (due time, transaction ID)
+func sortPending(pending []domain.TransferScheduled) {
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].DueAt.Equal(pending[j].DueAt) {
+			return pending[i].DueAt.Before(pending[j].DueAt)
+		}
+		return pending[i].TransactionID < pending[j].TransactionID
+	})
+}
+
+// unscheduleLocked forgets a pending transfer once it has run, failed or
+// been canceled. Caller must hold e.mu.
+func (e *WalletEngine) unscheduleLocked(txnID string) {
+	delete(e.scheduled, txnID)
+	e.wheel.remove(txnID)
+}
+
+// isPendingDuplicateLocked reports whether cmd reuses the ID of a pending
+// scheduled transfer. Only the due command itself (same due time, no longer
+// in the future) may run it. Caller must hold e.mu.
+func (e *WalletEngine) isPendingDuplicateLocked(cmd domain.TransferCommand) bool {
+	p, ok := e.scheduled[cmd.TransactionID]
+	if !ok {
+		return false
+	}
+	return !cmd.ExecuteAt.Equal(p.DueAt) || cmd.ExecuteAt.After(e.now())
+}
+
+// scheduleLocked turns a future-dated command into the event that queues it.
+// Caller must hold e.mu.
+func (e *WalletEngine) scheduleLocked(cmd domain.TransferCommand) domain.Event {
+	if e.maxScheduled > 0 && len(e.scheduled) >= e.maxScheduled {
+		return domain.TransactionFailed{
+			TransactionID: cmd.TransactionID,
+			FromAccount:   cmd.FromAccount,
+			Reason:        "too many scheduled transfers",
+			Memo:          cmd.Memo,
+		}
+	}
+	return domain.TransferScheduled{
+		TransactionID: cmd.TransactionID,
+		FromAccount:   cmd.FromAccount,
+		ToAccount:     cmd.ToAccount,
+		Amount:        cmd.Amount,
+		Currency:      cmd.Currency,
+		Mode:          cmd.Mode,
+		Percent:       cmd.Percent,
+		Memo:          cmd.Memo,
+		DueAt:         cmd.ExecuteAt,
+	}
+}
+
+// RunDueTransfers executes every pending transfer whose due time has passed,
+// earliest first, and returns how many ran. The timer wheel hands them out,
+// and each runs through ProcessCommand, so it emits the usual success or
+// failure events. It stops at the first write error (e.g. ErrDegraded); the
+// rest go back on the wheel for the next run.
+func (e *WalletEngine) RunDueTransfers(ctx context.Context) (int, error) {
+	e.mu.Lock()
+	var due []domain.TransferScheduled
+	for _, txnID := range e.wheel.advance(e.now()) {
+		if p, ok := e.scheduled[txnID]; ok {
+			due = append(due, p)
+		}
+	}
+	e.mu.Unlock()
+	sortPending(due)
+
+	for i, p := range due {
+		if _, err := e.ProcessCommand(ctx, p.Command()); err != nil {
+			e.mu.Lock()
+			for _, rest := range due[i:] {
+				if _, ok := e.scheduled[rest.TransactionID]; ok {
+					e.wheel.add(rest.TransactionID, rest.DueAt)
+				}
+			}
+			e.mu.Unlock()
+			return i, fmt.Errorf("scheduled transfer %s: %w", p.TransactionID, err)
+		}
+	}
+	return len(due), nil
+}
+
+// startScheduler runs due transfers in the background until Stop.
+// It is started once the engine accepts commands.
+func (e *WalletEngine) startScheduler() {
+	e.mu.Lock()
+	if e.schedulerStarted {
+		e.mu.Unlock()
+		return
+	}
+	e.schedulerStarted = true
+	e.mu.Unlock()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(scheduleTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := e.RunDueTransfers(e.ctx); err != nil {
+					log.Printf("Failed to run scheduled transfers: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// CancelScheduledTransfer withdraws a pending scheduled transfer. The
+// cancellation is persisted as a ScheduledTransferCanceled event and the
+// transaction ID counts as used afterwards.
+func (e *WalletEngine) CancelScheduledTransfer(txnID string) error {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	if e.IsStandby() {
+		return ErrStandby
+	}
+
+	e.mu.RLock()
+	_, pending := e.scheduled[txnID]
+	e.mu.RUnlock()
+	if !pending {
+		return ErrScheduledTransferNotFound
+	}
+
+	if !e.allowWrite() {
+		telemetry.DegradedRejectionsTotal.Inc()
+		return ErrDegraded
+	}
+
+	event := domain.ScheduledTransferCanceled{TransactionID: txnID}
+	sequenced, err := e.eventStore.AppendSequenced([]domain.Event{event})
+	e.recordPersistResult(err)
+	if err != nil {
+		return fmt.Errorf("failed to persist events: %w", err)
+	}
+	telemetry.EventsStoredTotal.WithLabelValues(event.GetType()).Inc()
+
+	e.mu.Lock()
+	e.applyEvent(event)
+	if n := len(sequenced); n > 0 {
+		e.lastSeq = sequenced[n-1].Sequence
+	}
+	snap := e.snapshotDueLocked(1)
+	e.mu.Unlock()
+	e.writeSnapshot(snap)
+
+	e.notifyEventHandlers(sequenced)
+	e.publishEvents(sequenced)
+	return nil
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// snapshotStore is implemented by event stores that keep state snapshots.
+// Replay starts from the latest snapshot instead of the first event.
+type snapshotStore interface {
+	WriteSnapshot(snap eventstore.Snapshot) error
+	LoadLatestSnapshot() (*eventstore.Snapshot, error)
+}
+
+// snapshotPolicy decides when the engine snapshots its state. Guarded by e.mu.
+type snapshotPolicy struct {
+	everyEvents int           // snapshot after this many applied events (0 = off)
+	every       time.Duration // snapshot when this much time has passed (0 = off)
+
+	sinceLast int       // events applied since the last snapshot
+	lastAt    time.Time // when the last snapshot was taken
+	inFlight  bool      // a snapshot is being written
+}
+
+// SetSnapshotPolicy makes the engine snapshot its state after every
+// everyEvents applied events or, once events have been applied, when every
+// has passed since the last snapshot; 0 turns a trigger off. The state is
+// copied under the engine lock and written in the background, so commands
+// only wait for the copy.
+func (e *WalletEngine) SetSnapshotPolicy(everyEvents int, every time.Duration) error {
+	if everyEvents < 0 || every < 0 {
+		return fmt.Errorf("snapshot triggers cannot be negative")
+	}
+	if _, ok := e.eventStore.(snapshotStore); !ok && (everyEvents > 0 || every > 0) {
+		return fmt.Errorf("event store does not support snapshots")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.snapshots.everyEvents = everyEvents
+	e.snapshots.every = every
+	e.snapshots.lastAt = e.now()
+	return nil
+}
+
+// loadSnapshot returns the latest snapshot, or nil when the store has none
+// or does not keep snapshots
+func (e *WalletEngine) loadSnapshot() (*eventstore.Snapshot, error) {
+	store, ok := e.eventStore.(snapshotStore)
+	if !ok {
+		return nil, nil
+	}
+	return store.LoadLatestSnapshot()
+}
+
+// installSnapshotLocked replaces the engine state with snap.
+// Caller must hold e.mu.
+func (e *WalletEngine) installSnapshotLocked(snap *eventstore.Snapshot) {
+	e.balances = make(map[string]map[string]int64, len(snap.Balances))
+	for account, balance := range snap.Balances {
+		e.addBalanceLocked(account, domain.DefaultCurrency, balance)
+	}
+	for currency, balances := range snap.CurrencyBalances {
+		for account, balance := range balances {
+			e.addBalanceLocked(account, currency, balance)
+		}
+	}
+	e.processedTxns.restore(snap.ProcessedTxns)
+	e.minBalances = snap.MinBalances
+	e.overdraftLimits = snap.OverdraftLimits
+	e.frozen = snap.Frozen
+	if e.frozen == nil {
+		e.frozen = make(map[string]bool)
+	}
+	e.scheduled = snap.Scheduled
+	if e.scheduled == nil {
+		e.scheduled = make(map[string]domain.TransferScheduled)
+	}
+	e.wheel.reset()
+	for txnID, p := range e.scheduled {
+		e.wheel.add(txnID, p.DueAt)
+	}
+	e.pendingApprovals = snap.PendingApprovals
+	if e.pendingApprovals == nil {
+		e.pendingApprovals = make(map[string]domain.TransferPendingApproval)
+	}
+	e.lastSeq = snap.Sequence
+	log.Printf("Wallet engine loaded snapshot at seq %d (%d accounts)", snap.Sequence, len(e.balances))
+}
+
+// snapshotDueLocked counts applied events and, when a trigger fires, returns
+// a copy of the state to write. Caller must hold e.mu.
+func (e *WalletEngine) snapshotDueLocked(applied int) *eventstore.Snapshot {
+	p := &e.snapshots
+	if p.everyEvents == 0 && p.every == 0 {
+		return nil
+	}
+	p.sinceLast += applied
+	if p.sinceLast == 0 || p.inFlight {
+		return nil
+	}
+
+	now := e.now()
+	byCount := p.everyEvents > 0 && p.sinceLast >= p.everyEvents
+	byTime := p.every > 0 && now.Sub(p.lastAt) >= p.every
+	if !byCount && !byTime {
+		return nil
+	}
+
+	snap := &eventstore.Snapshot{
+		Sequence:      e.lastSeq,
+		TakenAt:       now,
+		Balances:      make(map[string]int64, len(e.balances)),
+		ProcessedTxns: e.processedTxns.set(),
+		MinBalances:   make(map[string]int64, len(e.minBalances)),
+		Scheduled:     make(map[string]domain.TransferScheduled, len(e.scheduled)),
+
+		OverdraftLimits:  make(map[string]int64, len(e.overdraftLimits)),
+		PendingApprovals: make(map[string]domain.TransferPendingApproval, len(e.pendingApprovals)),
+	}
+	for account, wallets := range e.balances {
+		for currency, balance := range wallets {
+			if currency == domain.DefaultCurrency {
+				snap.Balances[account] = balance
+				continue
+			}
+			if snap.CurrencyBalances == nil {
+				snap.CurrencyBalances = make(map[string]map[string]int64)
+			}
+			if snap.CurrencyBalances[currency] == nil {
+				snap.CurrencyBalances[currency] = make(map[string]int64)
+			}
+			snap.CurrencyBalances[currency][account] = balance
+		}
+	}
+	for k, v := range e.minBalances {
+		snap.MinBalances[k] = v
+	}
+	for k, v := range e.overdraftLimits {
+		snap.OverdraftLimits[k] = v
+	}
+	if len(e.frozen) > 0 {
+		snap.Frozen = make(map[string]bool, len(e.frozen))
+		for k, v := range e.frozen {
+			snap.Frozen[k] = v
+		}
+	}
+	for k, v := range e.scheduled {
+		snap.Scheduled[k] = v
+	}
+	for k, v := range e.pendingApprovals {
+		snap.PendingApprovals[k] = v
+	}
+
+	p.sinceLast = 0
+	p.lastAt = now
+	p.inFlight = true
+	return snap
+}
+
+// writeSnapshot persists snap in the background
+func (e *WalletEngine) writeSnapshot(snap *eventstore.Snapshot) {
+	if snap == nil {
+		return
+	}
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		err := e.eventStore.(snapshotStore).WriteSnapshot(*snap)
+		if err != nil {
+			log.Printf("Failed to write snapshot at seq %d: %v", snap.Sequence, err)
+			telemetry.SnapshotsTotal.WithLabelValues("failed").Inc()
+		} else {
+			telemetry.SnapshotsTotal.WithLabelValues("success").Inc()
+		}
+
+		e.mu.Lock()
+		e.snapshots.inFlight = false
+		e.mu.Unlock()
+	}()
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nats-io/nats.go"
+)
+
+// CodeStandby marks a command rejected because the engine has not been promoted
+const CodeStandby = "STANDBY"
+
+// ErrStandby is returned for commands sent to an engine that is still in standby
+var ErrStandby = errors.New("wallet engine is in standby: commands are rejected until promoted")
+
+// sequencedLog is implemented by event stores that can replay from a sequence.
+// A standby uses it to fill gaps in the tailed events and to catch up on promotion.
+type sequencedLog interface {
+	LoadSince(afterSeq uint64) ([]domain.SequencedEvent, error)
+}
+
+// NewStandbyEngine creates a warm standby: it applies the primary's events
+// as they are published but rejects commands until Promote is called.
+//
+// Start on a standby tails EventSubject instead of consuming CommandSubject,
+// so the primary keeps sole ownership of the command stream.
+func NewStandbyEngine(eventStore EventLog, natsConn *nats.Conn) *WalletEngine {
+	e := NewWalletEngine(eventStore, natsConn)
+	e.standby = true
+	return e
+}
+
+// IsStandby reports whether the engine is rejecting commands awaiting promotion
+func (e *WalletEngine) IsStandby() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.standby
+}
+
+// LastSequence returns the event store sequence of the last applied event
+func (e *WalletEngine) LastSequence() uint64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.lastSeq
+}
+
+// ApplySequencedEvent applies an event replicated from the primary.
+// Events at or below the last applied sequence are ignored; a gap is filled
+// from the event store before the event is applied. It is an EventHandler,
+// so a standby can be fed directly from a primary's RegisterEventHandler.
+func (e *WalletEngine) ApplySequencedEvent(event domain.SequencedEvent) {
+	e.mu.Lock()
+	applied, err := e.applySequencedLocked([]domain.SequencedEvent{event})
+	e.mu.Unlock()
+	if err != nil {
+		log.Printf("Standby failed to fill event gap before seq %d: %v", event.Sequence, err)
+	}
+
+	e.notifyEventHandlers(applied)
+}
+
+// Promote catches up with the event store and starts accepting commands.
+// When the engine has a NATS connection it also stops tailing events and
+// starts consuming CommandSubject (and PriorityCommandSubject when enabled).
+func (e *WalletEngine) Promote() error {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	e.mu.Lock()
+	if !e.standby {
+		e.mu.Unlock()
+		return fmt.Errorf("wallet engine is already primary")
+	}
+	// Anything the primary persisted but never published must be applied
+	// before this engine makes decisions on balances
+	applied, err := e.catchUpLocked()
+	if err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("failed to catch up before promotion: %w", err)
+	}
+	e.standby = false
+	lastSeq := e.lastSeq
+	e.mu.Unlock()
+
+	e.notifyEventHandlers(applied)
+
+	if e.natsConn != nil {
+		if e.tailSub != nil {
+			e.tailSub.Unsubscribe()
+			e.tailSub = nil
+		}
+		if err := e.subscribeCommands(); err != nil {
+			return err
+		}
+		e.startScheduler()
+	}
+
+	log.Printf("Wallet engine promoted to primary at seq %d", lastSeq)
+	return nil
+}
+
+// startTailing subscribes a standby to the primary's published events
+func (e *WalletEngine) startTailing() error {
+	sub, err := e.natsConn.Subscribe(EventSubject, func(msg *nats.Msg) {
+		event, err := domain.DeserializeSequencedEvent(msg.Data)
+		if err != nil {
+			log.Printf("Standby failed to deserialize event: %v", err)
+			return
+		}
+		e.ApplySequencedEvent(event)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+
+	e.tailSub = sub
+	log.Printf("Wallet engine started in standby, tailing subject: %s", EventSubject)
+	return nil
+}
+
+// applySequencedLocked applies events in sequence order, skipping ones already
+// applied and filling gaps from the event store. Caller must hold e.mu.
+func (e *WalletEngine) applySequencedLocked(events []domain.SequencedEvent) ([]domain.SequencedEvent, error) {
+	var applied []domain.SequencedEvent
+	for _, event := range events {
+		if event.Sequence <= e.lastSeq {
+			continue
+		}
+		if event.Sequence > e.lastSeq+1 {
+			filled, err := e.catchUpLocked()
+			applied = append(applied, filled...)
+			if err != nil {
+				return applied, err
+			}
+			if event.Sequence <= e.lastSeq {
+				continue
+			}
+		}
+		e.applyEvent(event.Event)
+		e.lastSeq = event.Sequence
+		applied = append(applied, event)
+	}
+	return applied, nil
+}
+
+// catchUpLocked applies every stored event after lastSeq.
+// Caller must hold e.mu.
+func (e *WalletEngine) catchUpLocked() ([]domain.SequencedEvent, error) {
+	source, ok := e.eventStore.(sequencedLog)
+	if !ok {
+		return nil, nil
+	}
+	events, err := source.LoadSince(e.lastSeq)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		e.applyEvent(event.Event)
+		e.lastSeq = event.Sequence
+	}
+	return events, nil
+}
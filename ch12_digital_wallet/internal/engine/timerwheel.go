@@ -0,0 +1,75 @@
+package engine
+
+import "time"
+
+// timerWheel is a hashed timing wheel over transaction IDs. Time is cut into
+// ticks, and a transfer due in tick n sits in slot n mod len(slots). Each
+// advance only visits the slots of the ticks that passed, so the cost of
+// finding due transfers follows how many come due rather than how many are
+// pending. A slot also holds transfers due whole revolutions later; they are
+// left in place until their own tick comes around.
+type timerWheel struct {
+	tick  time.Duration
+	slots []map[string]time.Time // transaction ID -> due time
+	index map[string]int         // transaction ID -> slot
+	// pos is the tick the wheel has advanced to. It is visited again on the
+	// next advance, since a transfer due later within it may still be waiting.
+	pos int64
+}
+
+func newTimerWheel(tick time.Duration, size int) *timerWheel {
+	slots := make([]map[string]time.Time, size)
+	for i := range slots {
+		slots[i] = make(map[string]time.Time)
+	}
+	return &timerWheel{tick: tick, slots: slots, index: make(map[string]int)}
+}
+
+// tickOf returns the tick t falls in
+func (w *timerWheel) tickOf(t time.Time) int64 {
+	return t.UnixNano() / int64(w.tick)
+}
+
+// add places a transfer by its due time, replacing any earlier entry for the
+// same ID. One already due goes in the current tick, for the next advance.
+func (w *timerWheel) add(txnID string, due time.Time) {
+	w.remove(txnID)
+	slot := int(max(w.tickOf(due), w.pos) % int64(len(w.slots)))
+	w.slots[slot][txnID] = due
+	w.index[txnID] = slot
+}
+
+// remove drops a transfer, if the wheel holds it
+func (w *timerWheel) remove(txnID string) {
+	if slot, ok := w.index[txnID]; ok {
+		delete(w.slots[slot], txnID)
+		delete(w.index, txnID)
+	}
+}
+
+// advance moves the wheel to now and takes out every transfer due at or
+// before it. After a gap longer than a revolution, each slot is visited once.
+func (w *timerWheel) advance(now time.Time) []string {
+	target := w.tickOf(now)
+	var due []string
+	for t := w.pos; t <= target && t < w.pos+int64(len(w.slots)); t++ {
+		slot := int(t % int64(len(w.slots)))
+		for txnID, at := range w.slots[slot] {
+			if !at.After(now) {
+				due = append(due, txnID)
+				delete(w.slots[slot], txnID)
+				delete(w.index, txnID)
+			}
+		}
+	}
+	w.pos = max(w.pos, target)
+	return due
+}
+
+// reset empties the wheel
+func (w *timerWheel) reset() {
+	for _, slot := range w.slots {
+		clear(slot)
+	}
+	clear(w.index)
+}
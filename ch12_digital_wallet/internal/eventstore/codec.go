@@ -0,0 +1,175 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// Codecs.
+//
+// Events are encoded with the domain.Codec in Options.Codec, JSON by default.
+// JSON records are lines; records of other codecs are framed by a 4-byte
+// big-endian length, whose first byte is always 0 as records are limited to
+// maxRecordBytes. A segment whose codec differs from the log before it
+// starts with a header line "#codec <name>", so the log read as one stream
+// (the segments concatenated, or the replica) always says how the records
+// that follow are encoded. A log without headers is JSON, like every log
+// written before codecs existed, and JSON segments after JSON segments need
+// none. Opening a store with another codec than its newest segment's starts
+// a new segment, so a segment never mixes codecs.
+
+const (
+	codecHeaderPrefix = "#codec "
+	// maxRecordBytes bounds one encoded event, as the line scanner used to
+	maxRecordBytes = 1024 * 1024
+)
+
+// isJSON reports whether codec writes line-delimited JSON
+func isJSON(codec domain.Codec) bool {
+	return codec.Name() == domain.JSONCodec.Name()
+}
+
+// codecHeader returns the header line announcing codec
+func codecHeader(codec domain.Codec) []byte {
+	return []byte(codecHeaderPrefix + codec.Name() + "\n")
+}
+
+// appendRecord appends data to batch framed as one record of codec, with
+// its checksum (see checksum.go)
+func appendRecord(batch []byte, codec domain.Codec, data []byte) ([]byte, error) {
+	record := withChecksum(codec, data)
+	if len(record) > maxRecordBytes {
+		return nil, fmt.Errorf("event of %d bytes exceeds the %d byte limit", len(data), maxRecordBytes)
+	}
+	if isJSON(codec) {
+		batch = append(batch, record...)
+		return append(batch, '\n'), nil
+	}
+	batch = binary.BigEndian.AppendUint32(batch, uint32(len(record)))
+	return append(batch, record...), nil
+}
+
+// readCodecHeader returns the codec announced at the start of f, or nil when
+// f has no header
+func readCodecHeader(f *os.File) (domain.Codec, error) {
+	buf := make([]byte, 64)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read event store header: %w", err)
+	}
+	line, _, found := bytes.Cut(buf[:n], []byte("\n"))
+	if !found || !bytes.HasPrefix(line, []byte(codecHeaderPrefix)) {
+		return nil, nil
+	}
+	return domain.CodecByName(strings.TrimPrefix(string(line), codecHeaderPrefix))
+}
+
+// segmentCodec returns the codec the log is in at the end of segment n:
+// its header's, or that of the segments before it
+func (s *EventStore) segmentCodec(n int) (domain.Codec, error) {
+	codec := domain.JSONCodec
+	for i := 1; i <= n; i++ {
+		f, err := os.Open(s.segmentPath(i))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open event store segment: %w", err)
+		}
+		header, err := readCodecHeader(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if header != nil {
+			codec = header
+		}
+	}
+	return codec, nil
+}
+
+// recordReader reads the records of a log stream, following codec headers
+type recordReader struct {
+	r *bufio.Reader
+	// codec encodes the record last returned by next
+	codec domain.Codec
+	// offset counts the bytes consumed, headers and framing included
+	offset int64
+}
+
+// newRecordReader reads records from r, which starts in codec
+func newRecordReader(r io.Reader, codec domain.Codec) *recordReader {
+	return &recordReader{r: bufio.NewReaderSize(r, 64*1024), codec: codec}
+}
+
+// next returns the next record, skipping codec headers; in JSON an empty
+// line is an empty record. It returns io.EOF at the end of the stream.
+func (r *recordReader) next() ([]byte, error) {
+	for {
+		first, err := r.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+
+		if first[0] == '#' {
+			line, err := r.readLine()
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			name, ok := strings.CutPrefix(string(line), codecHeaderPrefix)
+			if !ok {
+				if isJSON(r.codec) {
+					// Not a header, just a malformed line
+					return line, nil
+				}
+				return nil, fmt.Errorf("malformed codec header %q", line)
+			}
+			if r.codec, err = domain.CodecByName(name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if isJSON(r.codec) {
+			line, err := r.readLine()
+			if err != nil && (!errors.Is(err, io.EOF) || len(line) == 0) {
+				return nil, err
+			}
+			return line, nil
+		}
+
+		var size [4]byte
+		if _, err := io.ReadFull(r.r, size[:]); err != nil {
+			return nil, fmt.Errorf("truncated record length: %w", err)
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n > maxRecordBytes {
+			return nil, fmt.Errorf("record length %d exceeds the %d byte limit", n, maxRecordBytes)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r.r, data); err != nil {
+			return nil, fmt.Errorf("truncated record: %w", err)
+		}
+		r.offset += int64(len(size)) + int64(n)
+		return data, nil
+	}
+}
+
+// readLine reads up to and including the next newline and returns the line
+// without it; a last line without newline comes with io.EOF
+func (r *recordReader) readLine() ([]byte, error) {
+	line, err := r.r.ReadBytes('\n')
+	r.offset += int64(len(line))
+	if len(line) > maxRecordBytes+1 {
+		return nil, fmt.Errorf("line of %d bytes exceeds the %d byte limit", len(line), maxRecordBytes)
+	}
+	return bytes.TrimSuffix(line, []byte("\n")), err
+}
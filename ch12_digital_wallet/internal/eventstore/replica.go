@@ -0,0 +1,125 @@
+package eventstore
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Replication.
+//
+// A replicated store writes every batch to a second log file, the replica,
+// under the same lock as the primary and before the append returns, so an
+// acknowledged event is always in both. A batch is all-or-nothing across the
+// two logs: if either write fails, both are cut back to where they were.
+// When the primary cannot be read (e.g. a corrupt line in strict mode) the
+// store reads the replica instead; copying the replica over the primary
+// repairs it. This is a single-machine stand-in for replicating to another
+// region, not a cluster: there is no catch-up of a lagging replica other
+// than seeding an empty one on open.
+
+// NewReplicatedEventStore creates an event store at primary that also
+// writes every batch to replica
+func NewReplicatedEventStore(primary, replica string) (*EventStore, error) {
+	return NewEventStoreWithOptions(primary, Options{ReplicaPath: replica})
+}
+
+// ReplicaPath returns the replica log of a replicated store, or "" when the
+// store is not replicated
+func (s *EventStore) ReplicaPath() string {
+	return s.replicaPath
+}
+
+// openReplica opens the replica log, seeding it with a copy of the primary
+// when it is empty so both start out identical
+func (s *EventStore) openReplica(path string) error {
+	replica, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open replica file: %w", err)
+	}
+
+	info, err := replica.Stat()
+	if err != nil {
+		replica.Close()
+		return fmt.Errorf("failed to stat replica file: %w", err)
+	}
+	if info.Size() == 0 {
+		if err := seedReplica(replica, s.segmentPaths()); err != nil {
+			replica.Close()
+			return err
+		}
+	}
+
+	s.replica = replica
+	s.replicaPath = path
+	return nil
+}
+
+// seedReplica copies the primary log, stored in segments, into replica
+func seedReplica(replica *os.File, segments []string) error {
+	primary, err := openLog(segments, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open event store for seeding replica: %w", err)
+	}
+	defer primary.Close()
+
+	n, err := io.Copy(replica, primary)
+	if err != nil {
+		return fmt.Errorf("failed to seed replica: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
+	log.Printf("Seeded empty replica with %d bytes from %s", n, segments[0])
+	return replica.Sync()
+}
+
+// writeReplicated appends batch to the primary and the replica and syncs
+// both. On any failure both files are truncated back to their previous size,
+// so the batch is in neither; if either cannot be, the error wraps
+// errBatchKept. Caller must hold s.mu.
+func (s *EventStore) writeReplicated(batch []byte) error {
+	primaryInfo, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat event store: %w", err)
+	}
+	replicaInfo, err := s.replica.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat replica: %w", err)
+	}
+
+	// Only a file that took some of the batch needs cutting back
+	var primaryWritten, replicaWritten int
+	rollback := func(cause error) error {
+		kept := false
+		if err := s.file.Truncate(primaryInfo.Size()); err != nil && primaryWritten > 0 {
+			log.Printf("Failed to roll back event store after a failed batch: %v", err)
+			kept = true
+		}
+		if err := s.replica.Truncate(replicaInfo.Size()); err != nil && replicaWritten > 0 {
+			log.Printf("Failed to roll back replica after a failed batch: %v", err)
+			kept = true
+		}
+		if kept {
+			return fmt.Errorf("%w (%w)", cause, errBatchKept)
+		}
+		return cause
+	}
+
+	if primaryWritten, err = s.file.Write(batch); err != nil {
+		return rollback(fmt.Errorf("failed to write event: %w", err))
+	}
+	if replicaWritten, err = s.replica.Write(batch); err != nil {
+		return rollback(fmt.Errorf("failed to write event to replica: %w", err))
+	}
+
+	// Ensure durability of both before acknowledging
+	if err := s.file.Sync(); err != nil {
+		return rollback(fmt.Errorf("failed to sync event store: %w", err))
+	}
+	if err := s.replica.Sync(); err != nil {
+		return rollback(fmt.Errorf("failed to sync replica: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package eventstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// Snapshot is the wallet engine state as of an event sequence. Replay starts
+// from the snapshot and only applies events after Sequence, read from Offset.
+type Snapshot struct {
+	Sequence uint64 `json:"sequence"`
+	// Offset is the byte offset in the event log just after the event at
+	// Sequence; WriteSnapshot fills it in. 0 means unknown and the log is
+	// read from the start.
+	Offset  int64     `json:"offset,omitempty"`
+	TakenAt time.Time `json:"taken_at"`
+	// Balances holds the default currency balances by account
+	Balances      map[string]int64 `json:"balances"`
+	ProcessedTxns map[string]bool  `json:"processed_txns"`
+	MinBalances   map[string]int64 `json:"min_balances,omitempty"`
+	// OverdraftLimits holds how far accounts may go below zero
+	OverdraftLimits map[string]int64 `json:"overdraft_limits,omitempty"`
+	// Frozen holds the accounts money may not leave
+	Frozen map[string]bool `json:"frozen,omitempty"`
+	// CurrencyBalances holds the balances in other currencies, by currency
+	// and then account
+	CurrencyBalances map[string]map[string]int64 `json:"currency_balances,omitempty"`
+	// Scheduled holds transfers accepted for later execution that have not run yet
+	Scheduled map[string]domain.TransferScheduled `json:"scheduled,omitempty"`
+	// PendingApprovals holds transfers waiting for a second approval
+	PendingApprovals map[string]domain.TransferPendingApproval `json:"pending_approvals,omitempty"`
+}
+
+// SnapshotPath returns the file the store keeps its latest snapshot in,
+// next to the event log
+func (s *EventStore) SnapshotPath() string {
+	return s.filePath + ".snapshot"
+}
+
+// checkpoint is a known position in the log: offset is the byte just after
+// the event at seq
+type checkpoint struct {
+	seq    uint64
+	offset int64
+}
+
+// errStaleCheckpoint means a snapshot offset does not point at the event
+// after its sequence, e.g. because the log was replaced
+var errStaleCheckpoint = errors.New("snapshot offset does not match the event log")
+
+// checkpointFor returns where to start reading for events after afterSeq:
+// the latest snapshot when it is not past afterSeq, else the start of the log
+func (s *EventStore) checkpointFor(afterSeq uint64) checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checkpoint.offset > 0 && s.checkpoint.seq <= afterSeq {
+		return s.checkpoint
+	}
+	return checkpoint{}
+}
+
+// readCheckpoint returns the position of the snapshot on disk, or the start
+// of the log when there is none or it cannot be read
+func (s *EventStore) readCheckpoint() checkpoint {
+	snap, err := s.LoadLatestSnapshot()
+	if err != nil || snap == nil || snap.Offset <= 0 {
+		return checkpoint{}
+	}
+	return checkpoint{seq: snap.Sequence, offset: snap.Offset}
+}
+
+// offsetAfter returns the byte offset just after the event at seq, or 0 when
+// it is not in the log
+func (s *EventStore) offsetAfter(seq uint64) int64 {
+	s.mu.Lock()
+	if seq == s.lastSeq {
+		size := s.size
+		s.mu.Unlock()
+		return size
+	}
+	s.mu.Unlock()
+
+	// Events were appended since; find the line
+	file, err := openLog(s.segmentPaths(), 0)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	records := file.records()
+	var position uint64
+	for {
+		line, err := records.next()
+		if err != nil {
+			return 0
+		}
+		if len(line) == 0 {
+			continue
+		}
+		se, _, err := decodeRecord(records.codec, line)
+		if err != nil {
+			continue
+		}
+		position++
+		if se.Sequence == 0 {
+			se.Sequence = position
+		}
+		if se.Sequence == seq {
+			return records.offset
+		}
+	}
+}
+
+// WriteSnapshot replaces the latest snapshot. The file is written to a
+// temporary path and renamed, so a crash never leaves a torn snapshot.
+func (s *EventStore) WriteSnapshot(snap Snapshot) error {
+	if snap.Offset == 0 && snap.Sequence > 0 {
+		snap.Offset = s.offsetAfter(snap.Sequence)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to serialize snapshot: %w", err)
+	}
+
+	path := s.SnapshotPath()
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to install snapshot: %w", err)
+	}
+
+	if snap.Offset > 0 {
+		s.mu.Lock()
+		s.checkpoint = checkpoint{seq: snap.Sequence, offset: snap.Offset}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// LoadLatestSnapshot reads the latest snapshot; it returns nil when none has
+// been written
+func (s *EventStore) LoadLatestSnapshot() (*Snapshot, error) {
+	data, err := os.ReadFile(s.SnapshotPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to deserialize snapshot: %w", err)
+	}
+	if snap.Balances == nil {
+		snap.Balances = make(map[string]int64)
+	}
+	if snap.ProcessedTxns == nil {
+		snap.ProcessedTxns = make(map[string]bool)
+	}
+	if snap.MinBalances == nil {
+		snap.MinBalances = make(map[string]int64)
+	}
+	if snap.OverdraftLimits == nil {
+		snap.OverdraftLimits = make(map[string]int64)
+	}
+	return &snap, nil
+}
@@ -1,131 +1,453 @@
 package eventstore
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"sync"
 
 	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
 )
 
+// ReplayMode decides what reading the log does with a line that fails its
+// checksum or does not deserialize
+type ReplayMode string
+
+const (
+	// ReplayStrict fails the read with the line number (the default)
+	ReplayStrict ReplayMode = "strict"
+	// ReplaySkip logs and skips the line, so a store with minor corruption
+	// still loads; the skipped lines are reported (see CorruptLines)
+	ReplaySkip ReplayMode = "skip"
+)
+
+// Valid reports whether m is a known replay mode
+func (m ReplayMode) Valid() bool {
+	return m == ReplayStrict || m == ReplaySkip
+}
+
+// Options configures an event store
+type Options struct {
+	// ReplayMode handles malformed lines when reading; empty means ReplayStrict
+	ReplayMode ReplayMode
+	// ReplicaPath, if set, is a second log every batch is also written to
+	// (see replica.go)
+	ReplicaPath string
+	// MaxSegmentBytes, if set, splits the log into segment files of about
+	// this size (see segment.go)
+	MaxSegmentBytes int64
+	// Codec encodes appended events; nil means domain.JSONCodec. Events
+	// already stored in another codec are still read (see codec.go).
+	Codec domain.Codec
+}
+
 // EventStore provides append-only storage for events
 type EventStore struct {
-	filePath string
-	file     *os.File
-	mu       sync.Mutex
+	filePath   string
+	file       *os.File
+	lastSeq    uint64 // sequence of the last persisted event
+	size       int64  // bytes in the log, i.e. the offset just after lastSeq
+	replayMode ReplayMode
+	corrupt    []CorruptLine // lines skipped by the last read
+
+	// Segment files (see segment.go); segment is the one appended to
+	maxSegmentBytes int64
+	segment         int
+	segmentSize     int64
+
+	// codec encodes appended events; logCodec is the codec the log is in at
+	// its end, which a new segment announces a change from (see codec.go)
+	codec    domain.Codec
+	logCodec domain.Codec
+
+	// Synchronous replica log (see replica.go); nil when not replicated
+	replica     *os.File
+	replicaPath string
+
+	// Where the latest snapshot ends in the log (see snapshot.go)
+	checkpoint checkpoint
+
+	mu sync.Mutex
 }
 
 // NewEventStore creates a new event store with the given file path
 func NewEventStore(filePath string) (*EventStore, error) {
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open event store file: %w", err)
+	return NewEventStoreWithOptions(filePath, Options{})
+}
+
+// NewEventStoreWithOptions creates a new event store with the given file
+// path and options. The existing log is read once here, so a malformed line
+// already fails this call in strict mode.
+func NewEventStoreWithOptions(filePath string, opts Options) (*EventStore, error) {
+	if opts.ReplayMode == "" {
+		opts.ReplayMode = ReplayStrict
+	}
+	if !opts.ReplayMode.Valid() {
+		return nil, fmt.Errorf("unknown replay mode %q", opts.ReplayMode)
+	}
+	if opts.MaxSegmentBytes < 0 {
+		return nil, fmt.Errorf("max segment bytes cannot be negative")
+	}
+	if opts.Codec == nil {
+		opts.Codec = domain.JSONCodec
 	}
 
-	return &EventStore{
-		filePath: filePath,
-		file:     file,
-	}, nil
-}
+	store := &EventStore{
+		filePath:        filePath,
+		replayMode:      opts.ReplayMode,
+		maxSegmentBytes: opts.MaxSegmentBytes,
+		codec:           opts.Codec,
+	}
 
-// Append writes an event to the event store
-func (s *EventStore) Append(event domain.Event) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// Append to the newest segment
+	store.segment = store.lastSegment()
+	file, err := os.OpenFile(store.segmentPath(store.segment), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event store file: %w", err)
+	}
+	store.file = file
+	if opts.ReplicaPath != "" {
+		if err := store.openReplica(opts.ReplicaPath); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
 
-	data, err := domain.SerializeEvent(event)
+	// Continue numbering from the last persisted event. With a snapshot
+	// only the events after its offset are read.
+	store.checkpoint = store.readCheckpoint()
+	from := store.checkpoint.seq
+	existing, err := store.LoadSince(from)
+	if err == nil && store.checkpoint.seq != from {
+		// The snapshot did not match the log; number from a full read
+		from = 0
+		existing, err = store.LoadSince(0)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to serialize event: %w", err)
+		store.Close()
+		return nil, err
+	}
+	store.lastSeq = from
+	if n := len(existing); n > 0 {
+		store.lastSeq = existing[n-1].Sequence
 	}
 
-	// Append newline for line-delimited JSON
-	data = append(data, '\n')
+	for _, path := range store.segmentPaths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to stat event store file: %w", err)
+		}
+		store.size += info.Size()
+		store.segmentSize = info.Size()
+	}
 
-	_, err = s.file.Write(data)
+	// A segment never mixes codecs
+	store.logCodec, err = store.segmentCodec(store.segment)
 	if err != nil {
-		return fmt.Errorf("failed to write event: %w", err)
+		store.Close()
+		return nil, err
 	}
-
-	// Ensure durability
-	if err := s.file.Sync(); err != nil {
-		return fmt.Errorf("failed to sync event store: %w", err)
+	if store.segmentSize > 0 && store.logCodec.Name() != store.codec.Name() {
+		store.mu.Lock()
+		err = store.rotate()
+		store.mu.Unlock()
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
 	}
 
-	return nil
+	return store, nil
+}
+
+// Append writes an event to the event store
+func (s *EventStore) Append(event domain.Event) error {
+	_, err := s.AppendSequenced([]domain.Event{event})
+	return err
 }
 
 // AppendBatch writes multiple events to the event store atomically
 func (s *EventStore) AppendBatch(events []domain.Event) error {
+	_, err := s.AppendSequenced(events)
+	return err
+}
+
+// AppendSequenced writes multiple events to the event store atomically and
+// returns them stamped with their global sequence numbers
+func (s *EventStore) AppendSequenced(events []domain.Event) ([]domain.SequencedEvent, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, event := range events {
-		data, err := domain.SerializeEvent(event)
+	// Serialize the whole batch first, so the primary and the replica get
+	// the same bytes and a bad event writes nothing
+	var batch []byte
+	sequenced := make([]domain.SequencedEvent, len(events))
+	for i, event := range events {
+		sequenced[i] = domain.SequencedEvent{Sequence: s.lastSeq + uint64(i) + 1, Event: event}
+
+		data, err := s.codec.Encode(sequenced[i])
+		if err == nil {
+			batch, err = appendRecord(batch, s.codec, data)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to serialize event: %w", err)
+			return nil, fmt.Errorf("failed to serialize event: %w", err)
 		}
+	}
 
-		data = append(data, '\n')
+	// Start a new segment rather than split the batch across two
+	if s.maxSegmentBytes > 0 && s.segmentSize > 0 && s.segmentSize+int64(len(batch)) > s.maxSegmentBytes {
+		if err := s.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	if s.segmentSize == 0 && s.logCodec.Name() != s.codec.Name() {
+		batch = append(codecHeader(s.codec), batch...)
+	}
 
-		_, err = s.file.Write(data)
-		if err != nil {
-			return fmt.Errorf("failed to write event: %w", err)
+	write := s.writeBatch
+	if s.replica != nil {
+		write = s.writeReplicated
+	}
+	if err := write(batch); err != nil {
+		if errors.Is(err, errBatchKept) {
+			// Some of the batch may be in the log for good: its sequence
+			// numbers are spent and must never be handed out again
+			s.lastSeq += uint64(len(events))
+			s.resyncSegmentSize()
 		}
+		return nil, err
 	}
 
-	if err := s.file.Sync(); err != nil {
-		return fmt.Errorf("failed to sync event store: %w", err)
+	s.lastSeq += uint64(len(events))
+	s.size += int64(len(batch))
+	s.segmentSize += int64(len(batch))
+	s.logCodec = s.codec
+	return sequenced, nil
+}
+
+// errBatchKept marks a failed append whose batch could not be rolled back,
+// so it may be partly or wholly in the log
+var errBatchKept = errors.New("failed batch could not be rolled back")
+
+// writeBatch appends batch to the log and syncs it. On failure the log is
+// truncated back to its previous size, so the batch is not in it; if that
+// fails too the error wraps errBatchKept. Caller must hold s.mu.
+func (s *EventStore) writeBatch(batch []byte) error {
+	var written int
+	rollback := func(cause error) error {
+		if err := s.file.Truncate(s.segmentSize); err != nil && written > 0 {
+			log.Printf("Failed to roll back event store after a failed batch: %v", err)
+			return fmt.Errorf("%w (%w)", cause, errBatchKept)
+		}
+		return cause
+	}
+
+	var err error
+	if written, err = s.file.Write(batch); err != nil {
+		return rollback(fmt.Errorf("failed to write event: %w", err))
 	}
 
+	// Ensure durability
+	if err := s.file.Sync(); err != nil {
+		return rollback(fmt.Errorf("failed to sync event store: %w", err))
+	}
 	return nil
 }
 
+// resyncSegmentSize re-reads the current segment's size after a failed
+// batch left an unknown number of bytes in it. Caller must hold s.mu.
+func (s *EventStore) resyncSegmentSize() {
+	info, err := s.file.Stat()
+	if err != nil {
+		log.Printf("Failed to stat event store after a failed batch: %v", err)
+		return
+	}
+	if info.Size() > 0 {
+		s.logCodec = s.codec
+	}
+	s.size += info.Size() - s.segmentSize
+	s.segmentSize = info.Size()
+}
+
+// LastSequence returns the sequence number of the last persisted event (0 if empty)
+func (s *EventStore) LastSequence() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSeq
+}
+
+// FilePath returns the path of the event log, i.e. its first segment
+func (s *EventStore) FilePath() string {
+	return s.filePath
+}
+
+// SegmentPaths returns the files the log is stored in, oldest first
+func (s *EventStore) SegmentPaths() []string {
+	return s.segmentPaths()
+}
+
 // LoadAll reads all events from the event store
 func (s *EventStore) LoadAll() ([]domain.Event, error) {
-	file, err := os.Open(s.filePath)
+	sequenced, err := s.LoadSince(0)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []domain.Event{}, nil
-		}
-		return nil, fmt.Errorf("failed to open event store for reading: %w", err)
+		return nil, err
+	}
+
+	events := make([]domain.Event, len(sequenced))
+	for i, se := range sequenced {
+		events[i] = se.Event
+	}
+	return events, nil
+}
+
+// LoadSince reads all events with a sequence number greater than afterSeq.
+// Events written before sequencing was introduced are numbered by position.
+// When afterSeq is at or past the latest snapshot, reading starts at the
+// snapshot's offset instead of the beginning of the log.
+// A replicated store falls back to the replica when the primary cannot be read.
+func (s *EventStore) LoadSince(afterSeq uint64) ([]domain.SequencedEvent, error) {
+	events, err := s.loadLog(s.segmentPaths(), afterSeq)
+	if err != nil && s.replicaPath != "" {
+		log.Printf("Reading event store %s failed, failing over to replica %s: %v", s.filePath, s.replicaPath, err)
+		return s.loadLog([]string{s.replicaPath}, afterSeq)
+	}
+	return events, err
+}
+
+// LoadAllLenient reads all events of the log like LoadAll, but whatever the
+// replay mode skips the lines that fail their checksum or do not deserialize,
+// and returns them alongside. It reads the primary log only, as it is meant
+// for recovering a damaged one.
+func (s *EventStore) LoadAllLenient() ([]domain.Event, []CorruptLine, error) {
+	sequenced, corrupt, err := s.readLog(s.segmentPaths(), checkpoint{}, 0, ReplaySkip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make([]domain.Event, len(sequenced))
+	for i, se := range sequenced {
+		events[i] = se.Event
+	}
+	return events, corrupt, nil
+}
+
+// loadLog is LoadSince for one copy of the log, stored in paths
+func (s *EventStore) loadLog(paths []string, afterSeq uint64) ([]domain.SequencedEvent, error) {
+	from := s.checkpointFor(afterSeq)
+	events, _, err := s.readLog(paths, from, afterSeq, s.replayMode)
+	if errors.Is(err, errStaleCheckpoint) {
+		log.Printf("Snapshot offset %d does not match %s, reading it from the start", from.offset, paths[0])
+		s.mu.Lock()
+		s.checkpoint = checkpoint{}
+		s.mu.Unlock()
+		events, _, err = s.readLog(paths, checkpoint{}, afterSeq, s.replayMode)
+	}
+	return events, err
+}
+
+// readLog reads the events after afterSeq from paths, starting at from.
+// Lines that fail their checksum or do not deserialize fail the read in
+// ReplayStrict mode and are skipped and returned in ReplaySkip mode.
+func (s *EventStore) readLog(paths []string, from checkpoint, afterSeq uint64, mode ReplayMode) ([]domain.SequencedEvent, []CorruptLine, error) {
+	file, err := openLog(paths, from.offset)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer file.Close()
 
-	var events []domain.Event
-	scanner := bufio.NewScanner(file)
-	// Increase buffer size for potentially large events
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	var events []domain.SequencedEvent
+	var corrupt []CorruptLine
+	position := from.seq
+	records := file.records()
 
 	lineNum := 0
-	for scanner.Scan() {
+	for {
+		start := from.offset + records.offset
+		line, err := records.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// A binary record there means the offset is off its framing
+			if from.offset > 0 && position == from.seq && !isJSON(records.codec) {
+				return nil, nil, errStaleCheckpoint
+			}
+			return nil, nil, fmt.Errorf("error reading event store: %w", err)
+		}
 		lineNum++
-		line := scanner.Bytes()
 		if len(line) == 0 {
 			continue
 		}
 
-		event, err := domain.DeserializeEvent(line)
+		se, _, err := decodeRecord(records.codec, line)
 		if err != nil {
-			return nil, fmt.Errorf("failed to deserialize event at line %d: %w", lineNum, err)
+			if from.offset > 0 && position == from.seq && !isJSON(records.codec) {
+				return nil, nil, errStaleCheckpoint
+			}
+			where := fmt.Sprintf("line %d", lineNum)
+			if !isJSON(records.codec) {
+				where = fmt.Sprintf("record %d", lineNum)
+			}
+			if from.offset > 0 {
+				where += fmt.Sprintf(" after byte %d", from.offset)
+			}
+			if mode != ReplaySkip {
+				return nil, nil, fmt.Errorf("failed to deserialize event at %s: %w", where, err)
+			}
+			log.Printf("Skipping malformed event at %s: %v", where, err)
+			corrupt = append(corrupt, CorruptLine{Line: lineNum, Offset: start, Reason: err.Error()})
+			continue
 		}
 
-		events = append(events, event)
+		// The first event past the offset must follow the snapshot
+		if from.offset > 0 && position == from.seq && se.Sequence != 0 && se.Sequence != from.seq+1 {
+			return nil, nil, errStaleCheckpoint
+		}
+		position++
+		if se.Sequence == 0 {
+			se.Sequence = position
+		}
+		if se.Sequence > afterSeq {
+			events = append(events, se)
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading event store: %w", err)
-	}
+	s.mu.Lock()
+	s.corrupt = corrupt
+	s.mu.Unlock()
+	telemetry.EventStoreSkippedLines.Set(float64(len(corrupt)))
 
-	return events, nil
+	return events, corrupt, nil
 }
 
-// Close closes the event store file
+// SkippedLines returns how many malformed lines the last read skipped;
+// always 0 in strict mode
+func (s *EventStore) SkippedLines() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.corrupt)
+}
+
+// CorruptLines returns the lines the last read skipped; always empty in
+// strict mode
+func (s *EventStore) CorruptLines() []CorruptLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]CorruptLine(nil), s.corrupt...)
+}
+
+// Close closes the event store file and its replica
 func (s *EventStore) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.replica != nil {
+		s.replica.Close()
+	}
 	if s.file != nil {
 		return s.file.Close()
 	}
@@ -141,12 +463,34 @@ func (s *EventStore) Clear() error {
 		s.file.Close()
 	}
 
-	// Truncate the file
+	// Drop later segments and truncate the first
+	for n := 2; n <= s.segment; n++ {
+		if err := os.Remove(s.segmentPath(n)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear event store segment: %w", err)
+		}
+	}
 	file, err := os.OpenFile(s.filePath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to clear event store: %w", err)
 	}
 
 	s.file = file
+	s.lastSeq = 0
+	s.size = 0
+	s.segment = 1
+	s.segmentSize = 0
+	s.logCodec = domain.JSONCodec
+	s.checkpoint = checkpoint{}
+
+	if s.replica != nil {
+		if err := s.replica.Truncate(0); err != nil {
+			return fmt.Errorf("failed to clear replica: %w", err)
+		}
+	}
+
+	// A snapshot of the cleared events would be replayed on top of nothing
+	if err := os.Remove(s.SnapshotPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear snapshot: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,189 @@
+// Package grpc serves the wallet API over gRPC (see walletpb/wallet.proto),
+// next to the HTTP API in package handler
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/grpc/walletpb"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TransactionIDHeader is the response header carrying a transfer's
+// transaction ID, also when the transfer fails with an error status
+const TransactionIDHeader = "x-transaction-id"
+
+// Server implements walletpb.WalletServiceServer on the same dependencies
+// as the HTTP handler. Transfers go through handler.SubmitTransfer, so
+// they are validated, assigned transaction IDs and deduplicated exactly as
+// over HTTP, and the handler's readiness gate applies to both APIs.
+type Server struct {
+	walletpb.UnimplementedWalletServiceServer
+
+	handler      *handler.Handler
+	readModel    *cqrs.ReadModel
+	walletEngine *engine.WalletEngine
+}
+
+// NewServer creates a gRPC server for the wallet API
+func NewServer(h *handler.Handler, readModel *cqrs.ReadModel, walletEngine *engine.WalletEngine) *Server {
+	return &Server{
+		handler:      h,
+		readModel:    readModel,
+		walletEngine: walletEngine,
+	}
+}
+
+// NewGRPCServer returns a gRPC server with s registered and requests traced
+func NewGRPCServer(s *Server) *grpclib.Server {
+	srv := grpclib.NewServer(grpclib.UnaryInterceptor(Tracing()))
+	walletpb.RegisterWalletServiceServer(srv, s)
+	return srv
+}
+
+// Transfer mirrors POST /v1/wallet/transfer. Transfers the engine rejected,
+// such as for insufficient funds, are answered with Success false, like the
+// HTTP body; only a degraded or standby engine is an Unavailable error.
+func (s *Server) Transfer(ctx context.Context, req *walletpb.TransferRequest) (*walletpb.TransferResponse, error) {
+	if err := s.requireReady(); err != nil {
+		return nil, err
+	}
+
+	code, resp, err := s.handler.SubmitTransfer(handler.TransferRequest{
+		FromAccount:   req.GetFromAccount(),
+		ToAccount:     req.GetToAccount(),
+		Amount:        req.GetAmount(),
+		Currency:      req.GetCurrency(),
+		TransactionID: req.GetTransactionId(),
+		Mode:          domain.TransferMode(req.GetMode()),
+		Percent:       req.GetPercent(),
+		Priority:      req.GetPriority(),
+		Memo:          req.GetMemo(),
+	})
+	if err != nil {
+		var terr *handler.TransferError
+		if !errors.As(err, &terr) {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		setTransactionID(ctx, terr.TransactionID)
+		return nil, status.Error(codeForHTTP(terr.Status), terr.Message)
+	}
+
+	setTransactionID(ctx, resp.TransactionID)
+	if code == http.StatusServiceUnavailable {
+		return nil, status.Error(codes.Unavailable, resp.Message)
+	}
+	return &walletpb.TransferResponse{
+		TransactionId: resp.TransactionID,
+		Success:       resp.Success,
+		Message:       resp.Message,
+		Events:        resp.Events,
+		Amount:        resp.Amount,
+		Memo:          resp.Memo,
+		Duplicate:     resp.Duplicate,
+	}, nil
+}
+
+// GetBalance mirrors GET /v1/wallet/balance/:account_id
+func (s *Server) GetBalance(ctx context.Context, req *walletpb.GetBalanceRequest) (*walletpb.GetBalanceResponse, error) {
+	if err := s.requireReady(); err != nil {
+		return nil, err
+	}
+	if req.GetAccount() == "" {
+		return nil, status.Error(codes.InvalidArgument, "account is required")
+	}
+	account, err := s.accountPolicy().Normalize(req.GetAccount())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	currency := domain.CurrencyOrDefault(req.GetCurrency())
+	// Non-existent accounts have a 0 balance
+	balance, _ := s.readModel.GetBalance(account, currency)
+	return &walletpb.GetBalanceResponse{
+		Account:  account,
+		Currency: currency,
+		Balance:  balance,
+	}, nil
+}
+
+// GetAllBalances mirrors GET /v1/wallet/balances
+func (s *Server) GetAllBalances(ctx context.Context, req *walletpb.GetAllBalancesRequest) (*walletpb.GetAllBalancesResponse, error) {
+	if err := s.requireReady(); err != nil {
+		return nil, err
+	}
+	balances, total := s.readModel.GetBalancesSnapshot()
+	return &walletpb.GetAllBalancesResponse{
+		Balances:     balances,
+		TotalBalance: total,
+		AccountCount: int64(len(balances)),
+	}, nil
+}
+
+// InitAccount mirrors POST /v1/wallet/init (for testing purposes)
+func (s *Server) InitAccount(ctx context.Context, req *walletpb.InitAccountRequest) (*walletpb.InitAccountResponse, error) {
+	if err := s.requireReady(); err != nil {
+		return nil, err
+	}
+	if req.GetAccount() == "" {
+		return nil, status.Error(codes.InvalidArgument, "account is required")
+	}
+	if req.GetBalance() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "balance must not be negative")
+	}
+	account, err := s.accountPolicy().Normalize(req.GetAccount())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Update both the wallet engine (for validation) and read model (for queries)
+	s.walletEngine.SetBalance(account, req.GetBalance())
+	s.readModel.SetBalance(account, req.GetBalance())
+	return &walletpb.InitAccountResponse{Account: account, Balance: req.GetBalance()}, nil
+}
+
+// requireReady is the gRPC side of the handler's readiness gate
+func (s *Server) requireReady() error {
+	if !s.handler.IsReady() {
+		return status.Error(codes.Unavailable, "service is starting")
+	}
+	return nil
+}
+
+func (s *Server) accountPolicy() domain.AccountPolicy {
+	if s.walletEngine == nil {
+		return domain.DefaultAccountPolicy()
+	}
+	return s.walletEngine.AccountPolicy()
+}
+
+// setTransactionID sends txnID in TransactionIDHeader and records it on
+// the request's span
+func setTransactionID(ctx context.Context, txnID string) {
+	if txnID == "" {
+		return
+	}
+	grpclib.SetHeader(ctx, metadata.Pairs(TransactionIDHeader, txnID))
+	setSpanTransactionID(ctx, txnID)
+}
+
+// codeForHTTP maps the HTTP status of a rejected transfer to a gRPC code
+func codeForHTTP(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
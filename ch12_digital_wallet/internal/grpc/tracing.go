@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Tracing is the gRPC counterpart of middleware.Tracing: one server span per
+// call, continuing the caller's trace when the request metadata carries one
+func Tracing() grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, next grpclib.UnaryHandler) (interface{}, error) {
+		if telemetry.Tracer == nil {
+			return next(ctx, req)
+		}
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		}
+		service, method := splitMethod(info.FullMethod)
+		ctx, span := telemetry.Tracer.Start(ctx, "gRPC "+info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("rpc.system", "grpc"),
+				attribute.String("rpc.service", service),
+				attribute.String("rpc.method", method),
+			),
+		)
+		defer span.End()
+
+		start := time.Now()
+		resp, err := next(ctx, req)
+		duration := time.Since(start)
+
+		code := status.Code(err)
+		span.SetAttributes(
+			attribute.Int("rpc.grpc.status_code", int(code)),
+			attribute.Float64("rpc.duration_ms", float64(duration.Milliseconds())),
+		)
+		if err != nil {
+			span.SetStatus(otelcodes.Error, code.String())
+		} else {
+			span.SetStatus(otelcodes.Ok, "")
+		}
+		return resp, err
+	}
+}
+
+// setSpanTransactionID records a transfer's transaction ID on the span in ctx
+func setSpanTransactionID(ctx context.Context, txnID string) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("transaction_id", txnID))
+}
+
+// splitMethod splits "/wallet.v1.WalletService/Transfer" into service and method
+func splitMethod(fullMethod string) (string, string) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return service, method
+}
+
+// metadataCarrier adapts incoming gRPC metadata to a propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
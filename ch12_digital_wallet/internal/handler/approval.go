@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+)
+
+// PendingApprovalsResponse is the response body for the pending approvals endpoint
+type PendingApprovalsResponse struct {
+	Transfers []domain.TransferPendingApproval `json:"transfers"`
+	Count     int                              `json:"count"`
+}
+
+// ListPendingApprovals handles GET /v1/wallet/approvals
+func (h *Handler) ListPendingApprovals(c *gin.Context) {
+	pending := h.walletEngine.PendingApprovals()
+	c.JSON(http.StatusOK, PendingApprovalsResponse{Transfers: pending, Count: len(pending)})
+}
+
+// ApproveTransfer handles POST /v1/wallet/approve/:transaction_id
+func (h *Handler) ApproveTransfer(c *gin.Context) {
+	txnID := c.Param("transaction_id")
+	events, err := h.walletEngine.ApproveTransfer(c.Request.Context(), txnID)
+	if err != nil {
+		h.approvalError(c, txnID, err)
+		return
+	}
+
+	resp := TransferResponse{TransactionID: txnID, Success: true, Message: "transfer approved"}
+	for _, ev := range events {
+		resp.Events = append(resp.Events, ev.GetType())
+		switch ev := ev.(type) {
+		case domain.MoneyDeducted:
+			if ev.Fee {
+				resp.Fee = ev.Amount
+				continue
+			}
+			resp.Amount = ev.Amount
+			resp.Memo = ev.Memo
+		case domain.TransactionFailed:
+			resp.Success = false
+			resp.Message = ev.Reason
+			resp.Memo = ev.Memo
+		}
+	}
+	if !resp.Success {
+		c.JSON(http.StatusConflict, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RejectTransferRequest is the optional request body for the reject endpoint
+type RejectTransferRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RejectTransfer handles POST /v1/wallet/reject/:transaction_id
+func (h *Handler) RejectTransfer(c *gin.Context) {
+	txnID := c.Param("transaction_id")
+	var req RejectTransferRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.walletEngine.RejectTransfer(txnID, req.Reason); err != nil {
+		h.approvalError(c, txnID, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "transfer rejected", "transaction_id": txnID})
+}
+
+// approvalError maps an approve or reject failure to a response
+func (h *Handler) approvalError(c *gin.Context, txnID string, err error) {
+	switch {
+	case errors.Is(err, engine.ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "transaction_id": txnID})
+	case errors.Is(err, engine.ErrDegraded), errors.Is(err, engine.ErrStandby):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "transaction_id": txnID})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decide transfer", "transaction_id": txnID})
+	}
+}
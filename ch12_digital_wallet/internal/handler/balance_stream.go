@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"golang.org/x/net/websocket"
+)
+
+// balanceStreamWriteTimeout is how long one update may take to reach a
+// balance stream client before it is disconnected
+const balanceStreamWriteTimeout = 10 * time.Second
+
+// StreamBalance handles GET /v1/wallet/balance/:account_id/stream?currency=X
+//
+// It upgrades to a WebSocket, sends the account's current balance as a
+// cqrs.BalanceUpdate, then one more on every change of that balance in the
+// read model. A client that cannot keep up is disconnected; it reconnects
+// to get the current balance again.
+func (h *Handler) StreamBalance(c *gin.Context) {
+	accountID, err := h.accountPolicy().Normalize(c.Param("account_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	currency := domain.CurrencyOrDefault(c.Query("currency"))
+
+	// The server's timeouts would cut a long-lived stream; lift them for this connection
+	rc := http.NewResponseController(c.Writer)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Balance stream: cannot clear write deadline: %v", err)
+	}
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		log.Printf("Balance stream: cannot clear read deadline: %v", err)
+	}
+
+	// websocket.Server rather than websocket.Handler: no Origin check, like
+	// the rest of the API
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		h.serveBalanceStream(ws, accountID, currency)
+	}}.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveBalanceStream writes balance updates to ws until the client goes
+// away or is dropped for falling behind
+func (h *Handler) serveBalanceStream(ws *websocket.Conn, account, currency string) {
+	updates, unsubscribe := h.readModel.SubscribeBalance(account, currency)
+	defer unsubscribe()
+
+	// Reading is how a closed connection is noticed; what clients send is ignored
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var msg []byte
+		for websocket.Message.Receive(ws, &msg) == nil {
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case update, ok := <-updates:
+			if !ok {
+				log.Printf("Balance stream: dropped slow client of %s", account)
+				return
+			}
+			if err := ws.SetWriteDeadline(time.Now().Add(balanceStreamWriteTimeout)); err != nil {
+				return
+			}
+			if err := websocket.JSON.Send(ws, update); err != nil {
+				return
+			}
+		}
+	}
+}
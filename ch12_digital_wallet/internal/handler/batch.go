@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+)
+
+// BatchTransferRequest is the request body for the batch transfer endpoint
+type BatchTransferRequest struct {
+	Transfers []TransferRequest `json:"transfers" binding:"required,min=1,dive"`
+}
+
+// BatchTransferResponse is the response body for the batch transfer
+// endpoint: one result per transfer, in request order
+type BatchTransferResponse struct {
+	Results []TransferResponse `json:"results"`
+}
+
+// TransferBatch handles POST /v1/wallet/transfers/batch
+//
+// The transfers are published as one batch command, which the engine
+// processes in order and persists as a single write. Each transfer succeeds
+// or fails on its own, e.g. for insufficient funds, and a transaction ID
+// repeated in the batch is a duplicate of its first use. The response is
+// 200 with a result per transfer whenever the batch was processed; a
+// request with an invalid transfer is rejected as a whole before publishing.
+func (h *Handler) TransferBatch(c *gin.Context) {
+	var req BatchTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Transfers) > engine.MaxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": engine.ErrBatchTooLarge.Error()})
+		return
+	}
+
+	cmds := make([]domain.TransferCommand, len(req.Transfers))
+	for i, transfer := range req.Transfers {
+		if transfer.Priority {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("transfers[%d]: a batch cannot use the priority lane", i)})
+			return
+		}
+		cmd, err := h.transferCommand(transfer)
+		if err != nil {
+			var terr *TransferError
+			if !errors.As(err, &terr) {
+				terr = &TransferError{Status: http.StatusInternalServerError, Message: err.Error()}
+			}
+			c.JSON(terr.Status, gin.H{"error": fmt.Sprintf("transfers[%d]: %s", i, terr.Message)})
+			return
+		}
+		cmds[i] = cmd
+	}
+
+	resp, err := h.natsClient.PublishBatch(cmds, h.timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process batch"})
+		return
+	}
+	if !resp.Success {
+		status := http.StatusInternalServerError
+		if resp.Code == engine.CodeDegraded || resp.Code == engine.CodeStandby {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": resp.Error})
+		return
+	}
+	if len(resp.Results) != len(cmds) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "batch answered with the wrong number of results"})
+		return
+	}
+
+	results := make([]TransferResponse, len(cmds))
+	for i, cmd := range cmds {
+		_, results[i] = transferResult(cmd, &resp.Results[i])
+	}
+	c.JSON(http.StatusOK, BatchTransferResponse{Results: results})
+}
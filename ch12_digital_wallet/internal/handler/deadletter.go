@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/queue"
+)
+
+// DeadLetters gives access to dead-lettered commands.
+// *queue.DeadLetterLog implements it.
+type DeadLetters interface {
+	Entries() ([]queue.DeadLetterEntry, error)
+	Replay() (int, error)
+}
+
+// EnableDeadLetters configures the source behind the dead-letter endpoints
+func (h *Handler) EnableDeadLetters(deadLetters DeadLetters) {
+	h.deadLetters = deadLetters
+}
+
+// ListDeadLetters handles GET /v1/wallet/deadletters
+func (h *Handler) ListDeadLetters(c *gin.Context) {
+	if h.deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dead letters not enabled"})
+		return
+	}
+	entries, err := h.deadLetters.Entries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": entries, "count": len(entries)})
+}
+
+// ReplayDeadLetters handles POST /v1/wallet/deadletters/replay
+//
+// Every well-formed dead-lettered command is published again; the results
+// are not awaited. Already processed transactions are ignored by the engine.
+func (h *Handler) ReplayDeadLetters(c *gin.Context) {
+	if h.deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dead letters not enabled"})
+		return
+	}
+	replayed, err := h.deadLetters.Replay()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "replayed": replayed})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+)
+
+// CashRequest is the request body for the deposit and withdraw endpoints
+type CashRequest struct {
+	Account       string `json:"account" binding:"required"`
+	Amount        int64  `json:"amount" binding:"gt=0"`
+	Currency      string `json:"currency"`       // Defaults to USD
+	TransactionID string `json:"transaction_id"` // Optional, will be generated if not provided
+	Memo          string `json:"memo"`
+}
+
+// Deposit handles POST /v1/wallet/deposit
+func (h *Handler) Deposit(c *gin.Context) {
+	var req CashRequest
+	if !h.bindCashRequest(c, &req) {
+		return
+	}
+	events, err := h.walletEngine.Deposit(c.Request.Context(), domain.DepositCommand{
+		TransactionID: req.TransactionID,
+		Account:       req.Account,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Memo:          req.Memo,
+	})
+	h.respondCash(c, req.TransactionID, req.Memo, events, err, "deposit completed")
+}
+
+// Withdraw handles POST /v1/wallet/withdraw
+func (h *Handler) Withdraw(c *gin.Context) {
+	var req CashRequest
+	if !h.bindCashRequest(c, &req) {
+		return
+	}
+	events, err := h.walletEngine.Withdraw(c.Request.Context(), domain.WithdrawCommand{
+		TransactionID: req.TransactionID,
+		Account:       req.Account,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Memo:          req.Memo,
+	})
+	h.respondCash(c, req.TransactionID, req.Memo, events, err, "withdrawal completed")
+}
+
+// bindCashRequest parses and normalizes a deposit or withdraw request,
+// writing the error response when it is invalid
+func (h *Handler) bindCashRequest(c *gin.Context, req *CashRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	account, err := h.accountPolicy().Normalize(req.Account)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	req.Account = account
+	if req.TransactionID == "" {
+		req.TransactionID = uuid.Must(uuid.NewV7()).String()
+	}
+	return true
+}
+
+// respondCash writes the response for a processed deposit, withdrawal or
+// reversal. A duplicate is answered with the original outcome when it is
+// remembered.
+func (h *Handler) respondCash(c *gin.Context, txnID, memo string, events []domain.Event, err error, message string) {
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, engine.ErrDegraded) || errors.Is(err, engine.ErrStandby) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "transaction_id": txnID})
+		return
+	}
+
+	resp := TransferResponse{TransactionID: txnID, Success: true, Message: message, Memo: memo}
+	if len(events) == 0 {
+		resp.Duplicate = true
+		resp.Message = "transaction already processed"
+		if outcome, ok := h.walletEngine.Outcome(txnID); ok {
+			events = outcome.Events
+		}
+	}
+	for _, ev := range events {
+		resp.Events = append(resp.Events, ev.GetType())
+		switch ev := ev.(type) {
+		case domain.MoneyDeposited:
+			resp.Amount = ev.Amount
+		case domain.MoneyWithdrawn:
+			resp.Amount = ev.Amount
+		case domain.MoneyDeducted:
+			resp.Amount = ev.Amount
+		case domain.TransactionFailed:
+			resp.Success = false
+			resp.Message = ev.Reason
+		}
+	}
+	if !resp.Success {
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
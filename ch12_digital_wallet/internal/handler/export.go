@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventExporter writes persisted events in [from, to) in a flat format.
+// *eventstore.EventStore implements it.
+type EventExporter interface {
+	ExportCSV(w io.Writer, from, to time.Time) error
+}
+
+// EnableEventExport configures the source behind GET /v1/wallet/events/export
+func (h *Handler) EnableEventExport(exporter EventExporter) {
+	h.eventExporter = exporter
+}
+
+// ExportEvents handles GET /v1/wallet/events/export?format=csv&from=&to=
+//
+// from and to are RFC 3339 timestamps bounding the event time, from
+// inclusive and to exclusive; either may be omitted. Rows are streamed as the
+// log is read, so once the header is sent a read failure can only cut the
+// response short.
+func (h *Handler) ExportEvents(c *gin.Context) {
+	if h.eventExporter == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "event export not enabled"})
+		return
+	}
+
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format, want csv"})
+		return
+	}
+
+	var from, to time.Time
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		s := c.Query(bound.name)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bound.name + " must be an RFC 3339 timestamp"})
+			return
+		}
+		*bound.dst = t
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="wallet-events.csv"`)
+	c.Status(http.StatusOK)
+	if err := h.eventExporter.ExportCSV(c.Writer, from, to); err != nil {
+		log.Printf("Event export failed: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+)
+
+// FreezeRequest is the request body for the freeze and unfreeze endpoints
+type FreezeRequest struct {
+	Account string `json:"account" binding:"required"`
+	Reason  string `json:"reason"` // Freeze only, e.g. "fraud review"
+}
+
+// Freeze handles POST /v1/wallet/freeze
+// Transfers and withdrawals from the account fail with "account frozen"
+// until it is unfrozen; money can still come in.
+func (h *Handler) Freeze(c *gin.Context) {
+	req, ok := h.bindFreezeRequest(c)
+	if !ok {
+		return
+	}
+	err := h.walletEngine.Freeze(domain.FreezeCommand{Account: req.Account, Reason: req.Reason})
+	h.respondFreeze(c, req.Account, err, "account frozen", true)
+}
+
+// Unfreeze handles POST /v1/wallet/unfreeze
+func (h *Handler) Unfreeze(c *gin.Context) {
+	req, ok := h.bindFreezeRequest(c)
+	if !ok {
+		return
+	}
+	err := h.walletEngine.Unfreeze(domain.UnfreezeCommand{Account: req.Account})
+	h.respondFreeze(c, req.Account, err, "account unfrozen", false)
+}
+
+// bindFreezeRequest parses a freeze or unfreeze request and normalizes its
+// account, writing the error response when it is invalid
+func (h *Handler) bindFreezeRequest(c *gin.Context) (FreezeRequest, bool) {
+	var req FreezeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	account, err := h.accountPolicy().Normalize(req.Account)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	req.Account = account
+	return req, true
+}
+
+// respondFreeze writes the response of a freeze or unfreeze
+func (h *Handler) respondFreeze(c *gin.Context, account string, err error, message string, frozen bool) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"message": message,
+			"account": account,
+			"frozen":  frozen,
+		})
+	case errors.Is(err, engine.ErrDegraded), errors.Is(err, engine.ErrStandby):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update account freeze"})
+	}
+}
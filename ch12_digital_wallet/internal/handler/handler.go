@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -18,24 +22,46 @@ type Handler struct {
 	readModel    *cqrs.ReadModel
 	walletEngine *engine.WalletEngine
 	timeout      time.Duration
+
+	// ready gates balance and transfer endpoints during startup (see readiness.go)
+	ready atomic.Bool
+	// broker, when set, also makes /readyz fail while NATS is disconnected
+	broker BrokerStatus
+
+	// Event stream sources (see stream.go); nil until EnableEventStream
+	eventHistory cqrs.EventSource
+	eventFeed    EventFeed
+	// eventExporter backs the CSV export (see export.go); nil until EnableEventExport
+	eventExporter EventExporter
+	// deadLetters backs the dead-letter endpoints (see deadletter.go); nil until EnableDeadLetters
+	deadLetters DeadLetters
 }
 
 // NewHandler creates a new handler
 func NewHandler(natsClient *queue.NATSClient, readModel *cqrs.ReadModel, walletEngine *engine.WalletEngine) *Handler {
-	return &Handler{
+	h := &Handler{
 		natsClient:   natsClient,
 		readModel:    readModel,
 		walletEngine: walletEngine,
 		timeout:      5 * time.Second,
 	}
+	h.ready.Store(true)
+	return h
 }
 
 // TransferRequest is the request body for transfer endpoint
 type TransferRequest struct {
-	FromAccount   string `json:"from_account" binding:"required"`
-	ToAccount     string `json:"to_account" binding:"required"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	TransactionID string `json:"transaction_id"` // Optional, will be generated if not provided
+	FromAccount   string              `json:"from_account" binding:"required"`
+	ToAccount     string              `json:"to_account" binding:"required"`
+	Amount        int64               `json:"amount" binding:"gte=0"` // Required for exact mode
+	Currency      string              `json:"currency"`               // Both accounts' currency; defaults to USD
+	TransactionID string              `json:"transaction_id"`         // Optional, will be generated if not provided
+	Mode          domain.TransferMode `json:"mode"`                   // exact (default), all or percent
+	Percent       int64               `json:"percent"`                // 1-100, percent mode only
+	Priority      bool                `json:"priority"`               // Route to the priority lane
+	Memo          string              `json:"memo"`                   // Optional annotation, e.g. "invoice #123"
+	ExecuteAt     *time.Time          `json:"execute_at"`             // Optional RFC3339 time to run the transfer at
+	ScheduledAt   *time.Time          `json:"scheduled_at"`           // Older name for execute_at
 }
 
 // TransferResponse is the response body for transfer endpoint
@@ -44,6 +70,10 @@ type TransferResponse struct {
 	Success       bool     `json:"success"`
 	Message       string   `json:"message,omitempty"`
 	Events        []string `json:"events,omitempty"`
+	Amount        int64    `json:"amount,omitempty"` // Amount moved
+	Fee           int64    `json:"fee,omitempty"`    // Fee charged on top of Amount
+	Memo          string   `json:"memo,omitempty"`
+	Duplicate     bool     `json:"duplicate,omitempty"` // Already processed; the response repeats the original result
 }
 
 // Transfer handles POST /v1/wallet/transfer
@@ -56,6 +86,111 @@ func (h *Handler) Transfer(c *gin.Context) {
 		return
 	}
 
+	status, resp, err := h.SubmitTransfer(req)
+	if err != nil {
+		var terr *TransferError
+		if !errors.As(err, &terr) {
+			terr = &TransferError{Status: http.StatusInternalServerError, Message: err.Error()}
+		}
+		body := gin.H{"error": terr.Message}
+		if terr.TransactionID != "" {
+			body["transaction_id"] = terr.TransactionID
+		}
+		c.JSON(terr.Status, body)
+		return
+	}
+	c.JSON(status, resp)
+}
+
+// TransferError is a transfer that was not submitted: the request was
+// invalid, or the command could not be published. Status is the HTTP status
+// it is answered with.
+type TransferError struct {
+	Status        int
+	Message       string
+	TransactionID string // Set once the transfer was assigned an ID
+}
+
+func (e *TransferError) Error() string {
+	return e.Message
+}
+
+// SubmitTransfer validates req, publishes it as a transfer command and waits
+// for the engine's result. It is the transport-neutral part of Transfer, so
+// every API validates and deduplicates transfers the same way. The returned
+// status is the HTTP status of resp; transfers the engine rejected come back
+// with Success false rather than as an error, which is a *TransferError.
+func (h *Handler) SubmitTransfer(req TransferRequest) (int, TransferResponse, error) {
+	cmd, err := h.transferCommand(req)
+	if err != nil {
+		return 0, TransferResponse{}, err
+	}
+
+	// Publish command and wait for response
+	publish := h.natsClient.PublishCommand
+	if req.Priority {
+		publish = h.natsClient.PublishPriorityCommand
+	}
+	resp, err := publish(cmd, h.timeout)
+	if err != nil {
+		return 0, TransferResponse{}, &TransferError{
+			Status:        http.StatusInternalServerError,
+			Message:       "failed to process transfer",
+			TransactionID: cmd.TransactionID,
+		}
+	}
+
+	status, result := transferResult(cmd, resp)
+	return status, result, nil
+}
+
+// transferCommand validates req and turns it into a transfer command,
+// assigning a transaction ID if it has none. An invalid request is a
+// *TransferError.
+func (h *Handler) transferCommand(req TransferRequest) (domain.TransferCommand, error) {
+	invalid := func(message string) (domain.TransferCommand, error) {
+		return domain.TransferCommand{}, &TransferError{Status: http.StatusBadRequest, Message: message}
+	}
+
+	policy := h.accountPolicy()
+	from, err := policy.Normalize(req.FromAccount)
+	if err == nil {
+		req.ToAccount, err = policy.Normalize(req.ToAccount)
+	}
+	if err != nil {
+		return invalid(err.Error())
+	}
+	req.FromAccount = from
+
+	switch req.Mode {
+	case "", domain.TransferModeExact:
+		if req.Amount <= 0 {
+			return invalid("amount must be positive")
+		}
+		if h.walletEngine != nil {
+			if max := h.walletEngine.MaxTransferAmount(); max > 0 && req.Amount > max {
+				return invalid("amount exceeds maximum")
+			}
+		}
+	case domain.TransferModeAll:
+	case domain.TransferModePercent:
+		if req.Percent < 1 || req.Percent > 100 {
+			return invalid("percent must be between 1 and 100")
+		}
+	default:
+		return invalid("mode must be 'exact', 'all' or 'percent'")
+	}
+
+	if h.walletEngine != nil {
+		if max := h.walletEngine.MaxMemoLength(); max > 0 && utf8.RuneCountInString(req.Memo) > max {
+			return invalid(fmt.Sprintf("memo exceeds %d characters", max))
+		}
+	}
+
+	if req.Priority && (h.walletEngine == nil || !h.walletEngine.PriorityLaneEnabled()) {
+		return invalid("priority lane is not enabled")
+	}
+
 	// Generate transaction ID if not provided
 	txnID := req.TransactionID
 	if txnID == "" {
@@ -68,64 +203,116 @@ func (h *Handler) Transfer(c *gin.Context) {
 		FromAccount:   req.FromAccount,
 		ToAccount:     req.ToAccount,
 		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Mode:          req.Mode,
+		Percent:       req.Percent,
+		Memo:          req.Memo,
 	}
-
-	// Publish command and wait for response
-	resp, err := h.natsClient.PublishCommand(cmd, h.timeout)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":          "failed to process transfer",
-			"transaction_id": txnID,
-		})
-		return
+	if req.ExecuteAt != nil {
+		cmd.ExecuteAt = *req.ExecuteAt
+	} else if req.ScheduledAt != nil {
+		cmd.ExecuteAt = *req.ScheduledAt
 	}
+	return cmd, nil
+}
 
+// transferResult turns the engine's answer to cmd into the API response and
+// its HTTP status
+func transferResult(cmd domain.TransferCommand, resp *engine.CommandResponse) (int, TransferResponse) {
+	txnID := cmd.TransactionID
 	if !resp.Success {
-		c.JSON(http.StatusBadRequest, TransferResponse{
+		status := http.StatusBadRequest
+		if resp.Code == engine.CodeDegraded || resp.Code == engine.CodeStandby {
+			status = http.StatusServiceUnavailable
+		}
+		return status, TransferResponse{
 			TransactionID: txnID,
 			Success:       false,
 			Message:       resp.Error,
-		})
-		return
+			Events:        resp.Events,
+			Memo:          cmd.Memo,
+			Duplicate:     resp.Duplicate,
+		}
 	}
 
-	c.JSON(http.StatusOK, TransferResponse{
+	for _, ev := range resp.Events {
+		var message string
+		switch ev {
+		case domain.EventTypeTransferScheduled:
+			message = "transfer scheduled"
+		case domain.EventTypeTransferPendingApproval:
+			message = "transfer pending approval"
+		default:
+			continue
+		}
+		return http.StatusAccepted, TransferResponse{
+			TransactionID: txnID,
+			Success:       true,
+			Message:       message,
+			Events:        resp.Events,
+			Memo:          cmd.Memo,
+			Duplicate:     resp.Duplicate,
+		}
+	}
+
+	message := "transfer completed"
+	if resp.Duplicate {
+		message = "transfer already processed"
+	}
+	return http.StatusOK, TransferResponse{
 		TransactionID: txnID,
 		Success:       true,
-		Message:       "transfer completed",
+		Message:       message,
 		Events:        resp.Events,
-	})
+		Amount:        resp.Amount,
+		Fee:           resp.Fee,
+		Memo:          cmd.Memo,
+		Duplicate:     resp.Duplicate,
+	}
 }
 
 // BalanceResponse is the response body for balance endpoint
 type BalanceResponse struct {
-	Account string `json:"account"`
-	Balance int64  `json:"balance"`
+	Account  string `json:"account"`
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+	Frozen   bool   `json:"frozen"` // Money cannot leave the account (see freeze.go)
 }
 
-// GetBalance handles GET /v1/wallet/balance/:account_id
+// GetBalance handles GET /v1/wallet/balance/:account_id?currency=EUR
+// (currency defaults to USD)
 func (h *Handler) GetBalance(c *gin.Context) {
-	accountID := c.Param("account_id")
-	if accountID == "" {
+	if c.Param("account_id") == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "account_id is required",
 		})
 		return
 	}
+	accountID, err := h.accountPolicy().Normalize(c.Param("account_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	balance, exists := h.readModel.GetBalance(accountID)
+	currency := domain.CurrencyOrDefault(c.Query("currency"))
+	balance, exists := h.readModel.GetBalance(accountID, currency)
+	frozen := h.readModel.IsFrozen(accountID)
 	if !exists {
 		// Return 0 balance for non-existent accounts
 		c.JSON(http.StatusOK, BalanceResponse{
-			Account: accountID,
-			Balance: 0,
+			Account:  accountID,
+			Currency: currency,
+			Balance:  0,
+			Frozen:   frozen,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, BalanceResponse{
-		Account: accountID,
-		Balance: balance,
+		Account:  accountID,
+		Currency: currency,
+		Balance:  balance,
+		Frozen:   frozen,
 	})
 }
 
@@ -138,8 +325,7 @@ type AllBalancesResponse struct {
 
 // GetAllBalances handles GET /v1/wallet/balances
 func (h *Handler) GetAllBalances(c *gin.Context) {
-	balances := h.readModel.GetAllBalances()
-	total := h.readModel.GetTotalBalance()
+	balances, total := h.readModel.GetBalancesSnapshot()
 
 	c.JSON(http.StatusOK, AllBalancesResponse{
 		Balances:     balances,
@@ -152,14 +338,31 @@ func (h *Handler) GetAllBalances(c *gin.Context) {
 type HealthResponse struct {
 	Status string `json:"status"`
 	Time   string `json:"time"`
+	Reason string `json:"reason,omitempty"`
 }
 
 // Health handles GET /health
+// A degraded engine still reports 200 because balance queries keep working;
+// the status field tells operators that transfers are being rejected.
+// Likewise a starting service reports 200 with status "starting".
 func (h *Handler) Health(c *gin.Context) {
-	c.JSON(http.StatusOK, HealthResponse{
+	resp := HealthResponse{
 		Status: "ok",
 		Time:   time.Now().UTC().Format(time.RFC3339),
-	})
+	}
+	if !h.IsReady() {
+		resp.Status = "starting"
+		resp.Reason = "replaying events, balance and transfer endpoints rejected"
+		if h.walletEngine != nil {
+			if progress := h.walletEngine.ReplayProgress(); progress.Replaying {
+				resp.Reason = progress.String() + ", balance and transfer endpoints rejected"
+			}
+		}
+	} else if h.walletEngine != nil && h.walletEngine.IsDegraded() {
+		resp.Status = "degraded"
+		resp.Reason = "event store writes failing, transfers rejected"
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // InitAccountRequest is the request body for account initialization
@@ -178,6 +381,13 @@ func (h *Handler) InitAccount(c *gin.Context) {
 		return
 	}
 
+	account, err := h.accountPolicy().Normalize(req.Account)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Account = account
+
 	// Update both the wallet engine (for validation) and read model (for queries)
 	h.walletEngine.SetBalance(req.Account, req.Balance)
 	h.readModel.SetBalance(req.Account, req.Balance)
@@ -189,17 +399,46 @@ func (h *Handler) InitAccount(c *gin.Context) {
 	})
 }
 
+// accountPolicy returns the engine's account naming policy, so IDs are
+// normalized the same way here as in the engine
+func (h *Handler) accountPolicy() domain.AccountPolicy {
+	if h.walletEngine == nil {
+		return domain.DefaultAccountPolicy()
+	}
+	return h.walletEngine.AccountPolicy()
+}
+
 // SetupRoutes configures all API routes
 func SetupRoutes(r *gin.Engine, h *Handler) {
 	// Health check
 	r.GET("/health", h.Health)
+	r.GET("/readyz", h.Readyz)
 
 	// API v1
 	v1 := r.Group("/v1/wallet")
 	{
-		v1.POST("/transfer", h.Transfer)
-		v1.GET("/balance/:account_id", h.GetBalance)
-		v1.GET("/balances", h.GetAllBalances)
-		v1.POST("/init", h.InitAccount) // For testing
+		v1.POST("/transfer", h.requireReady, h.Transfer)
+		v1.POST("/transfers/batch", h.requireReady, h.TransferBatch)
+		v1.POST("/deposit", h.requireReady, h.Deposit)
+		v1.POST("/withdraw", h.requireReady, h.Withdraw)
+		v1.POST("/reverse", h.requireReady, h.Reverse)
+		v1.GET("/balance/:account_id", h.requireReady, h.GetBalance)
+		v1.GET("/balance/:account_id/stream", h.requireReady, h.StreamBalance)
+		v1.GET("/balances", h.requireReady, h.GetAllBalances)
+		v1.GET("/history/:account_id", h.requireReady, h.GetHistory)
+		v1.GET("/transaction/:transaction_id", h.GetTransaction)
+		v1.GET("/deadletters", h.ListDeadLetters)
+		v1.POST("/deadletters/replay", h.ReplayDeadLetters)
+		v1.GET("/scheduled", h.requireReady, h.ListScheduledTransfers)
+		v1.DELETE("/scheduled/:transaction_id", h.requireReady, h.CancelScheduledTransfer)
+		v1.GET("/approvals", h.requireReady, h.ListPendingApprovals)
+		v1.POST("/approve/:transaction_id", h.requireReady, h.ApproveTransfer)
+		v1.POST("/reject/:transaction_id", h.requireReady, h.RejectTransfer)
+		v1.POST("/overdraft", h.requireReady, h.SetOverdraft)
+		v1.POST("/freeze", h.requireReady, h.Freeze)
+		v1.POST("/unfreeze", h.requireReady, h.Unfreeze)
+		v1.POST("/init", h.requireReady, h.InitAccount) // For testing
+		v1.GET("/events/stream", h.StreamEvents)
+		v1.GET("/events/export", h.ExportEvents)
 	}
 }
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+)
+
+// defaultHistoryLimit is how many entries the history endpoint returns when
+// the request does not set a limit
+const defaultHistoryLimit = 50
+
+// HistoryResponse is the response body for the history endpoint
+type HistoryResponse struct {
+	Account string              `json:"account"`
+	Entries []cqrs.HistoryEntry `json:"entries"`
+}
+
+// GetHistory handles GET /v1/wallet/history/:account_id?limit=50&offset=0
+//
+// Entries are the account's balance changes, newest first, as far back as
+// the read model retains them.
+func (h *Handler) GetHistory(c *gin.Context) {
+	accountID, err := h.accountPolicy().Normalize(c.Param("account_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset := defaultHistoryLimit, 0
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &limit}, {"offset", &offset}} {
+		s := c.Query(param.name)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param.name + " must be a non-negative integer"})
+			return
+		}
+		*param.dst = v
+	}
+
+	c.JSON(http.StatusOK, HistoryResponse{
+		Account: accountID,
+		Entries: h.readModel.GetHistory(accountID, limit, offset),
+	})
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+)
+
+// OverdraftRequest is the request body for the overdraft endpoint
+type OverdraftRequest struct {
+	Account string `json:"account" binding:"required"`
+	Limit   int64  `json:"limit" binding:"gte=0"` // How far the balance may go below zero; 0 removes the overdraft
+}
+
+// SetOverdraft handles POST /v1/wallet/overdraft
+func (h *Handler) SetOverdraft(c *gin.Context) {
+	var req OverdraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := h.accountPolicy().Normalize(req.Account)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.walletEngine.SetOverdraftLimit(account, req.Limit)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"message": "overdraft limit set",
+			"account": account,
+			"limit":   req.Limit,
+		})
+	case errors.Is(err, engine.ErrDegraded), errors.Is(err, engine.ErrStandby):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set overdraft limit"})
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// notReadyRetryAfter is the Retry-After hint, in seconds, sent with 503s
+// while the service is still starting
+const notReadyRetryAfter = "1"
+
+// SetReady opens or closes the readiness gate. While closed, balance and
+// transfer endpoints answer 503 instead of serving a read model or engine
+// that is still replaying. A new handler starts ready; the server closes the
+// gate before listening and opens it once the engine and read model have
+// been initialized and started.
+func (h *Handler) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// IsReady reports whether the readiness gate is open
+func (h *Handler) IsReady() bool {
+	return h.ready.Load()
+}
+
+// BrokerStatus reports whether the message broker connection is up
+type BrokerStatus interface {
+	IsConnected() bool
+}
+
+// SetBrokerStatus makes /readyz report not-ready while the broker is
+// disconnected, so orchestration stops routing transfers that would time out.
+// Pass nil to ignore the broker.
+func (h *Handler) SetBrokerStatus(status BrokerStatus) {
+	h.broker = status
+}
+
+// Readyz handles GET /readyz
+// Unlike /health it answers 503 whenever the service cannot take traffic:
+// while starting, or while the broker is disconnected.
+func (h *Handler) Readyz(c *gin.Context) {
+	resp := HealthResponse{
+		Status: "ready",
+		Time:   time.Now().UTC().Format(time.RFC3339),
+	}
+	switch {
+	case !h.IsReady():
+		resp.Status = "not_ready"
+		resp.Reason = "replaying events"
+	case h.broker != nil && !h.broker.IsConnected():
+		resp.Status = "not_ready"
+		resp.Reason = "NATS disconnected"
+	default:
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	c.Header("Retry-After", notReadyRetryAfter)
+	c.JSON(http.StatusServiceUnavailable, resp)
+}
+
+// requireReady rejects the request with 503 while the readiness gate is closed
+func (h *Handler) requireReady(c *gin.Context) {
+	if !h.ready.Load() {
+		c.Header("Retry-After", notReadyRetryAfter)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": "service is starting, state is still being rebuilt",
+		})
+		return
+	}
+	c.Next()
+}
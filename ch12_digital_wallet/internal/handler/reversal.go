@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// ReverseRequest is the request body for the reverse endpoint
+type ReverseRequest struct {
+	OriginalTransactionID string `json:"original_transaction_id" binding:"required"`
+	TransactionID         string `json:"transaction_id"` // Optional, will be generated if not provided
+}
+
+// Reverse handles POST /v1/wallet/reverse
+func (h *Handler) Reverse(c *gin.Context) {
+	var req ReverseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TransactionID == "" {
+		req.TransactionID = uuid.Must(uuid.NewV7()).String()
+	}
+
+	events, err := h.walletEngine.Reverse(c.Request.Context(), domain.ReverseCommand{
+		TransactionID:         req.TransactionID,
+		OriginalTransactionID: req.OriginalTransactionID,
+	})
+	h.respondCash(c, req.TransactionID, "", events, err, "transfer reversed")
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+)
+
+// ScheduledTransfersResponse is the response body for the scheduled transfers endpoint
+type ScheduledTransfersResponse struct {
+	Transfers []domain.TransferScheduled `json:"transfers"`
+	Count     int                        `json:"count"`
+}
+
+// ListScheduledTransfers handles GET /v1/wallet/scheduled
+func (h *Handler) ListScheduledTransfers(c *gin.Context) {
+	pending := h.walletEngine.ScheduledTransfers()
+	c.JSON(http.StatusOK, ScheduledTransfersResponse{Transfers: pending, Count: len(pending)})
+}
+
+// CancelScheduledTransfer handles DELETE /v1/wallet/scheduled/:transaction_id
+func (h *Handler) CancelScheduledTransfer(c *gin.Context) {
+	txnID := c.Param("transaction_id")
+	err := h.walletEngine.CancelScheduledTransfer(txnID)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "scheduled transfer canceled", "transaction_id": txnID})
+	case errors.Is(err, engine.ErrScheduledTransferNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "transaction_id": txnID})
+	case errors.Is(err, engine.ErrDegraded), errors.Is(err, engine.ErrStandby):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "transaction_id": txnID})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel scheduled transfer", "transaction_id": txnID})
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// streamBufferSize bounds live events queued per stream client. On overflow,
+// events are dropped and the gap is refilled from the event store.
+const streamBufferSize = 256
+
+// EventFeed delivers live sequenced events. *queue.NATSClient implements it
+// by subscribing to the engine's event subject.
+type EventFeed interface {
+	SubscribeEvents(fn func(domain.SequencedEvent)) (unsubscribe func(), err error)
+}
+
+// EnableEventStream configures the history source and live feed behind
+// GET /v1/wallet/events/stream
+func (h *Handler) EnableEventStream(history cqrs.EventSource, live EventFeed) {
+	h.eventHistory = history
+	h.eventFeed = live
+}
+
+// StreamEvent is one SSE payload: an event with its global sequence
+type StreamEvent struct {
+	Sequence uint64       `json:"seq"`
+	Type     string       `json:"type"`
+	Data     domain.Event `json:"data"`
+}
+
+// StreamEvents handles GET /v1/wallet/events/stream?from_seq=N
+//
+// It sends every persisted event with sequence >= from_seq as Server-Sent
+// Events, then tails live events on the same connection. The SSE id is the
+// event sequence, so a consumer resumes by reconnecting with from_seq=last+1.
+//
+// The live feed is subscribed before history is read, so nothing can fall
+// between the two; overlapping events are skipped by sequence, and a jump in
+// the live sequence is refilled from the event store.
+func (h *Handler) StreamEvents(c *gin.Context) {
+	if h.eventHistory == nil || h.eventFeed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "event stream not enabled"})
+		return
+	}
+
+	var fromSeq uint64
+	if s := c.Query("from_seq"); s != "" {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from_seq must be a non-negative integer"})
+			return
+		}
+		fromSeq = v
+	}
+
+	live := make(chan domain.SequencedEvent, streamBufferSize)
+	unsubscribe, err := h.eventFeed.SubscribeEvents(func(ev domain.SequencedEvent) {
+		select {
+		case live <- ev:
+		default:
+			// Slow client: drop, the gap is refilled from the store
+		}
+	})
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	defer unsubscribe()
+
+	// lastSent is the sequence the client has seen; history starts after it
+	var lastSent uint64
+	if fromSeq > 0 {
+		lastSent = fromSeq - 1
+	}
+
+	// The server's WriteTimeout would cut a long-lived stream; lift it for this response
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Event stream: cannot clear write deadline: %v", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	if !h.sendHistory(c, &lastSent) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-live:
+			if ev.Sequence <= lastSent {
+				continue
+			}
+			if ev.Sequence > lastSent+1 {
+				// Missed live events: catch up from the store, which includes ev
+				if !h.sendHistory(c, &lastSent) {
+					return
+				}
+				continue
+			}
+			writeStreamEvent(c, ev)
+			lastSent = ev.Sequence
+		}
+	}
+}
+
+// sendHistory writes all persisted events after *lastSent and advances it.
+// Returns false if the store could not be read.
+func (h *Handler) sendHistory(c *gin.Context, lastSent *uint64) bool {
+	events, err := h.eventHistory.LoadSince(*lastSent)
+	if err != nil {
+		log.Printf("Event stream: failed to load history: %v", err)
+		c.SSEvent("error", gin.H{"error": "failed to load event history"})
+		c.Writer.Flush()
+		return false
+	}
+
+	for _, ev := range events {
+		writeStreamEvent(c, ev)
+		*lastSent = ev.Sequence
+	}
+	return true
+}
+
+// writeStreamEvent writes one SSE frame: id is the sequence, event the type
+func writeStreamEvent(c *gin.Context, ev domain.SequencedEvent) {
+	data, err := json.Marshal(StreamEvent{
+		Sequence: ev.Sequence,
+		Type:     ev.Event.GetType(),
+		Data:     ev.Event,
+	})
+	if err != nil {
+		log.Printf("Event stream: failed to marshal event %d: %v", ev.Sequence, err)
+		return
+	}
+
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", ev.Sequence, ev.Event.GetType(), data)
+	c.Writer.Flush()
+}
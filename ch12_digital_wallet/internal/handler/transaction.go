@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+)
+
+// TransactionResponse is the response body for the transaction lookup endpoint
+type TransactionResponse struct {
+	TransactionID string        `json:"transaction_id"`
+	Success       bool          `json:"success"`
+	Memo          string        `json:"memo,omitempty"`
+	Events        []StreamEvent `json:"events"`
+}
+
+// GetTransaction handles GET /v1/wallet/transaction/:transaction_id
+//
+// It reads the transaction's events from the event store, so it scans the
+// whole log; it is meant for occasional lookups, not hot paths. Uses the
+// history source configured by EnableEventStream.
+func (h *Handler) GetTransaction(c *gin.Context) {
+	if h.eventHistory == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "event history not enabled"})
+		return
+	}
+
+	txnID := c.Param("transaction_id")
+	events, err := h.eventHistory.LoadSince(0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read event store"})
+		return
+	}
+
+	resp := TransactionResponse{TransactionID: txnID, Success: true}
+	for _, ev := range events {
+		if ev.Event.GetTransactionID() != txnID {
+			continue
+		}
+		resp.Events = append(resp.Events, StreamEvent{Sequence: ev.Sequence, Type: ev.Event.GetType(), Data: ev.Event})
+		switch e := ev.Event.(type) {
+		case domain.MoneyDeducted:
+			if !e.Fee {
+				resp.Memo = e.Memo
+			}
+		case domain.MoneyDeposited:
+			resp.Memo = e.Memo
+		case domain.MoneyWithdrawn:
+			resp.Memo = e.Memo
+		case domain.TransferScheduled:
+			resp.Memo = e.Memo
+		case domain.TransactionFailed:
+			resp.Success = false
+			resp.Memo = e.Memo
+		}
+	}
+	if len(resp.Events) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found", "transaction_id": txnID})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
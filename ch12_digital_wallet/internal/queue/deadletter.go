@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nats-io/nats.go"
+)
+
+// DeadLetterEntry is one dead-lettered command in the dead-letter log
+type DeadLetterEntry struct {
+	ReceivedAt time.Time `json:"received_at"`
+	Subject    string    `json:"subject"` // Subject the command was sent on
+	Reason     string    `json:"reason"`
+	Deliveries uint64    `json:"deliveries"`
+	Data       string    `json:"data"` // The command as sent, which may not be valid JSON
+}
+
+// DeadLetterLog consumes engine.DeadLetterSubject into an append-only file
+// of JSON lines, so dead-lettered commands can be inspected and replayed
+type DeadLetterLog struct {
+	client *NATSClient
+	path   string
+
+	mu   sync.Mutex
+	file *os.File
+	sub  *nats.Subscription
+}
+
+// NewDeadLetterLog opens (or creates) the dead-letter log at path. client
+// is used to subscribe and to replay.
+func NewDeadLetterLog(path string, client *NATSClient) (*DeadLetterLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter log: %w", err)
+	}
+	return &DeadLetterLog{client: client, path: path, file: file}, nil
+}
+
+// Start subscribes to engine.DeadLetterSubject
+func (d *DeadLetterLog) Start() error {
+	sub, err := d.client.conn.Subscribe(engine.DeadLetterSubject, d.handle)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to dead letters: %w", err)
+	}
+	d.mu.Lock()
+	d.sub = sub
+	d.mu.Unlock()
+	return nil
+}
+
+// Close unsubscribes and closes the log file
+func (d *DeadLetterLog) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sub != nil {
+		d.sub.Unsubscribe()
+		d.sub = nil
+	}
+	return d.file.Close()
+}
+
+func (d *DeadLetterLog) handle(msg *nats.Msg) {
+	entry := DeadLetterEntry{
+		ReceivedAt: time.Now().UTC(),
+		Subject:    msg.Header.Get(engine.DeadLetterSubjectHeader),
+		Reason:     msg.Header.Get(engine.DeadLetterReasonHeader),
+		Data:       string(msg.Data),
+	}
+	entry.Deliveries, _ = strconv.ParseUint(msg.Header.Get(engine.DeadLetterDeliveriesHeader), 10, 64)
+	if err := d.Append(entry); err != nil {
+		log.Printf("Failed to record dead letter: %v", err)
+	}
+}
+
+// Append writes entry to the log
+func (d *DeadLetterLog) Append(entry DeadLetterEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err = d.file.Write(append(line, '\n'))
+	return err
+}
+
+// Entries reads every entry in the log, oldest first
+func (d *DeadLetterLog) Entries() ([]DeadLetterEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	file, err := os.Open(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter log: %w", err)
+	}
+	defer file.Close()
+
+	entries := []DeadLetterEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry DeadLetterEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("malformed dead-letter entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Replay publishes every entry that holds a well-formed command again,
+// without waiting for results, and returns how many it published. Commands
+// are deduplicated by transaction ID, so replaying one that meanwhile
+// succeeded does nothing. The log is kept as it is.
+func (d *DeadLetterLog) Replay() (int, error) {
+	entries, err := d.Entries()
+	if err != nil {
+		return 0, err
+	}
+
+	var replayed int
+	var errs []error
+	for _, entry := range entries {
+		if !json.Valid([]byte(entry.Data)) {
+			continue
+		}
+		if err := d.client.publishRaw([]byte(entry.Data)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		replayed++
+	}
+	return replayed, errors.Join(errs...)
+}
@@ -2,7 +2,9 @@ package queue
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -12,23 +14,44 @@ import (
 
 // NATSClient wraps NATS connection for command publishing
 type NATSClient struct {
-	conn *nats.Conn
+	conn   *nats.Conn
+	status *ConnectionStatus
+	js     nats.JetStreamContext // set when commands go through JetStream
+
+
+	slowMu       sync.Mutex
+	slowHandlers []func(sub *nats.Subscription)
 }
 
 // NewNATSClient creates a new NATS client
 func NewNATSClient(url string) (*NATSClient, error) {
+	status := NewConnectionStatus(false)
+	client := &NATSClient{status: status}
 	opts := []nats.Option{
 		nats.Name("digital-wallet"),
 		nats.ReconnectWait(time.Second),
 		nats.MaxReconnects(10),
 		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+			status.Set(false)
 			if err != nil {
 				fmt.Printf("NATS disconnected: %v\n", err)
 			}
 		}),
 		nats.ReconnectHandler(func(nc *nats.Conn) {
+			status.Set(true)
 			fmt.Printf("NATS reconnected to %s\n", nc.ConnectedUrl())
 		}),
+		// Reconnect attempts are exhausted; the connection stays down
+		nats.ClosedHandler(func(nc *nats.Conn) {
+			status.Set(false)
+		}),
+		nats.ErrorHandler(func(nc *nats.Conn, sub *nats.Subscription, err error) {
+			if errors.Is(err, nats.ErrSlowConsumer) {
+				client.notifySlowConsumer(sub)
+				return
+			}
+			fmt.Printf("NATS error: %v\n", err)
+		}),
 	}
 
 	conn, err := nats.Connect(url, opts...)
@@ -36,7 +59,28 @@ func NewNATSClient(url string) (*NATSClient, error) {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
-	return &NATSClient{conn: conn}, nil
+	status.Set(conn.IsConnected())
+
+	client.conn = conn
+	return client, nil
+}
+
+// OnSlowConsumer registers a callback for subscriptions that fell behind and
+// had messages dropped by NATS
+func (c *NATSClient) OnSlowConsumer(handler func(sub *nats.Subscription)) {
+	c.slowMu.Lock()
+	defer c.slowMu.Unlock()
+	c.slowHandlers = append(c.slowHandlers, handler)
+}
+
+func (c *NATSClient) notifySlowConsumer(sub *nats.Subscription) {
+	c.slowMu.Lock()
+	handlers := append([]func(sub *nats.Subscription){}, c.slowHandlers...)
+	c.slowMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(sub)
+	}
 }
 
 // GetConn returns the underlying NATS connection
@@ -44,24 +88,104 @@ func (c *NATSClient) GetConn() *nats.Conn {
 	return c.conn
 }
 
+// Status returns the live connection status, updated by the disconnect and
+// reconnect handlers
+func (c *NATSClient) Status() *ConnectionStatus {
+	return c.status
+}
+
+// IsConnected reports whether the NATS connection is currently up
+func (c *NATSClient) IsConnected() bool {
+	return c.status.IsConnected()
+}
+
+// EnableJetStream makes the client publish commands to the engine's
+// JetStream command stream, creating it if needed, instead of sending core
+// NATS requests. A command is then kept until the engine processes it, even
+// when no response arrives before the timeout.
+func (c *NATSClient) EnableJetStream() error {
+	js, err := c.conn.JetStream()
+	if err != nil {
+		return fmt.Errorf("failed to open JetStream: %w", err)
+	}
+	if err := engine.EnsureCommandStream(js); err != nil {
+		return err
+	}
+	c.js = js
+	return nil
+}
+
 // PublishCommand publishes a transfer command and waits for response
 func (c *NATSClient) PublishCommand(cmd domain.TransferCommand, timeout time.Duration) (*engine.CommandResponse, error) {
+	return c.request(engine.CommandSubject, cmd, timeout)
+}
+
+// PublishPriorityCommand publishes a transfer command on the priority lane
+// and waits for response
+func (c *NATSClient) PublishPriorityCommand(cmd domain.TransferCommand, timeout time.Duration) (*engine.CommandResponse, error) {
+	return c.request(engine.PriorityCommandSubject, cmd, timeout)
+}
+
+func (c *NATSClient) request(subject string, cmd domain.TransferCommand, timeout time.Duration) (*engine.CommandResponse, error) {
+	var resp engine.CommandResponse
+	if err := c.roundTrip(subject, cmd, &resp, timeout); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PublishBatch publishes transfer commands as one batch and waits for the
+// per-transfer results. The engine processes the batch in order as a
+// single write (see engine.WalletEngine.ProcessBatch).
+func (c *NATSClient) PublishBatch(cmds []domain.TransferCommand, timeout time.Duration) (*engine.BatchResponse, error) {
+	var resp engine.BatchResponse
+	if err := c.roundTrip(engine.CommandSubject, cmds, &resp, timeout); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// roundTrip sends a command, or a batch of them, and decodes the answer into resp
+func (c *NATSClient) roundTrip(subject string, cmd any, resp any, timeout time.Duration) error {
 	data, err := json.Marshal(cmd)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal command: %w", err)
+		return fmt.Errorf("failed to marshal command: %w", err)
 	}
 
-	msg, err := c.conn.Request(engine.CommandSubject, data, timeout)
+	var msg *nats.Msg
+	if c.js != nil {
+		msg, err = c.requestStream(data, timeout)
+	} else {
+		msg, err = c.conn.Request(subject, data, timeout)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to publish command: %w", err)
+		return fmt.Errorf("failed to publish command: %w", err)
 	}
 
-	var resp engine.CommandResponse
-	if err := json.Unmarshal(msg.Data, &resp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := json.Unmarshal(msg.Data, resp); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	return nil
+}
 
-	return &resp, nil
+// requestStream stores a command in the command stream and waits for the
+// engine's answer on an inbox named in engine.ReplyHeader. Commands have a
+// single stream, so priority commands are queued with the others.
+func (c *NATSClient) requestStream(data []byte, timeout time.Duration) (*nats.Msg, error) {
+	inbox := nats.NewInbox()
+	sub, err := c.conn.SubscribeSync(inbox)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+
+	msg := nats.NewMsg(engine.StreamCommandSubject)
+	msg.Data = data
+	msg.Header.Set(engine.ReplyHeader, inbox)
+	if _, err := c.js.PublishMsg(msg, nats.AckWait(timeout)); err != nil {
+		return nil, err
+	}
+	return sub.NextMsg(timeout)
 }
 
 // PublishCommandAsync publishes a transfer command without waiting for response
@@ -70,8 +194,18 @@ func (c *NATSClient) PublishCommandAsync(cmd domain.TransferCommand) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal command: %w", err)
 	}
+	return c.publishRaw(data)
+}
 
-	if err := c.conn.Publish(engine.CommandSubject, data); err != nil {
+// publishRaw publishes an encoded command without waiting for response
+func (c *NATSClient) publishRaw(data []byte) error {
+	var err error
+	if c.js != nil {
+		_, err = c.js.Publish(engine.StreamCommandSubject, data)
+	} else {
+		err = c.conn.Publish(engine.CommandSubject, data)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to publish command: %w", err)
 	}
 
@@ -85,3 +219,20 @@ func (c *NATSClient) Close() {
 		c.conn.Close()
 	}
 }
+
+// SubscribeEvents delivers events published by the wallet engine to fn
+func (c *NATSClient) SubscribeEvents(fn func(domain.SequencedEvent)) (func(), error) {
+	sub, err := c.conn.Subscribe(engine.EventSubject, func(msg *nats.Msg) {
+		event, err := domain.DeserializeSequencedEvent(msg.Data)
+		if err != nil {
+			fmt.Printf("Failed to deserialize streamed event: %v\n", err)
+			return
+		}
+		fn(event)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+
+	return func() { sub.Unsubscribe() }, nil
+}
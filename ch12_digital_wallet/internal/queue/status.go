@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"sync/atomic"
+
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+)
+
+// ConnectionStatus tracks whether the broker connection is up. Every change
+// is mirrored to the wallet_nats_connected gauge.
+type ConnectionStatus struct {
+	connected atomic.Bool
+}
+
+// NewConnectionStatus creates a status with the given initial state
+func NewConnectionStatus(connected bool) *ConnectionStatus {
+	s := &ConnectionStatus{}
+	s.Set(connected)
+	return s
+}
+
+// Set records a connect (true) or disconnect (false)
+func (s *ConnectionStatus) Set(connected bool) {
+	s.connected.Store(connected)
+	if connected {
+		telemetry.NATSConnected.Set(1)
+	} else {
+		telemetry.NATSConnected.Set(0)
+	}
+}
+
+// IsConnected reports whether the broker connection is currently up
+func (s *ConnectionStatus) IsConnected() bool {
+	return s.connected.Load()
+}
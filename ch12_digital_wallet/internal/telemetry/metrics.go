@@ -59,6 +59,13 @@ var (
 		[]string{"type"}, // MoneyDeducted, MoneyCredited, TransactionFailed
 	)
 
+	EventStoreSkippedLines = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_event_store_skipped_lines",
+			Help: "Malformed event store lines skipped by the last replay in skip mode",
+		},
+	)
+
 	EventStoreWriteDuration = promauto.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "wallet_event_store_write_duration_seconds",
@@ -84,6 +91,13 @@ var (
 		[]string{"subject"},
 	)
 
+	NATSConnected = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_nats_connected",
+			Help: "Whether the NATS connection is up (1) or disconnected (0)",
+		},
+	)
+
 	// Account metrics
 	AccountBalanceGauge = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -96,7 +110,7 @@ var (
 	TotalBalanceGauge = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "wallet_total_balance",
-			Help: "Total balance across all accounts (in cents)",
+			Help: "Total balance across all accounts (in cents), net of overdrawn accounts",
 		},
 	)
 
@@ -107,6 +121,35 @@ var (
 		},
 	)
 
+	OverdrawnAccounts = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_overdrawn_accounts",
+			Help: "Number of accounts with a negative balance (using their overdraft limit)",
+		},
+	)
+
+	// Degraded mode metrics
+	EngineDegraded = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_engine_degraded",
+			Help: "Whether the engine is in degraded read-only mode (1) or healthy (0)",
+		},
+	)
+
+	EventStoreWriteFailuresTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_event_store_write_failures_total",
+			Help: "Total number of failed event store writes",
+		},
+	)
+
+	DegradedRejectionsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_degraded_rejections_total",
+			Help: "Total number of commands rejected while the engine was degraded",
+		},
+	)
+
 	// Idempotency metrics
 	DuplicateTransactionsTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
@@ -114,4 +157,85 @@ var (
 			Help: "Total number of duplicate transactions detected",
 		},
 	)
+
+	IdempotencyCacheSize = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_idempotency_cache_size",
+			Help: "Transaction IDs currently remembered for deduplication",
+		},
+	)
+
+	DeadLettersTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_dead_letters_total",
+			Help: "Total number of commands moved to the dead-letter subject",
+		},
+	)
+
+	IdempotencyEvictionsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_idempotency_evictions_total",
+			Help: "Total number of transaction IDs forgotten to keep the idempotency window bounded",
+		},
+	)
+
+	// Startup replay metrics
+	ReplayEventsTotal = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_replay_events_total",
+			Help: "Events applied so far by the engine's startup replay of the event store",
+		},
+	)
+
+	ReplayEventsExpected = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_replay_events_expected",
+			Help: "Events the engine's startup replay has to apply (0 while they are being loaded)",
+		},
+	)
+
+	// Snapshot metrics
+	SnapshotsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "wallet_snapshots_total",
+			Help: "Total number of engine state snapshots written",
+		},
+		[]string{"status"}, // success, failed
+	)
+
+	// Read model metrics
+	ReadModelReplayedEventsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_read_model_replayed_events_total",
+			Help: "Total number of events the read model replayed from the event store to fill gaps",
+		},
+	)
+
+	ReadModelSlowConsumerTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_read_model_slow_consumer_total",
+			Help: "Total number of times the read model subscription fell behind and was resynced",
+		},
+	)
+
+	ReadModelDroppedEventsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_read_model_dropped_events_total",
+			Help: "Total number of events NATS dropped on the read model subscription",
+		},
+	)
+
+	BalanceSubscribers = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "wallet_balance_stream_subscribers",
+			Help: "Current number of balance stream subscribers",
+		},
+	)
+
+	BalanceSubscribersDroppedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "wallet_balance_stream_dropped_subscribers_total",
+			Help: "Total number of balance stream subscribers dropped for falling behind",
+		},
+	)
 )
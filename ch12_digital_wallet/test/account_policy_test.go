@@ -0,0 +1,126 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var caseInsensitivePolicy = domain.AccountPolicy{
+	TrimSpace: true,
+	FoldCase:  true,
+	MaxLength: 16,
+	Pattern:   regexp.MustCompile(`^[a-z0-9_.-]+$`),
+}
+
+func TestAccountPolicy_Normalize(t *testing.T) {
+	for _, variant := range []string{"alice", "Alice", " ALICE ", "\talice\n"} {
+		account, err := caseInsensitivePolicy.Normalize(variant)
+		require.NoError(t, err, variant)
+		assert.Equal(t, "alice", account, variant)
+	}
+
+	for _, invalid := range []string{"", "   ", "alice smith", "alice!", strings.Repeat("a", 17)} {
+		_, err := caseInsensitivePolicy.Normalize(invalid)
+		assert.ErrorIs(t, err, domain.ErrInvalidAccount, invalid)
+	}
+
+	// The default only trims: case is kept and any characters are allowed
+	account, err := domain.DefaultAccountPolicy().Normalize(" Alice Smith! ")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith!", account)
+}
+
+func TestAccountPolicy_EngineMapsVariantsToOneAccount(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetAccountPolicy(caseInsensitivePolicy)
+	eng.SetBalance("alice", 1000)
+
+	for i, from := range []string{"Alice", " alice ", "ALICE"} {
+		events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: generateTestTxnID(i), FromAccount: from, ToAccount: " Bob", Amount: 100,
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 2, from)
+		assert.Equal(t, "alice", events[0].(domain.MoneyDeducted).Account)
+		assert.Equal(t, "bob", events[1].(domain.MoneyCredited).Account)
+	}
+	assert.Equal(t, map[string]int64{"alice": 700, "bob": 300}, eng.GetAllBalances())
+
+	// Variants of one account are the same account
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "self", FromAccount: "alice", ToAccount: "ALICE ", Amount: 100,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "cannot transfer to same account", events[0].(domain.TransactionFailed).Reason)
+
+	// Commands bypassing the API are validated too
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "bad", FromAccount: "alice", ToAccount: "bob smith", Amount: 100,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Contains(t, events[0].(domain.TransactionFailed).Reason, "invalid account")
+	assert.Equal(t, int64(700), eng.GetBalance("alice", "USD"))
+}
+
+func TestAccountPolicy_APINormalizesAndRejects(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	eng.SetAccountPolicy(caseInsensitivePolicy)
+
+	gin.SetMode(gin.TestMode)
+	readModel := cqrs.NewReadModel(nil)
+	h := handler.NewHandler(nil, readModel, eng)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+		return w
+	}
+
+	w := post("/v1/wallet/init", handler.InitAccountRequest{Account: " Alice ", Balance: 500})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(500), eng.GetBalance("alice", "USD"))
+
+	var balance handler.BalanceResponse
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/balance/%20ALICE", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &balance))
+	assert.Equal(t, handler.BalanceResponse{Account: "alice", Currency: "USD", Balance: 500}, balance)
+
+	// Invalid IDs are rejected before a command is published
+	w = post("/v1/wallet/transfer", handler.TransferRequest{FromAccount: "alice", ToAccount: "bob!", Amount: 1})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid account")
+
+	w = post("/v1/wallet/transfer", handler.TransferRequest{FromAccount: "  ", ToAccount: "bob", Amount: 1})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post("/v1/wallet/init", handler.InitAccountRequest{Account: strings.Repeat("a", 17), Balance: 1})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/balance/alice%20smith", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	loaded, err := store.LoadAll()
+	require.NoError(t, err)
+	assert.Empty(t, loaded)
+}
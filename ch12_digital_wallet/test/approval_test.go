@@ -0,0 +1,201 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupApprovalTest(t *testing.T) (*engine.WalletEngine, func() *engine.WalletEngine) {
+	eng, store := setupTransferModeTest(t)
+	eng.SetApprovalThreshold(1000)
+
+	_, err := store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 5000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.InitializeFromEventStore())
+
+	replay := func() *engine.WalletEngine {
+		restarted := engine.NewWalletEngine(store, nil)
+		restarted.SetApprovalThreshold(1000)
+		require.NoError(t, restarted.InitializeFromEventStore())
+		return restarted
+	}
+	return eng, replay
+}
+
+func TestApproval_LargeTransferHeldUntilApproved(t *testing.T) {
+	eng, replay := setupApprovalTest(t)
+
+	cmd := domain.TransferCommand{TransactionID: "big", FromAccount: "alice", ToAccount: "bob", Amount: 3000, Memo: "invoice #7"}
+	events, err := eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.TransferPendingApproval{
+		TransactionID: "big", FromAccount: "alice", ToAccount: "bob", Amount: 3000, Currency: "USD", Memo: "invoice #7",
+	}, events[0])
+	assert.Equal(t, int64(5000), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(0), eng.GetBalance("bob", "USD"))
+	assert.Equal(t, int64(3000), eng.HeldAmount("alice", "USD"))
+
+	// Resending while pending is a duplicate
+	events, err = eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// The held amount is not available to other transfers
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "other", FromAccount: "alice", ToAccount: "carol", Amount: 900,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "too-much", FromAccount: "alice", ToAccount: "carol", Amount: 1200,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+
+	// The pending set survives a restart
+	restarted := replay()
+	require.Len(t, restarted.PendingApprovals(), 1)
+	assert.Equal(t, int64(3000), restarted.HeldAmount("alice", "USD"))
+
+	events, err = eng.ApproveTransfer(context.Background(), "big")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.MoneyDeducted{TransactionID: "big", Account: "alice", Amount: 3000, Currency: "USD", Memo: "invoice #7"}, events[0])
+	assert.Equal(t, int64(1100), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(3000), eng.GetBalance("bob", "USD"))
+	assert.Equal(t, int64(0), eng.HeldAmount("alice", "USD"))
+	assert.Empty(t, eng.PendingApprovals())
+
+	// Approving twice fails, and the transfer stays done after a restart
+	_, err = eng.ApproveTransfer(context.Background(), "big")
+	assert.ErrorIs(t, err, engine.ErrApprovalNotFound)
+	restarted = replay()
+	assert.Empty(t, restarted.PendingApprovals())
+	assert.Equal(t, int64(3000), restarted.GetBalance("bob", "USD"))
+}
+
+func TestApproval_RejectReleasesHold(t *testing.T) {
+	eng, replay := setupApprovalTest(t)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "big", FromAccount: "alice", ToAccount: "bob", Amount: 4500,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(4500), eng.HeldAmount("alice", "USD"))
+
+	require.NoError(t, eng.RejectTransfer("big", "suspicious"))
+	assert.Equal(t, int64(0), eng.HeldAmount("alice", "USD"))
+	assert.Equal(t, int64(5000), eng.GetBalance("alice", "USD"))
+	assert.ErrorIs(t, eng.RejectTransfer("big", ""), engine.ErrApprovalNotFound)
+	_, err = eng.ApproveTransfer(context.Background(), "big")
+	assert.ErrorIs(t, err, engine.ErrApprovalNotFound)
+
+	// The ID is used up; a resend gets the rejection back
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "big", FromAccount: "alice", ToAccount: "bob", Amount: 4500,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	outcome, ok := eng.Outcome("big")
+	require.True(t, ok)
+	assert.Equal(t, "transfer rejected", outcome.Reason)
+
+	// The released funds can move again
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "small", FromAccount: "alice", ToAccount: "bob", Amount: 1000,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	restarted := replay()
+	assert.Empty(t, restarted.PendingApprovals())
+	assert.Equal(t, int64(4000), restarted.GetBalance("alice", "USD"))
+}
+
+func TestApproval_SmallTransferBypassesApproval(t *testing.T) {
+	eng, _ := setupApprovalTest(t)
+
+	// The threshold itself does not need approval
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "small", FromAccount: "alice", ToAccount: "bob", Amount: 1000,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(1000), eng.GetBalance("bob", "USD"))
+	assert.Empty(t, eng.PendingApprovals())
+
+	// Disabled approvals let any amount through
+	eng.SetApprovalThreshold(0)
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "large", FromAccount: "alice", ToAccount: "bob", Amount: 4000,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(0), eng.GetBalance("alice", "USD"))
+}
+
+func TestApproval_API(t *testing.T) {
+	eng, _ := setupApprovalTest(t)
+
+	gin.SetMode(gin.TestMode)
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), eng)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+		return w
+	}
+
+	for _, id := range []string{"a", "b"} {
+		_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 2000,
+		})
+		require.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/approvals", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list handler.PendingApprovalsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 2, list.Count)
+
+	w = post("/v1/wallet/approve/a", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp handler.TransferResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Success)
+	assert.Equal(t, int64(2000), resp.Amount)
+	assert.Equal(t, []string{domain.EventTypeMoneyDeducted, domain.EventTypeMoneyCredited}, resp.Events)
+
+	w = post("/v1/wallet/reject/b", handler.RejectTransferRequest{Reason: "not expected"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusNotFound, post("/v1/wallet/approve/b", nil).Code)
+	assert.Equal(t, http.StatusNotFound, post("/v1/wallet/reject/unknown", nil).Code)
+	assert.Equal(t, int64(3000), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(0), eng.HeldAmount("alice", "USD"))
+}
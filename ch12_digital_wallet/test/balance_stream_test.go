@@ -0,0 +1,117 @@
+package test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestBalanceStream_SubscriberGetsCurrentBalanceThenChanges(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	rm.SetBalance("alice", 1000)
+
+	updates, unsubscribe := rm.SubscribeBalance("alice", "")
+	defer unsubscribe()
+	assert.Equal(t, cqrs.BalanceUpdate{Account: "alice", Currency: "USD", Balance: 1000}, <-updates)
+
+	rm.HandleEventDirect(domain.MoneyDeducted{TransactionID: "t1", Account: "alice", Amount: 300})
+	rm.HandleEventDirect(domain.MoneyCredited{TransactionID: "t1", Account: "bob", Amount: 300})
+	rm.HandleEventDirect(domain.MoneyDeposited{TransactionID: "d1", Account: "alice", Amount: 5, Currency: "EUR"})
+	rm.HandleEventDirect(domain.MoneyCredited{TransactionID: "t2", Account: "alice", Amount: 50})
+
+	// Only alice's USD changes arrive
+	assert.Equal(t, cqrs.BalanceUpdate{Account: "alice", Currency: "USD", Balance: 700, TransactionID: "t1", Type: domain.EventTypeMoneyDeducted, Amount: -300}, <-updates)
+	assert.Equal(t, cqrs.BalanceUpdate{Account: "alice", Currency: "USD", Balance: 750, TransactionID: "t2", Type: domain.EventTypeMoneyCredited, Amount: 50}, <-updates)
+	select {
+	case u := <-updates:
+		t.Fatalf("unexpected update %+v", u)
+	default:
+	}
+
+	unsubscribe()
+	_, open := <-updates
+	assert.False(t, open)
+}
+
+func TestBalanceStream_SlowSubscriberIsDropped(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	slow, unsubscribeSlow := rm.SubscribeBalance("alice", "USD")
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := rm.SubscribeBalance("alice", "USD")
+	defer unsubscribeFast()
+	<-fast
+
+	// Applying events never waits for the subscriber that does not read
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < cqrs.BalanceSubscriberBuffer+10; i++ {
+			rm.HandleEventDirect(domain.MoneyDeposited{TransactionID: "d", Account: "alice", Amount: 1})
+			<-fast
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("applying events blocked on a slow subscriber")
+	}
+
+	// The slow subscriber got what fit in its buffer, then its channel closed
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Equal(t, cqrs.BalanceSubscriberBuffer+1, received)
+	assert.Equal(t, int64(cqrs.BalanceSubscriberBuffer+10), mustBalance(t, rm, "alice"))
+}
+
+func mustBalance(t *testing.T, rm *cqrs.ReadModel, account string) int64 {
+	balance, ok := rm.GetBalance(account, domain.DefaultCurrency)
+	require.True(t, ok)
+	return balance
+}
+
+func TestBalanceStream_WebSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eng, _ := setupTransferModeTest(t)
+	rm := cqrs.NewReadModel(nil)
+	rm.SetBalance("alice", 1000)
+	router := gin.New()
+	handler.SetupRoutes(router, handler.NewHandler(nil, rm, eng))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	subscribers := testutil.ToFloat64(telemetry.BalanceSubscribers)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/wallet/balance/alice/stream"
+	ws, err := websocket.Dial(url, "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	var update cqrs.BalanceUpdate
+	require.NoError(t, websocket.JSON.Receive(ws, &update))
+	assert.Equal(t, cqrs.BalanceUpdate{Account: "alice", Currency: "USD", Balance: 1000}, update)
+
+	rm.HandleSequencedEvent(domain.SequencedEvent{Sequence: 1, Event: domain.MoneyDeducted{TransactionID: "t1", Account: "alice", Amount: 250}})
+	require.NoError(t, websocket.JSON.Receive(ws, &update))
+	assert.Equal(t, int64(750), update.Balance)
+	assert.Equal(t, "t1", update.TransactionID)
+
+	// Closing the socket unsubscribes
+	assert.Equal(t, subscribers+1, testutil.ToFloat64(telemetry.BalanceSubscribers))
+	ws.Close()
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(telemetry.BalanceSubscribers) == subscribers
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,196 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/nathanyu/digital-wallet/internal/queue"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBatchTest(t *testing.T) (*engine.WalletEngine, *diskFullStore) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	tmpFile.Close()
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	real, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { real.Close() })
+
+	store := &diskFullStore{EventStore: real}
+	return engine.NewWalletEngine(store, nil), store
+}
+
+func TestBatch_ProcessedInOrderWithOneAppend(t *testing.T) {
+	eng, store := setupBatchTest(t)
+	eng.SetBalance("alice", 100)
+
+	var handled []domain.SequencedEvent
+	eng.RegisterEventHandler(func(event domain.SequencedEvent) {
+		handled = append(handled, event)
+	})
+
+	results, err := eng.ProcessBatch(context.Background(), []domain.TransferCommand{
+		{TransactionID: "t1", FromAccount: "alice", ToAccount: "bob", Amount: 100},
+		// Only covered by the transfer before it
+		{TransactionID: "t2", FromAccount: "bob", ToAccount: "carol", Amount: 60},
+		{TransactionID: "t3", FromAccount: "alice", ToAccount: "carol", Amount: 1},
+		{TransactionID: "t4", FromAccount: "bob", ToAccount: "alice", Amount: 40},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.Len(t, results[0], 2)
+	assert.Len(t, results[1], 2)
+	require.Len(t, results[2], 1)
+	assert.Equal(t, "insufficient funds", results[2][0].(domain.TransactionFailed).Reason)
+	assert.Len(t, results[3], 2)
+
+	assert.Equal(t, int32(1), store.writes.Load(), "the whole batch is one append")
+	assert.Equal(t, map[string]int64{"alice": 40, "bob": 0, "carol": 60}, eng.GetAllBalances())
+	require.Len(t, handled, 7)
+	assert.Equal(t, uint64(7), handled[6].Sequence)
+	assert.Equal(t, uint64(7), eng.LastSequence())
+
+	// Replay gives the same balances
+	restarted := engine.NewWalletEngine(store.EventStore, nil)
+	restarted.SetBalance("alice", 100)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, eng.GetAllBalances(), restarted.GetAllBalances())
+}
+
+func TestBatch_DuplicatesAreDeduped(t *testing.T) {
+	eng, _ := setupBatchTest(t)
+	eng.SetBalance("alice", 1000)
+
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "earlier", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+
+	results, err := eng.ProcessBatch(context.Background(), []domain.TransferCommand{
+		{TransactionID: "same", FromAccount: "alice", ToAccount: "bob", Amount: 10},
+		{TransactionID: "same", FromAccount: "alice", ToAccount: "bob", Amount: 10},
+		{TransactionID: "earlier", FromAccount: "alice", ToAccount: "bob", Amount: 100},
+		{TransactionID: "same", FromAccount: "alice", ToAccount: "carol", Amount: 500},
+	})
+	require.NoError(t, err)
+	assert.Len(t, results[0], 2)
+	assert.Empty(t, results[1])
+	assert.Empty(t, results[2])
+	assert.Empty(t, results[3])
+	assert.Equal(t, int64(890), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(110), eng.GetBalance("bob", "USD"))
+}
+
+func TestBatch_FailedAppendAppliesNothing(t *testing.T) {
+	eng, store := setupBatchTest(t)
+	eng.SetBalance("alice", 1000)
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "before", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+	balances := eng.GetAllBalances()
+	processed := eng.ProcessedTransactions()
+
+	store.full.Store(true)
+	_, err = eng.ProcessBatch(context.Background(), []domain.TransferCommand{
+		{TransactionID: "b1", FromAccount: "alice", ToAccount: "bob", Amount: 300},
+		{TransactionID: "b2", FromAccount: "bob", ToAccount: "dave", Amount: 350},
+		{TransactionID: "b3", FromAccount: "alice", ToAccount: "erin", Amount: 5000},
+	})
+	require.Error(t, err)
+
+	assert.Equal(t, balances, eng.GetAllBalances(), "new accounts and balances are undone")
+	assert.Equal(t, processed, eng.ProcessedTransactions())
+	_, ok := eng.Outcome("b3")
+	assert.False(t, ok)
+
+	// Once the store recovers the same batch goes through (as the probe write)
+	store.full.Store(false)
+	eng.SetDegradedPolicy(1, 0)
+	results, err := eng.ProcessBatch(context.Background(), []domain.TransferCommand{
+		{TransactionID: "b1", FromAccount: "alice", ToAccount: "bob", Amount: 300},
+		{TransactionID: "b2", FromAccount: "bob", ToAccount: "dave", Amount: 350},
+	})
+	require.NoError(t, err)
+	assert.Len(t, results[0], 2)
+	assert.Len(t, results[1], 2)
+	assert.Equal(t, map[string]int64{"alice": 600, "bob": 50, "dave": 350}, eng.GetAllBalances())
+}
+
+func TestBatch_TooLarge(t *testing.T) {
+	eng, store := setupBatchTest(t)
+	_, err := eng.ProcessBatch(context.Background(), make([]domain.TransferCommand, engine.MaxBatchSize+1))
+	assert.ErrorIs(t, err, engine.ErrBatchTooLarge)
+	assert.Equal(t, int32(0), store.writes.Load())
+}
+
+func TestBatch_HandlerRejectsInvalidTransfers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eng, _ := setupBatchTest(t)
+	router := gin.New()
+	handler.SetupRoutes(router, handler.NewHandler(nil, cqrs.NewReadModel(nil), eng))
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty", `{"transfers":[]}`, "min"},
+		{"invalid transfer", `{"transfers":[{"from_account":"alice","to_account":"bob","amount":10},{"from_account":"alice","to_account":"bob"}]}`, "transfers[1]: amount must be positive"},
+		{"priority", `{"transfers":[{"from_account":"alice","to_account":"bob","amount":10,"priority":true}]}`, "transfers[0]: a batch cannot use the priority lane"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/wallet/transfers/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+}
+
+func TestBatch_OverNATS(t *testing.T) {
+	eng, store := setupBatchTest(t)
+	client, err := queue.NewNATSClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS server not available")
+	}
+	defer client.Close()
+
+	eng = engine.NewWalletEngine(store, client.GetConn())
+	eng.SetBalance("alice", 100)
+	require.NoError(t, eng.Start())
+	defer eng.Stop()
+
+	resp, err := client.PublishBatch([]domain.TransferCommand{
+		{TransactionID: "n1", FromAccount: "alice", ToAccount: "bob", Amount: 70},
+		{TransactionID: "n2", FromAccount: "alice", ToAccount: "bob", Amount: 70},
+		{TransactionID: "n1", FromAccount: "alice", ToAccount: "bob", Amount: 70},
+	}, 5*time.Second)
+	require.NoError(t, err)
+	require.True(t, resp.Success)
+	require.Len(t, resp.Results, 3)
+	assert.True(t, resp.Results[0].Success)
+	assert.Equal(t, int64(70), resp.Results[0].Amount)
+	assert.False(t, resp.Results[1].Success)
+	assert.Equal(t, "insufficient funds", resp.Results[1].Error)
+	assert.True(t, resp.Results[2].Duplicate)
+	assert.Equal(t, int32(1), store.writes.Load())
+}
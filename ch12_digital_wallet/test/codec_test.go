@@ -0,0 +1,181 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryCodec_RoundTripsEveryEvent(t *testing.T) {
+	due := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := []domain.Event{
+		domain.MoneyDeducted{TransactionID: "t1", Account: "alice", Amount: 100, Currency: "EUR", Memo: "rent", Reverses: "t0"},
+		domain.MoneyCredited{TransactionID: "t1", Account: "bob", Amount: 100, Currency: "EUR"},
+		domain.TransactionFailed{TransactionID: "t2", FromAccount: "alice", Reason: "insufficient funds"},
+		domain.MoneyDeposited{TransactionID: "t3", Account: "alice", Amount: 5, Currency: "USD"},
+		domain.MoneyWithdrawn{TransactionID: "t4", Account: "alice", Amount: 5, Currency: "USD"},
+		domain.MinimumBalanceSet{Account: "reserve", Floor: 300},
+		domain.OverdraftLimitSet{Account: "credit", Limit: 500},
+		domain.AccountFrozen{Account: "mallory", Reason: "fraud review"},
+		domain.AccountUnfrozen{Account: "mallory"},
+		domain.TransferScheduled{TransactionID: "t5", FromAccount: "alice", ToAccount: "bob", Amount: 1, Currency: "USD", Mode: domain.TransferModePercent, Percent: 10, DueAt: due},
+		domain.ScheduledTransferCanceled{TransactionID: "t5"},
+		domain.TransferPendingApproval{TransactionID: "t6", FromAccount: "alice", ToAccount: "bob", Amount: 9000, Currency: "USD"},
+		domain.TransferRejected{TransactionID: "t6", FromAccount: "alice", Reason: "fraud"},
+	}
+	for i, event := range events {
+		t.Run(event.GetType(), func(t *testing.T) {
+			data, err := domain.BinaryCodec.Encode(domain.SequencedEvent{Sequence: uint64(i + 1), Event: event})
+			require.NoError(t, err)
+			se, at, err := domain.BinaryCodec.Decode(data)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(i+1), se.Sequence)
+			assert.Equal(t, event, se.Event)
+			assert.WithinDuration(t, time.Now(), at, time.Minute)
+
+			jsonData, err := domain.JSONCodec.Encode(domain.SequencedEvent{Sequence: uint64(i + 1), Event: event})
+			require.NoError(t, err)
+			assert.Less(t, len(data), len(jsonData))
+		})
+	}
+
+	// As with JSON, a missing currency decodes as the default
+	data, err := domain.BinaryCodec.Encode(domain.SequencedEvent{Event: domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1}})
+	require.NoError(t, err)
+	se, _, err := domain.BinaryCodec.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DefaultCurrency, se.Event.(domain.MoneyCredited).Currency)
+
+	_, _, err = domain.BinaryCodec.Decode(data[:len(data)-1])
+	assert.Error(t, err)
+}
+
+// A store written in JSON, then binary, then JSON again reads back as one
+// log, from its segments and from its replica
+func TestEventStore_MixedCodecs(t *testing.T) {
+	dir := t.TempDir()
+	path, replica := dir+"/events.log", dir+"/replica.log"
+	open := func(codec domain.Codec) *eventstore.EventStore {
+		store, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{Codec: codec, ReplicaPath: replica})
+		require.NoError(t, err)
+		return store
+	}
+	credit := func(store *eventstore.EventStore, id string) {
+		require.NoError(t, store.Append(domain.MoneyCredited{TransactionID: id, Account: "alice", Amount: 10, Currency: "USD"}))
+	}
+
+	store := open(nil)
+	credit(store, "json-1")
+	credit(store, "json-2")
+	require.NoError(t, store.Close())
+
+	store = open(domain.BinaryCodec)
+	credit(store, "bin-1")
+	credit(store, "bin-2")
+	require.NoError(t, store.Close())
+
+	store = open(domain.JSONCodec)
+	defer store.Close()
+	assert.Equal(t, uint64(4), store.LastSequence())
+	credit(store, "json-3")
+
+	segments := store.SegmentPaths()
+	require.Len(t, segments, 3, "each codec change starts a segment")
+	first, err := os.ReadFile(segments[0])
+	require.NoError(t, err)
+	assert.False(t, strings.HasPrefix(string(first), "#codec"), "a JSON log needs no header")
+	second, err := os.ReadFile(segments[1])
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(second), "#codec binary\n"))
+	third, err := os.ReadFile(segments[2])
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(third), "#codec json\n"))
+
+	want := []string{"json-1", "json-2", "bin-1", "bin-2", "json-3"}
+	assertLog := func() {
+		events, err := store.LoadSince(0)
+		require.NoError(t, err)
+		require.Len(t, events, len(want))
+		for i, se := range events {
+			assert.Equal(t, uint64(i+1), se.Sequence)
+			assert.Equal(t, want[i], se.Event.GetTransactionID())
+		}
+	}
+	assertLog()
+
+	// Snapshot offsets work across codecs
+	require.NoError(t, store.WriteSnapshot(eventstore.Snapshot{Sequence: 3}))
+	after, err := store.LoadSince(3)
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	assert.Equal(t, "bin-2", after[0].Event.GetTransactionID())
+
+	// The replica holds the same stream in one file
+	require.NoError(t, os.WriteFile(segments[0], []byte("corrupt\n"), 0644))
+	require.NoError(t, os.Remove(store.SnapshotPath()))
+	assertLog()
+}
+
+func TestEventStore_BinarySnapshotReopen(t *testing.T) {
+	path := t.TempDir() + "/events.log"
+	store, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{Codec: domain.BinaryCodec})
+	require.NoError(t, err)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, store.Append(domain.MoneyCredited{TransactionID: fmt.Sprintf("c%d", i), Account: "alice", Amount: 10}))
+	}
+	require.NoError(t, store.WriteSnapshot(eventstore.Snapshot{Sequence: 3}))
+	require.NoError(t, store.Close())
+
+	reopened, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{Codec: domain.BinaryCodec})
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, uint64(5), reopened.LastSequence())
+	assert.Len(t, reopened.SegmentPaths(), 1)
+	after, err := reopened.LoadSince(3)
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	assert.Equal(t, "c4", after[0].Event.GetTransactionID())
+
+	var csv strings.Builder
+	require.NoError(t, reopened.ExportCSV(&csv, time.Time{}, time.Time{}))
+	assert.Equal(t, 6, strings.Count(csv.String(), "\n"))
+}
+
+// BenchmarkEventStore_Append compares append throughput of the codecs.
+// Every append syncs the file, so run with -benchtime to taste.
+func BenchmarkEventStore_Append(b *testing.B) {
+	for _, codec := range []domain.Codec{domain.JSONCodec, domain.BinaryCodec} {
+		b.Run(codec.Name(), func(b *testing.B) {
+			store, err := eventstore.NewEventStoreWithOptions(b.TempDir()+"/events.log", eventstore.Options{Codec: codec})
+			require.NoError(b, err)
+			defer store.Close()
+
+			batch := make([]domain.Event, 0, 100)
+			for i := 0; i < 50; i++ {
+				id := fmt.Sprintf("txn-%d", i)
+				batch = append(batch,
+					domain.MoneyDeducted{TransactionID: id, Account: "alice", Amount: 100, Currency: "USD"},
+					domain.MoneyCredited{TransactionID: id, Account: "bob", Amount: 100, Currency: "USD"},
+				)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.AppendSequenced(batch); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			info, err := os.Stat(store.FilePath())
+			require.NoError(b, err)
+			b.ReportMetric(float64(info.Size())/float64(b.N*len(batch)), "bytes/event")
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrency_BalancesAreKeptPerCurrency(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	ctx := context.Background()
+
+	_, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-usd", Account: "alice", Amount: 1000})
+	require.NoError(t, err)
+	_, err = eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-eur", Account: "alice", Amount: 400, Currency: "EUR"})
+	require.NoError(t, err)
+
+	events, err := eng.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "eur-1", FromAccount: "alice", ToAccount: "bob", Amount: 150, Currency: "EUR",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Event{
+		domain.MoneyDeducted{TransactionID: "eur-1", Account: "alice", Amount: 150, Currency: "EUR"},
+		domain.MoneyCredited{TransactionID: "eur-1", Account: "bob", Amount: 150, Currency: "EUR"},
+	}, events)
+
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(250), eng.GetBalance("alice", "EUR"))
+	assert.Equal(t, int64(150), eng.GetBalance("bob", "EUR"))
+	assert.Equal(t, int64(0), eng.GetBalance("bob", "USD"))
+	assert.Equal(t, map[string]int64{"USD": 1000, "EUR": 250}, eng.GetAccountBalances("alice"))
+
+	// Funds in one currency do not cover a transfer in another
+	events, err = eng.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "eur-2", FromAccount: "alice", ToAccount: "bob", Amount: 500, Currency: "EUR",
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+
+	// Replaying the log rebuilds every wallet
+	restarted := engine.NewWalletEngine(store, nil)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, eng.GetAccountBalances("alice"), restarted.GetAccountBalances("alice"))
+	assert.Equal(t, eng.GetAccountBalances("bob"), restarted.GetAccountBalances("bob"))
+
+	rm := cqrs.NewReadModel(nil)
+	require.NoError(t, rm.InitializeFromEventStore(store))
+	balance, ok := rm.GetBalance("bob", "EUR")
+	require.True(t, ok)
+	assert.Equal(t, int64(150), balance)
+	_, ok = rm.GetBalance("bob", "USD")
+	assert.False(t, ok)
+}
+
+func TestCurrency_MismatchRejected(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	ctx := context.Background()
+
+	_, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-a", Account: "alice", Amount: 1000, Currency: "EUR"})
+	require.NoError(t, err)
+	_, err = eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-b", Account: "bob", Amount: 1000, Currency: "GBP"})
+	require.NoError(t, err)
+
+	transfer := func(id, currency string) string {
+		events, err := eng.ProcessCommand(ctx, domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 100, Currency: currency,
+		})
+		require.NoError(t, err)
+		if failed, ok := events[0].(domain.TransactionFailed); ok {
+			return failed.Reason
+		}
+		return ""
+	}
+
+	// bob holds only GBP and alice only EUR, so neither currency fits both
+	assert.Equal(t, "currency mismatch", transfer("eur", "EUR"))
+	assert.Equal(t, "currency mismatch", transfer("gbp", "GBP"))
+	assert.Equal(t, "currency mismatch", transfer("usd", ""))
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "EUR"))
+	assert.Equal(t, int64(1000), eng.GetBalance("bob", "GBP"))
+
+	// Once bob opens a EUR wallet the transfer goes through
+	_, err = eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-b-eur", Account: "bob", Amount: 1, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, "", transfer("eur-ok", "EUR"))
+	assert.Equal(t, int64(101), eng.GetBalance("bob", "EUR"))
+}
+
+func TestCurrency_SnapshotKeepsOtherCurrencies(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	t.Cleanup(func() { os.Remove(store.SnapshotPath()) })
+	require.NoError(t, eng.SetSnapshotPolicy(2, 0))
+	ctx := context.Background()
+
+	_, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-usd", Account: "alice", Amount: 700})
+	require.NoError(t, err)
+	_, err = eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-jpy", Account: "alice", Amount: 9000, Currency: "JPY"})
+	require.NoError(t, err)
+
+	var snap *eventstore.Snapshot
+	require.Eventually(t, func() bool {
+		snap, err = store.LoadLatestSnapshot()
+		return err == nil && snap != nil
+	}, 2*time.Second, 10*time.Millisecond, "auto-snapshot was not written")
+	assert.Equal(t, map[string]int64{"alice": 700}, snap.Balances)
+	assert.Equal(t, map[string]map[string]int64{"JPY": {"alice": 9000}}, snap.CurrencyBalances)
+
+	restarted := engine.NewWalletEngine(store, nil)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, map[string]int64{"USD": 700, "JPY": 9000}, restarted.GetAccountBalances("alice"))
+}
+
+func TestCurrency_EventsWithoutCurrencyDefaultToUSD(t *testing.T) {
+	line := []byte(`{"type":"MoneyCredited","seq":3,"timestamp":"2025-01-01T00:00:00Z","data":{"transaction_id":"old","account":"bob","amount":10}}`)
+	event, err := domain.DeserializeSequencedEvent(line)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MoneyCredited{TransactionID: "old", Account: "bob", Amount: 10, Currency: "USD"}, event.Event)
+}
+
+func TestCurrency_BalanceAPI(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	rm := cqrs.NewReadModel(nil)
+	rm.HandleEventDirect(domain.MoneyDeposited{TransactionID: "dep-1", Account: "alice", Amount: 500, Currency: "EUR"})
+	rm.HandleEventDirect(domain.MoneyDeposited{TransactionID: "dep-2", Account: "alice", Amount: 80, Currency: "USD"})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.SetupRoutes(router, handler.NewHandler(nil, rm, eng))
+
+	get := func(path string) handler.BalanceResponse {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp handler.BalanceResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	assert.Equal(t, handler.BalanceResponse{Account: "alice", Currency: "EUR", Balance: 500}, get("/v1/wallet/balance/alice?currency=EUR"))
+	assert.Equal(t, handler.BalanceResponse{Account: "alice", Currency: "USD", Balance: 80}, get("/v1/wallet/balance/alice"))
+	assert.Equal(t, handler.BalanceResponse{Account: "alice", Currency: "GBP", Balance: 0}, get("/v1/wallet/balance/alice?currency=GBP"))
+}
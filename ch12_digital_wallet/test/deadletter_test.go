@@ -0,0 +1,147 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/nathanyu/digital-wallet/internal/queue"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterLog_AppendAndEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadletter.log")
+	dl, err := queue.NewDeadLetterLog(path, nil)
+	require.NoError(t, err)
+	defer dl.Close()
+
+	entries, err := dl.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, dl.Append(queue.DeadLetterEntry{ReceivedAt: at, Subject: engine.CommandSubject, Reason: "invalid command format", Deliveries: 1, Data: "{not json"}))
+	require.NoError(t, dl.Append(queue.DeadLetterEntry{ReceivedAt: at, Subject: engine.StreamCommandSubject, Reason: "disk full", Deliveries: 5, Data: `{"transaction_id":"t1"}`}))
+
+	entries, err = dl.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "{not json", entries[0].Data)
+	assert.Equal(t, uint64(5), entries[1].Deliveries)
+
+	// The log survives reopening
+	require.NoError(t, dl.Close())
+	reopened, err := queue.NewDeadLetterLog(path, nil)
+	require.NoError(t, err)
+	defer reopened.Close()
+	entries, err = reopened.Entries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestEngine_MaxDeliveriesAtLeastOne(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	assert.Equal(t, engine.DefaultMaxDeliveries, eng.MaxDeliveries())
+	eng.SetMaxDeliveries(0)
+	assert.Equal(t, 1, eng.MaxDeliveries())
+}
+
+type fakeDeadLetters struct {
+	entries  []queue.DeadLetterEntry
+	replayed int
+}
+
+func (f *fakeDeadLetters) Entries() ([]queue.DeadLetterEntry, error) { return f.entries, nil }
+
+func (f *fakeDeadLetters) Replay() (int, error) {
+	f.replayed++
+	return len(f.entries), nil
+}
+
+func TestDeadLetters_API(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), nil)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/deadletters", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	fake := &fakeDeadLetters{entries: []queue.DeadLetterEntry{{Reason: "invalid command format", Data: "x"}}}
+	h.EnableDeadLetters(fake)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/deadletters", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		DeadLetters []queue.DeadLetterEntry `json:"dead_letters"`
+		Count       int                     `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Count)
+	assert.Equal(t, "invalid command format", list.DeadLetters[0].Reason)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/wallet/deadletters/replay", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"replayed":1}`, w.Body.String())
+	assert.Equal(t, 1, fake.replayed)
+}
+
+// A malformed command is dead-lettered and recorded, and the engine keeps
+// serving the commands after it
+func TestDeadLetter_MalformedCommandRecorded(t *testing.T) {
+	nc, err := nats.Connect(nats.DefaultURL, nats.NoReconnect())
+	if err != nil {
+		t.Skip("NATS server not available")
+	}
+	defer nc.Close()
+
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store.Close()
+
+	client, err := queue.NewNATSClient(nats.DefaultURL)
+	require.NoError(t, err)
+	defer client.Close()
+	dl, err := queue.NewDeadLetterLog(filepath.Join(t.TempDir(), "deadletter.log"), client)
+	require.NoError(t, err)
+	defer dl.Close()
+	require.NoError(t, dl.Start())
+
+	eng := engine.NewWalletEngine(store, nc)
+	eng.SetBalance("alice", 1000)
+	require.NoError(t, eng.Start())
+	defer eng.Stop()
+
+	msg, err := nc.Request(engine.CommandSubject, []byte("{not json"), 2*time.Second)
+	require.NoError(t, err)
+	var resp engine.CommandResponse
+	require.NoError(t, json.Unmarshal(msg.Data, &resp))
+	assert.False(t, resp.Success)
+
+	var entries []queue.DeadLetterEntry
+	require.Eventually(t, func() bool {
+		entries, err = dl.Entries()
+		return err == nil && len(entries) == 1
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, engine.CommandSubject, entries[0].Subject)
+	assert.Contains(t, entries[0].Reason, "invalid command format")
+	assert.Equal(t, "{not json", entries[0].Data)
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// diskFullStore wraps a real event store and fails writes with ENOSPC on demand
+type diskFullStore struct {
+	*eventstore.EventStore
+	full   atomic.Bool
+	writes atomic.Int32
+}
+
+func (s *diskFullStore) AppendSequenced(events []domain.Event) ([]domain.SequencedEvent, error) {
+	s.writes.Add(1)
+	if s.full.Load() {
+		return nil, fmt.Errorf("failed to write event: %w", &os.PathError{Op: "write", Path: "events.log", Err: syscall.ENOSPC})
+	}
+	return s.EventStore.AppendSequenced(events)
+}
+
+func TestDegradedMode_DiskFull(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	real, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer real.Close()
+	store := &diskFullStore{EventStore: real}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	eng := engine.NewWalletEngine(store, nil)
+	eng.SetClock(func() time.Time { return now })
+	eng.SetDegradedPolicy(3, 10*time.Second)
+	eng.SetBalance("alice", 1000)
+
+	ctx := context.Background()
+	transfer := func(id string) ([]domain.Event, error) {
+		return eng.ProcessCommand(ctx, domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 10,
+		})
+	}
+
+	// Healthy write
+	_, err = transfer("txn-1")
+	require.NoError(t, err)
+	assert.False(t, eng.IsDegraded())
+
+	// Disk fills: the first ENOSPC flips the engine into degraded mode
+	store.full.Store(true)
+	_, err = transfer("txn-2")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+	assert.True(t, eng.IsDegraded())
+
+	// New transfers are rejected without touching the store
+	writes := store.writes.Load()
+	_, err = transfer("txn-3")
+	assert.ErrorIs(t, err, engine.ErrDegraded)
+	assert.Equal(t, writes, store.writes.Load(), "degraded engine should not attempt writes")
+
+	// Balance queries are still served
+	assert.Equal(t, int64(990), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(10), eng.GetBalance("bob", "USD"))
+
+	// A probe after the interval still fails while the disk is full
+	now = now.Add(11 * time.Second)
+	_, err = transfer("txn-4")
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+	assert.True(t, eng.IsDegraded())
+
+	// Space is freed: the next probe succeeds and the engine recovers
+	store.full.Store(false)
+	_, err = transfer("txn-5")
+	assert.ErrorIs(t, err, engine.ErrDegraded, "probe interval has not elapsed yet")
+
+	now = now.Add(11 * time.Second)
+	events, err := transfer("txn-6")
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.False(t, eng.IsDegraded())
+
+	_, err = transfer("txn-7")
+	require.NoError(t, err)
+	assert.Equal(t, int64(970), eng.GetBalance("alice", "USD"))
+}
+
+func TestDegradedMode_ConsecutiveFailureThreshold(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	real, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer real.Close()
+
+	// A generic I/O error only degrades the engine after the threshold is reached
+	store := &flakyStore{EventStore: real}
+	eng := engine.NewWalletEngine(store, nil)
+	eng.SetDegradedPolicy(3, time.Minute)
+	eng.SetBalance("alice", 1000)
+
+	store.fail.Store(true)
+	for i := 0; i < 3; i++ {
+		assert.False(t, eng.IsDegraded(), "should not be degraded after %d failures", i)
+		_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: generateTestTxnID(i), FromAccount: "alice", ToAccount: "bob", Amount: 10,
+		})
+		require.Error(t, err)
+	}
+	assert.True(t, eng.IsDegraded())
+}
+
+type flakyStore struct {
+	*eventstore.EventStore
+	fail atomic.Bool
+}
+
+func (s *flakyStore) AppendSequenced(events []domain.Event) ([]domain.SequencedEvent, error) {
+	if s.fail.Load() {
+		return nil, fmt.Errorf("failed to write event: %w", syscall.EIO)
+	}
+	return s.EventStore.AppendSequenced(events)
+}
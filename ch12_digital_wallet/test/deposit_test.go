@@ -0,0 +1,135 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeposit_MoneyEntersAndLeavesThroughEvents(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	ctx := context.Background()
+
+	events, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-1", Account: "alice", Amount: 1000, Memo: "payroll"})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Event{
+		domain.MoneyDeposited{TransactionID: "dep-1", Account: "alice", Amount: 1000, Currency: "USD", Memo: "payroll"},
+	}, events)
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+
+	events, err = eng.Withdraw(ctx, domain.WithdrawCommand{TransactionID: "wd-1", Account: "alice", Amount: 300})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, domain.MoneyWithdrawn{TransactionID: "wd-1", Account: "alice", Amount: 300, Currency: "USD"}, events[0])
+	assert.Equal(t, int64(700), eng.GetBalance("alice", "USD"))
+
+	// Resending an ID does nothing
+	events, err = eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep-1", Account: "alice", Amount: 1000})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	events, err = eng.Withdraw(ctx, domain.WithdrawCommand{TransactionID: "dep-1", Account: "alice", Amount: 100})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, int64(700), eng.GetBalance("alice", "USD"))
+
+	// Both the engine and the read model rebuild the balance from the log
+	restarted := engine.NewWalletEngine(store, nil)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, int64(700), restarted.GetBalance("alice", "USD"))
+
+	rm := cqrs.NewReadModel(nil)
+	require.NoError(t, rm.InitializeFromEventStore(store))
+	balance, ok := rm.GetBalance("alice", "USD")
+	require.True(t, ok)
+	assert.Equal(t, int64(700), balance)
+}
+
+func TestWithdraw_ChecksFunds(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	ctx := context.Background()
+	_, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep", Account: "alice", Amount: 1000})
+	require.NoError(t, err)
+
+	withdraw := func(id string, amount int64) string {
+		events, err := eng.Withdraw(ctx, domain.WithdrawCommand{TransactionID: id, Account: "alice", Amount: amount})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		if failed, ok := events[0].(domain.TransactionFailed); ok {
+			return failed.Reason
+		}
+		return ""
+	}
+
+	assert.Equal(t, "insufficient funds", withdraw("too-much", 1001))
+	assert.Equal(t, "amount must be positive", withdraw("zero", 0))
+
+	require.NoError(t, eng.SetMinimumBalance("alice", 200))
+	assert.Equal(t, "below minimum balance", withdraw("floor", 900))
+
+	// Funds held for an approval are not available
+	eng.SetApprovalThreshold(100)
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{TransactionID: "big", FromAccount: "alice", ToAccount: "bob", Amount: 500})
+	require.NoError(t, err)
+	assert.Equal(t, "insufficient funds", withdraw("held", 600))
+	assert.Equal(t, "", withdraw("ok", 300))
+	assert.Equal(t, int64(700), eng.GetBalance("alice", "USD"))
+
+	// A failed withdrawal uses up its ID
+	outcome, ok := eng.Outcome("too-much")
+	require.True(t, ok)
+	assert.Equal(t, "insufficient funds", outcome.Reason)
+}
+
+func TestDeposit_API(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+
+	gin.SetMode(gin.TestMode)
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), eng)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	post := func(path string, body handler.CashRequest) (int, handler.TransferResponse) {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+		var resp handler.TransferResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := post("/v1/wallet/deposit", handler.CashRequest{Account: "alice", Amount: 500, TransactionID: "dep-1"})
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Success)
+	assert.Equal(t, int64(500), resp.Amount)
+	assert.Equal(t, []string{domain.EventTypeMoneyDeposited}, resp.Events)
+
+	// A duplicate repeats the original result
+	code, resp = post("/v1/wallet/deposit", handler.CashRequest{Account: "alice", Amount: 500, TransactionID: "dep-1"})
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Duplicate)
+	assert.Equal(t, int64(500), resp.Amount)
+
+	code, resp = post("/v1/wallet/withdraw", handler.CashRequest{Account: "alice", Amount: 800})
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "insufficient funds", resp.Message)
+	assert.NotEmpty(t, resp.TransactionID)
+
+	code, resp = post("/v1/wallet/withdraw", handler.CashRequest{Account: "alice", Amount: 200})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{domain.EventTypeMoneyWithdrawn}, resp.Events)
+
+	code, _ = post("/v1/wallet/deposit", handler.CashRequest{Account: "alice", Amount: -5})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, int64(300), eng.GetBalance("alice", "USD"))
+}
@@ -99,8 +99,8 @@ func TestBusinessValidation_InsufficientFunds(t *testing.T) {
 	assert.Equal(t, 5, failCount, "Expected 5 failed transactions")
 
 	// Verify final balance is 0, not negative
-	assert.Equal(t, int64(0), eng.GetBalance("sender"), "Sender balance should be 0")
-	assert.Equal(t, int64(100), eng.GetBalance("receiver"), "Receiver should have 100")
+	assert.Equal(t, int64(0), eng.GetBalance("sender", "USD"), "Sender balance should be 0")
+	assert.Equal(t, int64(100), eng.GetBalance("receiver", "USD"), "Receiver should have 100")
 }
 
 // AC4: Idempotency Test
@@ -139,8 +139,8 @@ func TestIdempotency_DuplicateTransactions(t *testing.T) {
 	applyEventsToEngine(eng, events1)
 
 	// Verify balance after first transfer
-	assert.Equal(t, int64(900), eng.GetBalance("alice"))
-	assert.Equal(t, int64(100), eng.GetBalance("bob"))
+	assert.Equal(t, int64(900), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(100), eng.GetBalance("bob", "USD"))
 
 	// Second execution with same transaction ID - should be skipped
 	events2, err := eng.Execute(cmd)
@@ -148,8 +148,8 @@ func TestIdempotency_DuplicateTransactions(t *testing.T) {
 	assert.Len(t, events2, 0, "Duplicate transaction should produce no events")
 
 	// Verify balance unchanged after duplicate
-	assert.Equal(t, int64(900), eng.GetBalance("alice"))
-	assert.Equal(t, int64(100), eng.GetBalance("bob"))
+	assert.Equal(t, int64(900), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(100), eng.GetBalance("bob", "USD"))
 }
 
 // AC3: Reproducibility Test
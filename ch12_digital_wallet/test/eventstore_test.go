@@ -1,10 +1,14 @@
 package test
 
 import (
+	"context"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
 	"github.com/nathanyu/digital-wallet/internal/eventstore"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -141,3 +145,408 @@ func TestEventStore_Clear(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, loaded, 0)
 }
+
+func TestEventStore_SequenceNumbers(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+
+	sequenced, err := store.AppendSequenced([]domain.Event{
+		domain.MoneyDeducted{TransactionID: "txn-1", Account: "a", Amount: 50},
+		domain.MoneyCredited{TransactionID: "txn-1", Account: "b", Amount: 50},
+	})
+	require.NoError(t, err)
+	require.Len(t, sequenced, 2)
+	assert.Equal(t, uint64(1), sequenced[0].Sequence)
+	assert.Equal(t, uint64(2), sequenced[1].Sequence)
+
+	require.NoError(t, store.Append(domain.TransactionFailed{TransactionID: "txn-2", FromAccount: "a", Reason: "insufficient funds"}))
+	assert.Equal(t, uint64(3), store.LastSequence())
+	store.Close()
+
+	// Reopening continues the sequence
+	store, err = eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store.Close()
+	assert.Equal(t, uint64(3), store.LastSequence())
+
+	since, err := store.LoadSince(1)
+	require.NoError(t, err)
+	require.Len(t, since, 2)
+	assert.Equal(t, uint64(2), since[0].Sequence)
+	assert.Equal(t, "txn-2", since[1].Event.GetTransactionID())
+}
+
+// writeCorruptLog writes a log with a torn line and a garbage line between
+// good events, and returns its path
+func writeCorruptLog(t *testing.T) string {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	tmpFile.Close()
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1000},
+	})
+	require.NoError(t, err)
+	store.Close()
+
+	f, err := os.OpenFile(tmpFile.Name(), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"type":"MoneyDeducted","seq":2,"data":{"transaction_id":"tor` + "\n" + "not json\n")
+	require.NoError(t, err)
+	f.Close()
+
+	store, err = eventstore.NewEventStoreWithOptions(tmpFile.Name(), eventstore.Options{ReplayMode: eventstore.ReplaySkip})
+	require.NoError(t, err)
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyDeducted{TransactionID: "txn-1", Account: "alice", Amount: 300},
+		domain.MoneyCredited{TransactionID: "txn-1", Account: "bob", Amount: 300},
+	})
+	require.NoError(t, err)
+	store.Close()
+	return tmpFile.Name()
+}
+
+func TestEventStore_StrictReplayRejectsCorruptLine(t *testing.T) {
+	path := writeCorruptLog(t)
+
+	_, err := eventstore.NewEventStore(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+
+	_, err = eventstore.NewEventStoreWithOptions(path, eventstore.Options{ReplayMode: "lenient"})
+	assert.Error(t, err)
+}
+
+func TestEventStore_SkipReplayAppliesGoodEvents(t *testing.T) {
+	path := writeCorruptLog(t)
+
+	store, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{ReplayMode: eventstore.ReplaySkip})
+	require.NoError(t, err)
+	defer store.Close()
+	assert.Equal(t, 2, store.SkippedLines())
+
+	// Numbering continues after the good events
+	assert.Equal(t, uint64(3), store.LastSequence())
+
+	eng := engine.NewWalletEngine(store, nil)
+	require.NoError(t, eng.InitializeFromEventStore())
+	assert.Equal(t, map[string]int64{"alice": 700, "bob": 300}, eng.GetAllBalances())
+	assert.Equal(t, 2, store.SkippedLines())
+}
+
+func TestEventStore_ChecksumDetectsGarbledLine(t *testing.T) {
+	path := t.TempDir() + "/events.log"
+	store, err := eventstore.NewEventStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.AppendBatch([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1000},
+		domain.MoneyDeducted{TransactionID: "txn-1", Account: "alice", Amount: 300},
+		domain.MoneyCredited{TransactionID: "txn-1", Account: "bob", Amount: 300},
+	}))
+
+	// Garble an amount: the line is still valid JSON, only the checksum
+	// tells it from the original
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(data), "\n")
+	require.Contains(t, lines[1], `"amount":300`)
+	lines[1] = strings.Replace(lines[1], `"amount":300`, `"amount":900`, 1)
+	// A line written before checksums is still accepted
+	lines = append(lines, `{"type":"MoneyDeposited","seq":4,"timestamp":"2025-01-01T00:00:00Z","data":{"transaction_id":"old","account":"bob","amount":5}}`+"\n")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "")), 0644))
+
+	_, err = store.LoadAll()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+	assert.Contains(t, err.Error(), "checksum mismatch")
+
+	events, corrupt, err := store.LoadAllLenient()
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "seed", events[0].GetTransactionID())
+	assert.Equal(t, domain.MoneyCredited{TransactionID: "txn-1", Account: "bob", Amount: 300, Currency: "USD"}, events[1])
+	assert.Equal(t, "old", events[2].GetTransactionID())
+	require.Len(t, corrupt, 1)
+	assert.Equal(t, 2, corrupt[0].Line)
+	assert.Equal(t, int64(len(lines[0])), corrupt[0].Offset)
+	assert.Contains(t, corrupt[0].Reason, "checksum mismatch")
+	assert.Equal(t, corrupt, store.CorruptLines())
+
+	// A store opened in skip mode reports the same line
+	skipping, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{ReplayMode: eventstore.ReplaySkip})
+	require.NoError(t, err)
+	defer skipping.Close()
+	assert.Equal(t, corrupt, skipping.CorruptLines())
+	assert.Equal(t, uint64(4), skipping.LastSequence())
+}
+
+func TestEventStore_ChecksumDetectsGarbledBinaryRecord(t *testing.T) {
+	path := t.TempDir() + "/events.log"
+	store, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{Codec: domain.BinaryCodec})
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Append(domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1000}))
+	require.NoError(t, store.Append(domain.MoneyCredited{TransactionID: "gift", Account: "bob", Amount: 50}))
+
+	// Flip a bit in the last byte of the file, inside the second record
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0x01
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	_, err = store.LoadAll()
+	assert.ErrorContains(t, err, "record 2")
+
+	events, corrupt, err := store.LoadAllLenient()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "seed", events[0].GetTransactionID())
+	require.Len(t, corrupt, 1)
+	assert.Equal(t, 2, corrupt[0].Line)
+}
+
+func newReplicatedLogs(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	return dir + "/events.log", dir + "/events.replica.log"
+}
+
+func TestReplicatedEventStore_BothLogsIdentical(t *testing.T) {
+	primary, replica := newReplicatedLogs(t)
+	store, err := eventstore.NewReplicatedEventStore(primary, replica)
+	require.NoError(t, err)
+	assert.Equal(t, replica, store.ReplicaPath())
+
+	eng := engine.NewWalletEngine(store, nil)
+	eng.SetBalance("alice", 1000)
+	for i := 0; i < 5; i++ {
+		_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: generateTestTxnID(i), FromAccount: "alice", ToAccount: "bob", Amount: 100,
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, store.Close())
+
+	primaryData, err := os.ReadFile(primary)
+	require.NoError(t, err)
+	replicaData, err := os.ReadFile(replica)
+	require.NoError(t, err)
+	assert.NotEmpty(t, primaryData)
+	assert.Equal(t, primaryData, replicaData)
+
+	// The replica alone rebuilds the same state
+	fromReplica, err := eventstore.NewEventStore(replica)
+	require.NoError(t, err)
+	defer fromReplica.Close()
+	restored := engine.NewWalletEngine(fromReplica, nil)
+	restored.SetBalance("alice", 1000)
+	require.NoError(t, restored.InitializeFromEventStore())
+	assert.Equal(t, eng.GetAllBalances(), restored.GetAllBalances())
+	assert.Equal(t, uint64(10), fromReplica.LastSequence())
+}
+
+func TestReplicatedEventStore_BatchAtomicAcrossLogs(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("needs /dev/full to fail replica writes")
+	}
+	primary, _ := newReplicatedLogs(t)
+
+	// Every replica write fails with ENOSPC
+	store, err := eventstore.NewReplicatedEventStore(primary, "/dev/full")
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyDeducted{TransactionID: "txn-1", Account: "alice", Amount: 100},
+		domain.MoneyCredited{TransactionID: "txn-1", Account: "bob", Amount: 100},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "replica")
+
+	// The primary write was rolled back with it
+	events, err := store.LoadAll()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, uint64(0), store.LastSequence())
+	info, err := os.Stat(primary)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+func TestReplicatedEventStore_FailsOverToReplica(t *testing.T) {
+	primary, replica := newReplicatedLogs(t)
+
+	// An existing log is copied into a new, empty replica
+	seed, err := eventstore.NewEventStore(primary)
+	require.NoError(t, err)
+	_, err = seed.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1000},
+	})
+	require.NoError(t, err)
+	seed.Close()
+
+	store, err := eventstore.NewReplicatedEventStore(primary, replica)
+	require.NoError(t, err)
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyDeducted{TransactionID: "txn-1", Account: "alice", Amount: 400},
+		domain.MoneyCredited{TransactionID: "txn-1", Account: "bob", Amount: 400},
+	})
+	require.NoError(t, err)
+	store.Close()
+
+	// Corrupt the primary
+	f, err := os.OpenFile(primary, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("not json\n")
+	require.NoError(t, err)
+	f.Close()
+	_, err = eventstore.NewEventStore(primary)
+	require.Error(t, err)
+
+	store, err = eventstore.NewReplicatedEventStore(primary, replica)
+	require.NoError(t, err)
+	defer store.Close()
+	eng := engine.NewWalletEngine(store, nil)
+	require.NoError(t, eng.InitializeFromEventStore())
+	assert.Equal(t, map[string]int64{"alice": 600, "bob": 400}, eng.GetAllBalances())
+	assert.Equal(t, uint64(3), store.LastSequence())
+}
+
+func TestEventStore_RotatesSegmentsBySize(t *testing.T) {
+	path := t.TempDir() + "/events.log"
+	store, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{MaxSegmentBytes: 600})
+	require.NoError(t, err)
+
+	// Each transfer is a two-event batch of roughly 300 bytes
+	eng := engine.NewWalletEngine(store, nil)
+	eng.SetBalance("alice", 1000)
+	for i := 0; i < 6; i++ {
+		_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: generateTestTxnID(i), FromAccount: "alice", ToAccount: "bob", Amount: 100,
+		})
+		require.NoError(t, err)
+	}
+
+	segments := store.SegmentPaths()
+	require.Greater(t, len(segments), 1)
+	assert.Equal(t, path, segments[0])
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "events.000002.log"), segments[1])
+	for _, segment := range segments {
+		info, err := os.Stat(segment)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(600), segment)
+
+		// No batch is split: every segment holds whole transfers
+		events := readSegment(t, segment)
+		require.Zero(t, len(events)%2, segment)
+		for i := 0; i < len(events); i += 2 {
+			assert.Equal(t, events[i].Event.GetTransactionID(), events[i+1].Event.GetTransactionID())
+		}
+	}
+
+	events, err := store.LoadSince(0)
+	require.NoError(t, err)
+	require.Len(t, events, 12)
+	for i, se := range events {
+		assert.Equal(t, uint64(i+1), se.Sequence)
+	}
+
+	// Snapshot offsets count across segments
+	require.NoError(t, store.WriteSnapshot(eventstore.Snapshot{Sequence: 8}))
+	snap, err := store.LoadLatestSnapshot()
+	require.NoError(t, err)
+	var before int64
+	for _, segment := range segments {
+		data, err := os.ReadFile(segment)
+		require.NoError(t, err)
+		for _, line := range strings.SplitAfter(string(data), "\n") {
+			se, err := domain.DeserializeSequencedEvent([]byte(withoutChecksum(line)))
+			if err == nil && se.Sequence <= 8 {
+				before += int64(len(line))
+			}
+		}
+	}
+	assert.Equal(t, before, snap.Offset)
+	require.NoError(t, store.Close())
+
+	// Reopening continues in the newest segment
+	reopened, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{MaxSegmentBytes: 600})
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, uint64(12), reopened.LastSequence())
+	assert.Equal(t, segments, reopened.SegmentPaths())
+	after, err := reopened.LoadSince(8)
+	require.NoError(t, err)
+	assert.Len(t, after, 4)
+	require.NoError(t, os.Remove(reopened.SnapshotPath()))
+
+	restored := engine.NewWalletEngine(reopened, nil)
+	restored.SetBalance("alice", 1000)
+	require.NoError(t, restored.InitializeFromEventStore())
+	assert.Equal(t, int64(400), restored.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(600), restored.GetBalance("bob", "USD"))
+}
+
+func TestEventStore_OversizedBatchGetsItsOwnSegment(t *testing.T) {
+	path := t.TempDir() + "/events.log"
+	store, err := eventstore.NewEventStoreWithOptions(path, eventstore.Options{MaxSegmentBytes: 100})
+	require.NoError(t, err)
+	defer store.Close()
+
+	credit := func(id string) domain.Event {
+		return domain.MoneyCredited{TransactionID: id, Account: "alice", Amount: 10}
+	}
+	require.NoError(t, store.Append(credit("a")))
+	require.NoError(t, store.AppendBatch([]domain.Event{credit("b"), credit("c"), credit("d")}))
+	require.NoError(t, store.Append(credit("e")))
+
+	segments := store.SegmentPaths()
+	require.Len(t, segments, 3)
+	assert.Len(t, readSegment(t, segments[0]), 1)
+	assert.Len(t, readSegment(t, segments[1]), 3)
+	assert.Len(t, readSegment(t, segments[2]), 1)
+
+	all, err := store.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+	assert.Equal(t, "e", all[4].GetTransactionID())
+
+	// Clear drops the later segments
+	require.NoError(t, store.Clear())
+	assert.Equal(t, []string{path}, store.SegmentPaths())
+	_, err = os.Stat(segments[1])
+	assert.True(t, os.IsNotExist(err))
+}
+
+// readSegment decodes the events in one segment file
+func readSegment(t *testing.T, path string) []domain.SequencedEvent {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var events []domain.SequencedEvent
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		se, err := domain.DeserializeSequencedEvent([]byte(withoutChecksum(line)))
+		require.NoError(t, err)
+		events = append(events, se)
+	}
+	return events
+}
+
+// withoutChecksum strips the checksum from a stored JSON line
+func withoutChecksum(line string) string {
+	line = strings.TrimSuffix(line, "\n")
+	if i := strings.LastIndexByte(line, '\t'); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newExportStore writes one batch before and one after the returned cutoff
+func newExportStore(t *testing.T) (*eventstore.EventStore, time.Time) {
+	t.Helper()
+
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	tmpFile.Close()
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MinimumBalanceSet{Account: "alice", Floor: 50},
+		domain.MoneyDeducted{TransactionID: "txn-1", Account: "alice", Amount: 100},
+		domain.MoneyCredited{TransactionID: "txn-1", Account: "bob", Amount: 100},
+	})
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.TransactionFailed{TransactionID: "txn-2", FromAccount: "carol", Reason: "insufficient funds"},
+	})
+	require.NoError(t, err)
+
+	return store, cutoff
+}
+
+func readCSV(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	return rows
+}
+
+func TestEventStore_ExportCSV(t *testing.T) {
+	store, cutoff := newExportStore(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, store.ExportCSV(&buf, time.Time{}, time.Time{}))
+	rows := readCSV(t, buf.Bytes())
+
+	require.Len(t, rows, 5) // header + one row per event
+	assert.Equal(t, []string{"type", "txn_id", "account", "amount", "timestamp", "currency"}, rows[0])
+	assert.Equal(t, []string{"MinimumBalanceSet", "", "alice", ""}, rows[1][:4])
+	assert.Equal(t, []string{"MoneyDeducted", "txn-1", "alice", "100"}, rows[2][:4])
+	assert.Equal(t, []string{"MoneyCredited", "txn-1", "bob", "100"}, rows[3][:4])
+	assert.Equal(t, []string{"TransactionFailed", "txn-2", "carol", ""}, rows[4][:4])
+	assert.Equal(t, "USD", rows[2][5])
+	assert.Equal(t, "", rows[4][5])
+	for _, row := range rows[1:] {
+		_, err := time.Parse(time.RFC3339Nano, row[4])
+		assert.NoError(t, err)
+	}
+
+	// Time filter: from is inclusive, to exclusive
+	buf.Reset()
+	require.NoError(t, store.ExportCSV(&buf, time.Time{}, cutoff))
+	rows = readCSV(t, buf.Bytes())
+	require.Len(t, rows, 4)
+	assert.Equal(t, "MoneyCredited", rows[3][0])
+
+	buf.Reset()
+	require.NoError(t, store.ExportCSV(&buf, cutoff, time.Time{}))
+	rows = readCSV(t, buf.Bytes())
+	require.Len(t, rows, 2)
+	assert.Equal(t, "TransactionFailed", rows[1][0])
+}
+
+func TestExportEvents_Endpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, cutoff := newExportStore(t)
+
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), nil)
+	h.EnableEventExport(store)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	get := func(query url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/events/export?"+query.Encode(), nil))
+		return w
+	}
+
+	w := get(url.Values{"format": {"csv"}, "from": {cutoff.Format(time.RFC3339Nano)}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	rows := readCSV(t, w.Body.Bytes())
+	require.Len(t, rows, 2)
+	assert.Equal(t, "txn-2", rows[1][1])
+
+	assert.Equal(t, http.StatusBadRequest, get(url.Values{"format": {"parquet"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, get(url.Values{"from": {"yesterday"}}).Code)
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFee_Policy(t *testing.T) {
+	p := engine.FeePolicy{Flat: 25, BasisPoints: 150}
+	assert.Equal(t, int64(25), p.Fee(0))
+	assert.Equal(t, int64(25+150), p.Fee(10000))
+	assert.Equal(t, int64(25+1), p.Fee(99), "rounded down")
+	assert.Equal(t, int64(0), engine.FeePolicy{Account: "fees"}.Fee(10000))
+
+	eng, _ := setupTransferModeTest(t)
+	assert.Error(t, eng.SetFeePolicy(engine.FeePolicy{Flat: -1}))
+	assert.Error(t, eng.SetFeePolicy(engine.FeePolicy{BasisPoints: 10001}))
+	require.NoError(t, eng.SetFeePolicy(engine.FeePolicy{Flat: 10}))
+	assert.Equal(t, engine.DefaultFeeAccount, eng.FeePolicy().Account)
+}
+
+func TestFee_ChargedOnTopOfTransfer(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	require.NoError(t, eng.SetFeePolicy(engine.FeePolicy{Account: "house", Flat: 10, BasisPoints: 100}))
+	eng.SetBalance("alice", 1000)
+	eng.SetBalance("bob", 0)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "pay", FromAccount: "alice", ToAccount: "bob", Amount: 500, Memo: "rent",
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, domain.MoneyDeducted{TransactionID: "pay", Account: "alice", Amount: 500, Currency: "USD", Memo: "rent"}, events[0])
+	assert.Equal(t, domain.MoneyCredited{TransactionID: "pay", Account: "bob", Amount: 500, Currency: "USD", Memo: "rent"}, events[1])
+	assert.Equal(t, domain.MoneyDeducted{TransactionID: "pay", Account: "alice", Amount: 15, Currency: "USD", Fee: true}, events[2])
+	assert.Equal(t, domain.MoneyCredited{TransactionID: "pay", Account: "house", Amount: 15, Currency: "USD", Fee: true}, events[3])
+	assert.Equal(t, map[string]int64{"alice": 485, "bob": 500, "house": 15}, eng.GetAllBalances())
+
+	// The outcome of the transfer keeps all four events
+	outcome, ok := eng.Outcome("pay")
+	require.True(t, ok)
+	assert.Len(t, outcome.Events, 4)
+
+	// Amount and fee together must be covered: 480 + 10 + 4 > 485
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "too-much", FromAccount: "alice", ToAccount: "bob", Amount: 480,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+
+	// The fee account pays no fee on its own transfers
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "payout", FromAccount: "house", ToAccount: "bob", Amount: 15,
+	})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestFee_AllModeLeavesRoomForFee(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	require.NoError(t, eng.SetFeePolicy(engine.FeePolicy{Flat: 5, BasisPoints: 100}))
+	eng.SetBalance("alice", 1010)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "sweep", FromAccount: "alice", ToAccount: "bob", Mode: domain.TransferModeAll,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	// 996 + 5 + 9 = 1010; one more cent would need a 10 cent share
+	assert.Equal(t, int64(996), events[0].(domain.MoneyDeducted).Amount)
+	assert.Equal(t, int64(14), events[2].(domain.MoneyDeducted).Amount)
+	assert.Equal(t, int64(0), eng.GetBalance("alice", "USD"))
+
+	// A balance that does not cover the flat fee moves nothing
+	eng.SetBalance("alice", 4)
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "sweep-again", FromAccount: "alice", ToAccount: "bob", Mode: domain.TransferModeAll,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+}
+
+// Total-balance conservation with fees: the fee account is one of the
+// accounts, so what leaves senders in fees shows up there
+func TestFee_TotalBalanceConservedWithFeeAccount(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	require.NoError(t, eng.SetFeePolicy(engine.FeePolicy{Flat: 1, BasisPoints: 250}))
+	eng.SetBalance("a", 1000)
+	eng.SetBalance("b", 2000)
+	eng.SetBalance("c", 3000)
+	initialTotal := eng.GetTotalBalance()
+
+	accounts := []string{"a", "b", "c"}
+	var fees int64
+	for i := 0; i < 100; i++ {
+		events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: fmt.Sprintf("txn-%d", i),
+			FromAccount:   accounts[i%3],
+			ToAccount:     accounts[(i+1)%3],
+			Amount:        int64(10 + i%50),
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 4)
+		fees += events[2].(domain.MoneyDeducted).Amount
+	}
+
+	assert.Equal(t, fees, eng.GetBalance(engine.DefaultFeeAccount, "USD"))
+	assert.Equal(t, initialTotal, eng.GetTotalBalance(), "fees move money, they do not create or destroy it")
+
+	// Replay reproduces every balance, the fee account's included
+	restarted := engine.NewWalletEngine(store, nil)
+	restarted.SetBalance("a", 1000)
+	restarted.SetBalance("b", 2000)
+	restarted.SetBalance("c", 3000)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, eng.GetAllBalances(), restarted.GetAllBalances())
+	assert.Equal(t, initialTotal, restarted.GetTotalBalance())
+
+	// So does the read model
+	rm := cqrs.NewReadModel(nil)
+	rm.SetBalance("a", 1000)
+	rm.SetBalance("b", 2000)
+	rm.SetBalance("c", 3000)
+	require.NoError(t, rm.InitializeFromEventStore(store))
+	assert.Equal(t, eng.GetAllBalances(), rm.GetAllBalances())
+	history := rm.GetHistory("a", 1, 0)
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Fee)
+	assert.Equal(t, engine.DefaultFeeAccount, history[0].Counterparty)
+}
+
+func TestFee_ReversalDoesNotRefundFee(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	require.NoError(t, eng.SetFeePolicy(engine.FeePolicy{Flat: 20}))
+	eng.SetBalance("alice", 1000)
+	eng.SetBalance("bob", 0)
+
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "pay", FromAccount: "alice", ToAccount: "bob", Amount: 300,
+	})
+	require.NoError(t, err)
+
+	events, err := eng.Reverse(context.Background(), domain.ReverseCommand{TransactionID: "undo", OriginalTransactionID: "pay"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.MoneyDeducted{TransactionID: "undo", Account: "bob", Amount: 300, Currency: "USD", Reverses: "pay"}, events[0])
+	assert.Equal(t, map[string]int64{"alice": 980, "bob": 0, "fees": 20}, eng.GetAllBalances())
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeze_BlocksMoneyLeavingOnly(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1000)
+	eng.SetBalance("bob", 1000)
+	require.NoError(t, eng.Freeze(domain.FreezeCommand{Account: "alice", Reason: "fraud review"}))
+	assert.True(t, eng.IsFrozen("alice"))
+	assert.Error(t, eng.Freeze(domain.FreezeCommand{}))
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "out", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "account frozen", events[0].(domain.TransactionFailed).Reason)
+
+	events, err = eng.Withdraw(context.Background(), domain.WithdrawCommand{TransactionID: "cash", Account: "alice", Amount: 100})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "account frozen", events[0].(domain.TransactionFailed).Reason)
+
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "later", FromAccount: "alice", ToAccount: "bob", Amount: 100, ExecuteAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "account frozen", events[0].(domain.TransactionFailed).Reason)
+
+	// Money still comes in
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "in", FromAccount: "bob", ToAccount: "alice", Amount: 100,
+	})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+	events, err = eng.Deposit(context.Background(), domain.DepositCommand{TransactionID: "dep", Account: "alice", Amount: 50})
+	require.NoError(t, err)
+	assert.IsType(t, domain.MoneyDeposited{}, events[0])
+	assert.Equal(t, int64(1150), eng.GetBalance("alice", "USD"))
+
+	require.NoError(t, eng.Unfreeze(domain.UnfreezeCommand{Account: "alice"}))
+	assert.False(t, eng.IsFrozen("alice"))
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "out-again", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestFreeze_ScheduledTransferFailsWhenDueWhileFrozen(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	eng.SetClock(func() time.Time { return now })
+	eng.SetBalance("alice", 1000)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "rent", FromAccount: "alice", ToAccount: "landlord", Amount: 500, ExecuteAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.IsType(t, domain.TransferScheduled{}, events[0])
+
+	require.NoError(t, eng.Freeze(domain.FreezeCommand{Account: "alice"}))
+	now = now.Add(2 * time.Hour)
+	ran, err := eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	outcome, ok := eng.Outcome("rent")
+	require.True(t, ok)
+	assert.Equal(t, "account frozen", outcome.Reason)
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+}
+
+func TestFreeze_SurvivesReplayAndSnapshot(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	defer os.Remove(store.SnapshotPath())
+	_, err := store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 100},
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.InitializeFromEventStore())
+	require.NoError(t, eng.Freeze(domain.FreezeCommand{Account: "alice"}))
+	require.NoError(t, eng.Freeze(domain.FreezeCommand{Account: "bob"}))
+	require.NoError(t, eng.Unfreeze(domain.UnfreezeCommand{Account: "bob"}))
+
+	restarted := engine.NewWalletEngine(store, nil)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.True(t, restarted.IsFrozen("alice"))
+	assert.False(t, restarted.IsFrozen("bob"))
+
+	rm := cqrs.NewReadModel(nil)
+	require.NoError(t, rm.InitializeFromEventStore(store))
+	assert.True(t, rm.IsFrozen("alice"))
+	assert.False(t, rm.IsFrozen("bob"))
+
+	// A snapshot carries the freeze when the events before it are skipped
+	require.NoError(t, restarted.SetSnapshotPolicy(1, 0))
+	require.NoError(t, restarted.Freeze(domain.FreezeCommand{Account: "carol"}))
+	require.NoError(t, restarted.Stop())
+	snap, err := store.LoadLatestSnapshot()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	assert.Equal(t, map[string]bool{"alice": true, "carol": true}, snap.Frozen)
+
+	fromSnapshot := engine.NewWalletEngine(store, nil)
+	require.NoError(t, fromSnapshot.InitializeFromEventStore())
+	assert.True(t, fromSnapshot.IsFrozen("alice"))
+	assert.True(t, fromSnapshot.IsFrozen("carol"))
+}
+
+func TestFreeze_API(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eng, _ := setupTransferModeTest(t)
+	rm := cqrs.NewReadModel(nil)
+	rm.SetBalance("alice", 1000)
+	eng.RegisterEventHandler(rm.HandleSequencedEvent)
+	router := gin.New()
+	handler.SetupRoutes(router, handler.NewHandler(nil, rm, eng))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+	balance := func() handler.BalanceResponse {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/balance/alice", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp handler.BalanceResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	assert.False(t, balance().Frozen)
+	require.Equal(t, http.StatusOK, post("/v1/wallet/freeze", `{"account":"alice","reason":"chargeback"}`).Code)
+	assert.True(t, eng.IsFrozen("alice"))
+	assert.Equal(t, handler.BalanceResponse{Account: "alice", Currency: "USD", Balance: 1000, Frozen: true}, balance())
+
+	require.Equal(t, http.StatusOK, post("/v1/wallet/unfreeze", `{"account":"alice"}`).Code)
+	assert.False(t, balance().Frozen)
+
+	assert.Equal(t, http.StatusBadRequest, post("/v1/wallet/freeze", `{}`).Code)
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	walletgrpc "github.com/nathanyu/digital-wallet/internal/grpc"
+	"github.com/nathanyu/digital-wallet/internal/grpc/walletpb"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/nathanyu/digital-wallet/internal/queue"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startGRPCTest serves h's API over an in-memory connection and returns a client
+func startGRPCTest(t *testing.T, h *handler.Handler, readModel *cqrs.ReadModel, eng *engine.WalletEngine) walletpb.WalletServiceClient {
+	lis := bufconn.Listen(1024 * 1024)
+	srv := walletgrpc.NewGRPCServer(walletgrpc.NewServer(h, readModel, eng))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return walletpb.NewWalletServiceClient(conn)
+}
+
+func TestGRPC_InitAccountAndBalances(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	readModel := cqrs.NewReadModel(nil)
+	client := startGRPCTest(t, handler.NewHandler(nil, readModel, eng), readModel, eng)
+	ctx := context.Background()
+
+	init, err := client.InitAccount(ctx, &walletpb.InitAccountRequest{Account: "alice", Balance: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", init.Account)
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+
+	bal, err := client.GetBalance(ctx, &walletpb.GetBalanceRequest{Account: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", bal.Currency)
+	assert.Equal(t, int64(1000), bal.Balance)
+
+	// Unknown accounts have a 0 balance, as over HTTP
+	bal, err = client.GetBalance(ctx, &walletpb.GetBalanceRequest{Account: "nobody"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), bal.Balance)
+
+	_, err = client.InitAccount(ctx, &walletpb.InitAccountRequest{Account: "bob", Balance: 500})
+	require.NoError(t, err)
+	all, err := client.GetAllBalances(ctx, &walletpb.GetAllBalancesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"alice": 1000, "bob": 500}, all.Balances)
+	assert.Equal(t, int64(1500), all.TotalBalance)
+	assert.Equal(t, int64(2), all.AccountCount)
+
+	_, err = client.InitAccount(ctx, &walletpb.InitAccountRequest{Account: "carol", Balance: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// Transfers are validated by the same code as the HTTP handler
+func TestGRPC_TransferValidation(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	readModel := cqrs.NewReadModel(nil)
+	client := startGRPCTest(t, handler.NewHandler(nil, readModel, eng), readModel, eng)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     *walletpb.TransferRequest
+		message string
+	}{
+		{"non-positive amount", &walletpb.TransferRequest{FromAccount: "alice", ToAccount: "bob"}, "amount must be positive"},
+		{"bad percent", &walletpb.TransferRequest{FromAccount: "alice", ToAccount: "bob", Mode: "percent", Percent: 101}, "percent must be between 1 and 100"},
+		{"bad mode", &walletpb.TransferRequest{FromAccount: "alice", ToAccount: "bob", Mode: "half", Amount: 1}, "mode must be 'exact', 'all' or 'percent'"},
+		{"priority lane disabled", &walletpb.TransferRequest{FromAccount: "alice", ToAccount: "bob", Amount: 1, Priority: true}, "priority lane is not enabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Transfer(ctx, tt.req)
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			assert.Equal(t, codes.InvalidArgument, st.Code())
+			assert.Equal(t, tt.message, st.Message())
+		})
+	}
+}
+
+func TestGRPC_UnavailableUntilReady(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	readModel := cqrs.NewReadModel(nil)
+	h := handler.NewHandler(nil, readModel, eng)
+	h.SetReady(false)
+	client := startGRPCTest(t, h, readModel, eng)
+
+	_, err := client.GetBalance(context.Background(), &walletpb.GetBalanceRequest{Account: "alice"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	h.SetReady(true)
+	_, err = client.GetBalance(context.Background(), &walletpb.GetBalanceRequest{Account: "alice"})
+	assert.NoError(t, err)
+}
+
+// A transfer returns its transaction ID in the response and its header, and
+// a retry with the same ID is answered as a duplicate
+func TestGRPC_TransferIdempotent(t *testing.T) {
+	nc, err := nats.Connect(nats.DefaultURL, nats.NoReconnect())
+	if err != nil {
+		t.Skip("NATS server not available")
+	}
+	defer nc.Close()
+
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store.Close()
+
+	eng := engine.NewWalletEngine(store, nc)
+	eng.SetBalance("alice", 1000)
+	require.NoError(t, eng.Start())
+	defer eng.Stop()
+
+	natsClient, err := queue.NewNATSClient(nats.DefaultURL)
+	require.NoError(t, err)
+	defer natsClient.Close()
+	readModel := cqrs.NewReadModel(nil)
+	client := startGRPCTest(t, handler.NewHandler(natsClient, readModel, eng), readModel, eng)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var header metadata.MD
+	resp, err := client.Transfer(ctx, &walletpb.TransferRequest{FromAccount: "alice", ToAccount: "bob", Amount: 100}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	require.NotEmpty(t, resp.TransactionId)
+	assert.Equal(t, []string{resp.TransactionId}, header.Get(walletgrpc.TransactionIDHeader))
+
+	retry, err := client.Transfer(ctx, &walletpb.TransferRequest{
+		FromAccount: "alice", ToAccount: "bob", Amount: 100, TransactionId: resp.TransactionId,
+	})
+	require.NoError(t, err)
+	assert.True(t, retry.Duplicate)
+	assert.Equal(t, int64(900), eng.GetBalance("alice", "USD"))
+}
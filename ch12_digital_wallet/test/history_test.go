@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory_RecordsBalanceChangesNewestFirst(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	rm.HandleEventDirect(domain.MoneyDeposited{TransactionID: "dep", Account: "alice", Amount: 1000, Currency: "USD"})
+	rm.HandleEventDirect(domain.MoneyDeducted{TransactionID: "pay", Account: "alice", Amount: 300, Currency: "USD", Memo: "invoice #123"})
+	rm.HandleEventDirect(domain.MoneyCredited{TransactionID: "pay", Account: "bob", Amount: 300, Currency: "USD", Memo: "invoice #123"})
+	rm.HandleEventDirect(domain.TransactionFailed{TransactionID: "fail", FromAccount: "alice", Reason: "insufficient funds"})
+	rm.HandleEventDirect(domain.MoneyWithdrawn{TransactionID: "wd", Account: "alice", Amount: 200, Currency: "USD"})
+
+	assert.Equal(t, []cqrs.HistoryEntry{
+		{TransactionID: "wd", Type: domain.EventTypeMoneyWithdrawn, Amount: -200, Currency: "USD", Balance: 500},
+		{TransactionID: "pay", Type: domain.EventTypeMoneyDeducted, Amount: -300, Currency: "USD", Balance: 700, Counterparty: "bob", Memo: "invoice #123"},
+		{TransactionID: "dep", Type: domain.EventTypeMoneyDeposited, Amount: 1000, Currency: "USD", Balance: 1000},
+	}, rm.GetHistory("alice", 0, 0))
+	assert.Equal(t, []cqrs.HistoryEntry{
+		{TransactionID: "pay", Type: domain.EventTypeMoneyCredited, Amount: 300, Currency: "USD", Balance: 300, Counterparty: "alice", Memo: "invoice #123"},
+	}, rm.GetHistory("bob", 0, 0))
+
+	// Paging
+	page := rm.GetHistory("alice", 1, 1)
+	require.Len(t, page, 1)
+	assert.Equal(t, "pay", page[0].TransactionID)
+	assert.Empty(t, rm.GetHistory("alice", 10, 3))
+	assert.Empty(t, rm.GetHistory("carol", 10, 0))
+}
+
+func TestHistory_LengthIsCapped(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	rm.SetHistoryLength(3)
+	for i := 1; i <= 5; i++ {
+		rm.HandleEventDirect(domain.MoneyDeposited{TransactionID: generateTestTxnID(i), Account: "alice", Amount: 10})
+	}
+
+	history := rm.GetHistory("alice", 0, 0)
+	require.Len(t, history, 3)
+	assert.Equal(t, generateTestTxnID(5), history[0].TransactionID)
+	assert.Equal(t, generateTestTxnID(3), history[2].TransactionID)
+	assert.Equal(t, int64(50), history[0].Balance)
+
+	rm.SetHistoryLength(1)
+	assert.Len(t, rm.GetHistory("alice", 0, 0), 1)
+	rm.SetHistoryLength(0)
+	rm.HandleEventDirect(domain.MoneyDeposited{TransactionID: "more", Account: "alice", Amount: 10})
+	assert.Empty(t, rm.GetHistory("alice", 0, 0))
+}
+
+func TestHistory_RebuiltFromEventStore(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	ctx := context.Background()
+	_, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep", Account: "alice", Amount: 1000, Currency: "EUR"})
+	require.NoError(t, err)
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{TransactionID: "pay", FromAccount: "alice", ToAccount: "bob", Amount: 400, Currency: "EUR", Memo: "rent"})
+	require.NoError(t, err)
+	_, err = eng.Reverse(ctx, domain.ReverseCommand{TransactionID: "rev", OriginalTransactionID: "pay"})
+	require.NoError(t, err)
+
+	rm := cqrs.NewReadModel(nil)
+	require.NoError(t, rm.InitializeFromEventStore(store))
+	assert.Equal(t, []cqrs.HistoryEntry{
+		{TransactionID: "rev", Type: domain.EventTypeMoneyDeducted, Amount: -400, Currency: "EUR", Balance: 0, Counterparty: "alice", Reverses: "pay"},
+		{TransactionID: "pay", Type: domain.EventTypeMoneyCredited, Amount: 400, Currency: "EUR", Balance: 400, Counterparty: "alice", Memo: "rent"},
+	}, rm.GetHistory("bob", 0, 0))
+	assert.Len(t, rm.GetHistory("alice", 0, 0), 3)
+
+	// Resyncing does not duplicate entries
+	require.NoError(t, rm.Resync())
+	assert.Len(t, rm.GetHistory("alice", 0, 0), 3)
+}
+
+func TestHistory_API(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	for i := 1; i <= 3; i++ {
+		rm.HandleEventDirect(domain.MoneyDeposited{TransactionID: generateTestTxnID(i), Account: "alice", Amount: 10})
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.SetupRoutes(router, handler.NewHandler(nil, rm, nil))
+
+	get := func(path string) (int, handler.HistoryResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp handler.HistoryResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := get("/v1/wallet/history/alice?limit=2")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "alice", resp.Account)
+	require.Len(t, resp.Entries, 2)
+	assert.Equal(t, generateTestTxnID(3), resp.Entries[0].TransactionID)
+
+	code, resp = get("/v1/wallet/history/alice?offset=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Entries, 1)
+	assert.Equal(t, generateTestTxnID(1), resp.Entries[0].TransactionID)
+
+	code, resp = get("/v1/wallet/history/nobody")
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Entries)
+
+	code, _ = get("/v1/wallet/history/alice?limit=-1")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,293 @@
+package test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Idempotency must survive a restart: processedTxns is rebuilt purely by
+// replaying the event store, so a retried command after restart is deduped.
+func TestIdempotency_SurvivesRestart(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+
+	// Seed balances through the log so replay reproduces them
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1000},
+	})
+	require.NoError(t, err)
+
+	eng := engine.NewWalletEngine(store, nil)
+	require.NoError(t, eng.InitializeFromEventStore())
+
+	cmd := domain.TransferCommand{TransactionID: "txn-restart", FromAccount: "alice", ToAccount: "bob", Amount: 100}
+	events, err := eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	// "Restart": drop the engine and build a new one from the same log
+	store.Close()
+	store2, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store2.Close()
+
+	eng2 := engine.NewWalletEngine(store2, nil)
+	require.NoError(t, eng2.InitializeFromEventStore())
+	assert.Equal(t, int64(900), eng2.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(100), eng2.GetBalance("bob", "USD"))
+
+	// The retried command is recognized from replayed state
+	events, err = eng2.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	assert.Empty(t, events, "duplicate after restart should produce no events")
+	assert.Equal(t, int64(900), eng2.GetBalance("alice", "USD"))
+
+	loaded, err := store2.LoadAll()
+	require.NoError(t, err)
+	assert.Len(t, loaded, 3, "no new events should be persisted for the duplicate")
+}
+
+// A command whose events never reached the log (crash before persist) was
+// never committed, so its retry after restart must apply exactly once.
+func TestIdempotency_RetryAfterCrashBeforePersist(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1000},
+	})
+	require.NoError(t, err)
+
+	eng := engine.NewWalletEngine(store, nil)
+	require.NoError(t, eng.InitializeFromEventStore())
+
+	cmd := domain.TransferCommand{TransactionID: "txn-crash", FromAccount: "alice", ToAccount: "bob", Amount: 100}
+
+	// Events generated but the process dies before AppendBatch
+	events, err := eng.Execute(cmd)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	store.Close()
+
+	store2, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store2.Close()
+	eng2 := engine.NewWalletEngine(store2, nil)
+	require.NoError(t, eng2.InitializeFromEventStore())
+
+	// First retry applies, second is deduped
+	events, err = eng2.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	events, err = eng2.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	assert.Equal(t, int64(900), eng2.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(100), eng2.GetBalance("bob", "USD"))
+}
+
+// gatedStore blocks the first write until released, holding a command
+// between event generation and persistence.
+type gatedStore struct {
+	*eventstore.EventStore
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *gatedStore) AppendSequenced(events []domain.Event) ([]domain.SequencedEvent, error) {
+	s.once.Do(func() {
+		close(s.entered)
+		<-s.release
+	})
+	return s.EventStore.AppendSequenced(events)
+}
+
+// A duplicate that arrives while the original is still being persisted must
+// not slip past the idempotency check.
+func TestIdempotency_DuplicateBeforeOriginalPersisted(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	real, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer real.Close()
+
+	store := &gatedStore{EventStore: real, entered: make(chan struct{}), release: make(chan struct{})}
+	eng := engine.NewWalletEngine(store, nil)
+	eng.SetBalance("alice", 1000)
+
+	cmd := domain.TransferCommand{TransactionID: "txn-inflight", FromAccount: "alice", ToAccount: "bob", Amount: 100}
+
+	var original []domain.Event
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var err error
+		original, err = eng.ProcessCommand(context.Background(), cmd)
+		assert.NoError(t, err)
+	}()
+	<-store.entered
+
+	// Submit the duplicate while the original is blocked inside persistence
+	dupDone := make(chan []domain.Event)
+	go func() {
+		events, err := eng.ProcessCommand(context.Background(), cmd)
+		assert.NoError(t, err)
+		dupDone <- events
+	}()
+
+	close(store.release)
+	wg.Wait()
+	duplicate := <-dupDone
+
+	assert.Len(t, original, 2)
+	assert.Empty(t, duplicate, "in-flight duplicate should produce no events")
+	assert.Equal(t, int64(900), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(100), eng.GetBalance("bob", "USD"))
+
+	loaded, err := real.LoadAll()
+	require.NoError(t, err)
+	assert.Len(t, loaded, 2)
+}
+
+func TestIdempotency_DuplicateReturnsOriginalSuccess(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1000)
+
+	cmd := domain.TransferCommand{TransactionID: "txn-ok", FromAccount: "alice", ToAccount: "bob", Amount: 250}
+	original, err := eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	require.Len(t, original, 2)
+
+	events, err := eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	assert.Empty(t, events, "a duplicate persists nothing")
+
+	outcome, ok := eng.Outcome("txn-ok")
+	require.True(t, ok)
+	assert.True(t, outcome.Success())
+	assert.Equal(t, original, outcome.Events)
+}
+
+func TestIdempotency_DuplicateReturnsOriginalFailure(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 100)
+
+	cmd := domain.TransferCommand{TransactionID: "txn-broke", FromAccount: "alice", ToAccount: "bob", Amount: 500}
+	_, err := eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+
+	// Funds arriving later do not change the answer to a retry
+	eng.SetBalance("alice", 1000)
+	events, err := eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	outcome, ok := eng.Outcome("txn-broke")
+	require.True(t, ok)
+	assert.False(t, outcome.Success())
+	assert.Equal(t, "insufficient funds", outcome.Reason)
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+}
+
+func TestIdempotency_OutcomesRebuiltAndBounded(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	_, err := store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.InitializeFromEventStore())
+
+	for _, id := range []string{"t1", "t2", "t3"} {
+		_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 100,
+		})
+		require.NoError(t, err)
+	}
+
+	restarted := engine.NewWalletEngine(store, nil)
+	restarted.SetOutcomeCacheSize(2)
+	require.NoError(t, restarted.InitializeFromEventStore())
+
+	// The oldest outcome was evicted but the transaction is still deduplicated
+	_, ok := restarted.Outcome("t1")
+	assert.False(t, ok)
+	events, err := restarted.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "t1", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	outcome, ok := restarted.Outcome("t3")
+	require.True(t, ok)
+	require.Len(t, outcome.Events, 2)
+	assert.Equal(t, int64(100), outcome.Events[1].(domain.MoneyCredited).Amount)
+}
+
+func TestIdempotency_WindowForgetsOldestIDs(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1000)
+	eng.SetIdempotencyWindow(2)
+	evictions := testutil.ToFloat64(telemetry.IdempotencyEvictionsTotal)
+
+	transfer := func(eng *engine.WalletEngine, id string) int {
+		events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 10,
+		})
+		require.NoError(t, err)
+		return len(events)
+	}
+
+	require.Equal(t, 2, transfer(eng, "t1"))
+	require.Equal(t, 2, transfer(eng, "t2"))
+	require.Equal(t, 2, transfer(eng, "t3"))
+	assert.Equal(t, 2, eng.ProcessedTransactions())
+	assert.Equal(t, float64(2), testutil.ToFloat64(telemetry.IdempotencyCacheSize))
+	assert.Equal(t, evictions+1, testutil.ToFloat64(telemetry.IdempotencyEvictionsTotal))
+
+	// Duplicates inside the window produce no events
+	assert.Equal(t, 0, transfer(eng, "t2"))
+	assert.Equal(t, 0, transfer(eng, "t3"))
+
+	// t1 has left the window, so it runs again
+	assert.Equal(t, 2, transfer(eng, "t1"))
+	assert.Equal(t, int64(40), eng.GetBalance("bob", "USD"))
+
+	// Replay applies the same bound
+	restarted := engine.NewWalletEngine(store, nil)
+	restarted.SetIdempotencyWindow(2)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, 2, restarted.ProcessedTransactions())
+	assert.Equal(t, 0, transfer(restarted, "t1"))
+	assert.Equal(t, 0, transfer(restarted, "t3"))
+
+	// Shrinking the window forgets at once
+	restarted.SetIdempotencyWindow(1)
+	assert.Equal(t, 1, restarted.ProcessedTransactions())
+	assert.Equal(t, 0, transfer(restarted, "t1"))
+}
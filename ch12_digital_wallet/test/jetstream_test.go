@@ -0,0 +1,96 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/queue"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Commands published while the engine is down wait in the stream and are
+// processed once it starts again
+func TestJetStream_CommandsSurviveEngineDowntime(t *testing.T) {
+	nc, err := nats.Connect(nats.DefaultURL, nats.NoReconnect())
+	if err != nil {
+		t.Skip("NATS server not available")
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	if _, err := js.AccountInfo(); err != nil {
+		t.Skip("JetStream not enabled on the NATS server")
+	}
+	js.DeleteStream(engine.CommandStream)
+	defer js.DeleteStream(engine.CommandStream)
+
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store.Close()
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyDeposited{TransactionID: "seed", Account: "alice", Amount: 1000},
+	})
+	require.NoError(t, err)
+
+	client, err := queue.NewNATSClient(nats.DefaultURL)
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.EnableJetStream())
+
+	startEngine := func() *engine.WalletEngine {
+		eng := engine.NewWalletEngine(store, nc)
+		eng.EnableJetStream()
+		require.NoError(t, eng.InitializeFromEventStore())
+		require.NoError(t, eng.Start())
+		return eng
+	}
+
+	// Answered while the engine runs
+	eng := startEngine()
+	resp, err := client.PublishCommand(domain.TransferCommand{
+		TransactionID: "js-1", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	}, 5*time.Second)
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	require.NoError(t, eng.Stop())
+
+	// Kept while it is down
+	for _, id := range []string{"js-2", "js-3"} {
+		require.NoError(t, client.PublishCommandAsync(domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 100,
+		}))
+	}
+	info, err := js.StreamInfo(engine.CommandStream)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), info.State.Msgs)
+
+	restarted := startEngine()
+	defer restarted.Stop()
+	require.Eventually(t, func() bool {
+		return restarted.GetBalance("bob", "USD") == 300
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Acknowledged commands leave the stream
+	require.Eventually(t, func() bool {
+		info, err := js.StreamInfo(engine.CommandStream)
+		return err == nil && info.State.Msgs == 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// A resent command is answered as a duplicate
+	resp, err = client.PublishCommand(domain.TransferCommand{
+		TransactionID: "js-2", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	}, 5*time.Second)
+	require.NoError(t, err)
+	assert.True(t, resp.Duplicate)
+	assert.Equal(t, int64(700), restarted.GetBalance("alice", "USD"))
+}
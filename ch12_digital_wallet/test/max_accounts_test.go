@@ -0,0 +1,60 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxAccounts_NewAccountRejectedAtCap(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 10000)
+	eng.SetMaxAccounts(3)
+
+	transfer := func(id, from, to string) []domain.Event {
+		events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: id, FromAccount: from, ToAccount: to, Amount: 100,
+		})
+		require.NoError(t, err)
+		return events
+	}
+
+	// Fill up to the cap
+	for i, to := range []string{"bob", "carol"} {
+		require.Len(t, transfer(fmt.Sprintf("fill-%d", i), "alice", to), 2)
+	}
+	assert.Len(t, eng.GetAllBalances(), 3)
+
+	// A fourth account is refused and nothing moves
+	events := transfer("new-account", "alice", "dave")
+	require.Len(t, events, 1)
+	assert.Equal(t, "account limit reached", events[0].(domain.TransactionFailed).Reason)
+	assert.Len(t, eng.GetAllBalances(), 3)
+	assert.Equal(t, int64(9800), eng.GetBalance("alice", "USD"))
+
+	// Existing accounts still transact with each other
+	require.Len(t, transfer("existing-1", "alice", "bob"), 2)
+	require.Len(t, transfer("existing-2", "bob", "carol"), 2)
+	assert.Equal(t, int64(9700), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(100), eng.GetBalance("bob", "USD"))
+	assert.Equal(t, int64(200), eng.GetBalance("carol", "USD"))
+}
+
+func TestMaxAccounts_UnlimitedByDefault(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 10000)
+	assert.Equal(t, 0, eng.MaxAccounts())
+
+	for i := 0; i < 20; i++ {
+		events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: fmt.Sprintf("txn-%d", i), FromAccount: "alice", ToAccount: fmt.Sprintf("user-%d", i), Amount: 1,
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+	}
+	assert.Len(t, eng.GetAllBalances(), 21)
+}
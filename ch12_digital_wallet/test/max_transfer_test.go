@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxTransferAmount_AtLimitSucceedsAboveFails(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 100000)
+	eng.SetMaxTransferAmount(5000)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "at-max", FromAccount: "alice", ToAccount: "bob", Amount: 5000,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(5000), eng.GetBalance("bob", "USD"))
+
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "over-max", FromAccount: "alice", ToAccount: "bob", Amount: 5001,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "amount exceeds maximum", events[0].(domain.TransactionFailed).Reason)
+	assert.Equal(t, int64(95000), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(5000), eng.GetBalance("bob", "USD"))
+}
+
+func TestMaxTransferAmount_AppliesToResolvedAmount(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 10000)
+	eng.SetMaxTransferAmount(5000)
+
+	// Sweeping the whole balance would move 10000, over the cap
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "sweep", FromAccount: "alice", ToAccount: "bob", Mode: domain.TransferModeAll,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "amount exceeds maximum", events[0].(domain.TransactionFailed).Reason)
+
+	// Half of it fits
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "half", FromAccount: "alice", ToAccount: "bob", Mode: domain.TransferModePercent, Percent: 50,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(5000), eng.GetBalance("bob", "USD"))
+}
+
+func TestMaxTransferAmount_Configurable(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1<<40)
+
+	// No maximum by default
+	assert.Zero(t, eng.MaxTransferAmount())
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "large", FromAccount: "alice", ToAccount: "bob", Amount: 1 << 39,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	eng.SetMaxTransferAmount(1000)
+	assert.Equal(t, int64(1000), eng.MaxTransferAmount())
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "capped", FromAccount: "alice", ToAccount: "bob", Amount: 1001,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	// Clearing the cap lifts it again
+	eng.SetMaxTransferAmount(0)
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "uncapped", FromAccount: "alice", ToAccount: "bob", Amount: 1001,
+	})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
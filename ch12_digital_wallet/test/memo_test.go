@@ -0,0 +1,138 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemo_RoundTripsThroughEventStore(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1000)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "pay-1", FromAccount: "alice", ToAccount: "bob", Amount: 250, Memo: "invoice #123",
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	loaded, err := store.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.Equal(t, "invoice #123", loaded[0].(domain.MoneyDeducted).Memo)
+	assert.Equal(t, "invoice #123", loaded[1].(domain.MoneyCredited).Memo)
+
+	// Failed transfers keep the memo too
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "pay-2", FromAccount: "alice", ToAccount: "bob", Amount: 5000, Memo: "too much",
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "too much", events[0].(domain.TransactionFailed).Memo)
+}
+
+func TestMemo_EventsWithoutMemoStillDeserialize(t *testing.T) {
+	line := []byte(`{"type":"MoneyDeducted","seq":7,"timestamp":"2025-01-01T00:00:00Z","data":{"transaction_id":"old","account":"alice","amount":10}}`)
+	event, err := domain.DeserializeSequencedEvent(line)
+	require.NoError(t, err)
+	assert.Equal(t, domain.MoneyDeducted{TransactionID: "old", Account: "alice", Amount: 10, Currency: "USD"}, event.Event)
+
+	// And events without a memo are written without the field
+	data, err := domain.SerializeEvent(domain.MoneyCredited{TransactionID: "new", Account: "bob", Amount: 10})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "memo")
+}
+
+func TestMemo_OverLengthRejected(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1000)
+	eng.SetMaxMemoLength(10)
+
+	// Length counts characters, not bytes
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "fits", FromAccount: "alice", ToAccount: "bob", Amount: 1, Memo: "éééééééééé",
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "long", FromAccount: "alice", ToAccount: "bob", Amount: 1, Memo: "01234567890",
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "memo too long", events[0].(domain.TransactionFailed).Reason)
+	assert.Equal(t, int64(999), eng.GetBalance("alice", "USD"))
+
+	// The API rejects it up front, before a command is published
+	gin.SetMode(gin.TestMode)
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), eng)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	body, _ := json.Marshal(handler.TransferRequest{
+		FromAccount: "alice", ToAccount: "bob", Amount: 1, Memo: strings.Repeat("x", 11),
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/wallet/transfer", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "memo exceeds 10 characters")
+
+	loaded, err := store.LoadAll()
+	require.NoError(t, err)
+	assert.Len(t, loaded, 3, "the API rejection must not reach the engine")
+}
+
+func TestMemo_TransactionLookupIncludesMemo(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1000)
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "pay-1", FromAccount: "alice", ToAccount: "bob", Amount: 250, Memo: "invoice #123",
+	})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), eng)
+	h.EnableEventStream(store, nil)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/transaction/pay-1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		TransactionID string `json:"transaction_id"`
+		Success       bool   `json:"success"`
+		Memo          string `json:"memo"`
+		Events        []struct {
+			Sequence uint64 `json:"seq"`
+			Type     string `json:"type"`
+			Data     struct {
+				Account string `json:"account"`
+				Memo    string `json:"memo"`
+			} `json:"data"`
+		} `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "pay-1", resp.TransactionID)
+	assert.True(t, resp.Success)
+	assert.Equal(t, "invoice #123", resp.Memo)
+	require.Len(t, resp.Events, 2)
+	assert.Equal(t, domain.EventTypeMoneyDeducted, resp.Events[0].Type)
+	assert.Equal(t, "invoice #123", resp.Events[1].Data.Memo)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/wallet/transaction/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimumBalance_RespectingFloorSucceedsBreachingFails(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("reserve", 10000)
+	require.NoError(t, eng.SetMinimumBalance("reserve", 3000))
+
+	// Leaves exactly the floor behind
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "to-floor", FromAccount: "reserve", ToAccount: "bob", Amount: 7000,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(3000), eng.GetBalance("reserve", "USD"))
+
+	// Covered by the balance, but would dip under the floor
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "breach", FromAccount: "reserve", ToAccount: "bob", Amount: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "below minimum balance", events[0].(domain.TransactionFailed).Reason)
+	assert.Equal(t, int64(3000), eng.GetBalance("reserve", "USD"))
+
+	// More than the balance holds is still reported as insufficient funds
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "overdraw", FromAccount: "reserve", ToAccount: "bob", Amount: 5000,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+
+	// Other accounts keep the default floor of zero
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "drain-bob", FromAccount: "bob", ToAccount: "reserve", Mode: domain.TransferModeAll,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(0), eng.GetBalance("bob", "USD"))
+}
+
+func TestMinimumBalance_SurvivesReplay(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	_, err := store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "reserve", Amount: 10000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.InitializeFromEventStore())
+
+	require.NoError(t, eng.SetMinimumBalance("reserve", 8000))
+	assert.Equal(t, int64(8000), eng.MinimumBalance("reserve"))
+	assert.Error(t, eng.SetMinimumBalance("reserve", -1), "a negative floor would be an overdraft")
+
+	// A restarted engine rebuilds the floor from the event store
+	restarted := engine.NewWalletEngine(store, nil)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, int64(8000), restarted.MinimumBalance("reserve"))
+	assert.Equal(t, int64(10000), restarted.GetBalance("reserve", "USD"))
+
+	events, err := restarted.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "after-restart", FromAccount: "reserve", ToAccount: "bob", Amount: 2001,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "below minimum balance", events[0].(domain.TransactionFailed).Reason)
+
+	// Clearing the floor is persisted too
+	require.NoError(t, restarted.SetMinimumBalance("reserve", 0))
+	again := engine.NewWalletEngine(store, nil)
+	require.NoError(t, again.InitializeFromEventStore())
+	assert.Equal(t, int64(0), again.MinimumBalance("reserve"))
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/nathanyu/digital-wallet/internal/queue"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyz_FollowsNATSConnection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Stands in for the status the NATS client's handlers would update
+	status := queue.NewConnectionStatus(true)
+
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), nil)
+	h.SetBrokerStatus(status)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	readyz := func() (int, handler.HealthResponse) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var resp handler.HealthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	code, resp := readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, float64(1), testutil.ToFloat64(telemetry.NATSConnected))
+
+	// Broker outage
+	status.Set(false)
+	code, resp = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", resp.Status)
+	assert.Contains(t, resp.Reason, "NATS")
+	assert.Equal(t, float64(0), testutil.ToFloat64(telemetry.NATSConnected))
+
+	// Liveness is unaffected
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Reconnected
+	status.Set(true)
+	code, _ = readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), testutil.ToFloat64(telemetry.NATSConnected))
+}
+
+func TestReadyz_StartingAndBrokerIgnored(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), nil)
+	h.SetReady(false)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, get())
+
+	// Without a broker status the NATS connection does not affect readiness
+	h.SetReady(true)
+	assert.Equal(t, http.StatusOK, get())
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverdraft_TransferUpToLimit(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("credit", 1000)
+	eng.SetBalance("bob", 0)
+	require.NoError(t, eng.SetOverdraftLimit("credit", 500))
+	initialTotal := eng.GetTotalBalance()
+
+	// Ends exactly at -limit
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "to-limit", FromAccount: "credit", ToAccount: "bob", Amount: 1500,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(-500), eng.GetBalance("credit", "USD"))
+
+	// One cent further is insufficient funds
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "past-limit", FromAccount: "credit", ToAccount: "bob", Amount: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+
+	// Withdrawals draw on the overdraft too
+	_, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "repay", FromAccount: "bob", ToAccount: "credit", Amount: 200,
+	})
+	require.NoError(t, err)
+	events, err = eng.Withdraw(context.Background(), domain.WithdrawCommand{TransactionID: "cash", Account: "credit", Amount: 200})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.IsType(t, domain.MoneyWithdrawn{}, events[0])
+	assert.Equal(t, int64(-500), eng.GetBalance("credit", "USD"))
+
+	// Transfers conserve the total, negative balances included
+	assert.Equal(t, initialTotal-200, eng.GetTotalBalance())
+
+	// Other accounts keep the default limit of zero
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "bob-overdraw", FromAccount: "bob", ToAccount: "credit", Amount: 1301,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+}
+
+func TestOverdraft_SurvivesReplay(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	_, err := store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "credit", Amount: 100},
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.InitializeFromEventStore())
+
+	require.NoError(t, eng.SetOverdraftLimit("credit", 300))
+	assert.Error(t, eng.SetOverdraftLimit("credit", -1))
+	_, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "spend", FromAccount: "credit", ToAccount: "bob", Amount: 350,
+	})
+	require.NoError(t, err)
+
+	// A restarted engine rebuilds the limit and the negative balance
+	restarted := engine.NewWalletEngine(store, nil)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, int64(300), restarted.OverdraftLimit("credit"))
+	assert.Equal(t, int64(-250), restarted.GetBalance("credit", "USD"))
+
+	events, err := restarted.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "after-restart", FromAccount: "credit", ToAccount: "bob", Amount: 51,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+
+	// Clearing the limit is persisted too
+	require.NoError(t, restarted.SetOverdraftLimit("credit", 0))
+	again := engine.NewWalletEngine(store, nil)
+	require.NoError(t, again.InitializeFromEventStore())
+	assert.Equal(t, int64(0), again.OverdraftLimit("credit"))
+}
+
+func TestOverdraft_API(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	eng, _ := setupTransferModeTest(t)
+	router := gin.New()
+	handler.SetupRoutes(router, handler.NewHandler(nil, cqrs.NewReadModel(nil), eng))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/wallet/overdraft",
+		strings.NewReader(`{"account":"credit","limit":2500}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(2500), eng.OverdraftLimit("credit"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/wallet/overdraft",
+		strings.NewReader(`{"account":"credit","limit":-1}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// laneOrderStore wraps a real event store, records the order transactions are
+// persisted in, and holds the first write until the gate is opened
+type laneOrderStore struct {
+	*eventstore.EventStore
+	gate    chan struct{}
+	blocked chan struct{}
+
+	mu    sync.Mutex
+	order []string
+}
+
+func (s *laneOrderStore) AppendSequenced(events []domain.Event) ([]domain.SequencedEvent, error) {
+	s.mu.Lock()
+	first := len(s.order) == 0
+	s.order = append(s.order, events[0].GetTransactionID())
+	s.mu.Unlock()
+
+	if first {
+		close(s.blocked)
+		<-s.gate
+	}
+	return s.EventStore.AppendSequenced(events)
+}
+
+func (s *laneOrderStore) persisted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.order...)
+}
+
+func TestPriorityLane_JumpsNormalBacklog(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	real, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer real.Close()
+	store := &laneOrderStore{EventStore: real, gate: make(chan struct{}), blocked: make(chan struct{})}
+
+	nc, err := nats.Connect(nats.DefaultURL, nats.NoReconnect())
+	if err != nil {
+		t.Skip("NATS server not available")
+	}
+	defer nc.Close()
+
+	eng := engine.NewWalletEngine(store, nc)
+	eng.EnablePriorityLane(64)
+	eng.SetBalance("ops", 10_000)
+	require.NoError(t, eng.Start())
+	defer eng.Stop()
+
+	publish := func(subject, txnID string) {
+		data, err := json.Marshal(domain.TransferCommand{
+			TransactionID: txnID, FromAccount: "ops", ToAccount: "customer", Amount: 10,
+		})
+		require.NoError(t, err)
+		require.NoError(t, nc.Publish(subject, data))
+	}
+
+	// The first normal command occupies the engine while the backlog builds
+	publish(engine.CommandSubject, "normal-0")
+	select {
+	case <-store.blocked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("first command never reached the event store")
+	}
+
+	const backlog = 10
+	for i := 1; i <= backlog; i++ {
+		publish(engine.CommandSubject, fmt.Sprintf("normal-%d", i))
+	}
+	publish(engine.PriorityCommandSubject, "urgent")
+	// Once the server answers the flush every message has been delivered to the lanes
+	require.NoError(t, nc.Flush())
+
+	close(store.gate)
+	require.Eventually(t, func() bool {
+		return len(store.persisted()) == backlog+2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	order := store.persisted()
+	assert.Equal(t, "normal-0", order[0])
+	assert.Equal(t, "urgent", order[1], "priority transfer must be processed ahead of the backlog")
+	for i := 1; i <= backlog; i++ {
+		assert.Equal(t, fmt.Sprintf("normal-%d", i), order[i+1], "normal lane must stay FIFO")
+	}
+	assert.Equal(t, int64(10_000-10*(backlog+2)), eng.GetBalance("ops", "USD"))
+}
+
+func TestPriorityLane_DisabledByDefault(t *testing.T) {
+	eng := engine.NewWalletEngine(nil, nil)
+	assert.False(t, eng.PriorityLaneEnabled())
+
+	eng.EnablePriorityLane(0)
+	assert.True(t, eng.PriorityLaneEnabled())
+}
@@ -0,0 +1,260 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadModel_BalancesSnapshot_RefreshesAfterTTL(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rm.SetClock(func() time.Time { return now })
+	rm.SetSnapshotTTL(500 * time.Millisecond)
+
+	rm.SetBalance("alice", 1000)
+	rm.SetBalance("bob", 500)
+
+	balances, total := rm.GetBalancesSnapshot()
+	assert.Equal(t, int64(1000), balances["alice"])
+	assert.Equal(t, int64(1500), total)
+
+	// A transfer lands; within the TTL the cached snapshot is served
+	rm.HandleEventDirect(domain.MoneyDeducted{TransactionID: "txn-1", Account: "alice", Amount: 100})
+	rm.HandleEventDirect(domain.MoneyCredited{TransactionID: "txn-1", Account: "bob", Amount: 100})
+
+	now = now.Add(200 * time.Millisecond)
+	balances, _ = rm.GetBalancesSnapshot()
+	assert.Equal(t, int64(1000), balances["alice"], "snapshot should still be cached")
+
+	// Once the TTL elapses the update is visible
+	now = now.Add(400 * time.Millisecond)
+	balances, total = rm.GetBalancesSnapshot()
+	assert.Equal(t, int64(900), balances["alice"])
+	assert.Equal(t, int64(600), balances["bob"])
+	assert.Equal(t, int64(1500), total)
+
+	// Per-account reads are never cached
+	balance, ok := rm.GetBalance("alice", "USD")
+	assert.True(t, ok)
+	assert.Equal(t, int64(900), balance)
+}
+
+func TestReadModel_BalancesSnapshot_InvalidatedOnInit(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	rm.SetSnapshotTTL(time.Hour)
+
+	rm.SetBalance("alice", 1000)
+	_, total := rm.GetBalancesSnapshot()
+	assert.Equal(t, int64(1000), total)
+
+	rm.SetBalance("carol", 250)
+	balances, total := rm.GetBalancesSnapshot()
+	assert.Equal(t, int64(250), balances["carol"])
+	assert.Equal(t, int64(1250), total)
+}
+
+func TestReadModel_BalancesSnapshot_Disabled(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	rm.SetSnapshotTTL(0)
+
+	rm.SetBalance("alice", 1000)
+	rm.GetBalancesSnapshot()
+	rm.HandleEventDirect(domain.MoneyDeducted{TransactionID: "txn-1", Account: "alice", Amount: 100})
+
+	balances, total := rm.GetBalancesSnapshot()
+	assert.Equal(t, int64(900), balances["alice"])
+	assert.Equal(t, int64(900), total)
+}
+
+func newBenchReadModel(accounts int, ttl time.Duration) *cqrs.ReadModel {
+	rm := cqrs.NewReadModel(nil)
+	rm.SetSnapshotTTL(ttl)
+	for i := 0; i < accounts; i++ {
+		rm.SetBalance(fmt.Sprintf("account-%d", i), 1000)
+	}
+	return rm
+}
+
+// BenchmarkReadModel_GetAllBalances: copies the full map on every poll
+func BenchmarkReadModel_GetAllBalances(b *testing.B) {
+	rm := newBenchReadModel(10_000, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = rm.GetAllBalances()
+			_ = rm.GetTotalBalance()
+		}
+	})
+}
+
+// BenchmarkReadModel_GetBalancesSnapshot: polls are served from the cached snapshot
+func BenchmarkReadModel_GetBalancesSnapshot(b *testing.B) {
+	rm := newBenchReadModel(10_000, time.Second)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = rm.GetBalancesSnapshot()
+		}
+	})
+}
+
+// setupReplayTest wires an engine (no NATS) to a read model through a
+// switchable direct handler, so tests can simulate the read model dropping
+// off the event stream.
+func setupReplayTest(t *testing.T) (*engine.WalletEngine, *cqrs.ReadModel, *atomic.Bool, func()) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+
+	eng := engine.NewWalletEngine(store, nil)
+	rm := cqrs.NewReadModel(nil)
+	require.NoError(t, rm.InitializeFromEventStore(store))
+
+	connected := &atomic.Bool{}
+	connected.Store(true)
+	eng.RegisterEventHandler(func(event domain.SequencedEvent) {
+		if connected.Load() {
+			rm.HandleSequencedEvent(event)
+		}
+	})
+
+	cleanup := func() {
+		store.Close()
+		os.Remove(tmpFile.Name())
+	}
+	return eng, rm, connected, cleanup
+}
+
+func transfer(t *testing.T, eng *engine.WalletEngine, id, from, to string, amount int64) {
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: id, FromAccount: from, ToAccount: to, Amount: amount,
+	})
+	require.NoError(t, err)
+}
+
+func TestReadModel_ResyncAfterDisconnect(t *testing.T) {
+	eng, rm, connected, cleanup := setupReplayTest(t)
+	defer cleanup()
+
+	eng.SetBalance("alice", 1000)
+	transfer(t, eng, "txn-1", "alice", "bob", 100)
+	assert.Equal(t, uint64(2), rm.LastSequence())
+
+	// Disconnect: events keep being persisted but the read model misses them
+	connected.Store(false)
+	transfer(t, eng, "txn-2", "alice", "bob", 200)
+	transfer(t, eng, "txn-3", "alice", "carol", 50)
+
+	balance, _ := rm.GetBalance("alice", "USD")
+	assert.Equal(t, int64(-100), balance, "read model missed the transfers")
+
+	// Reconnect: the gap is replayed from the event store
+	connected.Store(true)
+	require.NoError(t, rm.Resync())
+
+	assert.Equal(t, uint64(6), rm.LastSequence())
+	for _, account := range []string{"alice", "bob", "carol"} {
+		balance, _ := rm.GetBalance(account, "USD")
+		assert.Equal(t, eng.GetBalance(account, "USD")-initialBalance(account), balance, account)
+	}
+
+	// Live events after reconnecting apply normally
+	transfer(t, eng, "txn-4", "bob", "carol", 10)
+	assert.Equal(t, uint64(8), rm.LastSequence())
+}
+
+func TestReadModel_GapDetectedOnNextEvent(t *testing.T) {
+	eng, rm, connected, cleanup := setupReplayTest(t)
+	defer cleanup()
+
+	eng.SetBalance("alice", 1000)
+
+	connected.Store(false)
+	transfer(t, eng, "txn-1", "alice", "bob", 100)
+	transfer(t, eng, "txn-2", "alice", "bob", 100)
+	connected.Store(true)
+
+	// The next live event reveals the gap and triggers a replay before it is applied
+	transfer(t, eng, "txn-3", "alice", "bob", 100)
+
+	assert.Equal(t, uint64(6), rm.LastSequence())
+	balance, _ := rm.GetBalance("bob", "USD")
+	assert.Equal(t, int64(300), balance)
+}
+
+func TestReadModel_SlowConsumerDropsResynced(t *testing.T) {
+	eng, rm, connected, cleanup := setupReplayTest(t)
+	defer cleanup()
+	slow := testutil.ToFloat64(telemetry.ReadModelSlowConsumerTotal)
+
+	eng.SetBalance("alice", 1000)
+	transfer(t, eng, "txn-1", "alice", "bob", 100)
+
+	// NATS drops the tail of the stream; no later event arrives to reveal the gap
+	connected.Store(false)
+	transfer(t, eng, "txn-2", "alice", "bob", 250)
+	assert.Equal(t, uint64(2), rm.LastSequence())
+
+	// A report for some other subscription is ignored
+	rm.HandleSlowConsumer(nil)
+	assert.Equal(t, uint64(2), rm.LastSequence())
+
+	// The slow consumer report resyncs from the event store
+	require.NoError(t, rm.ResyncAfterDrops())
+	assert.Equal(t, uint64(4), rm.LastSequence())
+	balance, _ := rm.GetBalance("bob", "USD")
+	assert.Equal(t, int64(350), balance)
+	assert.Equal(t, slow+1, testutil.ToFloat64(telemetry.ReadModelSlowConsumerTotal))
+
+	// Delivery resumes without double-applying anything
+	connected.Store(true)
+	transfer(t, eng, "txn-3", "alice", "bob", 50)
+	balance, _ = rm.GetBalance("bob", "USD")
+	assert.Equal(t, int64(400), balance)
+}
+
+func TestReadModel_PendingLimitsValidated(t *testing.T) {
+	rm := cqrs.NewReadModel(nil)
+	assert.Error(t, rm.SetPendingLimits(-1, 0))
+	assert.NoError(t, rm.SetPendingLimits(1000, 0))
+}
+
+func TestReadModel_DuplicateEventsIgnored(t *testing.T) {
+	eng, rm, _, cleanup := setupReplayTest(t)
+	defer cleanup()
+
+	eng.SetBalance("alice", 1000)
+	// Deliver every event twice, as the direct handler and NATS both do in production
+	eng.RegisterEventHandler(rm.HandleSequencedEvent)
+
+	transfer(t, eng, "txn-1", "alice", "bob", 100)
+
+	balance, _ := rm.GetBalance("bob", "USD")
+	assert.Equal(t, int64(100), balance)
+}
+
+// initialBalance returns balances seeded via SetBalance, which bypasses the event log
+func initialBalance(account string) int64 {
+	if account == "alice" {
+		return 1000
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/nathanyu/digital-wallet/internal/telemetry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadiness_BalanceRejectedUntilInitialized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed-a", Account: "alice", Amount: 1000},
+	})
+	require.NoError(t, err)
+
+	// Wired up the way the server does before replaying
+	eng := engine.NewWalletEngine(store, nil)
+	readModel := cqrs.NewReadModel(nil)
+	h := handler.NewHandler(nil, readModel, eng)
+	h.SetReady(false)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// Before replay the read model is empty; the gate keeps that from
+	// being served as a zero balance
+	w := get("/v1/wallet/balance/alice")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusServiceUnavailable, get("/v1/wallet/balances").Code)
+
+	var health handler.HealthResponse
+	w = get("/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, "starting", health.Status)
+
+	require.NoError(t, eng.InitializeFromEventStore())
+	require.NoError(t, readModel.InitializeFromEventStore(store))
+	h.SetReady(true)
+
+	w = get("/v1/wallet/balance/alice")
+	require.Equal(t, http.StatusOK, w.Code)
+	var balance handler.BalanceResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &balance))
+	assert.Equal(t, int64(1000), balance.Balance)
+
+	w = get("/health")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, "ok", health.Status)
+}
+
+// gatedLoadStore holds LoadAll until release is closed
+type gatedLoadStore struct {
+	events  []domain.Event
+	loading chan struct{}
+	release chan struct{}
+}
+
+func (s *gatedLoadStore) AppendSequenced(events []domain.Event) ([]domain.SequencedEvent, error) {
+	return nil, nil
+}
+
+func (s *gatedLoadStore) LoadAll() ([]domain.Event, error) {
+	close(s.loading)
+	<-s.release
+	return s.events, nil
+}
+
+func TestReadiness_HealthReportsReplayProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &gatedLoadStore{loading: make(chan struct{}), release: make(chan struct{})}
+	for i := 0; i < 2500; i++ {
+		store.events = append(store.events, domain.MoneyDeposited{TransactionID: fmt.Sprintf("d-%d", i), Account: "alice", Amount: 1})
+	}
+	eng := engine.NewWalletEngine(store, nil)
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), eng)
+	h.SetReady(false)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+
+	health := func() handler.HealthResponse {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var resp handler.HealthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	assert.False(t, eng.ReplayProgress().Replaying)
+
+	done := make(chan error)
+	go func() { done <- eng.InitializeFromEventStore() }()
+	<-store.loading
+	assert.Equal(t, "starting", health().Status)
+	assert.Contains(t, health().Reason, "replaying (loading events)")
+
+	close(store.release)
+	require.NoError(t, <-done)
+	assert.Equal(t, engine.ReplayProgress{Applied: 2500, Total: 2500}, eng.ReplayProgress())
+	assert.Equal(t, float64(2500), testutil.ToFloat64(telemetry.ReplayEventsTotal))
+	assert.Equal(t, float64(2500), testutil.ToFloat64(telemetry.ReplayEventsExpected))
+	assert.Equal(t, int64(2500), eng.GetBalance("alice", "USD"))
+
+	// Between the engine's replay and the gate opening the generic reason is back
+	assert.Equal(t, "replaying events, balance and transfer endpoints rejected", health().Reason)
+	assert.Equal(t, "replaying (1000/2500 events)", engine.ReplayProgress{Replaying: true, Applied: 1000, Total: 2500}.String())
+}
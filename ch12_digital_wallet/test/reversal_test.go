@@ -0,0 +1,132 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverse_UndoesTransferAndSurvivesReplay(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	ctx := context.Background()
+	_, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep", Account: "alice", Amount: 1000})
+	require.NoError(t, err)
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{TransactionID: "pay", FromAccount: "alice", ToAccount: "bob", Amount: 300})
+	require.NoError(t, err)
+
+	events, err := eng.Reverse(ctx, domain.ReverseCommand{TransactionID: "rev", OriginalTransactionID: "pay"})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Event{
+		domain.MoneyDeducted{TransactionID: "rev", Account: "bob", Amount: 300, Currency: "USD", Reverses: "pay"},
+		domain.MoneyCredited{TransactionID: "rev", Account: "alice", Amount: 300, Currency: "USD", Reverses: "pay"},
+	}, events)
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(0), eng.GetBalance("bob", "USD"))
+
+	// Resending the reversal does nothing; a second reversal is rejected
+	events, err = eng.Reverse(ctx, domain.ReverseCommand{TransactionID: "rev", OriginalTransactionID: "pay"})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	events, err = eng.Reverse(ctx, domain.ReverseCommand{TransactionID: "rev-2", OriginalTransactionID: "pay"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "transfer already reversed", events[0].(domain.TransactionFailed).Reason)
+
+	restarted := engine.NewWalletEngine(store, nil)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, int64(1000), restarted.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(0), restarted.GetBalance("bob", "USD"))
+
+	rm := cqrs.NewReadModel(nil)
+	require.NoError(t, rm.InitializeFromEventStore(store))
+	balance, ok := rm.GetBalance("alice", "USD")
+	require.True(t, ok)
+	assert.Equal(t, int64(1000), balance)
+}
+
+func TestReverse_RejectsWhatCannotBeReversed(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	ctx := context.Background()
+	_, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep", Account: "alice", Amount: 1000})
+	require.NoError(t, err)
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{TransactionID: "too-much", FromAccount: "alice", ToAccount: "bob", Amount: 5000})
+	require.NoError(t, err)
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{TransactionID: "pay", FromAccount: "alice", ToAccount: "bob", Amount: 400})
+	require.NoError(t, err)
+
+	reverse := func(id, original string) string {
+		events, err := eng.Reverse(ctx, domain.ReverseCommand{TransactionID: id, OriginalTransactionID: original})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		return events[0].(domain.TransactionFailed).Reason
+	}
+
+	assert.Equal(t, "original transaction not found", reverse("r1", "nope"))
+	assert.Equal(t, "original transaction failed", reverse("r2", "too-much"))
+	assert.Equal(t, "original transaction is not a transfer", reverse("r3", "dep"))
+
+	// The receiver must still have the money
+	_, err = eng.Withdraw(ctx, domain.WithdrawCommand{TransactionID: "spent", Account: "bob", Amount: 250})
+	require.NoError(t, err)
+	assert.Equal(t, "insufficient funds", reverse("r4", "pay"))
+	_, err = eng.Deposit(ctx, domain.DepositCommand{TransactionID: "topup", Account: "bob", Amount: 250})
+	require.NoError(t, err)
+
+	events, err := eng.Reverse(ctx, domain.ReverseCommand{TransactionID: "r5", OriginalTransactionID: "pay"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "cannot reverse a reversal", reverse("r6", "r5"))
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+}
+
+func TestReverse_API(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	ctx := context.Background()
+	_, err := eng.Deposit(ctx, domain.DepositCommand{TransactionID: "dep", Account: "alice", Amount: 1000})
+	require.NoError(t, err)
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{TransactionID: "pay", FromAccount: "alice", ToAccount: "bob", Amount: 300})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.SetupRoutes(router, handler.NewHandler(nil, cqrs.NewReadModel(nil), eng))
+
+	post := func(body handler.ReverseRequest) (int, handler.TransferResponse) {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/wallet/reverse", bytes.NewReader(data)))
+		var resp handler.TransferResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := post(handler.ReverseRequest{OriginalTransactionID: "pay", TransactionID: "rev"})
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Success)
+	assert.Equal(t, int64(300), resp.Amount)
+	assert.Equal(t, []string{domain.EventTypeMoneyDeducted, domain.EventTypeMoneyCredited}, resp.Events)
+
+	code, resp = post(handler.ReverseRequest{OriginalTransactionID: "pay", TransactionID: "rev"})
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Duplicate)
+
+	code, resp = post(handler.ReverseRequest{OriginalTransactionID: "missing"})
+	require.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "original transaction not found", resp.Message)
+	assert.NotEmpty(t, resp.TransactionID)
+
+	code, _ = post(handler.ReverseRequest{})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+}
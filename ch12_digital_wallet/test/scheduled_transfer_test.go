@@ -0,0 +1,268 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a settable time source for engine.SetClock
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func setupScheduledTest(t *testing.T) (*engine.WalletEngine, *eventstore.EventStore, *fakeClock) {
+	eng, store := setupTransferModeTest(t)
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)}
+	eng.SetClock(clock.Now)
+
+	_, err := store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 10000},
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.InitializeFromEventStore())
+	return eng, store, clock
+}
+
+func TestScheduledTransfer_RunsWhenDue(t *testing.T) {
+	eng, _, clock := setupScheduledTest(t)
+	dueAt := clock.Now().Add(time.Hour)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "rent", FromAccount: "alice", ToAccount: "bob", Amount: 3000, ExecuteAt: dueAt,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, dueAt, events[0].(domain.TransferScheduled).DueAt)
+	assert.Equal(t, int64(10000), eng.GetBalance("alice", "USD"))
+	require.Len(t, eng.ScheduledTransfers(), 1)
+
+	// Resending the same request while it is pending is a duplicate
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "rent", FromAccount: "alice", ToAccount: "bob", Amount: 3000, ExecuteAt: dueAt,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// Not due yet
+	clock.Advance(59 * time.Minute)
+	ran, err := eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, ran)
+	assert.Equal(t, int64(10000), eng.GetBalance("alice", "USD"))
+
+	clock.Advance(time.Minute)
+	ran, err = eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.Equal(t, int64(7000), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(3000), eng.GetBalance("bob", "USD"))
+	assert.Empty(t, eng.ScheduledTransfers())
+
+	// Runs only once
+	ran, err = eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, ran)
+	assert.Equal(t, int64(7000), eng.GetBalance("alice", "USD"))
+}
+
+func TestScheduledTransfer_BalanceCheckedWhenDue(t *testing.T) {
+	eng, _, clock := setupScheduledTest(t)
+
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "too-big", FromAccount: "alice", ToAccount: "bob", Amount: 20000,
+		ExecuteAt: clock.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	clock.Advance(time.Minute)
+	ran, err := eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.Equal(t, int64(10000), eng.GetBalance("alice", "USD"))
+	assert.Empty(t, eng.ScheduledTransfers())
+}
+
+func TestScheduledTransfer_CancelBeforeDue(t *testing.T) {
+	eng, _, clock := setupScheduledTest(t)
+
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "gift", FromAccount: "alice", ToAccount: "bob", Amount: 500,
+		ExecuteAt: clock.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, eng.CancelScheduledTransfer("gift"))
+	assert.Empty(t, eng.ScheduledTransfers())
+	assert.ErrorIs(t, eng.CancelScheduledTransfer("gift"), engine.ErrScheduledTransferNotFound)
+
+	clock.Advance(2 * time.Hour)
+	ran, err := eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, ran)
+	assert.Equal(t, int64(10000), eng.GetBalance("alice", "USD"))
+
+	// The canceled ID stays used
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "gift", FromAccount: "alice", ToAccount: "bob", Amount: 500,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestScheduledTransfer_QueueIsBounded(t *testing.T) {
+	eng, _, clock := setupScheduledTest(t)
+	eng.SetMaxScheduledTransfers(1)
+
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "first", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+		ExecuteAt: clock.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "second", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+		ExecuteAt: clock.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "too many scheduled transfers", events[0].(domain.TransactionFailed).Reason)
+
+	// Immediate transfers are unaffected
+	events, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "now", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestScheduledTransfer_SurvivesReplay(t *testing.T) {
+	eng, store, clock := setupScheduledTest(t)
+
+	for _, id := range []string{"kept", "dropped"} {
+		_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 1000,
+			ExecuteAt: clock.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, eng.CancelScheduledTransfer("dropped"))
+
+	restarted := engine.NewWalletEngine(store, nil)
+	restarted.SetClock(clock.Now)
+	require.NoError(t, restarted.InitializeFromEventStore())
+
+	pending := restarted.ScheduledTransfers()
+	require.Len(t, pending, 1)
+	assert.Equal(t, "kept", pending[0].TransactionID)
+
+	clock.Advance(time.Hour)
+	ran, err := restarted.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.Equal(t, int64(9000), restarted.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(1000), restarted.GetBalance("bob", "USD"))
+}
+
+func TestScheduledTransfer_SurvivesSnapshot(t *testing.T) {
+	eng, store, clock := setupScheduledTest(t)
+	defer os.Remove(store.SnapshotPath())
+	require.NoError(t, eng.SetSnapshotPolicy(1, 0))
+
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "snap", FromAccount: "alice", ToAccount: "bob", Amount: 1000,
+		ExecuteAt: clock.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.Stop()) // waits for the background snapshot write
+
+	snap, err := store.LoadLatestSnapshot()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	assert.Contains(t, snap.Scheduled, "snap")
+
+	restarted := engine.NewWalletEngine(store, nil)
+	restarted.SetClock(clock.Now)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	require.Len(t, restarted.ScheduledTransfers(), 1)
+}
+
+func TestScheduledTransfer_RunsWhenDueBeyondOneWheelRevolution(t *testing.T) {
+	eng, _, clock := setupScheduledTest(t)
+
+	// The wheel turns once an hour: these three share a slot
+	for id, d := range map[string]time.Duration{"soon": time.Minute, "later": time.Minute + time.Hour + 500*time.Millisecond, "latest": time.Minute + 3*time.Hour} {
+		_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 100,
+			ExecuteAt: clock.Now().Add(d),
+		})
+		require.NoError(t, err)
+	}
+
+	clock.Advance(time.Minute)
+	ran, err := eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	require.Len(t, eng.ScheduledTransfers(), 2)
+
+	// Its slot comes around, but earlier in the tick than it is due
+	clock.Advance(time.Hour + 250*time.Millisecond)
+	ran, err = eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, ran)
+	clock.Advance(250 * time.Millisecond)
+	ran, err = eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.Equal(t, "latest", eng.ScheduledTransfers()[0].TransactionID)
+	assert.Equal(t, int64(9800), eng.GetBalance("alice", "USD"))
+}
+
+func TestScheduledTransfer_RunsEverythingDueAfterAGap(t *testing.T) {
+	eng, _, clock := setupScheduledTest(t)
+
+	// Spread over more than one revolution of the wheel, out of order
+	for i, d := range []time.Duration{90 * time.Minute, 10 * time.Second, 70 * time.Minute, 30 * time.Minute} {
+		_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: fmt.Sprintf("t%d", i), FromAccount: "alice", ToAccount: "bob", Amount: 100,
+			ExecuteAt: clock.Now().Add(d),
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, eng.CancelScheduledTransfer("t2"))
+
+	clock.Advance(3 * time.Hour)
+	ran, err := eng.RunDueTransfers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, ran)
+	assert.Empty(t, eng.ScheduledTransfers())
+	assert.Equal(t, int64(9700), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(300), eng.GetBalance("bob", "USD"))
+}
+
+func TestTransferCommand_AcceptsScheduledAtName(t *testing.T) {
+	dueAt := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var cmd domain.TransferCommand
+	require.NoError(t, json.Unmarshal([]byte(`{"transaction_id":"rent","scheduled_at":"2026-01-01T10:00:00Z"}`), &cmd))
+	assert.Equal(t, "rent", cmd.TransactionID)
+	assert.True(t, dueAt.Equal(cmd.ExecuteAt))
+
+	cmd = domain.TransferCommand{}
+	require.NoError(t, json.Unmarshal([]byte(`{"transaction_id":"rent","execute_at":"2026-01-01T10:00:00Z"}`), &cmd))
+	assert.True(t, dueAt.Equal(cmd.ExecuteAt))
+
+	encoded, err := json.Marshal(domain.TransferCommand{TransactionID: "rent", ExecuteAt: dueAt})
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"execute_at":"2026-01-01T10:00:00Z"`)
+}
@@ -0,0 +1,237 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replayTrackingStore records where the engine starts replaying from
+type replayTrackingStore struct {
+	*eventstore.EventStore
+	replayedFrom []uint64
+}
+
+func (s *replayTrackingStore) LoadSince(afterSeq uint64) ([]domain.SequencedEvent, error) {
+	s.replayedFrom = append(s.replayedFrom, afterSeq)
+	return s.EventStore.LoadSince(afterSeq)
+}
+
+func TestSnapshot_AutoTriggerAndRestartReplaysFromIt(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer os.Remove(store.SnapshotPath())
+
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed", Account: "alice", Amount: 1000},
+	})
+	require.NoError(t, err)
+
+	eng := engine.NewWalletEngine(store, nil)
+	require.NoError(t, eng.InitializeFromEventStore())
+	require.NoError(t, eng.SetSnapshotPolicy(5, 0))
+	require.NoError(t, eng.SetMinimumBalance("alice", 100))
+
+	ctx := context.Background()
+	transfer := func(id string) {
+		events, err := eng.ProcessCommand(ctx, domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 50,
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+	}
+
+	// Floor event plus two transfers make 5 events: seq 2..6
+	transfer("txn-1")
+	transfer("txn-2")
+
+	var snap *eventstore.Snapshot
+	require.Eventually(t, func() bool {
+		snap, err = store.LoadLatestSnapshot()
+		return err == nil && snap != nil
+	}, 2*time.Second, 10*time.Millisecond, "auto-snapshot was not written")
+	assert.Equal(t, uint64(6), snap.Sequence)
+	assert.Equal(t, map[string]int64{"alice": 900, "bob": 100}, snap.Balances)
+	assert.True(t, snap.ProcessedTxns["txn-1"])
+	assert.True(t, snap.ProcessedTxns["txn-2"])
+	assert.Equal(t, int64(100), snap.MinBalances["alice"])
+
+	// Events after the snapshot must still be replayed
+	transfer("txn-3")
+	require.NoError(t, eng.Stop())
+	store.Close()
+
+	real, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer real.Close()
+	restarted := &replayTrackingStore{EventStore: real}
+
+	eng2 := engine.NewWalletEngine(restarted, nil)
+	require.NoError(t, eng2.InitializeFromEventStore())
+	assert.Equal(t, []uint64{6}, restarted.replayedFrom, "replay should start after the snapshot")
+	assert.Equal(t, int64(850), eng2.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(150), eng2.GetBalance("bob", "USD"))
+	assert.Equal(t, int64(100), eng2.MinimumBalance("alice"))
+
+	// Idempotency carries across the snapshot
+	events, err := eng2.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "txn-1", FromAccount: "alice", ToAccount: "bob", Amount: 50,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestSnapshot_IntervalTrigger(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	defer os.Remove(store.SnapshotPath())
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	eng.SetClock(func() time.Time { return now })
+	eng.SetBalance("alice", 1000)
+	require.NoError(t, eng.SetSnapshotPolicy(0, time.Minute))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := eng.ProcessCommand(ctx, domain.TransferCommand{
+			TransactionID: fmt.Sprintf("txn-%d", i), FromAccount: "alice", ToAccount: "bob", Amount: 10,
+		})
+		require.NoError(t, err)
+	}
+	snap, err := store.LoadLatestSnapshot()
+	require.NoError(t, err)
+	assert.Nil(t, snap, "no snapshot before the interval has passed")
+
+	now = now.Add(time.Minute)
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "txn-late", FromAccount: "alice", ToAccount: "bob", Amount: 10,
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		snap, err = store.LoadLatestSnapshot()
+		return err == nil && snap != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(8), snap.Sequence)
+	assert.Equal(t, int64(960), snap.Balances["alice"])
+}
+
+func TestSnapshot_PolicyValidation(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	assert.Error(t, eng.SetSnapshotPolicy(-1, 0))
+	assert.Error(t, eng.SetSnapshotPolicy(0, -time.Second))
+
+	// Stores without snapshot support cannot enable it
+	plain := engine.NewWalletEngine(appendOnlyLog{}, nil)
+	assert.Error(t, plain.SetSnapshotPolicy(10, 0))
+	assert.NoError(t, plain.SetSnapshotPolicy(0, 0))
+}
+
+// appendOnlyLog is an EventLog with no snapshot support
+type appendOnlyLog struct{}
+
+func (appendOnlyLog) AppendSequenced(events []domain.Event) ([]domain.SequencedEvent, error) {
+	return nil, nil
+}
+
+func (appendOnlyLog) LoadAll() ([]domain.Event, error) { return nil, nil }
+
+func TestSnapshot_ReplayReadsFromOffset(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	defer os.Remove(store.SnapshotPath())
+	eng.SetBalance("alice", 1000)
+	require.NoError(t, eng.SetSnapshotPolicy(4, 0))
+
+	ctx := context.Background()
+	transfer := func(e *engine.WalletEngine, id string) {
+		events, err := e.ProcessCommand(ctx, domain.TransferCommand{
+			TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 100,
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+	}
+	transfer(eng, "txn-1")
+	transfer(eng, "txn-2")
+	info, err := os.Stat(store.FilePath())
+	require.NoError(t, err)
+	transfer(eng, "txn-3")
+	require.NoError(t, eng.Stop())
+
+	snap, err := store.LoadLatestSnapshot()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	assert.Equal(t, uint64(4), snap.Sequence)
+	assert.Equal(t, info.Size(), snap.Offset, "offset should point just after seq 4")
+	store.Close()
+
+	// Garble the first event in place: it is covered by the snapshot, so a
+	// strict reopen never reads it
+	data, err := os.ReadFile(store.FilePath())
+	require.NoError(t, err)
+	data[0] = 'x'
+	require.NoError(t, os.WriteFile(store.FilePath(), data, 0644))
+
+	reopened, err := eventstore.NewEventStore(store.FilePath())
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, uint64(6), reopened.LastSequence())
+
+	restarted := engine.NewWalletEngine(reopened, nil)
+	require.NoError(t, restarted.InitializeFromEventStore())
+	assert.Equal(t, int64(700), restarted.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(300), restarted.GetBalance("bob", "USD"))
+
+	// A full read still sees the damage
+	_, err = reopened.LoadSince(0)
+	assert.Error(t, err)
+}
+
+func TestSnapshot_StaleOffsetFallsBackToFullReplay(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	defer os.Remove(store.SnapshotPath())
+	eng.SetBalance("alice", 1000)
+	require.NoError(t, eng.SetSnapshotPolicy(2, 0))
+
+	_, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "txn-1", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+	require.NoError(t, eng.Stop())
+	snap, err := store.LoadLatestSnapshot()
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	store.Close()
+
+	// Replace the log with a longer one the offset does not line up with
+	other, err := eventstore.NewEventStore(store.FilePath() + ".other")
+	require.NoError(t, err)
+	defer os.Remove(store.FilePath() + ".other")
+	for i := 0; i < 4; i++ {
+		_, err = other.AppendSequenced([]domain.Event{domain.MoneyCredited{
+			TransactionID: fmt.Sprintf("credit-%d-with-a-longer-id", i), Account: "carol", Amount: 10,
+		}})
+		require.NoError(t, err)
+	}
+	other.Close()
+	require.NoError(t, os.Rename(store.FilePath()+".other", store.FilePath()))
+
+	reopened, err := eventstore.NewEventStore(store.FilePath())
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, uint64(4), reopened.LastSequence())
+	events, err := reopened.LoadSince(snap.Sequence)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(3), events[0].Sequence)
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPrimaryAndStandby wires a standby to a primary through the primary's
+// event handlers, standing in for the NATS event subject. Setting the
+// returned flag drops deliveries to simulate lost messages.
+func setupPrimaryAndStandby(t *testing.T) (*engine.WalletEngine, *engine.WalletEngine, *eventstore.EventStore, *atomic.Bool) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	tmpFile.Close()
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	_, err = store.AppendSequenced([]domain.Event{
+		domain.MoneyCredited{TransactionID: "seed-a", Account: "alice", Amount: 1000},
+		domain.MoneyCredited{TransactionID: "seed-b", Account: "bob", Amount: 500},
+	})
+	require.NoError(t, err)
+
+	primary := engine.NewWalletEngine(store, nil)
+	require.NoError(t, primary.InitializeFromEventStore())
+
+	standby := engine.NewStandbyEngine(store, nil)
+	require.NoError(t, standby.InitializeFromEventStore())
+
+	drop := &atomic.Bool{}
+	primary.RegisterEventHandler(func(ev domain.SequencedEvent) {
+		if !drop.Load() {
+			standby.ApplySequencedEvent(ev)
+		}
+	})
+
+	return primary, standby, store, drop
+}
+
+func TestStandby_MirrorsPrimaryAndRejectsCommands(t *testing.T) {
+	primary, standby, _, _ := setupPrimaryAndStandby(t)
+	ctx := context.Background()
+
+	for i, amount := range []int64{100, 250, 5000, 50} {
+		_, err := primary.ProcessCommand(ctx, domain.TransferCommand{
+			TransactionID: generateTestTxnID(i), FromAccount: "alice", ToAccount: "bob", Amount: amount,
+		})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, primary.GetAllBalances(), standby.GetAllBalances())
+	assert.Equal(t, primary.LastSequence(), standby.LastSequence())
+	assert.True(t, standby.IsStandby())
+
+	_, err := standby.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "on-standby", FromAccount: "alice", ToAccount: "bob", Amount: 1,
+	})
+	assert.ErrorIs(t, err, engine.ErrStandby)
+	assert.Equal(t, primary.GetAllBalances(), standby.GetAllBalances())
+}
+
+func TestStandby_IgnoresRedeliveredEvents(t *testing.T) {
+	primary, standby, store, _ := setupPrimaryAndStandby(t)
+
+	_, err := primary.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "txn-1", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+
+	// Redeliver the whole log: nothing should be applied twice
+	all, err := store.LoadSince(0)
+	require.NoError(t, err)
+	for _, ev := range all {
+		standby.ApplySequencedEvent(ev)
+	}
+
+	assert.Equal(t, int64(900), standby.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(600), standby.GetBalance("bob", "USD"))
+}
+
+func TestStandby_PromoteTakesOverCommands(t *testing.T) {
+	primary, standby, store, drop := setupPrimaryAndStandby(t)
+	ctx := context.Background()
+
+	_, err := primary.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "txn-1", FromAccount: "alice", ToAccount: "bob", Amount: 100,
+	})
+	require.NoError(t, err)
+
+	// The primary's last events never reach the standby before it fails
+	drop.Store(true)
+	_, err = primary.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "txn-2", FromAccount: "alice", ToAccount: "bob", Amount: 200,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), standby.GetBalance("alice", "USD"), "standby has not seen txn-2 yet")
+	primary.Stop()
+
+	// Promotion catches up from the event store before taking writes
+	require.NoError(t, standby.Promote())
+	assert.False(t, standby.IsStandby())
+	assert.Equal(t, int64(700), standby.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(800), standby.GetBalance("bob", "USD"))
+	assert.Error(t, standby.Promote(), "promoting twice should fail")
+
+	// A client retrying txn-2 against the new primary is deduplicated
+	events, err := standby.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "txn-2", FromAccount: "alice", ToAccount: "bob", Amount: 200,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// New commands are processed and persisted after the primary's events
+	lastSeq := store.LastSequence()
+	events, err = standby.ProcessCommand(ctx, domain.TransferCommand{
+		TransactionID: "txn-3", FromAccount: "bob", ToAccount: "carol", Amount: 300,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(500), standby.GetBalance("bob", "USD"))
+	assert.Equal(t, int64(300), standby.GetBalance("carol", "USD"))
+	assert.Equal(t, lastSeq+2, standby.LastSequence())
+	assert.Equal(t, int64(1500), standby.GetTotalBalance())
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/digital-wallet/internal/cqrs"
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/nathanyu/digital-wallet/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localFeed fans engine events out to stream subscribers in-process,
+// standing in for the NATS event subject
+type localFeed struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]func(domain.SequencedEvent)
+}
+
+func newLocalFeed() *localFeed {
+	return &localFeed{subs: make(map[int]func(domain.SequencedEvent))}
+}
+
+func (f *localFeed) SubscribeEvents(fn func(domain.SequencedEvent)) (func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.next
+	f.next++
+	f.subs[id] = fn
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, id)
+	}, nil
+}
+
+func (f *localFeed) publish(ev domain.SequencedEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, fn := range f.subs {
+		fn(ev)
+	}
+}
+
+func (f *localFeed) subscribers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
+
+// readStreamEvent reads the next SSE frame and decodes its data line
+func readStreamEvent(t *testing.T, r *bufio.Reader) handlerStreamEvent {
+	t.Helper()
+	var ev handlerStreamEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return ev
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			require.NoError(t, json.Unmarshal([]byte(data), &ev))
+		}
+	}
+}
+
+type handlerStreamEvent struct {
+	Sequence uint64          `json:"seq"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+}
+
+func TestStreamEvents_HistoryThenLive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store.Close()
+
+	feed := newLocalFeed()
+	eng := engine.NewWalletEngine(store, nil)
+	eng.RegisterEventHandler(feed.publish)
+	eng.SetBalance("alice", 1000)
+
+	ctx := context.Background()
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{TransactionID: "txn-1", FromAccount: "alice", ToAccount: "bob", Amount: 100})
+	require.NoError(t, err)
+
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), eng)
+	h.EnableEventStream(store, feed)
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/v1/wallet/events/stream?from_seq=0", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(resp.Body)
+
+	// Historical events from the store
+	ev := readStreamEvent(t, r)
+	assert.Equal(t, uint64(1), ev.Sequence)
+	assert.Equal(t, domain.EventTypeMoneyDeducted, ev.Type)
+	ev = readStreamEvent(t, r)
+	assert.Equal(t, uint64(2), ev.Sequence)
+	assert.Equal(t, domain.EventTypeMoneyCredited, ev.Type)
+
+	// A new transfer arrives live on the same connection
+	require.Eventually(t, func() bool { return feed.subscribers() == 1 }, time.Second, 10*time.Millisecond)
+	_, err = eng.ProcessCommand(ctx, domain.TransferCommand{TransactionID: "txn-2", FromAccount: "alice", ToAccount: "carol", Amount: 50})
+	require.NoError(t, err)
+
+	ev = readStreamEvent(t, r)
+	assert.Equal(t, uint64(3), ev.Sequence)
+	var deducted domain.MoneyDeducted
+	require.NoError(t, json.Unmarshal(ev.Data, &deducted))
+	assert.Equal(t, "txn-2", deducted.TransactionID)
+	ev = readStreamEvent(t, r)
+	assert.Equal(t, uint64(4), ev.Sequence)
+}
+
+func TestStreamEvents_ResumeFromSequence(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	defer store.Close()
+
+	eng := engine.NewWalletEngine(store, nil)
+	eng.SetBalance("alice", 1000)
+	for _, id := range []string{"txn-1", "txn-2"} {
+		_, err = eng.ProcessCommand(context.Background(), domain.TransferCommand{TransactionID: id, FromAccount: "alice", ToAccount: "bob", Amount: 10})
+		require.NoError(t, err)
+	}
+
+	h := handler.NewHandler(nil, cqrs.NewReadModel(nil), eng)
+	h.EnableEventStream(store, newLocalFeed())
+	router := gin.New()
+	handler.SetupRoutes(router, h)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/wallet/events/stream?from_seq=3", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	ev := readStreamEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, uint64(3), ev.Sequence, "stream should start at from_seq")
+}
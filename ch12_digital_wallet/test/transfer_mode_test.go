@@ -0,0 +1,165 @@
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nathanyu/digital-wallet/internal/domain"
+	"github.com/nathanyu/digital-wallet/internal/engine"
+	"github.com/nathanyu/digital-wallet/internal/eventstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTransferModeTest(t *testing.T) (*engine.WalletEngine, *eventstore.EventStore) {
+	tmpFile, err := os.CreateTemp("", "events-*.log")
+	require.NoError(t, err)
+	tmpFile.Close()
+	t.Cleanup(func() { os.Remove(tmpFile.Name()) })
+
+	store, err := eventstore.NewEventStore(tmpFile.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	return engine.NewWalletEngine(store, nil), store
+}
+
+func TestTransferMode_AllEmptiesSource(t *testing.T) {
+	eng, store := setupTransferModeTest(t)
+	eng.SetBalance("alice", 12345)
+	eng.SetBalance("bob", 100)
+
+	cmd := domain.TransferCommand{TransactionID: "sweep-1", FromAccount: "alice", ToAccount: "bob", Mode: domain.TransferModeAll}
+	events, err := eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(12345), events[0].(domain.MoneyDeducted).Amount)
+	assert.Equal(t, int64(12345), events[1].(domain.MoneyCredited).Amount)
+
+	assert.Equal(t, int64(0), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(12445), eng.GetBalance("bob", "USD"))
+
+	// Idempotent: the retry is deduped rather than sweeping again
+	eng.SetBalance("alice", 500)
+	events, err = eng.ProcessCommand(context.Background(), cmd)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, int64(500), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(12445), eng.GetBalance("bob", "USD"))
+
+	// The resolved amount is what gets persisted, so replay is deterministic
+	loaded, err := store.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.Equal(t, int64(12345), loaded[0].(domain.MoneyDeducted).Amount)
+}
+
+func TestTransferMode_AllFromEmptyAccountFails(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+
+	events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+		TransactionID: "sweep-empty", FromAccount: "alice", ToAccount: "bob", Mode: domain.TransferModeAll,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "insufficient funds", events[0].(domain.TransactionFailed).Reason)
+}
+
+func TestTransferMode_PercentRoundsDown(t *testing.T) {
+	tests := []struct {
+		balance  int64
+		percent  int64
+		expected int64
+	}{
+		{balance: 10000, percent: 25, expected: 2500},
+		{balance: 999, percent: 50, expected: 499}, // 499.5 rounds down
+		{balance: 101, percent: 33, expected: 33},  // 33.33 rounds down
+		{balance: 777, percent: 100, expected: 777},
+	}
+
+	for _, tt := range tests {
+		eng, _ := setupTransferModeTest(t)
+		eng.SetBalance("alice", tt.balance)
+
+		events, err := eng.ProcessCommand(context.Background(), domain.TransferCommand{
+			TransactionID: "pct", FromAccount: "alice", ToAccount: "bob",
+			Mode: domain.TransferModePercent, Percent: tt.percent,
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 2, "balance %d percent %d", tt.balance, tt.percent)
+		assert.Equal(t, tt.expected, events[0].(domain.MoneyDeducted).Amount, "balance %d percent %d", tt.balance, tt.percent)
+		assert.Equal(t, tt.balance-tt.expected, eng.GetBalance("alice", "USD"))
+		assert.Equal(t, tt.expected, eng.GetBalance("bob", "USD"))
+	}
+}
+
+func TestTransferMode_PercentIsIdempotent(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1000)
+
+	cmd := domain.TransferCommand{
+		TransactionID: "pct-retry", FromAccount: "alice", ToAccount: "bob",
+		Mode: domain.TransferModePercent, Percent: 50,
+	}
+	for i := 0; i < 3; i++ {
+		_, err := eng.ProcessCommand(context.Background(), cmd)
+		require.NoError(t, err)
+	}
+
+	// Retrying must not take half of the remaining balance again
+	assert.Equal(t, int64(500), eng.GetBalance("alice", "USD"))
+	assert.Equal(t, int64(500), eng.GetBalance("bob", "USD"))
+}
+
+func TestTransferMode_Validation(t *testing.T) {
+	eng, _ := setupTransferModeTest(t)
+	eng.SetBalance("alice", 1000)
+
+	tests := []struct {
+		name   string
+		cmd    domain.TransferCommand
+		reason string
+	}{
+		{
+			name:   "percent out of range",
+			cmd:    domain.TransferCommand{Mode: domain.TransferModePercent, Percent: 101},
+			reason: "percent must be between 1 and 100",
+		},
+		{
+			name:   "percent rounds to zero",
+			cmd:    domain.TransferCommand{Mode: domain.TransferModePercent, Percent: 1, FromAccount: "carol"},
+			reason: "insufficient funds",
+		},
+		{
+			name:   "unknown mode",
+			cmd:    domain.TransferCommand{Mode: "half"},
+			reason: `unknown transfer mode "half"`,
+		},
+		{
+			name:   "exact without amount",
+			cmd:    domain.TransferCommand{Mode: domain.TransferModeExact},
+			reason: "amount must be positive",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := tt.cmd
+			cmd.TransactionID = generateTestTxnID(i)
+			if cmd.FromAccount == "" {
+				cmd.FromAccount = "alice"
+			}
+			cmd.ToAccount = "bob"
+			if cmd.FromAccount == "carol" {
+				eng.SetBalance("carol", 50)
+			}
+
+			events, err := eng.ProcessCommand(context.Background(), cmd)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, tt.reason, events[0].(domain.TransactionFailed).Reason)
+		})
+	}
+	assert.Equal(t, int64(1000), eng.GetBalance("alice", "USD"))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/handler"
+	"github.com/nathanyu/stock-exchange/internal/loadtest"
+)
+
+// BenchmarkPipelinePlaceOrder measures concurrent POST /v1/order calls
+// through the full HTTP -> manager -> sequencer -> engine pipeline. The
+// workload is fixed so results compare across changes: each iteration places
+// 2000 one-share orders from 32 clients, alternating buys and sells at one
+// price so every sell crosses a buy, and waits until all of them have traded.
+// orders/s counts from the first request to the last trade; the latency
+// percentiles are per HTTP request.
+func BenchmarkPipelinePlaceOrder(b *testing.B) {
+	cfg := loadtest.Config{Orders: 2000, Concurrency: 32}
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	gin.SetMode(gin.TestMode)
+
+	p, srv := startTestPipeline(b, false)
+	defer p.shutdown(srv, 5*time.Second)
+	p.manager.InitWallet("alice", 1<<50, nil)
+	p.manager.InitWallet("bob", 0, map[string]int64{"AAPL": 1 << 40})
+
+	r := gin.New()
+	handler.NewHandler(p.manager, nil, p.publisher).RegisterRoutes(r)
+	api := httptest.NewServer(r)
+	defer api.Close()
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: cfg.Concurrency}}
+
+	place := func(placed []string) loadtest.PlaceFunc {
+		return func(i int) error {
+			req := handler.PlaceOrderRequest{Symbol: "AAPL", Side: domain.SideBuy, Price: 10000, Quantity: 1, UserID: "alice"}
+			if i%2 == 1 {
+				req.Side, req.UserID = domain.SideSell, "bob"
+			}
+			body, _ := json.Marshal(req)
+			resp, err := client.Post(api.URL+"/v1/order", "application/json", bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusCreated {
+				return fmt.Errorf("status %d", resp.StatusCode)
+			}
+			var order domain.Order
+			if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+				return err
+			}
+			placed[i] = order.OrderID
+			return nil
+		}
+	}
+
+	var (
+		elapsed  time.Duration
+		p50, p99 time.Duration
+		traded   = len(p.publisher.GetExecutions("AAPL", "", time.Time{}))
+	)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		placed := make([]string, cfg.Orders)
+		start := time.Now()
+		report := loadtest.Run(cfg, place(placed))
+		if report.Rejected > 0 {
+			b.Fatalf("%d orders rejected", report.Rejected)
+		}
+
+		traded += cfg.Orders / 2
+		deadline := time.Now().Add(10 * time.Second)
+		for len(p.publisher.GetExecutions("AAPL", "", time.Time{})) < traded {
+			if time.Now().After(deadline) {
+				b.Fatal("orders did not trade within 10s")
+			}
+			time.Sleep(100 * time.Microsecond)
+		}
+		elapsed += time.Since(start)
+		p50 += report.P50
+		p99 += report.P99
+
+		// Everything traded, but leave no order behind if it did not
+		b.StopTimer()
+		for _, id := range placed {
+			p.manager.CancelOrder(id)
+		}
+		b.StartTimer()
+	}
+
+	b.ReportMetric(float64(b.N*cfg.Orders)/elapsed.Seconds(), "orders/s")
+	b.ReportMetric(float64(p50.Microseconds())/float64(b.N), "p50-µs")
+	b.ReportMetric(float64(p99.Microseconds())/float64(b.N), "p99-µs")
+}
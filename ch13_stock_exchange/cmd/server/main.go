@@ -2,20 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/nathanyu/stock-exchange/internal/batching"
 	"github.com/nathanyu/stock-exchange/internal/handler"
 	"github.com/nathanyu/stock-exchange/internal/marketdata"
 	"github.com/nathanyu/stock-exchange/internal/matching"
 	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/nathanyu/stock-exchange/internal/orderbook"
 	"github.com/nathanyu/stock-exchange/internal/ordermanager"
 	"github.com/nathanyu/stock-exchange/internal/sequencer"
 )
@@ -31,16 +37,295 @@ func main() {
 	// --- Core components ---
 
 	// Matching engine (stateless dispatcher over per-symbol order books)
-	engine := matching.NewEngine()
+	// ORDERBOOK_POOLING=true recycles book objects to reduce GC pressure under churn
+	bookOpts := orderbook.Options{
+		Pooling: os.Getenv("ORDERBOOK_POOLING") == "true",
+	}
+	// MATCHING_ALLOCATION: fifo (default), pro_rata or hybrid; hybrid guarantees
+	// the first order at a level HYBRID_TOP_ALLOCATION_BPS of the fill (default 4000)
+	if p := os.Getenv("MATCHING_ALLOCATION"); p != "" {
+		policy, err := orderbook.ParseAllocationPolicy(p)
+		if err != nil {
+			log.Fatalf("Invalid MATCHING_ALLOCATION: %v", err)
+		}
+		bookOpts.Allocation = policy
+	}
+	// Only read where hybrid allocation is used, so a FIFO deployment does
+	// not fail on it
+	hybridTopBps := func() int64 {
+		n := os.Getenv("HYBRID_TOP_ALLOCATION_BPS")
+		if n == "" {
+			return 4000
+		}
+		bps, err := strconv.ParseInt(n, 10, 64)
+		if err != nil || bps <= 0 || bps > 10000 {
+			log.Fatalf("Invalid HYBRID_TOP_ALLOCATION_BPS %q: want 1-10000", n)
+		}
+		return bps
+	}
+	if bookOpts.Allocation == orderbook.AllocationHybrid {
+		bookOpts.TopAllocationBps = hybridTopBps()
+	}
+	engine := matching.NewEngineWithOptions(bookOpts)
+	// MATCHING_ALLOCATION_SYMBOLS (e.g. "ES=pro_rata,CL=hybrid") overrides
+	// MATCHING_ALLOCATION for individual symbols
+	if list := os.Getenv("MATCHING_ALLOCATION_SYMBOLS"); list != "" {
+		for _, entry := range strings.Split(list, ",") {
+			symbol, name, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if !ok || symbol == "" {
+				log.Fatalf("Invalid MATCHING_ALLOCATION_SYMBOLS entry %q: want SYMBOL=policy", entry)
+			}
+			policy, err := orderbook.ParseAllocationPolicy(name)
+			if err == nil {
+				var topBps int64
+				if policy == orderbook.AllocationHybrid {
+					topBps = hybridTopBps()
+				}
+				err = engine.SetSymbolAllocation(symbol, policy, topBps)
+			}
+			if err != nil {
+				log.Fatalf("Invalid MATCHING_ALLOCATION_SYMBOLS entry %q: %v", entry, err)
+			}
+		}
+	}
+	// AUDIT_EXECUTIONS=true checks every execution price against maker and taker
+	engine.SetPriceAudit(os.Getenv("AUDIT_EXECUTIONS") == "true")
+	// DUPLICATE_ORDER_POLICY: reject (default) or skip new orders reusing a resting order's ID
+	if p := os.Getenv("DUPLICATE_ORDER_POLICY"); p != "" {
+		policy, err := matching.ParseDuplicateOrderPolicy(p)
+		if err != nil {
+			log.Fatalf("Invalid DUPLICATE_ORDER_POLICY: %v", err)
+		}
+		engine.SetDuplicateOrderPolicy(policy)
+	}
+	// LAYERING_MAX_LEVEL_QTY flags a user resting more than this at one price
+	// level (off by default); LAYERING_REJECT=true also cancels the remainder
+	if n := os.Getenv("LAYERING_MAX_LEVEL_QTY"); n != "" {
+		maxQty, err := strconv.ParseInt(n, 10, 64)
+		if err != nil || maxQty < 0 {
+			log.Fatalf("Invalid LAYERING_MAX_LEVEL_QTY %q", n)
+		}
+		engine.SetLayeringCap(matching.LayeringCap{
+			MaxLevelQty: maxQty,
+			Reject:      os.Getenv("LAYERING_REJECT") == "true",
+		})
+	}
+	// FILL_RATIO_SYMBOLS (comma-separated) are the symbols exchange_fill_ratio
+	// is labeled with; every other symbol, or all of them when it is unset,
+	// is recorded as "other"
+	if list := os.Getenv("FILL_RATIO_SYMBOLS"); list != "" {
+		var symbols []string
+		for _, s := range strings.Split(list, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				symbols = append(symbols, s)
+			}
+		}
+		engine.SetFillRatioSymbols(symbols)
+	}
 
 	// Sequencer (stamps sequence IDs, feeds matching engine)
 	seq := sequencer.NewSequencer(engine, channelBufferSize)
 
 	// Order manager (risk check, wallet, order state)
 	manager := ordermanager.NewManager(maxDailyVolume, channelBufferSize)
+	// RESERVATION_TTL controls how long two-phase reservations withhold funds
+	if ttlStr := os.Getenv("RESERVATION_TTL"); ttlStr != "" {
+		ttl, err := time.ParseDuration(ttlStr)
+		if err == nil {
+			err = manager.SetReservationTTL(ttl)
+		}
+		if err != nil {
+			log.Fatalf("Invalid RESERVATION_TTL %q: %v", ttlStr, err)
+		}
+	}
+
+	// DAILY_VOLUME_TZ (e.g. "America/New_York") sets the timezone whose
+	// midnight resets each user's daily volume; unset uses the server's
+	if tz := os.Getenv("DAILY_VOLUME_TZ"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			log.Fatalf("Invalid DAILY_VOLUME_TZ %q: %v", tz, err)
+		}
+		manager.SetDailyVolumeTimezone(loc)
+	}
+
+	// TRADING_HOURS (e.g. "09:30-16:00,BTC=00:00-23:59") limits new orders to
+	// a daily session, in TRADING_TZ (default: the server's timezone). An
+	// entry without a symbol applies to every symbol without its own.
+	if list := os.Getenv("TRADING_HOURS"); list != "" {
+		loc := time.Local
+		if tz := os.Getenv("TRADING_TZ"); tz != "" {
+			var err error
+			if loc, err = time.LoadLocation(tz); err != nil {
+				log.Fatalf("Invalid TRADING_TZ %q: %v", tz, err)
+			}
+		}
+		for _, entry := range strings.Split(list, ",") {
+			entry = strings.TrimSpace(entry)
+			symbol, spec, ok := strings.Cut(entry, "=")
+			if !ok {
+				symbol, spec = "", entry
+			}
+			hours, err := ordermanager.ParseTradingHours(spec, loc)
+			if err == nil {
+				err = manager.SetTradingHours(symbol, hours)
+			}
+			if err != nil {
+				log.Fatalf("Invalid TRADING_HOURS entry %q: %v", entry, err)
+			}
+		}
+	}
+
+	// QUANTITY_SCALES (e.g. "AAPL=1000000,TSLA=1000") lets symbols trade in
+	// fractions of a share: quantities and holdings are then in units of
+	// 1/scale shares. Unlisted symbols trade whole shares.
+	if list := os.Getenv("QUANTITY_SCALES"); list != "" {
+		for _, entry := range strings.Split(list, ",") {
+			symbol, scaleStr, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			scale, err := strconv.ParseInt(scaleStr, 10, 64)
+			if !ok || symbol == "" || err != nil {
+				log.Fatalf("Invalid QUANTITY_SCALES entry %q: want SYMBOL=scale", entry)
+			}
+			spec, _ := manager.GetSymbolSpec(symbol)
+			spec.QuantityScale = scale
+			if err := manager.SetSymbolSpec(symbol, spec); err != nil {
+				log.Fatalf("Invalid QUANTITY_SCALES entry %q: %v", entry, err)
+			}
+		}
+	}
+
+	// PRICE_BANDS (e.g. "AAPL=500") rejects orders priced more than the given
+	// basis points away from the symbol's reference price, which the market
+	// data publisher tracks from the previous close
+	if list := os.Getenv("PRICE_BANDS"); list != "" {
+		for _, entry := range strings.Split(list, ",") {
+			symbol, bpsStr, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			bps, err := strconv.ParseInt(bpsStr, 10, 64)
+			if !ok || symbol == "" || err != nil {
+				log.Fatalf("Invalid PRICE_BANDS entry %q: want SYMBOL=bps", entry)
+			}
+			spec, _ := manager.GetSymbolSpec(symbol)
+			spec.PriceBandBps = bps
+			if err := manager.SetSymbolSpec(symbol, spec); err != nil {
+				log.Fatalf("Invalid PRICE_BANDS entry %q: %v", entry, err)
+			}
+		}
+	}
+
+	// LOCK_STRIPES sets how many per-user wallet locks orders are spread over;
+	// 1 serializes all wallet updates
+	if n := os.Getenv("LOCK_STRIPES"); n != "" {
+		stripes, err := strconv.Atoi(n)
+		if err == nil {
+			err = manager.SetLockStripes(stripes)
+		}
+		if err != nil {
+			log.Fatalf("Invalid LOCK_STRIPES %q: %v", n, err)
+		}
+	}
+
+	// ORDER_RETENTION (e.g. "1h") evicts filled and canceled orders from
+	// memory once they have been terminal that long; unset keeps every order
+	if retentionStr := os.Getenv("ORDER_RETENTION"); retentionStr != "" {
+		retention, err := time.ParseDuration(retentionStr)
+		if err == nil {
+			err = manager.SetOrderRetention(retention)
+		}
+		if err != nil {
+			log.Fatalf("Invalid ORDER_RETENTION %q: %v", retentionStr, err)
+		}
+	}
+
+	// FAIR_QUEUING=true round-robins order intake across per-user queues so
+	// one user flooding orders cannot starve the others
+	if v := os.Getenv("FAIR_QUEUING"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("Invalid FAIR_QUEUING %q: %v", v, err)
+		}
+		if enabled {
+			manager.EnableFairQueuing()
+		}
+	}
 
 	// Market data publisher (candlesticks, execution log)
 	publisher := marketdata.NewPublisher(channelBufferSize)
+	// Price bands are centered on the last trade (or reference price) it tracks
+	manager.SetReferencePriceSource(publisher.BandPrice)
+	// Market buys withhold cash at the ask a sweep of their quantity reaches
+	manager.SetAskDepthSource(engine.SweepAskPrice)
+	// Unrealized PnL marks positions to the last trade price
+	manager.SetLastPriceSource(publisher.LastPrice)
+	// The market data stream diffs the engine's books after each event
+	publisher.SetBookSource(engine.GetL2Snapshot)
+
+	// CANDLE_INTERVALS (e.g. "5m,1h") keeps candle series at these intervals
+	// next to the 1m candles, each built from the executions themselves
+	if list := os.Getenv("CANDLE_INTERVALS"); list != "" {
+		var intervals []time.Duration
+		for _, entry := range strings.Split(list, ",") {
+			interval, err := time.ParseDuration(strings.TrimSpace(entry))
+			if err != nil {
+				log.Fatalf("Invalid CANDLE_INTERVALS entry %q: %v", entry, err)
+			}
+			intervals = append(intervals, interval)
+		}
+		if err := publisher.SetCandleIntervals(intervals); err != nil {
+			log.Fatalf("Invalid CANDLE_INTERVALS: %v", err)
+		}
+	}
+
+	// CANDLE_COARSE_INTERVAL (e.g. "1h") keeps history past the 1m candles as
+	// downsampled candles; CANDLE_FINE_RETENTION (e.g. "24h") is how long 1m
+	// candles are kept before being folded in (default: until evicted)
+	if coarseStr := os.Getenv("CANDLE_COARSE_INTERVAL"); coarseStr != "" {
+		coarse, err := time.ParseDuration(coarseStr)
+		if err != nil {
+			log.Fatalf("Invalid CANDLE_COARSE_INTERVAL %q: %v", coarseStr, err)
+		}
+		var fineFor time.Duration
+		if fineStr := os.Getenv("CANDLE_FINE_RETENTION"); fineStr != "" {
+			if fineFor, err = time.ParseDuration(fineStr); err != nil {
+				log.Fatalf("Invalid CANDLE_FINE_RETENTION %q: %v", fineStr, err)
+			}
+		}
+		if err := publisher.SetCandleRetention(marketdata.CandleRetention{FineFor: fineFor, Coarse: coarse}); err != nil {
+			log.Fatalf("Invalid candle retention: %v", err)
+		}
+	}
+
+	// EXECUTION_LOG_PATH persists every execution; on startup candles and the
+	// execution history are rebuilt from it before new executions are appended
+	if logPath := os.Getenv("EXECUTION_LOG_PATH"); logPath != "" {
+		if err := publisher.RebuildFromLog(logPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("Failed to rebuild market data from %s: %v", logPath, err)
+		}
+		if err := publisher.EnableExecutionLog(logPath); err != nil {
+			log.Fatalf("Failed to open execution log %s: %v", logPath, err)
+		}
+	}
+
+	// EXECUTION_BATCH_WINDOW (e.g. "1ms", or "0s" to drain only queued events)
+	// lets both consumers coalesce bursts of execution events into one pass
+	if windowStr := os.Getenv("EXECUTION_BATCH_WINDOW"); windowStr != "" {
+		window, err := time.ParseDuration(windowStr)
+		if err != nil {
+			log.Fatalf("Invalid EXECUTION_BATCH_WINDOW %q: %v", windowStr, err)
+		}
+		opts := batching.Options{Enabled: true, Window: window}
+		manager.SetExecutionBatching(opts)
+		publisher.SetExecutionBatching(opts)
+	}
+
+	// ORDER_JOURNAL_PATH journals every sequenced order event, and
+	// <path>.wallets every wallet initialization. On startup both are
+	// replayed: the books and the order manager's wallets, orders and
+	// withholding are rebuilt before any new order is accepted.
+	if journalPath := os.Getenv("ORDER_JOURNAL_PATH"); journalPath != "" {
+		if err := recoverJournal(manager, seq, journalPath); err != nil {
+			log.Fatalf("Failed to recover from the order journal: %v", err)
+		}
+	}
 
 	// --- Wire channels (simulating ring buffers / mmap) ---
 	//
@@ -53,35 +338,31 @@ func main() {
 	// We use a fan-out goroutine to send execution events to both
 	// the order manager and the market data publisher.
 
-	// Start the fan-out from manager's OrderOut to sequencer's OrderIn
-	go func() {
-		for event := range manager.OrderOut {
-			seq.OrderIn <- event
+	// Start the fan-out from sequencer's ExecutionOut to both consumers.
+	// MARKETDATA_DELIVERY=reliable extends the settlement guarantee to
+	// market data; by default it is best-effort and drops when full.
+	marketDataDelivery := sequencer.DeliveryBestEffort
+	if d := os.Getenv("MARKETDATA_DELIVERY"); d != "" {
+		parsed, err := sequencer.ParseDelivery(d)
+		if err != nil {
+			log.Fatalf("Invalid MARKETDATA_DELIVERY: %v", err)
 		}
-	}()
-
-	// Start the fan-out from sequencer's ExecutionOut to both consumers
-	go func() {
-		for event := range seq.ExecutionOut {
-			// Fan out to order manager
-			select {
-			case manager.ExecutionIn <- event:
-			default:
-				log.Println("[main] WARN: order manager execution channel full")
-			}
-			// Fan out to market data publisher
-			select {
-			case publisher.ExecutionIn <- event:
-			default:
-				log.Println("[main] WARN: market data execution channel full")
-			}
+		marketDataDelivery = parsed
+	}
+	// SHUTDOWN_STAGE_TIMEOUT bounds each step of the graceful shutdown
+	// (default 5s): HTTP drain, order intake, sequencer, then consumers
+	shutdownStageTimeout := defaultShutdownStageTimeout
+	if timeoutStr := os.Getenv("SHUTDOWN_STAGE_TIMEOUT"); timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			log.Fatalf("Invalid SHUTDOWN_STAGE_TIMEOUT %q", timeoutStr)
 		}
-	}()
+		shutdownStageTimeout = timeout
+	}
 
 	// Start component goroutines
-	seq.Start()
-	manager.Start()
-	publisher.Start()
+	pipe := newPipeline(manager, seq, publisher, marketDataDelivery)
+	pipe.start()
 
 	// --- HTTP Server ---
 	port := os.Getenv("PORT")
@@ -93,6 +374,28 @@ func main() {
 	r.Use(middleware.PrometheusMiddleware())
 
 	h := handler.NewHandler(manager, engine, publisher)
+	if os.Getenv("DEBUG_ENDPOINTS") == "true" {
+		log.Println("Debug endpoints enabled")
+		h.EnableDebug()
+	}
+	// LOADTEST_ENDPOINT=true exposes POST /v1/admin/loadtest, which places
+	// real orders; keep it off outside test environments
+	if os.Getenv("LOADTEST_ENDPOINT") == "true" {
+		log.Println("Load test endpoint enabled")
+		h.EnableLoadTest()
+	}
+	// AUCTION_WINDOW (default 30s) is how long POST /v1/admin/auction collects
+	// orders before uncrossing, unless the request gives its own window
+	auctionWindow := 30 * time.Second
+	if windowStr := os.Getenv("AUCTION_WINDOW"); windowStr != "" {
+		window, err := time.ParseDuration(windowStr)
+		if err != nil || window <= 0 {
+			log.Fatalf("Invalid AUCTION_WINDOW %q", windowStr)
+		}
+		auctionWindow = window
+	}
+	h.EnableAuctions(seq, auctionWindow)
+	h.EnableSessionReset(seq)
 	h.RegisterRoutes(r)
 
 	srv := &http.Server{
@@ -135,16 +438,12 @@ func main() {
 
 	log.Println("Shutting down...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	seq.Stop()
-	manager.Stop()
-	publisher.Stop()
+	// Stop taking orders before the components behind the API stop
+	pipe.shutdown(srv, shutdownStageTimeout)
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("HTTP server shutdown error: %v", err)
-	}
+	// Metrics stay scrapeable until the pipeline has drained
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownStageTimeout)
+	defer cancel()
 	if err := metricsSrv.Shutdown(ctx); err != nil {
 		log.Printf("Metrics server shutdown error: %v", err)
 	}
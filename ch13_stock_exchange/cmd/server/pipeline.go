@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/marketdata"
+	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+	"github.com/nathanyu/stock-exchange/internal/sequencer"
+)
+
+// defaultShutdownStageTimeout bounds each shutdown stage unless
+// SHUTDOWN_STAGE_TIMEOUT overrides it.
+const defaultShutdownStageTimeout = 5 * time.Second
+
+// pipeline wires the order manager, sequencer and market data publisher
+// together and stops them in dependency order.
+type pipeline struct {
+	manager    *ordermanager.Manager
+	seq        *sequencer.Sequencer
+	publisher  *marketdata.Publisher
+	fanOut     *sequencer.FanOut
+	fanOutDone chan struct{}
+}
+
+// newPipeline creates the pipeline. Settlement is always delivered reliably:
+// a dropped execution would corrupt wallets. Market data gets
+// marketDataDelivery.
+func newPipeline(manager *ordermanager.Manager, seq *sequencer.Sequencer, publisher *marketdata.Publisher, marketDataDelivery sequencer.Delivery) *pipeline {
+	return &pipeline{
+		manager:   manager,
+		seq:       seq,
+		publisher: publisher,
+		fanOut: sequencer.NewFanOut(
+			sequencer.Consumer{Name: "ordermanager", In: manager.ExecutionIn, Delivery: sequencer.DeliveryReliable},
+			sequencer.Consumer{Name: "marketdata", In: publisher.ExecutionIn, Delivery: marketDataDelivery},
+		),
+		fanOutDone: make(chan struct{}),
+	}
+}
+
+// recoverJournal rebuilds the books and the order manager from the order
+// journal at path and the wallet journal next to it, then journals to both
+// from here on. Missing journals are a fresh start. Must be called before
+// start.
+func recoverJournal(manager *ordermanager.Manager, seq *sequencer.Sequencer, path string) error {
+	walletPath := path + ".wallets"
+	if err := manager.LoadWalletJournal(walletPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("load wallet journal %s: %w", walletPath, err)
+	}
+	if _, err := seq.Recover(path, manager.RecoverEvent); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("recover order books from %s: %w", path, err)
+	}
+	manager.FinishRecovery()
+	if err := seq.EnableJournal(path); err != nil {
+		return fmt.Errorf("open order journal %s: %w", path, err)
+	}
+	if err := manager.EnableWalletJournal(walletPath, seq.CurrentInboundSeq); err != nil {
+		return fmt.Errorf("open wallet journal %s: %w", walletPath, err)
+	}
+	return nil
+}
+
+// start connects the channels and starts every component.
+func (p *pipeline) start() {
+	// Forward the manager's OrderOut to the sequencer's OrderIn
+	go func() {
+		for event := range p.manager.OrderOut {
+			p.seq.OrderIn <- event
+		}
+	}()
+
+	// Fan the sequencer's executions out to settlement and market data
+	go p.fanOut.Run(p.seq.ExecutionOut, p.fanOutDone)
+
+	p.seq.Start()
+	p.manager.Start()
+	p.publisher.Start()
+}
+
+// shutdown stops the exchange without losing orders it has accepted:
+//  1. server stops accepting requests and finishes the ones in flight
+//  2. the order manager's intake closes and a drain marker is queued behind it
+//  3. the sequencer stops once it has matched everything ahead of the marker
+//  4. settlement and the publisher stop once they have applied every
+//     execution ahead of it
+//
+// Each stage is bounded by stageTimeout. One that overruns is logged and the
+// rest are stopped anyway, so a wedged component cannot hold up exit.
+func (p *pipeline) shutdown(server *http.Server, stageTimeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), stageTimeout)
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+	cancel()
+
+	// Handlers still running after the timeout get SHUTTING_DOWN from here on
+	p.manager.CloseIntake()
+	marker := domain.NewDrainMarker()
+
+	ctx, cancel = context.WithTimeout(context.Background(), stageTimeout)
+	drained := false
+	if err := p.manager.DrainIntake(ctx, marker); err != nil {
+		log.Printf("Shutdown: %v", err)
+	} else {
+		drained = waitStage(ctx, "sequencer", marker.Sequenced)
+	}
+	cancel()
+	p.seq.Stop()
+
+	// Without the marker through the sequencer there is nothing to wait for
+	if drained {
+		ctx, cancel = context.WithTimeout(context.Background(), stageTimeout)
+		waitStage(ctx, "settlement", marker.Settled)
+		waitStage(ctx, "market data", marker.Published)
+		cancel()
+	}
+	close(p.fanOutDone)
+	p.manager.Stop()
+	p.publisher.Stop()
+}
+
+// waitStage waits for a pipeline stage to pass the drain marker. It reports
+// false, after logging, if ctx expires first.
+func waitStage(ctx context.Context, name string, passed <-chan struct{}) bool {
+	select {
+	case <-passed:
+		return true
+	case <-ctx.Done():
+		log.Printf("Shutdown: %s did not drain within the stage timeout, stopping it anyway", name)
+		return false
+	}
+}
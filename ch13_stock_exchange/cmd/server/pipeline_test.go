@@ -0,0 +1,218 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/marketdata"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+	"github.com/nathanyu/stock-exchange/internal/sequencer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestPipeline starts a pipeline wired like main's, plus an idle HTTP
+// server for shutdown to stop first.
+func startTestPipeline(t testing.TB, fairQueuing bool) (*pipeline, *http.Server) {
+	t.Helper()
+	// Deep enough that the clients below never fill the intake, which drops
+	// orders whatever the shutdown does
+	const bufferSize = 1 << 16
+	seq := sequencer.NewSequencer(matching.NewEngine(), bufferSize)
+	manager := ordermanager.NewManager(1_000_000_000, bufferSize)
+	if fairQueuing {
+		manager.EnableFairQueuing()
+	}
+	manager.InitWallet("alice", 1_000_000_000, nil)
+	manager.InitWallet("bob", 0, map[string]int64{"AAPL": 1_000_000})
+
+	p := newPipeline(manager, seq, marketdata.NewPublisher(bufferSize), sequencer.DeliveryBestEffort)
+	p.start()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: http.NotFoundHandler()}
+	go srv.Serve(ln)
+	return p, srv
+}
+
+func TestPipelineShutdown_SettlesAcceptedOrders(t *testing.T) {
+	p, srv := startTestPipeline(t, false)
+
+	// Accepted right before shutdown, most still queued when it starts
+	const trades = 200
+	for i := 0; i < trades; i++ {
+		_, err := p.manager.PlaceOrder("bob", "AAPL", domain.SideSell, 10000, 1)
+		require.NoError(t, err)
+		_, err = p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 10000, 1)
+		require.NoError(t, err)
+	}
+
+	p.shutdown(srv, 5*time.Second)
+
+	assert.Equal(t, int64(trades), p.manager.GetWallet("alice").Holdings["AAPL"])
+	assert.Len(t, p.publisher.GetExecutions("AAPL", "", time.Time{}), trades)
+
+	_, err := p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 10000, 1)
+	assert.Equal(t, ordermanager.RejectShuttingDown, ordermanager.RejectCodeOf(err))
+}
+
+func TestPipelineShutdown_OrdersDuringShutdownProcessedOrRejected(t *testing.T) {
+	for _, fair := range []bool{false, true} {
+		p, srv := startTestPipeline(t, fair)
+
+		// Clients keep placing orders until shutdown turns them away
+		const clients = 4
+		var (
+			mu       sync.Mutex
+			accepted []*domain.Order
+			wg       sync.WaitGroup
+		)
+		started := make(chan struct{}, clients)
+		for c := 0; c < clients; c++ {
+			wg.Add(1)
+			go func(c int) {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					order, err := p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, int64(100+c), 1)
+					if err != nil {
+						assert.Equal(t, ordermanager.RejectShuttingDown, ordermanager.RejectCodeOf(err), "fair=%v: %v", fair, err)
+						return
+					}
+					mu.Lock()
+					accepted = append(accepted, order)
+					mu.Unlock()
+					if i == 0 {
+						started <- struct{}{}
+					}
+				}
+			}(c)
+		}
+		for c := 0; c < clients; c++ {
+			<-started
+		}
+
+		p.shutdown(srv, 5*time.Second)
+		wg.Wait()
+
+		require.NotEmpty(t, accepted)
+		for _, order := range accepted {
+			assert.NotZero(t, order.SequenceID, "fair=%v: accepted order %s never reached the sequencer", fair, order.OrderID)
+		}
+	}
+}
+
+// startJournaledPipeline starts a pipeline like main's with ORDER_JOURNAL_PATH
+// set to path, recovering whatever an earlier pipeline journaled there.
+func startJournaledPipeline(t *testing.T, path string) (*pipeline, *matching.Engine, *http.Server) {
+	t.Helper()
+	engine := matching.NewEngine()
+	seq := sequencer.NewSequencer(engine, 1024)
+	manager := ordermanager.NewManager(1_000_000_000, 1024)
+	require.NoError(t, recoverJournal(manager, seq, path))
+
+	p := newPipeline(manager, seq, marketdata.NewPublisher(1024), sequencer.DeliveryBestEffort)
+	p.start()
+	return p, engine, &http.Server{}
+}
+
+func TestPipelineRestart_RecoversBooksAndOrderManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.jsonl")
+	p, engine, srv := startJournaledPipeline(t, path)
+	p.manager.InitWallet("alice", 10_000_000, nil)
+	p.manager.InitWallet("bob", 0, map[string]int64{"AAPL": 1000})
+
+	ask, err := p.manager.PlaceOrder("bob", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	_, err = p.manager.PlaceOrder("bob", "AAPL", domain.SideSell, 10100, 50)
+	require.NoError(t, err)
+	_, err = p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 10000, 30)
+	require.NoError(t, err)
+	bid, err := p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 9900, 40)
+	require.NoError(t, err)
+	canceled, err := p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 9800, 10)
+	require.NoError(t, err)
+	_, err = p.manager.CancelOrder(canceled.OrderID)
+	require.NoError(t, err)
+	p.shutdown(srv, 5*time.Second)
+
+	book := engine.GetL2Snapshot("AAPL", 10)
+	wallets := p.manager.GetAllWallets()
+	orders := make(map[string]domain.Order)
+	for _, id := range []string{ask.OrderID, bid.OrderID, canceled.OrderID} {
+		orders[id] = *p.manager.GetOrder(id)
+	}
+	require.Equal(t, domain.OrderStatusPartiallyFilled, orders[ask.OrderID].Status)
+	require.Equal(t, domain.OrderStatusCanceled, orders[canceled.OrderID].Status)
+
+	p, engine, srv = startJournaledPipeline(t, path)
+	assert.Equal(t, book, engine.GetL2Snapshot("AAPL", 10))
+	assert.Equal(t, wallets, p.manager.GetAllWallets())
+	for id, before := range orders {
+		after := p.manager.GetOrder(id)
+		require.NotNil(t, after, "order %s not recovered", id)
+		assert.Equal(t, before.Status, after.Status)
+		assert.Equal(t, before.FilledQuantity, after.FilledQuantity)
+		assert.Equal(t, before.RemainingQuantity, after.RemainingQuantity)
+		assert.Equal(t, before.SequenceID, after.SequenceID)
+	}
+
+	// The resting orders still withhold what they need: alice has
+	// 10_000_000 - 30*10000 cash with 40*9900 of it withheld, bob 970
+	// shares with 70 + 50 withheld
+	_, err = p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 9000, 1034)
+	assert.Equal(t, ordermanager.RejectInsufficientFunds, ordermanager.RejectCodeOf(err))
+	_, err = p.manager.PlaceOrder("bob", "AAPL", domain.SideSell, 10200, 851)
+	assert.Equal(t, ordermanager.RejectInsufficientShares, ordermanager.RejectCodeOf(err))
+
+	// and settle against orders placed after the restart
+	_, err = p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 10000, 70)
+	require.NoError(t, err)
+	p.shutdown(srv, 5*time.Second)
+
+	assert.Equal(t, domain.OrderStatusFilled, p.manager.GetOrder(ask.OrderID).Status)
+	assert.Equal(t, int64(100), p.manager.GetWallet("alice").Holdings["AAPL"])
+	assert.Equal(t, int64(100*10000), p.manager.GetWallet("bob").CashBalance)
+	report, err := p.manager.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+}
+
+func TestPipelineRestart_GTCOrdersSurviveSessionClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.jsonl")
+	p, _, srv := startJournaledPipeline(t, path)
+	p.manager.InitWallet("alice", 10_000_000, nil)
+	p.manager.InitWallet("bob", 0, map[string]int64{"AAPL": 1000})
+
+	day, err := p.manager.PlaceOrderWithOptions("alice", "AAPL", domain.SideBuy, 10000, 100, ordermanager.OrderOptions{TimeInForce: domain.TimeInForceDay})
+	require.NoError(t, err)
+	gtc, err := p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 9900, 100)
+	require.NoError(t, err)
+	gtcSell, err := p.manager.PlaceOrder("bob", "AAPL", domain.SideSell, 10500, 50)
+	require.NoError(t, err)
+	require.Len(t, p.manager.CloseSymbol("AAPL"), 1)
+	p.shutdown(srv, 5*time.Second)
+
+	p, engine, srv := startJournaledPipeline(t, path)
+	defer p.shutdown(srv, 5*time.Second)
+	assert.Equal(t, domain.OrderStatusCanceled, p.manager.GetOrder(day.OrderID).Status)
+	assert.Equal(t, domain.OrderStatusNew, p.manager.GetOrder(gtc.OrderID).Status)
+	assert.Equal(t, domain.OrderStatusNew, p.manager.GetOrder(gtcSell.OrderID).Status)
+	assert.Equal(t, &domain.L2OrderBook{
+		Symbol: "AAPL",
+		Bids:   []domain.PriceLevel{{Price: 9900, Quantity: 100}},
+		Asks:   []domain.PriceLevel{{Price: 10500, Quantity: 50}},
+	}, engine.GetL2Snapshot("AAPL", 10))
+
+	// Only the GTC bid still withholds cash: 10_000_000 - 9900*100 is free
+	_, err = p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 9000, 1002)
+	assert.Equal(t, ordermanager.RejectInsufficientFunds, ordermanager.RejectCodeOf(err))
+	_, err = p.manager.PlaceOrder("alice", "AAPL", domain.SideBuy, 9000, 1001)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,61 @@
+// Package batching coalesces bursts of channel messages so a consumer can
+// handle them in one pass (one lock acquisition) instead of one at a time.
+package batching
+
+import "time"
+
+// DefaultMaxBatch caps how many messages one pass may coalesce.
+const DefaultMaxBatch = 256
+
+// Options configures coalescing. The zero value disables it.
+type Options struct {
+	// Enabled turns coalescing on.
+	Enabled bool
+	// Window is how long to keep collecting after the first message.
+	// Zero only drains messages that are already queued.
+	Window time.Duration
+	// MaxBatch caps the batch size (DefaultMaxBatch if <= 0).
+	MaxBatch int
+}
+
+// Collect returns first followed by any messages arriving on in within the
+// window, in channel order, up to MaxBatch. It returns early when done is
+// closed. With coalescing disabled it returns just first.
+func Collect[T any](first T, in <-chan T, opts Options, done <-chan struct{}) []T {
+	batch := []T{first}
+	if !opts.Enabled {
+		return batch
+	}
+	maxBatch := opts.MaxBatch
+	if maxBatch <= 0 {
+		maxBatch = DefaultMaxBatch
+	}
+
+	// Drain whatever is already queued without waiting
+	for len(batch) < maxBatch {
+		select {
+		case msg := <-in:
+			batch = append(batch, msg)
+			continue
+		default:
+		}
+		break
+	}
+	if opts.Window <= 0 || len(batch) >= maxBatch {
+		return batch
+	}
+
+	timer := time.NewTimer(opts.Window)
+	defer timer.Stop()
+	for len(batch) < maxBatch {
+		select {
+		case msg := <-in:
+			batch = append(batch, msg)
+		case <-timer.C:
+			return batch
+		case <-done:
+			return batch
+		}
+	}
+	return batch
+}
@@ -0,0 +1,61 @@
+package batching
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollect_Disabled(t *testing.T) {
+	in := make(chan int, 10)
+	in <- 2
+	in <- 3
+
+	batch := Collect(1, in, Options{}, nil)
+	assert.Equal(t, []int{1}, batch)
+	assert.Len(t, in, 2, "queued messages must be left for the next pass")
+}
+
+func TestCollect_DrainsQueuedInOrder(t *testing.T) {
+	in := make(chan int, 10)
+	for i := 2; i <= 5; i++ {
+		in <- i
+	}
+
+	batch := Collect(1, in, Options{Enabled: true}, nil)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, batch)
+}
+
+func TestCollect_RespectsMaxBatch(t *testing.T) {
+	in := make(chan int, 10)
+	for i := 2; i <= 6; i++ {
+		in <- i
+	}
+
+	batch := Collect(1, in, Options{Enabled: true, MaxBatch: 3}, nil)
+	assert.Equal(t, []int{1, 2, 3}, batch)
+	assert.Len(t, in, 3)
+}
+
+func TestCollect_WaitsForWindow(t *testing.T) {
+	in := make(chan int, 10)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		in <- 2
+	}()
+
+	batch := Collect(1, in, Options{Enabled: true, Window: 200 * time.Millisecond, MaxBatch: 2}, nil)
+	assert.Equal(t, []int{1, 2}, batch)
+}
+
+func TestCollect_DoneStopsWaiting(t *testing.T) {
+	in := make(chan int)
+	done := make(chan struct{})
+	close(done)
+
+	start := time.Now()
+	batch := Collect(1, in, Options{Enabled: true, Window: time.Minute}, done)
+	assert.Equal(t, []int{1}, batch)
+	assert.Less(t, time.Since(start), time.Second)
+}
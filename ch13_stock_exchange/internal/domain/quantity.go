@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quantities are integers in a per-symbol unit: with a quantity scale of 1
+// (the default) one unit is a whole share, with 1_000_000 it is a
+// micro-share and 1_500_000 means 1.5 shares. Scales are powers of ten so
+// every quantity has an exact decimal form.
+
+// MaxQuantityScale is the finest quantity unit a symbol may use (1e-9 shares).
+const MaxQuantityScale = 1_000_000_000
+
+// ValidQuantityScale reports whether scale is a power of ten between 1 and
+// MaxQuantityScale.
+func ValidQuantityScale(scale int64) bool {
+	for s := int64(1); s <= MaxQuantityScale; s *= 10 {
+		if s == scale {
+			return true
+		}
+	}
+	return false
+}
+
+// Notional returns the value in cents of quantity units at price cents per
+// share, rounded up to the cent when the fraction does not come out even.
+// The whole and fractional shares are priced separately so large orders do
+// not overflow.
+func Notional(price, quantity, scale int64) int64 {
+	if scale <= 1 {
+		return price * quantity
+	}
+	whole := price * (quantity / scale)
+	frac := price * (quantity % scale)
+	cost := whole + frac/scale
+	if frac%scale != 0 {
+		cost++
+	}
+	return cost
+}
+
+// FormatQuantity renders quantity units as a decimal number of shares
+// without trailing zeros, e.g. 1_500_000 at scale 1_000_000 is "1.5".
+func FormatQuantity(quantity, scale int64) string {
+	if scale <= 1 {
+		return strconv.FormatInt(quantity, 10)
+	}
+	sign := ""
+	if quantity < 0 {
+		sign = "-"
+		quantity = -quantity
+	}
+	digits := len(strconv.FormatInt(scale, 10)) - 1
+	frac := strings.TrimRight(fmt.Sprintf("%0*d", digits, quantity%scale), "0")
+	if frac == "" {
+		return fmt.Sprintf("%s%d", sign, quantity/scale)
+	}
+	return fmt.Sprintf("%s%d.%s", sign, quantity/scale, frac)
+}
@@ -20,11 +20,32 @@ const (
 	OrderStatusCanceled       OrderStatus = "canceled"
 )
 
-// OrderType represents the type of order. Only limit orders for this lab.
+// OrderType represents the type of order.
 type OrderType string
 
 const (
 	OrderTypeLimit OrderType = "limit"
+	// OrderTypeMarket matches at whatever the book offers; its unfilled
+	// remainder is canceled rather than rested.
+	OrderTypeMarket OrderType = "market"
+)
+
+// TimeInForce controls how long an order may rest in the book.
+type TimeInForce string
+
+const (
+	// TimeInForceGTC rests until filled or canceled, carrying over to the next session.
+	TimeInForceGTC TimeInForce = "GTC"
+	// TimeInForceDay is canceled when its symbol's session closes.
+	TimeInForceDay TimeInForce = "DAY"
+	// TimeInForceGTD rests until its ExpiresAt time, then is canceled.
+	TimeInForceGTD TimeInForce = "GTD"
+	// TimeInForceIOC (immediate or cancel) trades what it can on arrival;
+	// the remainder is canceled rather than rested.
+	TimeInForceIOC TimeInForce = "IOC"
+	// TimeInForceFOK (fill or kill) trades its full quantity on arrival or
+	// is canceled without trading at all.
+	TimeInForceFOK TimeInForce = "FOK"
 )
 
 // Order represents a limit order in the exchange.
@@ -41,6 +62,45 @@ type Order struct {
 	UserID            string      `json:"user_id"`
 	CreatedAt         time.Time   `json:"created_at"`
 	SequenceID        uint64      `json:"sequence_id"`
+	// MinExecQty is the smallest fill this order accepts (0 = no minimum).
+	// Once the remaining quantity drops below it, the remainder may fill in full.
+	MinExecQty int64 `json:"min_exec_qty,omitempty"`
+	// Type is the order type; empty means limit.
+	Type OrderType `json:"type,omitempty"`
+	// MaxSlippageBps caps how far a market order may trade away from the best
+	// opposite price seen on arrival, in basis points (0 = no cap). A market
+	// order with a non-zero Price also treats it as a protection limit.
+	MaxSlippageBps int64 `json:"max_slippage_bps,omitempty"`
+	// TimeInForce is how long the order may rest; empty means GTC.
+	TimeInForce TimeInForce `json:"time_in_force,omitempty"`
+	// ExpiresAt is when a GTD order is canceled; nil for every other order.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// StopPrice makes the order a stop order (0 = none): it is held off the
+	// book until the symbol's last trade price reaches it (at or above for a
+	// buy, at or below for a sell), then matched as the limit or market
+	// order it describes.
+	StopPrice int64 `json:"stop_price,omitempty"`
+}
+
+// IsDayOrder reports whether the order is canceled at session close.
+func (o *Order) IsDayOrder() bool {
+	return o.TimeInForce == TimeInForceDay
+}
+
+// IsImmediate reports whether the order must trade on arrival (IOC or FOK)
+// and never rests in the book.
+func (o *Order) IsImmediate() bool {
+	return o.TimeInForce == TimeInForceIOC || o.TimeInForce == TimeInForceFOK
+}
+
+// IsStop reports whether the order waits for a trigger price before it is matched.
+func (o *Order) IsStop() bool {
+	return o.StopPrice > 0
+}
+
+// IsMarket reports whether the order is a market order.
+func (o *Order) IsMarket() bool {
+	return o.Type == OrderTypeMarket
 }
 
 // Execution represents a trade execution between two orders.
@@ -69,6 +129,33 @@ type Candlestick struct {
 	Interval  string    `json:"interval"` // e.g. "1m", "5m"
 }
 
+// SessionStats are cumulative trading statistics for one symbol since its
+// session started.
+type SessionStats struct {
+	Symbol     string    `json:"symbol"`
+	Open       int64     `json:"open"`
+	High       int64     `json:"high"`
+	Low        int64     `json:"low"`
+	Close      int64     `json:"close"`
+	Volume     int64     `json:"volume"`
+	TradeCount int64     `json:"trade_count"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// Ticker summarizes a symbol's price against its reference price. Change and
+// ChangePercent are LastPrice minus ReferencePrice, and are zero until both
+// are known.
+type Ticker struct {
+	Symbol         string  `json:"symbol"`
+	LastPrice      int64   `json:"last_price"`
+	OpenPrice      int64   `json:"open_price"`      // first trade of the current session
+	ClosePrice     int64   `json:"close_price"`     // last trade of the previous session
+	ReferencePrice int64   `json:"reference_price"` // set manually, else ClosePrice
+	Change         int64   `json:"change"`
+	ChangePercent  float64 `json:"change_percent"`
+	Volume         int64   `json:"volume"`
+}
+
 // L2OrderBook represents an aggregated L2 order book snapshot.
 type L2OrderBook struct {
 	Symbol string       `json:"symbol"`
@@ -76,6 +163,18 @@ type L2OrderBook struct {
 	Asks   []PriceLevel `json:"asks"`
 }
 
+// BBO is the top of a symbol's book: the best bid and ask, the resting
+// quantity at each, and the spread between them. A side with no orders has
+// null price and size, and the spread is null unless both sides have orders.
+type BBO struct {
+	Symbol   string `json:"symbol"`
+	BidPrice *int64 `json:"bid_price"`
+	BidSize  *int64 `json:"bid_size"`
+	AskPrice *int64 `json:"ask_price"`
+	AskSize  *int64 `json:"ask_size"`
+	Spread   *int64 `json:"spread"`
+}
+
 // PriceLevel represents an aggregated price level in the L2 order book.
 type PriceLevel struct {
 	Price    int64 `json:"price"`
@@ -88,12 +187,46 @@ type OrderAction string
 const (
 	OrderActionNew    OrderAction = "new"
 	OrderActionCancel OrderAction = "cancel"
+	// Modify changes a resting order: Order carries its ID, symbol and side
+	// with the new price and total quantity
+	OrderActionModify OrderAction = "modify"
+	// Auction control: Order carries only the symbol
+	OrderActionAuctionStart OrderAction = "auction_start"
+	OrderActionAuctionEnd   OrderAction = "auction_end"
+	// Session control: Order carries only the symbol
+	OrderActionSessionReset OrderAction = "session_reset"
+	// Shutdown barrier: Order is nil and Drain is set
+	OrderActionDrain OrderAction = "drain"
 )
 
 // OrderEvent wraps an order with its action for the sequencer pipeline.
 type OrderEvent struct {
 	Action OrderAction
 	Order  *Order
+	Drain  *DrainMarker
+	// Timestamp is when the sequencer sequenced the event; the matching
+	// engine stamps the event's executions with it, so replaying the same
+	// events reproduces the same executions. Zero means now.
+	Timestamp time.Time
+}
+
+// DrainMarker is a barrier sent down the pipeline at shutdown, behind every
+// order accepted before it. Each stage closes its channel once it has
+// processed everything queued ahead of the marker, so shutdown can stop the
+// stages one at a time without losing work in between.
+type DrainMarker struct {
+	Sequenced chan struct{} // closed by the sequencer
+	Settled   chan struct{} // closed by the order manager's execution listener
+	Published chan struct{} // closed by the market data publisher
+}
+
+// NewDrainMarker creates a marker with all stages still pending.
+func NewDrainMarker() *DrainMarker {
+	return &DrainMarker{
+		Sequenced: make(chan struct{}),
+		Settled:   make(chan struct{}),
+		Published: make(chan struct{}),
+	}
 }
 
 // ExecutionEvent wraps executions with the updated orders for downstream processing.
@@ -102,4 +235,49 @@ type ExecutionEvent struct {
 	TakerOrder *Order
 	// MakerOrders that were fully or partially filled
 	MakerOrders []*Order
+	// SessionReset names the symbol whose trading session restarts at this
+	// point in the stream; empty for ordinary events
+	SessionReset string
+	// Rejected is a new order the engine refused without touching the book,
+	// e.g. one reusing a resting order's ID; RejectReason says why.
+	// RejectReason alone is set when the engine cancels TakerOrder's
+	// remainder instead of resting it, e.g. for breaching the layering cap.
+	Rejected     *Order
+	RejectReason string
+	// Modified marks the result of a modify request: TakerOrder is the
+	// modified order, or Rejected the refused request
+	Modified bool
+	// Triggered are stop orders this event's trades activated, in the order
+	// they were matched. Their executions and makers are appended to
+	// Executions and MakerOrders.
+	Triggered []*Order
+	// Drain is the shutdown barrier; set only on the event that carries it
+	Drain *DrainMarker
+	// States holds a copy of TakerOrder, Triggered and MakerOrders as they
+	// stood when the event was produced, by order ID. The matching engine
+	// keeps updating the orders it holds, so consumers on other goroutines
+	// read an order's progress from here, not from the order itself.
+	States map[string]OrderState
+}
+
+// OrderState is a point-in-time copy of an order's progress through the book.
+type OrderState struct {
+	Status            OrderStatus
+	Price             int64
+	Quantity          int64
+	FilledQuantity    int64
+	RemainingQuantity int64
+	SequenceID        uint64
+}
+
+// StateOf copies the progress of an order.
+func StateOf(o *Order) OrderState {
+	return OrderState{
+		Status:            o.Status,
+		Price:             o.Price,
+		Quantity:          o.Quantity,
+		FilledQuantity:    o.FilledQuantity,
+		RemainingQuantity: o.RemainingQuantity,
+		SequenceID:        o.SequenceID,
+	}
 }
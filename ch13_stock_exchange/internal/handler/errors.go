@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+)
+
+// OrderErrorResponse is the body of a refused order: a human-readable
+// message and a stable code clients can branch on.
+type OrderErrorResponse struct {
+	Error string                  `json:"error"`
+	Code  ordermanager.RejectCode `json:"code"`
+	// Bounds are the prices the symbol's band accepts, for PRICE_BAND
+	Bounds *ordermanager.PriceBounds `json:"bounds,omitempty"`
+}
+
+// rejectStatus maps each rejection code to its HTTP status: malformed input
+// is 400, an unknown user 404, and a well-formed order the user's wallet or
+// limits cannot take 422. An order arriving during shutdown is 503, so the
+// client knows to retry against another instance.
+var rejectStatus = map[ordermanager.RejectCode]int{
+	ordermanager.RejectInvalidRequest:     http.StatusBadRequest,
+	ordermanager.RejectInvalidSide:        http.StatusBadRequest,
+	ordermanager.RejectInvalidOptions:     http.StatusBadRequest,
+	ordermanager.RejectOffTick:            http.StatusBadRequest,
+	ordermanager.RejectOffLot:             http.StatusBadRequest,
+	ordermanager.RejectPriceBand:          http.StatusUnprocessableEntity,
+	ordermanager.RejectUnknownUser:        http.StatusNotFound,
+	ordermanager.RejectUnknownOrder:       http.StatusNotFound,
+	ordermanager.RejectDailyLimit:         http.StatusUnprocessableEntity,
+	ordermanager.RejectInsufficientFunds:  http.StatusUnprocessableEntity,
+	ordermanager.RejectInsufficientShares: http.StatusUnprocessableEntity,
+	ordermanager.RejectNoMarketPrice:      http.StatusUnprocessableEntity,
+	ordermanager.RejectMarketClosed:       http.StatusUnprocessableEntity,
+	ordermanager.RejectShuttingDown:       http.StatusServiceUnavailable,
+	ordermanager.RejectQueueFull:          http.StatusServiceUnavailable,
+}
+
+// bindPlaceOrder parses and checks an order request body, answering the
+// request itself when it is invalid.
+func bindPlaceOrder(c *gin.Context) (PlaceOrderRequest, bool) {
+	var req PlaceOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rejectOrder(c, &ordermanager.OrderError{Code: ordermanager.RejectInvalidRequest, Message: err.Error()})
+		return req, false
+	}
+	if req.Side != domain.SideBuy && req.Side != domain.SideSell {
+		rejectOrder(c, &ordermanager.OrderError{Code: ordermanager.RejectInvalidSide, Message: "side must be 'buy' or 'sell'"})
+		return req, false
+	}
+	// Only a market order may leave out its price
+	if req.Price == 0 && req.Type != domain.OrderTypeMarket {
+		rejectOrder(c, &ordermanager.OrderError{Code: ordermanager.RejectInvalidRequest, Message: "price is required for a limit order"})
+		return req, false
+	}
+	return req, true
+}
+
+// rejectOrder answers a refused order. Errors that are not order rejections
+// are internal and their details are not exposed.
+func rejectOrder(c *gin.Context, err error) {
+	code := ordermanager.RejectCodeOf(err)
+	status, known := rejectStatus[code]
+	if !known {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to place order"})
+		return
+	}
+	resp := OrderErrorResponse{Error: err.Error(), Code: code}
+	var oe *ordermanager.OrderError
+	if errors.As(err, &oe) {
+		resp.Bounds = oe.Bounds
+	}
+	c.JSON(status, resp)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOrderTestRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	m := ordermanager.NewManager(1000, 100)
+	m.InitWallet("alice", 100_000, map[string]int64{"AAPL": 50})
+	require.NoError(t, m.SetSymbolSpec("TICK", ordermanager.SymbolSpec{TickSize: 5}))
+
+	h := NewHandler(m, nil, nil)
+	r := gin.New()
+	r.POST("/v1/order", h.PlaceOrder)
+	r.POST("/v1/order/reserve", h.ReserveOrder)
+	r.PATCH("/v1/order/:id", h.ModifyOrder)
+	return r
+}
+
+func TestPlaceOrder_RejectionCodes(t *testing.T) {
+	r := newOrderTestRouter(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   ordermanager.RejectCode
+	}{
+		{"malformed body", `{"symbol": "AAPL"`, http.StatusBadRequest, ordermanager.RejectInvalidRequest},
+		{"missing quantity", `{"symbol":"AAPL","side":"buy","price":100,"user_id":"alice"}`, http.StatusBadRequest, ordermanager.RejectInvalidRequest},
+		{"bad side", `{"symbol":"AAPL","side":"hold","price":100,"quantity":1,"user_id":"alice"}`, http.StatusBadRequest, ordermanager.RejectInvalidSide},
+		{"missing price", `{"symbol":"AAPL","side":"buy","quantity":1,"user_id":"alice"}`, http.StatusBadRequest, ordermanager.RejectInvalidRequest},
+		{"unknown order type", `{"symbol":"AAPL","side":"buy","price":100,"quantity":1,"user_id":"alice","type":"stop"}`, http.StatusBadRequest, ordermanager.RejectInvalidOptions},
+		{"slippage on a limit order", `{"symbol":"AAPL","side":"buy","price":100,"quantity":1,"user_id":"alice","max_slippage_bps":50}`, http.StatusBadRequest, ordermanager.RejectInvalidOptions},
+		{"bad time in force", `{"symbol":"AAPL","side":"buy","price":100,"quantity":1,"user_id":"alice","time_in_force":"GTX"}`, http.StatusBadRequest, ordermanager.RejectInvalidOptions},
+		{"off tick", `{"symbol":"TICK","side":"buy","price":101,"quantity":1,"user_id":"alice"}`, http.StatusBadRequest, ordermanager.RejectOffTick},
+		{"off-tick stop price", `{"symbol":"TICK","side":"buy","price":100,"quantity":1,"user_id":"alice","stop_price":101}`, http.StatusBadRequest, ordermanager.RejectOffTick},
+		{"unknown user", `{"symbol":"AAPL","side":"buy","price":100,"quantity":1,"user_id":"mallory"}`, http.StatusNotFound, ordermanager.RejectUnknownUser},
+		{"daily limit", `{"symbol":"AAPL","side":"buy","price":1,"quantity":1001,"user_id":"alice"}`, http.StatusUnprocessableEntity, ordermanager.RejectDailyLimit},
+		{"insufficient funds", `{"symbol":"AAPL","side":"buy","price":1000,"quantity":101,"user_id":"alice"}`, http.StatusUnprocessableEntity, ordermanager.RejectInsufficientFunds},
+		{"insufficient shares", `{"symbol":"AAPL","side":"sell","price":100,"quantity":51,"user_id":"alice"}`, http.StatusUnprocessableEntity, ordermanager.RejectInsufficientShares},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, path := range []string{"/v1/order", "/v1/order/reserve"} {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body)))
+
+				assert.Equal(t, tt.status, w.Code, path)
+				var resp OrderErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.code, resp.Code, path)
+				assert.NotEmpty(t, resp.Error)
+			}
+		})
+	}
+}
+
+func TestPlaceOrder_AcceptedOrderHasNoCode(t *testing.T) {
+	r := newOrderTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/order",
+		strings.NewReader(`{"symbol":"AAPL","side":"buy","price":100,"quantity":1,"user_id":"alice"}`)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), `"code"`)
+}
+
+func TestPlaceOrder_MarketBuyWithoutAsks(t *testing.T) {
+	r := newOrderTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/order",
+		strings.NewReader(`{"symbol":"AAPL","side":"buy","type":"market","quantity":1,"user_id":"alice"}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp OrderErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ordermanager.RejectNoMarketPrice, resp.Code)
+}
+
+func TestModifyOrder_RejectionCodes(t *testing.T) {
+	r := newOrderTestRouter(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   ordermanager.RejectCode
+	}{
+		{"missing quantity", `{"price":100}`, http.StatusBadRequest, ordermanager.RejectInvalidRequest},
+		{"unknown order", `{"price":100,"quantity":1}`, http.StatusNotFound, ordermanager.RejectUnknownOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/v1/order/missing", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.status, w.Code)
+			var resp OrderErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.code, resp.Code)
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +13,7 @@ import (
 	"github.com/nathanyu/stock-exchange/internal/matching"
 	"github.com/nathanyu/stock-exchange/internal/marketdata"
 	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+	"github.com/nathanyu/stock-exchange/internal/sequencer"
 )
 
 // Handler holds the HTTP handler dependencies.
@@ -17,6 +21,18 @@ type Handler struct {
 	manager   *ordermanager.Manager
 	engine    *matching.Engine
 	publisher *marketdata.Publisher
+	debug     bool
+
+	// Auction control; nil unless EnableAuctions
+	sequencer     *sequencer.Sequencer
+	auctionWindow time.Duration
+
+	// Session reset control; nil unless EnableSessionReset
+	sessionSequencer *sequencer.Sequencer
+
+	// Built-in load generator (see loadtest.go)
+	loadTest     bool
+	loadTestRuns atomic.Int64
 }
 
 // NewHandler creates a new Handler.
@@ -28,6 +44,24 @@ func NewHandler(manager *ordermanager.Manager, engine *matching.Engine, publishe
 	}
 }
 
+// EnableDebug exposes the debug endpoints on the next RegisterRoutes call.
+func (h *Handler) EnableDebug() {
+	h.debug = true
+}
+
+// EnableAuctions exposes POST /v1/admin/auction on the next RegisterRoutes
+// call. Auctions without an explicit window collect orders for defaultWindow.
+func (h *Handler) EnableAuctions(seq *sequencer.Sequencer, defaultWindow time.Duration) {
+	h.sequencer = seq
+	h.auctionWindow = defaultWindow
+}
+
+// EnableSessionReset exposes POST /v1/admin/session/reset on the next
+// RegisterRoutes call. Resets are sequenced through seq.
+func (h *Handler) EnableSessionReset(seq *sequencer.Sequencer) {
+	h.sessionSequencer = seq
+}
+
 // RegisterRoutes sets up the Gin routes.
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	r.GET("/health", h.Health)
@@ -36,11 +70,42 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	{
 		v1.POST("/order", h.PlaceOrder)
 		v1.DELETE("/order/:id", h.CancelOrder)
+		v1.PATCH("/order/:id", h.ModifyOrder)
+		v1.GET("/order/:id", h.GetOrder)
+		v1.GET("/orders", h.ListOrders)
+		v1.DELETE("/orders", h.CancelAllOrders)
+		v1.GET("/orders/expiring", h.GetExpiringOrders)
+		v1.POST("/order/reserve", h.ReserveOrder)
+		v1.POST("/order/commit", h.CommitOrder)
+		v1.POST("/order/cancel-reservation", h.CancelReservation)
 		v1.GET("/execution", h.GetExecutions)
 		v1.GET("/marketdata/orderBook/L2", h.GetL2OrderBook)
+		v1.GET("/marketdata/bbo", h.GetBBO)
 		v1.GET("/marketdata/candles", h.GetCandles)
+		v1.GET("/marketdata/session", h.GetSessionStats)
+		v1.GET("/marketdata/ticker", h.GetTicker)
+		v1.GET("/marketdata/sequence", h.GetSequenceStatus)
+		v1.GET("/marketdata/stream", h.StreamMarketData)
+		v1.POST("/symbols", h.RegisterSymbol)
 		v1.GET("/wallet/balances", h.GetBalances)
+		v1.GET("/wallet/pnl", h.GetPnL)
 		v1.POST("/wallet/init", h.InitWallet)
+		v1.GET("/admin/conservation", h.GetConservation)
+		v1.POST("/admin/reconcile", h.Reconcile)
+		v1.POST("/admin/close", h.CloseSession)
+		v1.POST("/admin/reference", h.SetReferencePrice)
+		if h.sequencer != nil {
+			v1.POST("/admin/auction", h.StartAuction)
+		}
+		if h.sessionSequencer != nil {
+			v1.POST("/admin/session/reset", h.ResetSession)
+		}
+		if h.loadTest {
+			v1.POST("/admin/loadtest", h.RunLoadTest)
+		}
+		if h.debug {
+			v1.GET("/debug/orderbook", h.GetDebugOrderBook)
+		}
 	}
 }
 
@@ -56,33 +121,129 @@ func (h *Handler) Health(c *gin.Context) {
 type PlaceOrderRequest struct {
 	Symbol   string      `json:"symbol" binding:"required"`
 	Side     domain.Side `json:"side" binding:"required"`
-	Price    int64       `json:"price" binding:"required,gt=0"`
+	Price    int64       `json:"price" binding:"gte=0"` // optional for market orders
 	Quantity int64       `json:"quantity" binding:"required,gt=0"`
 	UserID   string      `json:"user_id" binding:"required"`
+	// MinExecQty is optional: fills smaller than this are skipped
+	MinExecQty int64 `json:"min_exec_qty" binding:"gte=0"`
+	// PriceRounding is optional: reject (default), round, floor or ceil for off-tick prices
+	PriceRounding ordermanager.PriceRoundingMode `json:"price_rounding"`
+	// TimeInForce is optional: GTC (default), DAY, GTD, IOC or FOK
+	TimeInForce domain.TimeInForce `json:"time_in_force"`
+	// ExpiresAt is required for GTD orders (RFC3339)
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Type is optional: limit (default) or market
+	Type domain.OrderType `json:"type"`
+	// MaxSlippageBps is optional for market orders: how far past the best
+	// opposite price they may trade
+	MaxSlippageBps int64 `json:"max_slippage_bps" binding:"gte=0"`
+	// StopPrice is optional: the last trade price that activates a
+	// stop-limit or stop-market order
+	StopPrice int64 `json:"stop_price" binding:"gte=0"`
+}
+
+// orderOptions converts the optional request fields to order options.
+func (req PlaceOrderRequest) orderOptions() ordermanager.OrderOptions {
+	opts := ordermanager.OrderOptions{
+		MinExecQty:    req.MinExecQty,
+		PriceRounding: req.PriceRounding,
+		TimeInForce:   req.TimeInForce,
+
+		Type:           req.Type,
+		MaxSlippageBps: req.MaxSlippageBps,
+		StopPrice:      req.StopPrice,
+	}
+	if req.ExpiresAt != nil {
+		opts.ExpiresAt = *req.ExpiresAt
+	}
+	return opts
 }
 
 // PlaceOrder handles POST /v1/order.
 func (h *Handler) PlaceOrder(c *gin.Context) {
-	var req PlaceOrderRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	req, ok := bindPlaceOrder(c)
+	if !ok {
 		return
 	}
 
-	if req.Side != domain.SideBuy && req.Side != domain.SideSell {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "side must be 'buy' or 'sell'"})
+	order, err := h.manager.PlaceOrderWithOptions(req.UserID, req.Symbol, req.Side, req.Price, req.Quantity, req.orderOptions())
+	if err != nil {
+		rejectOrder(c, err)
 		return
 	}
 
-	order, err := h.manager.PlaceOrder(req.UserID, req.Symbol, req.Side, req.Price, req.Quantity)
+	c.JSON(http.StatusCreated, order)
+}
+
+// ReserveOrder handles POST /v1/order/reserve.
+// It takes the same body as POST /v1/order but only withholds funds.
+func (h *Handler) ReserveOrder(c *gin.Context) {
+	req, ok := bindPlaceOrder(c)
+	if !ok {
+		return
+	}
+
+	reservation, err := h.manager.ReserveOrder(req.UserID, req.Symbol, req.Side, req.Price, req.Quantity, req.orderOptions())
 	if err != nil {
+		rejectOrder(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// CommitOrderRequest is the request body for committing a reservation.
+type CommitOrderRequest struct {
+	Token string `json:"token" binding:"required"`
+	// Quantity is optional: commit only part of the reserved quantity
+	Quantity int64 `json:"quantity" binding:"gte=0"`
+}
+
+// CommitOrder handles POST /v1/order/commit.
+func (h *Handler) CommitOrder(c *gin.Context) {
+	var req CommitOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	order, err := h.manager.CommitReservation(req.Token, req.Quantity)
+	if err != nil {
+		status := http.StatusBadRequest
+		if ordermanager.RejectCodeOf(err) == ordermanager.RejectShuttingDown {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusCreated, order)
 }
 
+// CancelReservationRequest is the request body for releasing a reservation.
+type CancelReservationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CancelReservation handles POST /v1/order/cancel-reservation.
+func (h *Handler) CancelReservation(c *gin.Context) {
+	var req CancelReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.manager.CancelReservation(req.Token); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "released",
+		"token":  req.Token,
+	})
+}
+
 // CancelOrder handles DELETE /v1/order/:id.
 func (h *Handler) CancelOrder(c *gin.Context) {
 	orderID := c.Param("id")
@@ -96,6 +257,91 @@ func (h *Handler) CancelOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, order)
 }
 
+// GetOrder handles GET /v1/order/:id.
+func (h *Handler) GetOrder(c *gin.Context) {
+	order, err := h.manager.OrderStatus(c.Param("id"))
+	if err != nil {
+		rejectOrder(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// ListOrders handles GET /v1/orders?user_id=user1&status=open&offset=0&limit=50.
+func (h *Handler) ListOrders(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be an integer"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+
+	page, err := h.manager.ListOrders(userID, c.Query("status"), offset, limit)
+	if err != nil {
+		rejectOrder(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// CancelAllOrders handles DELETE /v1/orders?user_id=user1&symbol=AAPL.
+func (h *Handler) CancelAllOrders(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	canceled := h.manager.CancelAll(userID, c.Query("symbol"))
+	c.JSON(http.StatusOK, gin.H{"canceled": canceled})
+}
+
+// ModifyOrderRequest is the request body for modifying a resting order.
+type ModifyOrderRequest struct {
+	Price    int64 `json:"price" binding:"required,gt=0"`
+	Quantity int64 `json:"quantity" binding:"required,gt=0"` // new total quantity, including what has filled
+}
+
+// ModifyOrder handles PATCH /v1/order/:id.
+func (h *Handler) ModifyOrder(c *gin.Context) {
+	var req ModifyOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rejectOrder(c, &ordermanager.OrderError{Code: ordermanager.RejectInvalidRequest, Message: err.Error()})
+		return
+	}
+
+	order, err := h.manager.ModifyOrder(c.Param("id"), req.Price, req.Quantity)
+	if err != nil {
+		rejectOrder(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, order)
+}
+
+// GetExpiringOrders handles GET /v1/orders/expiring?within=10m.
+func (h *Handler) GetExpiringOrders(c *gin.Context) {
+	within, err := time.ParseDuration(c.Query("within"))
+	if err != nil || within <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "within must be a positive duration, e.g. 10m"})
+		return
+	}
+
+	orders := h.manager.ExpiringOrders(within)
+	if orders == nil {
+		orders = []*domain.Order{}
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
 // GetExecutions handles GET /v1/execution.
 func (h *Handler) GetExecutions(c *gin.Context) {
 	symbol := c.Query("symbol")
@@ -134,10 +380,29 @@ func (h *Handler) GetL2OrderBook(c *gin.Context) {
 		depth = 10
 	}
 
-	snapshot := h.engine.GetL2Snapshot(symbol, depth)
+	var aggregate int64
+	if aggStr := c.Query("aggregate"); aggStr != "" {
+		aggregate, err = strconv.ParseInt(aggStr, 10, 64)
+		if err != nil || aggregate <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "aggregate must be a positive integer (cents)"})
+			return
+		}
+	}
+
+	snapshot := h.engine.GetL2SnapshotAggregated(symbol, depth, aggregate)
 	c.JSON(http.StatusOK, snapshot)
 }
 
+// GetBBO handles GET /v1/marketdata/bbo?symbol=AAPL.
+func (h *Handler) GetBBO(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+	c.JSON(http.StatusOK, h.engine.GetBBO(symbol))
+}
+
 // GetCandles handles GET /v1/marketdata/candles.
 func (h *Handler) GetCandles(c *gin.Context) {
 	symbol := c.Query("symbol")
@@ -146,13 +411,24 @@ func (h *Handler) GetCandles(c *gin.Context) {
 		return
 	}
 
+	// A from/to window switches to a time-range query
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if fromStr != "" || toStr != "" {
+		h.getCandlesRange(c, symbol, fromStr, toStr)
+		return
+	}
+
 	countStr := c.DefaultQuery("count", "100")
 	count, err := strconv.Atoi(countStr)
 	if err != nil || count <= 0 {
 		count = 100
 	}
 
-	candles := h.publisher.GetCandles(symbol, count)
+	candles, err := h.publisher.GetCandles(symbol, c.Query("interval"), count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if candles == nil {
 		candles = []*domain.Candlestick{}
 	}
@@ -160,6 +436,37 @@ func (h *Handler) GetCandles(c *gin.Context) {
 	c.JSON(http.StatusOK, candles)
 }
 
+// getCandlesRange serves GET /v1/marketdata/candles with from/to. A missing
+// from starts at the oldest retained candle; a missing to means now.
+func (h *Handler) getCandlesRange(c *gin.Context, symbol, fromStr, toStr string) {
+	var from time.Time
+	to := time.Now()
+	if fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from format, use RFC3339"})
+			return
+		}
+		from = parsed
+	}
+	if toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to format, use RFC3339"})
+			return
+		}
+		to = parsed
+	}
+
+	result, err := h.publisher.GetCandlesRange(symbol, c.Query("interval"), from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // InitWalletRequest is the request body for initializing a wallet.
 type InitWalletRequest struct {
 	UserID      string           `json:"user_id" binding:"required"`
@@ -187,6 +494,16 @@ func (h *Handler) InitWallet(c *gin.Context) {
 	})
 }
 
+// displayHoldings renders holdings, kept in each symbol's quantity units,
+// as decimal share counts such as "1.5".
+func (h *Handler) displayHoldings(holdings map[string]int64) map[string]string {
+	display := make(map[string]string, len(holdings))
+	for symbol, quantity := range holdings {
+		display[symbol] = h.manager.FormatQuantity(symbol, quantity)
+	}
+	return display
+}
+
 // GetBalances handles GET /v1/wallet/balances.
 func (h *Handler) GetBalances(c *gin.Context) {
 	userID := c.Query("user_id")
@@ -197,9 +514,10 @@ func (h *Handler) GetBalances(c *gin.Context) {
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{
-			"user_id":      userID,
-			"cash_balance": wallet.CashBalance,
-			"holdings":     wallet.Holdings,
+			"user_id":          userID,
+			"cash_balance":     wallet.CashBalance,
+			"holdings":         wallet.Holdings,
+			"holdings_display": h.displayHoldings(wallet.Holdings),
 		})
 		return
 	}
@@ -208,10 +526,202 @@ func (h *Handler) GetBalances(c *gin.Context) {
 	result := make([]gin.H, 0, len(wallets))
 	for uid, w := range wallets {
 		result = append(result, gin.H{
-			"user_id":      uid,
-			"cash_balance": w.CashBalance,
-			"holdings":     w.Holdings,
+			"user_id":          uid,
+			"cash_balance":     w.CashBalance,
+			"holdings":         w.Holdings,
+			"holdings_display": h.displayHoldings(w.Holdings),
 		})
 	}
 	c.JSON(http.StatusOK, result)
 }
+
+// GetPnL handles GET /v1/wallet/pnl?user_id=user1.
+func (h *Handler) GetPnL(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	report, err := h.manager.GetPnL(userID)
+	if err != nil {
+		rejectOrder(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetConservation handles GET /v1/admin/conservation.
+func (h *Handler) GetConservation(c *gin.Context) {
+	report, err := h.manager.VerifyConservation()
+	resp := gin.H{"report": report}
+	if err != nil {
+		resp["error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ReconcileRequest is the optional request body for wallet reconciliation.
+type ReconcileRequest struct {
+	// Correct overwrites drifted wallets; otherwise drift is only reported
+	Correct bool `json:"correct"`
+}
+
+// Reconcile handles POST /v1/admin/reconcile.
+// It replays the publisher's execution history over the seeded wallets.
+func (h *Handler) Reconcile(c *gin.Context) {
+	var req ReconcileRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.manager.ReconcileFromExecutions(h.publisher.GetExecutions("", "", time.Time{}), req.Correct)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"report": report, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// GetSessionStats handles GET /v1/marketdata/session?symbol=AAPL.
+func (h *Handler) GetSessionStats(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+	c.JSON(http.StatusOK, h.publisher.GetSessionStats(symbol))
+}
+
+// GetTicker handles GET /v1/marketdata/ticker?symbol=AAPL.
+func (h *Handler) GetTicker(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+	c.JSON(http.StatusOK, h.publisher.GetTicker(symbol))
+}
+
+// GetSequenceStatus handles GET /v1/marketdata/sequence.
+func (h *Handler) GetSequenceStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.publisher.SequenceStatus())
+}
+
+// SetReferencePrice handles POST /v1/admin/reference?symbol=AAPL&price=15000.
+func (h *Handler) SetReferencePrice(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+	price, err := strconv.ParseInt(c.Query("price"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be an integer number of cents"})
+		return
+	}
+	if err := h.publisher.SetReferencePrice(symbol, price); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.publisher.GetTicker(symbol))
+}
+
+// ResetSessionRequest is the request body for starting a new trading session.
+type ResetSessionRequest struct {
+	Symbol string `json:"symbol" binding:"required"`
+}
+
+// ResetSession handles POST /v1/admin/session/reset.
+// Only registered when session reset is enabled.
+func (h *Handler) ResetSession(c *gin.Context) {
+	var req ResetSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.sessionSequencer.ResetSession(req.Symbol); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"symbol": req.Symbol})
+}
+
+// CloseSessionRequest is the request body for closing a symbol's session.
+type CloseSessionRequest struct {
+	Symbol string `json:"symbol" binding:"required"`
+}
+
+// CloseSession handles POST /v1/admin/close.
+func (h *Handler) CloseSession(c *gin.Context) {
+	var req CloseSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	canceled := h.manager.CloseSymbol(req.Symbol)
+	ids := make([]string, len(canceled))
+	for i, order := range canceled {
+		ids[i] = order.OrderID
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"symbol":          req.Symbol,
+		"canceled_orders": ids,
+	})
+}
+
+// StartAuctionRequest is the request body for starting a call auction.
+type StartAuctionRequest struct {
+	Symbol string `json:"symbol" binding:"required"`
+	// Window is optional, e.g. "30s"; defaults to the configured window
+	Window string `json:"window"`
+}
+
+// StartAuction handles POST /v1/admin/auction.
+// Only registered when auctions are enabled.
+func (h *Handler) StartAuction(c *gin.Context) {
+	var req StartAuctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	window := h.auctionWindow
+	if req.Window != "" {
+		d, err := time.ParseDuration(req.Window)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration, e.g. 30s"})
+			return
+		}
+		window = d
+	}
+
+	if err := h.sequencer.RunAuction(req.Symbol, window); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, sequencer.ErrAuctionInProgress) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"symbol":  req.Symbol,
+		"window":  window.String(),
+		"ends_at": time.Now().Add(window).UTC().Format(time.RFC3339),
+	})
+}
+
+// GetDebugOrderBook handles GET /v1/debug/orderbook.
+// Only registered when debug mode is enabled.
+func (h *Handler) GetDebugOrderBook(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.engine.DebugSnapshot(symbol))
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/loadtest"
+)
+
+// loadTestTimeout bounds how long one order waits for the matching engine's
+// answer, and how long the pipeline gets to drain after the run.
+const loadTestTimeout = 10 * time.Second
+
+// LoadTestRequest is the optional request body for a load test run.
+type LoadTestRequest struct {
+	Orders      int    `json:"orders"`      // default 1000
+	Concurrency int    `json:"concurrency"` // default 16
+	Symbol      string `json:"symbol"`      // default LOADTEST
+	Price       int64  `json:"price"`       // default 10000
+}
+
+// EnableLoadTest exposes POST /v1/admin/loadtest on the next RegisterRoutes call.
+func (h *Handler) EnableLoadTest() {
+	h.loadTest = true
+}
+
+// RunLoadTest handles POST /v1/admin/loadtest.
+// Only registered when load testing is enabled. Each run funds a buyer and a
+// seller of its own with exactly what their orders need, and they place
+// alternating buys and sells of one unit at one price, so the orders cross
+// each other. An order's latency runs until the matching engine's answer to
+// it has been applied. Once the pipeline has drained, whatever is still open
+// is canceled, and after the cancels have drained both wallets are emptied
+// again, which leaves the exchange's totals as they were.
+func (h *Handler) RunLoadTest(c *gin.Context) {
+	req := LoadTestRequest{Orders: 1000, Concurrency: 16, Symbol: "LOADTEST", Price: 10000}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cfg := loadtest.Config{Orders: req.Orders, Concurrency: req.Concurrency}
+	if err := cfg.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Symbol == "" || req.Price <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol and a positive price are required"})
+		return
+	}
+
+	run := h.loadTestRuns.Add(1)
+	buyer := fmt.Sprintf("loadtest-%d-buyer", run)
+	seller := fmt.Sprintf("loadtest-%d-seller", run)
+	buys, sells := int64(cfg.Orders+1)/2, int64(cfg.Orders)/2
+	h.manager.InitWallet(buyer, req.Price*buys, nil)
+	h.manager.InitWallet(seller, 0, map[string]int64{req.Symbol: sells})
+
+	placed := make([]string, cfg.Orders)
+	report := loadtest.Run(cfg, func(i int) error {
+		user, side := buyer, domain.SideBuy
+		if i%2 == 1 {
+			user, side = seller, domain.SideSell
+		}
+		order, err := h.manager.PlaceOrder(user, req.Symbol, side, req.Price, 1)
+		if err != nil {
+			return err
+		}
+		placed[i] = order.OrderID
+		select {
+		case <-h.manager.Acked(order.OrderID):
+			return nil
+		case <-time.After(loadTestTimeout):
+			return fmt.Errorf("order %s not answered within %s", order.OrderID, loadTestTimeout)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), loadTestTimeout)
+	defer cancel()
+	if err := h.drainPipeline(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	// Orders already filled or canceled refuse the cancel, which is fine
+	for _, id := range placed {
+		if id != "" {
+			h.manager.CancelOrder(id)
+		}
+	}
+	if err := h.drainPipeline(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	h.manager.InitWallet(buyer, 0, nil)
+	h.manager.InitWallet(seller, 0, nil)
+
+	c.JSON(http.StatusOK, gin.H{"symbol": req.Symbol, "report": report})
+}
+
+// drainPipeline waits until every order event emitted so far has been
+// matched and settled.
+func (h *Handler) drainPipeline(ctx context.Context) error {
+	marker := domain.NewDrainMarker()
+	if err := h.manager.DrainIntake(ctx, marker); err != nil {
+		return err
+	}
+	select {
+	case <-marker.Settled:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("pipeline did not drain: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/stock-exchange/internal/loadtest"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+	"github.com/nathanyu/stock-exchange/internal/sequencer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLoadTest_PlacesAndCancelsOrders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := matching.NewEngine()
+	seq := sequencer.NewSequencer(engine, 1024)
+	m := ordermanager.NewManager(1_000_000, 1024)
+	m.InitWallet("user1", 10_000_000, map[string]int64{"AAPL": 100})
+	go func() {
+		for event := range m.OrderOut {
+			seq.OrderIn <- event
+		}
+	}()
+	go func() {
+		for event := range seq.ExecutionOut {
+			m.ExecutionIn <- event
+		}
+	}()
+	seq.Start()
+	m.Start()
+	defer seq.Stop()
+	defer m.Stop()
+	h := NewHandler(m, engine, nil)
+
+	// Off unless enabled
+	r := gin.New()
+	h.RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/loadtest", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	h.EnableLoadTest()
+	r = gin.New()
+	h.RegisterRoutes(r)
+
+	// The odd order out rests until the run cancels it
+	for _, body := range []string{`{"orders":100,"concurrency":4}`, `{"orders":101,"concurrency":4,"symbol":"MSFT"}`} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/loadtest", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Symbol string          `json:"symbol"`
+			Report loadtest.Report `json:"report"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Zero(t, resp.Report.Rejected, body)
+		assert.Positive(t, resp.Report.OrdersPerSec)
+		assert.Positive(t, resp.Report.P50)
+
+		snap := engine.GetL2Snapshot(resp.Symbol, 10)
+		assert.Empty(t, snap.Bids, body)
+		assert.Empty(t, snap.Asks, body)
+	}
+
+	// The runs' wallets are emptied again, so the totals are the seeded ones
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+	assert.Equal(t, int64(10_000_000), report.BaselineCash)
+	assert.Equal(t, map[string]int64{"AAPL": 100}, report.TotalShares)
+	assert.Zero(t, m.GetWallet("loadtest-1-buyer").CashBalance)
+	assert.Empty(t, m.GetWallet("loadtest-2-seller").Holdings)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/loadtest", strings.NewReader(`{"orders":0}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// streamWriteTimeout bounds one message write to a stream client; a client
+// that cannot take a message in that time is disconnected
+const streamWriteTimeout = 5 * time.Second
+
+// StreamMarketData handles GET /v1/marketdata/stream?symbol=AAPL.
+// It upgrades to a WebSocket and sends the symbol's book snapshot, then
+// L2 updates and trades as JSON messages until either side closes or the
+// client falls too far behind (see marketdata.Subscribe).
+func (h *Handler) StreamMarketData(c *gin.Context) {
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+
+	server := websocket.Server{
+		// Feed clients are not browsers only, so a missing Origin is accepted
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			sub := h.publisher.Subscribe(symbol)
+			defer h.publisher.Unsubscribe(sub)
+
+			// Clients only listen; reading detects when they go away
+			gone := make(chan struct{})
+			go func() {
+				io.Copy(io.Discard, ws)
+				close(gone)
+			}()
+
+			for {
+				select {
+				case msg, ok := <-sub.C:
+					if !ok {
+						return
+					}
+					ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+					if err := websocket.JSON.Send(ws, msg); err != nil {
+						return
+					}
+				case <-gone:
+					return
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
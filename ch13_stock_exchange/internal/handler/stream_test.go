@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/marketdata"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestStreamMarketData_SnapshotThenDeltas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := matching.NewEngine()
+	publisher := marketdata.NewPublisher(100)
+	publisher.SetBookSource(engine.GetL2Snapshot)
+	publisher.Start()
+	defer publisher.Stop()
+
+	send := func(order *domain.Order) {
+		publisher.ExecutionIn <- engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: order})
+	}
+	order := func(id string, side domain.Side, price, qty int64) *domain.Order {
+		return &domain.Order{OrderID: id, Symbol: "AAPL", Side: side, Price: price, Quantity: qty, RemainingQuantity: qty, Status: domain.OrderStatusNew}
+	}
+	send(order("s1", domain.SideSell, 10010, 100))
+
+	h := NewHandler(nil, engine, publisher)
+	r := gin.New()
+	r.GET("/v1/marketdata/stream", h.StreamMarketData)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/marketdata/stream?symbol=AAPL"
+	ws, err := websocket.Dial(url, "", srv.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var msg marketdata.StreamMessage
+	require.NoError(t, websocket.JSON.Receive(ws, &msg))
+	assert.Equal(t, marketdata.StreamSnapshot, msg.Type)
+	assert.Equal(t, []domain.PriceLevel{{Price: 10010, Quantity: 100}}, msg.Asks)
+
+	send(order("b1", domain.SideBuy, 10010, 30))
+
+	msg = marketdata.StreamMessage{}
+	require.NoError(t, websocket.JSON.Receive(ws, &msg))
+	assert.Equal(t, marketdata.StreamTrade, msg.Type)
+	require.Len(t, msg.Trades, 1)
+	assert.Equal(t, int64(30), msg.Trades[0].Quantity)
+
+	msg = marketdata.StreamMessage{}
+	require.NoError(t, websocket.JSON.Receive(ws, &msg))
+	assert.Equal(t, marketdata.StreamL2Update, msg.Type)
+	assert.Equal(t, []domain.PriceLevel{{Price: 10010, Quantity: 70}}, msg.Asks)
+}
+
+func TestStreamMarketData_SymbolRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(nil, nil, marketdata.NewPublisher(1))
+	r := gin.New()
+	r.GET("/v1/marketdata/stream", h.StreamMarketData)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/v1/marketdata/stream", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
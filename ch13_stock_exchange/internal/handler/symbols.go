@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+)
+
+// SymbolSpecRequest is the request body for registering a symbol's order
+// entry rules, and the response describing them.
+type SymbolSpecRequest struct {
+	Symbol       string                         `json:"symbol" binding:"required"`
+	TickSize     int64                          `json:"tick_size" binding:"gte=0"`
+	LotSize      int64                          `json:"lot_size" binding:"gte=0"`
+	RoundingMode ordermanager.PriceRoundingMode `json:"rounding_mode"`
+}
+
+// RegisterSymbol handles POST /v1/symbols. It sets the symbol's tick size,
+// lot size and rounding mode; its quantity scale and price band are kept.
+func (h *Handler) RegisterSymbol(c *gin.Context) {
+	var req SymbolSpecRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec, _ := h.manager.GetSymbolSpec(req.Symbol)
+	spec.TickSize = req.TickSize
+	spec.LotSize = req.LotSize
+	spec.RoundingMode = req.RoundingMode
+	if err := h.manager.SetSymbolSpec(req.Symbol, spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
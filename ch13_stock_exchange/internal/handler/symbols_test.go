@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterSymbol_ValidatesOrderEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := ordermanager.NewManager(1_000_000, 100)
+	m.InitWallet("alice", 10_000_000, nil)
+	require.NoError(t, m.SetSymbolSpec("MSFT", ordermanager.SymbolSpec{PriceBandBps: 500}))
+
+	h := NewHandler(m, nil, nil)
+	r := gin.New()
+	r.POST("/v1/symbols", h.RegisterSymbol)
+	r.POST("/v1/order", h.PlaceOrder)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := post("/v1/symbols", `{"symbol":"MSFT","tick_size":5,"lot_size":100}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	spec, ok := m.GetSymbolSpec("MSFT")
+	require.True(t, ok)
+	assert.Equal(t, ordermanager.SymbolSpec{TickSize: 5, LotSize: 100, PriceBandBps: 500}, spec)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   ordermanager.RejectCode
+	}{
+		{"off tick", `{"symbol":"MSFT","side":"buy","price":10003,"quantity":100,"user_id":"alice"}`, http.StatusBadRequest, ordermanager.RejectOffTick},
+		{"off lot", `{"symbol":"MSFT","side":"buy","price":10005,"quantity":150,"user_id":"alice"}`, http.StatusBadRequest, ordermanager.RejectOffLot},
+		{"on the grid", `{"symbol":"MSFT","side":"buy","price":10005,"quantity":200,"user_id":"alice"}`, http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post("/v1/order", tt.body)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			var resp OrderErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.code, resp.Code)
+		})
+	}
+
+	for _, body := range []string{
+		`{"tick_size":5}`,
+		`{"symbol":"MSFT","lot_size":-1}`,
+		`{"symbol":"MSFT","rounding_mode":"bogus"}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, post("/v1/symbols", body).Code, body)
+	}
+}
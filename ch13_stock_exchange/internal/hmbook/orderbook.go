@@ -22,7 +22,7 @@
 //
 // # 相較於 Naive 實作的關鍵優化
 //
-// Naive 做法（internal/orderbook）在每次 add 與 remove 後都呼叫 refreshBestPrice()—
+// Naive 做法（internal/orderbook 的早期版本）在每次 add 與 remove 後都呼叫 refreshBestPrice()—
 // 這是一次 O(n) 全掃描。本套件修正了這個問題：
 //
 //	addOrder    ：O(1) 比較並更新（新價格優於快取？更新。否則不動。）
@@ -39,6 +39,9 @@
 //	BestPrice         O(1) 快取              O(1) 快取             O(log n)
 //	MatchOrder/價位   O(1)                   O(n) 每個消耗價位      O(log n)
 //	GetL2Snapshot     O(n log n) 排序        O(n log n)            O(n) 中序走訪
+//
+// internal/orderbook 現已改用價格 heap（見 pricelevels.go）：新增／移除價位為
+// O(log n)，BestPrice 為 O(1)，不再有 O(n) 重掃。
 package hmbook
 
 import (
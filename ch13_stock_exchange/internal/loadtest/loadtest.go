@@ -0,0 +1,107 @@
+// Package loadtest drives a fixed number of concurrent order placements and
+// reports the throughput and latency achieved. It backs both the pipeline
+// benchmark and the optional POST /v1/admin/loadtest endpoint.
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config is a load test workload.
+type Config struct {
+	Orders      int // total orders to place
+	Concurrency int // clients placing them at once
+}
+
+// Validate checks that the workload places at least one order.
+func (c Config) Validate() error {
+	if c.Orders <= 0 {
+		return fmt.Errorf("orders must be positive, got %d", c.Orders)
+	}
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be positive, got %d", c.Concurrency)
+	}
+	return nil
+}
+
+// PlaceFunc places the i-th order of a run (0 <= i < Config.Orders).
+// It is called from Config.Concurrency goroutines at once.
+type PlaceFunc func(i int) error
+
+// Report is the outcome of a run. Latencies cover successful placements
+// only, from the start of the call to its return.
+type Report struct {
+	Orders       int           `json:"orders"`
+	Rejected     int           `json:"rejected"`
+	Concurrency  int           `json:"concurrency"`
+	Duration     time.Duration `json:"duration_ns"`
+	OrdersPerSec float64       `json:"orders_per_sec"`
+	P50          time.Duration `json:"p50_ns"`
+	P90          time.Duration `json:"p90_ns"`
+	P99          time.Duration `json:"p99_ns"`
+	Max          time.Duration `json:"max_ns"`
+}
+
+// Run places cfg.Orders orders through place from cfg.Concurrency clients
+// and measures them. Failed placements count as rejected.
+func Run(cfg Config, place PlaceFunc) Report {
+	latencies := make([]time.Duration, cfg.Orders)
+	failed := make([]bool, cfg.Orders)
+
+	var (
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+	start := time.Now()
+	for c := 0; c < cfg.Concurrency; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= cfg.Orders {
+					return
+				}
+				t := time.Now()
+				failed[i] = place(i) != nil
+				latencies[i] = time.Since(t)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := Report{Orders: cfg.Orders, Concurrency: cfg.Concurrency, Duration: elapsed}
+	ok := latencies[:0]
+	for i, l := range latencies {
+		if failed[i] {
+			report.Rejected++
+			continue
+		}
+		ok = append(ok, l)
+	}
+	if elapsed > 0 {
+		report.OrdersPerSec = float64(len(ok)) / elapsed.Seconds()
+	}
+	if len(ok) > 0 {
+		sort.Slice(ok, func(i, j int) bool { return ok[i] < ok[j] })
+		report.P50 = percentile(ok, 50)
+		report.P90 = percentile(ok, 90)
+		report.P99 = percentile(ok, 99)
+		report.Max = ok[len(ok)-1]
+	}
+	return report
+}
+
+// percentile returns the p-th percentile of sorted, nearest rank.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (len(sorted)*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,50 @@
+package loadtest
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_CountsEveryOrderOnce(t *testing.T) {
+	var calls atomic.Int64
+	seen := make([]atomic.Int32, 500)
+	report := Run(Config{Orders: 500, Concurrency: 8}, func(i int) error {
+		calls.Add(1)
+		seen[i].Add(1)
+		if i%10 == 0 {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+
+	assert.Equal(t, int64(500), calls.Load())
+	for i := range seen {
+		assert.Equal(t, int32(1), seen[i].Load(), i)
+	}
+	assert.Equal(t, 500, report.Orders)
+	assert.Equal(t, 50, report.Rejected)
+	assert.Positive(t, report.OrdersPerSec)
+	assert.LessOrEqual(t, report.P50, report.P90)
+	assert.LessOrEqual(t, report.P90, report.P99)
+	assert.LessOrEqual(t, report.P99, report.Max)
+}
+
+func TestPercentile_NearestRank(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, 7*time.Millisecond, percentile(sorted[6:7], 99))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{Orders: 1, Concurrency: 1}.Validate())
+	assert.Error(t, Config{Orders: 0, Concurrency: 1}.Validate())
+	assert.Error(t, Config{Orders: 1, Concurrency: 0}.Validate())
+}
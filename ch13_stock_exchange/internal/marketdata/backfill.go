@@ -0,0 +1,71 @@
+package marketdata
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// BackfillCandles rebuilds symbol's candles at interval from the execution
+// history (everything processed since start, or rebuilt by RebuildFromLog),
+// replacing what the series held for the symbol. Executions are replayed in
+// timestamp order, ties in the order they arrived, so the candles come out
+// the same however late or out of order the executions were processed. An
+// interval that is not kept yet is added, which is how a new interval gets
+// its history. The last candle keeps building if its interval has not ended.
+//
+// Backfilling 1m candles also rebuilds the symbol's downsampled candles
+// (see retention.go), since those are folded from 1m candles.
+func (p *Publisher) BackfillCandles(symbol string, interval time.Duration) error {
+	if interval <= 0 || interval%fineInterval != 0 {
+		return fmt.Errorf("candle interval must be a positive multiple of %v, got %v", fineInterval, interval)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	series := p.seriesAt(interval)
+	if series == nil {
+		series = newCandleSeries(interval)
+		p.series = append(p.series, series)
+		slices.SortFunc(p.series, func(a, b *candleSeries) int { return int(a.interval - b.interval) })
+	}
+
+	var history []*domain.Execution
+	for _, exec := range p.executions {
+		if exec.Symbol == symbol {
+			history = append(history, exec)
+		}
+	}
+	slices.SortStableFunc(history, func(a, b *domain.Execution) int { return a.Timestamp.Compare(b.Timestamp) })
+
+	delete(series.states, symbol)
+	delete(series.candles, symbol)
+	if series == p.fine() {
+		delete(p.coarse, symbol)
+	}
+	for _, exec := range history {
+		p.updateSeriesCandle(series, exec)
+	}
+
+	now := time.Now()
+	if state, ok := series.states[symbol]; ok && state.hasData && !now.Before(state.current.Timestamp.Add(interval)) {
+		p.closeCandle(series, symbol, state)
+	}
+	if series == p.fine() {
+		p.downsampleAged(now)
+	}
+	return nil
+}
+
+// seriesAt returns the series kept at interval, or nil. Caller must hold p.mu.
+func (p *Publisher) seriesAt(interval time.Duration) *candleSeries {
+	for _, series := range p.series {
+		if series.interval == interval {
+			return series
+		}
+	}
+	return nil
+}
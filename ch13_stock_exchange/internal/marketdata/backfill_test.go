@@ -0,0 +1,79 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillCandles_OutOfOrderMatchesLive(t *testing.T) {
+	base := time.Date(2025, 1, 15, 9, 58, 0, 0, time.UTC)
+	history := []*domain.Execution{
+		{Symbol: "AAPL", Price: 10000, Quantity: 1, Timestamp: base},
+		{Symbol: "AAPL", Price: 10050, Quantity: 2, Timestamp: base.Add(30 * time.Second)},
+		{Symbol: "AAPL", Price: 9950, Quantity: 3, Timestamp: base.Add(90 * time.Second)},
+		{Symbol: "AAPL", Price: 10100, Quantity: 4, Timestamp: base.Add(2 * time.Minute)},
+		{Symbol: "AAPL", Price: 10020, Quantity: 5, Timestamp: base.Add(4*time.Minute + 59*time.Second)},
+		{Symbol: "AAPL", Price: 9900, Quantity: 6, Timestamp: base.Add(7 * time.Minute)},
+		{Symbol: "AAPL", Price: 9920, Quantity: 7, Timestamp: base.Add(7 * time.Minute)},
+		{Symbol: "AAPL", Price: 9980, Quantity: 8, Timestamp: base.Add(16 * time.Minute)},
+	}
+
+	live := NewPublisher(100)
+	require.NoError(t, live.SetCandleIntervals([]time.Duration{5 * time.Minute, 15 * time.Minute}))
+	for _, exec := range history {
+		live.processExecutionEvent(&domain.ExecutionEvent{Executions: []*domain.Execution{exec}})
+	}
+	live.rotateCandlesticksAt(time.Now())
+
+	// The same trades arrive late and shuffled, ties keeping their order,
+	// and 15m candles are only added afterwards
+	pub := NewPublisher(100)
+	require.NoError(t, pub.SetCandleIntervals([]time.Duration{5 * time.Minute}))
+	for _, i := range []int{3, 0, 7, 5, 1, 6, 4, 2} {
+		pub.processExecutionEvent(&domain.ExecutionEvent{Executions: []*domain.Execution{history[i]}})
+	}
+	pub.processExecutionEvent(&domain.ExecutionEvent{Executions: []*domain.Execution{
+		{Symbol: "MSFT", Price: 30000, Quantity: 1, Timestamp: base},
+	}})
+	msft, err := pub.GetCandles("MSFT", "", 10)
+	require.NoError(t, err)
+
+	for _, interval := range []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute} {
+		require.NoError(t, pub.BackfillCandles("AAPL", interval))
+	}
+	assert.Equal(t, []string{"1m", "5m", "15m"}, pub.CandleIntervals())
+
+	for _, interval := range []string{"1m", "5m", "15m"} {
+		t.Run(interval, func(t *testing.T) {
+			want, err := live.GetCandles("AAPL", interval, 20)
+			require.NoError(t, err)
+			got, err := pub.GetCandles("AAPL", interval, 20)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	// Backfilling one symbol leaves the others alone
+	got, err := pub.GetCandles("MSFT", "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, msft, got)
+
+	// Running it again changes nothing
+	require.NoError(t, pub.BackfillCandles("AAPL", time.Minute))
+	again, err := pub.GetCandles("AAPL", "1m", 20)
+	require.NoError(t, err)
+	want, err := live.GetCandles("AAPL", "1m", 20)
+	require.NoError(t, err)
+	assert.Equal(t, want, again)
+}
+
+func TestBackfillCandles_RejectsInvalidInterval(t *testing.T) {
+	pub := NewPublisher(100)
+	assert.Error(t, pub.BackfillCandles("AAPL", 90*time.Second))
+	assert.Error(t, pub.BackfillCandles("AAPL", 0))
+	assert.Equal(t, []string{"1m"}, pub.CandleIntervals())
+}
@@ -0,0 +1,79 @@
+package marketdata
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// CandleRange is the result of a time-range candle query.
+type CandleRange struct {
+	Candles []*domain.Candlestick `json:"candles"`
+	// Truncated is set when the range starts before the oldest retained
+	// candle and older candles have already been evicted from the ring
+	// buffer, so the result may be missing the start of the range.
+	Truncated bool `json:"truncated"`
+}
+
+// GetCandlesRange returns a symbol's candles whose interval starts in
+// [from, to), oldest first, including the candle still being built.
+// An empty interval means the default; the downsampled interval is also
+// available when candle retention is enabled (see retention.go), unless
+// candles are kept at that interval anyway.
+func (p *Publisher) GetCandlesRange(symbol, interval string, from, to time.Time) (CandleRange, error) {
+	if !from.Before(to) {
+		return CandleRange{}, fmt.Errorf("from must be before to")
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	series, err := p.seriesFor(interval)
+	if err != nil && p.retention.Coarse != 0 && interval == intervalLabel(p.retention.Coarse) {
+		return p.coarseRange(symbol, from, to), nil
+	}
+	if err != nil {
+		return CandleRange{}, err
+	}
+
+	result := CandleRange{Candles: []*domain.Candlestick{}}
+	inRange := func(c *domain.Candlestick) bool {
+		return !c.Timestamp.Before(from) && c.Timestamp.Before(to)
+	}
+
+	if rb, exists := series.candles[symbol]; exists {
+		all := rb.GetAll()
+		for _, c := range all {
+			if inRange(c) {
+				result.Candles = append(result.Candles, c)
+			}
+		}
+		result.Truncated = rb.evicted && len(all) > 0 && from.Before(all[0].Timestamp)
+	}
+
+	if state, exists := series.states[symbol]; exists && state.hasData && inRange(state.current) {
+		result.Candles = append(result.Candles, state.current)
+	}
+
+	return result, nil
+}
+
+// coarseRange returns the downsampled candles starting in [from, to).
+// Caller must hold p.mu.
+func (p *Publisher) coarseRange(symbol string, from, to time.Time) CandleRange {
+	result := CandleRange{Candles: []*domain.Candlestick{}}
+	rb, exists := p.coarse[symbol]
+	if !exists {
+		return result
+	}
+
+	all := rb.GetAll()
+	for _, c := range all {
+		if !c.Timestamp.Before(from) && c.Timestamp.Before(to) {
+			result.Candles = append(result.Candles, c)
+		}
+	}
+	result.Truncated = rb.evicted && len(all) > 0 && from.Before(all[0].Timestamp)
+	return result
+}
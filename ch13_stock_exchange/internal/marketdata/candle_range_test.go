@@ -0,0 +1,92 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedCandles builds n one-minute candles starting at base, one trade each at
+// price 10000+i, leaving the last one as the building candle.
+func seedCandles(pub *Publisher, base time.Time, n int) {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			pub.rotateCandlesticks()
+		}
+		pub.processExecutionEvent(&domain.ExecutionEvent{
+			Executions: []*domain.Execution{
+				{Symbol: "AAPL", Price: int64(10000 + i), Quantity: 1, Timestamp: base.Add(time.Duration(i) * time.Minute)},
+			},
+		})
+	}
+}
+
+func TestGetCandlesRange_FiltersWindowInOrder(t *testing.T) {
+	pub := NewPublisher(100)
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	seedCandles(pub, base, 6) // 10:00 .. 10:05, 10:05 still building
+
+	// Other symbols don't leak in
+	pub.processExecutionEvent(&domain.ExecutionEvent{
+		Executions: []*domain.Execution{{Symbol: "GOOG", Price: 20000, Quantity: 1, Timestamp: base.Add(2 * time.Minute)}},
+	})
+
+	result, err := pub.GetCandlesRange("AAPL", "1m", base.Add(time.Minute), base.Add(4*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, result.Truncated)
+	require.Len(t, result.Candles, 3)
+	for i, c := range result.Candles {
+		assert.Equal(t, base.Add(time.Duration(i+1)*time.Minute), c.Timestamp, "candle %d", i)
+		assert.Equal(t, int64(10001+i), c.Open)
+	}
+
+	// The building candle is included when it falls in the window
+	result, err = pub.GetCandlesRange("AAPL", "", base.Add(4*time.Minute), base.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, result.Candles, 2)
+	assert.Equal(t, int64(10005), result.Candles[1].Open)
+
+	// A range before any trading is empty but not truncated: nothing was evicted
+	result, err = pub.GetCandlesRange("AAPL", "1m", base.Add(-time.Hour), base)
+	require.NoError(t, err)
+	assert.Empty(t, result.Candles)
+	assert.False(t, result.Truncated)
+}
+
+func TestGetCandlesRange_FlagsEvictedHistory(t *testing.T) {
+	pub := NewPublisher(100)
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	seedCandles(pub, base, ringBufferCapacity+11) // 10 oldest completed candles evicted
+
+	oldest := base.Add(10 * time.Minute)
+
+	result, err := pub.GetCandlesRange("AAPL", "1m", base, oldest.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	require.Len(t, result.Candles, 3, "returns what is still retained")
+	assert.Equal(t, oldest, result.Candles[0].Timestamp)
+
+	result, err = pub.GetCandlesRange("AAPL", "1m", oldest, oldest.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, result.Truncated)
+	assert.Len(t, result.Candles, 3)
+}
+
+func TestGetCandlesRange_Validation(t *testing.T) {
+	pub := NewPublisher(100)
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	_, err := pub.GetCandlesRange("AAPL", "5m", base, base.Add(time.Hour))
+	assert.Error(t, err)
+
+	_, err = pub.GetCandlesRange("AAPL", "1m", base, base)
+	assert.Error(t, err)
+
+	result, err := pub.GetCandlesRange("UNKNOWN", "1m", base, base.Add(time.Hour))
+	require.NoError(t, err)
+	assert.NotNil(t, result.Candles)
+	assert.Empty(t, result.Candles)
+}
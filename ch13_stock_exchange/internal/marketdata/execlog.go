@@ -0,0 +1,121 @@
+package marketdata
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// execLogMaxLine bounds one JSON-encoded execution in the log
+const execLogMaxLine = 1 << 20
+
+// EnableExecutionLog appends every execution the publisher processes to path,
+// one JSON object per line, so RebuildFromLog can restore market data after a
+// restart. Must be called before Start; the log is closed by Stop.
+func (p *Publisher) EnableExecutionLog(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open execution log: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.execLog != nil {
+		p.execLog.Close()
+	}
+	p.execLog = f
+	p.execLogBuf = bufio.NewWriter(f)
+	return nil
+}
+
+// appendExecutionLog writes a batch of executions to the log and flushes it.
+// Write failures are logged; market data keeps updating in memory.
+// Caller must hold p.mu.
+func (p *Publisher) appendExecutionLog(execs []*domain.Execution) {
+	if p.execLog == nil || len(execs) == 0 {
+		return
+	}
+	enc := json.NewEncoder(p.execLogBuf)
+	for _, exec := range execs {
+		if err := enc.Encode(exec); err != nil {
+			log.Printf("[marketdata] ERROR: execution log write failed: %v", err)
+			return
+		}
+	}
+	if err := p.execLogBuf.Flush(); err != nil {
+		log.Printf("[marketdata] ERROR: execution log flush failed: %v", err)
+	}
+}
+
+// closeExecutionLog flushes and closes the log. Caller must hold p.mu.
+func (p *Publisher) closeExecutionLog() {
+	if p.execLog == nil {
+		return
+	}
+	if err := p.execLogBuf.Flush(); err != nil {
+		log.Printf("[marketdata] ERROR: execution log flush failed: %v", err)
+	}
+	p.execLog.Close()
+	p.execLog = nil
+	p.execLogBuf = nil
+}
+
+// RebuildFromLog replaces the publisher's candles and execution history with
+// the state reconstructed from the execution log at path. Executions are
+// replayed in log order; a candle is closed whenever an execution's timestamp
+// falls in a later interval, and the last candle is closed too if its
+// interval has already ended. Rebuilt candles are therefore aligned to
+// interval boundaries. Candles past the retention set by SetCandleRetention
+// are downsampled as they would have been live. Must be called before Start.
+func (p *Publisher) RebuildFromLog(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open execution log: %w", err)
+	}
+	defer f.Close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, series := range p.series {
+		series.reset()
+	}
+	p.coarse = make(map[string]*RingBuffer)
+	p.executions = nil
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), execLogMaxLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var exec domain.Execution
+		if err := json.Unmarshal(scanner.Bytes(), &exec); err != nil {
+			return fmt.Errorf("execution log line %d: %w", line, err)
+		}
+		p.replayExecution(&exec)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read execution log: %w", err)
+	}
+
+	now := time.Now()
+	p.closeEnded(now)
+	p.downsampleAged(now)
+
+	log.Printf("[marketdata] rebuilt market data from %d executions", len(p.executions))
+	return nil
+}
+
+// replayExecution applies one logged execution. Caller must hold p.mu.
+func (p *Publisher) replayExecution(exec *domain.Execution) {
+	p.executions = append(p.executions, exec)
+	p.updateCandle(exec)
+}
@@ -0,0 +1,81 @@
+package marketdata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebuildFromLog_RestoresCandlesAndExecutions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "executions.log")
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	original := NewPublisher(100)
+	require.NoError(t, original.EnableExecutionLog(path))
+
+	// Three one-minute intervals with several trades each, rotated at each
+	// boundary as the live ticker would
+	prices := [][]int64{{10000, 10200, 9900}, {10100, 10050}, {9800, 10300, 10000, 10150}}
+	for i, interval := range prices {
+		if i > 0 {
+			original.rotateCandlesticks()
+		}
+		for j, price := range interval {
+			original.processExecutionEvent(&domain.ExecutionEvent{
+				Executions: []*domain.Execution{
+					{
+						ExecID: "e", Symbol: "AAPL", Price: price, Quantity: int64(j + 1),
+						Timestamp: base.Add(time.Duration(i)*time.Minute + time.Duration(j)*10*time.Second),
+					},
+					{
+						ExecID: "g", Symbol: "GOOG", Price: price * 2, Quantity: 1,
+						Timestamp: base.Add(time.Duration(i)*time.Minute + time.Duration(j)*10*time.Second),
+					},
+				},
+			})
+		}
+	}
+	original.Stop()
+
+	restarted := NewPublisher(100)
+	require.NoError(t, restarted.RebuildFromLog(path))
+
+	for _, symbol := range []string{"AAPL", "GOOG"} {
+		want, err := original.GetCandles(symbol, "", 10)
+		require.NoError(t, err)
+		got, err := restarted.GetCandles(symbol, "", 10)
+		require.NoError(t, err)
+		require.Len(t, got, len(prices), symbol)
+		assert.Equal(t, want, got, symbol)
+	}
+
+	// The last interval has long ended, so it is closed rather than building
+	assert.False(t, restarted.fine().states["AAPL"].hasData)
+	assert.Len(t, restarted.fine().candles["AAPL"].GetAll(), len(prices))
+
+	wantExecs := original.GetExecutions("", "", time.Time{})
+	gotExecs := restarted.GetExecutions("", "", time.Time{})
+	require.Len(t, gotExecs, len(wantExecs))
+	for i := range wantExecs {
+		assert.True(t, wantExecs[i].Timestamp.Equal(gotExecs[i].Timestamp))
+		assert.Equal(t, wantExecs[i].Price, gotExecs[i].Price)
+		assert.Equal(t, wantExecs[i].Symbol, gotExecs[i].Symbol)
+	}
+}
+
+func TestRebuildFromLog_Errors(t *testing.T) {
+	pub := NewPublisher(100)
+	err := pub.RebuildFromLog(filepath.Join(t.TempDir(), "missing.log"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	path := filepath.Join(t.TempDir(), "corrupt.log")
+	require.NoError(t, os.WriteFile(path, []byte("{\"symbol\":\"AAPL\"}\nnot json\n"), 0o644))
+	err = pub.RebuildFromLog(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
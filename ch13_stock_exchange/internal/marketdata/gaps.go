@@ -0,0 +1,63 @@
+package marketdata
+
+import (
+	"log"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// Sequence gap detection.
+//
+// The sequencer numbers executions consecutively across all symbols, so an
+// execution whose sequence ID skips ahead of the last one seen means the
+// ones in between never reached the publisher, usually because best-effort
+// delivery shed them. Each gap is logged and counted. The last contiguous
+// sequence ID stops advancing at the first gap: market data past it is
+// missing trades, and a consumer that has seen executions beyond it needs
+// to resync from another source.
+//
+// Tracking starts with the first execution received live; executions
+// rebuilt from the execution log are not checked.
+
+// SequenceStatus reports how far the publisher's execution stream is known
+// to be complete.
+type SequenceStatus struct {
+	// LastSeq is the highest execution sequence ID received.
+	LastSeq uint64 `json:"last_sequence"`
+	// LastContiguousSeq is the highest sequence ID up to which no execution
+	// has been missed since tracking started.
+	LastContiguousSeq uint64 `json:"last_contiguous_sequence"`
+	// Gaps counts the gaps seen since tracking started.
+	Gaps uint64 `json:"gaps"`
+}
+
+// SequenceStatus returns the publisher's execution sequence status.
+func (p *Publisher) SequenceStatus() SequenceStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.seqStatus
+}
+
+// observeSequence checks an execution's sequence ID against the last one
+// seen. Executions without one are ignored. Caller must hold p.mu.
+func (p *Publisher) observeSequence(exec *domain.Execution) {
+	seq := exec.SequenceID
+	status := &p.seqStatus
+	switch {
+	case seq == 0:
+		return
+	case status.LastSeq == 0:
+		status.LastContiguousSeq = seq
+	case seq <= status.LastSeq:
+		log.Printf("[marketdata] WARN: execution %s has sequence %d, not after %d", exec.ExecID, seq, status.LastSeq)
+		return
+	case seq > status.LastSeq+1:
+		status.Gaps++
+		middleware.MarketDataSequenceGaps.Inc()
+		log.Printf("[marketdata] WARN: sequence gap: missed executions %d-%d", status.LastSeq+1, seq-1)
+	case status.LastContiguousSeq == status.LastSeq:
+		status.LastContiguousSeq = seq
+	}
+	status.LastSeq = seq
+}
@@ -0,0 +1,41 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func sequencedExecs(seqs ...uint64) []*domain.Execution {
+	now := time.Now()
+	execs := make([]*domain.Execution, len(seqs))
+	for i, seq := range seqs {
+		execs[i] = &domain.Execution{Symbol: "AAPL", Price: 10010, Quantity: 10, Timestamp: now, SequenceID: seq}
+	}
+	return execs
+}
+
+func TestPublisher_DetectsSequenceGaps(t *testing.T) {
+	pub := NewPublisher(100)
+	gapsBefore := testutil.ToFloat64(middleware.MarketDataSequenceGaps)
+
+	// Tracking starts wherever the live stream does
+	pub.processExecutionEvent(&domain.ExecutionEvent{Executions: sequencedExecs(41, 42)})
+	pub.processExecutionEvent(&domain.ExecutionEvent{Executions: sequencedExecs(43)})
+	assert.Equal(t, SequenceStatus{LastSeq: 43, LastContiguousSeq: 43}, pub.SequenceStatus())
+	assert.Equal(t, gapsBefore, testutil.ToFloat64(middleware.MarketDataSequenceGaps))
+
+	// 44 and 45 were lost; the contiguous sequence stays behind the gap
+	pub.processExecutionEvent(&domain.ExecutionEvent{Executions: sequencedExecs(46, 47)})
+	assert.Equal(t, SequenceStatus{LastSeq: 47, LastContiguousSeq: 43, Gaps: 1}, pub.SequenceStatus())
+	assert.Equal(t, gapsBefore+1, testutil.ToFloat64(middleware.MarketDataSequenceGaps))
+
+	// Stale and unsequenced executions are not gaps
+	pub.processExecutionEvent(&domain.ExecutionEvent{Executions: sequencedExecs(45, 0, 48)})
+	assert.Equal(t, SequenceStatus{LastSeq: 48, LastContiguousSeq: 43, Gaps: 1}, pub.SequenceStatus())
+	assert.Equal(t, gapsBefore+1, testutil.ToFloat64(middleware.MarketDataSequenceGaps))
+}
@@ -0,0 +1,99 @@
+package marketdata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Candle intervals.
+//
+// Candles are built in parallel series, one per interval: 1m always, plus
+// any set with SetCandleIntervals (e.g. 5m and 1h). Every execution updates
+// the building candle of its symbol in each series, and each series keeps
+// its own ring buffer of completed candles. A candle closes when an
+// execution arrives in a later interval, or when the minute ticker finds
+// its interval over, so a long interval's candles are complete rather than
+// downsampled from 1m candles that may already have been evicted.
+
+// candleSeries holds every symbol's candles at one interval.
+type candleSeries struct {
+	interval time.Duration
+	label    string                  // e.g. "5m"
+	states   map[string]*candleState // building candle per symbol
+	candles  map[string]*RingBuffer  // completed candles per symbol
+}
+
+func newCandleSeries(interval time.Duration) *candleSeries {
+	return &candleSeries{
+		interval: interval,
+		label:    intervalLabel(interval),
+		states:   make(map[string]*candleState),
+		candles:  make(map[string]*RingBuffer),
+	}
+}
+
+// reset drops every candle of the series.
+func (s *candleSeries) reset() {
+	s.states = make(map[string]*candleState)
+	s.candles = make(map[string]*RingBuffer)
+}
+
+// SetCandleIntervals sets the candle intervals kept besides 1m. Each must be
+// a whole number of minutes; duplicates and 1m itself are ignored. Must be
+// called before Start.
+func (p *Publisher) SetCandleIntervals(intervals []time.Duration) error {
+	extra := make([]time.Duration, 0, len(intervals))
+	for _, d := range intervals {
+		if d <= 0 || d%fineInterval != 0 {
+			return fmt.Errorf("candle interval must be a positive multiple of %v, got %v", fineInterval, d)
+		}
+		if d != fineInterval && !slices.Contains(extra, d) {
+			extra = append(extra, d)
+		}
+	}
+	slices.Sort(extra)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	series := []*candleSeries{p.fine()}
+	for _, d := range extra {
+		series = append(series, newCandleSeries(d))
+	}
+	p.series = series
+	return nil
+}
+
+// CandleIntervals returns the labels of the kept candle intervals, shortest
+// first, e.g. ["1m", "5m", "1h"].
+func (p *Publisher) CandleIntervals() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	labels := make([]string, len(p.series))
+	for i, series := range p.series {
+		labels[i] = series.label
+	}
+	return labels
+}
+
+// fine returns the 1m series. Caller must hold p.mu.
+func (p *Publisher) fine() *candleSeries {
+	return p.series[0]
+}
+
+// seriesFor returns the series of an interval label; empty means 1m.
+// Caller must hold p.mu.
+func (p *Publisher) seriesFor(interval string) (*candleSeries, error) {
+	if interval == "" {
+		return p.fine(), nil
+	}
+	labels := make([]string, len(p.series))
+	for i, series := range p.series {
+		if series.label == interval {
+			return series, nil
+		}
+		labels[i] = series.label
+	}
+	return nil, fmt.Errorf("unsupported interval %q, candles are kept at %s", interval, strings.Join(labels, ", "))
+}
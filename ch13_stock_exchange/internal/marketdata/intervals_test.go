@@ -0,0 +1,99 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCandleIntervals_BucketByExecutionTime(t *testing.T) {
+	pub := NewPublisher(100)
+	require.NoError(t, pub.SetCandleIntervals([]time.Duration{time.Hour, 5 * time.Minute, 5 * time.Minute}))
+	assert.Equal(t, []string{"1m", "5m", "1h"}, pub.CandleIntervals())
+
+	// No rotation in between: every boundary is crossed by a trade's timestamp
+	base := time.Date(2025, 1, 15, 9, 58, 0, 0, time.UTC)
+	trades := []struct {
+		offset time.Duration
+		price  int64
+		qty    int64
+	}{
+		{0, 10000, 1},                              // 9:58
+		{30 * time.Second, 10050, 2},               // 9:58
+		{90 * time.Second, 9950, 3},                // 9:59
+		{2 * time.Minute, 10100, 4},                // 10:00, new hour
+		{4*time.Minute + 59*time.Second, 10020, 5}, // 10:02
+		{7 * time.Minute, 9900, 6},                 // 10:05
+	}
+	for _, tr := range trades {
+		pub.processExecutionEvent(&domain.ExecutionEvent{Executions: []*domain.Execution{
+			{Symbol: "AAPL", Price: tr.price, Quantity: tr.qty, Timestamp: base.Add(tr.offset)},
+		}})
+	}
+
+	tests := []struct {
+		interval string
+		want     []domain.Candlestick
+	}{
+		{"1m", []domain.Candlestick{
+			{Open: 10000, High: 10050, Low: 10000, Close: 10050, Volume: 3, Timestamp: base},
+			{Open: 9950, High: 9950, Low: 9950, Close: 9950, Volume: 3, Timestamp: base.Add(time.Minute)},
+			{Open: 10100, High: 10100, Low: 10100, Close: 10100, Volume: 4, Timestamp: base.Add(2 * time.Minute)},
+			{Open: 10020, High: 10020, Low: 10020, Close: 10020, Volume: 5, Timestamp: base.Add(4 * time.Minute)},
+			{Open: 9900, High: 9900, Low: 9900, Close: 9900, Volume: 6, Timestamp: base.Add(7 * time.Minute)},
+		}},
+		{"5m", []domain.Candlestick{
+			{Open: 10000, High: 10050, Low: 9950, Close: 9950, Volume: 6, Timestamp: base.Add(-3 * time.Minute)},
+			{Open: 10100, High: 10100, Low: 10020, Close: 10020, Volume: 9, Timestamp: base.Add(2 * time.Minute)},
+			{Open: 9900, High: 9900, Low: 9900, Close: 9900, Volume: 6, Timestamp: base.Add(7 * time.Minute)},
+		}},
+		{"1h", []domain.Candlestick{
+			{Open: 10000, High: 10050, Low: 9950, Close: 9950, Volume: 6, Timestamp: base.Add(-58 * time.Minute)},
+			{Open: 10100, High: 10100, Low: 9900, Close: 9900, Volume: 15, Timestamp: base.Add(2 * time.Minute)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			candles, err := pub.GetCandles("AAPL", tt.interval, 10)
+			require.NoError(t, err)
+			require.Len(t, candles, len(tt.want))
+			for i, want := range tt.want {
+				assertOHLCV(t, want, candles[i])
+				assert.Equal(t, want.Timestamp, candles[i].Timestamp)
+				assert.Equal(t, tt.interval, candles[i].Interval)
+			}
+		})
+	}
+}
+
+func TestCandleIntervals_RotationClosesOnlyEndedIntervals(t *testing.T) {
+	pub := NewPublisher(100)
+	require.NoError(t, pub.SetCandleIntervals([]time.Duration{5 * time.Minute}))
+	start := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	pub.processExecutionEvent(&domain.ExecutionEvent{Executions: []*domain.Execution{
+		{Symbol: "AAPL", Price: 10000, Quantity: 1, Timestamp: start.Add(30 * time.Second)},
+	}})
+
+	pub.rotateCandlesticksAt(start.Add(time.Minute))
+	assert.False(t, pub.series[0].states["AAPL"].hasData, "the 1m candle has ended")
+	assert.True(t, pub.series[1].states["AAPL"].hasData, "the 5m candle is still building")
+
+	pub.rotateCandlesticksAt(start.Add(5 * time.Minute))
+	assert.False(t, pub.series[1].states["AAPL"].hasData)
+	assert.Len(t, pub.series[1].candles["AAPL"].GetAll(), 1)
+}
+
+func TestCandleIntervals_Validation(t *testing.T) {
+	pub := NewPublisher(100)
+	assert.Error(t, pub.SetCandleIntervals([]time.Duration{90 * time.Second}))
+	assert.Error(t, pub.SetCandleIntervals([]time.Duration{0}))
+
+	_, err := pub.GetCandles("AAPL", "5m", 10)
+	assert.ErrorContains(t, err, "1m")
+	require.NoError(t, pub.SetCandleIntervals([]time.Duration{5 * time.Minute}))
+	_, err = pub.GetCandles("AAPL", "5m", 10)
+	assert.NoError(t, err)
+}
@@ -1,17 +1,17 @@
 package marketdata
 
 import (
+	"bufio"
 	"log"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/nathanyu/stock-exchange/internal/batching"
 	"github.com/nathanyu/stock-exchange/internal/domain"
 )
 
-const (
-	ringBufferCapacity = 100
-	defaultInterval    = "1m"
-)
+const ringBufferCapacity = 100
 
 // candleState tracks the current (building) candlestick for a symbol.
 type candleState struct {
@@ -22,18 +22,54 @@ type candleState struct {
 
 // RingBuffer is a fixed-size circular buffer of candlesticks.
 type RingBuffer struct {
-	data  [ringBufferCapacity]*domain.Candlestick
-	head  int // next write position
-	count int
+	data    [ringBufferCapacity]*domain.Candlestick
+	head    int // next write position
+	count   int
+	evicted bool // an older candle has been overwritten
 }
 
-// Push adds a candlestick to the ring buffer.
-func (rb *RingBuffer) Push(c *domain.Candlestick) {
+// Push adds a candlestick to the ring buffer and returns the oldest one if
+// it had to be overwritten.
+func (rb *RingBuffer) Push(c *domain.Candlestick) *domain.Candlestick {
+	old := rb.data[rb.head]
 	rb.data[rb.head] = c
 	rb.head = (rb.head + 1) % ringBufferCapacity
 	if rb.count < ringBufferCapacity {
 		rb.count++
+		return nil
+	}
+	rb.evicted = true
+	return old
+}
+
+// Oldest returns the oldest candlestick, or nil if the buffer is empty.
+func (rb *RingBuffer) Oldest() *domain.Candlestick {
+	if rb.count == 0 {
+		return nil
 	}
+	return rb.data[(rb.head-rb.count+ringBufferCapacity)%ringBufferCapacity]
+}
+
+// PopOldest removes and returns the oldest candlestick (nil if empty).
+// Like an overwrite, it marks the buffer as having evicted history.
+func (rb *RingBuffer) PopOldest() *domain.Candlestick {
+	if rb.count == 0 {
+		return nil
+	}
+	idx := (rb.head - rb.count + ringBufferCapacity) % ringBufferCapacity
+	c := rb.data[idx]
+	rb.data[idx] = nil
+	rb.count--
+	rb.evicted = true
+	return c
+}
+
+// Newest returns the most recent candlestick, or nil if the buffer is empty.
+func (rb *RingBuffer) Newest() *domain.Candlestick {
+	if rb.count == 0 {
+		return nil
+	}
+	return rb.data[(rb.head-1+ringBufferCapacity)%ringBufferCapacity]
 }
 
 // GetAll returns all candlesticks in chronological order.
@@ -73,17 +109,36 @@ func (rb *RingBuffer) GetRecent(n int) []*domain.Candlestick {
 type Publisher struct {
 	mu sync.RWMutex
 
-	// Per-symbol candlestick ring buffers (completed candles)
-	candles map[string]*RingBuffer
+	// Candle series, one per interval, the 1m series first (see intervals.go)
+	series []*candleSeries
 
-	// Per-symbol current (building) candle state
-	states map[string]*candleState
+	// Downsampled history of candles past retention (see retention.go)
+	retention CandleRetention
+	coarse    map[string]*RingBuffer
 
 	// Execution log (for querying)
 	executions []*domain.Execution
+	// Execution sequence IDs received live (see gaps.go)
+	seqStatus SequenceStatus
+
+	// Per-symbol cumulative session stats (see session.go)
+	sessions map[string]*domain.SessionStats
+	// Previous session closes and manual reference prices (see reference.go)
+	closes     map[string]int64
+	references map[string]int64
 
 	// Channel to receive execution events
 	ExecutionIn chan *domain.ExecutionEvent
+	batching    batching.Options
+
+	// Append-only execution log (see execlog.go); nil unless enabled
+	execLog    *os.File
+	execLogBuf *bufio.Writer
+
+	// Streaming subscribers and the book last sent to them (see stream.go)
+	bookSource  func(symbol string, depth int) *domain.L2OrderBook
+	subscribers map[string][]*Subscription
+	streamBooks map[string]*streamBook
 
 	done   chan struct{}
 	ticker *time.Ticker
@@ -92,13 +147,24 @@ type Publisher struct {
 // NewPublisher creates a new market data publisher.
 func NewPublisher(bufferSize int) *Publisher {
 	return &Publisher{
-		candles:     make(map[string]*RingBuffer),
-		states:      make(map[string]*candleState),
+		series:      []*candleSeries{newCandleSeries(fineInterval)},
+		coarse:      make(map[string]*RingBuffer),
+		sessions:    make(map[string]*domain.SessionStats),
+		closes:      make(map[string]int64),
+		references:  make(map[string]int64),
+		subscribers: make(map[string][]*Subscription),
+		streamBooks: make(map[string]*streamBook),
 		ExecutionIn: make(chan *domain.ExecutionEvent, bufferSize),
 		done:        make(chan struct{}),
 	}
 }
 
+// SetExecutionBatching coalesces execution events that arrive in a burst
+// into one processing pass. Must be called before Start.
+func (p *Publisher) SetExecutionBatching(opts batching.Options) {
+	p.batching = opts
+}
+
 // Start begins the publisher's application loop.
 func (p *Publisher) Start() {
 	p.ticker = time.NewTicker(1 * time.Minute)
@@ -111,6 +177,11 @@ func (p *Publisher) Stop() {
 		p.ticker.Stop()
 	}
 	close(p.done)
+
+	p.mu.Lock()
+	p.closeExecutionLog()
+	p.closeSubscribers()
+	p.mu.Unlock()
 }
 
 // run is the main application loop.
@@ -119,9 +190,10 @@ func (p *Publisher) run() {
 	for {
 		select {
 		case event := <-p.ExecutionIn:
-			p.processExecutionEvent(event)
-		case <-p.ticker.C:
-			p.rotateCandlesticks()
+			p.processExecutionEvents(batching.Collect(event, p.ExecutionIn, p.batching, p.done))
+		case now := <-p.ticker.C:
+			p.rotateCandlesticksAt(now)
+			p.applyRetention(now)
 		case <-p.done:
 			log.Println("[marketdata] publisher stopped")
 			return
@@ -131,23 +203,56 @@ func (p *Publisher) run() {
 
 // processExecutionEvent updates candlestick data from executions.
 func (p *Publisher) processExecutionEvent(event *domain.ExecutionEvent) {
+	p.processExecutionEvents([]*domain.ExecutionEvent{event})
+}
+
+// processExecutionEvents applies a batch of events in order under a single lock.
+func (p *Publisher) processExecutionEvents(events []*domain.ExecutionEvent) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	for _, exec := range event.Executions {
-		p.executions = append(p.executions, exec)
-		p.updateCandle(exec)
+	for _, event := range events {
+		if event.Drain != nil {
+			close(event.Drain.Published)
+			continue
+		}
+		if event.SessionReset != "" {
+			p.resetSession(event.SessionReset)
+		}
+		for _, exec := range event.Executions {
+			p.observeSequence(exec)
+			p.executions = append(p.executions, exec)
+			p.updateCandle(exec)
+			p.updateSession(exec)
+		}
+		p.appendExecutionLog(event.Executions)
 	}
+	p.streamEvents(events)
 }
 
-// updateCandle updates the current candlestick for a symbol based on an execution.
+// updateCandle adds an execution to the building candle of its symbol in
+// every series. An execution in a later interval than the building candle
+// closes that candle first, so candles follow execution timestamps even
+// between rotations.
 func (p *Publisher) updateCandle(exec *domain.Execution) {
-	state, exists := p.states[exec.Symbol]
+	for _, series := range p.series {
+		p.updateSeriesCandle(series, exec)
+	}
+}
+
+// updateSeriesCandle is updateCandle for one series. Caller must hold p.mu.
+func (p *Publisher) updateSeriesCandle(series *candleSeries, exec *domain.Execution) {
+	state, exists := series.states[exec.Symbol]
 	if !exists {
 		state = &candleState{
-			interval: 1 * time.Minute,
+			interval: series.interval,
 		}
-		p.states[exec.Symbol] = state
+		series.states[exec.Symbol] = state
+	}
+
+	bucket := exec.Timestamp.Truncate(state.interval)
+	if state.hasData && !bucket.Equal(state.current.Timestamp) {
+		p.closeCandle(series, exec.Symbol, state)
 	}
 
 	if !state.hasData {
@@ -159,8 +264,8 @@ func (p *Publisher) updateCandle(exec *domain.Execution) {
 			Low:       exec.Price,
 			Close:     exec.Price,
 			Volume:    exec.Quantity,
-			Timestamp: exec.Timestamp.Truncate(state.interval),
-			Interval:  defaultInterval,
+			Timestamp: bucket,
+			Interval:  series.label,
 		}
 		state.hasData = true
 		return
@@ -177,48 +282,73 @@ func (p *Publisher) updateCandle(exec *domain.Execution) {
 	c.Volume += exec.Quantity
 }
 
-// rotateCandlesticks closes the current candle and starts a new interval.
+// rotateCandlesticks closes every building candle whose interval has ended.
 func (p *Publisher) rotateCandlesticks() {
+	p.rotateCandlesticksAt(time.Now())
+}
+
+// rotateCandlesticksAt is rotateCandlesticks as of now.
+func (p *Publisher) rotateCandlesticksAt(now time.Time) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.closeEnded(now)
+}
 
-	for symbol, state := range p.states {
-		if !state.hasData {
-			continue
-		}
-
-		// Push completed candle to ring buffer
-		rb, exists := p.candles[symbol]
-		if !exists {
-			rb = &RingBuffer{}
-			p.candles[symbol] = rb
+// closeEnded closes the building candles of every series whose interval
+// ended by now, so quiet symbols' candles close without a later trade.
+// Caller must hold p.mu.
+func (p *Publisher) closeEnded(now time.Time) {
+	for _, series := range p.series {
+		for symbol, state := range series.states {
+			if state.hasData && !now.Before(state.current.Timestamp.Add(state.interval)) {
+				p.closeCandle(series, symbol, state)
+			}
 		}
-		rb.Push(state.current)
+	}
+}
 
-		// Reset state for next interval
-		state.hasData = false
-		state.current = nil
+// closeCandle pushes a symbol's building candle to the series' ring buffer
+// and resets the state for the next interval. 1m candles pushed out of the
+// ring buffer are downsampled (see retention.go). Caller must hold p.mu.
+func (p *Publisher) closeCandle(series *candleSeries, symbol string, state *candleState) {
+	rb, exists := series.candles[symbol]
+	if !exists {
+		rb = &RingBuffer{}
+		series.candles[symbol] = rb
+	}
+	if evicted := rb.Push(state.current); evicted != nil && series == p.fine() {
+		p.foldCoarse(symbol, evicted)
 	}
+
+	state.hasData = false
+	state.current = nil
 }
 
-// GetCandles returns recent candlesticks for a symbol.
-func (p *Publisher) GetCandles(symbol string, count int) []*domain.Candlestick {
+// GetCandles returns a symbol's recent candlesticks of an interval, oldest
+// first, ending with the candle still being built. An empty interval means
+// the default; see CandleIntervals for the others.
+func (p *Publisher) GetCandles(symbol, interval string, count int) ([]*domain.Candlestick, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	series, err := p.seriesFor(interval)
+	if err != nil {
+		return nil, err
+	}
+
 	var result []*domain.Candlestick
 
 	// Include completed candles from ring buffer
-	if rb, exists := p.candles[symbol]; exists {
+	if rb, exists := series.candles[symbol]; exists {
 		result = rb.GetRecent(count)
 	}
 
 	// Include current building candle if it has data
-	if state, exists := p.states[symbol]; exists && state.hasData {
+	if state, exists := series.states[symbol]; exists && state.hasData {
 		result = append(result, state.current)
 	}
 
-	return result
+	return result, nil
 }
 
 // GetExecutions returns executions matching the filter criteria.
@@ -1,9 +1,11 @@
 package marketdata
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/nathanyu/stock-exchange/internal/batching"
 	"github.com/nathanyu/stock-exchange/internal/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -80,7 +82,8 @@ func TestPublisher_CandlestickGeneration(t *testing.T) {
 
 	pub.processExecutionEvent(event)
 
-	candles := pub.GetCandles("AAPL", 10)
+	candles, err := pub.GetCandles("AAPL", "", 10)
+	require.NoError(t, err)
 	require.Len(t, candles, 1) // One building candle
 
 	c := candles[0]
@@ -112,7 +115,8 @@ func TestPublisher_CandlestickRotation(t *testing.T) {
 		},
 	})
 
-	candles := pub.GetCandles("AAPL", 10)
+	candles, err := pub.GetCandles("AAPL", "", 10)
+	require.NoError(t, err)
 	require.Len(t, candles, 2) // 1 completed + 1 building
 	assert.Equal(t, int64(10010), candles[0].Open) // Completed candle
 	assert.Equal(t, int64(10020), candles[1].Open) // Building candle
@@ -148,7 +152,8 @@ func TestPublisher_GetExecutions(t *testing.T) {
 
 func TestPublisher_GetCandles_Empty(t *testing.T) {
 	pub := NewPublisher(100)
-	candles := pub.GetCandles("AAPL", 10)
+	candles, err := pub.GetCandles("AAPL", "", 10)
+	require.NoError(t, err)
 	assert.Empty(t, candles)
 }
 
@@ -163,11 +168,48 @@ func TestPublisher_MultipleSymbols(t *testing.T) {
 		},
 	})
 
-	aapl := pub.GetCandles("AAPL", 10)
-	goog := pub.GetCandles("GOOG", 10)
+	aapl, err := pub.GetCandles("AAPL", "", 10)
+	require.NoError(t, err)
+	goog, err := pub.GetCandles("GOOG", "", 10)
+	require.NoError(t, err)
 
 	require.Len(t, aapl, 1)
 	require.Len(t, goog, 1)
 	assert.Equal(t, int64(10010), aapl[0].Open)
 	assert.Equal(t, int64(20000), goog[0].Open)
 }
+
+func TestPublisher_ExecutionBatching_PreservesOrder(t *testing.T) {
+	pub := NewPublisher(1000)
+	pub.SetExecutionBatching(batching.Options{Enabled: true, Window: time.Millisecond})
+	now := time.Now()
+
+	// Queue the burst before starting so the first pass coalesces it
+	const n = 300
+	for i := range n {
+		pub.ExecutionIn <- &domain.ExecutionEvent{
+			Executions: []*domain.Execution{
+				{ExecID: fmt.Sprintf("e%d", i), Symbol: "AAPL", Price: int64(10000 + i), Quantity: 1, Timestamp: now},
+			},
+		}
+	}
+	pub.Start()
+	defer pub.Stop()
+
+	require.Eventually(t, func() bool {
+		return len(pub.GetExecutions("AAPL", "", time.Time{})) == n
+	}, time.Second, time.Millisecond)
+
+	execs := pub.GetExecutions("AAPL", "", time.Time{})
+	require.Len(t, execs, n)
+	for i, e := range execs {
+		assert.Equal(t, fmt.Sprintf("e%d", i), e.ExecID)
+	}
+
+	pub.mu.RLock()
+	c := pub.fine().states["AAPL"].current
+	pub.mu.RUnlock()
+	assert.Equal(t, int64(10000), c.Open)
+	assert.Equal(t, int64(10000+n-1), c.Close)
+	assert.Equal(t, int64(n), c.Volume)
+}
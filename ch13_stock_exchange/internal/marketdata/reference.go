@@ -0,0 +1,100 @@
+package marketdata
+
+import (
+	"fmt"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Reference prices.
+//
+// A symbol's reference price is what percent changes and price bands are
+// measured against. By default it is the close of the previous session: the
+// last trade before the session was reset. SetReferencePrice overrides it
+// for the current session, e.g. to set an opening price for a new listing;
+// the override is dropped when the session resets and the new close takes
+// over. Like sessions, reference prices are not restored after a restart.
+//
+// Price bands (see BandPrice) follow the last trade once the session has
+// one, so a band keeps up with a market that moves during the day; before
+// the first trade they fall back to the reference price.
+
+// SetReferencePrice sets the reference price of symbol until its session resets.
+func (p *Publisher) SetReferencePrice(symbol string, price int64) error {
+	if price <= 0 {
+		return fmt.Errorf("reference price must be positive, got %d", price)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.references[symbol] = price
+	return nil
+}
+
+// ReferencePrice returns the reference price of symbol, or 0 when it has
+// neither a manual reference nor a previous close.
+func (p *Publisher) ReferencePrice(symbol string) int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.referencePrice(symbol)
+}
+
+// referencePrice is ReferencePrice for callers that hold p.mu.
+func (p *Publisher) referencePrice(symbol string) int64 {
+	if ref, ok := p.references[symbol]; ok {
+		return ref
+	}
+	return p.closes[symbol]
+}
+
+// LastPrice returns the price of symbol's last trade: this session's, else
+// the previous session's close, or 0 if it has not traded.
+func (p *Publisher) LastPrice(symbol string) int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if s, ok := p.sessions[symbol]; ok && s.TradeCount > 0 {
+		return s.Close
+	}
+	return p.closes[symbol]
+}
+
+// BandPrice returns the price symbol's price band is centered on: its last
+// trade this session, else its reference price, or 0 when it has neither.
+func (p *Publisher) BandPrice(symbol string) int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if s, ok := p.sessions[symbol]; ok && s.TradeCount > 0 {
+		return s.Close
+	}
+	return p.referencePrice(symbol)
+}
+
+// closeSession records the last trade of symbol's ending session as its
+// close and drops any manual reference. Caller must hold p.mu.
+func (p *Publisher) closeSession(symbol string) {
+	if s, ok := p.sessions[symbol]; ok && s.TradeCount > 0 {
+		p.closes[symbol] = s.Close
+	}
+	delete(p.references, symbol)
+}
+
+// GetTicker returns symbol's ticker for the current session.
+func (p *Publisher) GetTicker(symbol string) *domain.Ticker {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	t := &domain.Ticker{
+		Symbol:         symbol,
+		ClosePrice:     p.closes[symbol],
+		ReferencePrice: p.referencePrice(symbol),
+	}
+	if s, ok := p.sessions[symbol]; ok && s.TradeCount > 0 {
+		t.LastPrice = s.Close
+		t.OpenPrice = s.Open
+		t.Volume = s.Volume
+	}
+	if t.LastPrice > 0 && t.ReferencePrice > 0 {
+		t.Change = t.LastPrice - t.ReferencePrice
+		t.ChangePercent = float64(t.Change) * 100 / float64(t.ReferencePrice)
+	}
+	return t
+}
@@ -0,0 +1,100 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func trade(symbol string, price, quantity int64) *domain.ExecutionEvent {
+	return &domain.ExecutionEvent{Executions: []*domain.Execution{
+		{Symbol: symbol, Price: price, Quantity: quantity, Timestamp: time.Now()},
+	}}
+}
+
+func TestPublisher_OpenCapturedOnFirstTrade(t *testing.T) {
+	pub := NewPublisher(100)
+	assert.Equal(t, &domain.Ticker{Symbol: "AAPL"}, pub.GetTicker("AAPL"))
+
+	pub.processExecutionEvent(trade("AAPL", 15050, 10))
+	pub.processExecutionEvent(trade("AAPL", 15100, 20))
+
+	ticker := pub.GetTicker("AAPL")
+	assert.Equal(t, int64(15050), ticker.OpenPrice)
+	assert.Equal(t, int64(15100), ticker.LastPrice)
+	assert.Equal(t, int64(30), ticker.Volume)
+	// Nothing to compare against before the first close
+	assert.Zero(t, ticker.ReferencePrice)
+	assert.Zero(t, ticker.Change)
+	assert.Zero(t, ticker.ChangePercent)
+}
+
+func TestPublisher_CloseCapturedOnSessionEnd(t *testing.T) {
+	pub := NewPublisher(100)
+	pub.processExecutionEvents([]*domain.ExecutionEvent{
+		trade("AAPL", 15050, 10),
+		trade("AAPL", 15000, 10),
+		{SessionReset: "AAPL"},
+	})
+
+	ticker := pub.GetTicker("AAPL")
+	assert.Equal(t, &domain.Ticker{Symbol: "AAPL", ClosePrice: 15000, ReferencePrice: 15000}, ticker)
+	assert.Equal(t, int64(15000), pub.ReferencePrice("AAPL"))
+
+	// A session without trades keeps the last close
+	pub.processExecutionEvent(&domain.ExecutionEvent{SessionReset: "AAPL"})
+	assert.Equal(t, int64(15000), pub.GetTicker("AAPL").ClosePrice)
+
+	// The new session opens fresh against the previous close
+	pub.processExecutionEvent(trade("AAPL", 15300, 5))
+	ticker = pub.GetTicker("AAPL")
+	assert.Equal(t, int64(15300), ticker.OpenPrice)
+	assert.Equal(t, int64(15000), ticker.ClosePrice)
+	assert.Equal(t, int64(300), ticker.Change)
+	assert.InDelta(t, 2.0, ticker.ChangePercent, 1e-9)
+}
+
+func TestPublisher_ChangeMeasuredAgainstReference(t *testing.T) {
+	pub := NewPublisher(100)
+	pub.processExecutionEvents([]*domain.ExecutionEvent{
+		trade("AAPL", 10000, 1),
+		{SessionReset: "AAPL"},
+		trade("AAPL", 9000, 1),
+	})
+	assert.InDelta(t, -10.0, pub.GetTicker("AAPL").ChangePercent, 1e-9)
+
+	// A manual reference overrides the close for the session
+	require.NoError(t, pub.SetReferencePrice("AAPL", 8000))
+	ticker := pub.GetTicker("AAPL")
+	assert.Equal(t, int64(8000), ticker.ReferencePrice)
+	assert.Equal(t, int64(10000), ticker.ClosePrice)
+	assert.Equal(t, int64(1000), ticker.Change)
+	assert.InDelta(t, 12.5, ticker.ChangePercent, 1e-9)
+
+	// ...and gives way to the next close
+	pub.processExecutionEvent(&domain.ExecutionEvent{SessionReset: "AAPL"})
+	assert.Equal(t, int64(9000), pub.ReferencePrice("AAPL"))
+
+	assert.Error(t, pub.SetReferencePrice("AAPL", 0))
+	assert.Error(t, pub.SetReferencePrice("AAPL", -5))
+}
+
+func TestPublisher_BandPriceFollowsLastTrade(t *testing.T) {
+	pub := NewPublisher(100)
+	assert.Zero(t, pub.BandPrice("AAPL"))
+
+	require.NoError(t, pub.SetReferencePrice("AAPL", 15000))
+	assert.Equal(t, int64(15000), pub.BandPrice("AAPL"))
+
+	pub.processExecutionEvent(trade("AAPL", 15500, 10))
+	assert.Equal(t, int64(15500), pub.BandPrice("AAPL"))
+
+	// A new session starts from its reference, the previous close
+	pub.processExecutionEvent(&domain.ExecutionEvent{SessionReset: "AAPL"})
+	assert.Equal(t, int64(15500), pub.BandPrice("AAPL"))
+	pub.processExecutionEvent(trade("AAPL", 15200, 10))
+	assert.Equal(t, int64(15200), pub.BandPrice("AAPL"))
+}
@@ -0,0 +1,142 @@
+package marketdata
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// fineInterval is the resolution candles are built at.
+const fineInterval = time.Minute
+
+// CandleRetention keeps fine candles only for a while and folds older ones
+// into coarse candles, so long-range history survives at lower resolution
+// instead of being evicted. Fine candles pushed out of their ring buffer are
+// folded too, whatever their age.
+type CandleRetention struct {
+	// FineFor is how long 1m candles are kept before they are downsampled
+	// (0 = until evicted by the ring buffer)
+	FineFor time.Duration
+	// Coarse is the downsampled interval, e.g. time.Hour (0 disables)
+	Coarse time.Duration
+}
+
+// SetCandleRetention enables candle downsampling. Coarse must be a whole
+// number of minutes longer than one. Must be called before Start.
+func (p *Publisher) SetCandleRetention(r CandleRetention) error {
+	if r.FineFor < 0 {
+		return fmt.Errorf("fine candle retention must not be negative, got %v", r.FineFor)
+	}
+	if r.Coarse != 0 && (r.Coarse <= fineInterval || r.Coarse%fineInterval != 0) {
+		return fmt.Errorf("coarse candle interval must be a multiple of %v greater than it, got %v", fineInterval, r.Coarse)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retention = r
+	return nil
+}
+
+// CoarseInterval returns the label of downsampled candles, e.g. "1h", or ""
+// when downsampling is off.
+func (p *Publisher) CoarseInterval() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.retention.Coarse == 0 {
+		return ""
+	}
+	return intervalLabel(p.retention.Coarse)
+}
+
+// applyRetention downsamples every fine candle whose interval ended more
+// than FineFor before now.
+func (p *Publisher) applyRetention(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.downsampleAged(now)
+}
+
+// downsampleAged is applyRetention for a caller already holding p.mu.
+func (p *Publisher) downsampleAged(now time.Time) {
+	if p.retention.Coarse == 0 || p.retention.FineFor == 0 {
+		return
+	}
+	cutoff := now.Add(-p.retention.FineFor)
+	for symbol, rb := range p.fine().candles {
+		for c := rb.Oldest(); c != nil && !c.Timestamp.Add(fineInterval).After(cutoff); c = rb.Oldest() {
+			p.foldCoarse(symbol, rb.PopOldest())
+		}
+	}
+}
+
+// foldCoarse merges a fine candle into the symbol's coarse series. Fine
+// candles arrive oldest first, so a candle either extends the newest coarse
+// candle or starts the next one. Caller must hold p.mu.
+func (p *Publisher) foldCoarse(symbol string, fine *domain.Candlestick) {
+	if p.retention.Coarse == 0 {
+		return
+	}
+	rb, exists := p.coarse[symbol]
+	if !exists {
+		rb = &RingBuffer{}
+		p.coarse[symbol] = rb
+	}
+
+	bucket := fine.Timestamp.Truncate(p.retention.Coarse)
+	if last := rb.Newest(); last != nil && last.Timestamp.Equal(bucket) {
+		mergeCandle(last, fine)
+		return
+	}
+	rb.Push(&domain.Candlestick{
+		Symbol:    fine.Symbol,
+		Open:      fine.Open,
+		High:      fine.High,
+		Low:       fine.Low,
+		Close:     fine.Close,
+		Volume:    fine.Volume,
+		Timestamp: bucket,
+		Interval:  intervalLabel(p.retention.Coarse),
+	})
+}
+
+// Downsample aggregates time-ordered candles into candles of the given
+// interval: the first open, highest high, lowest low, last close and summed
+// volume of the candles each one covers.
+func Downsample(candles []*domain.Candlestick, interval time.Duration) []*domain.Candlestick {
+	var result []*domain.Candlestick
+	for _, c := range candles {
+		bucket := c.Timestamp.Truncate(interval)
+		if n := len(result); n > 0 && result[n-1].Timestamp.Equal(bucket) {
+			mergeCandle(result[n-1], c)
+			continue
+		}
+		result = append(result, &domain.Candlestick{
+			Symbol:    c.Symbol,
+			Open:      c.Open,
+			High:      c.High,
+			Low:       c.Low,
+			Close:     c.Close,
+			Volume:    c.Volume,
+			Timestamp: bucket,
+			Interval:  intervalLabel(interval),
+		})
+	}
+	return result
+}
+
+// mergeCandle extends coarse with a later candle inside its interval.
+func mergeCandle(coarse, next *domain.Candlestick) {
+	coarse.High = max(coarse.High, next.High)
+	coarse.Low = min(coarse.Low, next.Low)
+	coarse.Close = next.Close
+	coarse.Volume += next.Volume
+}
+
+// intervalLabel formats an interval the way candles are labelled: "5m", "1h".
+func intervalLabel(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
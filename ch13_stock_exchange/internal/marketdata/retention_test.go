@@ -0,0 +1,136 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tradeSeries returns two trades per minute for n minutes from base with
+// prices that move up and down.
+func tradeSeries(base time.Time, n int) []*domain.Execution {
+	var execs []*domain.Execution
+	for i := 0; i < n; i++ {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		execs = append(execs,
+			&domain.Execution{Symbol: "AAPL", Price: int64(10000 + (i*37)%101), Quantity: int64(i%5 + 1), Timestamp: ts},
+			&domain.Execution{Symbol: "AAPL", Price: int64(10000 + (i*53)%89), Quantity: 2, Timestamp: ts.Add(30 * time.Second)},
+		)
+	}
+	return execs
+}
+
+// expectCandle aggregates trades directly, as a reference for downsampling.
+func expectCandle(execs []*domain.Execution, from, to time.Time) domain.Candlestick {
+	var c domain.Candlestick
+	first := true
+	for _, e := range execs {
+		if e.Timestamp.Before(from) || !e.Timestamp.Before(to) {
+			continue
+		}
+		if first {
+			c = domain.Candlestick{Open: e.Price, High: e.Price, Low: e.Price}
+			first = false
+		}
+		c.High = max(c.High, e.Price)
+		c.Low = min(c.Low, e.Price)
+		c.Close = e.Price
+		c.Volume += e.Quantity
+	}
+	return c
+}
+
+func assertOHLCV(t *testing.T, want domain.Candlestick, got *domain.Candlestick) {
+	t.Helper()
+	assert.Equal(t, want.Open, got.Open, "open")
+	assert.Equal(t, want.High, got.High, "high")
+	assert.Equal(t, want.Low, got.Low, "low")
+	assert.Equal(t, want.Close, got.Close, "close")
+	assert.Equal(t, want.Volume, got.Volume, "volume")
+}
+
+// feed replays trades, closing a candle at each minute boundary
+func feed(pub *Publisher, execs []*domain.Execution) {
+	for i, e := range execs {
+		if i > 0 && !e.Timestamp.Truncate(time.Minute).Equal(execs[i-1].Timestamp.Truncate(time.Minute)) {
+			pub.rotateCandlesticks()
+		}
+		pub.processExecutionEvent(&domain.ExecutionEvent{Executions: []*domain.Execution{e}})
+	}
+}
+
+func TestDownsample_AggregatesFineCandles(t *testing.T) {
+	base := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	execs := tradeSeries(base, 120)
+
+	pub := NewPublisher(100)
+	feed(pub, execs[:100]) // 50 minutes, all kept at 1m
+	pub.rotateCandlesticks()
+	fine, err := pub.GetCandles("AAPL", "", 100)
+	require.NoError(t, err)
+	require.Len(t, fine, 50)
+
+	coarse := Downsample(fine, 15*time.Minute)
+	require.Len(t, coarse, 4) // 9:00, 9:15, 9:30, 9:45 (partial)
+	for i, c := range coarse {
+		from := base.Add(time.Duration(i) * 15 * time.Minute)
+		assert.Equal(t, from, c.Timestamp)
+		assert.Equal(t, "15m", c.Interval)
+		assertOHLCV(t, expectCandle(execs[:100], from, from.Add(15*time.Minute)), c)
+	}
+}
+
+func TestCandleRetention_FoldsAgedAndEvictedCandles(t *testing.T) {
+	base := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	execs := tradeSeries(base, 180) // 9:00 - 11:59
+
+	pub := NewPublisher(100)
+	require.NoError(t, pub.SetCandleRetention(CandleRetention{FineFor: 30 * time.Minute, Coarse: time.Hour}))
+	assert.Equal(t, "1h", pub.CoarseInterval())
+	feed(pub, execs)
+
+	// 180 minutes overflow the 100-candle ring: the oldest 79 were folded on eviction
+	pub.applyRetention(base.Add(180 * time.Minute))
+
+	// Fine candles ending more than 30m before 12:00 are gone
+	fine, err := pub.GetCandlesRange("AAPL", "1m", base, base.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, fine.Candles, 30) // 11:30 - 11:59, 11:59 still building
+	assert.Equal(t, base.Add(150*time.Minute), fine.Candles[0].Timestamp)
+	assert.True(t, fine.Truncated)
+
+	coarse, err := pub.GetCandlesRange("AAPL", "1h", base, base.Add(3*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, coarse.Candles, 3)
+	for i, c := range coarse.Candles {
+		from := base.Add(time.Duration(i) * time.Hour)
+		to := from.Add(time.Hour)
+		if i == 2 {
+			to = base.Add(150 * time.Minute) // 11:30 onwards is still fine-grained
+		}
+		assert.Equal(t, from, c.Timestamp)
+		assert.Equal(t, "1h", c.Interval)
+		assertOHLCV(t, expectCandle(execs, from, to), c)
+	}
+
+	// Nothing is lost across the two resolutions
+	var volume int64
+	for _, c := range append(coarse.Candles, fine.Candles...) {
+		volume += c.Volume
+	}
+	assert.Equal(t, expectCandle(execs, base, base.Add(3*time.Hour)).Volume, volume)
+}
+
+func TestCandleRetention_Validation(t *testing.T) {
+	pub := NewPublisher(10)
+	assert.Error(t, pub.SetCandleRetention(CandleRetention{Coarse: 90 * time.Second}))
+	assert.Error(t, pub.SetCandleRetention(CandleRetention{Coarse: time.Minute}))
+	assert.Error(t, pub.SetCandleRetention(CandleRetention{FineFor: -time.Hour, Coarse: time.Hour}))
+
+	// Without retention only 1m candles are served
+	_, err := pub.GetCandlesRange("AAPL", "1h", time.Now().Add(-time.Hour), time.Now())
+	assert.Error(t, err)
+}
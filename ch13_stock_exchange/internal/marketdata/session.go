@@ -0,0 +1,61 @@
+package marketdata
+
+import (
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Session statistics.
+//
+// Unlike candles, which roll over every interval, session stats accumulate
+// from the start of a symbol's trading session until it is reset. Resets
+// arrive as SessionReset markers in the execution stream (see
+// sequencer.ResetSession), so every execution counts toward exactly the
+// session it was sequenced in. Sessions are not restored from the execution
+// log: after a restart every symbol starts a fresh session.
+
+// updateSession adds an execution to its symbol's session. Caller must hold p.mu.
+func (p *Publisher) updateSession(exec *domain.Execution) {
+	s, exists := p.sessions[exec.Symbol]
+	if !exists {
+		s = &domain.SessionStats{Symbol: exec.Symbol, StartedAt: time.Now()}
+		p.sessions[exec.Symbol] = s
+	}
+
+	if s.TradeCount == 0 {
+		s.Open = exec.Price
+		s.High = exec.Price
+		s.Low = exec.Price
+	}
+	if exec.Price > s.High {
+		s.High = exec.Price
+	}
+	if exec.Price < s.Low {
+		s.Low = exec.Price
+	}
+	s.Close = exec.Price
+	s.Volume += exec.Quantity
+	s.TradeCount++
+}
+
+// resetSession closes symbol's session and starts a new, empty one.
+// Caller must hold p.mu.
+func (p *Publisher) resetSession(symbol string) {
+	p.closeSession(symbol)
+	p.sessions[symbol] = &domain.SessionStats{Symbol: symbol, StartedAt: time.Now()}
+}
+
+// GetSessionStats returns a copy of the current session stats for a symbol.
+// A symbol with no trades in its session has zero stats.
+func (p *Publisher) GetSessionStats(symbol string) *domain.SessionStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	s, exists := p.sessions[symbol]
+	if !exists {
+		return &domain.SessionStats{Symbol: symbol}
+	}
+	stats := *s
+	return &stats
+}
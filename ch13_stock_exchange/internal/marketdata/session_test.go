@@ -0,0 +1,56 @@
+package marketdata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublisher_SessionStatsAccumulateAndReset(t *testing.T) {
+	pub := NewPublisher(100)
+	now := time.Now()
+
+	pub.processExecutionEvents([]*domain.ExecutionEvent{
+		{Executions: []*domain.Execution{
+			{Symbol: "AAPL", Price: 10010, Quantity: 100, Timestamp: now},
+			{Symbol: "AAPL", Price: 10050, Quantity: 200, Timestamp: now},
+		}},
+		// Sessions keep accumulating across candle intervals
+		{Executions: []*domain.Execution{
+			{Symbol: "AAPL", Price: 9990, Quantity: 50, Timestamp: now.Add(5 * time.Minute)},
+			{Symbol: "GOOG", Price: 28000, Quantity: 10, Timestamp: now},
+		}},
+	})
+
+	s := pub.GetSessionStats("AAPL")
+	assert.Equal(t, int64(10010), s.Open)
+	assert.Equal(t, int64(10050), s.High)
+	assert.Equal(t, int64(9990), s.Low)
+	assert.Equal(t, int64(9990), s.Close)
+	assert.Equal(t, int64(350), s.Volume)
+	assert.Equal(t, int64(3), s.TradeCount)
+
+	// A reset starts an empty session
+	pub.processExecutionEvents([]*domain.ExecutionEvent{
+		{SessionReset: "AAPL"},
+	})
+	s = pub.GetSessionStats("AAPL")
+	assert.Equal(t, domain.SessionStats{Symbol: "AAPL", StartedAt: s.StartedAt}, *s)
+	assert.False(t, s.StartedAt.IsZero())
+
+	pub.processExecutionEvent(&domain.ExecutionEvent{Executions: []*domain.Execution{
+		{Symbol: "AAPL", Price: 10100, Quantity: 30, Timestamp: now},
+	}})
+	s = pub.GetSessionStats("AAPL")
+	assert.Equal(t, int64(10100), s.Open)
+	assert.Equal(t, int64(10100), s.High)
+	assert.Equal(t, int64(10100), s.Low)
+	assert.Equal(t, int64(30), s.Volume)
+	assert.Equal(t, int64(1), s.TradeCount)
+
+	// Other symbols keep their session
+	assert.Equal(t, int64(1), pub.GetSessionStats("GOOG").TradeCount)
+	assert.Equal(t, &domain.SessionStats{Symbol: "MSFT"}, pub.GetSessionStats("MSFT"))
+}
@@ -0,0 +1,258 @@
+package marketdata
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// Streaming market data.
+//
+// Subscribers receive a symbol's L2 book and trade prints as the publisher
+// processes execution events. The publisher keeps no book of its own: after
+// each batch of events it reads the touched symbols' books from the book
+// source (the matching engine) and sends each symbol's subscribers the
+// levels that differ from the book it last sent them. A subscriber that
+// applies every update to its first snapshot therefore holds the book as of
+// the last update, even when events were coalesced or dropped on the way.
+//
+// Sends never block the publisher goroutine. A subscriber whose buffer is
+// full is dropped: its channel is closed and it must resubscribe, which
+// starts over from a fresh snapshot.
+
+const (
+	// StreamDepth is how many levels per side the stream's book covers.
+	// A level moving into or out of the top StreamDepth shows up as an
+	// update like any other change.
+	StreamDepth = 20
+	// streamBuffer is how many messages a subscriber may fall behind by
+	// before it is dropped
+	streamBuffer = 256
+)
+
+// Stream message types.
+const (
+	StreamSnapshot = "snapshot"  // the full book; always the first message
+	StreamL2Update = "l2_update" // changed levels; quantity 0 removes the level
+	StreamTrade    = "trade"     // executions, in the order they happened
+)
+
+// StreamMessage is one message on a symbol's market data stream.
+type StreamMessage struct {
+	Type   string              `json:"type"`
+	Symbol string              `json:"symbol"`
+	Bids   []domain.PriceLevel `json:"bids,omitempty"`
+	Asks   []domain.PriceLevel `json:"asks,omitempty"`
+	Trades []*domain.Execution `json:"trades,omitempty"`
+}
+
+// Subscription receives one symbol's stream on C, starting with a snapshot.
+// C is closed when the subscriber is dropped for falling behind or
+// unsubscribes.
+type Subscription struct {
+	C      <-chan *StreamMessage
+	symbol string
+	ch     chan *StreamMessage
+}
+
+// streamBook is the book last sent to a symbol's subscribers.
+type streamBook struct {
+	bids map[int64]int64 // price -> quantity
+	asks map[int64]int64
+}
+
+// SetBookSource sets where the stream reads a symbol's L2 book from,
+// usually the matching engine's GetL2Snapshot. It must not call back into
+// the publisher. Without one the stream carries trades only.
+func (p *Publisher) SetBookSource(source func(symbol string, depth int) *domain.L2OrderBook) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bookSource = source
+}
+
+// Subscribe starts streaming symbol's market data. The first message is a
+// snapshot of the book; updates follow from the next event processed.
+func (p *Publisher) Subscribe(symbol string) *Subscription {
+	ch := make(chan *StreamMessage, streamBuffer)
+	sub := &Subscription{C: ch, symbol: symbol, ch: ch}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	book, ok := p.streamBooks[symbol]
+	if !ok {
+		book = p.readStreamBook(symbol)
+		p.streamBooks[symbol] = book
+	}
+	ch <- &StreamMessage{
+		Type:   StreamSnapshot,
+		Symbol: symbol,
+		Bids:   bookLevels(book.bids, true),
+		Asks:   bookLevels(book.asks, false),
+	}
+	p.subscribers[symbol] = append(p.subscribers[symbol], sub)
+	return sub
+}
+
+// Unsubscribe stops a subscription and closes its channel. Unsubscribing a
+// dropped subscription does nothing.
+func (p *Publisher) Unsubscribe(sub *Subscription) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeSubscriber(sub)
+}
+
+// removeSubscriber closes sub and forgets the symbol's book once nobody
+// subscribes to it. Caller must hold p.mu.
+func (p *Publisher) removeSubscriber(sub *Subscription) {
+	subs := p.subscribers[sub.symbol]
+	for i, s := range subs {
+		if s == sub {
+			close(sub.ch)
+			subs = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(p.subscribers, sub.symbol)
+		delete(p.streamBooks, sub.symbol)
+		return
+	}
+	p.subscribers[sub.symbol] = subs
+}
+
+// closeSubscribers drops every subscriber. Caller must hold p.mu.
+func (p *Publisher) closeSubscribers() {
+	for _, subs := range p.subscribers {
+		for _, sub := range subs {
+			close(sub.ch)
+		}
+	}
+	clear(p.subscribers)
+	clear(p.streamBooks)
+}
+
+// streamEvents sends subscribers the trades of a batch of events and then
+// the book changes of every symbol the batch touched. Caller must hold p.mu.
+func (p *Publisher) streamEvents(events []*domain.ExecutionEvent) {
+	if len(p.subscribers) == 0 {
+		return
+	}
+
+	var touched []string
+	seen := make(map[string]bool)
+	touch := func(symbol string) {
+		if _, ok := p.subscribers[symbol]; ok && !seen[symbol] {
+			seen[symbol] = true
+			touched = append(touched, symbol)
+		}
+	}
+	for _, event := range events {
+		bySymbol := make(map[string][]*domain.Execution)
+		var order []string
+		for _, exec := range event.Executions {
+			if _, ok := bySymbol[exec.Symbol]; !ok {
+				order = append(order, exec.Symbol)
+			}
+			bySymbol[exec.Symbol] = append(bySymbol[exec.Symbol], exec)
+		}
+		for _, symbol := range order {
+			touch(symbol)
+			p.broadcast(symbol, &StreamMessage{Type: StreamTrade, Symbol: symbol, Trades: bySymbol[symbol]})
+		}
+		if event.TakerOrder != nil {
+			touch(event.TakerOrder.Symbol)
+		}
+		for _, maker := range event.MakerOrders {
+			touch(maker.Symbol)
+		}
+	}
+
+	for _, symbol := range touched {
+		book, ok := p.streamBooks[symbol]
+		if !ok {
+			// Every subscriber was dropped while trades were sent
+			continue
+		}
+		next := p.readStreamBook(symbol)
+		msg := &StreamMessage{
+			Type:   StreamL2Update,
+			Symbol: symbol,
+			Bids:   diffLevels(book.bids, next.bids, true),
+			Asks:   diffLevels(book.asks, next.asks, false),
+		}
+		p.streamBooks[symbol] = next
+		if len(msg.Bids) > 0 || len(msg.Asks) > 0 {
+			p.broadcast(symbol, msg)
+		}
+	}
+}
+
+// broadcast sends msg to symbol's subscribers without blocking, dropping
+// any whose buffer is full. Caller must hold p.mu.
+func (p *Publisher) broadcast(symbol string, msg *StreamMessage) {
+	// Iterate over a copy: dropping a subscriber edits the registry
+	for _, sub := range append([]*Subscription(nil), p.subscribers[symbol]...) {
+		select {
+		case sub.ch <- msg:
+		default:
+			middleware.StreamSubscribersDropped.WithLabelValues(symbol).Inc()
+			p.removeSubscriber(sub)
+		}
+	}
+}
+
+// readStreamBook reads symbol's book from the book source. Caller must hold p.mu.
+func (p *Publisher) readStreamBook(symbol string) *streamBook {
+	book := &streamBook{bids: make(map[int64]int64), asks: make(map[int64]int64)}
+	if p.bookSource == nil {
+		return book
+	}
+	snapshot := p.bookSource(symbol, StreamDepth)
+	for _, level := range snapshot.Bids {
+		book.bids[level.Price] = level.Quantity
+	}
+	for _, level := range snapshot.Asks {
+		book.asks[level.Price] = level.Quantity
+	}
+	return book
+}
+
+// bookLevels lists one side of the book best price first.
+func bookLevels(side map[int64]int64, bids bool) []domain.PriceLevel {
+	out := make([]domain.PriceLevel, 0, len(side))
+	for price, qty := range side {
+		out = append(out, domain.PriceLevel{Price: price, Quantity: qty})
+	}
+	sortLevels(out, bids)
+	return out
+}
+
+// diffLevels lists the levels of one side that changed from prev to next, best
+// price first, with quantity 0 for levels that are gone.
+func diffLevels(prev, next map[int64]int64, bids bool) []domain.PriceLevel {
+	var out []domain.PriceLevel
+	for price, qty := range next {
+		if prev[price] != qty {
+			out = append(out, domain.PriceLevel{Price: price, Quantity: qty})
+		}
+	}
+	for price := range prev {
+		if _, ok := next[price]; !ok {
+			out = append(out, domain.PriceLevel{Price: price, Quantity: 0})
+		}
+	}
+	sortLevels(out, bids)
+	return out
+}
+
+// sortLevels orders levels best price first: highest bid, lowest ask.
+func sortLevels(levels []domain.PriceLevel, bids bool) {
+	slices.SortFunc(levels, func(a, b domain.PriceLevel) int {
+		if bids {
+			return cmp.Compare(b.Price, a.Price)
+		}
+		return cmp.Compare(a.Price, b.Price)
+	})
+}
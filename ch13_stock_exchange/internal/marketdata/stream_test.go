@@ -0,0 +1,91 @@
+package marketdata
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBook is a book source whose levels the test sets directly.
+type fakeBook struct {
+	bids, asks []domain.PriceLevel
+}
+
+func (b *fakeBook) snapshot(symbol string, depth int) *domain.L2OrderBook {
+	return &domain.L2OrderBook{Symbol: symbol, Bids: b.bids, Asks: b.asks}
+}
+
+func levels(pq ...int64) []domain.PriceLevel {
+	var out []domain.PriceLevel
+	for i := 0; i < len(pq); i += 2 {
+		out = append(out, domain.PriceLevel{Price: pq[i], Quantity: pq[i+1]})
+	}
+	return out
+}
+
+func TestStream_SnapshotThenUpdates(t *testing.T) {
+	book := &fakeBook{bids: levels(9990, 100, 9980, 50), asks: levels(10010, 100)}
+	p := NewPublisher(100)
+	p.SetBookSource(book.snapshot)
+
+	sub := p.Subscribe("AAPL")
+	snapshot := <-sub.C
+	assert.Equal(t, StreamSnapshot, snapshot.Type)
+	assert.Equal(t, levels(9990, 100, 9980, 50), snapshot.Bids)
+	assert.Equal(t, levels(10010, 100), snapshot.Asks)
+
+	// A buy takes 40 at 10010 and rests 60 at 10000; 9980 is canceled
+	book.bids = levels(10000, 60, 9990, 100)
+	book.asks = levels(10010, 60)
+	exec := &domain.Execution{Symbol: "AAPL", Price: 10010, Quantity: 40}
+	p.processExecutionEvent(&domain.ExecutionEvent{
+		Executions: []*domain.Execution{exec},
+		TakerOrder: &domain.Order{Symbol: "AAPL"},
+	})
+
+	trade := <-sub.C
+	assert.Equal(t, StreamTrade, trade.Type)
+	assert.Equal(t, []*domain.Execution{exec}, trade.Trades)
+
+	update := <-sub.C
+	assert.Equal(t, StreamL2Update, update.Type)
+	assert.Equal(t, levels(10000, 60, 9980, 0), update.Bids)
+	assert.Equal(t, levels(10010, 60), update.Asks)
+
+	// Other symbols' events and events that change nothing send nothing
+	p.processExecutionEvent(&domain.ExecutionEvent{TakerOrder: &domain.Order{Symbol: "GOOG"}})
+	p.processExecutionEvent(&domain.ExecutionEvent{TakerOrder: &domain.Order{Symbol: "AAPL"}})
+	assert.Empty(t, sub.C)
+}
+
+func TestStream_SlowSubscriberDropped(t *testing.T) {
+	p := NewPublisher(100)
+	slow := p.Subscribe("AAPL")
+	fast := p.Subscribe("AAPL")
+	<-fast.C // snapshot
+
+	exec := &domain.Execution{Symbol: "AAPL", Price: 10000, Quantity: 1}
+	for range streamBuffer {
+		p.processExecutionEvent(&domain.ExecutionEvent{Executions: []*domain.Execution{exec}})
+		<-fast.C
+	}
+
+	// The slow subscriber's channel is closed after what it had buffered:
+	// the snapshot and one less trade than the others got
+	received := 0
+	for range slow.C {
+		received++
+	}
+	assert.Equal(t, streamBuffer, received)
+	require.Len(t, p.subscribers["AAPL"], 1)
+	assert.Same(t, fast, p.subscribers["AAPL"][0])
+
+	p.Unsubscribe(fast)
+	_, open := <-fast.C
+	assert.False(t, open)
+	assert.Empty(t, p.subscribers)
+	assert.Empty(t, p.streamBooks)
+	p.Unsubscribe(slow) // already dropped
+}
@@ -0,0 +1,61 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/nathanyu/stock-exchange/internal/orderbook"
+)
+
+// Per-symbol allocation.
+//
+// The engine's book options set one allocation policy for every symbol
+// (FIFO unless configured). SetSymbolAllocation overrides it for a single
+// symbol, so e.g. a futures contract can be matched pro-rata while equities
+// stay in strict price-time priority. The override applies to the symbol's
+// book from its next match, or from its creation if it has none yet.
+//
+// Matching must stay deterministic for journal replay (see the sequencer's
+// journal.go), so overrides are configuration: set them before the engine
+// handles its first event, the same way on every start.
+
+// symbolAllocation is one symbol's allocation override.
+type symbolAllocation struct {
+	policy orderbook.AllocationPolicy
+	topBps int64
+}
+
+// SetSymbolAllocation sets how fills are shared within a price level of
+// symbol's book. topBps is the share, in basis points, guaranteed to a
+// level's first order under AllocationHybrid and is ignored otherwise.
+func (e *Engine) SetSymbolAllocation(symbol string, policy orderbook.AllocationPolicy, topBps int64) error {
+	if _, err := orderbook.ParseAllocationPolicy(string(policy)); err != nil {
+		return err
+	}
+	if policy != orderbook.AllocationHybrid {
+		topBps = 0
+	} else if topBps <= 0 || topBps > 10000 {
+		return fmt.Errorf("hybrid top allocation must be 1-10000 bps, got %d", topBps)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.allocations == nil {
+		e.allocations = make(map[string]symbolAllocation)
+	}
+	e.allocations[symbol] = symbolAllocation{policy: policy, topBps: topBps}
+	if book := e.books[symbol]; book != nil {
+		book.SetAllocation(policy, topBps)
+	}
+	return nil
+}
+
+// bookOptions returns the options symbol's book is created with.
+// Caller must hold e.mu.
+func (e *Engine) bookOptions(symbol string) orderbook.Options {
+	opts := e.bookOpts
+	if a, ok := e.allocations[symbol]; ok {
+		opts.Allocation = a.policy
+		opts.TopAllocationBps = a.topBps
+	}
+	return opts
+}
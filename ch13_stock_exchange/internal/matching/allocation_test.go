@@ -0,0 +1,53 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/orderbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makerFills rests sells of 100 and 300 for symbol at one price, takes 100
+// of them and returns what each maker received
+func makerFills(t *testing.T, e *Engine, symbol string) map[string]int64 {
+	t.Helper()
+	e.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder(symbol+"-m1", symbol, domain.SideSell, 10000, 100)})
+	e.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder(symbol+"-m2", symbol, domain.SideSell, 10000, 300)})
+	result := e.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder(symbol+"-t1", symbol, domain.SideBuy, 10000, 100)})
+
+	fills := make(map[string]int64)
+	var total int64
+	for _, exec := range result.Executions {
+		fills[exec.MakerOrderID] += exec.Quantity
+		total += exec.Quantity
+	}
+	assert.Equal(t, int64(100), total)
+	return fills
+}
+
+func TestEngine_SymbolAllocationOverridesDefault(t *testing.T) {
+	e := NewEngine()
+	require.NoError(t, e.SetSymbolAllocation("ES", orderbook.AllocationProRata, 0))
+
+	assert.Equal(t, map[string]int64{"ES-m1": 25, "ES-m2": 75}, makerFills(t, e, "ES"))
+	// Other symbols keep strict FIFO
+	assert.Equal(t, map[string]int64{"AAPL-m1": 100}, makerFills(t, e, "AAPL"))
+}
+
+func TestEngine_SymbolAllocationAppliesToExistingBook(t *testing.T) {
+	e := NewEngineWithOptions(orderbook.Options{Allocation: orderbook.AllocationProRata})
+	e.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("rest", "CL", domain.SideBuy, 9000, 10)})
+
+	require.NoError(t, e.SetSymbolAllocation("CL", orderbook.AllocationFIFO, 0))
+	assert.Equal(t, map[string]int64{"CL-m1": 100}, makerFills(t, e, "CL"))
+}
+
+func TestEngine_SetSymbolAllocationValidates(t *testing.T) {
+	e := NewEngine()
+	assert.Error(t, e.SetSymbolAllocation("ES", "random", 0))
+	assert.Error(t, e.SetSymbolAllocation("ES", orderbook.AllocationHybrid, 0))
+	assert.Error(t, e.SetSymbolAllocation("ES", orderbook.AllocationHybrid, 10001))
+	assert.NoError(t, e.SetSymbolAllocation("ES", orderbook.AllocationHybrid, 4000))
+}
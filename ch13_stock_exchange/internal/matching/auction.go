@@ -0,0 +1,69 @@
+package matching
+
+import (
+	"fmt"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Auction mode.
+//
+// Between an auction start and end event for a symbol, continuous matching is
+// suspended: limit orders rest in the book even when they cross, and market
+// orders are canceled since they carry no price to take part with. The end
+// event uncrosses the book once, at the single price that maximizes matched
+// volume (see orderbook.ClearingPrice), and the symbol returns to continuous
+// matching. Both events are routed through the sequencer like orders.
+
+// InAuction reports whether a symbol is collecting orders for an auction.
+func (e *Engine) InAuction(symbol string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.auctions[symbol]
+}
+
+// handleAuctionStart suspends continuous matching for the symbol.
+func (e *Engine) handleAuctionStart(symbol string) *domain.ExecutionEvent {
+	e.auctions[symbol] = true
+	return nil
+}
+
+// handleAuctionEnd uncrosses the symbol's book at its clearing price and
+// resumes continuous matching. The returned event has no taker order: every
+// filled order is listed in MakerOrders. seq is the end event's sequence ID,
+// which keeps execution IDs unique across auctions.
+func (e *Engine) handleAuctionEnd(symbol string, seq uint64) *domain.ExecutionEvent {
+	if !e.auctions[symbol] {
+		return nil
+	}
+	delete(e.auctions, symbol)
+
+	book := e.books[symbol]
+	if book == nil {
+		return nil
+	}
+
+	_, executions, filled := book.Uncross(fmt.Sprintf("%s-auction-%d", symbol, seq))
+	if len(executions) == 0 {
+		return nil
+	}
+
+	for _, exec := range executions {
+		exec.Timestamp = e.eventTime
+	}
+	return &domain.ExecutionEvent{
+		Executions:  executions,
+		MakerOrders: filled,
+	}
+}
+
+// collectForAuction rests a limit order without matching it. Orders that
+// cannot rest (market, IOC, FOK) have nothing to trade against and are canceled.
+func (e *Engine) collectForAuction(order *domain.Order) *domain.ExecutionEvent {
+	if order.IsMarket() || order.IsImmediate() {
+		order.Status = domain.OrderStatusCanceled
+	} else {
+		e.getOrCreateBook(order.Symbol).AddOrder(order)
+	}
+	return &domain.ExecutionEvent{TakerOrder: order}
+}
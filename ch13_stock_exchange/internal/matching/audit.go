@@ -0,0 +1,78 @@
+package matching
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// SetPriceAudit turns the execution price audit on or off.
+//
+// With the audit on, every execution is checked against its maker and taker:
+// the price must equal the maker's resting price and lie within the taker's
+// limit, or its protection price for a market order. Violations are logged and counted in
+// exchange_execution_audit_violations_total; matching itself is not altered.
+func (e *Engine) SetPriceAudit(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.audit = enabled
+}
+
+// auditExecutions checks each execution against the maker that produced it.
+// makers[i] is the maker of executions[i]. Returns the violations found.
+func (e *Engine) auditExecutions(taker *domain.Order, executions []*domain.Execution, makers []*domain.Order) []error {
+	var violations []error
+	for i, exec := range executions {
+		var maker *domain.Order
+		if i < len(makers) {
+			maker = makers[i]
+		}
+		for _, v := range checkExecution(taker, maker, exec) {
+			middleware.ExecutionAuditViolations.WithLabelValues(exec.Symbol, v.check).Inc()
+			log.Printf("[matching] AUDIT VIOLATION (%s) exec=%s: %v", v.check, exec.ExecID, v.err)
+			violations = append(violations, v.err)
+		}
+	}
+	return violations
+}
+
+type auditViolation struct {
+	check string
+	err   error
+}
+
+// checkExecution validates one execution's price against its maker and taker.
+func checkExecution(taker, maker *domain.Order, exec *domain.Execution) []auditViolation {
+	var out []auditViolation
+
+	if maker == nil || maker.OrderID != exec.MakerOrderID {
+		return append(out, auditViolation{"maker_mismatch",
+			fmt.Errorf("execution maker %s does not match resting order", exec.MakerOrderID)})
+	}
+
+	if exec.Price != maker.Price {
+		out = append(out, auditViolation{"maker_price",
+			fmt.Errorf("price %d differs from maker %s resting price %d", exec.Price, maker.OrderID, maker.Price)})
+	}
+
+	// A market order's price is only a protection limit, and 0 means it has
+	// none: any ask is within reach
+	unlimited := taker.IsMarket() && taker.Price == 0
+	if taker.Side == domain.SideBuy && !unlimited && exec.Price > taker.Price {
+		out = append(out, auditViolation{"taker_limit",
+			fmt.Errorf("price %d above buy limit %d of taker %s", exec.Price, taker.Price, taker.OrderID)})
+	}
+	if taker.Side == domain.SideSell && exec.Price < taker.Price {
+		out = append(out, auditViolation{"taker_limit",
+			fmt.Errorf("price %d below sell limit %d of taker %s", exec.Price, taker.Price, taker.OrderID)})
+	}
+
+	if maker.Side == taker.Side {
+		out = append(out, auditViolation{"same_side",
+			fmt.Errorf("maker %s and taker %s are both %s", maker.OrderID, taker.OrderID, taker.Side)})
+	}
+
+	return out
+}
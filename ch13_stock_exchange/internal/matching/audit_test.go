@@ -0,0 +1,95 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit_NormalExecutionsPass(t *testing.T) {
+	engine := NewEngine()
+	engine.SetPriceAudit(true)
+
+	before := testutil.ToFloat64(middleware.ExecutionAuditViolations.WithLabelValues("AUDT", "maker_price"))
+
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s1", "AUDT", domain.SideSell, 10010, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AUDT", domain.SideSell, 10020, 100)})
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("b1", "AUDT", domain.SideBuy, 10050, 150)})
+
+	require.Len(t, result.Executions, 2)
+	assert.Len(t, result.MakerOrders, 2)
+	assert.Equal(t, before, testutil.ToFloat64(middleware.ExecutionAuditViolations.WithLabelValues("AUDT", "maker_price")))
+}
+
+func TestAudit_MarketOrdersPass(t *testing.T) {
+	engine := NewEngine()
+	engine.SetPriceAudit(true)
+
+	limitBefore := testutil.ToFloat64(middleware.ExecutionAuditViolations.WithLabelValues("AUDK", "taker_limit"))
+
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s1", "AUDK", domain.SideSell, 10010, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AUDK", domain.SideSell, 10020, 100)})
+	buy := newOrder("b1", "AUDK", domain.SideBuy, 0, 150)
+	buy.Type = domain.OrderTypeMarket
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: buy})
+	require.Len(t, result.Executions, 2)
+
+	// A protected market sell fills within its protection price
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("b2", "AUDK", domain.SideBuy, 10000, 10)})
+	sell := newOrder("s3", "AUDK", domain.SideSell, 9990, 10)
+	sell.Type = domain.OrderTypeMarket
+	result = engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: sell})
+	require.Len(t, result.Executions, 1)
+
+	assert.Equal(t, limitBefore, testutil.ToFloat64(middleware.ExecutionAuditViolations.WithLabelValues("AUDK", "taker_limit")))
+
+	// A fill beyond a market buy's protection price is still flagged
+	exec := &domain.Execution{ExecID: "b3-exec-1", Symbol: "AUDK", Price: 10020, MakerOrderID: "s2"}
+	protected := newOrder("b3", "AUDK", domain.SideBuy, 10010, 10)
+	protected.Type = domain.OrderTypeMarket
+	violations := engine.auditExecutions(protected, []*domain.Execution{exec}, []*domain.Order{newOrder("s2", "AUDK", domain.SideSell, 10020, 10)})
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Error(), "above buy limit")
+}
+
+func TestAudit_FlagsOutOfRangeExecution(t *testing.T) {
+	engine := NewEngine()
+
+	taker := newOrder("b1", "AUDX", domain.SideBuy, 10000, 100)
+	maker := newOrder("s1", "AUDX", domain.SideSell, 9990, 100)
+
+	// Forced regression: executed above both the maker's price and the buy limit
+	exec := &domain.Execution{
+		ExecID:       "b1-exec-1",
+		Symbol:       "AUDX",
+		Price:        10005,
+		Quantity:     100,
+		MakerOrderID: maker.OrderID,
+		TakerOrderID: taker.OrderID,
+	}
+
+	violations := engine.auditExecutions(taker, []*domain.Execution{exec}, []*domain.Order{maker})
+	assert.Len(t, violations, 2)
+	assert.Equal(t, 1.0, testutil.ToFloat64(middleware.ExecutionAuditViolations.WithLabelValues("AUDX", "maker_price")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(middleware.ExecutionAuditViolations.WithLabelValues("AUDX", "taker_limit")))
+
+	// A correct execution at the maker price is silent
+	exec.Price = 9990
+	assert.Empty(t, engine.auditExecutions(taker, []*domain.Execution{exec}, []*domain.Order{maker}))
+}
+
+func TestAudit_FlagsMakerMismatch(t *testing.T) {
+	engine := NewEngine()
+
+	taker := newOrder("s1", "AUDM", domain.SideSell, 10000, 100)
+	maker := newOrder("b1", "AUDM", domain.SideBuy, 10000, 100)
+	exec := &domain.Execution{ExecID: "s1-exec-1", Symbol: "AUDM", Price: 10000, MakerOrderID: "other"}
+
+	violations := engine.auditExecutions(taker, []*domain.Execution{exec}, []*domain.Order{maker})
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Error(), "does not match")
+}
@@ -0,0 +1,18 @@
+package matching
+
+import (
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// observeDepth sets a symbol's order book depth gauge, the number of price
+// levels on each side, after an event that may have changed its book.
+// Caller must hold e.mu.
+func (e *Engine) observeDepth(symbol string) {
+	book := e.books[symbol]
+	if book == nil {
+		return
+	}
+	middleware.OrderBookDepth.WithLabelValues(symbol, string(domain.SideBuy)).Set(float64(len(book.BuyBook.LimitMap)))
+	middleware.OrderBookDepth.WithLabelValues(symbol, string(domain.SideSell)).Set(float64(len(book.SellBook.LimitMap)))
+}
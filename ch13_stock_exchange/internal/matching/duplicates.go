@@ -0,0 +1,54 @@
+package matching
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// DuplicateOrderPolicy decides what the engine does with a new order whose ID
+// is already resting in the book.
+type DuplicateOrderPolicy string
+
+const (
+	// DuplicateOrderReject (the default) answers the duplicate with a
+	// rejection event so downstream consumers see it was refused.
+	DuplicateOrderReject DuplicateOrderPolicy = "reject"
+	// DuplicateOrderSkip logs the duplicate and drops it without an event.
+	DuplicateOrderSkip DuplicateOrderPolicy = "skip"
+)
+
+// ParseDuplicateOrderPolicy parses a duplicate order policy name.
+func ParseDuplicateOrderPolicy(s string) (DuplicateOrderPolicy, error) {
+	switch p := DuplicateOrderPolicy(s); p {
+	case DuplicateOrderReject, DuplicateOrderSkip:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown duplicate order policy %q (want %q or %q)", s, DuplicateOrderReject, DuplicateOrderSkip)
+}
+
+// SetDuplicateOrderPolicy sets how new orders reusing a resting order's ID
+// are handled. Either way the duplicate never matches or rests, so the
+// original keeps its place in the book.
+func (e *Engine) SetDuplicateOrderPolicy(policy DuplicateOrderPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.duplicates = policy
+}
+
+// handleDuplicate refuses a new order whose ID is already in the book.
+func (e *Engine) handleDuplicate(order *domain.Order) *domain.ExecutionEvent {
+	middleware.DuplicateOrdersRejected.WithLabelValues(order.Symbol).Inc()
+	log.Printf("[matching] WARN: order %s already rests in the %s book, refusing duplicate (seq %d)",
+		order.OrderID, order.Symbol, order.SequenceID)
+
+	if e.duplicates == DuplicateOrderSkip {
+		return nil
+	}
+	return &domain.ExecutionEvent{
+		Rejected:     order,
+		RejectReason: "duplicate order ID",
+	}
+}
@@ -0,0 +1,65 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_DuplicateOrderIDRejected(t *testing.T) {
+	engine := NewEngine()
+
+	first := newOrder("o1", "AAPL", domain.SideSell, 10010, 100)
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: first})
+
+	// Same ID, crossing price: must neither trade nor overwrite the resting order
+	dup := newOrder("o1", "AAPL", domain.SideBuy, 10100, 40)
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: dup})
+	require.NotNil(t, result)
+	assert.Equal(t, dup, result.Rejected)
+	assert.Equal(t, "duplicate order ID", result.RejectReason)
+	assert.Empty(t, result.Executions)
+	assert.Nil(t, result.TakerOrder)
+	assert.Equal(t, int64(40), dup.RemainingQuantity)
+
+	snap := engine.GetL2Snapshot("AAPL", 5)
+	require.Len(t, snap.Asks, 1)
+	assert.Equal(t, domain.PriceLevel{Price: 10010, Quantity: 100}, snap.Asks[0])
+	assert.Empty(t, snap.Bids)
+
+	// The original is still matchable...
+	taker := newOrder("o2", "AAPL", domain.SideBuy, 10010, 60)
+	result = engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: taker})
+	require.Len(t, result.Executions, 1)
+	assert.Equal(t, "o1", result.Executions[0].MakerOrderID)
+	assert.Equal(t, int64(40), first.RemainingQuantity)
+
+	// ...and cancelable, leaving nothing orphaned in the level
+	result = engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionCancel, Order: first})
+	assert.Equal(t, domain.OrderStatusCanceled, result.TakerOrder.Status)
+	assert.Empty(t, engine.GetL2Snapshot("AAPL", 5).Asks)
+
+	// Once o1 has left the book its ID may be used again
+	reused := newOrder("o1", "AAPL", domain.SideBuy, 9900, 10)
+	result = engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: reused})
+	assert.Nil(t, result.Rejected)
+	assert.Equal(t, reused, result.TakerOrder)
+}
+
+func TestEngine_DuplicateOrderIDSkipped(t *testing.T) {
+	engine := NewEngine()
+	engine.SetDuplicateOrderPolicy(DuplicateOrderSkip)
+
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("o1", "AAPL", domain.SideBuy, 10000, 100)})
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("o1", "AAPL", domain.SideBuy, 10000, 50)})
+	assert.Nil(t, result)
+
+	snap := engine.GetL2Snapshot("AAPL", 5)
+	require.Len(t, snap.Bids, 1)
+	assert.Equal(t, int64(100), snap.Bids[0].Quantity)
+
+	_, err := ParseDuplicateOrderPolicy("ignore")
+	assert.Error(t, err)
+}
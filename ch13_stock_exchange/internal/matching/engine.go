@@ -1,6 +1,7 @@
 package matching
 
 import (
+	"sync"
 	"time"
 
 	"github.com/nathanyu/stock-exchange/internal/domain"
@@ -10,13 +11,45 @@ import (
 // Engine is the matching engine. It maintains per-symbol order books and
 // dispatches incoming orders for matching.
 type Engine struct {
-	books map[string]*orderbook.OrderBook // symbol -> order book
+	// mu guards books: HandleOrder runs on the sequencer goroutine while
+	// snapshots are read from HTTP handlers.
+	mu       sync.RWMutex
+	books    map[string]*orderbook.OrderBook // symbol -> order book
+	bookOpts orderbook.Options
+	audit    bool            // validate execution prices (see audit.go)
+	auctions map[string]bool // symbols collecting orders for an auction (see auction.go)
+
+	stops     map[string][]*domain.Order // parked stop orders by symbol, oldest first (see stops.go)
+	lastPrice map[string]int64           // last trade price by symbol, which triggers stops
+
+	duplicates DuplicateOrderPolicy // what to do with reused order IDs (see duplicates.go)
+	layering   LayeringCap          // per-user resting size limit per level (see surveillance.go)
+
+	fillRatioSymbols map[string]bool // symbols labeled in the fill ratio metric; nil = all (see fillratio.go)
+
+	allocations map[string]symbolAllocation // per-symbol overrides of bookOpts.Allocation (see allocation.go)
+
+	// eventTime is the timestamp of the event being handled, which every
+	// execution it produces is stamped with
+	eventTime time.Time
 }
 
 // NewEngine creates a new matching engine.
 func NewEngine() *Engine {
+	return NewEngineWithOptions(orderbook.Options{})
+}
+
+// NewEngineWithOptions creates a matching engine whose books use the given options.
+func NewEngineWithOptions(opts orderbook.Options) *Engine {
 	return &Engine{
-		books: make(map[string]*orderbook.OrderBook),
+		books:    make(map[string]*orderbook.OrderBook),
+		bookOpts: opts,
+		auctions: make(map[string]bool),
+
+		stops:     make(map[string][]*domain.Order),
+		lastPrice: make(map[string]int64),
+
+		duplicates: DuplicateOrderReject,
 	}
 }
 
@@ -24,50 +57,149 @@ func NewEngine() *Engine {
 func (e *Engine) getOrCreateBook(symbol string) *orderbook.OrderBook {
 	book, exists := e.books[symbol]
 	if !exists {
-		book = orderbook.NewOrderBook(symbol)
+		book = orderbook.NewOrderBookWithOptions(symbol, e.bookOptions(symbol))
 		e.books[symbol] = book
 	}
 	return book
 }
 
-// HandleOrder processes an order event (new or cancel) and returns any resulting executions.
+// HandleOrder processes an order event (new, cancel, or auction start/end)
+// and returns any resulting executions, including those of stop orders the
+// executions triggered. Executions are stamped with the event's Timestamp,
+// or the current time if it has none.
 func (e *Engine) HandleOrder(event *domain.OrderEvent) *domain.ExecutionEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.eventTime = event.Timestamp
+	if e.eventTime.IsZero() {
+		e.eventTime = time.Now()
+	}
+
+	var result *domain.ExecutionEvent
 	switch event.Action {
 	case domain.OrderActionNew:
-		return e.handleNew(event.Order)
+		result = e.handleNew(event.Order)
 	case domain.OrderActionCancel:
-		return e.handleCancel(event.Order)
+		result = e.handleCancel(event.Order)
+	case domain.OrderActionModify:
+		result = e.handleModify(event.Order)
+	case domain.OrderActionAuctionStart:
+		result = e.handleAuctionStart(event.Order.Symbol)
+	case domain.OrderActionAuctionEnd:
+		result = e.handleAuctionEnd(event.Order.Symbol, event.Order.SequenceID)
+	case domain.OrderActionSessionReset:
+		// Nothing changes in the book; the marker tells downstream consumers
+		// where in the execution stream the new session begins
+		return &domain.ExecutionEvent{SessionReset: event.Order.Symbol}
 	default:
 		return nil
 	}
+
+	if result != nil && len(result.Executions) > 0 {
+		e.triggerStops(event.Order.Symbol, result)
+		e.observeSpread(event.Order.Symbol)
+	}
+	e.observeDepth(event.Order.Symbol)
+	if result != nil {
+		recordStates(result)
+	}
+	return result
+}
+
+// recordStates copies the progress of every order an event touched into
+// its States, before later events change the orders again.
+func recordStates(result *domain.ExecutionEvent) {
+	result.States = make(map[string]domain.OrderState, 1+len(result.Triggered)+len(result.MakerOrders))
+	if result.TakerOrder != nil {
+		result.States[result.TakerOrder.OrderID] = domain.StateOf(result.TakerOrder)
+	}
+	for _, order := range result.Triggered {
+		result.States[order.OrderID] = domain.StateOf(order)
+	}
+	for _, order := range result.MakerOrders {
+		result.States[order.OrderID] = domain.StateOf(order)
+	}
 }
 
 // handleNew processes a new order: match against opposite side, then rest remainder.
 func (e *Engine) handleNew(order *domain.Order) *domain.ExecutionEvent {
 	book := e.getOrCreateBook(order.Symbol)
-	now := time.Now()
+	// A second order under a resting order's ID would overwrite its OrderMap
+	// entry and orphan it in the price level
+	if book.HasOrder(order.OrderID) || e.parkedStop(order.Symbol, order.OrderID) >= 0 {
+		return e.handleDuplicate(order)
+	}
+
+	if order.IsStop() && !e.stopTriggered(order) {
+		e.parkStop(order)
+		return &domain.ExecutionEvent{TakerOrder: order}
+	}
+
+	if e.auctions[order.Symbol] {
+		return e.collectForAuction(order)
+	}
+	return e.activate(book, order)
+}
+
+// activate matches an order that may trade now: a new order, or a stop
+// order whose trigger price was reached.
+func (e *Engine) activate(book *orderbook.OrderBook, order *domain.Order) *domain.ExecutionEvent {
+	// Fill or kill: all or nothing, decided before anything trades
+	if order.TimeInForce == domain.TimeInForceFOK && !book.CanFill(order) {
+		order.Status = domain.OrderStatusCanceled
+		return &domain.ExecutionEvent{
+			TakerOrder:   order,
+			RejectReason: "fill or kill: not enough liquidity",
+		}
+	}
 
+	event := e.matchAndRest(book, order)
+	e.observeFillRatio(order)
+	return event
+}
+
+// matchAndRest matches an order against the book and rests what is left,
+// unless the order may not rest.
+func (e *Engine) matchAndRest(book *orderbook.OrderBook, order *domain.Order) *domain.ExecutionEvent {
 	// Attempt to match
-	executions := book.MatchOrder(order)
+	executions, makers := book.MatchOrderWithMakers(order)
 
 	// Stamp timestamps on executions
 	for _, exec := range executions {
-		exec.Timestamp = now
+		exec.Timestamp = e.eventTime
+	}
+
+	if e.audit {
+		e.auditExecutions(order, executions, makers)
 	}
 
 	// Collect affected maker orders
 	makerOrders := make([]*domain.Order, 0, len(executions))
 	seen := make(map[string]bool)
-	for _, exec := range executions {
-		if !seen[exec.MakerOrderID] {
-			seen[exec.MakerOrderID] = true
-			// Look up maker order from the book's order map if still there,
-			// or we already have it from the execution
+	for _, maker := range makers {
+		if !seen[maker.OrderID] {
+			seen[maker.OrderID] = true
+			makerOrders = append(makerOrders, maker)
 		}
 	}
 
-	// If order has remaining quantity, add it as a resting order
-	if order.RemainingQuantity > 0 {
+	// Market, IOC and FOK orders never rest: whatever the book (or a
+	// slippage cap) could not fill is canceled
+	var rejectReason string
+	if (order.IsMarket() || order.IsImmediate()) && order.RemainingQuantity > 0 {
+		order.Status = domain.OrderStatusCanceled
+	} else if order.RemainingQuantity > 0 && book.Crosses(order) {
+		// Makers were skipped for a minimum execution quantity: resting the
+		// remainder at a price they would trade at would show a crossed book
+		order.Status = domain.OrderStatusCanceled
+		rejectReason = "minimum execution quantity: remainder would cross the book"
+	} else if order.RemainingQuantity > 0 && e.breachesLayeringCap(book, order) {
+		// Rejected by surveillance: the remainder is canceled, not rested
+		order.Status = domain.OrderStatusCanceled
+		rejectReason = "layering cap exceeded"
+	} else if order.RemainingQuantity > 0 {
+		// If order has remaining quantity, add it as a resting order
 		if order.Status == domain.OrderStatusNew {
 			order.Status = domain.OrderStatusNew
 		}
@@ -76,14 +208,16 @@ func (e *Engine) handleNew(order *domain.Order) *domain.ExecutionEvent {
 
 	if len(executions) == 0 {
 		return &domain.ExecutionEvent{
-			TakerOrder: order,
+			TakerOrder:   order,
+			RejectReason: rejectReason,
 		}
 	}
 
 	return &domain.ExecutionEvent{
-		Executions:  executions,
-		TakerOrder:  order,
-		MakerOrders: makerOrders,
+		Executions:   executions,
+		TakerOrder:   order,
+		MakerOrders:  makerOrders,
+		RejectReason: rejectReason,
 	}
 }
 
@@ -91,6 +225,9 @@ func (e *Engine) handleNew(order *domain.Order) *domain.ExecutionEvent {
 func (e *Engine) handleCancel(order *domain.Order) *domain.ExecutionEvent {
 	book := e.getOrCreateBook(order.Symbol)
 	canceled := book.CancelOrder(order.OrderID)
+	if canceled == nil {
+		canceled = e.cancelStop(order.Symbol, order.OrderID)
+	}
 	if canceled != nil {
 		return &domain.ExecutionEvent{
 			TakerOrder: canceled,
@@ -103,11 +240,60 @@ func (e *Engine) handleCancel(order *domain.Order) *domain.ExecutionEvent {
 
 // GetOrderBook returns the order book for a symbol (nil if it doesn't exist).
 func (e *Engine) GetOrderBook(symbol string) *orderbook.OrderBook {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 	return e.books[symbol]
 }
 
+// BestAsk returns the lowest resting sell price for a symbol, or 0 if there
+// is none.
+func (e *Engine) BestAsk(symbol string) int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	book := e.books[symbol]
+	if book == nil {
+		return 0
+	}
+	return book.SellBook.BestPrice()
+}
+
+// SweepAskPrice returns the highest ask a buy of quantity would reach
+// sweeping symbol's book, or 0 if there are no asks. The order manager
+// prices market buys with it.
+func (e *Engine) SweepAskPrice(symbol string, quantity int64) int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	book := e.books[symbol]
+	if book == nil {
+		return 0
+	}
+	return book.SweepPrice(domain.SideBuy, quantity)
+}
+
+// DebugSnapshot returns the raw FIFO queues of a symbol's book, copied under
+// the engine lock so it is consistent with concurrent matching.
+func (e *Engine) DebugSnapshot(symbol string) *orderbook.DebugView {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	book := e.books[symbol]
+	if book == nil {
+		return &orderbook.DebugView{
+			Symbol: symbol,
+			Bids:   []orderbook.DebugLevel{},
+			Asks:   []orderbook.DebugLevel{},
+		}
+	}
+	return book.DebugSnapshot()
+}
+
 // GetL2Snapshot returns an L2 snapshot for a symbol.
 func (e *Engine) GetL2Snapshot(symbol string, depth int) *domain.L2OrderBook {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
 	book := e.books[symbol]
 	if book == nil {
 		return &domain.L2OrderBook{
@@ -118,3 +304,32 @@ func (e *Engine) GetL2Snapshot(symbol string, depth int) *domain.L2OrderBook {
 	}
 	return book.GetL2Snapshot(depth)
 }
+
+// GetBBO returns the best bid and offer for a symbol.
+func (e *Engine) GetBBO(symbol string) *domain.BBO {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	book := e.books[symbol]
+	if book == nil {
+		return &domain.BBO{Symbol: symbol}
+	}
+	return book.BBO()
+}
+
+// GetL2SnapshotAggregated returns an L2 snapshot for a symbol with price
+// levels grouped into buckets of `bucket` cents.
+func (e *Engine) GetL2SnapshotAggregated(symbol string, depth int, bucket int64) *domain.L2OrderBook {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	book := e.books[symbol]
+	if book == nil {
+		return &domain.L2OrderBook{
+			Symbol: symbol,
+			Bids:   []domain.PriceLevel{},
+			Asks:   []domain.PriceLevel{},
+		}
+	}
+	return book.GetL2SnapshotAggregated(depth, bucket)
+}
@@ -1,7 +1,9 @@
 package matching
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/nathanyu/stock-exchange/internal/domain"
 	"github.com/stretchr/testify/assert"
@@ -99,11 +101,13 @@ func TestEngine_MultipleSymbols(t *testing.T) {
 }
 
 func TestEngine_Determinism(t *testing.T) {
-	// Given the same sequence of orders, we should get the same executions
+	// Given the same sequence of orders, we should get the same executions,
+	// timestamps included
+	start := time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)
 	orders := []*domain.OrderEvent{
-		{Action: domain.OrderActionNew, Order: newOrder("s1", "AAPL", domain.SideSell, 10010, 100)},
-		{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10010, 200)},
-		{Action: domain.OrderActionNew, Order: newOrder("b1", "AAPL", domain.SideBuy, 10010, 150)},
+		{Action: domain.OrderActionNew, Order: newOrder("s1", "AAPL", domain.SideSell, 10010, 100), Timestamp: start},
+		{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10010, 200), Timestamp: start.Add(time.Millisecond)},
+		{Action: domain.OrderActionNew, Order: newOrder("b1", "AAPL", domain.SideBuy, 10010, 150), Timestamp: start.Add(2 * time.Millisecond)},
 	}
 
 	// Run twice and compare
@@ -116,7 +120,7 @@ func TestEngine_Determinism(t *testing.T) {
 			o.RemainingQuantity = o.Quantity
 			o.FilledQuantity = 0
 			o.Status = domain.OrderStatusNew
-			result := e.HandleOrder(&domain.OrderEvent{Action: evt.Action, Order: &o})
+			result := e.HandleOrder(&domain.OrderEvent{Action: evt.Action, Order: &o, Timestamp: evt.Timestamp})
 			allExecs = append(allExecs, result.Executions...)
 		}
 		return allExecs
@@ -125,11 +129,10 @@ func TestEngine_Determinism(t *testing.T) {
 	execs1 := run()
 	execs2 := run()
 
-	require.Equal(t, len(execs1), len(execs2))
-	for i := range execs1 {
-		assert.Equal(t, execs1[i].Quantity, execs2[i].Quantity)
-		assert.Equal(t, execs1[i].Price, execs2[i].Price)
-		assert.Equal(t, execs1[i].MakerOrderID, execs2[i].MakerOrderID)
+	require.Len(t, execs1, 2)
+	assert.Equal(t, execs1, execs2)
+	for _, exec := range execs1 {
+		assert.Equal(t, start.Add(2*time.Millisecond), exec.Timestamp)
 	}
 }
 
@@ -139,3 +142,225 @@ func TestEngine_GetL2Snapshot_NonexistentSymbol(t *testing.T) {
 	assert.Empty(t, snap.Bids)
 	assert.Empty(t, snap.Asks)
 }
+
+func TestEngine_DebugSnapshot_ConcurrentMatching(t *testing.T) {
+	engine := NewEngine()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			side := domain.SideSell
+			if i%2 == 0 {
+				side = domain.SideBuy
+			}
+			engine.HandleOrder(&domain.OrderEvent{
+				Action: domain.OrderActionNew,
+				Order:  newOrder(fmt.Sprintf("o%d", i), "AAPL", side, 10000+int64(i%5), 10),
+			})
+		}
+	}()
+
+	// Readers run alongside matching; -race flags any unguarded access
+	for i := 0; i < 100; i++ {
+		view := engine.DebugSnapshot("AAPL")
+		for _, level := range append(view.Bids, view.Asks...) {
+			var sum int64
+			for _, o := range level.Orders {
+				sum += o.RemainingQuantity
+			}
+			assert.Equal(t, level.TotalVolume, sum)
+		}
+		engine.GetL2Snapshot("AAPL", 5)
+	}
+	<-done
+
+	assert.Empty(t, engine.DebugSnapshot("UNKNOWN").Bids)
+}
+
+func TestEngine_MarketOrder_SlippageRemainderCanceled(t *testing.T) {
+	engine := NewEngine()
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s1", "AAPL", domain.SideSell, 10000, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10050, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s3", "AAPL", domain.SideSell, 12000, 100)})
+
+	buy := newOrder("b1", "AAPL", domain.SideBuy, 0, 300)
+	buy.Type = domain.OrderTypeMarket
+	buy.MaxSlippageBps = 100
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: buy})
+
+	require.Len(t, result.Executions, 2)
+	assert.Equal(t, domain.OrderStatusCanceled, buy.Status)
+	assert.Equal(t, int64(200), buy.FilledQuantity)
+	assert.Equal(t, int64(100), buy.RemainingQuantity)
+
+	// The remainder did not rest on the bid side
+	snap := engine.GetL2Snapshot("AAPL", 5)
+	assert.Empty(t, snap.Bids)
+	require.Len(t, snap.Asks, 1)
+	assert.Equal(t, int64(12000), snap.Asks[0].Price)
+}
+
+func TestEngine_MarketOrder_EmptyBookCanceled(t *testing.T) {
+	engine := NewEngine()
+	assert.Zero(t, engine.BestAsk("AAPL"))
+
+	buy := newOrder("b1", "AAPL", domain.SideBuy, 0, 100)
+	buy.Type = domain.OrderTypeMarket
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: buy})
+
+	assert.Empty(t, result.Executions)
+	assert.Equal(t, domain.OrderStatusCanceled, buy.Status)
+	assert.Empty(t, engine.GetL2Snapshot("AAPL", 5).Bids, "a market order never rests")
+}
+
+func TestEngine_BestAsk(t *testing.T) {
+	engine := NewEngine()
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s1", "AAPL", domain.SideSell, 10050, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10000, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("b1", "AAPL", domain.SideBuy, 9900, 100)})
+	assert.Equal(t, int64(10000), engine.BestAsk("AAPL"))
+}
+
+func TestEngine_SweepAskPrice(t *testing.T) {
+	engine := NewEngine()
+	assert.Zero(t, engine.SweepAskPrice("AAPL", 10))
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s1", "AAPL", domain.SideSell, 10050, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10000, 100)})
+
+	assert.Equal(t, int64(10000), engine.SweepAskPrice("AAPL", 100))
+	assert.Equal(t, int64(10050), engine.SweepAskPrice("AAPL", 101))
+	assert.Equal(t, int64(10050), engine.SweepAskPrice("AAPL", 500), "the last level when the book runs out")
+}
+
+func TestEngine_IOC_PartialFillThenCancel(t *testing.T) {
+	engine := NewEngine()
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s1", "AAPL", domain.SideSell, 10000, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10100, 100)})
+
+	buy := newOrder("b1", "AAPL", domain.SideBuy, 10000, 150)
+	buy.TimeInForce = domain.TimeInForceIOC
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: buy})
+
+	require.Len(t, result.Executions, 1)
+	assert.Equal(t, int64(100), buy.FilledQuantity)
+	assert.Equal(t, domain.OrderStatusCanceled, buy.Status)
+	assert.Empty(t, engine.GetL2Snapshot("AAPL", 5).Bids, "the remainder does not rest")
+}
+
+func TestEngine_FOK_AllOrNothing(t *testing.T) {
+	engine := NewEngine()
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s1", "AAPL", domain.SideSell, 10000, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10100, 100)})
+	before := engine.DebugSnapshot("AAPL")
+
+	// 250 is more than the book holds
+	kill := newOrder("b1", "AAPL", domain.SideBuy, 10100, 250)
+	kill.TimeInForce = domain.TimeInForceFOK
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: kill})
+	assert.Empty(t, result.Executions)
+	assert.NotEmpty(t, result.RejectReason)
+	assert.Equal(t, domain.OrderStatusCanceled, kill.Status)
+	assert.Zero(t, kill.FilledQuantity)
+	assert.Equal(t, before, engine.DebugSnapshot("AAPL"), "a killed order leaves the book untouched")
+
+	// 200 is there, but only up to 10000 the second level is out of reach
+	kill = newOrder("b2", "AAPL", domain.SideBuy, 10000, 200)
+	kill.TimeInForce = domain.TimeInForceFOK
+	result = engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: kill})
+	assert.Empty(t, result.Executions)
+	assert.Equal(t, domain.OrderStatusCanceled, kill.Status)
+
+	fill := newOrder("b3", "AAPL", domain.SideBuy, 10100, 200)
+	fill.TimeInForce = domain.TimeInForceFOK
+	result = engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: fill})
+	assert.Len(t, result.Executions, 2)
+	assert.Equal(t, domain.OrderStatusFilled, fill.Status)
+}
+
+func TestEngine_Auction_CollectsThenUncrosses(t *testing.T) {
+	engine := NewEngine()
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionAuctionStart, Order: &domain.Order{Symbol: "AAPL"}})
+	assert.True(t, engine.InAuction("AAPL"))
+
+	orders := []*domain.Order{
+		newOrder("b1", "AAPL", domain.SideBuy, 10100, 100),
+		newOrder("s1", "AAPL", domain.SideSell, 9800, 150),
+		newOrder("b2", "AAPL", domain.SideBuy, 10000, 200),
+		newOrder("s2", "AAPL", domain.SideSell, 10000, 150),
+	}
+	for i, o := range orders {
+		o.SequenceID = uint64(i + 2)
+		result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: o})
+		assert.Empty(t, result.Executions, "no continuous matching during the auction")
+	}
+
+	// Market orders have no price to take part in the auction with
+	market := newOrder("m1", "AAPL", domain.SideBuy, 0, 10)
+	market.Type = domain.OrderTypeMarket
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: market})
+	assert.Equal(t, domain.OrderStatusCanceled, market.Status)
+	// Nor can an IOC order trade on arrival
+	ioc := newOrder("i1", "AAPL", domain.SideBuy, 10000, 10)
+	ioc.TimeInForce = domain.TimeInForceIOC
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: ioc})
+	assert.Equal(t, domain.OrderStatusCanceled, ioc.Status)
+
+	// Other symbols keep matching continuously
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("g1", "GOOG", domain.SideSell, 20000, 10)})
+	goog := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("g2", "GOOG", domain.SideBuy, 20000, 10)})
+	assert.Len(t, goog.Executions, 1)
+
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionAuctionEnd, Order: &domain.Order{Symbol: "AAPL", SequenceID: 9}})
+	require.NotNil(t, result)
+	assert.False(t, engine.InAuction("AAPL"))
+	assert.Nil(t, result.TakerOrder)
+	require.Len(t, result.Executions, 3)
+	for _, exec := range result.Executions {
+		assert.Equal(t, int64(10000), exec.Price)
+		assert.False(t, exec.Timestamp.IsZero())
+	}
+	assert.Len(t, result.MakerOrders, 4)
+
+	// Back to continuous matching: a crossing order trades immediately
+	sell := newOrder("s3", "AAPL", domain.SideSell, 9000, 50)
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("b3", "AAPL", domain.SideBuy, 9500, 50)})
+	after := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: sell})
+	require.Len(t, after.Executions, 1)
+	assert.Equal(t, int64(9500), after.Executions[0].Price)
+}
+
+func TestEngine_MinExecQtyNeverRestsCrossed(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("s1", "AAPL", domain.SideSell, 10000, 10))
+	picky := newOrder("s2", "AAPL", domain.SideSell, 10010, 100)
+	picky.MinExecQty = 40
+	submit(engine, picky)
+
+	// Too small a fill for the taker: it skips s1 and must not rest above it
+	taker := newOrder("b1", "AAPL", domain.SideBuy, 10000, 100)
+	taker.MinExecQty = 50
+	result := submit(engine, taker)
+	assert.Empty(t, result.Executions)
+	assert.Equal(t, domain.OrderStatusCanceled, taker.Status)
+	assert.NotEmpty(t, result.RejectReason)
+
+	// Too small a fill for the maker: the taker trades s1 and skips s2
+	small := newOrder("b2", "AAPL", domain.SideBuy, 10010, 30)
+	result = submit(engine, small)
+	require.Len(t, result.Executions, 1)
+	assert.Equal(t, "s1", result.Executions[0].MakerOrderID)
+	assert.Equal(t, domain.OrderStatusCanceled, small.Status)
+
+	snap := engine.GetL2Snapshot("AAPL", 10)
+	assert.Empty(t, snap.Bids)
+	require.Len(t, snap.Asks, 1)
+	assert.Equal(t, int64(10010), snap.Asks[0].Price)
+
+	// Below the asks a minimum does not stop the order resting
+	resting := newOrder("b3", "AAPL", domain.SideBuy, 9990, 100)
+	resting.MinExecQty = 50
+	submit(engine, resting)
+	assert.Equal(t, domain.OrderStatusNew, resting.Status)
+	assert.Equal(t, int64(9990), engine.GetL2Snapshot("AAPL", 10).Bids[0].Price)
+}
@@ -0,0 +1,51 @@
+package matching
+
+import (
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// Fill ratio outcomes, the outcome label of exchange_fill_ratio
+const (
+	FillOutcomeFull    = "full"
+	FillOutcomePartial = "partial"
+	FillOutcomeNone    = "none"
+)
+
+// fillRatioOtherSymbol labels orders for symbols outside the configured set
+const fillRatioOtherSymbol = "other"
+
+// SetFillRatioSymbols sets the symbols the fill ratio metric is labeled
+// with; orders for any other symbol are recorded as "other", so clients
+// cannot grow the metric's cardinality with made-up symbols. Until it is
+// called, or with an empty list, every order is recorded as "other".
+func (e *Engine) SetFillRatioSymbols(symbols []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fillRatioSymbols = make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		e.fillRatioSymbols[s] = true
+	}
+}
+
+// observeFillRatio records how much of a just-matched taker order executed
+// on arrival. Caller must hold e.mu.
+func (e *Engine) observeFillRatio(order *domain.Order) {
+	if order.Quantity <= 0 {
+		return
+	}
+	filled := order.Quantity - order.RemainingQuantity
+	outcome := FillOutcomePartial
+	switch {
+	case filled >= order.Quantity:
+		outcome = FillOutcomeFull
+	case filled <= 0:
+		outcome = FillOutcomeNone
+	}
+
+	symbol := order.Symbol
+	if !e.fillRatioSymbols[symbol] {
+		symbol = fillRatioOtherSymbol
+	}
+	middleware.FillRatio.WithLabelValues(symbol, outcome).Observe(float64(filled) / float64(order.Quantity))
+}
@@ -0,0 +1,74 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fillRatioSamples returns the observation count and sum of one fill ratio series
+func fillRatioSamples(t *testing.T, symbol, outcome string) (uint64, float64) {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, middleware.FillRatio.WithLabelValues(symbol, outcome).(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestEngine_FillRatioByLiquidity(t *testing.T) {
+	engine := NewEngine()
+	engine.SetFillRatioSymbols([]string{"FILL"})
+	submit := func(o *domain.Order) {
+		engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: o})
+	}
+
+	// No liquidity: the first order rests unfilled
+	submit(newOrder("s1", "FILL", domain.SideSell, 10000, 100))
+	count, sum := fillRatioSamples(t, "FILL", FillOutcomeNone)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 0.0, sum)
+
+	// Fully filled by the resting 100
+	submit(newOrder("b1", "FILL", domain.SideBuy, 10000, 60))
+	count, sum = fillRatioSamples(t, "FILL", FillOutcomeFull)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 1.0, sum)
+
+	// Only 40 left against 160: a quarter fills
+	submit(newOrder("b2", "FILL", domain.SideBuy, 10000, 160))
+	count, sum = fillRatioSamples(t, "FILL", FillOutcomePartial)
+	assert.Equal(t, uint64(1), count)
+	assert.InDelta(t, 0.25, sum, 1e-9)
+}
+
+func TestEngine_FillRatioSymbolsBoundLabels(t *testing.T) {
+	engine := NewEngine()
+	engine.SetFillRatioSymbols([]string{"KNOWN"})
+	other, _ := fillRatioSamples(t, "other", FillOutcomeNone)
+
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("k1", "KNOWN", domain.SideBuy, 10000, 10)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("u1", "MADEUP", domain.SideBuy, 10000, 10)})
+
+	count, _ := fillRatioSamples(t, "KNOWN", FillOutcomeNone)
+	assert.Equal(t, uint64(1), count)
+	count, _ = fillRatioSamples(t, "other", FillOutcomeNone)
+	assert.Equal(t, other+1, count)
+	count, _ = fillRatioSamples(t, "MADEUP", FillOutcomeNone)
+	assert.Equal(t, uint64(0), count)
+}
+
+func TestEngine_FillRatioUnconfiguredIsOther(t *testing.T) {
+	engine := NewEngine()
+	other, _ := fillRatioSamples(t, "other", FillOutcomeNone)
+
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("u1", "NOCFG", domain.SideBuy, 10000, 10)})
+
+	count, _ := fillRatioSamples(t, "other", FillOutcomeNone)
+	assert.Equal(t, other+1, count)
+	count, _ = fillRatioSamples(t, "NOCFG", FillOutcomeNone)
+	assert.Equal(t, uint64(0), count)
+}
@@ -0,0 +1,57 @@
+package matching
+
+import (
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/orderbook"
+)
+
+// handleModify changes a resting order's price and quantity. req carries the
+// order's ID, symbol and side with the new price and total quantity.
+//
+// A smaller quantity at the same price is applied in place and keeps the
+// order's queue position. Anything else costs it its time priority: the
+// order leaves the book and comes back as if new, so a price that now
+// crosses trades immediately and the layering cap applies to the new size.
+// During an auction nothing trades, so the order is simply requeued.
+func (e *Engine) handleModify(req *domain.Order) *domain.ExecutionEvent {
+	book := e.books[req.Symbol]
+	var order *domain.Order
+	if book != nil {
+		order = book.Order(req.OrderID)
+	}
+	switch {
+	case order == nil:
+		return modifyRejected(req, "order is not resting")
+	case req.Quantity <= order.FilledQuantity:
+		return modifyRejected(req, "quantity must exceed the filled quantity")
+	}
+
+	inPlace := req.Price == order.Price && req.Quantity <= order.Quantity
+	if inPlace || e.auctions[req.Symbol] {
+		book.ModifyOrder(order.OrderID, req.Price, req.Quantity)
+		if !inPlace {
+			order.SequenceID = req.SequenceID
+		}
+		return &domain.ExecutionEvent{TakerOrder: order, Modified: true}
+	}
+
+	requeue(book, order, req)
+	event := e.matchAndRest(book, order)
+	event.Modified = true
+	return event
+}
+
+// requeue takes order out of the book with req's price and quantity and the
+// sequence ID of the modify, ready to be matched and rested like a new order.
+func requeue(book *orderbook.OrderBook, order, req *domain.Order) {
+	book.RemoveOrder(order.OrderID)
+	order.Price = req.Price
+	order.Quantity = req.Quantity
+	order.RemainingQuantity = req.Quantity - order.FilledQuantity
+	order.SequenceID = req.SequenceID
+}
+
+// modifyRejected refuses a modify request, leaving the order as it was.
+func modifyRejected(req *domain.Order, reason string) *domain.ExecutionEvent {
+	return &domain.ExecutionEvent{Rejected: req, RejectReason: reason, Modified: true}
+}
@@ -0,0 +1,82 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func modify(engine *Engine, id string, side domain.Side, price, qty int64) *domain.ExecutionEvent {
+	req := newOrder(id, "AAPL", side, price, qty)
+	return engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionModify, Order: req})
+}
+
+func TestEngine_Modify_DecreaseKeepsPriority(t *testing.T) {
+	engine := NewEngine()
+	s1 := newOrder("s1", "AAPL", domain.SideSell, 10000, 100)
+	s1.SequenceID = 1
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: s1})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10000, 100)})
+
+	result := modify(engine, "s1", domain.SideSell, 10000, 50)
+	assert.True(t, result.Modified)
+	assert.Same(t, s1, result.TakerOrder)
+	assert.Equal(t, int64(50), s1.RemainingQuantity)
+	assert.Equal(t, uint64(1), s1.SequenceID)
+
+	buy := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("b1", "AAPL", domain.SideBuy, 10000, 50)})
+	require.Len(t, buy.Executions, 1)
+	assert.Equal(t, "s1", buy.Executions[0].MakerOrderID)
+}
+
+func TestEngine_Modify_PriceChangeRequeues(t *testing.T) {
+	engine := NewEngine()
+	s1 := newOrder("s1", "AAPL", domain.SideSell, 10010, 100)
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: s1})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("s2", "AAPL", domain.SideSell, 10000, 100)})
+
+	req := newOrder("s1", "AAPL", domain.SideSell, 10000, 100)
+	req.SequenceID = 7
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionModify, Order: req})
+	assert.True(t, result.Modified)
+	assert.Empty(t, result.Executions)
+	assert.Equal(t, uint64(7), s1.SequenceID, "the requeued order takes the modify's place in time")
+
+	view := engine.DebugSnapshot("AAPL")
+	require.Len(t, view.Asks, 1)
+	assert.Equal(t, "s2", view.Asks[0].Orders[0].OrderID)
+	assert.Equal(t, "s1", view.Asks[0].Orders[1].OrderID)
+}
+
+func TestEngine_Modify_CrossingPriceTrades(t *testing.T) {
+	engine := NewEngine()
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("b1", "AAPL", domain.SideBuy, 9990, 60)})
+	s1 := newOrder("s1", "AAPL", domain.SideSell, 10010, 100)
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: s1})
+
+	result := modify(engine, "s1", domain.SideSell, 9990, 100)
+	assert.True(t, result.Modified)
+	require.Len(t, result.Executions, 1)
+	assert.Equal(t, int64(60), result.Executions[0].Quantity)
+	assert.Equal(t, int64(40), s1.RemainingQuantity)
+	assert.Equal(t, domain.OrderStatusPartiallyFilled, s1.Status)
+	assert.Equal(t, int64(9990), engine.GetL2Snapshot("AAPL", 5).Asks[0].Price)
+}
+
+func TestEngine_Modify_Rejected(t *testing.T) {
+	engine := NewEngine()
+	result := modify(engine, "missing", domain.SideSell, 10000, 100)
+	assert.True(t, result.Modified)
+	require.NotNil(t, result.Rejected)
+	assert.NotEmpty(t, result.RejectReason)
+
+	s1 := newOrder("s1", "AAPL", domain.SideSell, 10000, 100)
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: s1})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: newOrder("b1", "AAPL", domain.SideBuy, 10000, 70)})
+	result = modify(engine, "s1", domain.SideSell, 10000, 70)
+	require.NotNil(t, result.Rejected)
+	assert.Equal(t, int64(100), s1.Quantity, "the order is left as it was")
+	assert.Equal(t, int64(30), s1.RemainingQuantity)
+}
@@ -0,0 +1,19 @@
+package matching
+
+import "github.com/nathanyu/stock-exchange/internal/middleware"
+
+// observeSpread sets a symbol's spread gauge after a match. A match that
+// empties a side leaves no spread, so the symbol's series is removed rather
+// than showing a stale or zero spread. Caller must hold e.mu.
+func (e *Engine) observeSpread(symbol string) {
+	book := e.books[symbol]
+	if book == nil {
+		return
+	}
+	bbo := book.BBO()
+	if bbo.Spread == nil {
+		middleware.SpreadCents.DeleteLabelValues(symbol)
+		return
+	}
+	middleware.SpreadCents.WithLabelValues(symbol).Set(float64(*bbo.Spread))
+}
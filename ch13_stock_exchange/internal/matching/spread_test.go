@@ -0,0 +1,80 @@
+package matching
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBBO_TopOfBook(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("s1", "BBO", domain.SideSell, 10020, 100))
+	submit(engine, newOrder("s2", "BBO", domain.SideSell, 10010, 30))
+	submit(engine, newOrder("s3", "BBO", domain.SideSell, 10010, 20))
+	submit(engine, newOrder("b1", "BBO", domain.SideBuy, 9990, 70))
+	submit(engine, newOrder("b2", "BBO", domain.SideBuy, 9980, 10))
+
+	bbo := engine.GetBBO("BBO")
+	require.NotNil(t, bbo.BidPrice)
+	require.NotNil(t, bbo.AskPrice)
+	require.NotNil(t, bbo.Spread)
+	assert.Equal(t, int64(9990), *bbo.BidPrice)
+	assert.Equal(t, int64(70), *bbo.BidSize)
+	assert.Equal(t, int64(10010), *bbo.AskPrice)
+	assert.Equal(t, int64(50), *bbo.AskSize)
+	assert.Equal(t, int64(20), *bbo.Spread)
+}
+
+func TestGetBBO_EmptySideIsNull(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("b1", "BBON", domain.SideBuy, 9990, 70))
+
+	out, err := json.Marshal(engine.GetBBO("BBON"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"symbol":"BBON","bid_price":9990,"bid_size":70,"ask_price":null,"ask_size":null,"spread":null}`, string(out))
+
+	out, err = json.Marshal(engine.GetBBO("NONE"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"symbol":"NONE","bid_price":null,"bid_size":null,"ask_price":null,"ask_size":null,"spread":null}`, string(out))
+}
+
+func TestSpreadGauge_UpdatedOnMatch(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("s1", "SPRD", domain.SideSell, 10010, 100))
+	submit(engine, newOrder("s2", "SPRD", domain.SideSell, 10030, 100))
+	submit(engine, newOrder("b1", "SPRD", domain.SideBuy, 9990, 100))
+	// No match yet: the gauge has no series for the symbol
+	assert.False(t, hasSpreadSeries(t, "SPRD"))
+
+	submit(engine, newOrder("b2", "SPRD", domain.SideBuy, 10010, 100))
+	assert.Equal(t, float64(10030-9990), testutil.ToFloat64(middleware.SpreadCents.WithLabelValues("SPRD")))
+
+	// Emptying a side removes the series instead of leaving a stale spread
+	submit(engine, newOrder("b3", "SPRD", domain.SideBuy, 10030, 100))
+	assert.False(t, hasSpreadSeries(t, "SPRD"))
+}
+
+// hasSpreadSeries reports whether the spread gauge has a series for symbol.
+func hasSpreadSeries(t *testing.T, symbol string) bool {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	middleware.SpreadCents.Collect(ch)
+	close(ch)
+	for m := range ch {
+		var out dto.Metric
+		require.NoError(t, m.Write(&out))
+		for _, label := range out.GetLabel() {
+			if label.GetName() == "symbol" && label.GetValue() == symbol {
+				return true
+			}
+		}
+	}
+	return false
+}
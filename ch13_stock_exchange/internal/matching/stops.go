@@ -0,0 +1,104 @@
+package matching
+
+import "github.com/nathanyu/stock-exchange/internal/domain"
+
+// Stop orders.
+//
+// A stop order is parked off the book, invisible to matching and market
+// data, until its symbol's last trade price reaches the stop price: at or
+// above it for a buy, at or below it for a sell. It is then matched as the
+// limit or market order it describes, at the point in the stream where the
+// triggering trade printed, and its executions are appended to that trade's
+// event (see domain.ExecutionEvent.Triggered). A stop whose trigger has
+// already been reached when it arrives is matched straight away.
+//
+// Parked stops are checked after every event that traded, oldest first,
+// against every price the event traded at: a taker sweeping several levels
+// reaches stops on the way even if its last trade prints back inside them. A
+// triggered stop's own trades move the price and can trigger more, so the
+// check repeats until the price stops moving anything.
+
+// stopTriggered reports whether the symbol's last trade price has reached
+// the order's stop price. A symbol that has not traded triggers nothing.
+func (e *Engine) stopTriggered(order *domain.Order) bool {
+	last := e.lastPrice[order.Symbol]
+	if last == 0 {
+		return false
+	}
+	return stopReached(order, last, last)
+}
+
+// stopReached reports whether trades between low and high, inclusive, reach
+// the order's stop price.
+func stopReached(order *domain.Order, low, high int64) bool {
+	if order.Side == domain.SideBuy {
+		return high >= order.StopPrice
+	}
+	return low <= order.StopPrice
+}
+
+// parkStop holds a stop order until it is triggered or canceled.
+func (e *Engine) parkStop(order *domain.Order) {
+	e.stops[order.Symbol] = append(e.stops[order.Symbol], order)
+}
+
+// parkedStop returns the position of a parked stop order, or -1.
+func (e *Engine) parkedStop(symbol, orderID string) int {
+	for i, order := range e.stops[symbol] {
+		if order.OrderID == orderID {
+			return i
+		}
+	}
+	return -1
+}
+
+// cancelStop cancels a parked stop order; nil if it is not parked.
+func (e *Engine) cancelStop(symbol, orderID string) *domain.Order {
+	i := e.parkedStop(symbol, orderID)
+	if i < 0 {
+		return nil
+	}
+	order := e.stops[symbol][i]
+	e.stops[symbol] = append(e.stops[symbol][:i], e.stops[symbol][i+1:]...)
+	order.Status = domain.OrderStatusCanceled
+	return order
+}
+
+// takeTriggered removes and returns the symbol's parked stops that trades
+// between low and high reach, oldest first.
+func (e *Engine) takeTriggered(symbol string, low, high int64) []*domain.Order {
+	var triggered []*domain.Order
+	parked := e.stops[symbol][:0]
+	for _, order := range e.stops[symbol] {
+		if stopReached(order, low, high) {
+			triggered = append(triggered, order)
+		} else {
+			parked = append(parked, order)
+		}
+	}
+	clear(e.stops[symbol][len(parked):])
+	e.stops[symbol] = parked
+	return triggered
+}
+
+// triggerStops records the last trade price of event and matches the stops
+// any of its trades triggers, appending their results to event.
+func (e *Engine) triggerStops(symbol string, event *domain.ExecutionEvent) {
+	book := e.getOrCreateBook(symbol)
+	for checked := 0; checked < len(event.Executions); {
+		batch := event.Executions[checked:]
+		checked = len(event.Executions)
+		low, high := batch[0].Price, batch[0].Price
+		for _, exec := range batch[1:] {
+			low, high = min(low, exec.Price), max(high, exec.Price)
+		}
+		e.lastPrice[symbol] = batch[len(batch)-1].Price
+
+		for _, order := range e.takeTriggered(symbol, low, high) {
+			result := e.activate(book, order)
+			event.Executions = append(event.Executions, result.Executions...)
+			event.MakerOrders = append(event.MakerOrders, result.MakerOrders...)
+			event.Triggered = append(event.Triggered, order)
+		}
+	}
+}
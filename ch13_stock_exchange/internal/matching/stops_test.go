@@ -0,0 +1,130 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func submit(engine *Engine, order *domain.Order) *domain.ExecutionEvent {
+	return engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: order})
+}
+
+func stopOrder(id string, side domain.Side, price, stop, qty int64) *domain.Order {
+	order := newOrder(id, "AAPL", side, price, qty)
+	order.StopPrice = stop
+	return order
+}
+
+func TestEngine_StopBuy_ActivatesAtTrigger(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("s1", "AAPL", domain.SideSell, 10000, 50))
+	submit(engine, newOrder("s2", "AAPL", domain.SideSell, 10050, 100))
+
+	stop := stopOrder("stop", domain.SideBuy, 10100, 10050, 80)
+	result := submit(engine, stop)
+	assert.Empty(t, result.Executions)
+	assert.Equal(t, domain.OrderStatusNew, stop.Status)
+	assert.Empty(t, engine.GetL2Snapshot("AAPL", 10).Bids, "a parked stop is not in the book")
+
+	// A trade below the trigger leaves it parked
+	result = submit(engine, newOrder("b1", "AAPL", domain.SideBuy, 10000, 50))
+	require.Len(t, result.Executions, 1)
+	assert.Empty(t, result.Triggered)
+
+	// A trade at the trigger activates it right behind the trade
+	result = submit(engine, newOrder("b2", "AAPL", domain.SideBuy, 10050, 10))
+	require.Len(t, result.Executions, 2)
+	assert.Equal(t, "b2", result.Executions[0].TakerOrderID)
+	assert.Equal(t, "stop", result.Executions[1].TakerOrderID)
+	assert.Equal(t, int64(10050), result.Executions[1].Price)
+	assert.Equal(t, int64(80), result.Executions[1].Quantity)
+	require.Len(t, result.Triggered, 1)
+	assert.Same(t, stop, result.Triggered[0])
+	assert.Equal(t, domain.OrderStatusFilled, stop.Status)
+}
+
+func TestEngine_StopSell_ActivatesAtOrBelowTrigger(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("b1", "AAPL", domain.SideBuy, 9950, 100))
+	stop := stopOrder("stop", domain.SideSell, 9900, 9960, 100)
+	stop.Type = domain.OrderTypeMarket
+	submit(engine, stop)
+
+	// A trade above the trigger leaves it parked
+	submit(engine, newOrder("b2", "AAPL", domain.SideBuy, 10000, 10))
+	result := submit(engine, newOrder("s1", "AAPL", domain.SideSell, 10000, 10))
+	require.Len(t, result.Executions, 1)
+	assert.Empty(t, result.Triggered)
+
+	result = submit(engine, newOrder("s2", "AAPL", domain.SideSell, 9950, 10))
+	require.Len(t, result.Triggered, 1)
+	require.Len(t, result.Executions, 2)
+	assert.Equal(t, int64(90), result.Executions[1].Quantity)
+	assert.Equal(t, domain.OrderStatusCanceled, stop.Status, "the stop-market remainder is canceled")
+	assert.Equal(t, int64(90), stop.FilledQuantity)
+}
+
+func TestEngine_Stop_TriggeredByAnyPriceOfASweep(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("bid", "AAPL", domain.SideBuy, 9000, 50))
+	submit(engine, newOrder("s1", "AAPL", domain.SideSell, 9900, 10))
+	submit(engine, newOrder("s2", "AAPL", domain.SideSell, 10100, 10))
+	stop := stopOrder("stop", domain.SideSell, 9000, 9950, 5)
+	submit(engine, stop)
+
+	// The sweep prints 9900 on its way to a last price of 10100
+	result := submit(engine, newOrder("b1", "AAPL", domain.SideBuy, 10100, 20))
+	require.Len(t, result.Triggered, 1)
+	assert.Same(t, stop, result.Triggered[0])
+	require.Len(t, result.Executions, 3)
+	assert.Equal(t, "stop", result.Executions[2].TakerOrderID)
+	assert.Equal(t, int64(9000), result.Executions[2].Price)
+}
+
+func TestEngine_Stop_Cascade(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("s1", "AAPL", domain.SideSell, 10000, 10))
+	submit(engine, newOrder("s2", "AAPL", domain.SideSell, 10010, 10))
+	submit(engine, newOrder("s3", "AAPL", domain.SideSell, 10020, 10))
+	submit(engine, stopOrder("stop2", domain.SideBuy, 10020, 10010, 10))
+	submit(engine, stopOrder("stop1", domain.SideBuy, 10010, 10000, 10))
+
+	// The first trade triggers stop1, whose trade at 10010 triggers stop2
+	result := submit(engine, newOrder("b1", "AAPL", domain.SideBuy, 10000, 10))
+	require.Len(t, result.Executions, 3)
+	assert.Equal(t, []string{"b1", "stop1", "stop2"}, []string{
+		result.Executions[0].TakerOrderID, result.Executions[1].TakerOrderID, result.Executions[2].TakerOrderID,
+	})
+	assert.Len(t, result.Triggered, 2)
+}
+
+func TestEngine_Stop_AlreadyTriggeredMatchesOnArrival(t *testing.T) {
+	engine := NewEngine()
+	submit(engine, newOrder("s1", "AAPL", domain.SideSell, 10000, 20))
+	submit(engine, newOrder("b1", "AAPL", domain.SideBuy, 10000, 10))
+
+	result := submit(engine, stopOrder("stop", domain.SideBuy, 10000, 9990, 10))
+	require.Len(t, result.Executions, 1)
+	assert.Equal(t, "stop", result.Executions[0].TakerOrderID)
+}
+
+func TestEngine_Stop_CancelAndDuplicate(t *testing.T) {
+	engine := NewEngine()
+	stop := stopOrder("stop", domain.SideBuy, 10100, 10050, 80)
+	submit(engine, stop)
+
+	dup := submit(engine, newOrder("stop", "AAPL", domain.SideBuy, 10000, 10))
+	require.NotNil(t, dup.Rejected)
+
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionCancel, Order: stop})
+	assert.Equal(t, domain.OrderStatusCanceled, result.TakerOrder.Status)
+
+	// Canceled stops never trigger
+	submit(engine, newOrder("s1", "AAPL", domain.SideSell, 10100, 10))
+	result = submit(engine, newOrder("b1", "AAPL", domain.SideBuy, 10100, 10))
+	assert.Empty(t, result.Triggered)
+	assert.Empty(t, engine.stops["AAPL"])
+}
@@ -0,0 +1,51 @@
+package matching
+
+import (
+	"log"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/nathanyu/stock-exchange/internal/orderbook"
+)
+
+// LayeringCap is a simple layering/spoofing heuristic: one user's resting
+// quantity at a single price level should stay below MaxLevelQty.
+type LayeringCap struct {
+	// MaxLevelQty is the most one user may display at one price (0 = off)
+	MaxLevelQty int64
+	// Reject cancels a breaching order's remainder instead of resting it;
+	// otherwise the breach is only flagged
+	Reject bool
+}
+
+// SetLayeringCap enables per-user price level surveillance. Breaches are
+// counted in exchange_layering_flags_total and logged either way.
+func (e *Engine) SetLayeringCap(c LayeringCap) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.layering = c
+}
+
+// breachesLayeringCap checks whether resting the order would push its user's
+// size at that level over the cap. It reports true only when the breach
+// should be rejected.
+func (e *Engine) breachesLayeringCap(book *orderbook.OrderBook, order *domain.Order) bool {
+	if e.layering.MaxLevelQty <= 0 || order.UserID == "" {
+		return false
+	}
+
+	displayed := book.UserVolumeAt(order.Side, order.Price, order.UserID) + order.RemainingQuantity
+	if displayed <= e.layering.MaxLevelQty {
+		return false
+	}
+
+	action := "flagged"
+	if e.layering.Reject {
+		action = "rejected"
+	}
+	middleware.LayeringFlags.WithLabelValues(order.Symbol, action).Inc()
+	log.Printf("[matching] WARN: order %s would rest %d for user %s at %s %d, over the layering cap of %d (%s)",
+		order.OrderID, displayed, order.UserID, order.Symbol, order.Price, e.layering.MaxLevelQty, action)
+
+	return e.layering.Reject
+}
@@ -0,0 +1,88 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func userOrder(id, user string, side domain.Side, price, qty int64) *domain.Order {
+	o := newOrder(id, "LAYR", side, price, qty)
+	o.UserID = user
+	return o
+}
+
+func TestEngine_LayeringCapFlagsOnly(t *testing.T) {
+	engine := NewEngine()
+	engine.SetLayeringCap(LayeringCap{MaxLevelQty: 500})
+	flagged := testutil.ToFloat64(middleware.LayeringFlags.WithLabelValues("LAYR", "flagged"))
+
+	// Within the cap across two orders
+	for _, o := range []*domain.Order{
+		userOrder("a1", "alice", domain.SideBuy, 10000, 300),
+		userOrder("a2", "alice", domain.SideBuy, 10000, 200),
+	} {
+		result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: o})
+		assert.Empty(t, result.RejectReason)
+	}
+	assert.Equal(t, flagged, testutil.ToFloat64(middleware.LayeringFlags.WithLabelValues("LAYR", "flagged")))
+
+	// Over the cap: counted, but still rests
+	over := userOrder("a3", "alice", domain.SideBuy, 10000, 1)
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: over})
+	assert.Empty(t, result.RejectReason)
+	assert.Equal(t, domain.OrderStatusNew, over.Status)
+	assert.Equal(t, flagged+1, testutil.ToFloat64(middleware.LayeringFlags.WithLabelValues("LAYR", "flagged")))
+
+	snap := engine.GetL2Snapshot("LAYR", 5)
+	require.Len(t, snap.Bids, 1)
+	assert.Equal(t, int64(501), snap.Bids[0].Quantity)
+}
+
+func TestEngine_LayeringCapRejects(t *testing.T) {
+	engine := NewEngine()
+	engine.SetLayeringCap(LayeringCap{MaxLevelQty: 500, Reject: true})
+	rejected := testutil.ToFloat64(middleware.LayeringFlags.WithLabelValues("LAYR", "rejected"))
+
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: userOrder("a1", "alice", domain.SideBuy, 10000, 400)})
+
+	over := userOrder("a2", "alice", domain.SideBuy, 10000, 200)
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: over})
+	require.NotNil(t, result)
+	assert.Equal(t, "layering cap exceeded", result.RejectReason)
+	assert.Equal(t, over, result.TakerOrder)
+	assert.Equal(t, domain.OrderStatusCanceled, over.Status)
+	assert.Nil(t, result.Rejected)
+	assert.Equal(t, rejected+1, testutil.ToFloat64(middleware.LayeringFlags.WithLabelValues("LAYR", "rejected")))
+
+	// Other users at the same level and alice at another level are unaffected
+	bob := userOrder("b1", "bob", domain.SideBuy, 10000, 500)
+	result = engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: bob})
+	assert.Empty(t, result.RejectReason)
+	result = engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: userOrder("a3", "alice", domain.SideBuy, 9900, 500)})
+	assert.Empty(t, result.RejectReason)
+
+	snap := engine.GetL2Snapshot("LAYR", 5)
+	require.Len(t, snap.Bids, 2)
+	assert.Equal(t, domain.PriceLevel{Price: 10000, Quantity: 900}, snap.Bids[0])
+	assert.Equal(t, domain.PriceLevel{Price: 9900, Quantity: 500}, snap.Bids[1])
+}
+
+func TestEngine_LayeringCapCountsOnlyRestingRemainder(t *testing.T) {
+	engine := NewEngine()
+	engine.SetLayeringCap(LayeringCap{MaxLevelQty: 100, Reject: true})
+
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: userOrder("s1", "bob", domain.SideSell, 10000, 100)})
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: userOrder("s2", "carol", domain.SideSell, 10000, 100)})
+
+	// 250 arrives but only 50 is left to rest after filling against both
+	taker := userOrder("a1", "alice", domain.SideBuy, 10000, 250)
+	result := engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionNew, Order: taker})
+	require.Len(t, result.Executions, 2)
+	assert.Empty(t, result.RejectReason)
+	assert.Equal(t, int64(50), taker.RemainingQuantity)
+}
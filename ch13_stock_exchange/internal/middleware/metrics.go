@@ -20,11 +20,21 @@ var (
 		[]string{"method", "path", "status"},
 	)
 
-	// OrdersTotal counts orders by action.
+	// OrdersTotal counts orders handed to the sequencer by action.
 	OrdersTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "exchange_orders_total",
-			Help: "Total number of orders by action",
+			Help: "Total number of orders sent to the sequencer by action",
+		},
+		[]string{"action", "symbol"},
+	)
+
+	// OrdersDropped counts order events dropped because the order intake was
+	// full; they never reached the sequencer.
+	OrdersDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_orders_dropped_total",
+			Help: "Total number of order events dropped by a full order intake",
 		},
 		[]string{"action", "symbol"},
 	)
@@ -47,6 +57,92 @@ var (
 		[]string{"symbol", "side"},
 	)
 
+	// SpreadCents tracks the best ask minus the best bid after each match.
+	SpreadCents = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "exchange_spread_cents",
+			Help: "Best ask minus best bid in cents, updated on each match",
+		},
+		[]string{"symbol"},
+	)
+
+	// ExecutionAuditViolations counts executions that failed the price sanity audit.
+	ExecutionAuditViolations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_execution_audit_violations_total",
+			Help: "Total number of executions failing the matching price audit",
+		},
+		[]string{"symbol", "check"},
+	)
+
+	// DuplicateOrdersRejected counts new orders refused for reusing a resting order's ID.
+	DuplicateOrdersRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_duplicate_orders_rejected_total",
+			Help: "Total number of new orders refused because their ID already rests in the book",
+		},
+		[]string{"symbol"},
+	)
+
+	// LayeringFlags counts orders that would push one user's resting size at a
+	// price level over the layering cap; action is flagged or rejected.
+	LayeringFlags = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_layering_flags_total",
+			Help: "Total number of orders breaching the per-user price level size cap",
+		},
+		[]string{"symbol", "action"},
+	)
+
+	// OrdersExpired counts good-till-date orders the expiry reaper canceled.
+	OrdersExpired = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_orders_expired_total",
+			Help: "Total number of good-till-date orders canceled on expiry",
+		},
+		[]string{"symbol"},
+	)
+
+	// FillRatio records the fraction of each taker order's quantity that
+	// executed on arrival; outcome is full, partial or none. symbol is one of
+	// the configured FILL_RATIO_SYMBOLS or "other".
+	FillRatio = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "exchange_fill_ratio",
+			Help:    "Filled over submitted quantity of each incoming order at match time",
+			Buckets: []float64{0, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 1},
+		},
+		[]string{"symbol", "outcome"},
+	)
+
+	// ExecutionEventsDropped counts execution events a best-effort consumer had no room for.
+	ExecutionEventsDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_execution_events_dropped_total",
+			Help: "Total number of execution events dropped by downstream consumer",
+		},
+		[]string{"consumer"},
+	)
+
+	// StreamSubscribersDropped counts market data stream subscribers dropped
+	// for falling behind.
+	StreamSubscribersDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_stream_subscribers_dropped_total",
+			Help: "Total number of market data stream subscribers dropped for a full send buffer",
+		},
+		[]string{"symbol"},
+	)
+
+	// MarketDataSequenceGaps counts gaps in the execution sequence IDs the
+	// market data publisher received.
+	MarketDataSequenceGaps = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "marketdata_sequence_gaps_total",
+			Help: "Total number of gaps in execution sequence IDs received by market data",
+		},
+	)
+
 	// SequencerInboundSeq tracks the current inbound sequence number.
 	SequencerInboundSeq = promauto.NewGauge(
 		prometheus.GaugeOpts{
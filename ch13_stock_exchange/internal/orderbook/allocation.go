@@ -0,0 +1,140 @@
+package orderbook
+
+import (
+	"container/list"
+	"fmt"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// AllocationPolicy decides how a taker's quantity is shared among the resting
+// orders at one price level. Whatever the policy, price levels are still
+// walked best first.
+type AllocationPolicy string
+
+const (
+	// AllocationFIFO (the default) fills orders in time priority.
+	AllocationFIFO AllocationPolicy = "fifo"
+	// AllocationProRata shares the fill in proportion to each order's
+	// remaining quantity.
+	AllocationProRata AllocationPolicy = "pro_rata"
+	// AllocationHybrid first gives the level's oldest order its guaranteed
+	// share (Options.TopAllocationBps) and shares the rest pro-rata among the
+	// other orders, as some futures exchanges do.
+	AllocationHybrid AllocationPolicy = "hybrid"
+)
+
+// ParseAllocationPolicy parses an allocation policy name.
+func ParseAllocationPolicy(s string) (AllocationPolicy, error) {
+	switch p := AllocationPolicy(s); p {
+	case AllocationFIFO, AllocationProRata, AllocationHybrid:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown allocation policy %q (want %q, %q or %q)", s, AllocationFIFO, AllocationProRata, AllocationHybrid)
+}
+
+// SetAllocation changes how the book shares fills within a price level from
+// the next match on; topBps is the first order's guaranteed share under
+// AllocationHybrid. Resting orders keep their time priority.
+func (ob *OrderBook) SetAllocation(policy AllocationPolicy, topBps int64) {
+	ob.allocation = policy
+	ob.topBps = topBps
+}
+
+// levelFill is one maker's share of a taker at a level.
+type levelFill struct {
+	maker *domain.Order
+	elem  *list.Element
+	qty   int64
+}
+
+// allocatable reports whether the level is shared by the configured
+// allocation policy. Proportional slices ignore minimum execution quantities,
+// so a level where either side has one falls back to FIFO.
+func (ob *OrderBook) allocatable(taker *domain.Order, level *bookLevel) bool {
+	if ob.allocation != AllocationProRata && ob.allocation != AllocationHybrid {
+		return false
+	}
+	if taker.MinExecQty > 0 {
+		return false
+	}
+	for e := level.Orders.Front(); e != nil; e = e.Next() {
+		if e.Value.(*domain.Order).MinExecQty > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// allocateLevel works out each maker's fill at the level, in time priority.
+// Makers allocated nothing are left out.
+func (ob *OrderBook) allocateLevel(taker *domain.Order, level *bookLevel) []levelFill {
+	elems := make([]*list.Element, 0, level.Orders.Len())
+	sizes := make([]int64, 0, level.Orders.Len())
+	for e := level.Orders.Front(); e != nil; e = e.Next() {
+		elems = append(elems, e)
+		sizes = append(sizes, e.Value.(*domain.Order).RemainingQuantity)
+	}
+
+	topBps := int64(0)
+	if ob.allocation == AllocationHybrid {
+		topBps = ob.topBps
+	}
+
+	var fills []levelFill
+	for i, qty := range allocate(taker.RemainingQuantity, sizes, topBps) {
+		if qty > 0 {
+			fills = append(fills, levelFill{maker: elems[i].Value.(*domain.Order), elem: elems[i], qty: qty})
+		}
+	}
+	return fills
+}
+
+// allocate splits qty across orders of the given sizes (oldest first). With
+// topBps > 0 the first order is guaranteed that share of qty and the rest is
+// split pro-rata among the others; with topBps == 0 everything is pro-rata.
+// Shares are rounded down and the leftover lots handed out one order at a
+// time in time priority, so the result is deterministic and never exceeds an
+// order's size.
+func allocate(qty int64, sizes []int64, topBps int64) []int64 {
+	alloc := make([]int64, len(sizes))
+
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+	if qty >= total {
+		copy(alloc, sizes)
+		return alloc
+	}
+
+	rest := sizes
+	offset := 0
+	remaining := qty
+	if topBps > 0 && len(sizes) > 0 {
+		alloc[0] = min(sizes[0], qty*topBps/10000)
+		remaining -= alloc[0]
+		rest, offset = sizes[1:], 1
+	}
+
+	var restTotal int64
+	for _, size := range rest {
+		restTotal += size
+	}
+	if restTotal > 0 {
+		share := min(remaining, restTotal)
+		for i, size := range rest {
+			alloc[offset+i] = share * size / restTotal
+			remaining -= alloc[offset+i]
+		}
+	}
+
+	// Rounding leftovers (and, under hybrid, whatever the other orders could
+	// not absorb) go in time priority
+	for i := 0; remaining > 0 && i < len(sizes); i++ {
+		extra := min(remaining, sizes[i]-alloc[i])
+		alloc[i] += extra
+		remaining -= extra
+	}
+	return alloc
+}
@@ -0,0 +1,147 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// matchLevel rests sells of 100, 200 and 300 at one price and sweeps them
+// with a 300 buy, returning the quantity each maker received
+func matchLevel(t *testing.T, opts Options) map[string]int64 {
+	t.Helper()
+	ob := NewOrderBookWithOptions("AAPL", opts)
+	ob.AddOrder(newOrder("m1", domain.SideSell, 10000, 100))
+	ob.AddOrder(newOrder("m2", domain.SideSell, 10000, 200))
+	ob.AddOrder(newOrder("m3", domain.SideSell, 10000, 300))
+
+	taker := newOrder("t1", domain.SideBuy, 10000, 300)
+	executions := ob.MatchOrder(taker)
+	assert.Equal(t, domain.OrderStatusFilled, taker.Status)
+
+	fills := make(map[string]int64)
+	var total int64
+	for _, exec := range executions {
+		fills[exec.MakerOrderID] += exec.Quantity
+		total += exec.Quantity
+	}
+	assert.Equal(t, int64(300), total)
+	assert.Equal(t, int64(300), ob.GetL2Snapshot(1).Asks[0].Quantity)
+	return fills
+}
+
+func TestAllocation_FIFO(t *testing.T) {
+	fills := matchLevel(t, Options{})
+	assert.Equal(t, map[string]int64{"m1": 100, "m2": 200}, fills)
+}
+
+func TestAllocation_ProRata(t *testing.T) {
+	fills := matchLevel(t, Options{Allocation: AllocationProRata})
+	assert.Equal(t, map[string]int64{"m1": 50, "m2": 100, "m3": 150}, fills)
+}
+
+func TestAllocation_Hybrid(t *testing.T) {
+	// m1 is guaranteed 20% (60); the other 240 is shared 200:300 by m2 and m3
+	fills := matchLevel(t, Options{Allocation: AllocationHybrid, TopAllocationBps: 2000})
+	assert.Equal(t, map[string]int64{"m1": 60, "m2": 96, "m3": 144}, fills)
+}
+
+func TestAllocation_ExecutionsInTimePriority(t *testing.T) {
+	ob := NewOrderBookWithOptions("AAPL", Options{Allocation: AllocationHybrid, TopAllocationBps: 2000})
+	ob.AddOrder(newOrder("m1", domain.SideSell, 10000, 100))
+	ob.AddOrder(newOrder("m2", domain.SideSell, 10000, 200))
+	ob.AddOrder(newOrder("m3", domain.SideSell, 10000, 300))
+
+	executions := ob.MatchOrder(newOrder("t1", domain.SideBuy, 10000, 300))
+	require.Len(t, executions, 3)
+	for i, id := range []string{"m1", "m2", "m3"} {
+		assert.Equal(t, id, executions[i].MakerOrderID)
+	}
+}
+
+func TestAllocation_SweepsWholeLevelThenNext(t *testing.T) {
+	ob := NewOrderBookWithOptions("AAPL", Options{Allocation: AllocationProRata})
+	ob.AddOrder(newOrder("m1", domain.SideSell, 10000, 100))
+	ob.AddOrder(newOrder("m2", domain.SideSell, 10000, 100))
+	ob.AddOrder(newOrder("m3", domain.SideSell, 10100, 100))
+	ob.AddOrder(newOrder("m4", domain.SideSell, 10100, 300))
+
+	executions := ob.MatchOrder(newOrder("t1", domain.SideBuy, 10100, 300))
+	fills := make(map[string]int64)
+	for _, exec := range executions {
+		fills[exec.MakerOrderID] += exec.Quantity
+	}
+	// The first level is taken in full; the 100 left is shared 1:3 at the next
+	assert.Equal(t, map[string]int64{"m1": 100, "m2": 100, "m3": 25, "m4": 75}, fills)
+	assert.False(t, ob.HasOrder("m1"))
+	assert.True(t, ob.HasOrder("m3"))
+}
+
+func TestAllocation_MinExecQtyFallsBackToFIFO(t *testing.T) {
+	ob := NewOrderBookWithOptions("AAPL", Options{Allocation: AllocationProRata})
+	ob.AddOrder(newOrder("m1", domain.SideSell, 10000, 100))
+	m2 := newOrder("m2", domain.SideSell, 10000, 100)
+	m2.MinExecQty = 100
+	ob.AddOrder(m2)
+
+	executions := ob.MatchOrder(newOrder("t1", domain.SideBuy, 10000, 100))
+	require.Len(t, executions, 1)
+	assert.Equal(t, "m1", executions[0].MakerOrderID)
+}
+
+func TestAllocate_Rounding(t *testing.T) {
+	// Each gets 2 rounded down; the leftover lot goes to the oldest
+	assert.Equal(t, []int64{3, 2, 2}, allocate(7, []int64{3, 3, 3}, 0))
+	// What the others cannot absorb returns to the top order
+	assert.Equal(t, []int64{80, 10}, allocate(90, []int64{100, 10}, 5000))
+	// The guaranteed share never exceeds the top order's size
+	assert.Equal(t, []int64{10, 45, 45}, allocate(100, []int64{10, 100, 100}, 5000))
+}
+
+func TestAllocate_SumsToTakerQuantity(t *testing.T) {
+	sizes := []int64{7, 13, 1, 29, 50}
+	for _, topBps := range []int64{0, 2500} {
+		for qty := int64(1); qty <= 100; qty++ {
+			alloc := allocate(qty, sizes, topBps)
+			var sum int64
+			for i, a := range alloc {
+				assert.LessOrEqual(t, a, sizes[i])
+				sum += a
+			}
+			assert.Equal(t, qty, sum, "qty %d, top %d bps", qty, topBps)
+			assert.Equal(t, alloc, allocate(qty, sizes, topBps), "same input, same split")
+		}
+	}
+}
+
+func TestAllocate_RemaindersGoToOldestFirst(t *testing.T) {
+	// Nobody's share reaches a whole lot, so the oldest order takes all it can
+	assert.Equal(t, []int64{3, 0, 0, 0}, allocate(3, []int64{5, 5, 5, 5}, 0))
+	// 1 and 2 rounded down; the leftover goes to the older order even
+	// though the newer one is larger
+	assert.Equal(t, []int64{2, 2}, allocate(4, []int64{2, 5}, 0))
+}
+
+func TestOrderBook_SetAllocation(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(newOrder("m1", domain.SideSell, 10000, 100))
+	ob.AddOrder(newOrder("m2", domain.SideSell, 10000, 300))
+
+	// Orders already resting are shared under the new policy
+	ob.SetAllocation(AllocationProRata, 0)
+	executions := ob.MatchOrder(newOrder("t1", domain.SideBuy, 10000, 100))
+	require.Len(t, executions, 2)
+	assert.Equal(t, int64(25), executions[0].Quantity)
+	assert.Equal(t, int64(75), executions[1].Quantity)
+}
+
+func TestParseAllocationPolicy(t *testing.T) {
+	p, err := ParseAllocationPolicy("hybrid")
+	require.NoError(t, err)
+	assert.Equal(t, AllocationHybrid, p)
+
+	_, err = ParseAllocationPolicy("random")
+	assert.Error(t, err)
+}
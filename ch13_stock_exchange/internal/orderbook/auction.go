@@ -0,0 +1,179 @@
+package orderbook
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// ClearingPrice computes the auction clearing price of the book: the price at
+// which the most volume can trade, given every bid at or above it and every
+// ask at or below it. Ties go to the price leaving the smallest imbalance
+// between the two sides, then to the highest tied price with buyers in
+// surplus, else the lowest tied price. ok is false when the book does not
+// cross.
+//
+// Only resting limit prices need to be considered: between two adjacent
+// prices the matched volume can be no larger than at the lower one.
+func (ob *OrderBook) ClearingPrice() (price, volume int64, ok bool) {
+	seen := make(map[int64]bool, len(ob.BuyBook.LimitMap)+len(ob.SellBook.LimitMap))
+	candidates := make([]int64, 0, len(seen))
+	for _, side := range []*Book{ob.BuyBook, ob.SellBook} {
+		for p := range side.LimitMap {
+			if !seen[p] {
+				seen[p] = true
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	var bestImbalance int64
+	for _, p := range candidates {
+		demand, supply := ob.auctionVolumes(p)
+		matched := min(demand, supply)
+		if matched == 0 {
+			continue
+		}
+		imbalance := demand - supply
+		buySurplus := imbalance > 0
+		if imbalance < 0 {
+			imbalance = -imbalance
+		}
+
+		// Candidates ascend, so a tie only moves the price up under buy pressure
+		better := !ok || matched > volume ||
+			(matched == volume && (imbalance < bestImbalance || (imbalance == bestImbalance && buySurplus)))
+		if better {
+			price, volume, ok = p, matched, true
+			bestImbalance = imbalance
+		}
+	}
+	return price, volume, ok
+}
+
+// auctionVolumes returns the bid volume willing to buy at price and the ask
+// volume willing to sell at price.
+func (ob *OrderBook) auctionVolumes(price int64) (demand, supply int64) {
+	for p, level := range ob.BuyBook.LimitMap {
+		if p >= price {
+			demand += level.TotalVolume
+		}
+	}
+	for p, level := range ob.SellBook.LimitMap {
+		if p <= price {
+			supply += level.TotalVolume
+		}
+	}
+	return demand, supply
+}
+
+// Uncross executes every crossable order at the clearing price in a single
+// pass. Bids and asks are allocated in price-time priority; all executions
+// carry the same uniform price. Within each matched pair, the later order
+// (higher sequence ID) is reported as the taker. Execution IDs are prefixed
+// with idPrefix. Minimum execution quantities are not applied.
+//
+// Returns the executions and every order that received a fill, buys first.
+// The book is left uncrossed.
+func (ob *OrderBook) Uncross(idPrefix string) (price int64, executions []*domain.Execution, filled []*domain.Order) {
+	price, _, ok := ob.ClearingPrice()
+	if !ok {
+		return 0, nil, nil
+	}
+
+	buys := ob.BuyBook.ordersAtOrBetter(price)
+	sells := ob.SellBook.ordersAtOrBetter(price)
+
+	touched := make(map[string]bool)
+	var filledBuys, filledSells []*domain.Order
+	i, j := 0, 0
+	for i < len(buys) && j < len(sells) {
+		buy, sell := buys[i], sells[j]
+		qty := min(buy.RemainingQuantity, sell.RemainingQuantity)
+
+		ob.fillResting(ob.BuyBook, buy, qty)
+		ob.fillResting(ob.SellBook, sell, qty)
+
+		taker, maker := buy, sell
+		if sell.SequenceID > buy.SequenceID {
+			taker, maker = sell, buy
+		}
+		executions = append(executions, &domain.Execution{
+			ExecID:       fmt.Sprintf("%s-exec-%d", idPrefix, len(executions)+1),
+			OrderID:      taker.OrderID,
+			Symbol:       ob.Symbol,
+			Side:         taker.Side,
+			Price:        price,
+			Quantity:     qty,
+			MakerOrderID: maker.OrderID,
+			TakerOrderID: taker.OrderID,
+		})
+
+		if !touched[buy.OrderID] {
+			touched[buy.OrderID] = true
+			filledBuys = append(filledBuys, buy)
+		}
+		if !touched[sell.OrderID] {
+			touched[sell.OrderID] = true
+			filledSells = append(filledSells, sell)
+		}
+		if buy.RemainingQuantity == 0 {
+			i++
+		}
+		if sell.RemainingQuantity == 0 {
+			j++
+		}
+	}
+
+	ob.BuyBook.refreshBestPrice()
+	ob.SellBook.refreshBestPrice()
+	return price, executions, append(filledBuys, filledSells...)
+}
+
+// ordersAtOrBetter returns the resting orders on this side that would trade
+// at price, in price-time priority.
+func (b *Book) ordersAtOrBetter(price int64) []*domain.Order {
+	prices := make([]int64, 0, len(b.LimitMap))
+	for p := range b.LimitMap {
+		if (b.Side == domain.SideBuy && p >= price) || (b.Side == domain.SideSell && p <= price) {
+			prices = append(prices, p)
+		}
+	}
+	if b.Side == domain.SideBuy {
+		sort.Slice(prices, func(i, j int) bool { return prices[i] > prices[j] })
+	} else {
+		sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+	}
+
+	var orders []*domain.Order
+	for _, p := range prices {
+		for elem := b.LimitMap[p].Orders.Front(); elem != nil; elem = elem.Next() {
+			orders = append(orders, elem.Value.(*domain.Order))
+		}
+	}
+	return orders
+}
+
+// fillResting fills qty of a resting order, removing it from the book once it
+// is complete. The caller refreshes the best price afterwards.
+func (ob *OrderBook) fillResting(book *Book, order *domain.Order, qty int64) {
+	entry := ob.OrderMap[order.OrderID]
+	level := entry.level
+
+	order.FilledQuantity += qty
+	order.RemainingQuantity -= qty
+	level.TotalVolume -= qty
+
+	if order.RemainingQuantity > 0 {
+		order.Status = domain.OrderStatusPartiallyFilled
+		return
+	}
+	order.Status = domain.OrderStatusFilled
+	level.Orders.Remove(entry.element)
+	ob.dropEntry(order.OrderID) // entry must not be used after this
+	if level.Orders.Len() == 0 {
+		book.removeLevel(level)
+	}
+}
@@ -0,0 +1,105 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crossedBook rests a crossed set of bids and asks, as an auction collects them.
+func crossedBook(opts Options) *OrderBook {
+	ob := NewOrderBookWithOptions("AAPL", opts)
+	orders := []*domain.Order{
+		newOrder("b1", domain.SideBuy, 10100, 100),
+		newOrder("b2", domain.SideBuy, 10000, 200),
+		newOrder("b3", domain.SideBuy, 9900, 300),
+		newOrder("s1", domain.SideSell, 9800, 150),
+		newOrder("s2", domain.SideSell, 10000, 150),
+		newOrder("s3", domain.SideSell, 10200, 200),
+	}
+	for i, o := range orders {
+		o.SequenceID = uint64(i + 1)
+		ob.AddOrder(o)
+	}
+	return ob
+}
+
+func TestClearingPrice_MaximizesMatchedVolume(t *testing.T) {
+	ob := crossedBook(Options{})
+
+	price, volume, ok := ob.ClearingPrice()
+	require.True(t, ok)
+	assert.Equal(t, int64(10000), price)
+	assert.Equal(t, int64(300), volume)
+
+	// No price in or around the book trades more
+	for p := int64(9700); p <= 10300; p++ {
+		demand, supply := ob.auctionVolumes(p)
+		assert.LessOrEqual(t, min(demand, supply), volume, "price %d", p)
+	}
+}
+
+func TestClearingPrice_TieBreaks(t *testing.T) {
+	// 100 trades anywhere from 9900 to 10000; the smallest imbalance is at 10000
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(newOrder("b1", domain.SideBuy, 10000, 100))
+	ob.AddOrder(newOrder("b2", domain.SideBuy, 9900, 50))
+	ob.AddOrder(newOrder("s1", domain.SideSell, 9900, 100))
+
+	price, volume, ok := ob.ClearingPrice()
+	require.True(t, ok)
+	assert.Equal(t, int64(10000), price)
+	assert.Equal(t, int64(100), volume)
+
+	// Uncrossed book: nothing to clear
+	flat := NewOrderBook("AAPL")
+	flat.AddOrder(newOrder("b1", domain.SideBuy, 9900, 100))
+	flat.AddOrder(newOrder("s1", domain.SideSell, 10000, 100))
+	_, _, ok = flat.ClearingPrice()
+	assert.False(t, ok)
+}
+
+func TestUncross_FillsAtSinglePrice(t *testing.T) {
+	for _, pooled := range []bool{false, true} {
+		ob := crossedBook(Options{Pooling: pooled})
+
+		price, executions, filled := ob.Uncross("AAPL-auction-7")
+		assert.Equal(t, int64(10000), price)
+		require.Len(t, executions, 3)
+
+		var total int64
+		for _, exec := range executions {
+			assert.Equal(t, price, exec.Price, "every fill is at the clearing price")
+			total += exec.Quantity
+		}
+		assert.Equal(t, int64(300), total)
+
+		// Price-time priority on both sides; the later order of a pair is the taker
+		assert.Equal(t, "AAPL-auction-7-exec-1", executions[0].ExecID)
+		assert.Equal(t, "s1", executions[0].TakerOrderID)
+		assert.Equal(t, "b1", executions[0].MakerOrderID)
+		assert.Equal(t, int64(100), executions[0].Quantity)
+		assert.Equal(t, "b2", executions[1].MakerOrderID)
+		assert.Equal(t, int64(50), executions[1].Quantity)
+		assert.Equal(t, "s2", executions[2].TakerOrderID)
+		assert.Equal(t, int64(150), executions[2].Quantity)
+
+		ids := make([]string, len(filled))
+		for i, o := range filled {
+			ids[i] = o.OrderID
+			assert.Equal(t, domain.OrderStatusFilled, o.Status)
+			assert.Zero(t, o.RemainingQuantity)
+		}
+		assert.Equal(t, []string{"b1", "b2", "s1", "s2"}, ids)
+
+		// The unfilled orders are left on an uncrossed book
+		snap := ob.GetL2Snapshot(5)
+		assert.Equal(t, []domain.PriceLevel{{Price: 9900, Quantity: 300}}, snap.Bids)
+		assert.Equal(t, []domain.PriceLevel{{Price: 10200, Quantity: 200}}, snap.Asks)
+		assert.Len(t, ob.OrderMap, 2)
+		_, _, ok := ob.ClearingPrice()
+		assert.False(t, ok)
+	}
+}
@@ -21,14 +21,17 @@ type bookLevel struct {
 	Price       int64
 	TotalVolume int64
 	Orders      *list.List // of *domain.Order
+	heapIndex   int        // position in Book.prices (see pricelevels.go)
 }
 
 // Book represents one side (buy or sell) of an order book.
 type Book struct {
 	Side      domain.Side
 	LimitMap  map[int64]*bookLevel // price -> level
+	prices    *priceHeap           // the same levels, best price first
 	bestPrice int64                // best bid (highest buy) or best ask (lowest sell)
 	hasOrders bool
+	pooled    bool // recycle bookLevels (see pool.go)
 }
 
 // NewBook creates a new order book side.
@@ -36,6 +39,7 @@ func NewBook(side domain.Side) *Book {
 	return &Book{
 		Side:     side,
 		LimitMap: make(map[int64]*bookLevel),
+		prices:   newPriceHeap(side),
 	}
 }
 
@@ -56,11 +60,7 @@ func (b *Book) HasOrders() bool {
 func (b *Book) addOrder(order *domain.Order) *list.Element {
 	level, exists := b.LimitMap[order.Price]
 	if !exists {
-		level = &bookLevel{
-			Price:  order.Price,
-			Orders: list.New(),
-		}
-		b.LimitMap[order.Price] = level
+		level = b.addLevel(order.Price)
 	}
 
 	level.TotalVolume += order.RemainingQuantity
@@ -77,40 +77,22 @@ func (b *Book) removeOrder(entry *orderEntry) {
 	level.TotalVolume -= entry.order.RemainingQuantity
 
 	if level.Orders.Len() == 0 {
-		delete(b.LimitMap, level.Price)
+		b.removeLevel(level)
 	}
 
 	b.refreshBestPrice()
 }
 
-// refreshBestPrice recalculates the best price.
+// refreshBestPrice caches the best price from the top of the price heap.
 func (b *Book) refreshBestPrice() {
-	if len(b.LimitMap) == 0 {
+	top := b.prices.top()
+	if top == nil {
 		b.hasOrders = false
 		b.bestPrice = 0
 		return
 	}
-
 	b.hasOrders = true
-	if b.Side == domain.SideBuy {
-		// Best bid = highest price
-		best := int64(0)
-		for price := range b.LimitMap {
-			if price > best {
-				best = price
-			}
-		}
-		b.bestPrice = best
-	} else {
-		// Best ask = lowest price
-		best := int64(1<<62 - 1)
-		for price := range b.LimitMap {
-			if price < best {
-				best = price
-			}
-		}
-		b.bestPrice = best
-	}
+	b.bestPrice = top.Price
 }
 
 // OrderBook holds the full two-sided order book for a single symbol.
@@ -119,6 +101,10 @@ type OrderBook struct {
 	BuyBook  *Book
 	SellBook *Book
 	OrderMap map[string]*orderEntry // orderID -> entry for O(1) lookup/cancel
+	pooled   bool                   // recycle orderEntries (see pool.go)
+
+	allocation AllocationPolicy // how a level's fills are shared (see allocation.go)
+	topBps     int64            // hybrid: share reserved for the level's first order
 }
 
 // NewOrderBook creates a new order book for a symbol.
@@ -142,37 +128,129 @@ func (ob *OrderBook) AddOrder(order *domain.Order) {
 
 	elem := book.addOrder(order)
 	level := book.LimitMap[order.Price]
-	ob.OrderMap[order.OrderID] = &orderEntry{
-		order:   order,
-		element: elem,
-		level:   level,
+	ob.OrderMap[order.OrderID] = ob.newEntry(order, elem, level)
+}
+
+// HasOrder reports whether an order with this ID rests in the book.
+func (ob *OrderBook) HasOrder(orderID string) bool {
+	_, exists := ob.OrderMap[orderID]
+	return exists
+}
+
+// UserVolumeAt returns how much of one user's quantity rests at a price on
+// one side of the book.
+func (ob *OrderBook) UserVolumeAt(side domain.Side, price int64, userID string) int64 {
+	book := ob.SellBook
+	if side == domain.SideBuy {
+		book = ob.BuyBook
+	}
+	level, exists := book.LimitMap[price]
+	if !exists {
+		return 0
 	}
+
+	var total int64
+	for e := level.Orders.Front(); e != nil; e = e.Next() {
+		if o := e.Value.(*domain.Order); o.UserID == userID {
+			total += o.RemainingQuantity
+		}
+	}
+	return total
+}
+
+// Order returns the resting order with this ID, or nil.
+func (ob *OrderBook) Order(orderID string) *domain.Order {
+	entry, exists := ob.OrderMap[orderID]
+	if !exists {
+		return nil
+	}
+	return entry.order
 }
 
 // CancelOrder removes an order from the book by ID. Returns the order if found, nil otherwise.
 func (ob *OrderBook) CancelOrder(orderID string) *domain.Order {
+	order := ob.RemoveOrder(orderID)
+	if order != nil {
+		order.Status = domain.OrderStatusCanceled
+	}
+	return order
+}
+
+// RemoveOrder takes an order out of the book without canceling it, so it can
+// be matched and rested again. Returns the order if found, nil otherwise.
+func (ob *OrderBook) RemoveOrder(orderID string) *domain.Order {
 	entry, exists := ob.OrderMap[orderID]
 	if !exists {
 		return nil
 	}
 
-	var book *Book
-	if entry.order.Side == domain.SideBuy {
-		book = ob.BuyBook
-	} else {
-		book = ob.SellBook
+	order := entry.order
+	ob.side(order.Side).removeOrder(entry)
+	ob.dropEntry(orderID) // entry must not be used after this
+	return order
+}
+
+// ModifyOrder changes a resting order's price and total quantity. Reducing
+// the quantity at the same price keeps the order's place in its queue;
+// raising it or changing the price moves the order to the back of its (new)
+// price level, as a new order would be. The order is never matched here, so
+// the caller must not move it to a price that crosses the opposite side.
+// Returns nil if the order is not resting or quantity does not exceed what
+// has already been filled.
+func (ob *OrderBook) ModifyOrder(orderID string, price, quantity int64) *domain.Order {
+	entry, exists := ob.OrderMap[orderID]
+	if !exists || quantity <= entry.order.FilledQuantity {
+		return nil
+	}
+	order := entry.order
+	remaining := quantity - order.FilledQuantity
+
+	if price == order.Price && quantity <= order.Quantity {
+		entry.level.TotalVolume -= order.RemainingQuantity - remaining
+		order.Quantity = quantity
+		order.RemainingQuantity = remaining
+		return order
 	}
 
-	book.removeOrder(entry)
-	delete(ob.OrderMap, orderID)
+	ob.RemoveOrder(orderID)
+	order.Price = price
+	order.Quantity = quantity
+	order.RemainingQuantity = remaining
+	ob.AddOrder(order)
+	return order
+}
 
-	entry.order.Status = domain.OrderStatusCanceled
-	return entry.order
+// Crosses reports whether an order at its price would trade against the
+// best order on the opposite side, i.e. resting it would cross the book.
+// Market orders never rest and are not checked.
+func (ob *OrderBook) Crosses(order *domain.Order) bool {
+	opposite := ob.SellBook
+	if order.Side == domain.SideSell {
+		opposite = ob.BuyBook
+	}
+	return !order.IsMarket() && opposite.HasOrders() && crosses(order, opposite.BestPrice())
+}
+
+// side returns the buy or sell half of the book.
+func (ob *OrderBook) side(side domain.Side) *Book {
+	if side == domain.SideBuy {
+		return ob.BuyBook
+	}
+	return ob.SellBook
 }
 
 // MatchOrder attempts to match an incoming order against the opposite side.
 // Returns a list of executions and whether the taker order has remaining quantity.
+// Resting orders that cannot satisfy either side's minimum execution quantity
+// are skipped without losing their queue position.
 func (ob *OrderBook) MatchOrder(taker *domain.Order) []*domain.Execution {
+	executions, _ := ob.MatchOrderWithMakers(taker)
+	return executions
+}
+
+// MatchOrderWithMakers is MatchOrder that also returns the maker order of
+// each execution (makers[i] filled executions[i]).
+func (ob *OrderBook) MatchOrderWithMakers(taker *domain.Order) ([]*domain.Execution, []*domain.Order) {
 	var oppositeBook *Book
 	if taker.Side == domain.SideBuy {
 		oppositeBook = ob.SellBook
@@ -181,89 +259,255 @@ func (ob *OrderBook) MatchOrder(taker *domain.Order) []*domain.Execution {
 	}
 
 	var executions []*domain.Execution
+	var makers []*domain.Order
 	execSeq := 0
 
-	for taker.RemainingQuantity > 0 && oppositeBook.HasOrders() {
-		bestPrice := oppositeBook.BestPrice()
+	protection, protected := protectionPrice(taker, oppositeBook)
 
-		// Check price match
-		if taker.Side == domain.SideBuy && taker.Price < bestPrice {
-			break // buy price too low
+	// Levels are walked best first without taking them off the price heap,
+	// so a level that keeps orders the taker could not trade with (minimum
+	// execution quantity) stays where it is. Levels the walk empties are
+	// removed once it is done.
+	var emptied []*bookLevel
+	oppositeBook.eachLevel(func(level *bookLevel) bool {
+		if !crosses(taker, level.Price) {
+			return false
 		}
-		if taker.Side == domain.SideSell && taker.Price > bestPrice {
-			break // sell price too high
+		// Slippage protection: once a level is past the cap every remaining
+		// level is too
+		if protected && beyondPrice(taker.Side, level.Price, protection) {
+			return false
 		}
 
-		level := oppositeBook.LimitMap[bestPrice]
+		// Pro-rata and hybrid allocation (see allocation.go) take the level
+		// when they can; otherwise it is filled FIFO
+		if ob.allocatable(taker, level) {
+			for _, fill := range ob.allocateLevel(taker, level) {
+				execSeq++
+				executions = append(executions, ob.fill(taker, fill.maker, fill.elem, level, fill.qty, execSeq))
+				makers = append(makers, fill.maker)
+			}
+		}
 
-		// FIFO: consume from head of the linked list at this price level
-		for taker.RemainingQuantity > 0 && level.Orders.Len() > 0 {
-			front := level.Orders.Front()
-			maker := front.Value.(*domain.Order)
+		// FIFO: walk the linked list at this price level from the head
+		for elem := level.Orders.Front(); elem != nil && taker.RemainingQuantity > 0; {
+			next := elem.Next()
+			maker := elem.Value.(*domain.Order)
 
 			matchQty := min(taker.RemainingQuantity, maker.RemainingQuantity)
-
-			// Update quantities
-			taker.FilledQuantity += matchQty
-			taker.RemainingQuantity -= matchQty
-			maker.FilledQuantity += matchQty
-			maker.RemainingQuantity -= matchQty
-
-			// Update level volume
-			level.TotalVolume -= matchQty
-
-			// Update statuses
-			if maker.RemainingQuantity == 0 {
-				maker.Status = domain.OrderStatusFilled
-				level.Orders.Remove(front)
-				delete(ob.OrderMap, maker.OrderID)
-			} else {
-				maker.Status = domain.OrderStatusPartiallyFilled
-			}
-
-			if taker.RemainingQuantity == 0 {
-				taker.Status = domain.OrderStatusFilled
-			} else {
-				taker.Status = domain.OrderStatusPartiallyFilled
+			if !meetsMinExecQty(taker, matchQty) || !meetsMinExecQty(maker, matchQty) {
+				elem = next
+				continue
 			}
 
 			execSeq++
-			exec := &domain.Execution{
-				ExecID:       fmt.Sprintf("%s-exec-%d", taker.OrderID, execSeq),
-				OrderID:      taker.OrderID,
-				Symbol:       taker.Symbol,
-				Side:         taker.Side,
-				Price:        maker.Price, // execute at maker's (resting) price
-				Quantity:     matchQty,
-				MakerOrderID: maker.OrderID,
-				TakerOrderID: taker.OrderID,
-			}
-			executions = append(executions, exec)
+			executions = append(executions, ob.fill(taker, maker, elem, level, matchQty, execSeq))
+			makers = append(makers, maker)
+			elem = next
 		}
 
-		// Clean up empty price level
 		if level.Orders.Len() == 0 {
-			delete(oppositeBook.LimitMap, bestPrice)
-			oppositeBook.refreshBestPrice()
+			emptied = append(emptied, level)
 		}
+		return taker.RemainingQuantity > 0
+	})
+
+	// Clean up empty price levels
+	for _, level := range emptied {
+		oppositeBook.removeLevel(level)
 	}
+	oppositeBook.refreshBestPrice()
 
-	return executions
+	return executions, makers
+}
+
+// CanFill reports whether MatchOrder would fill the taker's whole remaining
+// quantity, without trading or changing the book. It walks the levels the
+// same way: best first, stopping at the taker's limit or slippage cap,
+// sharing a level by the allocation policy when it can and otherwise
+// skipping makers that fail either side's minimum execution quantity.
+func (ob *OrderBook) CanFill(taker *domain.Order) bool {
+	oppositeBook := ob.SellBook
+	if taker.Side == domain.SideSell {
+		oppositeBook = ob.BuyBook
+	}
+	protection, protected := protectionPrice(taker, oppositeBook)
+
+	// Quantities are tracked on copies; nothing here may modify the orders
+	sim := *taker
+	oppositeBook.eachLevel(func(level *bookLevel) bool {
+		if !crosses(&sim, level.Price) || (protected && beyondPrice(sim.Side, level.Price, protection)) {
+			return false
+		}
+		// Allocation hands out the whole level, up to the taker's quantity
+		if ob.allocatable(&sim, level) {
+			sim.RemainingQuantity -= min(sim.RemainingQuantity, level.TotalVolume)
+			return sim.RemainingQuantity > 0
+		}
+		for elem := level.Orders.Front(); elem != nil && sim.RemainingQuantity > 0; elem = elem.Next() {
+			maker := elem.Value.(*domain.Order)
+			matchQty := min(sim.RemainingQuantity, maker.RemainingQuantity)
+			if meetsMinExecQty(&sim, matchQty) && meetsMinExecQty(maker, matchQty) {
+				sim.RemainingQuantity -= matchQty
+			}
+		}
+		return sim.RemainingQuantity > 0
+	})
+	return sim.RemainingQuantity == 0
+}
+
+// SweepPrice returns the worst price a taker on side would reach filling
+// quantity against the opposite side: the price of the level where quantity
+// runs out, or of the last level if the book cannot fill it all. Minimum
+// execution quantities and the allocation policy are ignored. 0 if the
+// opposite side is empty.
+func (ob *OrderBook) SweepPrice(side domain.Side, quantity int64) int64 {
+	oppositeBook := ob.SellBook
+	if side == domain.SideSell {
+		oppositeBook = ob.BuyBook
+	}
+	var worst int64
+	oppositeBook.eachLevel(func(level *bookLevel) bool {
+		worst = level.Price
+		quantity -= level.TotalVolume
+		return quantity > 0
+	})
+	return worst
+}
+
+// fill trades qty between the taker and a resting maker at the maker's
+// price, removing the maker from its level once it is filled.
+func (ob *OrderBook) fill(taker, maker *domain.Order, elem *list.Element, level *bookLevel, qty int64, execSeq int) *domain.Execution {
+	// Update quantities
+	taker.FilledQuantity += qty
+	taker.RemainingQuantity -= qty
+	maker.FilledQuantity += qty
+	maker.RemainingQuantity -= qty
+
+	// Update level volume
+	level.TotalVolume -= qty
+
+	// Update statuses
+	if maker.RemainingQuantity == 0 {
+		maker.Status = domain.OrderStatusFilled
+		level.Orders.Remove(elem)
+		ob.dropEntry(maker.OrderID)
+	} else {
+		maker.Status = domain.OrderStatusPartiallyFilled
+	}
+
+	if taker.RemainingQuantity == 0 {
+		taker.Status = domain.OrderStatusFilled
+	} else {
+		taker.Status = domain.OrderStatusPartiallyFilled
+	}
+
+	return &domain.Execution{
+		ExecID:       fmt.Sprintf("%s-exec-%d", taker.OrderID, execSeq),
+		OrderID:      taker.OrderID,
+		Symbol:       taker.Symbol,
+		Side:         taker.Side,
+		Price:        maker.Price, // execute at maker's (resting) price
+		Quantity:     qty,
+		MakerOrderID: maker.OrderID,
+		TakerOrderID: taker.OrderID,
+	}
+}
+
+// protectionPrice returns the worst price a market order may trade at: the
+// tighter of its explicit Price and the MaxSlippageBps band around the best
+// opposite price. ok is false for limit orders and unprotected market orders.
+func protectionPrice(taker *domain.Order, opposite *Book) (int64, bool) {
+	if !taker.IsMarket() {
+		return 0, false
+	}
+
+	limit, ok := taker.Price, taker.Price > 0
+	if taker.MaxSlippageBps > 0 && opposite.hasOrders {
+		band := opposite.bestPrice * taker.MaxSlippageBps / 10000
+		slip := opposite.bestPrice + band
+		if taker.Side == domain.SideSell {
+			slip = opposite.bestPrice - band
+		}
+		if !ok || beyondPrice(taker.Side, limit, slip) {
+			limit, ok = slip, true
+		}
+	}
+	return limit, ok
+}
+
+// beyondPrice reports whether price is worse than limit for a taker on side.
+func beyondPrice(side domain.Side, price, limit int64) bool {
+	if side == domain.SideBuy {
+		return price > limit
+	}
+	return price < limit
+}
+
+// crosses reports whether the taker can trade at a resting price.
+// A market order crosses every level.
+func crosses(taker *domain.Order, price int64) bool {
+	if taker.IsMarket() {
+		return true
+	}
+	if taker.Side == domain.SideBuy {
+		return price <= taker.Price
+	}
+	return price >= taker.Price
+}
+
+// meetsMinExecQty reports whether a fill of qty satisfies the order's minimum
+// execution quantity. A remainder smaller than the minimum may fill in full.
+func meetsMinExecQty(order *domain.Order, qty int64) bool {
+	if order.MinExecQty <= 0 {
+		return true
+	}
+	return qty >= min(order.MinExecQty, order.RemainingQuantity)
 }
 
 // GetL2Snapshot returns an aggregated L2 order book snapshot.
 func (ob *OrderBook) GetL2Snapshot(depth int) *domain.L2OrderBook {
+	return ob.GetL2SnapshotAggregated(depth, 0)
+}
+
+// GetL2SnapshotAggregated returns an L2 snapshot with price levels grouped
+// into buckets of `bucket` cents. Bids round down and asks round up, so a
+// bucket never shows a better price than is actually available.
+// A bucket of 0 or 1 returns every distinct price as its own level.
+// Depth is applied after bucketing.
+func (ob *OrderBook) GetL2SnapshotAggregated(depth int, bucket int64) *domain.L2OrderBook {
 	snapshot := &domain.L2OrderBook{
 		Symbol: ob.Symbol,
-		Bids:   aggregateLevels(ob.BuyBook, depth, true),
-		Asks:   aggregateLevels(ob.SellBook, depth, false),
+		Bids:   aggregateLevels(ob.BuyBook, depth, true, bucket),
+		Asks:   aggregateLevels(ob.SellBook, depth, false, bucket),
 	}
 	return snapshot
 }
 
+// BBO returns the best bid and ask with the quantity resting at each.
+func (ob *OrderBook) BBO() *domain.BBO {
+	bbo := &domain.BBO{Symbol: ob.Symbol}
+	if ob.BuyBook.HasOrders() {
+		price := ob.BuyBook.BestPrice()
+		size := ob.BuyBook.LimitMap[price].TotalVolume
+		bbo.BidPrice, bbo.BidSize = &price, &size
+	}
+	if ob.SellBook.HasOrders() {
+		price := ob.SellBook.BestPrice()
+		size := ob.SellBook.LimitMap[price].TotalVolume
+		bbo.AskPrice, bbo.AskSize = &price, &size
+	}
+	if bbo.BidPrice != nil && bbo.AskPrice != nil {
+		spread := *bbo.AskPrice - *bbo.BidPrice
+		bbo.Spread = &spread
+	}
+	return bbo
+}
+
 // aggregateLevels collects price levels sorted by price.
 // For bids: descending (highest first). For asks: ascending (lowest first).
-func aggregateLevels(book *Book, depth int, descending bool) []domain.PriceLevel {
+// When bucket > 1, adjacent prices falling into the same bucket are merged.
+func aggregateLevels(book *Book, depth int, descending bool, bucket int64) []domain.PriceLevel {
 	prices := make([]int64, 0, len(book.LimitMap))
 	for price := range book.LimitMap {
 		prices = append(prices, price)
@@ -275,16 +519,103 @@ func aggregateLevels(book *Book, depth int, descending bool) []domain.PriceLevel
 		sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
 	}
 
-	if depth > 0 && len(prices) > depth {
-		prices = prices[:depth]
+	levels := make([]domain.PriceLevel, 0, len(prices))
+	for _, price := range prices {
+		level := book.LimitMap[price]
+		display := bucketPrice(price, bucket, descending)
+
+		// Sorted input means a bucket's prices are contiguous
+		if n := len(levels); n > 0 && levels[n-1].Price == display {
+			levels[n-1].Quantity += level.TotalVolume
+			continue
+		}
+		if depth > 0 && len(levels) == depth {
+			break
+		}
+		levels = append(levels, domain.PriceLevel{
+			Price:    display,
+			Quantity: level.TotalVolume,
+		})
+	}
+	return levels
+}
+
+// bucketPrice maps a price to its display bucket: down for bids, up for asks.
+func bucketPrice(price, bucket int64, roundDown bool) int64 {
+	if bucket <= 1 {
+		return price
+	}
+	floor := price - price%bucket
+	if roundDown || floor == price {
+		return floor
+	}
+	return floor + bucket
+}
+
+// DebugOrder is a read-only view of one resting order in a price level queue.
+type DebugOrder struct {
+	OrderID           string `json:"order_id"`
+	UserID            string `json:"user_id"`
+	RemainingQuantity int64  `json:"remaining_quantity"`
+	SequenceID        uint64 `json:"sequence_id"`
+}
+
+// DebugLevel is a read-only view of a price level and its FIFO queue.
+type DebugLevel struct {
+	Price       int64        `json:"price"`
+	TotalVolume int64        `json:"total_volume"`
+	Orders      []DebugOrder `json:"orders"` // head of the queue (next to match) first
+}
+
+// DebugView exposes the raw book structure: every price level with its
+// linked-list queue, bids descending and asks ascending.
+type DebugView struct {
+	Symbol     string       `json:"symbol"`
+	Bids       []DebugLevel `json:"bids"`
+	Asks       []DebugLevel `json:"asks"`
+	OrderCount int          `json:"order_count"`
+}
+
+// DebugSnapshot returns a copy of the book internals for inspection.
+func (ob *OrderBook) DebugSnapshot() *DebugView {
+	return &DebugView{
+		Symbol:     ob.Symbol,
+		Bids:       debugLevels(ob.BuyBook, true),
+		Asks:       debugLevels(ob.SellBook, false),
+		OrderCount: len(ob.OrderMap),
+	}
+}
+
+// debugLevels walks every price level's linked list in FIFO order.
+func debugLevels(book *Book, descending bool) []DebugLevel {
+	prices := make([]int64, 0, len(book.LimitMap))
+	for price := range book.LimitMap {
+		prices = append(prices, price)
 	}
 
-	levels := make([]domain.PriceLevel, len(prices))
+	if descending {
+		sort.Slice(prices, func(i, j int) bool { return prices[i] > prices[j] })
+	} else {
+		sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+	}
+
+	levels := make([]DebugLevel, len(prices))
 	for i, price := range prices {
 		level := book.LimitMap[price]
-		levels[i] = domain.PriceLevel{
-			Price:    price,
-			Quantity: level.TotalVolume,
+		orders := make([]DebugOrder, 0, level.Orders.Len())
+		for elem := level.Orders.Front(); elem != nil; elem = elem.Next() {
+			order := elem.Value.(*domain.Order)
+			orders = append(orders, DebugOrder{
+				OrderID:           order.OrderID,
+				UserID:            order.UserID,
+				RemainingQuantity: order.RemainingQuantity,
+				SequenceID:        order.SequenceID,
+			})
+		}
+		levels[i] = DebugLevel{
+			Price:       price,
+			TotalVolume: level.TotalVolume,
+			Orders:      orders,
 		}
 	}
 	return levels
@@ -193,6 +193,73 @@ func TestCancelOrder_MiddleOfLevel(t *testing.T) {
 	assert.Equal(t, int64(400), snap.Asks[0].Quantity) // 100 + 300
 }
 
+// queue returns the order IDs resting at a sell price, head first.
+func queue(ob *OrderBook, price int64) []string {
+	var ids []string
+	for e := ob.SellBook.LimitMap[price].Orders.Front(); e != nil; e = e.Next() {
+		ids = append(ids, e.Value.(*domain.Order).OrderID)
+	}
+	return ids
+}
+
+func TestModifyOrder_DecreaseKeepsPriority(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10010, 100))
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10010, 100))
+
+	modified := ob.ModifyOrder("s1", 10010, 60)
+	require.NotNil(t, modified)
+	assert.Equal(t, int64(60), modified.Quantity)
+	assert.Equal(t, int64(60), modified.RemainingQuantity)
+	assert.Equal(t, []string{"s1", "s2"}, queue(ob, 10010))
+	assert.Equal(t, int64(160), ob.GetL2Snapshot(5).Asks[0].Quantity)
+
+	execs := ob.MatchOrder(newOrder("b1", domain.SideBuy, 10010, 60))
+	require.Len(t, execs, 1)
+	assert.Equal(t, "s1", execs[0].MakerOrderID)
+}
+
+func TestModifyOrder_IncreaseRequeues(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10010, 100))
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10010, 100))
+
+	require.NotNil(t, ob.ModifyOrder("s1", 10010, 150))
+	assert.Equal(t, []string{"s2", "s1"}, queue(ob, 10010))
+	assert.Equal(t, int64(250), ob.GetL2Snapshot(5).Asks[0].Quantity)
+}
+
+func TestModifyOrder_PriceChangeRequeues(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10010, 100))
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10020, 100))
+
+	// A smaller quantity does not save the priority when the price moves
+	require.NotNil(t, ob.ModifyOrder("s1", 10020, 50))
+	assert.Equal(t, []string{"s2", "s1"}, queue(ob, 10020))
+	assert.NotContains(t, ob.SellBook.LimitMap, int64(10010), "the emptied level is removed")
+	assert.Equal(t, int64(10020), ob.SellBook.BestPrice())
+
+	execs := ob.MatchOrder(newOrder("b1", domain.SideBuy, 10020, 100))
+	require.Len(t, execs, 1)
+	assert.Equal(t, "s2", execs[0].MakerOrderID)
+}
+
+func TestModifyOrder_PartiallyFilled(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10010, 100))
+	ob.MatchOrder(newOrder("b1", domain.SideBuy, 10010, 40))
+
+	// Quantity is the new total, so it must leave something to rest
+	assert.Nil(t, ob.ModifyOrder("s1", 10010, 40))
+	assert.Nil(t, ob.ModifyOrder("missing", 10010, 40))
+
+	modified := ob.ModifyOrder("s1", 10010, 70)
+	require.NotNil(t, modified)
+	assert.Equal(t, int64(30), modified.RemainingQuantity)
+	assert.Equal(t, int64(30), ob.GetL2Snapshot(5).Asks[0].Quantity)
+}
+
 func TestL2Snapshot_Depth(t *testing.T) {
 	ob := NewOrderBook("AAPL")
 
@@ -221,3 +288,272 @@ func TestL2Snapshot_Empty(t *testing.T) {
 	assert.Empty(t, snap.Bids)
 	assert.Empty(t, snap.Asks)
 }
+
+func TestL2Snapshot_Aggregated(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10001, 100))
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10005, 200))
+	ob.AddOrder(newOrder("s3", domain.SideSell, 10010, 300))
+	ob.AddOrder(newOrder("s4", domain.SideSell, 10011, 50))
+
+	snap := ob.GetL2SnapshotAggregated(5, 10)
+	require.Len(t, snap.Asks, 2)
+	assert.Equal(t, int64(10010), snap.Asks[0].Price)
+	assert.Equal(t, int64(600), snap.Asks[0].Quantity) // 100 + 200 + 300
+	assert.Equal(t, int64(10020), snap.Asks[1].Price)
+	assert.Equal(t, int64(50), snap.Asks[1].Quantity)
+
+	// Raw book is unchanged
+	raw := ob.GetL2Snapshot(5)
+	assert.Len(t, raw.Asks, 4)
+	assert.Equal(t, int64(10001), raw.Asks[0].Price)
+}
+
+func TestL2Snapshot_Aggregated_BidsRoundDown(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	ob.AddOrder(newOrder("b1", domain.SideBuy, 9999, 100))
+	ob.AddOrder(newOrder("b2", domain.SideBuy, 9995, 200))
+	ob.AddOrder(newOrder("b3", domain.SideBuy, 9990, 300))
+	ob.AddOrder(newOrder("b4", domain.SideBuy, 9985, 400))
+	ob.AddOrder(newOrder("b5", domain.SideBuy, 9970, 500))
+
+	// Depth counts buckets, not raw prices
+	snap := ob.GetL2SnapshotAggregated(2, 10)
+	require.Len(t, snap.Bids, 2)
+	assert.Equal(t, int64(9990), snap.Bids[0].Price)
+	assert.Equal(t, int64(600), snap.Bids[0].Quantity)
+	assert.Equal(t, int64(9980), snap.Bids[1].Price)
+	assert.Equal(t, int64(400), snap.Bids[1].Quantity)
+}
+
+func TestMatchOrder_MinExecQty_SkipsSmallMaker(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10000, 10))  // too small
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10000, 200)) // large enough
+
+	taker := newOrder("b1", domain.SideBuy, 10000, 100)
+	taker.MinExecQty = 50
+	execs := ob.MatchOrder(taker)
+
+	require.Len(t, execs, 1)
+	assert.Equal(t, "s2", execs[0].MakerOrderID)
+	assert.Equal(t, int64(100), execs[0].Quantity)
+	assert.Equal(t, domain.OrderStatusFilled, taker.Status)
+
+	// The skipped maker keeps its place at the head of the level
+	snap := ob.GetL2Snapshot(5)
+	require.Len(t, snap.Asks, 1)
+	assert.Equal(t, int64(110), snap.Asks[0].Quantity)
+	front := ob.SellBook.LimitMap[10000].Orders.Front().Value.(*domain.Order)
+	assert.Equal(t, "s1", front.OrderID)
+}
+
+func TestMatchOrder_MinExecQty_RestsWhenUnmet(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10000, 10))
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10010, 20))
+
+	taker := newOrder("b1", domain.SideBuy, 10010, 100)
+	taker.MinExecQty = 50
+	execs := ob.MatchOrder(taker)
+
+	assert.Empty(t, execs)
+	assert.Equal(t, int64(100), taker.RemainingQuantity)
+	assert.Equal(t, domain.OrderStatusNew, taker.Status)
+}
+
+func TestMatchOrder_MinExecQty_NextLevel(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10000, 10))
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10010, 80))
+
+	taker := newOrder("b1", domain.SideBuy, 10010, 100)
+	taker.MinExecQty = 50
+	execs := ob.MatchOrder(taker)
+
+	// Skips the small best-price order and fills at the next level
+	require.Len(t, execs, 1)
+	assert.Equal(t, "s2", execs[0].MakerOrderID)
+	assert.Equal(t, int64(10010), execs[0].Price)
+	assert.Equal(t, int64(20), taker.RemainingQuantity)
+	assert.Equal(t, int64(10000), ob.SellBook.BestPrice())
+}
+
+func TestMatchOrder_MinExecQty_RestingMaker(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	maker := newOrder("s1", domain.SideSell, 10000, 100)
+	maker.MinExecQty = 40
+	ob.AddOrder(maker)
+
+	// Too small for the maker's minimum
+	small := newOrder("b1", domain.SideBuy, 10000, 30)
+	assert.Empty(t, ob.MatchOrder(small))
+
+	big := newOrder("b2", domain.SideBuy, 10000, 60)
+	execs := ob.MatchOrder(big)
+	require.Len(t, execs, 1)
+	assert.Equal(t, int64(60), execs[0].Quantity)
+
+	// Remaining 40 is at the minimum; a remainder below it fills in full
+	last := newOrder("b3", domain.SideBuy, 10000, 40)
+	require.Len(t, ob.MatchOrder(last), 1)
+	assert.False(t, ob.SellBook.HasOrders())
+}
+
+func TestDebugSnapshot_QueueOrder(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10010, 100))
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10010, 200))
+	ob.AddOrder(newOrder("s3", domain.SideSell, 10020, 50))
+	ob.AddOrder(newOrder("s4", domain.SideSell, 10010, 300))
+	ob.AddOrder(newOrder("b1", domain.SideBuy, 9990, 100))
+	ob.AddOrder(newOrder("b2", domain.SideBuy, 10000, 100))
+
+	// Partially consume the head of the 10010 queue
+	ob.MatchOrder(newOrder("t1", domain.SideBuy, 10010, 60))
+
+	view := ob.DebugSnapshot()
+	assert.Equal(t, 6, view.OrderCount)
+
+	require.Len(t, view.Asks, 2)
+	assert.Equal(t, int64(10010), view.Asks[0].Price)
+	assert.Equal(t, int64(540), view.Asks[0].TotalVolume)
+	require.Len(t, view.Asks[0].Orders, 3)
+	assert.Equal(t, "s1", view.Asks[0].Orders[0].OrderID)
+	assert.Equal(t, int64(40), view.Asks[0].Orders[0].RemainingQuantity)
+	assert.Equal(t, "s2", view.Asks[0].Orders[1].OrderID)
+	assert.Equal(t, "s4", view.Asks[0].Orders[2].OrderID)
+	assert.Equal(t, int64(10020), view.Asks[1].Price)
+
+	require.Len(t, view.Bids, 2)
+	assert.Equal(t, int64(10000), view.Bids[0].Price)
+	assert.Equal(t, "b2", view.Bids[0].Orders[0].OrderID)
+	assert.Equal(t, int64(9990), view.Bids[1].Price)
+}
+
+func TestMatchOrder_MarketSlippageProtection(t *testing.T) {
+	setup := func() *OrderBook {
+		ob := NewOrderBook("AAPL")
+		ob.AddOrder(newOrder("s1", domain.SideSell, 10000, 100))
+		ob.AddOrder(newOrder("s2", domain.SideSell, 10050, 100))
+		ob.AddOrder(newOrder("s3", domain.SideSell, 12000, 100)) // far away
+		return ob
+	}
+
+	t.Run("protected stops before far level", func(t *testing.T) {
+		ob := setup()
+		buy := newOrder("b1", domain.SideBuy, 0, 300)
+		buy.Type = domain.OrderTypeMarket
+		buy.MaxSlippageBps = 100 // cap at 10100
+
+		execs := ob.MatchOrder(buy)
+		require.Len(t, execs, 2)
+		assert.Equal(t, int64(10000), execs[0].Price)
+		assert.Equal(t, int64(10050), execs[1].Price)
+		assert.Equal(t, int64(100), buy.RemainingQuantity)
+
+		// The far level is untouched
+		assert.Equal(t, int64(12000), ob.SellBook.BestPrice())
+	})
+
+	t.Run("price acts as protection cap", func(t *testing.T) {
+		ob := setup()
+		buy := newOrder("b1", domain.SideBuy, 10000, 300)
+		buy.Type = domain.OrderTypeMarket
+
+		execs := ob.MatchOrder(buy)
+		require.Len(t, execs, 1)
+		assert.Equal(t, int64(200), buy.RemainingQuantity)
+	})
+
+	t.Run("protected sell uses band below best bid", func(t *testing.T) {
+		ob := NewOrderBook("AAPL")
+		ob.AddOrder(newOrder("b1", domain.SideBuy, 10000, 100))
+		ob.AddOrder(newOrder("b2", domain.SideBuy, 9000, 100))
+		sell := newOrder("s1", domain.SideSell, 0, 200)
+		sell.Type = domain.OrderTypeMarket
+		sell.MaxSlippageBps = 500 // floor at 9500
+
+		execs := ob.MatchOrder(sell)
+		require.Len(t, execs, 1)
+		assert.Equal(t, int64(10000), execs[0].Price)
+		assert.Equal(t, int64(100), sell.RemainingQuantity)
+	})
+
+	t.Run("unprotected sweeps every level", func(t *testing.T) {
+		ob := setup()
+		buy := newOrder("b1", domain.SideBuy, 0, 300)
+		buy.Type = domain.OrderTypeMarket
+
+		execs := ob.MatchOrder(buy)
+		require.Len(t, execs, 3)
+		assert.Equal(t, int64(12000), execs[2].Price)
+		assert.Equal(t, domain.OrderStatusFilled, buy.Status)
+		assert.False(t, ob.SellBook.HasOrders())
+	})
+}
+
+func TestMatchOrder_MarketEmptyBook(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(newOrder("b1", domain.SideBuy, 9900, 100)) // same side only
+
+	buy := newOrder("b2", domain.SideBuy, 0, 100)
+	buy.Type = domain.OrderTypeMarket
+	execs := ob.MatchOrder(buy)
+
+	assert.Empty(t, execs)
+	assert.Equal(t, int64(100), buy.RemainingQuantity)
+	assert.False(t, ob.SellBook.HasOrders())
+}
+
+func TestCanFill(t *testing.T) {
+	setup := func(opts Options) *OrderBook {
+		ob := NewOrderBookWithOptions("AAPL", opts)
+		ob.AddOrder(newOrder("s1", domain.SideSell, 10000, 100))
+		ob.AddOrder(newOrder("s2", domain.SideSell, 10000, 50))
+		picky := newOrder("s3", domain.SideSell, 10010, 100)
+		picky.MinExecQty = 100
+		ob.AddOrder(picky)
+		ob.AddOrder(newOrder("s4", domain.SideSell, 10020, 100))
+		return ob
+	}
+
+	tests := []struct {
+		name  string
+		opts  Options
+		price int64
+		qty   int64
+		want  bool
+	}{
+		{"within the best level", Options{}, 10000, 150, true},
+		{"past the limit price", Options{}, 10000, 151, false},
+		{"across levels", Options{}, 10020, 350, true},
+		{"more than the book", Options{}, 10020, 351, false},
+		// 160 leaves 10 for s3, below its minimum, so it is skipped
+		{"maker minimum skipped", Options{}, 10010, 160, false},
+		{"maker minimum met", Options{}, 10010, 250, true},
+		{"pro-rata level", Options{Allocation: AllocationProRata}, 10000, 150, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := setup(tt.opts)
+			before := ob.DebugSnapshot()
+			buy := newOrder("b1", domain.SideBuy, tt.price, tt.qty)
+
+			assert.Equal(t, tt.want, ob.CanFill(buy))
+			assert.Equal(t, before, ob.DebugSnapshot(), "the book is unchanged")
+			assert.Equal(t, tt.qty, buy.RemainingQuantity)
+
+			// MatchOrder agrees
+			ob.MatchOrder(buy)
+			assert.Equal(t, tt.want, buy.RemainingQuantity == 0)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package orderbook
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Object pooling for high-churn books.
+//
+// Every resting order costs an orderEntry, and every new price level costs a
+// bookLevel plus its list.List. With pooling enabled these are recycled
+// through sync.Pool instead of being left to the GC.
+//
+// Lifecycle (the invariants that make reuse safe):
+//
+//   - orderEntry is only referenced from OrderMap. It is released right after
+//     it is deleted from OrderMap (cancel, or maker fully filled), and nothing
+//     may touch it afterwards. The *domain.Order it pointed to is NOT pooled:
+//     it is owned by the caller and stays valid.
+//   - bookLevel is referenced from LimitMap and from the orderEntry of every
+//     order resting at that price. It is released only when its queue is empty
+//     (so no orderEntry still points at it) and after it is deleted from LimitMap.
+//     Its list.List is kept and reset with Init for the next price level.
+//   - list.Element values are allocated by container/list and cannot be pooled.
+//
+// Released objects are zeroed, so a stale reference does not read another
+// order's data: a released orderEntry has a nil order and level and fails
+// loudly, but a released bookLevel keeps its (now empty) list, so a stale one
+// silently reads as an empty level at price 0 until it is reused.
+var (
+	entryPool = sync.Pool{New: func() any { return new(orderEntry) }}
+	levelPool = sync.Pool{New: func() any { return &bookLevel{Orders: list.New()} }}
+)
+
+// Options configures an OrderBook.
+type Options struct {
+	// Pooling recycles orderEntry and bookLevel objects to reduce allocations
+	// on the matching path. See the lifecycle notes in pool.go.
+	Pooling bool
+	// Allocation decides how a taker's quantity is shared among the orders
+	// at one price level; empty means FIFO. See allocation.go.
+	Allocation AllocationPolicy
+	// TopAllocationBps is the share of a level's fill, in basis points,
+	// guaranteed to its first order under AllocationHybrid.
+	TopAllocationBps int64
+}
+
+// NewOrderBookWithOptions creates a new order book for a symbol.
+func NewOrderBookWithOptions(symbol string, opts Options) *OrderBook {
+	ob := NewOrderBook(symbol)
+	ob.pooled = opts.Pooling
+	ob.allocation = opts.Allocation
+	ob.topBps = opts.TopAllocationBps
+	ob.BuyBook.pooled = opts.Pooling
+	ob.SellBook.pooled = opts.Pooling
+	return ob
+}
+
+// newLevel returns an empty price level, recycled if pooling is enabled.
+func (b *Book) newLevel(price int64) *bookLevel {
+	if !b.pooled {
+		return &bookLevel{Price: price, Orders: list.New()}
+	}
+	level := levelPool.Get().(*bookLevel)
+	level.Price = price
+	return level
+}
+
+// releaseLevel recycles a level that has been removed from LimitMap.
+// The level's queue must be empty.
+func (b *Book) releaseLevel(level *bookLevel) {
+	if !b.pooled || level.Orders.Len() != 0 {
+		return
+	}
+	level.Price = 0
+	level.TotalVolume = 0
+	level.Orders.Init()
+	levelPool.Put(level)
+}
+
+// newEntry returns an orderEntry, recycled if pooling is enabled.
+func (ob *OrderBook) newEntry(order *domain.Order, elem *list.Element, level *bookLevel) *orderEntry {
+	var entry *orderEntry
+	if ob.pooled {
+		entry = entryPool.Get().(*orderEntry)
+	} else {
+		entry = new(orderEntry)
+	}
+	entry.order = order
+	entry.element = elem
+	entry.level = level
+	return entry
+}
+
+// dropEntry deletes an order from OrderMap and recycles its entry.
+func (ob *OrderBook) dropEntry(orderID string) {
+	entry, exists := ob.OrderMap[orderID]
+	delete(ob.OrderMap, orderID)
+	if !ob.pooled || !exists {
+		return
+	}
+	*entry = orderEntry{}
+	entryPool.Put(entry)
+}
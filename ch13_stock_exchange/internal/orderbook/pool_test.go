@@ -0,0 +1,126 @@
+package orderbook
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPooling_MatchesUnpooled drives identical add/cancel/match cycles through
+// a pooled and an unpooled book and requires them to stay identical. A recycled
+// object that is still referenced would show up as a diverging queue or volume.
+func TestPooling_MatchesUnpooled(t *testing.T) {
+	plain := NewOrderBook("AAPL")
+	pooled := NewOrderBookWithOptions("AAPL", Options{Pooling: true})
+
+	rng := rand.New(rand.NewSource(7))
+	var live []string
+
+	for i := 0; i < 5000; i++ {
+		switch op := rng.Intn(10); {
+		case op < 3 && len(live) > 0:
+			// Cancel a random resting (or already filled) order
+			idx := rng.Intn(len(live))
+			id := live[idx]
+			live = append(live[:idx], live[idx+1:]...)
+
+			a := plain.CancelOrder(id)
+			b := pooled.CancelOrder(id)
+			require.Equal(t, a == nil, b == nil, "cancel %s", id)
+			if a != nil {
+				assert.Equal(t, a.OrderID, b.OrderID)
+				assert.Equal(t, a.RemainingQuantity, b.RemainingQuantity)
+			}
+		default:
+			side := domain.SideBuy
+			if rng.Intn(2) == 0 {
+				side = domain.SideSell
+			}
+			id := fmt.Sprintf("o%d", i)
+			price := int64(9990 + rng.Intn(20))
+			qty := int64(1 + rng.Intn(100))
+
+			execA := plain.MatchOrder(newOrder(id, side, price, qty))
+			takerB := newOrder(id, side, price, qty)
+			execB := pooled.MatchOrder(takerB)
+			require.Equal(t, len(execA), len(execB), "executions for %s", id)
+			for j := range execA {
+				assert.Equal(t, execA[j].MakerOrderID, execB[j].MakerOrderID)
+				assert.Equal(t, execA[j].Quantity, execB[j].Quantity)
+				assert.Equal(t, execA[j].Price, execB[j].Price)
+			}
+
+			if takerB.RemainingQuantity > 0 {
+				takerA := newOrder(id, side, price, takerB.RemainingQuantity)
+				plain.AddOrder(takerA)
+				pooled.AddOrder(takerB)
+				live = append(live, id)
+			}
+		}
+
+		if i%250 == 0 {
+			requireSameBook(t, plain, pooled)
+		}
+	}
+	requireSameBook(t, plain, pooled)
+}
+
+func requireSameBook(t *testing.T, a, b *OrderBook) {
+	t.Helper()
+	va, vb := a.DebugSnapshot(), b.DebugSnapshot()
+	require.Equal(t, va, vb)
+
+	// Every entry must still point at the level holding its order
+	for id, entry := range b.OrderMap {
+		require.NotNil(t, entry.level, id)
+		require.Equal(t, entry.order.Price, entry.level.Price, id)
+		require.Same(t, entry.order, entry.element.Value.(*domain.Order), id)
+	}
+}
+
+func TestPooling_ReleasedLevelIsReset(t *testing.T) {
+	ob := NewOrderBookWithOptions("AAPL", Options{Pooling: true})
+
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10010, 100))
+	level := ob.SellBook.LimitMap[10010]
+	require.NotNil(t, ob.CancelOrder("s1"))
+
+	// The level left the book and was zeroed before going back to the pool
+	assert.Empty(t, ob.SellBook.LimitMap)
+	assert.Equal(t, int64(0), level.Price)
+	assert.Equal(t, 0, level.Orders.Len())
+
+	// A canceled order stays valid for the caller
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10020, 50))
+	snap := ob.GetL2Snapshot(5)
+	require.Len(t, snap.Asks, 1)
+	assert.Equal(t, int64(10020), snap.Asks[0].Price)
+	assert.Equal(t, int64(50), snap.Asks[0].Quantity)
+}
+
+func benchmarkChurn(b *testing.B, opts Options) {
+	ob := NewOrderBookWithOptions("AAPL", opts)
+	orders := make([]*domain.Order, 1024)
+	for i := range orders {
+		orders[i] = newOrder(fmt.Sprintf("o%d", i), domain.SideSell, 10000+int64(i%64), 100)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		o := orders[i%len(orders)]
+		o.RemainingQuantity = 100
+		ob.AddOrder(o)
+		ob.CancelOrder(o.OrderID)
+	}
+}
+
+// BenchmarkChurn_Unpooled: every add allocates an orderEntry and a bookLevel.
+func BenchmarkChurn_Unpooled(b *testing.B) { benchmarkChurn(b, Options{}) }
+
+// BenchmarkChurn_Pooled: entries and levels are recycled across cycles.
+func BenchmarkChurn_Pooled(b *testing.B) { benchmarkChurn(b, Options{Pooling: true}) }
@@ -0,0 +1,126 @@
+package orderbook
+
+import (
+	"container/heap"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Price ordering for one side of the book.
+//
+// LimitMap answers "which level is at this price" in O(1) but has no order,
+// so finding the best price used to mean scanning every level on each add and
+// remove. The levels are also kept in a binary heap ordered best price first
+// (max-heap for bids, min-heap for asks):
+//
+//	best price                  O(1)      heap root
+//	new price level             O(log n)  heap.Push
+//	empty level removed         O(log n)  heap.Remove by the level's heapIndex
+//	next level after exhaustion O(log n)  the new root after the removal
+//
+// Every level in LimitMap is in the heap and vice versa; addLevel and
+// removeLevel are the only places that change either.
+
+// priceHeap implements heap.Interface over a side's price levels.
+type priceHeap struct {
+	levels []*bookLevel
+	buy    bool // max-heap for bids, min-heap for asks
+}
+
+func (h *priceHeap) Len() int { return len(h.levels) }
+
+func (h *priceHeap) Less(i, j int) bool {
+	if h.buy {
+		return h.levels[i].Price > h.levels[j].Price
+	}
+	return h.levels[i].Price < h.levels[j].Price
+}
+
+func (h *priceHeap) Swap(i, j int) {
+	h.levels[i], h.levels[j] = h.levels[j], h.levels[i]
+	h.levels[i].heapIndex = i
+	h.levels[j].heapIndex = j
+}
+
+func (h *priceHeap) Push(x any) {
+	level := x.(*bookLevel)
+	level.heapIndex = len(h.levels)
+	h.levels = append(h.levels, level)
+}
+
+func (h *priceHeap) Pop() any {
+	n := len(h.levels)
+	level := h.levels[n-1]
+	h.levels[n-1] = nil
+	h.levels = h.levels[:n-1]
+	level.heapIndex = -1
+	return level
+}
+
+// top returns the best price level, or nil if the side is empty.
+func (h *priceHeap) top() *bookLevel {
+	if len(h.levels) == 0 {
+		return nil
+	}
+	return h.levels[0]
+}
+
+func newPriceHeap(side domain.Side) *priceHeap {
+	return &priceHeap{buy: side == domain.SideBuy}
+}
+
+// addLevel creates the level for a price that is not yet in the book.
+func (b *Book) addLevel(price int64) *bookLevel {
+	level := b.newLevel(price)
+	b.LimitMap[price] = level
+	heap.Push(b.prices, level)
+	return level
+}
+
+// removeLevel drops an empty level from the book and recycles it.
+func (b *Book) removeLevel(level *bookLevel) {
+	delete(b.LimitMap, level.Price)
+	heap.Remove(b.prices, level.heapIndex)
+	b.releaseLevel(level)
+}
+
+// levelCursor orders positions of a priceHeap's levels best price first. A
+// heap's children are never better than their parent, so walking it from the
+// root while always taking the best frontier position visits the levels in
+// price order without popping anything from the heap itself.
+type levelCursor struct {
+	positions []int
+	heap      *priceHeap
+}
+
+func (c *levelCursor) Len() int           { return len(c.positions) }
+func (c *levelCursor) Less(i, j int) bool { return c.heap.Less(c.positions[i], c.positions[j]) }
+func (c *levelCursor) Swap(i, j int)      { c.positions[i], c.positions[j] = c.positions[j], c.positions[i] }
+func (c *levelCursor) Push(x any)         { c.positions = append(c.positions, x.(int)) }
+
+func (c *levelCursor) Pop() any {
+	n := len(c.positions)
+	pos := c.positions[n-1]
+	c.positions = c.positions[:n-1]
+	return pos
+}
+
+// eachLevel calls fn on the side's levels best price first until it returns
+// false, leaving the book unchanged. Visiting k levels costs O(k log k).
+func (b *Book) eachLevel(fn func(level *bookLevel) bool) {
+	if b.prices.Len() == 0 {
+		return
+	}
+	cursor := &levelCursor{positions: []int{0}, heap: b.prices}
+	for cursor.Len() > 0 {
+		pos := heap.Pop(cursor).(int)
+		if !fn(b.prices.levels[pos]) {
+			return
+		}
+		for _, child := range []int{2*pos + 1, 2*pos + 2} {
+			if child < b.prices.Len() {
+				heap.Push(cursor, child)
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package orderbook
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scanBestPrice is the full LimitMap scan the price heap replaced, kept as
+// the reference for tests and the baseline for benchmarks.
+func scanBestPrice(b *Book) int64 {
+	var best int64
+	for price := range b.LimitMap {
+		if best == 0 ||
+			(b.Side == domain.SideBuy && price > best) ||
+			(b.Side == domain.SideSell && price < best) {
+			best = price
+		}
+	}
+	return best
+}
+
+func TestPriceHeap_NextLevelAfterExhaustion(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	ob.AddOrder(newOrder("s1", domain.SideSell, 10010, 100))
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10000, 100))
+	ob.AddOrder(newOrder("s3", domain.SideSell, 10020, 100))
+	assert.Equal(t, int64(10000), ob.SellBook.BestPrice())
+
+	ob.MatchOrder(newOrder("b1", domain.SideBuy, 10000, 100))
+	assert.Equal(t, int64(10010), ob.SellBook.BestPrice())
+
+	ob.CancelOrder("s1")
+	assert.Equal(t, int64(10020), ob.SellBook.BestPrice())
+
+	ob.CancelOrder("s3")
+	assert.False(t, ob.SellBook.HasOrders())
+	assert.Zero(t, ob.SellBook.BestPrice())
+}
+
+func TestPriceHeap_SkippedLevelIsRestored(t *testing.T) {
+	ob := NewOrderBook("AAPL")
+	maker := newOrder("s1", domain.SideSell, 10000, 100)
+	maker.MinExecQty = 100
+	ob.AddOrder(maker)
+	ob.AddOrder(newOrder("s2", domain.SideSell, 10010, 100))
+
+	// The best level cannot trade with a 50 lot, so the taker moves past it
+	buy := newOrder("b1", domain.SideBuy, 10010, 50)
+	execs := ob.MatchOrder(buy)
+	require.Len(t, execs, 1)
+	assert.Equal(t, "s2", execs[0].MakerOrderID)
+
+	assert.Equal(t, int64(10000), ob.SellBook.BestPrice())
+	assert.Equal(t, 2, ob.SellBook.prices.Len())
+}
+
+func TestPriceHeap_MatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ob := NewOrderBookWithOptions("AAPL", Options{Pooling: true})
+	var resting []string
+
+	for i := range 5000 {
+		switch op := rng.Intn(10); {
+		case op < 6:
+			side := domain.SideBuy
+			price := int64(9900 + rng.Intn(100))
+			if rng.Intn(2) == 0 {
+				side = domain.SideSell
+				price = int64(10001 + rng.Intn(100))
+			}
+			id := fmt.Sprintf("o%d", i)
+			ob.AddOrder(newOrder(id, side, price, int64(1+rng.Intn(50))))
+			resting = append(resting, id)
+		case op < 8 && len(resting) > 0:
+			j := rng.Intn(len(resting))
+			ob.CancelOrder(resting[j])
+			resting[j] = resting[len(resting)-1]
+			resting = resting[:len(resting)-1]
+		default:
+			side := domain.SideBuy
+			price := int64(10001 + rng.Intn(100))
+			if rng.Intn(2) == 0 {
+				side = domain.SideSell
+				price = int64(9900 + rng.Intn(100))
+			}
+			ob.MatchOrder(newOrder(fmt.Sprintf("t%d", i), side, price, int64(1+rng.Intn(200))))
+		}
+
+		for _, book := range []*Book{ob.BuyBook, ob.SellBook} {
+			require.Equal(t, scanBestPrice(book), book.BestPrice(), "step %d", i)
+			require.Equal(t, len(book.LimitMap), book.prices.Len(), "step %d", i)
+		}
+	}
+}
+
+func TestEachLevel_BestFirst(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	ob := NewOrderBook("AAPL")
+	for i := range 200 {
+		ob.AddOrder(newOrder(fmt.Sprintf("b%d", i), domain.SideBuy, int64(9000+rng.Intn(500)), 10))
+	}
+	heapOrder := append([]*bookLevel(nil), ob.BuyBook.prices.levels...)
+
+	var prices []int64
+	ob.BuyBook.eachLevel(func(level *bookLevel) bool {
+		prices = append(prices, level.Price)
+		return true
+	})
+	require.Len(t, prices, len(ob.BuyBook.LimitMap))
+	assert.IsNonIncreasing(t, prices)
+	assert.Equal(t, heapOrder, ob.BuyBook.prices.levels, "the heap is not modified")
+
+	var first []int64
+	ob.BuyBook.eachLevel(func(level *bookLevel) bool {
+		first = append(first, level.Price)
+		return len(first) < 3
+	})
+	assert.Equal(t, prices[:3], first)
+}
+
+// ── 10k price levels ─────────────────────────────────────────────────────────
+//
+// Run with:
+//   go test ./internal/orderbook/ -run=^$ -bench=10kLevels -benchmem
+//
+// _Heap is the book as it is; _Scan adds the old full LimitMap scan to the
+// same operation, which is what every add and remove used to pay.
+
+const benchLevels = 10_000
+
+// deepBook returns a book with one 100 lot resting at each of benchLevels ask prices.
+func deepBook() *OrderBook {
+	ob := NewOrderBook("AAPL")
+	for i := range benchLevels {
+		ob.AddOrder(newOrder(fmt.Sprintf("s%d", i), domain.SideSell, int64(10000+i), 100))
+	}
+	return ob
+}
+
+// BenchmarkAddCancel_10kLevels_Heap: a new level is pushed and removed, O(log n) each.
+func BenchmarkAddCancel_10kLevels_Heap(b *testing.B) {
+	ob := deepBook()
+	order := newOrder("new", domain.SideSell, 10000+benchLevels, 100)
+	for b.Loop() {
+		order.RemainingQuantity = 100
+		ob.AddOrder(order)
+		ob.CancelOrder("new")
+	}
+}
+
+// BenchmarkAddCancel_10kLevels_Scan: the same plus a full scan after each, O(n).
+func BenchmarkAddCancel_10kLevels_Scan(b *testing.B) {
+	ob := deepBook()
+	order := newOrder("new", domain.SideSell, 10000+benchLevels, 100)
+	for b.Loop() {
+		order.RemainingQuantity = 100
+		ob.AddOrder(order)
+		_ = scanBestPrice(ob.SellBook)
+		ob.CancelOrder("new")
+		_ = scanBestPrice(ob.SellBook)
+	}
+}
+
+// BenchmarkSweep_10kLevels_Heap: a taker exhausts the best level and the next
+// one surfaces from the heap.
+func BenchmarkSweep_10kLevels_Heap(b *testing.B) {
+	ob := deepBook()
+	i := 0
+	for b.Loop() {
+		price := ob.SellBook.BestPrice()
+		ob.MatchOrder(newOrder("t", domain.SideBuy, price, 100))
+		// Refill behind the book so its depth stays at benchLevels
+		ob.AddOrder(newOrder(fmt.Sprintf("r%d", i), domain.SideSell, price+benchLevels, 100))
+		i++
+	}
+}
+
+// BenchmarkSweep_10kLevels_Scan: the same plus the scan that found the next level.
+func BenchmarkSweep_10kLevels_Scan(b *testing.B) {
+	ob := deepBook()
+	i := 0
+	for b.Loop() {
+		price := ob.SellBook.BestPrice()
+		ob.MatchOrder(newOrder("t", domain.SideBuy, price, 100))
+		_ = scanBestPrice(ob.SellBook)
+		ob.AddOrder(newOrder(fmt.Sprintf("r%d", i), domain.SideSell, price+benchLevels, 100))
+		_ = scanBestPrice(ob.SellBook)
+		i++
+	}
+}
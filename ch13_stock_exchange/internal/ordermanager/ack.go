@@ -0,0 +1,47 @@
+package ordermanager
+
+// Order acknowledgement.
+//
+// PlaceOrder returns once the order is on its way to the sequencer. Callers
+// that need to know when the exchange has actually handled it, like the load
+// test measuring end-to-end latency, wait on Acked: it fires once the
+// matching engine's answer (the order resting, filling or being canceled)
+// has been applied here, fills settled included.
+
+// closedAck is returned for orders already acknowledged.
+var closedAck = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// Acked returns a channel that is closed once the matching engine's answer
+// to the order has been applied. It is closed already if that has happened;
+// it is nil for an order the manager does not know, and never closed for
+// one the engine refused as a duplicate.
+func (m *Manager) Acked(orderID string) <-chan struct{} {
+	m.ordersMu.Lock()
+	defer m.ordersMu.Unlock()
+	order, exists := m.orders[orderID]
+	if !exists {
+		return nil
+	}
+	if order.SequenceID != 0 {
+		return closedAck
+	}
+	ch, waiting := m.acks[orderID]
+	if !waiting {
+		ch = make(chan struct{})
+		m.acks[orderID] = ch
+	}
+	return ch
+}
+
+// ack wakes whoever waits for the order's acknowledgement. Caller must hold
+// ordersMu for writing.
+func (m *Manager) ack(orderID string) {
+	if ch, exists := m.acks[orderID]; exists {
+		close(ch)
+		delete(m.acks, orderID)
+	}
+}
@@ -0,0 +1,47 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcked_ClosesOnceTheEngineAnswerIsApplied(t *testing.T) {
+	m := newTestManager()
+	engine := matching.NewEngine()
+	var seq uint64
+	match := func() {
+		event := <-m.OrderOut
+		seq++
+		event.Order.SequenceID = seq
+		m.processExecutionEvent(engine.HandleOrder(event))
+	}
+	sell, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+
+	acked := m.Acked(sell.OrderID)
+	select {
+	case <-acked:
+		t.Fatal("acknowledged before it was matched")
+	default:
+	}
+	match()
+	assert.Equal(t, domain.OrderStatusNew, m.GetOrder(sell.OrderID).Status)
+	<-acked
+
+	// A taker is acknowledged with its fills settled
+	buy, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 100)
+	require.NoError(t, err)
+	acked = m.Acked(buy.OrderID)
+	match()
+	<-acked
+	assert.Equal(t, int64(5100), m.GetWallet("user1").Holdings["AAPL"])
+
+	// Already acknowledged, or unknown
+	<-m.Acked(sell.OrderID)
+	assert.Nil(t, m.Acked("missing"))
+	assert.Empty(t, m.acks)
+}
@@ -0,0 +1,35 @@
+package ordermanager
+
+import (
+	"sort"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// CancelAll cancels every open order of a user, only those on symbol unless
+// symbol is empty, through the sequencer like a user cancel, oldest first.
+// Filled and canceled orders are skipped. The orders are collected and the
+// cancels emitted under the order map lock, so a fill applied concurrently
+// either lands before an order is looked at or after its cancel is queued.
+// Returns how many cancels were issued.
+func (m *Manager) CancelAll(userID, symbol string) int {
+	m.ordersMu.RLock()
+	defer m.ordersMu.RUnlock()
+
+	var canceling []*domain.Order
+	for _, order := range m.orders {
+		if order.UserID != userID || (symbol != "" && order.Symbol != symbol) {
+			continue
+		}
+		if !isOpen(order) {
+			continue
+		}
+		canceling = append(canceling, order)
+	}
+	sort.Slice(canceling, func(i, j int) bool { return canceling[i].CreatedAt.Before(canceling[j].CreatedAt) })
+
+	for _, order := range canceling {
+		m.emitOrderEvent(&domain.OrderEvent{Action: domain.OrderActionCancel, Order: order})
+	}
+	return len(canceling)
+}
@@ -0,0 +1,51 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelAll_UserAndSymbol(t *testing.T) {
+	m := newTestManager()
+	m.InitWallet("user1", 10_000_000, map[string]int64{"AAPL": 5000, "MSFT": 100})
+	_, match := newMatcher(t, m, false)
+	place := func(user, symbol string, side domain.Side, price, qty int64) *domain.Order {
+		t.Helper()
+		order, err := m.PlaceOrder(user, symbol, side, price, qty)
+		require.NoError(t, err)
+		match()
+		return order
+	}
+
+	filled := place("user1", "AAPL", domain.SideSell, 10000, 10)
+	place("user2", "AAPL", domain.SideBuy, 10000, 10)
+	bid := place("user1", "AAPL", domain.SideBuy, 9900, 100)
+	ask := place("user1", "AAPL", domain.SideSell, 10500, 50)
+	msft := place("user1", "MSFT", domain.SideSell, 30000, 10)
+	other := place("user2", "AAPL", domain.SideBuy, 9800, 10)
+
+	assert.Equal(t, 2, m.CancelAll("user1", "AAPL"))
+	for range 2 {
+		match()
+	}
+	assert.Equal(t, domain.OrderStatusFilled, m.GetOrder(filled.OrderID).Status)
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(bid.OrderID).Status)
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(ask.OrderID).Status)
+	assert.Equal(t, domain.OrderStatusNew, m.GetOrder(msft.OrderID).Status)
+	assert.Equal(t, domain.OrderStatusNew, m.GetOrder(other.OrderID).Status)
+	assert.NotContains(t, m.wallets["user1"].WithheldCash, bid.OrderID)
+	assert.NotContains(t, m.wallets["user1"].WithheldShares, ask.OrderID)
+
+	// Without a symbol every remaining open order of the user goes
+	assert.Equal(t, 1, m.CancelAll("user1", ""))
+	match()
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(msft.OrderID).Status)
+
+	assert.Equal(t, 0, m.CancelAll("user1", ""))
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+}
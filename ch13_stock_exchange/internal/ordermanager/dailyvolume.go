@@ -0,0 +1,62 @@
+package ordermanager
+
+import (
+	"time"
+)
+
+// Daily volume.
+//
+// Each wallet counts, per symbol, the quantity its orders added since the
+// start of the trading day, and orders that would take it past
+// maxDailyVolume are rejected. The trading day is the calendar day in the
+// manager's timezone (the server's local one unless SetDailyVolumeTimezone
+// says otherwise). Counts roll over lazily: the first time a wallet's volume
+// is touched on a new day, every count of that wallet starts over at zero.
+// Volume given back later (a shrunk or released reservation, a modify the
+// matching engine refused) comes off the current day's count, never below
+// zero, so an order placed before midnight cannot free up more than today's
+// volume.
+
+// SetDailyVolumeTimezone sets the timezone whose midnight resets daily
+// volume. Must be called before Start.
+func (m *Manager) SetDailyVolumeTimezone(loc *time.Location) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.volumeLocation = loc
+}
+
+// rollDailyVolume starts the wallet's counts over if its day has ended.
+// Caller must hold m.mu and the wallet user's lock.
+func (m *Manager) rollDailyVolume(wallet *Wallet) {
+	today := m.volumeDayOf(m.now())
+	if wallet.volumeDay != today {
+		clear(wallet.dailyVolume)
+		wallet.volumeDay = today
+	}
+}
+
+// volumeDayOf returns the trading day t falls on.
+func (m *Manager) volumeDayOf(t time.Time) string {
+	return t.In(m.volumeLocation).Format(time.DateOnly)
+}
+
+// dailyVolumeOf returns the wallet's volume today on symbol. Caller must hold
+// m.mu and the wallet user's lock.
+func (m *Manager) dailyVolumeOf(wallet *Wallet, symbol string) int64 {
+	m.rollDailyVolume(wallet)
+	return wallet.dailyVolume[symbol]
+}
+
+// addDailyVolume counts quantity towards the wallet's volume today on
+// symbol. Caller must hold m.mu and the wallet user's lock.
+func (m *Manager) addDailyVolume(wallet *Wallet, symbol string, quantity int64) {
+	m.rollDailyVolume(wallet)
+	wallet.dailyVolume[symbol] += quantity
+}
+
+// refundDailyVolume gives back quantity counted by addDailyVolume. Caller
+// must hold m.mu and the wallet user's lock.
+func (m *Manager) refundDailyVolume(wallet *Wallet, symbol string, quantity int64) {
+	m.rollDailyVolume(wallet)
+	wallet.dailyVolume[symbol] = max(0, wallet.dailyVolume[symbol]-quantity)
+}
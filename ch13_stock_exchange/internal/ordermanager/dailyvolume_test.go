@@ -0,0 +1,55 @@
+package ordermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyVolume_ResetsAtMidnight(t *testing.T) {
+	newYork := time.FixedZone("EST", -5*60*60)
+	m := NewManager(100, 100)
+	m.InitWallet("user1", 10_000_000, nil)
+	m.SetDailyVolumeTimezone(newYork)
+	clock := time.Date(2025, 1, 15, 23, 30, 0, 0, newYork)
+	m.SetClock(func() time.Time { return clock })
+
+	_, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 100, 100)
+	require.NoError(t, err)
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 100, 1)
+	assert.Equal(t, RejectDailyLimit, RejectCodeOf(err))
+
+	// Midnight UTC has passed, but not midnight in New York
+	clock = time.Date(2025, 1, 15, 23, 59, 0, 0, newYork)
+	require.Equal(t, 16, clock.UTC().Day())
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 100, 1)
+	assert.Equal(t, RejectDailyLimit, RejectCodeOf(err))
+
+	clock = time.Date(2025, 1, 16, 0, 0, 0, 0, newYork)
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 100, 100)
+	require.NoError(t, err)
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 100, 1)
+	assert.Equal(t, RejectDailyLimit, RejectCodeOf(err))
+}
+
+func TestDailyVolume_RefundAfterRolloverStaysAtZero(t *testing.T) {
+	m := NewManager(100, 100)
+	m.InitWallet("user1", 10_000_000, nil)
+	m.SetDailyVolumeTimezone(time.UTC)
+	clock := time.Date(2025, 1, 15, 23, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return clock })
+
+	r, err := m.ReserveOrder("user1", "AAPL", domain.SideBuy, 100, 80, OrderOptions{})
+	require.NoError(t, err)
+
+	// The reservation placed yesterday is released today
+	clock = clock.Add(2 * time.Hour)
+	require.NoError(t, m.CancelReservation(r.Token))
+	assert.Zero(t, m.wallets["user1"].dailyVolume["AAPL"])
+
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 100, 101)
+	assert.Equal(t, RejectDailyLimit, RejectCodeOf(err))
+}
@@ -0,0 +1,72 @@
+package ordermanager
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RejectCode classifies why an order was refused, so API clients can branch
+// on the reason instead of parsing messages.
+type RejectCode string
+
+const (
+	// RejectInvalidRequest: the request body is malformed or misses fields
+	RejectInvalidRequest RejectCode = "INVALID_REQUEST"
+	// RejectInvalidSide: side is neither buy nor sell
+	RejectInvalidSide RejectCode = "INVALID_SIDE"
+	// RejectInvalidOptions: min_exec_qty, time in force, expiry or rounding mode is invalid
+	RejectInvalidOptions RejectCode = "INVALID_OPTIONS"
+	// RejectUnknownUser: the user has no wallet
+	RejectUnknownUser RejectCode = "UNKNOWN_USER"
+	// RejectUnknownOrder: a modify names an order that does not exist
+	RejectUnknownOrder RejectCode = "UNKNOWN_ORDER"
+	// RejectDailyLimit: the order would exceed the user's daily volume on the symbol
+	RejectDailyLimit RejectCode = "DAILY_LIMIT"
+	// RejectInsufficientFunds: a buy costs more cash than is available
+	RejectInsufficientFunds RejectCode = "INSUFFICIENT_FUNDS"
+	// RejectInsufficientShares: a sell needs more shares than are available
+	RejectInsufficientShares RejectCode = "INSUFFICIENT_SHARES"
+	// RejectOffTick: the price is not on the symbol's tick grid and may not be rounded
+	RejectOffTick RejectCode = "OFF_TICK"
+	// RejectOffLot: the quantity is not a multiple of the symbol's lot size
+	RejectOffLot RejectCode = "OFF_LOT"
+	// RejectPriceBand: the price is further from the symbol's reference price than its band allows
+	RejectPriceBand RejectCode = "PRICE_BAND"
+	// RejectNoMarketPrice: a market buy cannot be priced because the book has no asks
+	RejectNoMarketPrice RejectCode = "NO_MARKET_PRICE"
+	// RejectMarketClosed: the symbol's trading hours are over
+	RejectMarketClosed RejectCode = "MARKET_CLOSED"
+	// RejectShuttingDown: the exchange stopped accepting orders to shut down
+	RejectShuttingDown RejectCode = "SHUTTING_DOWN"
+	// RejectQueueFull: the order intake had no room for the request
+	RejectQueueFull RejectCode = "QUEUE_FULL"
+)
+
+// OrderError is an order rejected by validation or a risk check.
+type OrderError struct {
+	Code    RejectCode
+	Message string
+	Bounds  *PriceBounds // the accepted prices, for RejectPriceBand
+}
+
+// PriceBounds is an inclusive range of prices in cents.
+type PriceBounds struct {
+	Low  int64 `json:"low"`
+	High int64 `json:"high"`
+}
+
+func (e *OrderError) Error() string { return e.Message }
+
+// rejectf returns an *OrderError with a formatted message.
+func rejectf(code RejectCode, format string, args ...any) error {
+	return &OrderError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// RejectCodeOf returns the code of an order rejection, or "" when err is not one.
+func RejectCodeOf(err error) RejectCode {
+	var oe *OrderError
+	if errors.As(err, &oe) {
+		return oe.Code
+	}
+	return ""
+}
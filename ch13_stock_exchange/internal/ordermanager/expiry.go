@@ -0,0 +1,128 @@
+package ordermanager
+
+import (
+	"container/heap"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+const expirySweepInterval = time.Second
+
+// expiryEntry is one good-till-date order in the expiry index.
+type expiryEntry struct {
+	expiresAt time.Time
+	orderID   string
+}
+
+// expiryQueue is a min-heap of GTD orders by expiry time, so the reaper only
+// looks at orders that are due. Entries of orders that finished early stay
+// until they come due and are skipped then.
+type expiryQueue []expiryEntry
+
+func (q expiryQueue) Len() int { return len(q) }
+func (q expiryQueue) Less(i, j int) bool {
+	if !q[i].expiresAt.Equal(q[j].expiresAt) {
+		return q[i].expiresAt.Before(q[j].expiresAt)
+	}
+	return q[i].orderID < q[j].orderID
+}
+func (q expiryQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x any)   { *q = append(*q, x.(expiryEntry)) }
+func (q *expiryQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// trackExpiry adds a GTD order to the expiry index. Caller must hold ordersMu.
+func (m *Manager) trackExpiry(order *domain.Order) {
+	if order.ExpiresAt == nil {
+		return
+	}
+	heap.Push(&m.expiries, expiryEntry{expiresAt: *order.ExpiresAt, orderID: order.OrderID})
+}
+
+// isOpen reports whether an order can still rest or fill.
+func isOpen(order *domain.Order) bool {
+	return order.Status != domain.OrderStatusFilled && order.Status != domain.OrderStatusCanceled
+}
+
+// ExpireOrders cancels every open GTD order whose expiry has passed, through
+// the sequencer like a user cancel, and returns them in expiry order. Each
+// one counts towards exchange_orders_expired_total. When the order intake is
+// full the remaining orders stay in the expiry index for the next sweep, and
+// only the cancels actually sent are returned.
+func (m *Manager) ExpireOrders() []*domain.Order {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.ordersMu.Lock()
+	defer m.ordersMu.Unlock()
+
+	now := m.now()
+	var expired []*domain.Order
+	for m.expiries.Len() > 0 && !m.expiries[0].expiresAt.After(now) {
+		entry := heap.Pop(&m.expiries).(expiryEntry)
+		order, exists := m.orders[entry.orderID]
+		if !exists || !isOpen(order) {
+			continue
+		}
+		if !m.emitOrderEvent(&domain.OrderEvent{Action: domain.OrderActionCancel, Order: order}) {
+			heap.Push(&m.expiries, entry)
+			break
+		}
+		middleware.OrdersExpired.WithLabelValues(order.Symbol).Inc()
+		expired = append(expired, order)
+	}
+	return expired
+}
+
+// ExpiringOrders returns copies of the open GTD orders that expire within
+// the given window from now, soonest first, so clients can renew them.
+func (m *Manager) ExpiringOrders(within time.Duration) []*domain.Order {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.ordersMu.RLock()
+	defer m.ordersMu.RUnlock()
+
+	cutoff := m.now().Add(within)
+	var expiring []*domain.Order
+	for _, entry := range m.expiries {
+		if entry.expiresAt.After(cutoff) {
+			continue
+		}
+		order, exists := m.orders[entry.orderID]
+		if !exists || !isOpen(order) {
+			continue
+		}
+		copied := *order
+		expiring = append(expiring, &copied)
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		if !expiring[i].ExpiresAt.Equal(*expiring[j].ExpiresAt) {
+			return expiring[i].ExpiresAt.Before(*expiring[j].ExpiresAt)
+		}
+		return expiring[i].OrderID < expiring[j].OrderID
+	})
+	return expiring
+}
+
+// sweepExpiredOrders periodically cancels GTD orders past their expiry.
+func (m *Manager) sweepExpiredOrders() {
+	ticker := time.NewTicker(expirySweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if expired := m.ExpireOrders(); len(expired) > 0 {
+				log.Printf("[ordermanager] expired %d GTD orders", len(expired))
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package ordermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gtd(expiresAt time.Time) OrderOptions {
+	return OrderOptions{TimeInForce: domain.TimeInForceGTD, ExpiresAt: expiresAt}
+}
+
+func TestPlaceOrder_GTDValidation(t *testing.T) {
+	m := newTestManager()
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return now })
+
+	_, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 10, OrderOptions{TimeInForce: domain.TimeInForceGTD})
+	assert.Error(t, err, "GTD needs an expiry")
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 10, gtd(now))
+	assert.Error(t, err, "expiry must be in the future")
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 10, OrderOptions{ExpiresAt: now.Add(time.Hour)})
+	assert.Error(t, err, "expiry only applies to GTD")
+
+	order, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 10, gtd(now.Add(time.Hour)))
+	require.NoError(t, err)
+	require.NotNil(t, order.ExpiresAt)
+	assert.Equal(t, now.Add(time.Hour), *order.ExpiresAt)
+}
+
+func TestExpiringOrders_ListsOnlyNearExpiries(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return now })
+
+	later, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 9900, 10, gtd(now.Add(4*time.Minute)))
+	require.NoError(t, err)
+	match()
+	sooner, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 9800, 10, gtd(now.Add(time.Minute)))
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 9700, 10, gtd(now.Add(time.Hour)))
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 9600, 10)
+	require.NoError(t, err)
+	match()
+
+	// A near GTD order that already filled is not listed
+	filled, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10100, 10, gtd(now.Add(time.Minute)))
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, 10100, 10)
+	require.NoError(t, err)
+	match()
+	require.Equal(t, domain.OrderStatusFilled, m.GetOrder(filled.OrderID).Status)
+
+	expiring := m.ExpiringOrders(5 * time.Minute)
+	require.Len(t, expiring, 2)
+	assert.Equal(t, sooner.OrderID, expiring[0].OrderID)
+	assert.Equal(t, later.OrderID, expiring[1].OrderID)
+}
+
+func TestExpireOrders_CancelsDueOrdersAndCounts(t *testing.T) {
+	m := newTestManager()
+	engine, match := newMatcher(t, m, false)
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return now })
+	expiredBefore := testutil.ToFloat64(middleware.OrdersExpired.WithLabelValues("AAPL"))
+
+	near, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 100, gtd(now.Add(time.Minute)))
+	require.NoError(t, err)
+	match()
+	far, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 9900, 100, gtd(now.Add(time.Hour)))
+	require.NoError(t, err)
+	match()
+
+	// Nothing is due yet
+	assert.Empty(t, m.ExpireOrders())
+
+	now = now.Add(time.Minute)
+	expired := m.ExpireOrders()
+	require.Len(t, expired, 1)
+	assert.Equal(t, near.OrderID, expired[0].OrderID)
+	assert.Equal(t, expiredBefore+1, testutil.ToFloat64(middleware.OrdersExpired.WithLabelValues("AAPL")))
+
+	// The cancel goes through matching and releases the withheld cash
+	match()
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(near.OrderID).Status)
+	assert.NotContains(t, m.wallets["user1"].WithheldCash, near.OrderID)
+	assert.Equal(t, domain.OrderStatusNew, m.GetOrder(far.OrderID).Status)
+
+	// Each order expires once
+	assert.Empty(t, m.ExpireOrders())
+	assert.Equal(t, expiredBefore+1, testutil.ToFloat64(middleware.OrdersExpired.WithLabelValues("AAPL")))
+
+	snap := engine.GetOrderBook("AAPL").GetL2Snapshot(10)
+	require.Len(t, snap.Bids, 1)
+	assert.Equal(t, int64(9900), snap.Bids[0].Price)
+}
+
+func TestExpireOrders_FullIntakeKeepsOrdersForNextSweep(t *testing.T) {
+	m := NewManager(1_000_000, 2)
+	m.InitWallet("user1", 10_000_000, nil)
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return now })
+
+	first, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 1, gtd(now.Add(time.Minute)))
+	require.NoError(t, err)
+	second, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 9900, 1, gtd(now.Add(2*time.Minute)))
+	require.NoError(t, err)
+
+	// Both new orders still fill the intake, so no cancel can be sent
+	now = now.Add(time.Hour)
+	assert.Empty(t, m.ExpireOrders())
+	assert.Equal(t, 2, m.expiries.Len())
+
+	<-m.OrderOut
+	expired := m.ExpireOrders()
+	require.Len(t, expired, 1)
+	assert.Equal(t, first.OrderID, expired[0].OrderID)
+
+	<-m.OrderOut
+	expired = m.ExpireOrders()
+	require.Len(t, expired, 1)
+	assert.Equal(t, second.OrderID, expired[0].OrderID)
+	assert.Zero(t, m.expiries.Len())
+}
@@ -0,0 +1,165 @@
+package ordermanager
+
+import (
+	"log"
+	"sync"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// fairQueue buffers order events in one sub-queue per active user and hands
+// them out round-robin across users, so a user flooding orders cannot starve
+// everyone else behind one FIFO. All events of one user land in that user's
+// sub-queue, which keeps their orders (and cancels) in submission order.
+type fairQueue struct {
+	mu       sync.Mutex
+	queues   map[string][]*domain.OrderEvent // by user ID, only while non-empty
+	active   []string                        // users with queued events, in round-robin order
+	next     int                             // index in active to serve on the next pop
+	pending  int
+	capacity int
+	notify   chan struct{}
+	// last is handed out, oldest first, once every sub-queue is empty (see
+	// pushLast)
+	last []*domain.OrderEvent
+}
+
+func newFairQueue(capacity int) *fairQueue {
+	return &fairQueue{
+		queues:   make(map[string][]*domain.OrderEvent),
+		capacity: capacity,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// push appends an event to its user's sub-queue. It returns false when the
+// queue is at capacity.
+func (q *fairQueue) push(event *domain.OrderEvent) bool {
+	userID := event.Order.UserID
+	q.mu.Lock()
+	queued := q.queues[userID]
+	if q.pending >= q.capacity {
+		q.mu.Unlock()
+		return false
+	}
+	if len(queued) == 0 {
+		q.active = append(q.active, userID)
+	}
+	q.queues[userID] = append(queued, event)
+	q.pending++
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pushLast queues an event that is handed out only after everything else
+// queued, regardless of round-robin order. It is not counted against the
+// capacity.
+func (q *fairQueue) pushLast(event *domain.OrderEvent) {
+	q.mu.Lock()
+	q.last = append(q.last, event)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop removes the head of the next active user's sub-queue in round-robin
+// order. A user whose sub-queue empties drops out of the rotation.
+func (q *fairQueue) pop() (*domain.OrderEvent, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending == 0 {
+		if len(q.last) > 0 {
+			last := q.last[0]
+			q.last = q.last[1:]
+			return last, true
+		}
+		return nil, false
+	}
+	userID := q.active[q.next]
+	queued := q.queues[userID]
+	event := queued[0]
+	queued[0] = nil
+	q.pending--
+	if len(queued) == 1 {
+		delete(q.queues, userID)
+		q.active = append(q.active[:q.next], q.active[q.next+1:]...)
+	} else {
+		q.queues[userID] = queued[1:]
+		q.next++
+	}
+	if q.next >= len(q.active) {
+		q.next = 0
+	}
+	return event, true
+}
+
+// EnableFairQueuing routes order intake through a sub-queue per user, served
+// round-robin, instead of one shared FIFO. OrderOut becomes unbuffered so the
+// backlog stays in the fair queue, where it can be reordered. Must be called
+// before Start and before OrderOut is consumed.
+func (m *Manager) EnableFairQueuing() {
+	m.fair = newFairQueue(cap(m.OrderOut))
+	m.OrderOut = make(chan *domain.OrderEvent)
+}
+
+// emitOrderEvent hands an order event to the sequencer, through the fair
+// queue when enabled, and reports whether it was accepted. Sends never block
+// the caller; a full intake drops the event with a warning. Sent events count
+// in the orders metric by action, dropped ones in the dropped-orders metric.
+func (m *Manager) emitOrderEvent(event *domain.OrderEvent) bool {
+	// The sequencer and the matching engine update the order they are handed
+	// on their own goroutines: give them a copy, so the stored order only
+	// changes from the States of the execution events that come back
+	order := *event.Order
+	event.Order = &order
+
+	var sent bool
+	if m.fair != nil {
+		sent = m.fair.push(event)
+	} else {
+		select {
+		case m.OrderOut <- event:
+			sent = true
+		default:
+		}
+	}
+
+	labels := []string{string(event.Action), event.Order.Symbol}
+	if !sent {
+		log.Printf("[ordermanager] WARN: order intake full, dropped %s %s", event.Action, event.Order.OrderID)
+		middleware.OrdersDropped.WithLabelValues(labels...).Inc()
+		return false
+	}
+	middleware.OrdersTotal.WithLabelValues(labels...).Inc()
+	return true
+}
+
+// dispatchFairQueue feeds OrderOut from the fair queue until Stop.
+func (m *Manager) dispatchFairQueue() {
+	for {
+		event, ok := m.fair.pop()
+		if !ok {
+			select {
+			case <-m.fair.notify:
+				continue
+			case <-m.done:
+				return
+			}
+		}
+		select {
+		case m.OrderOut <- event:
+		case <-m.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package ordermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFairQueuing_BurstDoesNotStarveOtherUsers(t *testing.T) {
+	m := NewManager(1_000_000, 1000)
+	m.InitWallet("whale", 0, map[string]int64{"AAPL": 10_000})
+	m.InitWallet("alice", 0, map[string]int64{"AAPL": 10_000})
+	m.EnableFairQueuing()
+
+	// Queue a burst and a few orders behind it before anything is dispatched
+	const burst = 100
+	var whaleIDs, aliceIDs []string
+	for i := 0; i < burst; i++ {
+		o, err := m.PlaceOrder("whale", "AAPL", domain.SideSell, int64(10000+i), 1)
+		require.NoError(t, err)
+		whaleIDs = append(whaleIDs, o.OrderID)
+	}
+	for i := 0; i < 3; i++ {
+		o, err := m.PlaceOrder("alice", "AAPL", domain.SideSell, 10000, 1)
+		require.NoError(t, err)
+		aliceIDs = append(aliceIDs, o.OrderID)
+	}
+
+	m.Start()
+	defer m.Stop()
+
+	var sequenced []*domain.Order
+	for len(sequenced) < burst+3 {
+		select {
+		case ev := <-m.OrderOut:
+			sequenced = append(sequenced, ev.Order)
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of %d orders dispatched", len(sequenced), burst+3)
+		}
+	}
+
+	// The other user's orders interleave with the burst instead of waiting behind it
+	var gotAlice, gotWhale []string
+	lastAlice := -1
+	for i, o := range sequenced {
+		if o.UserID == "alice" {
+			gotAlice = append(gotAlice, o.OrderID)
+			lastAlice = i
+		} else {
+			gotWhale = append(gotWhale, o.OrderID)
+		}
+	}
+	assert.Equal(t, 5, lastAlice, "other user's orders should be sequenced within the first few slots")
+
+	// Per-user submission order is preserved
+	assert.Equal(t, aliceIDs, gotAlice)
+	assert.Equal(t, whaleIDs, gotWhale)
+}
+
+func TestFairQueuing_CancelStaysBehindItsOrder(t *testing.T) {
+	m := newTestManager()
+	m.EnableFairQueuing()
+
+	order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 1)
+	require.NoError(t, err)
+	_, err = m.CancelOrder(order.OrderID)
+	require.NoError(t, err)
+
+	m.Start()
+	defer m.Stop()
+
+	first := <-m.OrderOut
+	second := <-m.OrderOut
+	assert.Equal(t, domain.OrderActionNew, first.Action)
+	assert.Equal(t, domain.OrderActionCancel, second.Action)
+}
+
+func TestFairQueuing_DisabledIsFIFO(t *testing.T) {
+	m := NewManager(1_000_000, 1000)
+	m.InitWallet("whale", 0, map[string]int64{"AAPL": 10_000})
+	m.InitWallet("alice", 0, map[string]int64{"AAPL": 10_000})
+
+	for i := 0; i < 10; i++ {
+		_, err := m.PlaceOrder("whale", "AAPL", domain.SideSell, 10000, 1)
+		require.NoError(t, err)
+	}
+	_, err := m.PlaceOrder("alice", "AAPL", domain.SideSell, 10000, 1)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "whale", (<-m.OrderOut).Order.UserID)
+	}
+	assert.Equal(t, "alice", (<-m.OrderOut).Order.UserID)
+}
+
+func TestFairQueuing_RoundRobinsAcrossManyUsers(t *testing.T) {
+	m := NewManager(1_000_000, 1000)
+	users := []string{"u1", "u2", "u3", "u4", "u5", "u6", "u7", "u8"}
+	for _, u := range users {
+		m.InitWallet(u, 0, map[string]int64{"AAPL": 100})
+	}
+	m.EnableFairQueuing()
+
+	// Every user gets a turn before anyone's second order
+	for round := 0; round < 2; round++ {
+		for _, u := range users {
+			_, err := m.PlaceOrder(u, "AAPL", domain.SideSell, 10000, 1)
+			require.NoError(t, err)
+		}
+	}
+
+	m.Start()
+	defer m.Stop()
+
+	for round := 0; round < 2; round++ {
+		for _, u := range users {
+			assert.Equal(t, u, (<-m.OrderOut).Order.UserID)
+		}
+	}
+}
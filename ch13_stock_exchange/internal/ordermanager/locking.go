@@ -0,0 +1,69 @@
+package ordermanager
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// DefaultLockStripes is the number of per-user wallet locks. Users are hashed
+// onto stripes, so orders from users on different stripes proceed in parallel.
+const DefaultLockStripes = 64
+
+// Lock hierarchy. Locks are always taken in this order, never the reverse:
+//
+//  1. mu: manager-wide state (wallet registry, symbols, baselines, settings).
+//     Order and settlement paths hold it shared; admin operations and
+//     system-wide snapshots (InitWallet, VerifyConservation, setters) hold it
+//     exclusively, which waits out every in-flight wallet update.
+//  2. resMu: the reservations map.
+//  3. userLocks: one stripe per user, guarding that user's Wallet (balances,
+//     withholdings, daily volume). Settlement takes two stripes in index order.
+//  4. ordersMu: the orders map, closedAt, the expiry index, and stored order state.
+
+// SetLockStripes sets the number of per-user wallet locks; 1 serializes all
+// wallet operations as a single lock would. Must be called before Start and
+// before any order is placed.
+func (m *Manager) SetLockStripes(n int) error {
+	if n < 1 {
+		return fmt.Errorf("lock stripes must be at least 1, got %d", n)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.userLocks = make([]sync.Mutex, n)
+	return nil
+}
+
+// stripeIndex hashes a user onto one of n stripes.
+func stripeIndex(userID string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	return int(h.Sum32() % uint32(n))
+}
+
+// lockUser locks the stripe guarding userID's wallet and returns its unlock.
+// Caller must hold m.mu (shared or exclusive).
+func (m *Manager) lockUser(userID string) func() {
+	l := &m.userLocks[stripeIndex(userID, len(m.userLocks))]
+	l.Lock()
+	return l.Unlock
+}
+
+// lockUsers locks the stripes of two users in index order, so concurrent
+// settlements between the same pair cannot deadlock. Caller must hold m.mu.
+func (m *Manager) lockUsers(a, b string) func() {
+	i := stripeIndex(a, len(m.userLocks))
+	j := stripeIndex(b, len(m.userLocks))
+	if i == j {
+		return m.lockUser(a)
+	}
+	if j < i {
+		i, j = j, i
+	}
+	m.userLocks[i].Lock()
+	m.userLocks[j].Lock()
+	return func() {
+		m.userLocks[j].Unlock()
+		m.userLocks[i].Unlock()
+	}
+}
@@ -0,0 +1,215 @@
+package ordermanager
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentOrdersAndSettlement places orders from many users in
+// parallel while a stand-in matching engine pairs buys with sells and feeds
+// executions back. Run with -race: besides data races it checks that every
+// trade settled exactly once and conservation held throughout.
+func TestConcurrentOrdersAndSettlement(t *testing.T) {
+	const (
+		users         = 16
+		ordersPerUser = 200
+		price         = 100
+	)
+
+	m := NewManager(1_000_000_000, users*ordersPerUser)
+	require.NoError(t, m.SetLockStripes(4)) // force some users to share a stripe
+	for u := 0; u < users; u++ {
+		m.InitWallet(fmt.Sprintf("user%d", u), 1_000_000, map[string]int64{"AAPL": 1_000})
+	}
+
+	bought := make(map[string]int64)
+	sold := make(map[string]int64)
+	var unmatched []*domain.Order
+
+	// Stand-in matching engine: every buy matches the oldest waiting sell and
+	// vice versa, one share at a time
+	engineDone := make(chan struct{})
+	go func() {
+		defer close(engineDone)
+		var buys, sells []*domain.Order
+		for received := 0; received < users*ordersPerUser; received++ {
+			var event *domain.OrderEvent
+			select {
+			case event = <-m.OrderOut:
+			case <-time.After(10 * time.Second):
+				t.Errorf("timed out after %d orders", received)
+				return
+			}
+			taker := *event.Order
+			var maker *domain.Order
+			if taker.Side == domain.SideBuy && len(sells) > 0 {
+				maker, sells = sells[0], sells[1:]
+			} else if taker.Side == domain.SideSell && len(buys) > 0 {
+				maker, buys = buys[0], buys[1:]
+			}
+			if maker == nil {
+				if taker.Side == domain.SideBuy {
+					buys = append(buys, &taker)
+				} else {
+					sells = append(sells, &taker)
+				}
+				continue
+			}
+
+			filledTaker, filledMaker := taker, *maker
+			filledTaker.Status, filledTaker.FilledQuantity, filledTaker.RemainingQuantity = domain.OrderStatusFilled, 1, 0
+			filledMaker.Status, filledMaker.FilledQuantity, filledMaker.RemainingQuantity = domain.OrderStatusFilled, 1, 0
+			m.processExecutionEvent(withStates(&domain.ExecutionEvent{
+				TakerOrder:  &filledTaker,
+				MakerOrders: []*domain.Order{&filledMaker},
+				Executions: []*domain.Execution{{
+					ExecID: taker.OrderID + "-exec-1", Symbol: "AAPL", Price: price, Quantity: 1,
+					TakerOrderID: taker.OrderID, MakerOrderID: maker.OrderID,
+				}},
+			}))
+
+			if taker.Side == domain.SideBuy {
+				bought[taker.UserID]++
+				sold[maker.UserID]++
+			} else {
+				bought[maker.UserID]++
+				sold[taker.UserID]++
+			}
+		}
+		unmatched = append(buys, sells...)
+	}()
+
+	// Readers exercise the snapshot paths while orders and settlements run
+	stop := make(chan struct{})
+	var violations atomic.Int64
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := m.VerifyConservation(); err != nil {
+				violations.Add(1)
+			}
+			m.GetWallet("user0")
+		}
+	}()
+
+	var placers sync.WaitGroup
+	for u := 0; u < users; u++ {
+		placers.Add(1)
+		go func(userID string) {
+			defer placers.Done()
+			for i := 0; i < ordersPerUser; i++ {
+				side := domain.SideBuy
+				if i%2 == 1 {
+					side = domain.SideSell
+				}
+				if _, err := m.PlaceOrder(userID, "AAPL", side, price, 1); err != nil {
+					t.Errorf("%s order %d: %v", userID, i, err)
+				}
+			}
+		}(fmt.Sprintf("user%d", u))
+	}
+
+	placers.Wait()
+	<-engineDone
+	close(stop)
+	readers.Wait()
+
+	assert.Zero(t, violations.Load(), "conservation must hold at every snapshot")
+	_, err := m.VerifyConservation()
+	require.NoError(t, err)
+
+	pendingBuys := make(map[string]int64)
+	pendingSells := make(map[string]int64)
+	for _, o := range unmatched {
+		if o.Side == domain.SideBuy {
+			pendingBuys[o.UserID]++
+		} else {
+			pendingSells[o.UserID]++
+		}
+	}
+
+	for u := 0; u < users; u++ {
+		userID := fmt.Sprintf("user%d", u)
+		w := m.wallets[userID]
+		assert.Equal(t, 1_000_000-price*bought[userID]+price*sold[userID], w.CashBalance, userID)
+		assert.Equal(t, 1_000+bought[userID]-sold[userID], w.Holdings["AAPL"], userID)
+		assert.Equal(t, price*pendingBuys[userID], m.totalWithheldCash(w), userID)
+		assert.Equal(t, pendingSells[userID], m.totalWithheldShares(w, "AAPL"), userID)
+		assert.Equal(t, int64(ordersPerUser), w.dailyVolume["AAPL"], userID)
+	}
+}
+
+func TestSetLockStripes_RejectsZero(t *testing.T) {
+	m := NewManager(1_000, 10)
+	assert.Error(t, m.SetLockStripes(0))
+	assert.NoError(t, m.SetLockStripes(1))
+}
+
+// BenchmarkPlaceOrderParallel compares one wallet lock for everyone with the
+// default striping, each goroutine placing and canceling orders for its own
+// user.
+func BenchmarkPlaceOrderParallel(b *testing.B) {
+	for _, stripes := range []int{1, DefaultLockStripes} {
+		b.Run(fmt.Sprintf("stripes=%d", stripes), func(b *testing.B) {
+			const users = 256
+			m := NewManager(1<<62, 4096)
+			if err := m.SetLockStripes(stripes); err != nil {
+				b.Fatal(err)
+			}
+			for u := 0; u < users; u++ {
+				m.InitWallet(fmt.Sprintf("user%d", u), 1<<50, nil)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case <-m.OrderOut:
+					case <-done:
+						return
+					}
+				}
+			}()
+			defer close(done)
+
+			// The drain can fall behind; keep channel-full warnings out of the results
+			log.SetOutput(io.Discard)
+			defer log.SetOutput(os.Stderr)
+
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				userID := fmt.Sprintf("user%d", next.Add(1)%users)
+				for pb.Next() {
+					order, err := m.PlaceOrder(userID, "AAPL", domain.SideBuy, 100, 1)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					// Cancel right away so withholdings don't pile up and the
+					// per-order wallet scan stays constant
+					canceled := *order
+					canceled.Status = domain.OrderStatusCanceled
+					m.processExecutionEvent(withStates(&domain.ExecutionEvent{TakerOrder: &canceled}))
+				}
+			})
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/nathanyu/stock-exchange/internal/batching"
 	"github.com/nathanyu/stock-exchange/internal/domain"
 )
 
@@ -18,6 +19,17 @@ type Wallet struct {
 	WithheldCash map[string]int64 // orderID -> withheld cents
 	// Withheld shares for pending sell orders
 	WithheldShares map[string]withheldShare // orderID -> withheld share info
+
+	// Risk check: daily volume per symbol, counted against maxDailyVolume
+	dailyVolume map[string]int64 // symbol -> volume today, in the symbol's quantity units
+	volumeDay   string           // the day dailyVolume counts, e.g. "2025-01-15" (see dailyvolume.go)
+
+	// Average cost accounting (see pnl.go)
+	costBasis   map[string]int64 // symbol -> cents paid for the held quantity
+	realizedPnL map[string]int64 // symbol -> cents
+
+	// What each open buy order in a fractional symbol has paid so far (see fillCost)
+	fillCharges map[string]*fillCharge // orderID -> running charge
 }
 
 type withheldShare struct {
@@ -28,21 +40,73 @@ type withheldShare struct {
 // Manager handles order validation, risk checks, and wallet management.
 // It receives orders from the API, validates them, and forwards them to the sequencer.
 // It also receives execution events to update wallet balances and order states.
+// See locking.go for how its locks divide up the state.
 type Manager struct {
 	mu sync.RWMutex
 
-	wallets map[string]*Wallet        // userID -> wallet
-	orders  map[string]*domain.Order  // orderID -> order
+	wallets   map[string]*Wallet // userID -> wallet
+	userLocks []sync.Mutex       // striped per-user wallet locks
 
-	// Risk check: per-user per-symbol daily volume limit
-	dailyVolume map[string]int64 // "userID:symbol" -> volume today
+	ordersMu sync.RWMutex
+	orders   map[string]*domain.Order // orderID -> order
+
+	// Risk check: per-user per-symbol daily volume limit in whole shares (tracked per wallet)
 	maxDailyVolume int64
+	// Timezone whose midnight resets daily volume (see dailyvolume.go)
+	volumeLocation *time.Location
+	// Sessions new orders are limited to (see tradinghours.go); "" = default
+	tradingHours map[string]TradingHours
+
+	// Per-symbol trading rules (see symbols.go)
+	symbols map[string]SymbolSpec
+	// Reference prices for price bands (see symbols.go); nil disables bands
+	referencePrice func(symbol string) int64
+	// Ask depth per symbol for pricing market buys (see market.go); nil
+	// rejects every market buy
+	askDepth func(symbol string, quantity int64) int64
+	// Last trade price per symbol for unrealized PnL (see pnl.go)
+	lastPrice func(symbol string) int64
+
+	// Conservation baseline: totals seeded through InitWallet
+	baselineCash   int64
+	baselineShares map[string]int64 // symbol -> shares
+	// Per-user starting balances, replayed by reconciliation (see reconcile.go)
+	initialWallets map[string]walletBaseline
+
+	// Two-phase orders awaiting commit (see reservations.go)
+	resMu          sync.Mutex
+	reservations   map[string]*Reservation // token -> reservation
+	reservationTTL time.Duration
+
+	// Good-till-date expiry index (see expiry.go); guarded by ordersMu
+	expiries expiryQueue
+
+	// Waiters for the matching engine's first answer to an order (see
+	// ack.go); guarded by ordersMu
+	acks map[string]chan struct{}
+
+	// Terminal order eviction (see retention.go)
+	closedAt       map[string]time.Time // orderID -> when it was filled or canceled
+	orderRetention time.Duration
+
+	now func() time.Time
 
 	// Channel to send validated orders to the sequencer
 	OrderOut chan *domain.OrderEvent
 
 	// Channel to receive execution events from the sequencer
 	ExecutionIn chan *domain.ExecutionEvent
+	batching    batching.Options
+
+	// Optional per-user fair intake in front of OrderOut (see fairqueue.go)
+	fair *fairQueue
+	// Set at shutdown: no new orders are accepted (see shutdown.go); guarded by mu
+	intakeClosed bool
+
+	// Wallet initializations journaled for restarts, and those loaded from
+	// the previous run still to be replayed (see recovery.go); guarded by mu
+	walletJournal *walletJournal
+	pendingInits  []walletInit
 
 	done chan struct{}
 }
@@ -51,40 +115,100 @@ type Manager struct {
 func NewManager(maxDailyVolume int64, bufferSize int) *Manager {
 	return &Manager{
 		wallets:        make(map[string]*Wallet),
+		userLocks:      make([]sync.Mutex, DefaultLockStripes),
 		orders:         make(map[string]*domain.Order),
-		dailyVolume:    make(map[string]int64),
 		maxDailyVolume: maxDailyVolume,
+		volumeLocation: time.Local,
+		tradingHours:   make(map[string]TradingHours),
+		baselineShares: make(map[string]int64),
+		initialWallets: make(map[string]walletBaseline),
+		symbols:        make(map[string]SymbolSpec),
+		reservations:   make(map[string]*Reservation),
+		reservationTTL: DefaultReservationTTL,
+		acks:           make(map[string]chan struct{}),
+		closedAt:       make(map[string]time.Time),
+		now:            time.Now,
 		OrderOut:       make(chan *domain.OrderEvent, bufferSize),
 		ExecutionIn:    make(chan *domain.ExecutionEvent, bufferSize),
 		done:           make(chan struct{}),
 	}
 }
 
-// Start begins the execution listener goroutine.
+// Start begins the execution listener, reservation, expiry and terminal
+// order sweepers, and the fair queue dispatcher when fair queuing is enabled.
 func (m *Manager) Start() {
 	go m.listenExecutions()
+	go m.sweepReservations()
+	go m.sweepExpiredOrders()
+	go m.sweepTerminalOrders()
+	if m.fair != nil {
+		go m.dispatchFairQueue()
+	}
 }
 
 // Stop shuts down the manager.
 func (m *Manager) Stop() {
 	close(m.done)
+	m.closeWalletJournal()
+}
+
+// SetExecutionBatching coalesces execution events that arrive in a burst
+// into one processing pass. Must be called before Start.
+func (m *Manager) SetExecutionBatching(opts batching.Options) {
+	m.batching = opts
+}
+
+// SetClock overrides the time source (used by tests).
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
 }
 
 // InitWallet initializes a user's wallet with starting balances.
 func (m *Manager) InitWallet(userID string, cashBalance int64, holdings map[string]int64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.initWallet(userID, cashBalance, holdings)
+	m.walletJournal.append(walletInit{UserID: userID, Cash: cashBalance, Holdings: holdings})
+}
+
+// initWallet is InitWallet for callers that hold m.mu exclusively.
+func (m *Manager) initWallet(userID string, cashBalance int64, holdings map[string]int64) {
+	// Re-initializing a wallet replaces its balances, so move the baseline
+	// by the difference rather than counting the user twice. Today's volume
+	// and realized PnL carry over; the new holdings start at zero cost.
+	volume := make(map[string]int64)
+	var volumeDay string
+	realized := make(map[string]int64)
+	if old, exists := m.wallets[userID]; exists {
+		m.baselineCash -= old.CashBalance
+		for sym, qty := range old.Holdings {
+			m.baselineShares[sym] -= qty
+		}
+		volume, volumeDay = old.dailyVolume, old.volumeDay
+		realized = old.realizedPnL
+	}
 
 	h := make(map[string]int64)
+	initial := make(map[string]int64)
 	for k, v := range holdings {
 		h[k] = v
+		initial[k] = v
+		m.baselineShares[k] += v
 	}
+	m.baselineCash += cashBalance
+	m.initialWallets[userID] = walletBaseline{cash: cashBalance, holdings: initial}
 
 	m.wallets[userID] = &Wallet{
 		CashBalance:    cashBalance,
 		Holdings:       h,
 		WithheldCash:   make(map[string]int64),
 		WithheldShares: make(map[string]withheldShare),
+		dailyVolume:    volume,
+		volumeDay:      volumeDay,
+		costBasis:      make(map[string]int64),
+		realizedPnL:    realized,
 	}
 }
 
@@ -97,6 +221,7 @@ func (m *Manager) GetWallet(userID string) *Wallet {
 	if !exists {
 		return nil
 	}
+	defer m.lockUser(userID)()
 
 	// Return a copy
 	holdings := make(map[string]int64)
@@ -109,10 +234,10 @@ func (m *Manager) GetWallet(userID string) *Wallet {
 	}
 }
 
-// GetAllWallets returns a copy of all wallets.
+// GetAllWallets returns a consistent copy of all wallets.
 func (m *Manager) GetAllWallets() map[string]*Wallet {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	result := make(map[string]*Wallet)
 	for userID, w := range m.wallets {
@@ -128,35 +253,204 @@ func (m *Manager) GetAllWallets() map[string]*Wallet {
 	return result
 }
 
-// PlaceOrder validates and submits a new order.
-func (m *Manager) PlaceOrder(userID, symbol string, side domain.Side, price, quantity int64) (*domain.Order, error) {
+// ConservationReport compares current system-wide totals against the
+// totals seeded through InitWallet. Trades only move cash and shares between
+// wallets, so any drift indicates a settlement bug.
+type ConservationReport struct {
+	TotalCash      int64            `json:"total_cash"`
+	BaselineCash   int64            `json:"baseline_cash"`
+	CashDrift      int64            `json:"cash_drift"`
+	TotalShares    map[string]int64 `json:"total_shares"`
+	BaselineShares map[string]int64 `json:"baseline_shares"`
+	ShareDrift     map[string]int64 `json:"share_drift"` // only symbols with non-zero drift
+	Balanced       bool             `json:"balanced"`
+}
+
+// VerifyConservation sums cash and per-symbol holdings across all wallets
+// and compares them to the seeded baseline. It returns an error describing
+// the drift when the totals do not match.
+func (m *Manager) VerifyConservation() (ConservationReport, error) {
+	// Exclusive, so no settlement is half-applied while the totals are summed
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	report := ConservationReport{
+		BaselineCash:   m.baselineCash,
+		TotalShares:    make(map[string]int64),
+		BaselineShares: make(map[string]int64),
+		ShareDrift:     make(map[string]int64),
+	}
+
+	for _, w := range m.wallets {
+		report.TotalCash += w.CashBalance
+		for sym, qty := range w.Holdings {
+			report.TotalShares[sym] += qty
+		}
+	}
+	for sym, qty := range m.baselineShares {
+		report.BaselineShares[sym] = qty
+	}
+
+	report.CashDrift = report.TotalCash - report.BaselineCash
+	for sym := range report.TotalShares {
+		if d := report.TotalShares[sym] - report.BaselineShares[sym]; d != 0 {
+			report.ShareDrift[sym] = d
+		}
+	}
+	for sym, qty := range report.BaselineShares {
+		if _, seen := report.TotalShares[sym]; !seen && qty != 0 {
+			report.ShareDrift[sym] = -qty
+		}
+	}
+
+	report.Balanced = report.CashDrift == 0 && len(report.ShareDrift) == 0
+	if !report.Balanced {
+		return report, fmt.Errorf("conservation violated: cash drift %d, share drift %v", report.CashDrift, report.ShareDrift)
+	}
+	return report, nil
+}
+
+// OrderOptions holds optional order attributes beyond side, price and quantity.
+type OrderOptions struct {
+	// MinExecQty is the smallest fill the order accepts (0 = no minimum).
+	MinExecQty int64
+	// PriceRounding overrides the symbol's rounding mode for off-tick prices.
+	PriceRounding PriceRoundingMode
+	// TimeInForce is GTC (the default), DAY (see CloseSymbol), GTD, or IOC
+	// and FOK, which trade on arrival and never rest.
+	TimeInForce domain.TimeInForce
+	// ExpiresAt is when a GTD order is canceled (see expiry.go); required
+	// for GTD and rejected for every other time in force.
+	ExpiresAt time.Time
+	// Type is limit (the default) or market. A market order's price is
+	// optional and caps how far it may trade (see market.go).
+	Type domain.OrderType
+	// MaxSlippageBps caps a market order at this far past the best opposite
+	// price, in basis points (0 = no cap).
+	MaxSlippageBps int64
+	// StopPrice makes a stop-limit or stop-market order (0 = none), held by
+	// the matching engine until the last trade price reaches it.
+	StopPrice int64
+}
+
+// PlaceOrder validates and submits a new order.
+func (m *Manager) PlaceOrder(userID, symbol string, side domain.Side, price, quantity int64) (*domain.Order, error) {
+	return m.PlaceOrderWithOptions(userID, symbol, side, price, quantity, OrderOptions{})
+}
+
+// PlaceOrderWithOptions validates and submits a new order with optional attributes.
+func (m *Manager) PlaceOrderWithOptions(userID, symbol string, side domain.Side, price, quantity int64, opts OrderOptions) (*domain.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Expired reservations must give their funds back before this order is checked
+	m.expireReservations()
+
+	if err := m.checkIntakeLocked(); err != nil {
+		return nil, err
+	}
+
+	defer m.lockUser(userID)()
+	order, err := m.prepareOrder(userID, symbol, side, price, quantity, opts)
+	if err != nil {
+		return nil, err
+	}
+	m.submitOrder(order)
+	return order, nil
+}
+
+// prepareOrder runs the risk and wallet checks and withholds funds or shares
+// for a new order. The order is not stored or sent to the sequencer.
+// Caller must hold m.mu and userID's lock.
+func (m *Manager) prepareOrder(userID, symbol string, side domain.Side, price, quantity int64, opts OrderOptions) (*domain.Order, error) {
+	if opts.MinExecQty < 0 || opts.MinExecQty > quantity {
+		return nil, rejectf(RejectInvalidOptions, "min_exec_qty must be between 0 and order quantity %d", quantity)
+	}
+	switch opts.TimeInForce {
+	case "", domain.TimeInForceGTC, domain.TimeInForceDay, domain.TimeInForceIOC, domain.TimeInForceFOK:
+		if !opts.ExpiresAt.IsZero() {
+			return nil, rejectf(RejectInvalidOptions, "expires_at requires time in force %s", domain.TimeInForceGTD)
+		}
+	case domain.TimeInForceGTD:
+		if !opts.ExpiresAt.After(m.now()) {
+			return nil, rejectf(RejectInvalidOptions, "GTD order needs an expires_at in the future")
+		}
+	default:
+		return nil, rejectf(RejectInvalidOptions, "unknown time in force %q", opts.TimeInForce)
+	}
+	if err := checkOrderType(opts); err != nil {
+		return nil, err
+	}
+	market := opts.Type == domain.OrderTypeMarket
+	if opts.StopPrice < 0 {
+		return nil, rejectf(RejectInvalidOptions, "stop_price must not be negative")
+	}
+
 	wallet, exists := m.wallets[userID]
 	if !exists {
-		return nil, fmt.Errorf("user %s not found", userID)
+		return nil, rejectf(RejectUnknownUser, "user %s not found", userID)
+	}
+	if err := m.checkTradingHours(symbol); err != nil {
+		return nil, err
 	}
 
-	// Risk check: daily volume limit
-	volKey := userID + ":" + symbol
-	if m.dailyVolume[volKey]+quantity > m.maxDailyVolume {
-		return nil, fmt.Errorf("daily volume limit exceeded for %s on %s", userID, symbol)
+	if err := m.checkLotSize(symbol, quantity); err != nil {
+		return nil, err
+	}
+
+	// Put the price on the tick grid before any funds are checked. A market
+	// order without a protection price has nothing to check.
+	if !market || price > 0 {
+		var err error
+		price, err = m.normalizePrice(symbol, price, opts.PriceRounding)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.checkPriceBand(symbol, price); err != nil {
+			return nil, err
+		}
+	}
+	stopPrice := opts.StopPrice
+	if stopPrice > 0 {
+		var err error
+		if stopPrice, err = m.normalizePrice(symbol, stopPrice, opts.PriceRounding); err != nil {
+			return nil, err
+		}
+	}
+
+	// Risk check: daily volume limit, set in whole shares
+	if m.dailyVolumeOf(wallet, symbol)+quantity > m.maxDailyVolume*m.quantityScale(symbol) {
+		return nil, rejectf(RejectDailyLimit, "daily volume limit exceeded for %s on %s", userID, symbol)
 	}
 
 	// Wallet check
+	var cost, cashPrice int64
 	if side == domain.SideBuy {
-		// Withhold cash: price * quantity (in cents)
-		cost := price * quantity
+		// Withhold cash: price * quantity (in cents), quantity in the symbol's
+		// scale. A market buy is priced off the book.
+		cashPrice = price
+		if market && stopPrice > 0 {
+			// Nothing bounds where the book is once the stop triggers
+			if price == 0 && opts.MaxSlippageBps == 0 {
+				return nil, rejectf(RejectInvalidOptions, "a stop-market buy needs a protection price or max_slippage_bps")
+			}
+			cashPrice = marketLimit(stopPrice, price, opts.MaxSlippageBps)
+		} else if market {
+			var err error
+			if cashPrice, err = m.marketBuyPrice(symbol, quantity, price, opts.MaxSlippageBps); err != nil {
+				return nil, err
+			}
+		}
+		cost = m.notional(symbol, cashPrice, quantity)
 		available := wallet.CashBalance - m.totalWithheldCash(wallet)
 		if available < cost {
-			return nil, fmt.Errorf("insufficient funds: need %d, available %d", cost, available)
+			return nil, rejectf(RejectInsufficientFunds, "insufficient funds: need %d, available %d", cost, available)
 		}
 	} else {
 		// Withhold shares
 		available := wallet.Holdings[symbol] - m.totalWithheldShares(wallet, symbol)
 		if available < quantity {
-			return nil, fmt.Errorf("insufficient shares: need %d %s, available %d", quantity, symbol, available)
+			return nil, rejectf(RejectInsufficientShares, "insufficient shares: need %d %s, available %d", quantity, symbol, available)
 		}
 	}
 
@@ -169,12 +463,25 @@ func (m *Manager) PlaceOrder(userID, symbol string, side domain.Side, price, qua
 		RemainingQuantity: quantity,
 		Status:            domain.OrderStatusNew,
 		UserID:            userID,
-		CreatedAt:         time.Now(),
+		CreatedAt:         m.now(),
+		MinExecQty:        opts.MinExecQty,
+		Type:              opts.Type,
+		MaxSlippageBps:    opts.MaxSlippageBps,
+		TimeInForce:       opts.TimeInForce,
+		StopPrice:         stopPrice,
+	}
+	// A market buy may not trade above the price its cash was withheld at
+	if market && side == domain.SideBuy && (price == 0 || cashPrice < price) {
+		order.Price = cashPrice
+	}
+	if !opts.ExpiresAt.IsZero() {
+		expiresAt := opts.ExpiresAt
+		order.ExpiresAt = &expiresAt
 	}
 
 	// Withhold funds/shares
 	if side == domain.SideBuy {
-		wallet.WithheldCash[order.OrderID] = price * quantity
+		wallet.WithheldCash[order.OrderID] = cost
 	} else {
 		wallet.WithheldShares[order.OrderID] = withheldShare{
 			Symbol:   symbol,
@@ -183,25 +490,28 @@ func (m *Manager) PlaceOrder(userID, symbol string, side domain.Side, price, qua
 	}
 
 	// Track daily volume
-	m.dailyVolume[volKey] += quantity
+	m.addDailyVolume(wallet, symbol, quantity)
 
-	// Store order
+	return order, nil
+}
+
+// submitOrder stores a prepared order and sends it to the sequencer.
+// Caller must hold m.mu and the order user's lock, which keeps a user's
+// orders reaching the sequencer in the order they were accepted.
+func (m *Manager) submitOrder(order *domain.Order) {
+	m.ordersMu.Lock()
 	m.orders[order.OrderID] = order
+	m.trackExpiry(order)
+	m.ordersMu.Unlock()
 
 	// Send to sequencer (non-blocking)
-	select {
-	case m.OrderOut <- &domain.OrderEvent{Action: domain.OrderActionNew, Order: order}:
-	default:
-		log.Println("[ordermanager] WARN: order output channel full")
-	}
-
-	return order, nil
+	m.emitOrderEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: order})
 }
 
 // CancelOrder submits a cancel request.
 func (m *Manager) CancelOrder(orderID string) (*domain.Order, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.ordersMu.RLock()
+	defer m.ordersMu.RUnlock()
 
 	order, exists := m.orders[orderID]
 	if !exists {
@@ -213,19 +523,15 @@ func (m *Manager) CancelOrder(orderID string) (*domain.Order, error) {
 	}
 
 	// Send cancel to sequencer
-	select {
-	case m.OrderOut <- &domain.OrderEvent{Action: domain.OrderActionCancel, Order: order}:
-	default:
-		log.Println("[ordermanager] WARN: order output channel full")
-	}
+	m.emitOrderEvent(&domain.OrderEvent{Action: domain.OrderActionCancel, Order: order})
 
 	return order, nil
 }
 
 // GetOrder returns an order by ID.
 func (m *Manager) GetOrder(orderID string) *domain.Order {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.ordersMu.RLock()
+	defer m.ordersMu.RUnlock()
 	return m.orders[orderID]
 }
 
@@ -235,7 +541,7 @@ func (m *Manager) listenExecutions() {
 	for {
 		select {
 		case event := <-m.ExecutionIn:
-			m.processExecutionEvent(event)
+			m.processExecutionEvents(batching.Collect(event, m.ExecutionIn, m.batching, m.done))
 		case <-m.done:
 			log.Println("[ordermanager] execution listener stopped")
 			return
@@ -245,58 +551,123 @@ func (m *Manager) listenExecutions() {
 
 // processExecutionEvent updates order states and wallet balances based on executions.
 func (m *Manager) processExecutionEvent(event *domain.ExecutionEvent) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.applyExecutionEvent(event)
+}
 
-	if event.TakerOrder != nil {
-		// Update stored order with latest state from matching engine
-		if stored, exists := m.orders[event.TakerOrder.OrderID]; exists {
-			stored.Status = event.TakerOrder.Status
-			stored.FilledQuantity = event.TakerOrder.FilledQuantity
-			stored.RemainingQuantity = event.TakerOrder.RemainingQuantity
-			stored.SequenceID = event.TakerOrder.SequenceID
-		}
+// processExecutionEvents applies a batch of events in order under a single
+// acquisition of the manager lock.
+func (m *Manager) processExecutionEvents(events []*domain.ExecutionEvent) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, event := range events {
+		m.applyExecutionEvent(event)
+	}
+}
 
-		// Release withheld funds on cancel
-		if event.TakerOrder.Status == domain.OrderStatusCanceled {
-			m.releaseWithheld(event.TakerOrder)
+// applyExecutionEvent applies one event. Caller must hold m.mu.
+func (m *Manager) applyExecutionEvent(event *domain.ExecutionEvent) {
+	if event.Drain != nil {
+		close(event.Drain.Settled)
+		return
+	}
+	// A rejected order shares its ID with one the engine still holds, so the
+	// stored order and its withholding belong to that one: leave them alone
+	if event.Rejected != nil {
+		if event.Modified {
+			m.applyModify(event)
+			return
 		}
+		log.Printf("[ordermanager] order %s rejected by matching engine: %s", event.Rejected.OrderID, event.RejectReason)
+		return
+	}
+	if event.RejectReason != "" && event.TakerOrder != nil {
+		log.Printf("[ordermanager] order %s canceled by matching engine: %s", event.TakerOrder.OrderID, event.RejectReason)
 	}
 
 	for _, exec := range event.Executions {
 		m.settleExecution(exec)
 	}
+
+	// Once the fills are settled, every order the event touched takes its
+	// state from it. Stop orders the event's trades triggered traded as
+	// takers too, and an auction uncross has no single taker: every filled
+	// order is listed in MakerOrders.
+	if event.TakerOrder != nil {
+		m.applyOrderState(event.TakerOrder.OrderID, event.States)
+	}
+	for _, order := range event.Triggered {
+		m.applyOrderState(order.OrderID, event.States)
+	}
+	for _, order := range event.MakerOrders {
+		m.applyOrderState(order.OrderID, event.States)
+	}
+
+	// A modified order's withholding follows its new price and remainder,
+	// after the fills it may have just traded are settled
+	if event.Modified {
+		m.applyModify(event)
+	}
+}
+
+// applyOrderState updates a stored order with its latest state from the
+// matching engine and releases its withholding once it is done: an order
+// that filled at better prices than it was withheld at, like a market buy
+// priced at the far end of its sweep or a buy crossed at an auction's
+// clearing price, leaves the difference behind. Caller must hold m.mu.
+func (m *Manager) applyOrderState(orderID string, states map[string]domain.OrderState) {
+	state := states[orderID]
+	m.ordersMu.Lock()
+	stored, exists := m.orders[orderID]
+	if exists {
+		m.applyState(stored, state)
+	}
+	m.ordersMu.Unlock()
+
+	// Release withheld funds on cancel or fill
+	if exists && (state.Status == domain.OrderStatusCanceled || state.Status == domain.OrderStatusFilled) {
+		unlock := m.lockUser(stored.UserID)
+		m.releaseWithheld(stored)
+		unlock()
+	}
 }
 
-// settleExecution adjusts wallet balances for a trade.
+// applyState copies an order's progress from an execution event onto the
+// stored order. The stored order is never handed to the matching engine, so
+// this is the only place its progress changes. Caller must hold ordersMu.
+func (m *Manager) applyState(stored *domain.Order, state domain.OrderState) {
+	stored.Status = state.Status
+	stored.FilledQuantity = state.FilledQuantity
+	stored.RemainingQuantity = state.RemainingQuantity
+	stored.SequenceID = state.SequenceID
+	m.markTerminal(stored)
+	m.ack(stored.OrderID)
+}
+
+// settleExecution adjusts wallet balances for a trade. Caller must hold m.mu.
 func (m *Manager) settleExecution(exec *domain.Execution) {
 	// Look up orders to find users
-	takerOrder := m.orders[exec.TakerOrderID]
-	makerOrder := m.orders[exec.MakerOrderID]
-	if takerOrder == nil || makerOrder == nil {
+	buyer, seller, _ := m.executionParties(exec)
+	if buyer == nil {
 		return
 	}
 
-	var buyer, seller *domain.Order
-	if takerOrder.Side == domain.SideBuy {
-		buyer = takerOrder
-		seller = makerOrder
-	} else {
-		buyer = makerOrder
-		seller = takerOrder
-	}
-
 	buyerWallet := m.wallets[buyer.UserID]
 	sellerWallet := m.wallets[seller.UserID]
 	if buyerWallet == nil || sellerWallet == nil {
 		return
 	}
 
-	cost := exec.Price * exec.Quantity
+	// Both sides move together: lock the two users' stripes in a fixed order
+	unlock := m.lockUsers(buyer.UserID, seller.UserID)
+
+	cost := m.fillCost(buyerWallet, buyer.OrderID, exec)
 
 	// Buyer: deduct cash, receive shares
 	buyerWallet.CashBalance -= cost
 	buyerWallet.Holdings[exec.Symbol] += exec.Quantity
+	addPositionCost(buyerWallet, exec.Symbol, cost)
 	// Reduce withheld cash for the buyer's order
 	if withheld, ok := buyerWallet.WithheldCash[buyer.OrderID]; ok {
 		buyerWallet.WithheldCash[buyer.OrderID] = withheld - cost
@@ -306,6 +677,7 @@ func (m *Manager) settleExecution(exec *domain.Execution) {
 	}
 
 	// Seller: deduct shares, receive cash
+	realizePosition(sellerWallet, exec.Symbol, exec.Quantity, sellerWallet.Holdings[exec.Symbol], cost)
 	sellerWallet.CashBalance += cost
 	sellerWallet.Holdings[exec.Symbol] -= exec.Quantity
 	// Reduce withheld shares for the seller's order
@@ -317,16 +689,28 @@ func (m *Manager) settleExecution(exec *domain.Execution) {
 			sellerWallet.WithheldShares[seller.OrderID] = ws
 		}
 	}
+	unlock()
+}
+
+// executionParties returns the buy and sell orders of an execution and which
+// of them was the maker; all nil if either order is unknown.
+func (m *Manager) executionParties(exec *domain.Execution) (buyer, seller, maker *domain.Order) {
+	m.ordersMu.RLock()
+	takerOrder := m.orders[exec.TakerOrderID]
+	makerOrder := m.orders[exec.MakerOrderID]
+	m.ordersMu.RUnlock()
+	if takerOrder == nil || makerOrder == nil {
+		return nil, nil, nil
+	}
 
-	// Update maker order state in our map
-	if stored, exists := m.orders[makerOrder.OrderID]; exists {
-		stored.Status = makerOrder.Status
-		stored.FilledQuantity = makerOrder.FilledQuantity
-		stored.RemainingQuantity = makerOrder.RemainingQuantity
+	if takerOrder.Side == domain.SideBuy {
+		return takerOrder, makerOrder, makerOrder
 	}
+	return makerOrder, takerOrder, makerOrder
 }
 
 // releaseWithheld releases withheld funds/shares when an order is canceled.
+// Caller must hold m.mu and the order user's lock.
 func (m *Manager) releaseWithheld(order *domain.Order) {
 	wallet := m.wallets[order.UserID]
 	if wallet == nil {
@@ -335,6 +719,7 @@ func (m *Manager) releaseWithheld(order *domain.Order) {
 
 	delete(wallet.WithheldCash, order.OrderID)
 	delete(wallet.WithheldShares, order.OrderID)
+	delete(wallet.fillCharges, order.OrderID)
 }
 
 func (m *Manager) totalWithheldCash(w *Wallet) int64 {
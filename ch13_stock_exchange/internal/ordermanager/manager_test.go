@@ -1,9 +1,13 @@
 package ordermanager
 
 import (
+	"math/rand"
 	"testing"
+	"time"
 
+	"github.com/nathanyu/stock-exchange/internal/batching"
 	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/matching"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,6 +19,33 @@ func newTestManager() *Manager {
 	return m
 }
 
+// withStates fills in an execution event's States from its orders, like
+// the matching engine does, for events built by hand.
+func withStates(event *domain.ExecutionEvent) *domain.ExecutionEvent {
+	event.States = make(map[string]domain.OrderState)
+	for _, order := range append(append([]*domain.Order{event.TakerOrder}, event.Triggered...), event.MakerOrders...) {
+		if order != nil {
+			event.States[order.OrderID] = domain.StateOf(order)
+		}
+	}
+	return event
+}
+
+// newMatcher wires m to a fresh matching engine and returns it with a func
+// that sends m's next order event through the engine and applies the result.
+// With priceMarketBuys, market buys are priced off the engine's ask depth;
+// otherwise every market buy is rejected.
+func newMatcher(t *testing.T, m *Manager, priceMarketBuys bool) (*matching.Engine, func()) {
+	engine := matching.NewEngine()
+	if priceMarketBuys {
+		m.SetAskDepthSource(engine.SweepAskPrice)
+	}
+	return engine, func() {
+		t.Helper()
+		m.processExecutionEvent(engine.HandleOrder(<-m.OrderOut))
+	}
+}
+
 func TestPlaceOrder_Buy(t *testing.T) {
 	m := newTestManager()
 
@@ -141,3 +172,252 @@ func TestGetAllWallets(t *testing.T) {
 	assert.Contains(t, wallets, "user1")
 	assert.Contains(t, wallets, "user2")
 }
+
+func TestVerifyConservation_AfterTrades(t *testing.T) {
+	m := NewManager(1_000_000, 1000)
+	engine := matching.NewEngine()
+
+	users := []string{"user1", "user2", "user3", "user4"}
+	for _, u := range users {
+		m.InitWallet(u, 50_000_000, map[string]int64{"AAPL": 10_000, "GOOG": 5_000})
+	}
+
+	_, err := m.VerifyConservation()
+	require.NoError(t, err)
+
+	// Drive orders through the matching engine synchronously
+	rng := rand.New(rand.NewSource(42))
+	symbols := []string{"AAPL", "GOOG"}
+	for i := 0; i < 500; i++ {
+		side := domain.SideBuy
+		if rng.Intn(2) == 0 {
+			side = domain.SideSell
+		}
+		price := int64(9950 + rng.Intn(100))
+		qty := int64(1 + rng.Intn(50))
+
+		_, err := m.PlaceOrder(users[rng.Intn(len(users))], symbols[rng.Intn(len(symbols))], side, price, qty)
+		if err != nil {
+			continue
+		}
+		event := <-m.OrderOut
+		m.processExecutionEvent(engine.HandleOrder(event))
+	}
+
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+	assert.Equal(t, int64(200_000_000), report.TotalCash)
+	assert.Equal(t, int64(40_000), report.TotalShares["AAPL"])
+	assert.Equal(t, int64(20_000), report.TotalShares["GOOG"])
+
+	// Sanity: trades actually happened, so value really moved between users
+	moved := false
+	for _, w := range m.GetAllWallets() {
+		if w.CashBalance != 50_000_000 {
+			moved = true
+		}
+	}
+	assert.True(t, moved)
+}
+
+func TestVerifyConservation_DetectsDrift(t *testing.T) {
+	m := newTestManager()
+
+	// Simulate a settlement bug that mints cash and burns shares
+	m.wallets["user1"].CashBalance += 100
+	m.wallets["user2"].Holdings["AAPL"] -= 5
+
+	report, err := m.VerifyConservation()
+	require.Error(t, err)
+	assert.False(t, report.Balanced)
+	assert.Equal(t, int64(100), report.CashDrift)
+	assert.Equal(t, int64(-5), report.ShareDrift["AAPL"])
+}
+
+func TestVerifyConservation_ReinitWallet(t *testing.T) {
+	m := newTestManager()
+
+	m.InitWallet("user1", 1_000, map[string]int64{"GOOG": 10})
+
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.Equal(t, int64(10_001_000), report.BaselineCash)
+	assert.Equal(t, int64(5000), report.BaselineShares["AAPL"])
+	assert.Equal(t, int64(10), report.BaselineShares["GOOG"])
+}
+
+func TestPlaceOrderWithOptions_MinExecQty(t *testing.T) {
+	m := newTestManager()
+
+	order, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10010, 100, OrderOptions{MinExecQty: 50})
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), order.MinExecQty)
+	<-m.OrderOut
+
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10010, 100, OrderOptions{MinExecQty: 101})
+	assert.Error(t, err)
+}
+
+func TestPlaceOrderWithOptions_ImmediateOrdersNeverRest(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	_, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	match()
+
+	// FOK: more than the book holds, so nothing trades and the cash comes back
+	fok, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 150, OrderOptions{TimeInForce: domain.TimeInForceFOK})
+	require.NoError(t, err)
+	match()
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(fok.OrderID).Status)
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+	assert.Equal(t, int64(10_000_000), m.GetWallet("user1").CashBalance)
+
+	// IOC: takes the 100 there is and cancels the rest
+	ioc, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 150, OrderOptions{TimeInForce: domain.TimeInForceIOC})
+	require.NoError(t, err)
+	match()
+	stored := m.GetOrder(ioc.OrderID)
+	assert.Equal(t, domain.OrderStatusCanceled, stored.Status)
+	assert.Equal(t, int64(100), stored.FilledQuantity)
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+	assert.Equal(t, int64(10_000_000-10000*100), m.GetWallet("user1").CashBalance)
+
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 1, OrderOptions{
+		TimeInForce: domain.TimeInForceIOC,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	assert.Equal(t, RejectInvalidOptions, RejectCodeOf(err), "only GTD orders expire")
+}
+
+// burstEvents drives orders through a matching engine and returns the
+// resulting execution events without applying them to m.
+func burstEvents(t testing.TB, m *Manager, n int) []*domain.ExecutionEvent {
+	engine := matching.NewEngine()
+	var events []*domain.ExecutionEvent
+	for i := 0; i < n; i++ {
+		side, user := domain.SideSell, "user1"
+		if i%2 == 1 {
+			side, user = domain.SideBuy, "user2"
+		}
+		_, err := m.PlaceOrder(user, "AAPL", side, 10000, 1)
+		require.NoError(t, err)
+		events = append(events, engine.HandleOrder(<-m.OrderOut))
+	}
+	return events
+}
+
+func TestExecutionBatching_ProcessesEachExecutionOnce(t *testing.T) {
+	m := NewManager(1_000_000, 1000)
+	m.InitWallet("user1", 10_000_000, map[string]int64{"AAPL": 5000})
+	m.InitWallet("user2", 10_000_000, map[string]int64{"AAPL": 5000})
+	m.SetExecutionBatching(batching.Options{Enabled: true, Window: time.Millisecond})
+
+	events := burstEvents(t, m, 400)
+
+	m.Start()
+	defer m.Stop()
+	for _, ev := range events {
+		m.ExecutionIn <- ev
+	}
+
+	// 200 one-share trades at 10000 cents move exactly 200 shares and 2,000,000 cents
+	require.Eventually(t, func() bool {
+		w := m.GetWallet("user2")
+		return w.Holdings["AAPL"] == 5200
+	}, time.Second, time.Millisecond)
+
+	// Give any duplicate application a chance to show up
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int64(5200), m.GetWallet("user2").Holdings["AAPL"])
+	assert.Equal(t, int64(8_000_000), m.GetWallet("user2").CashBalance)
+	assert.Equal(t, int64(4800), m.GetWallet("user1").Holdings["AAPL"])
+	assert.Equal(t, int64(12_000_000), m.GetWallet("user1").CashBalance)
+
+	_, err := m.VerifyConservation()
+	assert.NoError(t, err)
+}
+
+// BenchmarkExecutionBurst compares applying a burst of execution events one
+// lock acquisition at a time against a single batched pass, with a reader
+// contending for the manager lock the way API balance queries do.
+func BenchmarkExecutionBurst(b *testing.B) {
+	const burst = 256
+
+	run := func(b *testing.B, apply func(m *Manager, events []*domain.ExecutionEvent)) {
+		m := NewManager(1_000_000_000, burst)
+		m.InitWallet("user1", 1_000_000_000, map[string]int64{"AAPL": 1_000_000})
+		m.InitWallet("user2", 1_000_000_000, map[string]int64{"AAPL": 1_000_000})
+		events := burstEvents(b, m, burst)
+
+		stop := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					m.GetWallet("user1")
+				}
+			}
+		}()
+		defer close(stop)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			apply(m, events)
+		}
+	}
+
+	b.Run("individual", func(b *testing.B) {
+		run(b, func(m *Manager, events []*domain.ExecutionEvent) {
+			for _, ev := range events {
+				m.processExecutionEvent(ev)
+			}
+		})
+	})
+	b.Run("batched", func(b *testing.B) {
+		run(b, func(m *Manager, events []*domain.ExecutionEvent) {
+			m.processExecutionEvents(events)
+		})
+	})
+}
+
+func TestAuctionUncross_SettlesAtClearingPrice(t *testing.T) {
+	m := newTestManager()
+	engine, match := newMatcher(t, m, false)
+	engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionAuctionStart, Order: &domain.Order{Symbol: "AAPL"}})
+
+	buy, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10100, 100)
+	require.NoError(t, err)
+	match()
+	sell, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 9900, 100)
+	require.NoError(t, err)
+	match()
+
+	m.processExecutionEvent(engine.HandleOrder(&domain.OrderEvent{Action: domain.OrderActionAuctionEnd, Order: &domain.Order{Symbol: "AAPL"}}))
+
+	for _, id := range []string{buy.OrderID, sell.OrderID} {
+		order := m.GetOrder(id)
+		require.NotNil(t, order)
+		assert.Equal(t, domain.OrderStatusFilled, order.Status)
+		assert.Contains(t, m.closedAt, id)
+	}
+
+	// Both sides settle at the uniform price, not their own limits
+	w1 := m.GetWallet("user1")
+	w2 := m.GetWallet("user2")
+	assert.Equal(t, int64(10_000_000-9900*100), w1.CashBalance)
+	assert.Equal(t, int64(10_000_000+9900*100), w2.CashBalance)
+	assert.Equal(t, int64(5100), w1.Holdings["AAPL"])
+
+	// The buy was withheld at its limit; the difference to the clearing
+	// price is released with the rest
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+	assert.Empty(t, m.wallets["user2"].WithheldShares)
+
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+}
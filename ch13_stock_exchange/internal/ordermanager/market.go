@@ -0,0 +1,82 @@
+package ordermanager
+
+import "github.com/nathanyu/stock-exchange/internal/domain"
+
+// Market orders.
+//
+// The matching engine fills a market order at whatever the book offers and
+// cancels the rest (see orderbook.MatchOrder), so the manager never knows its
+// execution price up front. A market sell withholds shares like any sell. A
+// market buy withholds cash at the worst price it may pay, taken from the
+// book:
+//
+//   - unprotected: the highest ask a sweep of its full quantity reaches.
+//   - with a protection price or MaxSlippageBps: the worst price either lets
+//     it trade at, measured from the best ask.
+//
+// That price then becomes the order's protection price, so the engine never
+// fills it above what was withheld, even if the book has thinned out by the
+// time the order is matched: the order fills what it can within the price
+// and the rest is canceled. With no ask to estimate from, a market buy is
+// rejected rather than sent to an empty book. A stop-market buy trades only
+// once the price has risen to its stop price, so it is priced from the stop
+// price instead of the current ask, with its protection price and slippage
+// cap on top. The book may have gapped well past the stop by then, so a
+// stop-market buy without either is rejected.
+
+// SetAskDepthSource sets where market buys are priced from: for a symbol and
+// quantity, the highest ask a buy of that quantity would reach, usually the
+// matching engine's SweepAskPrice. It must not call back into the manager.
+// Without one every market buy is rejected.
+func (m *Manager) SetAskDepthSource(source func(symbol string, quantity int64) int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.askDepth = source
+}
+
+// checkOrderType rejects an unknown order type and slippage caps on orders
+// that are not market orders.
+func checkOrderType(opts OrderOptions) error {
+	switch opts.Type {
+	case "", domain.OrderTypeLimit, domain.OrderTypeMarket:
+	default:
+		return rejectf(RejectInvalidOptions, "unknown order type %q", opts.Type)
+	}
+	if opts.MaxSlippageBps < 0 {
+		return rejectf(RejectInvalidOptions, "max_slippage_bps must not be negative")
+	}
+	if opts.MaxSlippageBps > 0 && opts.Type != domain.OrderTypeMarket {
+		return rejectf(RejectInvalidOptions, "max_slippage_bps requires a market order")
+	}
+	return nil
+}
+
+// marketBuyPrice is the price a market buy's cash is withheld at, which
+// also caps what it may trade at: the ask a sweep of quantity reaches, or,
+// with a protection price or slippage cap, the worst price they allow from
+// the best ask. Caller must hold m.mu.
+func (m *Manager) marketBuyPrice(symbol string, quantity, protection, slippageBps int64) (int64, error) {
+	if m.askDepth == nil {
+		return 0, rejectf(RejectNoMarketPrice, "no asks for %s to price a market buy", symbol)
+	}
+	if protection == 0 && slippageBps == 0 {
+		if sweep := m.askDepth(symbol, quantity); sweep > 0 {
+			return sweep, nil
+		}
+	} else if ask := m.askDepth(symbol, 1); ask > 0 {
+		return marketLimit(ask, protection, slippageBps), nil
+	}
+	return 0, rejectf(RejectNoMarketPrice, "no asks for %s to price a market buy", symbol)
+}
+
+// marketLimit is the worst price a market buy expected to trade at ref may
+// reach under its protection price and slippage cap, and at least ref.
+func marketLimit(ref, protection, slippageBps int64) int64 {
+	limit := protection
+	if slippageBps > 0 {
+		if slip := ref + ref*slippageBps/10000; limit == 0 || slip < limit {
+			limit = slip
+		}
+	}
+	return max(ref, limit)
+}
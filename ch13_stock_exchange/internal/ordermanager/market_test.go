@@ -0,0 +1,167 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketBuy_SweepsLevelsAndWithholdsAtSweepPrice(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, true)
+	_, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, 10050, 100)
+	require.NoError(t, err)
+	match()
+
+	buy, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 0, 150, OrderOptions{Type: domain.OrderTypeMarket})
+	require.NoError(t, err)
+	// The sweep reaches the 10050 level, which also caps what it may pay
+	assert.Equal(t, int64(10050*150), m.wallets["user1"].WithheldCash[buy.OrderID])
+	assert.Equal(t, int64(10050), buy.Price)
+	match()
+
+	stored := m.GetOrder(buy.OrderID)
+	assert.Equal(t, domain.OrderStatusFilled, stored.Status)
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+	wallet := m.GetWallet("user1")
+	assert.Equal(t, int64(10_000_000-10000*100-10050*50), wallet.CashBalance)
+	assert.Equal(t, int64(5150), wallet.Holdings["AAPL"])
+}
+
+func TestMarketBuy_CashNeverGoesNegative(t *testing.T) {
+	m := newTestManager()
+	engine, match := newMatcher(t, m, true)
+	_, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 1)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, 90000, 100)
+	require.NoError(t, err)
+	match()
+
+	// Sweeping 10 reaches the 90000 level, which a tight balance cannot cover
+	m.InitWallet("tight", 100_000, nil)
+	_, err = m.PlaceOrderWithOptions("tight", "AAPL", domain.SideBuy, 0, 10, OrderOptions{Type: domain.OrderTypeMarket})
+	assert.Equal(t, RejectInsufficientFunds, RejectCodeOf(err))
+	assert.Equal(t, int64(100_000), m.GetWallet("tight").CashBalance)
+
+	// Enough for the sweep: it pays 10000 + 9*90000 out of 900000 withheld
+	m.InitWallet("exact", 900_000, nil)
+	buy, err := m.PlaceOrderWithOptions("exact", "AAPL", domain.SideBuy, 0, 10, OrderOptions{Type: domain.OrderTypeMarket})
+	require.NoError(t, err)
+	match()
+	assert.Equal(t, domain.OrderStatusFilled, m.GetOrder(buy.OrderID).Status)
+	assert.Equal(t, int64(900_000-10000-9*90000), m.GetWallet("exact").CashBalance)
+	assert.Empty(t, m.wallets["exact"].WithheldCash)
+
+	// The book thins out between placing and matching: the buy does not
+	// chase the asks above the price it withheld at
+	m.InitWallet("late", 900_000, nil)
+	late, err := m.PlaceOrderWithOptions("late", "AAPL", domain.SideBuy, 0, 10, OrderOptions{Type: domain.OrderTypeMarket})
+	require.NoError(t, err)
+	lateEvent := <-m.OrderOut
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 90000, 91)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, 200000, 100)
+	require.NoError(t, err)
+	match()
+	m.processExecutionEvent(engine.HandleOrder(lateEvent))
+
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(late.OrderID).Status)
+	assert.Zero(t, m.GetOrder(late.OrderID).FilledQuantity)
+	assert.Equal(t, int64(900_000), m.GetWallet("late").CashBalance)
+	assert.Empty(t, m.wallets["late"].WithheldCash)
+}
+
+func TestMarketBuy_RemainderCanceled(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, true)
+	_, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	match()
+
+	buy, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 0, 300, OrderOptions{Type: domain.OrderTypeMarket})
+	require.NoError(t, err)
+	match()
+
+	stored := m.GetOrder(buy.OrderID)
+	assert.Equal(t, domain.OrderStatusCanceled, stored.Status)
+	assert.Equal(t, int64(100), stored.FilledQuantity)
+	assert.Empty(t, m.wallets["user1"].WithheldCash, "the unfilled remainder gives its cash back")
+}
+
+func TestMarketBuy_RejectedWithoutAsks(t *testing.T) {
+	// No ask depth source
+	m := newTestManager()
+	_, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 0, 10, OrderOptions{Type: domain.OrderTypeMarket})
+	assert.Equal(t, RejectNoMarketPrice, RejectCodeOf(err))
+
+	// Empty opposite book
+	m = newTestManager()
+	_, match := newMatcher(t, m, true)
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideBuy, 9900, 10)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 0, 10, OrderOptions{Type: domain.OrderTypeMarket})
+	assert.Equal(t, RejectNoMarketPrice, RejectCodeOf(err))
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+
+	// A market sell needs no price, and with no bids it is simply canceled
+	sell, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideSell, 0, 10, OrderOptions{Type: domain.OrderTypeMarket})
+	require.NoError(t, err)
+	match()
+	assert.Equal(t, int64(10), m.GetOrder(sell.OrderID).FilledQuantity)
+	sell, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideSell, 0, 10, OrderOptions{Type: domain.OrderTypeMarket})
+	require.NoError(t, err)
+	match()
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(sell.OrderID).Status)
+	assert.Empty(t, m.wallets["user1"].WithheldShares)
+}
+
+func TestMarketBuy_WithholdsAtProtectionLimit(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, true)
+	_, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	match()
+
+	tests := []struct {
+		name       string
+		price      int64
+		slippage   int64
+		withheldAt int64
+	}{
+		{"slippage cap", 0, 100, 10100},
+		{"protection price", 10050, 0, 10050},
+		{"tighter of both", 10050, 100, 10050},
+		{"protection below the ask", 9900, 0, 10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buy, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, tt.price, 10, OrderOptions{
+				Type:           domain.OrderTypeMarket,
+				MaxSlippageBps: tt.slippage,
+			})
+			require.NoError(t, err)
+			<-m.OrderOut
+			assert.Equal(t, tt.withheldAt*10, m.wallets["user1"].WithheldCash[buy.OrderID])
+		})
+	}
+}
+
+func TestOrderType_InvalidOptions(t *testing.T) {
+	m := newTestManager()
+	m.SetAskDepthSource(func(string, int64) int64 { return 10000 })
+
+	_, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 10, OrderOptions{Type: "stop"})
+	assert.Equal(t, RejectInvalidOptions, RejectCodeOf(err))
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 10, OrderOptions{MaxSlippageBps: 50})
+	assert.Equal(t, RejectInvalidOptions, RejectCodeOf(err), "slippage caps are for market orders")
+	_, err = m.ReserveOrder("user1", "AAPL", domain.SideBuy, 0, 10, OrderOptions{Type: domain.OrderTypeMarket})
+	assert.Equal(t, RejectInvalidOptions, RejectCodeOf(err))
+}
@@ -0,0 +1,47 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrdersTotal_CountsPlacedAndCanceledOrders(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	newBefore := testutil.ToFloat64(middleware.OrdersTotal.WithLabelValues("new", "AAPL"))
+	cancelBefore := testutil.ToFloat64(middleware.OrdersTotal.WithLabelValues("cancel", "AAPL"))
+
+	order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 9900, 10)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 9800, 10)
+	require.NoError(t, err)
+	match()
+	_, err = m.CancelOrder(order.OrderID)
+	require.NoError(t, err)
+	match()
+
+	assert.Equal(t, newBefore+2, testutil.ToFloat64(middleware.OrdersTotal.WithLabelValues("new", "AAPL")))
+	assert.Equal(t, cancelBefore+1, testutil.ToFloat64(middleware.OrdersTotal.WithLabelValues("cancel", "AAPL")))
+}
+
+func TestOrdersTotal_CountsDroppedEventsSeparately(t *testing.T) {
+	m := NewManager(1_000_000, 1)
+	m.InitWallet("user1", 10_000_000, nil)
+	sentBefore := testutil.ToFloat64(middleware.OrdersTotal.WithLabelValues("cancel", "MSFT"))
+	droppedBefore := testutil.ToFloat64(middleware.OrdersDropped.WithLabelValues("cancel", "MSFT"))
+
+	// The new order fills the one-slot intake, so the cancel has no room.
+	order, err := m.PlaceOrder("user1", "MSFT", domain.SideBuy, 9900, 10)
+	require.NoError(t, err)
+	_, err = m.CancelOrder(order.OrderID)
+	require.NoError(t, err)
+
+	assert.Equal(t, sentBefore, testutil.ToFloat64(middleware.OrdersTotal.WithLabelValues("cancel", "MSFT")))
+	assert.Equal(t, droppedBefore+1, testutil.ToFloat64(middleware.OrdersDropped.WithLabelValues("cancel", "MSFT")))
+}
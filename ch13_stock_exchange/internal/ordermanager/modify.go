@@ -0,0 +1,175 @@
+package ordermanager
+
+import (
+	"log"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Order modification.
+//
+// ModifyOrder runs the same checks as a new order for whatever the change
+// adds (daily volume, cash or shares) and raises the order's withholding
+// before the request is sequenced, so a concurrent order cannot spend what
+// the modify needs. A withholding the change lowers stays as it is until
+// then: the order keeps resting on its old terms until the engine applies
+// the modify. The matching engine has the final say: by the time the
+// request reaches it the order may have filled further, filled completely or
+// been canceled. Its answer (Modified on the execution event) re-syncs the
+// withholding to the order's actual remainder, and a refused modify gives
+// its daily volume back.
+
+// ModifyOrder changes a resting order's price and total quantity. Reducing
+// the quantity at the same price keeps the order's place in its queue;
+// anything else sends it to the back of its price level. price goes through
+// the symbol's tick rounding and price band like a new order's.
+func (m *Manager) ModifyOrder(orderID string, price, quantity int64) (*domain.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.checkIntakeLocked(); err != nil {
+		return nil, err
+	}
+
+	m.ordersMu.RLock()
+	order, exists := m.orders[orderID]
+	m.ordersMu.RUnlock()
+	if !exists {
+		return nil, rejectf(RejectUnknownOrder, "order %s not found", orderID)
+	}
+	defer m.lockUser(order.UserID)()
+
+	m.ordersMu.RLock()
+	current := *order
+	m.ordersMu.RUnlock()
+	switch {
+	case current.Status == domain.OrderStatusFilled || current.Status == domain.OrderStatusCanceled:
+		return nil, rejectf(RejectInvalidRequest, "order %s is already %s", orderID, current.Status)
+	case current.IsMarket() || current.IsImmediate():
+		return nil, rejectf(RejectInvalidRequest, "order %s never rests and cannot be modified", orderID)
+	case quantity <= current.FilledQuantity:
+		return nil, rejectf(RejectInvalidRequest, "quantity must exceed the filled quantity %d", current.FilledQuantity)
+	}
+
+	if err := m.checkTradingHours(current.Symbol); err != nil {
+		return nil, err
+	}
+	if err := m.checkLotSize(current.Symbol, quantity); err != nil {
+		return nil, err
+	}
+	price, err := m.normalizePrice(current.Symbol, price, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkPriceBand(current.Symbol, price); err != nil {
+		return nil, err
+	}
+
+	wallet := m.wallets[current.UserID]
+	if wallet == nil {
+		return nil, rejectf(RejectUnknownUser, "user %s not found", current.UserID)
+	}
+	added := quantity - current.Quantity
+	if added > 0 && m.dailyVolumeOf(wallet, current.Symbol)+added > m.maxDailyVolume*m.quantityScale(current.Symbol) {
+		return nil, rejectf(RejectDailyLimit, "daily volume limit exceeded for %s on %s", current.UserID, current.Symbol)
+	}
+
+	// Only what the new remainder needs beyond the current withholding is
+	// checked, and the withholding only ever goes up here
+	remaining := quantity - current.FilledQuantity
+	withheldCash, withheldShares := wallet.WithheldCash[orderID], wallet.WithheldShares[orderID]
+	if current.Side == domain.SideBuy {
+		cost := m.notional(current.Symbol, price, remaining)
+		available := wallet.CashBalance - m.totalWithheldCash(wallet)
+		if extra := cost - withheldCash; extra > available {
+			return nil, rejectf(RejectInsufficientFunds, "insufficient funds: need %d more, available %d", extra, available)
+		}
+		wallet.WithheldCash[orderID] = max(withheldCash, cost)
+	} else {
+		available := wallet.Holdings[current.Symbol] - m.totalWithheldShares(wallet, current.Symbol)
+		if extra := remaining - withheldShares.Quantity; extra > available {
+			return nil, rejectf(RejectInsufficientShares, "insufficient shares: need %d more %s, available %d", extra, current.Symbol, available)
+		}
+		wallet.WithheldShares[orderID] = withheldShare{Symbol: current.Symbol, Quantity: max(withheldShares.Quantity, remaining)}
+	}
+	if added > 0 {
+		m.addDailyVolume(wallet, current.Symbol, added)
+	} else {
+		m.refundDailyVolume(wallet, current.Symbol, -added)
+	}
+
+	sent := m.emitOrderEvent(&domain.OrderEvent{Action: domain.OrderActionModify, Order: &domain.Order{
+		OrderID:  orderID,
+		Symbol:   current.Symbol,
+		Side:     current.Side,
+		Price:    price,
+		Quantity: quantity,
+		UserID:   current.UserID,
+	}})
+	if !sent {
+		// Nothing changes: put the withholding and daily volume back
+		if current.Side == domain.SideBuy {
+			wallet.WithheldCash[orderID] = withheldCash
+		} else {
+			wallet.WithheldShares[orderID] = withheldShares
+		}
+		if added > 0 {
+			m.refundDailyVolume(wallet, current.Symbol, added)
+		} else {
+			m.addDailyVolume(wallet, current.Symbol, -added)
+		}
+		return nil, rejectf(RejectQueueFull, "order intake is full, modify of %s not sent", orderID)
+	}
+
+	current.Price = price
+	current.Quantity = quantity
+	current.RemainingQuantity = remaining
+	return &current, nil
+}
+
+// applyModify settles the engine's answer to a modify request once any
+// executions it caused are settled. Caller must hold m.mu.
+func (m *Manager) applyModify(event *domain.ExecutionEvent) {
+	req := event.Rejected
+	if req == nil {
+		req = event.TakerOrder
+	}
+	m.ordersMu.Lock()
+	stored, exists := m.orders[req.OrderID]
+	if exists && event.Rejected == nil {
+		state := event.States[req.OrderID]
+		stored.Price = state.Price
+		stored.Quantity = state.Quantity
+	}
+	m.ordersMu.Unlock()
+	if !exists {
+		return
+	}
+
+	defer m.lockUser(stored.UserID)()
+	if event.Rejected != nil {
+		log.Printf("[ordermanager] modify of order %s refused by matching engine: %s", req.OrderID, event.RejectReason)
+		if wallet := m.wallets[stored.UserID]; wallet != nil {
+			m.refundDailyVolume(wallet, stored.Symbol, req.Quantity-stored.Quantity)
+		}
+	}
+	m.resyncWithheld(stored)
+}
+
+// resyncWithheld sets an order's withholding to what its resting remainder
+// needs, or releases it once the order is done. Caller must hold m.mu and
+// the order user's lock.
+func (m *Manager) resyncWithheld(order *domain.Order) {
+	wallet := m.wallets[order.UserID]
+	if wallet == nil {
+		return
+	}
+	if order.RemainingQuantity <= 0 || order.Status == domain.OrderStatusFilled || order.Status == domain.OrderStatusCanceled {
+		m.releaseWithheld(order)
+		return
+	}
+	if order.Side == domain.SideBuy {
+		wallet.WithheldCash[order.OrderID] = m.notional(order.Symbol, order.Price, order.RemainingQuantity)
+	} else {
+		wallet.WithheldShares[order.OrderID] = withheldShare{Symbol: order.Symbol, Quantity: order.RemainingQuantity}
+	}
+}
@@ -0,0 +1,139 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModifyOrder_AdjustsWithheldCash(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	buy, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 100)
+	require.NoError(t, err)
+	match()
+
+	// Down: the order rests on its old terms until the engine applies the
+	// modify, so the cash is only given back then
+	modified, err := m.ModifyOrder(buy.OrderID, 10000, 40)
+	require.NoError(t, err)
+	assert.Equal(t, int64(40), modified.Quantity)
+	assert.Equal(t, int64(10000*100), m.wallets["user1"].WithheldCash[buy.OrderID])
+	match()
+	assert.Equal(t, int64(40), m.GetOrder(buy.OrderID).RemainingQuantity)
+	assert.Equal(t, int64(10000*40), m.wallets["user1"].WithheldCash[buy.OrderID])
+
+	// Up and at a new price
+	_, err = m.ModifyOrder(buy.OrderID, 10100, 200)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10100*200), m.wallets["user1"].WithheldCash[buy.OrderID])
+	assert.Equal(t, int64(200), m.wallets["user1"].dailyVolume["AAPL"])
+	match()
+	stored := m.GetOrder(buy.OrderID)
+	assert.Equal(t, int64(10100), stored.Price)
+	assert.Equal(t, int64(200), stored.RemainingQuantity)
+
+	// More than the wallet can pay for
+	_, err = m.ModifyOrder(buy.OrderID, 10100, 1000)
+	assert.Equal(t, RejectInsufficientFunds, RejectCodeOf(err))
+	assert.Equal(t, int64(10100*200), m.wallets["user1"].WithheldCash[buy.OrderID])
+}
+
+func TestModifyOrder_AdjustsWithheldShares(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	sell, err := m.PlaceOrder("user1", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	match()
+
+	_, err = m.ModifyOrder(sell.OrderID, 10000, 5001)
+	assert.Equal(t, RejectInsufficientShares, RejectCodeOf(err))
+
+	_, err = m.ModifyOrder(sell.OrderID, 10000, 5000)
+	require.NoError(t, err)
+	match()
+	assert.Equal(t, int64(5000), m.wallets["user1"].WithheldShares[sell.OrderID].Quantity)
+}
+
+func TestModifyOrder_CrossingPriceSettles(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	_, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 50)
+	require.NoError(t, err)
+	match()
+	buy, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 9900, 100)
+	require.NoError(t, err)
+	match()
+
+	_, err = m.ModifyOrder(buy.OrderID, 10000, 100)
+	require.NoError(t, err)
+	match()
+
+	stored := m.GetOrder(buy.OrderID)
+	assert.Equal(t, domain.OrderStatusPartiallyFilled, stored.Status)
+	assert.Equal(t, int64(50), stored.RemainingQuantity)
+	// What is withheld covers exactly the resting 50
+	assert.Equal(t, int64(10000*50), m.wallets["user1"].WithheldCash[buy.OrderID])
+	assert.Equal(t, int64(10_000_000-10000*50), m.GetWallet("user1").CashBalance)
+
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+}
+
+func TestModifyOrder_RefusedByEngine(t *testing.T) {
+	m := newTestManager()
+	engine, match := newMatcher(t, m, false)
+	buy, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 100)
+	require.NoError(t, err)
+	match()
+
+	// The modify is accepted, but the order fills before it is sequenced
+	_, err = m.ModifyOrder(buy.OrderID, 10000, 150)
+	require.NoError(t, err)
+	modifyEvent := <-m.OrderOut
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	match()
+	m.processExecutionEvent(engine.HandleOrder(modifyEvent))
+
+	assert.Equal(t, domain.OrderStatusFilled, m.GetOrder(buy.OrderID).Status)
+	assert.Empty(t, m.wallets["user1"].WithheldCash, "nothing is left withheld for the filled order")
+	assert.Equal(t, int64(100), m.wallets["user1"].dailyVolume["AAPL"], "the refused increase gives its volume back")
+}
+
+func TestModifyOrder_FullIntakeRollsBack(t *testing.T) {
+	m := NewManager(1_000_000, 1)
+	m.InitWallet("user1", 10_000_000, nil)
+	buy, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 100)
+	require.NoError(t, err)
+
+	// The new order still fills the intake
+	_, err = m.ModifyOrder(buy.OrderID, 10100, 200)
+	assert.Equal(t, RejectQueueFull, RejectCodeOf(err))
+	assert.Equal(t, int64(10000*100), m.wallets["user1"].WithheldCash[buy.OrderID])
+	assert.Equal(t, int64(100), m.wallets["user1"].dailyVolume["AAPL"])
+
+	_, err = m.ModifyOrder(buy.OrderID, 10000, 40)
+	assert.Equal(t, RejectQueueFull, RejectCodeOf(err))
+	assert.Equal(t, int64(10000*100), m.wallets["user1"].WithheldCash[buy.OrderID])
+	assert.Equal(t, int64(100), m.wallets["user1"].dailyVolume["AAPL"])
+}
+
+func TestModifyOrder_Invalid(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	_, err := m.ModifyOrder("missing", 10000, 10)
+	assert.Equal(t, RejectUnknownOrder, RejectCodeOf(err))
+
+	order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 10)
+	require.NoError(t, err)
+	match()
+	_, err = m.CancelOrder(order.OrderID)
+	require.NoError(t, err)
+	match()
+	_, err = m.ModifyOrder(order.OrderID, 10000, 5)
+	assert.Equal(t, RejectInvalidRequest, RejectCodeOf(err))
+}
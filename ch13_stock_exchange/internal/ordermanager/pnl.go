@@ -0,0 +1,124 @@
+package ordermanager
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Positions and PnL.
+//
+// Each wallet keeps, per symbol, the cost of the shares it holds at average
+// cost, and the profit it has realized selling them. A buy adds what was
+// paid to the cost; a sell takes off the held shares' average cost for the
+// quantity sold, and realizes the proceeds minus that cost. Holdings seeded
+// through InitWallet were not bought on the exchange and enter at zero cost.
+// Unrealized PnL marks the holdings to the symbol's last trade price.
+
+// PositionPnL is a user's position in one symbol and its PnL, in cents.
+type PositionPnL struct {
+	Symbol        string `json:"symbol"`
+	Quantity      int64  `json:"quantity"`
+	AvgPrice      int64  `json:"avg_price"`  // cost per share, rounded to the cent
+	Cost          int64  `json:"cost"`       // average cost of the held quantity
+	LastPrice     int64  `json:"last_price"` // 0 if the symbol has not traded
+	MarketValue   int64  `json:"market_value"`
+	RealizedPnL   int64  `json:"realized_pnl"`
+	UnrealizedPnL int64  `json:"unrealized_pnl"` // 0 if the symbol has not traded
+}
+
+// PnLReport is a user's positions, by symbol, and their PnL totals.
+type PnLReport struct {
+	UserID        string        `json:"user_id"`
+	Positions     []PositionPnL `json:"positions"`
+	RealizedPnL   int64         `json:"realized_pnl"`
+	UnrealizedPnL int64         `json:"unrealized_pnl"`
+}
+
+// SetLastPriceSource sets where unrealized PnL gets each symbol's last trade
+// price from, usually the market data publisher's LastPrice. It must not
+// call back into the manager. Without one unrealized PnL is always 0.
+func (m *Manager) SetLastPriceSource(source func(symbol string) int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastPrice = source
+}
+
+// GetPnL returns a user's positions and PnL: every symbol the user holds or
+// has realized profit or loss on. Returns RejectUnknownUser for a user
+// without a wallet.
+func (m *Manager) GetPnL(userID string) (*PnLReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wallet := m.wallets[userID]
+	if wallet == nil {
+		return nil, rejectf(RejectUnknownUser, "user %s not found", userID)
+	}
+	unlock := m.lockUser(userID)
+	symbols := make(map[string]bool)
+	for symbol, qty := range wallet.Holdings {
+		if qty != 0 {
+			symbols[symbol] = true
+		}
+	}
+	for symbol := range wallet.realizedPnL {
+		symbols[symbol] = true
+	}
+	report := &PnLReport{UserID: userID, Positions: []PositionPnL{}}
+	for symbol := range symbols {
+		qty := wallet.Holdings[symbol]
+		pos := PositionPnL{
+			Symbol:      symbol,
+			Quantity:    qty,
+			Cost:        wallet.costBasis[symbol],
+			RealizedPnL: wallet.realizedPnL[symbol],
+		}
+		if qty > 0 {
+			pos.AvgPrice = mulDiv(pos.Cost, m.quantityScale(symbol), qty)
+		}
+		report.Positions = append(report.Positions, pos)
+	}
+	unlock()
+
+	// The price source is called without the user's lock
+	for i := range report.Positions {
+		pos := &report.Positions[i]
+		if m.lastPrice != nil {
+			pos.LastPrice = m.lastPrice(pos.Symbol)
+		}
+		if pos.LastPrice > 0 {
+			pos.MarketValue = m.notional(pos.Symbol, pos.LastPrice, pos.Quantity)
+			pos.UnrealizedPnL = pos.MarketValue - pos.Cost
+		}
+		report.RealizedPnL += pos.RealizedPnL
+		report.UnrealizedPnL += pos.UnrealizedPnL
+	}
+	sort.Slice(report.Positions, func(i, j int) bool { return report.Positions[i].Symbol < report.Positions[j].Symbol })
+	return report, nil
+}
+
+// addPositionCost records a buy that cost cents. Call it with the user's
+// lock held.
+func addPositionCost(w *Wallet, symbol string, cost int64) {
+	w.costBasis[symbol] += cost
+}
+
+// realizePosition records a sale of quantity units out of held, the holding
+// before the sale, for proceeds cents: the sold units' average cost comes
+// off the cost basis and the difference is realized. Call it with the
+// user's lock held.
+func realizePosition(w *Wallet, symbol string, quantity, held, proceeds int64) {
+	cost := w.costBasis[symbol]
+	if held > 0 {
+		cost = mulDiv(cost, min(quantity, held), held)
+	}
+	w.costBasis[symbol] -= cost
+	w.realizedPnL[symbol] += proceeds - cost
+}
+
+// mulDiv returns a*b/c, rounded toward zero, without overflowing in between.
+func mulDiv(a, b, c int64) int64 {
+	var r big.Int
+	r.Mul(big.NewInt(a), big.NewInt(b))
+	return r.Quo(&r, big.NewInt(c)).Int64()
+}
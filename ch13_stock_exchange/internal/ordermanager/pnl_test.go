@@ -0,0 +1,78 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPnL_BuyThenPartialSell(t *testing.T) {
+	m := NewManager(1_000_000, 100)
+	m.InitWallet("user1", 10_000_000, nil)
+	m.InitWallet("user2", 10_000_000, map[string]int64{"AAPL": 5000})
+	lastPrice := map[string]int64{}
+	m.SetLastPriceSource(func(symbol string) int64 { return lastPrice[symbol] })
+	_, match := newMatcher(t, m, false)
+	place := func(user string, side domain.Side, price, qty int64) {
+		t.Helper()
+		_, err := m.PlaceOrder(user, "AAPL", side, price, qty)
+		require.NoError(t, err)
+		match()
+	}
+
+	// Bought 100 in two fills: 60 at 100.00 and 40 at 101.00
+	place("user2", domain.SideSell, 10000, 60)
+	place("user2", domain.SideSell, 10100, 40)
+	place("user1", domain.SideBuy, 10100, 100)
+
+	report, err := m.GetPnL("user1")
+	require.NoError(t, err)
+	require.Len(t, report.Positions, 1)
+	assert.Equal(t, PositionPnL{Symbol: "AAPL", Quantity: 100, AvgPrice: 10040, Cost: 1_004_000}, report.Positions[0])
+
+	// Sold 30 at 102.00: 30 * 100.40 comes off the cost, the rest is realized
+	place("user2", domain.SideBuy, 10200, 30)
+	place("user1", domain.SideSell, 10200, 30)
+	lastPrice["AAPL"] = 10300
+
+	report, err = m.GetPnL("user1")
+	require.NoError(t, err)
+	require.Len(t, report.Positions, 1)
+	assert.Equal(t, PositionPnL{
+		Symbol:        "AAPL",
+		Quantity:      70,
+		AvgPrice:      10040,
+		Cost:          702_800,
+		LastPrice:     10300,
+		MarketValue:   721_000,
+		RealizedPnL:   306_000 - 301_200,
+		UnrealizedPnL: 721_000 - 702_800,
+	}, report.Positions[0])
+	assert.Equal(t, int64(4_800), report.RealizedPnL)
+	assert.Equal(t, int64(18_200), report.UnrealizedPnL)
+
+	// Selling the rest closes the position; the realized PnL stays listed
+	place("user2", domain.SideBuy, 10000, 70)
+	place("user1", domain.SideSell, 10000, 70)
+	report, err = m.GetPnL("user1")
+	require.NoError(t, err)
+	require.Len(t, report.Positions, 1)
+	assert.Zero(t, report.Positions[0].Quantity)
+	assert.Zero(t, report.Positions[0].Cost)
+	assert.Equal(t, int64(4_800+700_000-702_800), report.RealizedPnL)
+	assert.Zero(t, report.UnrealizedPnL)
+
+	_, err = m.GetPnL("nobody")
+	assert.Equal(t, RejectUnknownUser, RejectCodeOf(err))
+}
+
+func TestPnL_SeededHoldingsAtZeroCost(t *testing.T) {
+	m := newTestManager()
+	report, err := m.GetPnL("user1")
+	require.NoError(t, err)
+	require.Len(t, report.Positions, 1)
+	assert.Equal(t, PositionPnL{Symbol: "AAPL", Quantity: 5000}, report.Positions[0])
+	assert.Zero(t, report.UnrealizedPnL, "no last price source")
+}
@@ -0,0 +1,94 @@
+package ordermanager
+
+import (
+	"sort"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Order list status filters
+const (
+	OrderFilterOpen     = "open"     // new or partially filled
+	OrderFilterFilled   = "filled"   // fully filled
+	OrderFilterCanceled = "canceled" // canceled, possibly after partial fills
+)
+
+// MaxOrderPageSize caps how many orders one ListOrders page returns.
+const MaxOrderPageSize = 500
+
+// OrderPage is one page of a user's orders, newest first. Total counts every
+// order matching the filter, so clients can tell when they have seen them all.
+type OrderPage struct {
+	Orders []*domain.Order `json:"orders"`
+	Total  int             `json:"total"`
+	Offset int             `json:"offset"`
+	Limit  int             `json:"limit"`
+}
+
+// OrderStatus returns a copy of an order as of the last execution event
+// applied, with its filled and remaining quantities. Orders evicted by
+// SetOrderRetention are unknown.
+func (m *Manager) OrderStatus(orderID string) (*domain.Order, error) {
+	m.ordersMu.RLock()
+	defer m.ordersMu.RUnlock()
+	order, exists := m.orders[orderID]
+	if !exists {
+		return nil, rejectf(RejectUnknownOrder, "order %s not found", orderID)
+	}
+	copied := *order
+	return &copied, nil
+}
+
+// ListOrders returns a page of copies of a user's orders, newest first,
+// optionally narrowed to one of the OrderFilter statuses; an empty status
+// lists every order the manager still holds. limit is clamped to
+// [1, MaxOrderPageSize].
+func (m *Manager) ListOrders(userID, status string, offset, limit int) (OrderPage, error) {
+	match, err := orderFilter(status)
+	if err != nil {
+		return OrderPage{}, err
+	}
+	if offset < 0 {
+		return OrderPage{}, rejectf(RejectInvalidRequest, "offset must not be negative, got %d", offset)
+	}
+	limit = max(1, min(limit, MaxOrderPageSize))
+
+	m.ordersMu.RLock()
+	var orders []*domain.Order
+	for _, order := range m.orders {
+		if order.UserID == userID && match(order) {
+			copied := *order
+			orders = append(orders, &copied)
+		}
+	}
+	m.ordersMu.RUnlock()
+
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].OrderID > orders[j].OrderID
+	})
+
+	page := OrderPage{Orders: []*domain.Order{}, Total: len(orders), Offset: offset, Limit: limit}
+	if offset < len(orders) {
+		page.Orders = orders[offset:min(offset+limit, len(orders))]
+	}
+	return page, nil
+}
+
+// orderFilter returns the predicate of a list status filter.
+func orderFilter(status string) (func(*domain.Order) bool, error) {
+	switch status {
+	case "":
+		return func(*domain.Order) bool { return true }, nil
+	case OrderFilterOpen:
+		return isOpen, nil
+	case OrderFilterFilled:
+		return func(o *domain.Order) bool { return o.Status == domain.OrderStatusFilled }, nil
+	case OrderFilterCanceled:
+		return func(o *domain.Order) bool { return o.Status == domain.OrderStatusCanceled }, nil
+	}
+	return nil, rejectf(RejectInvalidRequest, "status must be %s, %s or %s, got %q",
+		OrderFilterOpen, OrderFilterFilled, OrderFilterCanceled, status)
+}
@@ -0,0 +1,106 @@
+package ordermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderStatus_ReflectsFills(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	sell, err := m.PlaceOrder("user1", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideBuy, 10000, 30)
+	require.NoError(t, err)
+	match()
+
+	status, err := m.OrderStatus(sell.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusPartiallyFilled, status.Status)
+	assert.Equal(t, int64(30), status.FilledQuantity)
+	assert.Equal(t, int64(70), status.RemainingQuantity)
+
+	// A copy: later fills don't change what was returned
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideBuy, 10000, 70)
+	require.NoError(t, err)
+	match()
+	assert.Equal(t, int64(70), status.RemainingQuantity)
+	filled, err := m.OrderStatus(sell.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.OrderStatusFilled, filled.Status)
+
+	_, err = m.OrderStatus("missing")
+	assert.Equal(t, RejectUnknownOrder, RejectCodeOf(err))
+}
+
+func TestListOrders_FiltersAndPaginates(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	clock := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	})
+
+	place := func(user string, side domain.Side, price, qty int64) *domain.Order {
+		t.Helper()
+		order, err := m.PlaceOrder(user, "AAPL", side, price, qty)
+		require.NoError(t, err)
+		match()
+		return order
+	}
+	filled := place("user1", domain.SideSell, 10000, 10)
+	place("user2", domain.SideBuy, 10000, 10)
+	canceled := place("user1", domain.SideBuy, 9000, 10)
+	_, err := m.CancelOrder(canceled.OrderID)
+	require.NoError(t, err)
+	match()
+	open1 := place("user1", domain.SideBuy, 9100, 10)
+	open2 := place("user1", domain.SideSell, 11000, 10)
+
+	ids := func(page OrderPage) []string {
+		var out []string
+		for _, o := range page.Orders {
+			out = append(out, o.OrderID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		status string
+		want   []string
+	}{
+		{"", []string{open2.OrderID, open1.OrderID, canceled.OrderID, filled.OrderID}},
+		{OrderFilterOpen, []string{open2.OrderID, open1.OrderID}},
+		{OrderFilterFilled, []string{filled.OrderID}},
+		{OrderFilterCanceled, []string{canceled.OrderID}},
+	}
+	for _, tt := range tests {
+		t.Run("status="+tt.status, func(t *testing.T) {
+			page, err := m.ListOrders("user1", tt.status, 0, 50)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(page))
+			assert.Equal(t, len(tt.want), page.Total)
+		})
+	}
+
+	page, err := m.ListOrders("user1", "", 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{open1.OrderID, canceled.OrderID}, ids(page))
+	assert.Equal(t, 4, page.Total)
+
+	page, err = m.ListOrders("user1", "", 10, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page.Orders)
+	assert.NotNil(t, page.Orders)
+
+	_, err = m.ListOrders("user1", "pending", 0, 50)
+	assert.Equal(t, RejectInvalidRequest, RejectCodeOf(err))
+	_, err = m.ListOrders("user1", "", -1, 50)
+	assert.Equal(t, RejectInvalidRequest, RejectCodeOf(err))
+}
@@ -0,0 +1,143 @@
+package ordermanager
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// walletBaseline is a wallet's balances as last seeded through InitWallet.
+type walletBaseline struct {
+	cash     int64
+	holdings map[string]int64
+}
+
+// WalletCorrection is one wallet's drift from what the execution history
+// implies. Drift is actual minus expected, so a dropped buy settlement shows
+// as positive cash drift and negative share drift.
+type WalletCorrection struct {
+	UserID           string           `json:"user_id"`
+	ExpectedCash     int64            `json:"expected_cash"`
+	ActualCash       int64            `json:"actual_cash"`
+	CashDrift        int64            `json:"cash_drift"`
+	ExpectedHoldings map[string]int64 `json:"expected_holdings"`
+	ShareDrift       map[string]int64 `json:"share_drift,omitempty"` // only symbols with non-zero drift
+}
+
+// ReconcileReport summarizes a reconciliation run.
+type ReconcileReport struct {
+	Executions  int                `json:"executions"`
+	Corrections []WalletCorrection `json:"corrections"`
+	// Unresolved lists executions whose orders or wallets are unknown,
+	// e.g. evicted by order retention; nothing is corrected when non-empty
+	Unresolved []string `json:"unresolved,omitempty"`
+	Corrected  bool     `json:"corrected"`
+}
+
+// ReconcileFromExecutions replays execs, the full execution history, over
+// the balances seeded by InitWallet and compares the result with each
+// wallet. With correct set, drifted wallets are overwritten with the
+// expected cash and holdings; withholdings are left alone.
+//
+// Executions are deduplicated by ExecID. The history must be complete and
+// every execution in it already settled (or dropped), so run this while
+// trading is halted: an execution still queued for settlement would be
+// counted twice. Re-initializing a wallet restarts its baseline, so history
+// from before the re-init must not be passed in.
+func (m *Manager) ReconcileFromExecutions(execs []*domain.Execution, correct bool) (ReconcileReport, error) {
+	// Exclusive, so no settlement runs between computing and correcting
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expected := make(map[string]*walletBaseline, len(m.initialWallets))
+	for userID, base := range m.initialWallets {
+		holdings := make(map[string]int64, len(base.holdings))
+		for sym, qty := range base.holdings {
+			holdings[sym] = qty
+		}
+		expected[userID] = &walletBaseline{cash: base.cash, holdings: holdings}
+	}
+
+	report := ReconcileReport{Corrections: []WalletCorrection{}}
+	seen := make(map[string]bool, len(execs))
+	// Fractional fills are charged on each buy order's running value, as in settlement
+	charges := make(map[string]*fillCharge)
+	for _, exec := range execs {
+		if seen[exec.ExecID] {
+			continue
+		}
+		seen[exec.ExecID] = true
+		report.Executions++
+
+		buyer, seller, _ := m.executionParties(exec)
+		if buyer == nil || expected[buyer.UserID] == nil || expected[seller.UserID] == nil {
+			report.Unresolved = append(report.Unresolved, exec.ExecID)
+			continue
+		}
+		charge := charges[buyer.OrderID]
+		if charge == nil {
+			charge = &fillCharge{}
+			charges[buyer.OrderID] = charge
+		}
+		cost := charge.add(exec.Price, exec.Quantity, m.quantityScale(exec.Symbol))
+		expected[buyer.UserID].cash -= cost
+		expected[buyer.UserID].holdings[exec.Symbol] += exec.Quantity
+		expected[seller.UserID].cash += cost
+		expected[seller.UserID].holdings[exec.Symbol] -= exec.Quantity
+	}
+
+	for userID, want := range expected {
+		w := m.wallets[userID]
+		if w == nil {
+			continue
+		}
+		correction := WalletCorrection{
+			UserID:           userID,
+			ExpectedCash:     want.cash,
+			ActualCash:       w.CashBalance,
+			CashDrift:        w.CashBalance - want.cash,
+			ShareDrift:       make(map[string]int64),
+			ExpectedHoldings: want.holdings,
+		}
+		for sym, qty := range w.Holdings {
+			if d := qty - want.holdings[sym]; d != 0 {
+				correction.ShareDrift[sym] = d
+			}
+		}
+		for sym, qty := range want.holdings {
+			if _, held := w.Holdings[sym]; !held && qty != 0 {
+				correction.ShareDrift[sym] = -qty
+			}
+		}
+		if correction.CashDrift != 0 || len(correction.ShareDrift) > 0 {
+			report.Corrections = append(report.Corrections, correction)
+		}
+	}
+
+	sort.Slice(report.Corrections, func(i, j int) bool {
+		return report.Corrections[i].UserID < report.Corrections[j].UserID
+	})
+
+	if len(report.Unresolved) > 0 {
+		return report, fmt.Errorf("%d executions could not be attributed to known orders and wallets", len(report.Unresolved))
+	}
+	if !correct || len(report.Corrections) == 0 {
+		return report, nil
+	}
+
+	for _, c := range report.Corrections {
+		w := m.wallets[c.UserID]
+		unlock := m.lockUser(c.UserID)
+		w.CashBalance = c.ExpectedCash
+		w.Holdings = make(map[string]int64, len(c.ExpectedHoldings))
+		for sym, qty := range c.ExpectedHoldings {
+			w.Holdings[sym] = qty
+		}
+		unlock()
+		log.Printf("[ordermanager] reconciled wallet %s: cash drift %d, share drift %v", c.UserID, c.CashDrift, c.ShareDrift)
+	}
+	report.Corrected = true
+	return report, nil
+}
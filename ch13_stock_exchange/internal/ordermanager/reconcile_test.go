@@ -0,0 +1,86 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileFromExecutions_RestoresSkippedSettlement(t *testing.T) {
+	m := newTestManager()
+	engine := matching.NewEngine()
+
+	var history []*domain.Execution
+	trade := func(userID string, side domain.Side, price, qty int64, settle bool) {
+		_, err := m.PlaceOrder(userID, "AAPL", side, price, qty)
+		require.NoError(t, err)
+		event := engine.HandleOrder(<-m.OrderOut)
+		history = append(history, event.Executions...)
+		if settle {
+			m.processExecutionEvent(event)
+		}
+	}
+
+	trade("user1", domain.SideSell, 10000, 100, true)
+	trade("user2", domain.SideBuy, 10000, 60, true)
+	// Settlement of this trade is lost
+	trade("user2", domain.SideBuy, 10000, 40, false)
+	require.Len(t, history, 2)
+
+	// Only the first trade moved money
+	assert.Equal(t, int64(10_000_000+600_000), m.GetWallet("user1").CashBalance)
+	assert.Equal(t, int64(5060), m.GetWallet("user2").Holdings["AAPL"])
+
+	// A dry run reports the drift without touching wallets
+	report, err := m.ReconcileFromExecutions(history, false)
+	require.NoError(t, err)
+	assert.False(t, report.Corrected)
+	assert.Equal(t, 2, report.Executions)
+	require.Len(t, report.Corrections, 2)
+	assert.Equal(t, "user1", report.Corrections[0].UserID)
+	assert.Equal(t, int64(-400_000), report.Corrections[0].CashDrift)
+	assert.Equal(t, map[string]int64{"AAPL": 40}, report.Corrections[0].ShareDrift)
+	assert.Equal(t, "user2", report.Corrections[1].UserID)
+	assert.Equal(t, int64(400_000), report.Corrections[1].CashDrift)
+	assert.Equal(t, map[string]int64{"AAPL": -40}, report.Corrections[1].ShareDrift)
+	assert.Equal(t, int64(10_000_000+600_000), m.GetWallet("user1").CashBalance)
+
+	report, err = m.ReconcileFromExecutions(history, true)
+	require.NoError(t, err)
+	assert.True(t, report.Corrected)
+
+	user1, user2 := m.GetWallet("user1"), m.GetWallet("user2")
+	assert.Equal(t, int64(11_000_000), user1.CashBalance)
+	assert.Equal(t, int64(4900), user1.Holdings["AAPL"])
+	assert.Equal(t, int64(9_000_000), user2.CashBalance)
+	assert.Equal(t, int64(5100), user2.Holdings["AAPL"])
+
+	_, err = m.VerifyConservation()
+	assert.NoError(t, err)
+
+	// Nothing left to correct; repeated executions are counted once
+	report, err = m.ReconcileFromExecutions(append(history, history...), true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Executions)
+	assert.Empty(t, report.Corrections)
+	assert.False(t, report.Corrected)
+}
+
+func TestReconcileFromExecutions_UnknownOrdersBlockCorrection(t *testing.T) {
+	m := newTestManager()
+	m.wallets["user1"].CashBalance += 500
+
+	report, err := m.ReconcileFromExecutions([]*domain.Execution{{
+		ExecID: "x-exec-1", Symbol: "AAPL", Price: 10000, Quantity: 1,
+		TakerOrderID: "gone-1", MakerOrderID: "gone-2",
+	}}, true)
+	require.Error(t, err)
+	assert.Equal(t, []string{"x-exec-1"}, report.Unresolved)
+	assert.False(t, report.Corrected)
+	// Drift is still reported but left in place
+	require.Len(t, report.Corrections, 1)
+	assert.Equal(t, int64(10_000_500), m.GetWallet("user1").CashBalance)
+}
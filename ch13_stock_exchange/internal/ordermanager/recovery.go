@@ -0,0 +1,209 @@
+package ordermanager
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Restart recovery.
+//
+// The sequencer's journal rebuilds the order books by replaying every
+// sequenced event into a fresh engine (see sequencer.Recover). The manager
+// rebuilds its side from the same replay: RecoverEvent registers each
+// accepted order with the withholding it was placed with, then applies the
+// engine's result like a live execution event, so fills settle, cancels
+// release and the orders end up in their last state.
+//
+// Wallets are not sequenced, so InitWallet calls are journaled separately,
+// each with the inbound sequence number the sequencer had reached. Replay
+// puts each one back between the same two order events.
+
+// walletInit is one InitWallet call as written to the wallet journal.
+type walletInit struct {
+	// AfterSeq is the last sequence number stamped before the call
+	AfterSeq uint64           `json:"after_seq"`
+	UserID   string           `json:"user_id"`
+	Cash     int64            `json:"cash"`
+	Holdings map[string]int64 `json:"holdings,omitempty"`
+}
+
+// walletJournal appends wallet initializations to a file.
+type walletJournal struct {
+	file    *os.File
+	lastSeq func() uint64
+}
+
+// append writes one initialization, stamped with the current sequence
+// number. Write failures are logged; the wallet is still initialized. A nil
+// journal writes nothing.
+func (j *walletJournal) append(init walletInit) {
+	if j == nil {
+		return
+	}
+	init.AfterSeq = j.lastSeq()
+	line, err := json.Marshal(init)
+	if err == nil {
+		_, err = j.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		log.Printf("[ordermanager] ERROR: wallet journal write failed for %s: %v", init.UserID, err)
+	}
+}
+
+// EnableWalletJournal appends every InitWallet call to path, one JSON object
+// per line, stamped with lastSeq, usually the sequencer's CurrentInboundSeq.
+// Call it after recovery, so replayed wallets are not journaled twice.
+func (m *Manager) EnableWalletJournal(path string, lastSeq func() uint64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open wallet journal: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.walletJournal = &walletJournal{file: f, lastSeq: lastSeq}
+	return nil
+}
+
+// closeWalletJournal stops journaling wallet initializations.
+func (m *Manager) closeWalletJournal() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.walletJournal == nil {
+		return
+	}
+	if err := m.walletJournal.file.Close(); err != nil {
+		log.Printf("[ordermanager] ERROR: wallet journal close failed: %v", err)
+	}
+	m.walletJournal = nil
+}
+
+// LoadWalletJournal reads the wallet initializations journaled at path for
+// RecoverEvent and FinishRecovery to replay. Must be called before the
+// order journal is replayed.
+func (m *Manager) LoadWalletJournal(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open wallet journal: %w", err)
+	}
+	defer f.Close()
+
+	var inits []walletInit
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var init walletInit
+		if err := json.Unmarshal(scanner.Bytes(), &init); err != nil {
+			return fmt.Errorf("wallet journal line %d: %w", line, err)
+		}
+		inits = append(inits, init)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read wallet journal: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pendingInits = inits
+	return nil
+}
+
+// RecoverEvent rebuilds the manager's state for one event replayed from the
+// sequencer's journal, with the engine's result; pass it to
+// sequencer.Recover. Wallets initialized before the event are restored
+// first. Must be called before Start.
+func (m *Manager) RecoverEvent(event *domain.OrderEvent, result *domain.ExecutionEvent) {
+	m.mu.Lock()
+	m.replayWalletInits(event.Order.SequenceID)
+	m.mu.Unlock()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	accepted := result == nil || result.Rejected == nil
+	switch {
+	// A refused duplicate was never the manager's order
+	case event.Action == domain.OrderActionNew && accepted:
+		m.restoreOrder(event.Order)
+	case event.Action == domain.OrderActionModify && accepted:
+		m.restoreModifyVolume(event)
+	}
+	if result != nil {
+		m.applyExecutionEvent(result)
+	}
+}
+
+// FinishRecovery restores the wallets initialized after the last replayed
+// order event. Must be called once the journal is replayed, before Start.
+func (m *Manager) FinishRecovery() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replayWalletInits(^uint64(0))
+}
+
+// replayWalletInits initializes the pending wallets journaled before seq.
+// Caller must hold m.mu exclusively.
+func (m *Manager) replayWalletInits(seq uint64) {
+	for len(m.pendingInits) > 0 && m.pendingInits[0].AfterSeq < seq {
+		init := m.pendingInits[0]
+		m.initWallet(init.UserID, init.Cash, init.Holdings)
+		m.pendingInits = m.pendingInits[1:]
+	}
+}
+
+// restoreOrder registers a replayed order and withholds for it what
+// PlaceOrder did; its fills and state are applied from the engine's result
+// afterwards. Caller must hold m.mu.
+func (m *Manager) restoreOrder(replayed *domain.Order) {
+	// The engine has already matched its copy; start from the order as placed
+	order := *replayed
+	order.Status = domain.OrderStatusNew
+	order.FilledQuantity = 0
+	order.RemainingQuantity = order.Quantity
+	if wallet := m.wallets[order.UserID]; wallet != nil {
+		unlock := m.lockUser(order.UserID)
+		if order.Side == domain.SideBuy {
+			wallet.WithheldCash[order.OrderID] = m.notional(order.Symbol, order.Price, order.Quantity)
+		} else {
+			wallet.WithheldShares[order.OrderID] = withheldShare{Symbol: order.Symbol, Quantity: order.Quantity}
+		}
+		// Orders from an earlier trading day no longer count
+		if m.volumeDayOf(order.CreatedAt) == m.volumeDayOf(m.now()) {
+			m.addDailyVolume(wallet, order.Symbol, order.Quantity)
+		}
+		unlock()
+	}
+
+	m.ordersMu.Lock()
+	m.orders[order.OrderID] = &order
+	m.trackExpiry(&order)
+	m.ordersMu.Unlock()
+}
+
+// restoreModifyVolume counts what a replayed modify changed towards its
+// user's daily volume, as ModifyOrder did, if it was made today. Caller must
+// hold m.mu.
+func (m *Manager) restoreModifyVolume(event *domain.OrderEvent) {
+	m.ordersMu.RLock()
+	stored, exists := m.orders[event.Order.OrderID]
+	var quantity int64
+	if exists {
+		quantity = stored.Quantity
+	}
+	m.ordersMu.RUnlock()
+	wallet := m.wallets[event.Order.UserID]
+	if !exists || wallet == nil || m.volumeDayOf(event.Timestamp) != m.volumeDayOf(m.now()) {
+		return
+	}
+	defer m.lockUser(event.Order.UserID)()
+	if added := event.Order.Quantity - quantity; added > 0 {
+		m.addDailyVolume(wallet, event.Order.Symbol, added)
+	} else {
+		m.refundDailyVolume(wallet, event.Order.Symbol, -added)
+	}
+}
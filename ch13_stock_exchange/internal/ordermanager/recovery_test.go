@@ -0,0 +1,77 @@
+package ordermanager
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// journaledEvent is an order event as the sequencer journals it, with the
+// engine's result.
+type journaledEvent struct {
+	event  domain.OrderEvent
+	order  domain.Order
+	result *domain.ExecutionEvent
+}
+
+func TestRecoverEvent_RebuildsOrdersAndWithholding(t *testing.T) {
+	walletPath := filepath.Join(t.TempDir(), "orders.jsonl.wallets")
+	m := NewManager(1_000_000, 100)
+	var seq uint64
+	require.NoError(t, m.EnableWalletJournal(walletPath, func() uint64 { return seq }))
+
+	// match stamps and matches the next event like the sequencer, keeping
+	// what it journals
+	engine := matching.NewEngine()
+	var journal []journaledEvent
+	match := func() {
+		event := <-m.OrderOut
+		seq++
+		event.Order.SequenceID = seq
+		entry := journaledEvent{event: *event, order: *event.Order}
+		result := engine.HandleOrder(event)
+		entry.result = result
+		journal = append(journal, entry)
+		m.processExecutionEvent(result)
+	}
+
+	m.InitWallet("user1", 1_000_000, nil)
+	buy, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 10)
+	require.NoError(t, err)
+	match()
+	// Initialized between two events: its sell must not replay before it
+	m.InitWallet("user2", 0, map[string]int64{"AAPL": 20})
+	sell, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 4)
+	require.NoError(t, err)
+	match()
+	m.InitWallet("user3", 500, nil)
+	m.Stop()
+
+	recovered := NewManager(1_000_000, 100)
+	require.NoError(t, recovered.LoadWalletJournal(walletPath))
+	replay := matching.NewEngine()
+	for _, entry := range journal {
+		order := entry.order
+		event := entry.event
+		event.Order = &order
+		recovered.RecoverEvent(&event, replay.HandleOrder(&event))
+	}
+	recovered.FinishRecovery()
+
+	assert.Equal(t, m.GetAllWallets(), recovered.GetAllWallets())
+	assert.Equal(t, int64(6*10000), recovered.wallets["user1"].WithheldCash[buy.OrderID])
+	assert.Empty(t, recovered.wallets["user2"].WithheldShares)
+	for _, id := range []string{buy.OrderID, sell.OrderID} {
+		assert.Equal(t, m.GetOrder(id).Status, recovered.GetOrder(id).Status)
+		assert.Equal(t, m.GetOrder(id).RemainingQuantity, recovered.GetOrder(id).RemainingQuantity)
+	}
+	assert.Equal(t, int64(10), recovered.wallets["user1"].dailyVolume["AAPL"])
+
+	report, err := recovered.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+}
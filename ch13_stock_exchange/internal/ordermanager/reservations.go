@@ -0,0 +1,199 @@
+package ordermanager
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+const (
+	// DefaultReservationTTL is how long a reservation holds funds before it is released.
+	DefaultReservationTTL = 30 * time.Second
+
+	reservationSweepInterval = time.Second
+)
+
+// Reservation is the first phase of a two-phase order: the order has passed
+// the risk and wallet checks and its funds or shares are withheld, but it has
+// not been sent to the sequencer. It is either committed, canceled, or
+// released automatically once ExpiresAt passes.
+type Reservation struct {
+	Token     string        `json:"token"`
+	Order     *domain.Order `json:"order"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// SetReservationTTL sets how long new reservations stay valid.
+func (m *Manager) SetReservationTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("reservation ttl must be positive, got %v", ttl)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reservationTTL = ttl
+	return nil
+}
+
+// ReserveOrder validates an order and withholds its funds or shares without
+// sending it to the book. The returned token commits or cancels it.
+func (m *Manager) ReserveOrder(userID, symbol string, side domain.Side, price, quantity int64, opts OrderOptions) (*Reservation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.checkIntakeLocked(); err != nil {
+		return nil, err
+	}
+
+	m.expireReservations()
+
+	// A market order is priced off the book when it is placed, which a
+	// reservation may be long after
+	if opts.Type == domain.OrderTypeMarket {
+		return nil, rejectf(RejectInvalidOptions, "market orders cannot be reserved")
+	}
+
+	m.resMu.Lock()
+	defer m.resMu.Unlock()
+	unlock := m.lockUser(userID)
+	order, err := m.prepareOrder(userID, symbol, side, price, quantity, opts)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reservation{
+		Token:     uuid.New().String(),
+		Order:     order,
+		ExpiresAt: m.now().Add(m.reservationTTL),
+	}
+	m.reservations[r.Token] = r
+
+	return r, nil
+}
+
+// CommitReservation sends a reserved order to the sequencer. A quantity of 0
+// commits the full reserved quantity; a smaller quantity commits only part of
+// it and releases the withholding for the rest.
+func (m *Manager) CommitReservation(token string, quantity int64) (*domain.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.checkIntakeLocked(); err != nil {
+		return nil, err
+	}
+	m.resMu.Lock()
+	defer m.resMu.Unlock()
+
+	r, exists := m.reservations[token]
+	if !exists {
+		return nil, fmt.Errorf("reservation %s not found", token)
+	}
+	defer m.lockUser(r.Order.UserID)()
+
+	if !m.now().Before(r.ExpiresAt) {
+		m.releaseReservation(r)
+		return nil, fmt.Errorf("reservation %s expired", token)
+	}
+
+	order := r.Order
+	if err := m.checkTradingHours(order.Symbol); err != nil {
+		return nil, err
+	}
+	if quantity < 0 || quantity > order.Quantity {
+		return nil, fmt.Errorf("commit quantity must be between 1 and reserved quantity %d", order.Quantity)
+	}
+	if quantity > 0 && quantity < order.Quantity {
+		if quantity < order.MinExecQty {
+			return nil, fmt.Errorf("commit quantity %d is below min_exec_qty %d", quantity, order.MinExecQty)
+		}
+		if err := m.checkLotSize(order.Symbol, quantity); err != nil {
+			return nil, err
+		}
+		m.shrinkReservation(order, quantity)
+	}
+
+	delete(m.reservations, token)
+	order.CreatedAt = m.now()
+	m.submitOrder(order)
+
+	return order, nil
+}
+
+// CancelReservation releases a reservation's withheld funds or shares.
+func (m *Manager) CancelReservation(token string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.resMu.Lock()
+	defer m.resMu.Unlock()
+
+	r, exists := m.reservations[token]
+	if !exists {
+		return fmt.Errorf("reservation %s not found", token)
+	}
+	defer m.lockUser(r.Order.UserID)()
+	m.releaseReservation(r)
+	return nil
+}
+
+// shrinkReservation reduces a reserved order to quantity, giving back the
+// withholding and daily volume for the difference. Caller must hold m.mu,
+// m.resMu and the order user's lock.
+func (m *Manager) shrinkReservation(order *domain.Order, quantity int64) {
+	wallet := m.wallets[order.UserID]
+	if wallet != nil {
+		if order.Side == domain.SideBuy {
+			wallet.WithheldCash[order.OrderID] = m.notional(order.Symbol, order.Price, quantity)
+		} else {
+			wallet.WithheldShares[order.OrderID] = withheldShare{Symbol: order.Symbol, Quantity: quantity}
+		}
+		m.refundDailyVolume(wallet, order.Symbol, order.Quantity-quantity)
+	}
+
+	order.Quantity = quantity
+	order.RemainingQuantity = quantity
+}
+
+// releaseReservation drops a reservation and undoes its withholding and
+// daily volume. Caller must hold m.mu, m.resMu and the order user's lock.
+func (m *Manager) releaseReservation(r *Reservation) {
+	delete(m.reservations, r.Token)
+	m.releaseWithheld(r.Order)
+	if wallet := m.wallets[r.Order.UserID]; wallet != nil {
+		m.refundDailyVolume(wallet, r.Order.Symbol, r.Order.Quantity)
+	}
+}
+
+// expireReservations releases every reservation past its expiry.
+// Caller must hold m.mu and must not hold m.resMu or any user lock.
+func (m *Manager) expireReservations() {
+	m.resMu.Lock()
+	defer m.resMu.Unlock()
+
+	now := m.now()
+	for _, r := range m.reservations {
+		if !now.Before(r.ExpiresAt) {
+			log.Printf("[ordermanager] reservation %s expired, releasing order %s", r.Token, r.Order.OrderID)
+			unlock := m.lockUser(r.Order.UserID)
+			m.releaseReservation(r)
+			unlock()
+		}
+	}
+}
+
+// sweepReservations periodically releases expired reservations so their
+// funds come back even when no new orders arrive.
+func (m *Manager) sweepReservations() {
+	ticker := time.NewTicker(reservationSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.mu.RLock()
+			m.expireReservations()
+			m.mu.RUnlock()
+		case <-m.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package ordermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReservationTestManager(now *time.Time) *Manager {
+	m := NewManager(1_000_000, 100)
+	m.SetClock(func() time.Time { return *now })
+	m.InitWallet("user1", 1_000_000, map[string]int64{"AAPL": 500})
+	return m
+}
+
+func TestReserveOrder_CommitPlacesOrder(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m := newReservationTestManager(&now)
+
+	r, err := m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 50, OrderOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, r.Token)
+	assert.Equal(t, now.Add(DefaultReservationTTL), r.ExpiresAt)
+
+	// Nothing reaches the sequencer until commit
+	select {
+	case ev := <-m.OrderOut:
+		t.Fatalf("reserve should not send the order, got %v", ev.Order.OrderID)
+	default:
+	}
+	assert.Nil(t, m.GetOrder(r.Order.OrderID))
+
+	order, err := m.CommitReservation(r.Token, 0)
+	require.NoError(t, err)
+	assert.Equal(t, r.Order.OrderID, order.OrderID)
+	assert.Equal(t, int64(50), order.Quantity)
+
+	event := <-m.OrderOut
+	assert.Equal(t, domain.OrderActionNew, event.Action)
+	assert.Equal(t, order.OrderID, event.Order.OrderID)
+	assert.NotNil(t, m.GetOrder(order.OrderID))
+
+	// The token is single-use
+	_, err = m.CommitReservation(r.Token, 0)
+	assert.Error(t, err)
+}
+
+func TestReserveOrder_SecondReserveSeesReducedFunds(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m := newReservationTestManager(&now)
+
+	// Withhold 600,000 of 1,000,000 cents
+	_, err := m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 60, OrderOptions{})
+	require.NoError(t, err)
+
+	_, err = m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 50, OrderOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "available 400000")
+
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 50)
+	assert.Error(t, err, "direct placement must also see the withholding")
+
+	_, err = m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 40, OrderOptions{})
+	assert.NoError(t, err)
+}
+
+func TestReserveOrder_ExpiryReleasesFunds(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m := newReservationTestManager(&now)
+	require.NoError(t, m.SetReservationTTL(10*time.Second))
+
+	r, err := m.ReserveOrder("user1", "AAPL", domain.SideSell, 10000, 500, OrderOptions{})
+	require.NoError(t, err)
+
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideSell, 10000, 1)
+	require.Error(t, err, "all shares are withheld")
+
+	now = now.Add(11 * time.Second)
+
+	// Expired reservations cannot be committed
+	_, err = m.CommitReservation(r.Token, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expired")
+
+	// and their shares are available again
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideSell, 10000, 500)
+	assert.NoError(t, err)
+}
+
+func TestReserveOrder_ExpiryWithoutCommitAttempt(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m := newReservationTestManager(&now)
+	require.NoError(t, m.SetReservationTTL(10*time.Second))
+
+	_, err := m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 100, OrderOptions{})
+	require.NoError(t, err)
+
+	now = now.Add(10 * time.Second)
+	m.mu.Lock()
+	m.expireReservations()
+	m.mu.Unlock()
+
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+	assert.Empty(t, m.reservations)
+	assert.Zero(t, m.wallets["user1"].dailyVolume["AAPL"])
+}
+
+func TestCancelReservation_ReleasesFunds(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m := newReservationTestManager(&now)
+
+	r, err := m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 100, OrderOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, m.CancelReservation(r.Token))
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+
+	_, err = m.CommitReservation(r.Token, 0)
+	assert.Error(t, err)
+	assert.Error(t, m.CancelReservation(r.Token))
+}
+
+func TestCommitReservation_PartialQuantity(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m := newReservationTestManager(&now)
+
+	r, err := m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 100, OrderOptions{})
+	require.NoError(t, err)
+
+	_, err = m.CommitReservation(r.Token, 101)
+	require.Error(t, err)
+
+	order, err := m.CommitReservation(r.Token, 30)
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), order.Quantity)
+	assert.Equal(t, int64(30), order.RemainingQuantity)
+	assert.Equal(t, int64(300_000), m.wallets["user1"].WithheldCash[order.OrderID])
+	assert.Equal(t, int64(30), m.wallets["user1"].dailyVolume["AAPL"])
+}
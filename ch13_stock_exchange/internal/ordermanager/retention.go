@@ -0,0 +1,76 @@
+package ordermanager
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+const retentionSweepInterval = time.Minute
+
+// SetOrderRetention evicts filled and canceled orders from the order map once
+// they have been terminal for longer than retention; zero (the default) keeps
+// every order. Evicted orders are no longer returned by GetOrder.
+func (m *Manager) SetOrderRetention(retention time.Duration) error {
+	if retention < 0 {
+		return fmt.Errorf("order retention must not be negative, got %v", retention)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orderRetention = retention
+	return nil
+}
+
+// markTerminal records when an order reached filled or canceled, starting its
+// retention window. Caller must hold m.mu and m.ordersMu.
+func (m *Manager) markTerminal(order *domain.Order) {
+	if order.Status != domain.OrderStatusFilled && order.Status != domain.OrderStatusCanceled {
+		return
+	}
+	if _, seen := m.closedAt[order.OrderID]; !seen {
+		m.closedAt[order.OrderID] = m.now()
+	}
+}
+
+// evictTerminalOrders drops orders terminal for longer than the retention
+// window and returns how many were evicted. Caller must hold m.mu.
+func (m *Manager) evictTerminalOrders() int {
+	if m.orderRetention <= 0 {
+		return 0
+	}
+	m.ordersMu.Lock()
+	defer m.ordersMu.Unlock()
+
+	cutoff := m.now().Add(-m.orderRetention)
+	evicted := 0
+	for orderID, closed := range m.closedAt {
+		if closed.After(cutoff) {
+			continue
+		}
+		delete(m.orders, orderID)
+		delete(m.closedAt, orderID)
+		evicted++
+	}
+	return evicted
+}
+
+// sweepTerminalOrders periodically evicts expired terminal orders so the
+// order map stays bounded over a long session.
+func (m *Manager) sweepTerminalOrders() {
+	ticker := time.NewTicker(retentionSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.mu.RLock()
+			if n := m.evictTerminalOrders(); n > 0 {
+				log.Printf("[ordermanager] evicted %d terminal orders", n)
+			}
+			m.mu.RUnlock()
+		case <-m.done:
+			return
+		}
+	}
+}
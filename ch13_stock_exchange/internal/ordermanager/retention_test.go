@@ -0,0 +1,96 @@
+package ordermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderRetention_EvictsOnlyExpiredTerminalOrders(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m := NewManager(1_000_000, 100)
+	m.SetClock(func() time.Time { return now })
+	require.NoError(t, m.SetOrderRetention(time.Hour))
+	m.InitWallet("buyer", 10_000_000, nil)
+	m.InitWallet("seller", 0, map[string]int64{"AAPL": 1000})
+
+	sell, err := m.PlaceOrder("seller", "AAPL", domain.SideSell, 10000, 100)
+	require.NoError(t, err)
+	buy, err := m.PlaceOrder("buyer", "AAPL", domain.SideBuy, 10000, 100)
+	require.NoError(t, err)
+	canceled, err := m.PlaceOrder("buyer", "AAPL", domain.SideBuy, 9000, 10)
+	require.NoError(t, err)
+	live, err := m.PlaceOrder("seller", "AAPL", domain.SideSell, 11000, 50)
+	require.NoError(t, err)
+	partial, err := m.PlaceOrder("seller", "AAPL", domain.SideSell, 10500, 50)
+	require.NoError(t, err)
+
+	// buy fills against sell, canceled is canceled, partial half fills
+	filledBuy := *buy
+	filledBuy.Status, filledBuy.FilledQuantity, filledBuy.RemainingQuantity = domain.OrderStatusFilled, 100, 0
+	sell.Status, sell.FilledQuantity, sell.RemainingQuantity = domain.OrderStatusFilled, 100, 0
+	m.processExecutionEvent(withStates(&domain.ExecutionEvent{
+		TakerOrder:  &filledBuy,
+		MakerOrders: []*domain.Order{sell},
+		Executions: []*domain.Execution{{
+			ExecID: "e1", Symbol: "AAPL", Price: 10000, Quantity: 100,
+			TakerOrderID: buy.OrderID, MakerOrderID: sell.OrderID,
+		}},
+	}))
+
+	canceledCopy := *canceled
+	canceledCopy.Status = domain.OrderStatusCanceled
+	m.processExecutionEvent(withStates(&domain.ExecutionEvent{TakerOrder: &canceledCopy}))
+
+	partialCopy := *partial
+	partialCopy.Status, partialCopy.FilledQuantity, partialCopy.RemainingQuantity = domain.OrderStatusPartiallyFilled, 25, 25
+	m.processExecutionEvent(withStates(&domain.ExecutionEvent{TakerOrder: &partialCopy}))
+
+	// Inside the window nothing is evicted
+	now = now.Add(59 * time.Minute)
+	m.mu.Lock()
+	assert.Equal(t, 0, m.evictTerminalOrders())
+	m.mu.Unlock()
+	assert.NotNil(t, m.GetOrder(buy.OrderID))
+
+	// Past the window the three terminal orders go, live ones stay
+	now = now.Add(2 * time.Minute)
+	m.mu.Lock()
+	assert.Equal(t, 3, m.evictTerminalOrders())
+	m.mu.Unlock()
+
+	for _, id := range []string{buy.OrderID, sell.OrderID, canceled.OrderID} {
+		assert.Nil(t, m.GetOrder(id), "terminal order %s should be evicted", id)
+	}
+	assert.NotNil(t, m.GetOrder(live.OrderID))
+	assert.NotNil(t, m.GetOrder(partial.OrderID))
+
+	_, err = m.CancelOrder(buy.OrderID)
+	assert.Error(t, err, "an evicted order is not found")
+	_, err = m.CancelOrder(live.OrderID)
+	assert.NoError(t, err, "live orders remain cancelable")
+}
+
+func TestOrderRetention_DisabledByDefault(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	m := NewManager(1_000_000, 100)
+	m.SetClock(func() time.Time { return now })
+	m.InitWallet("user1", 1_000_000, nil)
+
+	order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 10)
+	require.NoError(t, err)
+	canceled := *order
+	canceled.Status = domain.OrderStatusCanceled
+	m.processExecutionEvent(withStates(&domain.ExecutionEvent{TakerOrder: &canceled}))
+
+	now = now.Add(30 * 24 * time.Hour)
+	m.mu.Lock()
+	assert.Equal(t, 0, m.evictTerminalOrders())
+	m.mu.Unlock()
+	assert.NotNil(t, m.GetOrder(order.OrderID))
+
+	assert.Error(t, m.SetOrderRetention(-time.Second))
+}
@@ -0,0 +1,38 @@
+package ordermanager
+
+import (
+	"sort"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// CloseSymbol ends the trading session for a symbol: every open DAY order on
+// it is canceled through the sequencer, while GTC orders stay in the book for
+// the next session. Withheld funds and shares are released as each cancel
+// comes back from the matching engine, like a user cancel. Returns the orders
+// whose cancel was sent, oldest first; an order whose cancel was dropped by a
+// full intake is left open.
+func (m *Manager) CloseSymbol(symbol string) []*domain.Order {
+	m.ordersMu.RLock()
+	defer m.ordersMu.RUnlock()
+
+	var closing []*domain.Order
+	for _, order := range m.orders {
+		if order.Symbol != symbol || !order.IsDayOrder() {
+			continue
+		}
+		if order.Status == domain.OrderStatusFilled || order.Status == domain.OrderStatusCanceled {
+			continue
+		}
+		closing = append(closing, order)
+	}
+	sort.Slice(closing, func(i, j int) bool { return closing[i].CreatedAt.Before(closing[j].CreatedAt) })
+
+	canceled := closing[:0]
+	for _, order := range closing {
+		if m.emitOrderEvent(&domain.OrderEvent{Action: domain.OrderActionCancel, Order: order}) {
+			canceled = append(canceled, order)
+		}
+	}
+	return canceled
+}
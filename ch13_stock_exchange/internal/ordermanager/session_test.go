@@ -0,0 +1,97 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseSymbol_CancelsDayOrdersOnly(t *testing.T) {
+	m := newTestManager()
+	engine, match := newMatcher(t, m, false)
+
+	day, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 100, OrderOptions{TimeInForce: domain.TimeInForceDay})
+	require.NoError(t, err)
+	match()
+	gtc, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 9900, 100)
+	require.NoError(t, err)
+	match()
+	daySell, err := m.PlaceOrderWithOptions("user2", "AAPL", domain.SideSell, 10500, 50, OrderOptions{TimeInForce: domain.TimeInForceDay})
+	require.NoError(t, err)
+	match()
+
+	canceled := m.CloseSymbol("AAPL")
+	require.Len(t, canceled, 2)
+	assert.Equal(t, day.OrderID, canceled[0].OrderID)
+	assert.Equal(t, daySell.OrderID, canceled[1].OrderID)
+	for range canceled {
+		match()
+	}
+
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(day.OrderID).Status)
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(daySell.OrderID).Status)
+	assert.Equal(t, domain.OrderStatusNew, m.GetOrder(gtc.OrderID).Status)
+
+	// Only the GTC bid is left in the book
+	snap := engine.GetOrderBook("AAPL").GetL2Snapshot(10)
+	require.Len(t, snap.Bids, 1)
+	assert.Equal(t, domain.PriceLevel{Price: 9900, Quantity: 100}, snap.Bids[0])
+	assert.Empty(t, snap.Asks)
+
+	// Withholdings for the DAY orders are released; the GTC one is kept
+	assert.NotContains(t, m.wallets["user1"].WithheldCash, day.OrderID)
+	assert.Equal(t, int64(9900*100), m.wallets["user1"].WithheldCash[gtc.OrderID])
+	assert.Empty(t, m.wallets["user2"].WithheldShares)
+
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+}
+
+func TestCloseSymbol_LeavesOtherSymbolsAndClosedOrders(t *testing.T) {
+	m := newTestManager()
+	m.InitWallet("user3", 10_000_000, map[string]int64{"MSFT": 100})
+	_, match := newMatcher(t, m, false)
+	dayOpts := OrderOptions{TimeInForce: domain.TimeInForceDay}
+
+	other, err := m.PlaceOrderWithOptions("user3", "MSFT", domain.SideSell, 30000, 10, dayOpts)
+	require.NoError(t, err)
+	match()
+
+	// A DAY order that already filled has nothing to cancel
+	_, err = m.PlaceOrderWithOptions("user2", "AAPL", domain.SideSell, 10000, 100, dayOpts)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 100, dayOpts)
+	require.NoError(t, err)
+	match()
+
+	assert.Empty(t, m.CloseSymbol("AAPL"))
+	assert.Empty(t, m.OrderOut)
+	assert.Equal(t, domain.OrderStatusNew, m.GetOrder(other.OrderID).Status)
+}
+
+func TestCloseSymbol_ReturnsOnlySentCancels(t *testing.T) {
+	m := NewManager(1_000_000, 2)
+	m.InitWallet("user1", 10_000_000, nil)
+	dayOpts := OrderOptions{TimeInForce: domain.TimeInForceDay}
+
+	first, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 1, dayOpts)
+	require.NoError(t, err)
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 9900, 1, dayOpts)
+	require.NoError(t, err)
+
+	// One slot frees up, so only the oldest order's cancel gets through
+	<-m.OrderOut
+	canceled := m.CloseSymbol("AAPL")
+	require.Len(t, canceled, 1)
+	assert.Equal(t, first.OrderID, canceled[0].OrderID)
+}
+
+func TestPlaceOrder_RejectsUnknownTimeInForce(t *testing.T) {
+	m := newTestManager()
+	_, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 100, OrderOptions{TimeInForce: "GTD"})
+	assert.Error(t, err)
+}
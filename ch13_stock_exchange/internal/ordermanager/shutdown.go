@@ -0,0 +1,47 @@
+package ordermanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// CloseIntake stops accepting orders: placements, reservations and
+// reservation commits fail with RejectShuttingDown from now on. It waits for
+// the ones already in progress, so once it returns every accepted order has
+// been handed to the sequencer. Cancels are still accepted.
+func (m *Manager) CloseIntake() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.intakeClosed = true
+}
+
+// checkIntakeLocked rejects new orders once the intake is closed.
+// Caller must hold m.mu.
+func (m *Manager) checkIntakeLocked() error {
+	if m.intakeClosed {
+		return rejectf(RejectShuttingDown, "exchange is shutting down")
+	}
+	return nil
+}
+
+// DrainIntake queues marker behind every order event emitted so far, so the
+// sequencer reaches it only after all of them; marker.Sequenced closes then.
+// Shutdown calls it after CloseIntake, so nothing follows the marker; while
+// the intake is open it is a barrier for what was emitted before it. With
+// fair queuing the marker waits until the fair queue is empty.
+func (m *Manager) DrainIntake(ctx context.Context, marker *domain.DrainMarker) error {
+	event := &domain.OrderEvent{Action: domain.OrderActionDrain, Drain: marker}
+	if m.fair != nil {
+		m.fair.pushLast(event)
+		return nil
+	}
+
+	select {
+	case m.OrderOut <- event:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("order intake did not drain: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,67 @@
+package ordermanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseIntake_RejectsNewOrders(t *testing.T) {
+	m := NewManager(1_000_000, 100)
+	m.InitWallet("alice", 1_000_000, map[string]int64{"AAPL": 100})
+
+	accepted, err := m.PlaceOrder("alice", "AAPL", domain.SideSell, 10000, 1)
+	require.NoError(t, err)
+	r, err := m.ReserveOrder("alice", "AAPL", domain.SideSell, 10000, 1, OrderOptions{})
+	require.NoError(t, err)
+
+	m.CloseIntake()
+
+	_, err = m.PlaceOrder("alice", "AAPL", domain.SideBuy, 10000, 1)
+	assert.Equal(t, RejectShuttingDown, RejectCodeOf(err))
+	_, err = m.ReserveOrder("alice", "AAPL", domain.SideBuy, 10000, 1, OrderOptions{})
+	assert.Equal(t, RejectShuttingDown, RejectCodeOf(err))
+	_, err = m.CommitReservation(r.Token, 0)
+	assert.Equal(t, RejectShuttingDown, RejectCodeOf(err))
+
+	// Releasing funds and canceling still work
+	require.NoError(t, m.CancelReservation(r.Token))
+	_, err = m.CancelOrder(accepted.OrderID)
+	require.NoError(t, err)
+}
+
+func TestDrainIntake_MarkerFollowsQueuedOrders(t *testing.T) {
+	for _, fair := range []bool{false, true} {
+		m := NewManager(1_000_000, 100)
+		m.InitWallet("alice", 0, map[string]int64{"AAPL": 100})
+		m.InitWallet("bob", 0, map[string]int64{"AAPL": 100})
+		if fair {
+			m.EnableFairQueuing()
+		}
+
+		for i := 0; i < 5; i++ {
+			for _, user := range []string{"alice", "bob"} {
+				_, err := m.PlaceOrder(user, "AAPL", domain.SideSell, 10000, 1)
+				require.NoError(t, err)
+			}
+		}
+		m.CloseIntake()
+		marker := domain.NewDrainMarker()
+		require.NoError(t, m.DrainIntake(context.Background(), marker))
+
+		m.Start()
+		var actions []domain.OrderAction
+		for len(actions) < 11 {
+			actions = append(actions, (<-m.OrderOut).Action)
+		}
+		m.Stop()
+
+		for _, action := range actions[:10] {
+			assert.Equal(t, domain.OrderActionNew, action, "fair=%v", fair)
+		}
+		assert.Equal(t, domain.OrderActionDrain, actions[10], "fair=%v", fair)
+	}
+}
@@ -0,0 +1,89 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopBuy_SettlesWhenTriggered(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, true)
+	_, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 10)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, 10050, 100)
+	require.NoError(t, err)
+	match()
+
+	stop, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 0, 100, OrderOptions{
+		Type:           domain.OrderTypeMarket,
+		StopPrice:      10000,
+		MaxSlippageBps: 100,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10100*100), m.wallets["user1"].WithheldCash[stop.OrderID], "withheld at the stop price plus slippage")
+	match()
+	assert.Equal(t, domain.OrderStatusNew, m.GetOrder(stop.OrderID).Status)
+
+	// user3 takes the 10000 ask; the print triggers the stop into the 10050 level
+	m.InitWallet("user3", 10_000_000, nil)
+	_, err = m.PlaceOrder("user3", "AAPL", domain.SideBuy, 10000, 10)
+	require.NoError(t, err)
+	match()
+
+	stored := m.GetOrder(stop.OrderID)
+	assert.Equal(t, domain.OrderStatusFilled, stored.Status)
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+	wallet := m.GetWallet("user1")
+	assert.Equal(t, int64(10_000_000-10050*100), wallet.CashBalance)
+	assert.Equal(t, int64(5100), wallet.Holdings["AAPL"])
+}
+
+func TestStopBuy_GapCappedAtWithheldPrice(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, true)
+	_, err := m.PlaceOrder("user2", "AAPL", domain.SideSell, 10000, 10)
+	require.NoError(t, err)
+	match()
+	_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, 20000, 100)
+	require.NoError(t, err)
+	match()
+
+	// Without a protection price or slippage cap the cost has no bound
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 0, 100, OrderOptions{
+		Type:      domain.OrderTypeMarket,
+		StopPrice: 10000,
+	})
+	assert.Equal(t, RejectInvalidOptions, RejectCodeOf(err))
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+
+	m.InitWallet("tight", 10100*100, nil)
+	stop, err := m.PlaceOrderWithOptions("tight", "AAPL", domain.SideBuy, 0, 100, OrderOptions{
+		Type:           domain.OrderTypeMarket,
+		StopPrice:      10000,
+		MaxSlippageBps: 100,
+	})
+	require.NoError(t, err)
+	match()
+
+	// The trigger print leaves only the 20000 level, beyond what was withheld
+	m.InitWallet("user3", 10_000_000, nil)
+	_, err = m.PlaceOrder("user3", "AAPL", domain.SideBuy, 10000, 10)
+	require.NoError(t, err)
+	match()
+
+	stored := m.GetOrder(stop.OrderID)
+	assert.Equal(t, domain.OrderStatusCanceled, stored.Status)
+	assert.Zero(t, stored.FilledQuantity)
+	assert.Equal(t, int64(10100*100), m.GetWallet("tight").CashBalance)
+	assert.Empty(t, m.wallets["tight"].WithheldCash)
+}
+
+func TestStopPrice_Invalid(t *testing.T) {
+	m := newTestManager()
+	_, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideBuy, 10000, 10, OrderOptions{StopPrice: -1})
+	assert.Equal(t, RejectInvalidOptions, RejectCodeOf(err))
+}
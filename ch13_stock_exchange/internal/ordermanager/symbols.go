@@ -0,0 +1,235 @@
+package ordermanager
+
+import (
+	"fmt"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// PriceRoundingMode controls what happens to a price that is not on the tick grid.
+type PriceRoundingMode string
+
+const (
+	RoundingReject  PriceRoundingMode = "reject" // default: off-tick prices are rejected
+	RoundingNearest PriceRoundingMode = "round"  // nearest tick, halves round up
+	RoundingFloor   PriceRoundingMode = "floor"  // next tick down
+	RoundingCeil    PriceRoundingMode = "ceil"   // next tick up
+)
+
+// SymbolSpec holds per-instrument trading rules.
+type SymbolSpec struct {
+	TickSize     int64             // minimum price increment in cents (0 or 1 = any price)
+	RoundingMode PriceRoundingMode // default handling of off-tick prices
+	// LotSize is the quantity every order must be a multiple of, in the
+	// symbol's quantity units (0 or 1 = any quantity).
+	LotSize int64
+	// QuantityScale is how many quantity units make one share, a power of
+	// ten (0 or 1 = whole shares). With 1_000_000, quantities, holdings and
+	// withheld shares are all in micro-shares; prices stay per whole share.
+	QuantityScale int64
+	// PriceBandBps rejects orders priced more than this many basis points
+	// away from the symbol's reference price (0 = no band). Symbols without
+	// a reference price yet are not checked.
+	PriceBandBps int64
+}
+
+// SetSymbolSpec registers the trading rules for a symbol.
+func (m *Manager) SetSymbolSpec(symbol string, spec SymbolSpec) error {
+	if spec.TickSize < 0 {
+		return fmt.Errorf("tick size must be non-negative, got %d", spec.TickSize)
+	}
+	if spec.LotSize < 0 {
+		return fmt.Errorf("lot size must be non-negative, got %d", spec.LotSize)
+	}
+	if spec.RoundingMode != "" && !spec.RoundingMode.valid() {
+		return fmt.Errorf("unknown price rounding mode %q", spec.RoundingMode)
+	}
+	if spec.QuantityScale != 0 && !domain.ValidQuantityScale(spec.QuantityScale) {
+		return fmt.Errorf("quantity scale must be a power of ten up to %d, got %d", domain.MaxQuantityScale, spec.QuantityScale)
+	}
+	if spec.PriceBandBps < 0 {
+		return fmt.Errorf("price band must be non-negative, got %d bps", spec.PriceBandBps)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.symbols[symbol] = spec
+	return nil
+}
+
+// GetSymbolSpec returns the trading rules for a symbol, if registered.
+func (m *Manager) GetSymbolSpec(symbol string) (SymbolSpec, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	spec, ok := m.symbols[symbol]
+	return spec, ok
+}
+
+// QuantityScale returns how many quantity units make one share of symbol;
+// 1 unless the symbol trades in fractions.
+func (m *Manager) QuantityScale(symbol string) int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.quantityScale(symbol)
+}
+
+// FormatQuantity renders a quantity of symbol as a decimal number of shares,
+// for display.
+func (m *Manager) FormatQuantity(symbol string, quantity int64) string {
+	return domain.FormatQuantity(quantity, m.QuantityScale(symbol))
+}
+
+// quantityScale is QuantityScale for callers that hold the lock.
+func (m *Manager) quantityScale(symbol string) int64 {
+	if scale := m.symbols[symbol].QuantityScale; scale > 1 {
+		return scale
+	}
+	return 1
+}
+
+// notional is the cash value of quantity units of symbol at price.
+// Caller must hold the lock.
+func (m *Manager) notional(symbol string, price, quantity int64) int64 {
+	return domain.Notional(price, quantity, m.quantityScale(symbol))
+}
+
+// fillCharge is the exact value of a buy order's fills so far: whole cents
+// plus a remainder in 1/scale of a cent.
+type fillCharge struct {
+	cents     int64
+	remainder int64
+}
+
+// add records a fill and returns what it costs: the running value rounded
+// up to the cent, less what the earlier fills were charged. An order filled
+// in pieces then pays what one fill of the same total would, never more
+// than it was withheld.
+func (c *fillCharge) add(price, quantity, scale int64) int64 {
+	before := c.charged()
+	frac := price*(quantity%scale) + c.remainder
+	c.cents += price*(quantity/scale) + frac/scale
+	c.remainder = frac % scale
+	return c.charged() - before
+}
+
+// charged is the running value rounded up to the cent.
+func (c *fillCharge) charged() int64 {
+	if c.remainder > 0 {
+		return c.cents + 1
+	}
+	return c.cents
+}
+
+// fillCost returns what the buy order orderID pays for exec. Whole-share
+// symbols cost exactly price times quantity; fractional fills are charged
+// on the order's running value, kept in the buyer's wallet until the order
+// is done. Caller must hold m.mu and the buyer's lock.
+func (m *Manager) fillCost(buyer *Wallet, orderID string, exec *domain.Execution) int64 {
+	scale := m.quantityScale(exec.Symbol)
+	if scale <= 1 {
+		return exec.Price * exec.Quantity
+	}
+	if buyer.fillCharges == nil {
+		buyer.fillCharges = make(map[string]*fillCharge)
+	}
+	charge := buyer.fillCharges[orderID]
+	if charge == nil {
+		charge = &fillCharge{}
+		buyer.fillCharges[orderID] = charge
+	}
+	return charge.add(exec.Price, exec.Quantity, scale)
+}
+
+// SetReferencePriceSource sets where price bands get the price each symbol's
+// band is centered on, usually the market data publisher's BandPrice (the
+// last trade, or the reference price before the session's first). It must
+// not call back into the manager. Without one no band is enforced.
+func (m *Manager) SetReferencePriceSource(source func(symbol string) int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.referencePrice = source
+}
+
+// checkPriceBand rejects a price outside symbol's band around its reference
+// price, reporting the band's bounds. Caller must hold the lock.
+func (m *Manager) checkPriceBand(symbol string, price int64) error {
+	band := m.symbols[symbol].PriceBandBps
+	if band == 0 || m.referencePrice == nil {
+		return nil
+	}
+	ref := m.referencePrice(symbol)
+	if ref <= 0 {
+		return nil
+	}
+	// The band is rounded up to a whole cent
+	limit := (ref*band + 9999) / 10000
+	bounds := &PriceBounds{Low: ref - limit, High: ref + limit}
+	if price < bounds.Low || price > bounds.High {
+		return &OrderError{
+			Code: RejectPriceBand,
+			Message: fmt.Sprintf("price %d is outside the %d bps band [%d, %d] around reference price %d for %s",
+				price, band, bounds.Low, bounds.High, ref, symbol),
+			Bounds: bounds,
+		}
+	}
+	return nil
+}
+
+func (mode PriceRoundingMode) valid() bool {
+	switch mode {
+	case RoundingReject, RoundingNearest, RoundingFloor, RoundingCeil:
+		return true
+	}
+	return false
+}
+
+// normalizePrice puts price on the tick grid according to mode.
+// Caller must hold the lock.
+func (m *Manager) normalizePrice(symbol string, price int64, mode PriceRoundingMode) (int64, error) {
+	spec := m.symbols[symbol]
+	if mode == "" {
+		mode = spec.RoundingMode
+	}
+	if mode == "" {
+		mode = RoundingReject
+	}
+	if !mode.valid() {
+		return 0, rejectf(RejectInvalidOptions, "unknown price rounding mode %q", mode)
+	}
+
+	tick := spec.TickSize
+	if tick <= 1 || price%tick == 0 {
+		return price, nil
+	}
+
+	floor := price - price%tick
+	var rounded int64
+	switch mode {
+	case RoundingReject:
+		return 0, rejectf(RejectOffTick, "price %d is not a multiple of tick size %d for %s", price, tick, symbol)
+	case RoundingFloor:
+		rounded = floor
+	case RoundingCeil:
+		rounded = floor + tick
+	case RoundingNearest:
+		rounded = floor
+		if price-floor >= tick-(price-floor) {
+			rounded = floor + tick
+		}
+	}
+
+	if rounded <= 0 {
+		return 0, rejectf(RejectOffTick, "price %d rounds to %d with tick size %d for %s", price, rounded, tick, symbol)
+	}
+	return rounded, nil
+}
+
+// checkLotSize rejects a quantity that is not a whole number of lots.
+// Caller must hold the lock.
+func (m *Manager) checkLotSize(symbol string, quantity int64) error {
+	lot := m.symbols[symbol].LotSize
+	if lot <= 1 || quantity%lot == 0 {
+		return nil
+	}
+	return rejectf(RejectOffLot, "quantity %d is not a multiple of lot size %d for %s", quantity, lot, symbol)
+}
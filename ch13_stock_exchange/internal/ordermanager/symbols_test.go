@@ -0,0 +1,288 @@
+package ordermanager
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTickManager(t *testing.T, mode PriceRoundingMode) *Manager {
+	m := newTestManager()
+	require.NoError(t, m.SetSymbolSpec("AAPL", SymbolSpec{TickSize: 5, RoundingMode: mode}))
+	return m
+}
+
+func TestPriceRounding_Modes(t *testing.T) {
+	tests := []struct {
+		mode  PriceRoundingMode
+		price int64
+		want  int64
+	}{
+		{RoundingNearest, 10001, 10000},
+		{RoundingNearest, 10002, 10000},
+		{RoundingNearest, 10003, 10005},
+		{RoundingFloor, 10004, 10000},
+		{RoundingCeil, 10001, 10005},
+		{RoundingCeil, 10005, 10005}, // already on the grid
+	}
+
+	for _, tt := range tests {
+		m := newTickManager(t, tt.mode)
+		order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, tt.price, 10)
+		require.NoError(t, err, "%s %d", tt.mode, tt.price)
+		assert.Equal(t, tt.want, order.Price, "%s %d", tt.mode, tt.price)
+		assert.Zero(t, order.Price%5, "price must be on the tick grid")
+
+		event := <-m.OrderOut
+		assert.Equal(t, tt.want, event.Order.Price)
+	}
+}
+
+func TestPriceRounding_RejectIsDefault(t *testing.T) {
+	m := newTickManager(t, "")
+
+	_, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10001, 10)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tick size")
+
+	order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10005, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10005), order.Price)
+}
+
+func TestPriceRounding_PerRequestOverride(t *testing.T) {
+	m := newTickManager(t, RoundingReject)
+
+	order, err := m.PlaceOrderWithOptions("user1", "AAPL", domain.SideSell, 10001, 10, OrderOptions{PriceRounding: RoundingCeil})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10005), order.Price)
+
+	_, err = m.PlaceOrderWithOptions("user1", "AAPL", domain.SideSell, 10001, 10, OrderOptions{PriceRounding: "sideways"})
+	assert.Error(t, err)
+}
+
+func TestPriceRounding_FloorToZeroRejected(t *testing.T) {
+	m := newTestManager()
+	require.NoError(t, m.SetSymbolSpec("AAPL", SymbolSpec{TickSize: 100, RoundingMode: RoundingFloor}))
+
+	_, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 50, 10)
+	assert.Error(t, err)
+}
+
+func TestPriceRounding_WithholdsAdjustedPrice(t *testing.T) {
+	m := newTickManager(t, RoundingCeil)
+
+	order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10001, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10005*100), m.wallets["user1"].WithheldCash[order.OrderID])
+}
+
+func TestSetSymbolSpec_Invalid(t *testing.T) {
+	m := newTestManager()
+	assert.Error(t, m.SetSymbolSpec("AAPL", SymbolSpec{TickSize: -1}))
+	assert.Error(t, m.SetSymbolSpec("AAPL", SymbolSpec{TickSize: 5, RoundingMode: "bogus"}))
+
+	// Symbols without a spec accept any price
+	order, err := m.PlaceOrder("user1", "GOOG", domain.SideBuy, 10001, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10001), order.Price)
+}
+
+func newFractionalManager(t *testing.T, maxDailyVolume int64) *Manager {
+	m := NewManager(maxDailyVolume, 100)
+	require.NoError(t, m.SetSymbolSpec("AAPL", SymbolSpec{QuantityScale: 1_000_000}))
+	m.InitWallet("alice", 100_000, nil)
+	m.InitWallet("bob", 0, map[string]int64{"AAPL": 2_000_000}) // 2 shares
+	return m
+}
+
+func TestQuantityScale_FractionalTradeSettlesExactly(t *testing.T) {
+	m := newFractionalManager(t, 1_000_000)
+	_, match := newMatcher(t, m, false)
+	place := func(user string, side domain.Side, price, qty int64) {
+		t.Helper()
+		_, err := m.PlaceOrder(user, "AAPL", side, price, qty)
+		require.NoError(t, err)
+		match()
+	}
+
+	// Bob offers 1.25 shares at $100; Alice buys half a share, then 0.75
+	place("bob", domain.SideSell, 10000, 1_250_000)
+	place("alice", domain.SideBuy, 10000, 500_000)
+
+	alice, bob := m.GetWallet("alice"), m.GetWallet("bob")
+	assert.Equal(t, int64(95_000), alice.CashBalance)
+	assert.Equal(t, int64(500_000), alice.Holdings["AAPL"])
+	assert.Equal(t, int64(5_000), bob.CashBalance)
+	assert.Equal(t, int64(1_500_000), bob.Holdings["AAPL"])
+
+	place("alice", domain.SideBuy, 10000, 750_000)
+	alice, bob = m.GetWallet("alice"), m.GetWallet("bob")
+	assert.Equal(t, int64(87_500), alice.CashBalance)
+	assert.Equal(t, int64(1_250_000), alice.Holdings["AAPL"])
+	assert.Equal(t, int64(12_500), bob.CashBalance)
+	assert.Equal(t, int64(750_000), bob.Holdings["AAPL"])
+	assert.Empty(t, m.wallets["bob"].WithheldShares)
+
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+	assert.Equal(t, int64(2_000_000), report.TotalShares["AAPL"])
+
+	assert.Equal(t, "1.25", m.FormatQuantity("AAPL", alice.Holdings["AAPL"]))
+	assert.Equal(t, "0.75", m.FormatQuantity("AAPL", bob.Holdings["AAPL"]))
+}
+
+func TestQuantityScale_PiecewiseFillsChargeWhatWasWithheld(t *testing.T) {
+	m := newFractionalManager(t, 1_000_000)
+	_, match := newMatcher(t, m, false)
+
+	// One share at 1 cent is withheld 1 cent...
+	buy, err := m.PlaceOrder("alice", "AAPL", domain.SideBuy, 1, 1_000_000)
+	require.NoError(t, err)
+	match()
+	assert.Equal(t, int64(1), m.wallets["alice"].WithheldCash[buy.OrderID])
+
+	// ...and filled in ten tenths, each worth a tenth of a cent
+	for i := 0; i < 10; i++ {
+		_, err := m.PlaceOrder("bob", "AAPL", domain.SideSell, 1, 100_000)
+		require.NoError(t, err)
+		match()
+	}
+
+	assert.Equal(t, domain.OrderStatusFilled, m.GetOrder(buy.OrderID).Status)
+	alice, bob := m.GetWallet("alice"), m.GetWallet("bob")
+	assert.Equal(t, int64(100_000-1), alice.CashBalance)
+	assert.Equal(t, int64(1_000_000), alice.Holdings["AAPL"])
+	assert.Equal(t, int64(1), bob.CashBalance)
+	assert.Empty(t, m.wallets["alice"].WithheldCash)
+	assert.Empty(t, m.wallets["alice"].fillCharges)
+
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+}
+
+func TestQuantityScale_RiskChecksUseScaledCost(t *testing.T) {
+	m := newFractionalManager(t, 2)
+	m.InitWallet("carol", 100, nil) // $1
+	m.InitWallet("dave", 100, nil)
+
+	// 0.01 share at $100 costs exactly $1
+	order, err := m.PlaceOrder("carol", "AAPL", domain.SideBuy, 10000, 10_000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), m.wallets["carol"].WithheldCash[order.OrderID])
+
+	// A fraction of a cent more is rounded up and no longer affordable
+	_, err = m.PlaceOrder("dave", "AAPL", domain.SideBuy, 10000, 10_001)
+	assert.Equal(t, RejectInsufficientFunds, RejectCodeOf(err))
+
+	// The daily volume limit stays in whole shares
+	_, err = m.PlaceOrder("alice", "AAPL", domain.SideBuy, 100, 2_000_000)
+	require.NoError(t, err)
+	_, err = m.PlaceOrder("alice", "AAPL", domain.SideBuy, 100, 1)
+	assert.Equal(t, RejectDailyLimit, RejectCodeOf(err))
+}
+
+func TestQuantityScale_Validation(t *testing.T) {
+	m := newTestManager()
+	assert.Error(t, m.SetSymbolSpec("AAPL", SymbolSpec{QuantityScale: 3}))
+	assert.Error(t, m.SetSymbolSpec("AAPL", SymbolSpec{QuantityScale: -10}))
+	assert.Error(t, m.SetSymbolSpec("AAPL", SymbolSpec{QuantityScale: 10_000_000_000}))
+	require.NoError(t, m.SetSymbolSpec("AAPL", SymbolSpec{QuantityScale: 1000}))
+
+	assert.Equal(t, int64(1000), m.QuantityScale("AAPL"))
+	assert.Equal(t, int64(1), m.QuantityScale("GOOG"))
+	assert.Equal(t, "1.5", m.FormatQuantity("AAPL", 1500))
+	assert.Equal(t, "0.001", m.FormatQuantity("AAPL", 1))
+	assert.Equal(t, "-2.05", m.FormatQuantity("AAPL", -2050))
+	assert.Equal(t, "7", m.FormatQuantity("AAPL", 7000))
+	assert.Equal(t, "1500", m.FormatQuantity("GOOG", 1500))
+}
+
+func TestPriceBand_RejectsPricesFarFromReference(t *testing.T) {
+	m := newTestManager()
+	require.NoError(t, m.SetSymbolSpec("AAPL", SymbolSpec{PriceBandBps: 500}))
+	assert.Error(t, m.SetSymbolSpec("GOOG", SymbolSpec{PriceBandBps: -1}))
+
+	// No reference source: nothing to check against
+	_, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 20000, 1)
+	require.NoError(t, err)
+
+	refs := map[string]int64{}
+	m.SetReferencePriceSource(func(symbol string) int64 { return refs[symbol] })
+
+	// No reference price yet
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 20000, 1)
+	require.NoError(t, err)
+
+	refs["AAPL"] = 10000
+	for _, price := range []int64{9500, 10000, 10500} {
+		_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, price, 1)
+		assert.NoError(t, err, price)
+	}
+	for _, price := range []int64{9499, 10501} {
+		_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, price, 1)
+		assert.Equal(t, RejectPriceBand, RejectCodeOf(err), price)
+	}
+
+	// Symbols without a band are not checked
+	refs["GOOG"] = 10000
+	_, err = m.PlaceOrder("user1", "GOOG", domain.SideBuy, 50000, 1)
+	assert.NoError(t, err)
+}
+
+func TestLotSize_RejectsOddQuantities(t *testing.T) {
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	require.NoError(t, m.SetSymbolSpec("AAPL", SymbolSpec{LotSize: 100}))
+	assert.Error(t, m.SetSymbolSpec("AAPL", SymbolSpec{LotSize: -1}))
+
+	_, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 150)
+	assert.Equal(t, RejectOffLot, RejectCodeOf(err))
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+
+	order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 200)
+	require.NoError(t, err)
+	match()
+
+	_, err = m.ModifyOrder(order.OrderID, 10000, 250)
+	assert.Equal(t, RejectOffLot, RejectCodeOf(err))
+	_, err = m.ModifyOrder(order.OrderID, 10000, 100)
+	require.NoError(t, err)
+
+	r, err := m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 300, OrderOptions{})
+	require.NoError(t, err)
+	_, err = m.CommitReservation(r.Token, 120)
+	assert.Equal(t, RejectOffLot, RejectCodeOf(err))
+	_, err = m.CommitReservation(r.Token, 200)
+	require.NoError(t, err)
+}
+
+func TestPriceBand_FollowsLastTradeAndReportsBounds(t *testing.T) {
+	m := newTestManager()
+	require.NoError(t, m.SetSymbolSpec("AAPL", SymbolSpec{PriceBandBps: 1000}))
+	var band int64 // what the publisher's BandPrice would return
+	m.SetReferencePriceSource(func(string) int64 { return band })
+
+	// First trade of a new listing: no last trade and no reference yet
+	_, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 25000, 1)
+	require.NoError(t, err)
+
+	// It traded at 150.00: the band is 135.00 to 165.00
+	band = 15000
+	for _, price := range []int64{13500, 16500} {
+		_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, price, 1)
+		assert.NoError(t, err, price)
+	}
+	for _, price := range []int64{13499, 16501} {
+		_, err = m.PlaceOrder("user2", "AAPL", domain.SideSell, price, 1)
+		require.Equal(t, RejectPriceBand, RejectCodeOf(err), price)
+		var oe *OrderError
+		require.ErrorAs(t, err, &oe)
+		assert.Equal(t, &PriceBounds{Low: 13500, High: 16500}, oe.Bounds)
+		assert.ErrorContains(t, err, "[13500, 16500]")
+	}
+}
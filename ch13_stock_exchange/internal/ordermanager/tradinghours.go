@@ -0,0 +1,98 @@
+package ordermanager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Trading hours.
+//
+// A symbol with trading hours only takes new orders while its session is
+// open: placing, reserving or committing an order and modifying a resting
+// one are rejected with RejectMarketClosed outside them. Cancels are taken
+// at any time, and resting orders stay in the book overnight. Hours set for
+// the empty symbol apply to every symbol without hours of its own; without
+// any, orders are taken around the clock. The session is checked against
+// the manager's clock (see SetClock).
+
+// TradingHours is a daily session from Open until Close, both times of day
+// in Location. A Close earlier than Open runs through midnight.
+type TradingHours struct {
+	Open     time.Duration // since midnight
+	Close    time.Duration // since midnight
+	Location *time.Location
+}
+
+// ParseTradingHours parses a session such as "09:30-16:00" in loc; a nil
+// loc is the server's timezone.
+func ParseTradingHours(spec string, loc *time.Location) (TradingHours, error) {
+	openStr, closeStr, ok := strings.Cut(spec, "-")
+	if !ok {
+		return TradingHours{}, fmt.Errorf("trading hours must look like 09:30-16:00, got %q", spec)
+	}
+	open, err := parseTimeOfDay(openStr)
+	if err != nil {
+		return TradingHours{}, err
+	}
+	closeAt, err := parseTimeOfDay(closeStr)
+	if err != nil {
+		return TradingHours{}, err
+	}
+	return TradingHours{Open: open, Close: closeAt, Location: loc}, nil
+}
+
+// parseTimeOfDay parses "15:04" into the time since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsOpen reports whether the session is open at t.
+func (h TradingHours) IsOpen(t time.Time) bool {
+	loc := h.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	local := t.In(loc)
+	now := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+	if h.Open < h.Close {
+		return now >= h.Open && now < h.Close
+	}
+	return now >= h.Open || now < h.Close
+}
+
+// SetTradingHours sets the trading hours of symbol, or of every symbol
+// without its own when symbol is empty.
+func (m *Manager) SetTradingHours(symbol string, hours TradingHours) error {
+	day := 24 * time.Hour
+	if hours.Open < 0 || hours.Open >= day || hours.Close < 0 || hours.Close >= day {
+		return fmt.Errorf("trading hours must be times of day, got %v-%v", hours.Open, hours.Close)
+	}
+	if hours.Open == hours.Close {
+		return fmt.Errorf("trading hours open and close at the same time %v", hours.Open)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tradingHours[symbol] = hours
+	return nil
+}
+
+// checkTradingHours rejects a new order on symbol outside its trading
+// hours. Caller must hold m.mu.
+func (m *Manager) checkTradingHours(symbol string) error {
+	hours, ok := m.tradingHours[symbol]
+	if !ok {
+		if hours, ok = m.tradingHours[""]; !ok {
+			return nil
+		}
+	}
+	if !hours.IsOpen(m.now()) {
+		return rejectf(RejectMarketClosed, "market closed for %s", symbol)
+	}
+	return nil
+}
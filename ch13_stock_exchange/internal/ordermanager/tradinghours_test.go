@@ -0,0 +1,78 @@
+package ordermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTradingHours_GatesNewOrders(t *testing.T) {
+	est := time.FixedZone("EST", -5*60*60)
+	m := newTestManager()
+	_, match := newMatcher(t, m, false)
+	clock := time.Date(2025, 1, 15, 9, 29, 59, 0, est)
+	m.SetClock(func() time.Time { return clock })
+	hours, err := ParseTradingHours("09:30-16:00", est)
+	require.NoError(t, err)
+	require.NoError(t, m.SetTradingHours("", hours))
+	require.NoError(t, m.SetReservationTTL(24*time.Hour))
+
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 100)
+	assert.Equal(t, RejectMarketClosed, RejectCodeOf(err))
+	assert.Empty(t, m.wallets["user1"].WithheldCash)
+	_, err = m.ReserveOrder("user1", "AAPL", domain.SideBuy, 10000, 100, OrderOptions{})
+	assert.Equal(t, RejectMarketClosed, RejectCodeOf(err))
+
+	clock = time.Date(2025, 1, 15, 9, 30, 0, 0, est)
+	order, err := m.PlaceOrder("user1", "AAPL", domain.SideBuy, 10000, 100)
+	require.NoError(t, err)
+	match()
+	r, err := m.ReserveOrder("user1", "AAPL", domain.SideBuy, 9000, 10, OrderOptions{})
+	require.NoError(t, err)
+
+	clock = time.Date(2025, 1, 15, 16, 0, 0, 0, est)
+	_, err = m.ModifyOrder(order.OrderID, 10000, 200)
+	assert.Equal(t, RejectMarketClosed, RejectCodeOf(err))
+	_, err = m.CommitReservation(r.Token, 0)
+	assert.Equal(t, RejectMarketClosed, RejectCodeOf(err))
+
+	// Cancels are still taken after the close
+	_, err = m.CancelOrder(order.OrderID)
+	require.NoError(t, err)
+	match()
+	assert.Equal(t, domain.OrderStatusCanceled, m.GetOrder(order.OrderID).Status)
+}
+
+func TestTradingHours_PerSymbolAndOvernight(t *testing.T) {
+	m := newTestManager()
+	clock := time.Date(2025, 1, 15, 23, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return clock })
+	day, err := ParseTradingHours("09:30-16:00", time.UTC)
+	require.NoError(t, err)
+	overnight, err := ParseTradingHours("20:00-04:00", time.UTC)
+	require.NoError(t, err)
+	require.NoError(t, m.SetTradingHours("", day))
+	require.NoError(t, m.SetTradingHours("BTC", overnight))
+
+	_, err = m.PlaceOrder("user1", "AAPL", domain.SideBuy, 100, 1)
+	assert.Equal(t, RejectMarketClosed, RejectCodeOf(err))
+	_, err = m.PlaceOrder("user1", "BTC", domain.SideBuy, 100, 1)
+	assert.NoError(t, err)
+
+	clock = time.Date(2025, 1, 16, 4, 0, 0, 0, time.UTC)
+	_, err = m.PlaceOrder("user1", "BTC", domain.SideBuy, 100, 1)
+	assert.Equal(t, RejectMarketClosed, RejectCodeOf(err))
+}
+
+func TestTradingHours_Invalid(t *testing.T) {
+	for _, spec := range []string{"09:30", "9h-16h", "09:30-24:00"} {
+		_, err := ParseTradingHours(spec, nil)
+		assert.Error(t, err, spec)
+	}
+	m := newTestManager()
+	assert.Error(t, m.SetTradingHours("", TradingHours{Open: time.Hour, Close: time.Hour}))
+	assert.Error(t, m.SetTradingHours("", TradingHours{Open: 25 * time.Hour, Close: time.Hour}))
+}
@@ -33,9 +33,9 @@ type orderEntry struct {
 //
 //	操作            RBBook          HashMap+List
 //	-----------     ----------      ------------
-//	addOrder        O(log n)        O(1) 插入 + O(n) refreshBestPrice
-//	removeOrder     O(log n)        O(1) 移除 + O(n) refreshBestPrice
-//	BestPrice       O(log n)        O(1) 快取（O(n) 維護後讀取）
+//	addOrder        O(log n)        O(1) 插入 + O(log n) 價格 heap（新價位時）
+//	removeOrder     O(log n)        O(1) 移除 + O(log n) 價格 heap（價位清空時）
+//	BestPrice       O(log n)        O(1) heap 頂端
 //	GetL2Snapshot   O(n) 中序走訪   O(n log n) 排序
 type RBBook struct {
 	Side domain.Side
@@ -0,0 +1,76 @@
+package sequencer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// ErrAuctionInProgress is returned when a symbol already has an auction running.
+var ErrAuctionInProgress = errors.New("auction already in progress")
+
+// RunAuction starts a call auction for symbol: a start event is sequenced
+// now, and an end event after window, which uncrosses the book at the
+// auction clearing price. Orders sequenced in between collect without
+// matching. Both events travel through OrderIn, so they are ordered with
+// respect to every order like any other input.
+func (s *Sequencer) RunAuction(symbol string, window time.Duration) error {
+	if symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+	if window <= 0 {
+		return fmt.Errorf("auction window must be positive, got %s", window)
+	}
+
+	s.auctionMu.Lock()
+	if s.auctions[symbol] {
+		s.auctionMu.Unlock()
+		return fmt.Errorf("%w for %s", ErrAuctionInProgress, symbol)
+	}
+	s.auctions[symbol] = true
+	s.auctionMu.Unlock()
+
+	if !s.submitControl(domain.OrderActionAuctionStart, symbol) {
+		s.finishAuction(symbol)
+		return fmt.Errorf("sequencer stopped")
+	}
+
+	time.AfterFunc(window, func() {
+		s.submitControl(domain.OrderActionAuctionEnd, symbol)
+		s.finishAuction(symbol)
+	})
+	return nil
+}
+
+// ResetSession starts a new trading session for symbol. The reset is
+// sequenced like an order, so downstream session statistics split exactly
+// between the executions before and after it.
+func (s *Sequencer) ResetSession(symbol string) error {
+	if symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+	if !s.submitControl(domain.OrderActionSessionReset, symbol) {
+		return fmt.Errorf("sequencer stopped")
+	}
+	return nil
+}
+
+// submitControl queues a control event. Returns false if the
+// sequencer stopped first.
+func (s *Sequencer) submitControl(action domain.OrderAction, symbol string) bool {
+	event := &domain.OrderEvent{Action: action, Order: &domain.Order{Symbol: symbol}}
+	select {
+	case s.OrderIn <- event:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *Sequencer) finishAuction(symbol string) {
+	s.auctionMu.Lock()
+	defer s.auctionMu.Unlock()
+	delete(s.auctions, symbol)
+}
@@ -0,0 +1,33 @@
+package sequencer
+
+import (
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Event time.
+//
+// The sequencer stamps every event it sequences with a timestamp as well as
+// a sequence ID, and the matching engine stamps the event's executions with
+// that timestamp instead of reading the wall clock. Timestamps never go
+// backwards: an event stamped while the clock reads earlier than the last
+// one gets the last one's time. The journal records each event's timestamp,
+// so Recover reproduces the same executions, timestamps included.
+
+// SetClock sets the clock events are stamped from; time.Now by default.
+// Must be called before Start.
+func (s *Sequencer) SetClock(clock func() time.Time) {
+	s.clock = clock
+}
+
+// stampTime gives event the sequencer's next event time. Called only from
+// the run loop.
+func (s *Sequencer) stampTime(event *domain.OrderEvent) {
+	now := s.clock()
+	if now.Before(s.lastTime) {
+		now = s.lastTime
+	}
+	s.lastTime = now
+	event.Timestamp = now
+}
@@ -0,0 +1,102 @@
+package sequencer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns the given times in turn, repeating the last one.
+func fakeClock(times ...time.Time) func() time.Time {
+	i := 0
+	return func() time.Time {
+		now := times[min(i, len(times)-1)]
+		i++
+		return now
+	}
+}
+
+// drainExecutions collects the executions of every event sent downstream so far.
+func drainExecutions(seq *Sequencer) []*domain.Execution {
+	var executions []*domain.Execution
+	for {
+		select {
+		case result := <-seq.ExecutionOut:
+			executions = append(executions, result.Executions...)
+		default:
+			return executions
+		}
+	}
+}
+
+func TestSequencer_StampsMonotonicEventTime(t *testing.T) {
+	start := time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)
+	seq := NewSequencer(matching.NewEngine(), 100)
+	// The clock steps back before the second event
+	seq.SetClock(fakeClock(start, start.Add(-time.Second), start.Add(time.Second)))
+
+	events := []*domain.OrderEvent{
+		{Action: domain.OrderActionNew, Order: journalOrder("s1", domain.SideSell, 10010, 100)},
+		{Action: domain.OrderActionNew, Order: journalOrder("b1", domain.SideBuy, 10010, 40)},
+		{Action: domain.OrderActionNew, Order: journalOrder("b2", domain.SideBuy, 10010, 10)},
+	}
+	for _, event := range events {
+		seq.processEvent(event)
+	}
+
+	assert.Equal(t, start, events[0].Timestamp)
+	assert.Equal(t, start, events[1].Timestamp)
+	assert.Equal(t, start.Add(time.Second), events[2].Timestamp)
+
+	// Executions carry their event's time, not the wall clock
+	executions := drainExecutions(seq)
+	require.Len(t, executions, 2)
+	assert.Equal(t, start, executions[0].Timestamp)
+	assert.Equal(t, start.Add(time.Second), executions[1].Timestamp)
+}
+
+func TestSequencer_ReplayReproducesExecutions(t *testing.T) {
+	start := time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)
+	run := func() []*domain.Execution {
+		seq := NewSequencer(matching.NewEngine(), 100)
+		seq.SetClock(fakeClock(start, start.Add(time.Millisecond), start.Add(2*time.Millisecond)))
+		seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: journalOrder("s1", domain.SideSell, 10010, 100)})
+		seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: journalOrder("s2", domain.SideSell, 10020, 100)})
+		seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: journalOrder("b1", domain.SideBuy, 10020, 150)})
+		return drainExecutions(seq)
+	}
+
+	first := run()
+	require.Len(t, first, 2)
+	assert.Equal(t, first, run())
+}
+
+func TestJournal_RecoverKeepsEventTime(t *testing.T) {
+	start := time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	seq := NewSequencer(matching.NewEngine(), 100)
+	seq.SetClock(fakeClock(start, start.Add(time.Second)))
+	require.NoError(t, seq.EnableJournal(path))
+	seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: journalOrder("s1", domain.SideSell, 10010, 100)})
+	seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: journalOrder("b1", domain.SideBuy, 10010, 40)})
+	seq.closeJournal()
+
+	// Recovering on a clock behind the journal does not move time backwards
+	recovered := NewSequencer(matching.NewEngine(), 100)
+	recovered.SetClock(fakeClock(start.Add(-time.Hour)))
+	_, err := recovered.Recover(path, nil)
+	require.NoError(t, err)
+
+	next := &domain.OrderEvent{Action: domain.OrderActionNew, Order: journalOrder("b2", domain.SideBuy, 10010, 10)}
+	recovered.processEvent(next)
+	assert.Equal(t, start.Add(time.Second), next.Timestamp)
+
+	executions := drainExecutions(recovered)
+	require.Len(t, executions, 1)
+	assert.Equal(t, start.Add(time.Second), executions[0].Timestamp)
+}
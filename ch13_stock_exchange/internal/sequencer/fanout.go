@@ -0,0 +1,111 @@
+package sequencer
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// Delivery is the guarantee a fan-out consumer gets.
+type Delivery string
+
+const (
+	// DeliveryReliable blocks until the consumer accepts each event, pushing
+	// backpressure up to the sequencer. Used for settlement: a dropped
+	// execution would leave wallets wrong.
+	DeliveryReliable Delivery = "reliable"
+	// DeliveryBestEffort drops events the consumer has no room for. Fine for
+	// derived views such as market data.
+	DeliveryBestEffort Delivery = "best_effort"
+)
+
+// ParseDelivery parses a delivery mode name.
+func ParseDelivery(s string) (Delivery, error) {
+	switch d := Delivery(s); d {
+	case DeliveryReliable, DeliveryBestEffort:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown delivery mode %q (want %q or %q)", s, DeliveryReliable, DeliveryBestEffort)
+}
+
+// Consumer is one downstream receiver of execution events.
+type Consumer struct {
+	Name     string
+	In       chan<- *domain.ExecutionEvent
+	Delivery Delivery
+}
+
+// FanOut copies every execution event to each consumer, in order, with that
+// consumer's delivery guarantee. A reliable consumer that falls behind stalls
+// the whole fan-out, so best-effort consumers see events no sooner than it does.
+type FanOut struct {
+	consumers []Consumer
+	dropped   []atomic.Uint64
+}
+
+// NewFanOut creates a fan-out over the given consumers.
+func NewFanOut(consumers ...Consumer) *FanOut {
+	return &FanOut{
+		consumers: consumers,
+		dropped:   make([]atomic.Uint64, len(consumers)),
+	}
+}
+
+// Run forwards events from in until it is closed or done is closed.
+func (f *FanOut) Run(in <-chan *domain.ExecutionEvent, done <-chan struct{}) {
+	for {
+		select {
+		case event, ok := <-in:
+			if !ok {
+				return
+			}
+			for i := range f.consumers {
+				if !f.deliver(i, event, done) {
+					return
+				}
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// deliver sends one event to consumer i. Returns false if done closed while
+// waiting on a reliable consumer.
+func (f *FanOut) deliver(i int, event *domain.ExecutionEvent, done <-chan struct{}) bool {
+	c := f.consumers[i]
+	select {
+	case c.In <- event:
+		return true
+	default:
+	}
+
+	// Session boundaries are never dropped: losing one would merge two
+	// sessions. Nor is the shutdown barrier, which the consumer must ack.
+	if c.Delivery != DeliveryReliable && event.SessionReset == "" && event.Drain == nil {
+		f.dropped[i].Add(1)
+		middleware.ExecutionEventsDropped.WithLabelValues(c.Name).Inc()
+		log.Printf("[fanout] WARN: %s execution channel full, dropping event", c.Name)
+		return true
+	}
+
+	select {
+	case c.In <- event:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// Dropped returns how many events were dropped for the named consumer.
+func (f *FanOut) Dropped(name string) uint64 {
+	for i, c := range f.consumers {
+		if c.Name == name {
+			return f.dropped[i].Load()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,183 @@
+package sequencer
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/marketdata"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/nathanyu/stock-exchange/internal/ordermanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOut_SettlementNeverDroppedUnderSaturation(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	const trades = 500
+	const price = 10000
+
+	m := ordermanager.NewManager(1_000_000, trades*2)
+	m.InitWallet("buyer", trades*price, nil)
+	m.InitWallet("seller", 0, map[string]int64{"AAPL": trades})
+	// A one-slot settlement channel keeps the reliable consumer saturated
+	m.ExecutionIn = make(chan *domain.ExecutionEvent, 1)
+	m.Start()
+	defer m.Stop()
+
+	// The publisher is never started, so its channel fills after one event
+	pub := marketdata.NewPublisher(1)
+
+	fanOut := NewFanOut(
+		Consumer{Name: "ordermanager", In: m.ExecutionIn, Delivery: DeliveryReliable},
+		Consumer{Name: "marketdata", In: pub.ExecutionIn, Delivery: DeliveryBestEffort},
+	)
+	in := make(chan *domain.ExecutionEvent)
+	done := make(chan struct{})
+	defer close(done)
+	go fanOut.Run(in, done)
+
+	// The engine matches copies of the orders and only trades are forwarded, so
+	// no order the engine still holds is read by settlement concurrently
+	engine := matching.NewEngine()
+	for range trades {
+		for _, side := range []domain.Side{domain.SideBuy, domain.SideSell} {
+			user := "buyer"
+			if side == domain.SideSell {
+				user = "seller"
+			}
+			_, err := m.PlaceOrder(user, "AAPL", side, price, 1)
+			require.NoError(t, err)
+
+			event := <-m.OrderOut
+			order := *event.Order
+			event.Order = &order
+			if result := engine.HandleOrder(event); len(result.Executions) > 0 {
+				in <- result
+			}
+		}
+	}
+
+	// Every settlement lands, however far behind the manager fell
+	require.Eventually(t, func() bool {
+		return m.GetWallet("buyer").Holdings["AAPL"] == trades
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(0), m.GetWallet("buyer").CashBalance)
+	assert.Equal(t, int64(trades*price), m.GetWallet("seller").CashBalance)
+	assert.Equal(t, int64(0), m.GetWallet("seller").Holdings["AAPL"])
+
+	report, err := m.VerifyConservation()
+	require.NoError(t, err)
+	assert.True(t, report.Balanced)
+
+	// Market data, meanwhile, was shed
+	assert.Zero(t, fanOut.Dropped("ordermanager"))
+	assert.Equal(t, uint64(trades-1), fanOut.Dropped("marketdata"))
+}
+
+func TestFanOut_ReliablePipelineLosesNothingUnderFlood(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	const trades = 2000
+
+	// One-slot channels everywhere, so every stage is saturated and the
+	// sequencer is held back by the slowest consumer
+	seq := NewSequencer(matching.NewEngine(), 1)
+	pub := marketdata.NewPublisher(1)
+	pub.Start()
+	defer pub.Stop()
+	settled := make(chan *domain.ExecutionEvent, 1)
+
+	fanOut := NewFanOut(
+		Consumer{Name: "ordermanager", In: settled, Delivery: DeliveryReliable},
+		Consumer{Name: "marketdata", In: pub.ExecutionIn, Delivery: DeliveryReliable},
+	)
+	done := make(chan struct{})
+	defer close(done)
+	go fanOut.Run(seq.ExecutionOut, done)
+	seq.Start()
+	defer seq.Stop()
+
+	// A settlement consumer that keeps falling behind
+	received := make(chan int)
+	go func() {
+		count := 0
+		for count < trades {
+			event := <-settled
+			count += len(event.Executions)
+			if count%100 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		received <- count
+	}()
+
+	// Buyers and sellers flood the sequencer at once; every order crosses
+	flood := func(side domain.Side, prefix string) {
+		for i := range trades {
+			order := journalOrder(fmt.Sprintf("%s%d", prefix, i), side, 10000, 1)
+			seq.OrderIn <- &domain.OrderEvent{Action: domain.OrderActionNew, Order: order}
+		}
+	}
+	go flood(domain.SideBuy, "b")
+	go flood(domain.SideSell, "s")
+
+	select {
+	case count := <-received:
+		assert.Equal(t, trades, count)
+	case <-time.After(10 * time.Second):
+		t.Fatal("settlement did not receive every execution")
+	}
+	require.Eventually(t, func() bool {
+		return pub.SequenceStatus().LastSeq == trades
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, uint64(trades), seq.CurrentOutboundSeq())
+	assert.Equal(t, marketdata.SequenceStatus{LastSeq: trades, LastContiguousSeq: trades}, pub.SequenceStatus())
+	assert.Zero(t, fanOut.Dropped("ordermanager"))
+	assert.Zero(t, fanOut.Dropped("marketdata"))
+}
+
+func TestFanOut_SessionResetNeverDropped(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	in := make(chan *domain.ExecutionEvent)
+	full := make(chan *domain.ExecutionEvent, 1)
+	full <- &domain.ExecutionEvent{}
+	fanOut := NewFanOut(Consumer{Name: "marketdata", In: full, Delivery: DeliveryBestEffort})
+	done := make(chan struct{})
+	defer close(done)
+	go fanOut.Run(in, done)
+
+	// An ordinary event is dropped while the consumer is full
+	in <- &domain.ExecutionEvent{Executions: []*domain.Execution{{Symbol: "AAPL"}}}
+	require.Eventually(t, func() bool { return fanOut.Dropped("marketdata") == 1 }, time.Second, time.Millisecond)
+
+	// A session reset waits for room instead
+	in <- &domain.ExecutionEvent{SessionReset: "AAPL"}
+	<-full
+	select {
+	case ev := <-full:
+		assert.Equal(t, "AAPL", ev.SessionReset)
+	case <-time.After(time.Second):
+		t.Fatal("session reset was dropped")
+	}
+	assert.Equal(t, uint64(1), fanOut.Dropped("marketdata"))
+}
+
+func TestParseDelivery(t *testing.T) {
+	d, err := ParseDelivery("reliable")
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryReliable, d)
+
+	_, err = ParseDelivery("sometimes")
+	assert.Error(t, err)
+}
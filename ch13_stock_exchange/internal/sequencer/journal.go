@@ -0,0 +1,138 @@
+package sequencer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+)
+
+// Order event journal.
+//
+// The order books live only in the matching engine's memory. With a journal
+// enabled, every event the sequencer stamps is appended to it before the
+// engine sees it, so the books can be rebuilt after a restart by feeding the
+// same events, in the same order, to a fresh engine: matching is
+// deterministic given its input, and each event's timestamp is journaled
+// with it so the rebuilt executions carry the original timestamps. Recover
+// does that before Start, and the inbound and outbound sequences continue
+// from where the journal ends. Each replayed event is also handed, with the
+// engine's result, to a callback, which the order manager uses to rebuild
+// its orders, withholding and balances (see ordermanager.RecoverEvent).
+
+// journalMaxLine bounds one JSON-encoded order event in the journal
+const journalMaxLine = 1 << 20
+
+// journalEntry is one sequenced order event as written to the journal.
+type journalEntry struct {
+	Seq    uint64             `json:"seq"`
+	Action domain.OrderAction `json:"action"`
+	Order  *domain.Order      `json:"order"`
+	// Time is the event's timestamp; entries written before events were
+	// timestamped have none and replay at the current time.
+	Time time.Time `json:"time,omitempty"`
+}
+
+// EnableJournal appends every event the sequencer stamps to path, one JSON
+// object per line. Must be called before Start; the journal is closed when
+// the sequencer stops.
+func (s *Sequencer) EnableJournal(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open journal: %w", err)
+	}
+	s.closeJournal()
+	s.journal = f
+	s.journalBuf = bufio.NewWriter(f)
+	return nil
+}
+
+// appendJournal writes one stamped event to the journal and flushes it.
+// Write failures are logged; the event is still matched.
+func (s *Sequencer) appendJournal(seq uint64, event *domain.OrderEvent) {
+	if s.journal == nil {
+		return
+	}
+	entry := journalEntry{Seq: seq, Action: event.Action, Order: event.Order, Time: event.Timestamp}
+	if err := json.NewEncoder(s.journalBuf).Encode(entry); err != nil {
+		log.Printf("[sequencer] ERROR: journal write failed at seq %d: %v", seq, err)
+		return
+	}
+	if err := s.journalBuf.Flush(); err != nil {
+		log.Printf("[sequencer] ERROR: journal flush failed at seq %d: %v", seq, err)
+	}
+}
+
+// closeJournal flushes and closes the journal.
+func (s *Sequencer) closeJournal() {
+	if s.journal == nil {
+		return
+	}
+	if err := s.journalBuf.Flush(); err != nil {
+		log.Printf("[sequencer] ERROR: journal flush failed: %v", err)
+	}
+	s.journal.Close()
+	s.journal = nil
+	s.journalBuf = nil
+}
+
+// Recover replays the journal at path into the sequencer's engine, which
+// must be fresh, and continues the inbound and outbound sequences from the
+// last replayed event. The executions replayed events produce are not sent
+// downstream, they were delivered before the restart; replayed, unless nil,
+// is called with each event and its result instead. Must be called before
+// Start; returns how many events were replayed.
+func (s *Sequencer) Recover(path string, replayed func(event *domain.OrderEvent, result *domain.ExecutionEvent)) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), journalMaxLine)
+	line, count := 0, 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return count, fmt.Errorf("journal line %d: %w", line, err)
+		}
+		if entry.Order == nil {
+			return count, fmt.Errorf("journal line %d: event has no order", line)
+		}
+		if last := s.inboundSeq.Load(); entry.Seq <= last {
+			return count, fmt.Errorf("journal line %d: seq %d does not follow %d", line, entry.Seq, last)
+		}
+
+		s.inboundSeq.Store(entry.Seq)
+		entry.Order.SequenceID = entry.Seq
+		if entry.Time.After(s.lastTime) {
+			s.lastTime = entry.Time
+		}
+		event := &domain.OrderEvent{Action: entry.Action, Order: entry.Order, Timestamp: entry.Time}
+		result := s.engine.HandleOrder(event)
+		if result != nil {
+			for _, exec := range result.Executions {
+				exec.SequenceID = s.outboundSeq.Add(1)
+			}
+		}
+		if replayed != nil {
+			replayed(event, result)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("read journal: %w", err)
+	}
+
+	log.Printf("[sequencer] recovered %d events from journal, continuing at seq %d", count, s.inboundSeq.Load())
+	return count, nil
+}
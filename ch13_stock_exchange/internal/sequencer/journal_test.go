@@ -0,0 +1,79 @@
+package sequencer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func journalOrder(id string, side domain.Side, price, qty int64) *domain.Order {
+	return &domain.Order{
+		OrderID:           id,
+		Symbol:            "AAPL",
+		Side:              side,
+		Price:             price,
+		Quantity:          qty,
+		RemainingQuantity: qty,
+		Status:            domain.OrderStatusNew,
+		UserID:            "user1",
+	}
+}
+
+func TestJournal_RecoverRebuildsBook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	engine := matching.NewEngine()
+	seq := NewSequencer(engine, 100)
+	require.NoError(t, seq.EnableJournal(path))
+
+	events := []*domain.OrderEvent{
+		{Action: domain.OrderActionNew, Order: journalOrder("s1", domain.SideSell, 10010, 100)},
+		{Action: domain.OrderActionNew, Order: journalOrder("s2", domain.SideSell, 10020, 100)},
+		{Action: domain.OrderActionNew, Order: journalOrder("s3", domain.SideSell, 10020, 50)},
+		{Action: domain.OrderActionNew, Order: journalOrder("b1", domain.SideBuy, 9990, 70)},
+		{Action: domain.OrderActionNew, Order: journalOrder("b2", domain.SideBuy, 10010, 40)},
+		{Action: domain.OrderActionCancel, Order: journalOrder("s3", domain.SideSell, 10020, 50)},
+		{Action: domain.OrderActionModify, Order: journalOrder("b1", domain.SideBuy, 9995, 60)},
+	}
+	for _, event := range events {
+		seq.processEvent(event)
+	}
+	seq.closeJournal()
+
+	restarted := matching.NewEngine()
+	recovered := NewSequencer(restarted, 100)
+	n, err := recovered.Recover(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, len(events), n)
+
+	assert.Equal(t, engine.GetL2Snapshot("AAPL", 10), restarted.GetL2Snapshot("AAPL", 10))
+	assert.Equal(t, seq.CurrentInboundSeq(), recovered.CurrentInboundSeq())
+	assert.Equal(t, seq.CurrentOutboundSeq(), recovered.CurrentOutboundSeq())
+
+	// New events continue the sequence and extend the same journal
+	require.NoError(t, recovered.EnableJournal(path))
+	next := journalOrder("b3", domain.SideBuy, 10020, 10)
+	recovered.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: next})
+	recovered.closeJournal()
+	assert.Equal(t, uint64(len(events)+1), next.SequenceID)
+
+	again := matching.NewEngine()
+	_, err = NewSequencer(again, 100).Recover(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, restarted.GetL2Snapshot("AAPL", 10), again.GetL2Snapshot("AAPL", 10))
+}
+
+func TestJournal_RecoverRejectsOutOfOrderSeq(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.journal")
+	journal := `{"seq":2,"action":"new","order":{"order_id":"s1","symbol":"AAPL","side":"sell","price":10010,"quantity":1,"remaining_quantity":1,"status":"new"}}
+{"seq":2,"action":"new","order":{"order_id":"s2","symbol":"AAPL","side":"sell","price":10010,"quantity":1,"remaining_quantity":1,"status":"new"}}
+`
+	require.NoError(t, os.WriteFile(path, []byte(journal), 0o644))
+
+	_, err := NewSequencer(matching.NewEngine(), 100).Recover(path, nil)
+	assert.ErrorContains(t, err, "line 2")
+}
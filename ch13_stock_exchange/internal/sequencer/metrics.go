@@ -0,0 +1,30 @@
+package sequencer
+
+import (
+	"time"
+
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+)
+
+// seqMetricsInterval is how often the sequence number gauges are refreshed.
+const seqMetricsInterval = time.Second
+
+// reportSeqMetrics periodically sets the sequence number gauges until Stop.
+func (s *Sequencer) reportSeqMetrics() {
+	ticker := time.NewTicker(seqMetricsInterval)
+	defer ticker.Stop()
+	for {
+		s.observeSeqs()
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// observeSeqs sets the sequence number gauges to the current sequences.
+func (s *Sequencer) observeSeqs() {
+	middleware.SequencerInboundSeq.Set(float64(s.CurrentInboundSeq()))
+	middleware.SequencerOutboundSeq.Set(float64(s.CurrentOutboundSeq()))
+}
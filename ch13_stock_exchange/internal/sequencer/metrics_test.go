@@ -0,0 +1,37 @@
+package sequencer
+
+import (
+	"testing"
+
+	"github.com/nathanyu/stock-exchange/internal/domain"
+	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func metricsOrder(id string, side domain.Side, price, qty int64) *domain.Order {
+	order := journalOrder(id, side, price, qty)
+	order.Symbol = "METR"
+	return order
+}
+
+func TestSequencer_MetricsFollowOrderFlow(t *testing.T) {
+	seq := NewSequencer(matching.NewEngine(), 100)
+	matchesBefore := testutil.ToFloat64(middleware.MatchesTotal.WithLabelValues("METR"))
+
+	seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: metricsOrder("s1", domain.SideSell, 10010, 100)})
+	seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: metricsOrder("s2", domain.SideSell, 10020, 100)})
+	seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: metricsOrder("b1", domain.SideBuy, 9990, 100)})
+	assert.Equal(t, 1.0, testutil.ToFloat64(middleware.OrderBookDepth.WithLabelValues("METR", "buy")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(middleware.OrderBookDepth.WithLabelValues("METR", "sell")))
+
+	// Sweeps both ask levels
+	seq.processEvent(&domain.OrderEvent{Action: domain.OrderActionNew, Order: metricsOrder("b2", domain.SideBuy, 10020, 200)})
+	assert.Equal(t, matchesBefore+2, testutil.ToFloat64(middleware.MatchesTotal.WithLabelValues("METR")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(middleware.OrderBookDepth.WithLabelValues("METR", "sell")))
+
+	seq.observeSeqs()
+	assert.Equal(t, 4.0, testutil.ToFloat64(middleware.SequencerInboundSeq))
+	assert.Equal(t, 2.0, testutil.ToFloat64(middleware.SequencerOutboundSeq))
+}
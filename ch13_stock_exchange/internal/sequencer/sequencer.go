@@ -1,11 +1,16 @@
 package sequencer
 
 import (
+	"bufio"
 	"log"
+	"os"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/nathanyu/stock-exchange/internal/domain"
 	"github.com/nathanyu/stock-exchange/internal/matching"
+	"github.com/nathanyu/stock-exchange/internal/middleware"
 )
 
 // Sequencer stamps monotonically increasing sequence IDs on incoming orders,
@@ -22,6 +27,18 @@ type Sequencer struct {
 	OrderIn     chan *domain.OrderEvent     // inbound orders from order manager
 	ExecutionOut chan *domain.ExecutionEvent // outbound executions to order manager + market data
 
+	// Symbols with an auction scheduled (see auction.go)
+	auctionMu sync.Mutex
+	auctions  map[string]bool
+
+	// Order event journal, written by the run loop (see journal.go)
+	journal    *os.File
+	journalBuf *bufio.Writer
+
+	// Event time stamped on each event, owned by the run loop (see clock.go)
+	clock    func() time.Time
+	lastTime time.Time
+
 	done chan struct{}
 }
 
@@ -31,6 +48,8 @@ func NewSequencer(engine *matching.Engine, bufferSize int) *Sequencer {
 		engine:       engine,
 		OrderIn:      make(chan *domain.OrderEvent, bufferSize),
 		ExecutionOut: make(chan *domain.ExecutionEvent, bufferSize),
+		auctions:     make(map[string]bool),
+		clock:        time.Now,
 		done:         make(chan struct{}),
 	}
 }
@@ -38,6 +57,7 @@ func NewSequencer(engine *matching.Engine, bufferSize int) *Sequencer {
 // Start begins the sequencer's application loop in a goroutine.
 func (s *Sequencer) Start() {
 	go s.run()
+	go s.reportSeqMetrics()
 }
 
 // Stop signals the sequencer to shut down.
@@ -53,6 +73,7 @@ func (s *Sequencer) run() {
 		case event := <-s.OrderIn:
 			s.processEvent(event)
 		case <-s.done:
+			s.closeJournal()
 			log.Println("[sequencer] stopped")
 			return
 		}
@@ -61,9 +82,16 @@ func (s *Sequencer) run() {
 
 // processEvent stamps sequence IDs and dispatches to the matching engine.
 func (s *Sequencer) processEvent(event *domain.OrderEvent) {
+	if event.Action == domain.OrderActionDrain {
+		s.forwardDrain(event.Drain)
+		return
+	}
+
 	// Stamp inbound sequence ID
 	seq := s.inboundSeq.Add(1)
 	event.Order.SequenceID = seq
+	s.stampTime(event)
+	s.appendJournal(seq, event)
 
 	// Dispatch to matching engine (synchronous — single-threaded critical path)
 	result := s.engine.HandleOrder(event)
@@ -75,13 +103,24 @@ func (s *Sequencer) processEvent(event *domain.OrderEvent) {
 	for _, exec := range result.Executions {
 		outSeq := s.outboundSeq.Add(1)
 		exec.SequenceID = outSeq
+		middleware.MatchesTotal.WithLabelValues(exec.Symbol).Inc()
 	}
 
-	// Send execution event downstream (non-blocking with buffered channel)
+	// Send execution event downstream. Executions carry settlement, so a full
+	// channel applies backpressure instead of dropping the event.
 	select {
 	case s.ExecutionOut <- result:
-	default:
-		log.Println("[sequencer] WARN: execution output channel full, dropping event")
+	case <-s.done:
+	}
+}
+
+// forwardDrain passes the shutdown barrier on to the execution consumers.
+// Every order ahead of it has been matched by now.
+func (s *Sequencer) forwardDrain(marker *domain.DrainMarker) {
+	select {
+	case s.ExecutionOut <- &domain.ExecutionEvent{Drain: marker}:
+		close(marker.Sequenced)
+	case <-s.done:
 	}
 }
 
@@ -95,3 +95,82 @@ done:
 	require.NotNil(t, execEvent)
 	assert.Equal(t, uint64(1), execEvent.Executions[0].SequenceID)
 }
+
+func TestSequencer_RunAuction(t *testing.T) {
+	engine := matching.NewEngine()
+	seq := NewSequencer(engine, 100)
+	seq.Start()
+	defer seq.Stop()
+
+	require.NoError(t, seq.RunAuction("AAPL", 100*time.Millisecond))
+	assert.ErrorIs(t, seq.RunAuction("AAPL", time.Second), ErrAuctionInProgress)
+	assert.Error(t, seq.RunAuction("GOOG", 0))
+
+	// Crossing orders collect instead of matching
+	for _, o := range []*domain.Order{
+		{OrderID: "b1", Symbol: "AAPL", Side: domain.SideBuy, Price: 10100, Quantity: 100, RemainingQuantity: 100},
+		{OrderID: "s1", Symbol: "AAPL", Side: domain.SideSell, Price: 9900, Quantity: 100, RemainingQuantity: 100},
+	} {
+		seq.OrderIn <- &domain.OrderEvent{Action: domain.OrderActionNew, Order: o}
+	}
+
+	var uncross *domain.ExecutionEvent
+	timeout := time.After(2 * time.Second)
+	for uncross == nil {
+		select {
+		case ev := <-seq.ExecutionOut:
+			if len(ev.Executions) > 0 {
+				uncross = ev
+			}
+		case <-timeout:
+			t.Fatal("auction never uncrossed")
+		}
+	}
+
+	require.Len(t, uncross.Executions, 1)
+	// Both sides are tied on volume and imbalance, so the lowest price wins
+	assert.Equal(t, int64(9900), uncross.Executions[0].Price)
+	assert.NotZero(t, uncross.Executions[0].SequenceID)
+	assert.Equal(t, uint64(4), seq.CurrentInboundSeq(), "start, two orders and end are all sequenced")
+
+	// The symbol can be auctioned again once the first one has ended
+	require.Eventually(t, func() bool { return seq.RunAuction("AAPL", time.Hour) == nil }, time.Second, 10*time.Millisecond)
+}
+
+func TestSequencer_ResetSessionIsOrderedWithExecutions(t *testing.T) {
+	engine := matching.NewEngine()
+	seq := NewSequencer(engine, 100)
+	seq.Start()
+	defer seq.Stop()
+
+	trade := func(id string) {
+		seq.OrderIn <- &domain.OrderEvent{Action: domain.OrderActionNew, Order: &domain.Order{
+			OrderID: id + "-s", Symbol: "AAPL", Side: domain.SideSell, Price: 10000, Quantity: 10, RemainingQuantity: 10,
+		}}
+		seq.OrderIn <- &domain.OrderEvent{Action: domain.OrderActionNew, Order: &domain.Order{
+			OrderID: id + "-b", Symbol: "AAPL", Side: domain.SideBuy, Price: 10000, Quantity: 10, RemainingQuantity: 10,
+		}}
+	}
+
+	trade("before")
+	require.NoError(t, seq.ResetSession("AAPL"))
+	trade("after")
+	assert.Error(t, seq.ResetSession(""))
+
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for len(got) < 3 {
+		select {
+		case ev := <-seq.ExecutionOut:
+			switch {
+			case ev.SessionReset != "":
+				got = append(got, "reset:"+ev.SessionReset)
+			case len(ev.Executions) > 0:
+				got = append(got, ev.Executions[0].TakerOrderID)
+			}
+		case <-timeout:
+			t.Fatalf("only saw %v", got)
+		}
+	}
+	assert.Equal(t, []string{"before-b", "reset:AAPL", "after-b"}, got)
+}
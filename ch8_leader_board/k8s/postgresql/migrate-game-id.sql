@@ -0,0 +1,40 @@
+-- ====================================================
+-- 多遊戲隔離遷移：為既有資料庫加上 game_id
+-- initdb 腳本只在全新資料庫執行；已經存在的資料庫用 `make migrate-postgresql` 套用。
+-- 既有資料歸入 'default' 遊戲。可重複執行。
+-- ====================================================
+BEGIN;
+
+-- 分數歷史表：match_id 改為在遊戲內唯一
+ALTER TABLE score_history ADD COLUMN IF NOT EXISTS game_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE score_history DROP CONSTRAINT IF EXISTS score_history_match_id_key;
+ALTER TABLE score_history DROP CONSTRAINT IF EXISTS score_history_game_id_match_id_key;
+ALTER TABLE score_history ADD CONSTRAINT score_history_game_id_match_id_key UNIQUE (game_id, match_id);
+
+-- 月度排行榜表：主鍵加上 game_id
+ALTER TABLE monthly_leaderboard ADD COLUMN IF NOT EXISTS game_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE monthly_leaderboard DROP CONSTRAINT IF EXISTS monthly_leaderboard_pkey;
+ALTER TABLE monthly_leaderboard ADD PRIMARY KEY (game_id, user_id, month);
+
+-- 索引改為依遊戲查詢
+DROP INDEX IF EXISTS idx_monthly_score;
+CREATE INDEX idx_monthly_score ON monthly_leaderboard(game_id, month, score DESC);
+
+-- Top 10 視圖改為每個遊戲各自排名，查詢時以 game_id 篩選
+DROP MATERIALIZED VIEW IF EXISTS top10_current_month;
+CREATE MATERIALIZED VIEW top10_current_month AS
+SELECT game_id, user_id, score, rank
+FROM (
+  SELECT
+    game_id,
+    user_id,
+    score,
+    RANK() OVER (PARTITION BY game_id ORDER BY score DESC) as rank,
+    ROW_NUMBER() OVER (PARTITION BY game_id ORDER BY score DESC) as pos
+  FROM monthly_leaderboard
+  WHERE month = TO_CHAR(CURRENT_DATE, 'YYYY-MM')
+) ranked
+WHERE pos <= 10;
+CREATE UNIQUE INDEX idx_top10_user_id ON top10_current_month(game_id, user_id);
+
+COMMIT;
@@ -33,6 +33,9 @@ func main() {
 
 	// Load configuration
 	cfg := config.Load()
+	if err := repository.ValidateGameID(cfg.GameID); err != nil {
+		log.Fatalf("Invalid GAME_ID: %v", err)
+	}
 
 	// Connect to PostgreSQL
 	db, err := sql.Open("postgres", cfg.DB.DSN)
@@ -50,17 +53,8 @@ func main() {
 	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
 
 	// Test database connection with retry
-	maxRetries := 10
-	for i := 0; i < maxRetries; i++ {
-		err = db.Ping()
-		if err == nil {
-			break
-		}
-		log.Printf("Waiting for database... (attempt %d/%d)", i+1, maxRetries)
-		time.Sleep(3 * time.Second)
-	}
-	if err != nil {
-		log.Fatalf("Database not available after retries: %v", err)
+	if err := waitForDependency("database", db.Ping, cfg.DependencyRetry); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	log.Println("Successfully connected to PostgreSQL")
 
@@ -69,6 +63,7 @@ func main() {
 
 	// Initialize v1 handler (PostgreSQL only)
 	h := handler.NewHandler(postgresRepo)
+	h.SetCacheMaxAge(cfg.LeaderboardCacheMaxAge)
 
 	// Setup router
 	r := mux.NewRouter()
@@ -79,12 +74,18 @@ func main() {
 	// ============================================
 	// v1 API routes - PostgreSQL only (Scenario 1)
 	// ============================================
+	// Each leaderboard is scoped to a game: /v1/games/{game_id}/scores, or the
+	// X-Game-ID header on /v1/scores (GAME_ID when neither is given)
+	gameMiddleware := middleware.GameMiddleware(cfg.GameID)
+
 	apiV1 := r.PathPrefix("/v1").Subrouter()
-	apiV1.Use(middleware.MetricsMiddleware)
+	apiV1.Use(middleware.MetricsMiddleware, gameMiddleware)
 
-	apiV1.HandleFunc("/scores", h.UpdateScore).Methods("POST")
-	apiV1.HandleFunc("/scores", h.GetLeaderboard).Methods("GET")
-	apiV1.HandleFunc("/scores/{user_id}", h.GetUserRank).Methods("GET")
+	for _, prefix := range []string{"", "/games/{game_id}"} {
+		apiV1.HandleFunc(prefix+"/scores", h.UpdateScore).Methods("POST")
+		apiV1.HandleFunc(prefix+"/scores", h.GetLeaderboard).Methods("GET")
+		apiV1.HandleFunc(prefix+"/scores/{user_id}", h.GetUserRank).Methods("GET")
+	}
 
 	// ============================================
 	// v2 API routes - Redis + PostgreSQL (Scenario 2)
@@ -98,21 +99,17 @@ func main() {
 
 	// Test Redis connection with retry
 	ctx := context.Background()
-	for i := 0; i < maxRetries; i++ {
-		_, err = redisClient.Ping(ctx).Result()
-		if err == nil {
-			break
-		}
-		log.Printf("Waiting for Redis... (attempt %d/%d): %v", i+1, maxRetries, err)
-		time.Sleep(3 * time.Second)
-	}
+	err = waitForDependency("Redis", func() error {
+		return redisClient.Ping(ctx).Err()
+	}, cfg.DependencyRetry)
 
 	var hV2 *handler.HandlerV2
 	if err != nil {
-		log.Printf("Warning: Redis not available, v2 endpoints will fallback to PostgreSQL only: %v", err)
+		log.Printf("Warning: v2 endpoints will fallback to PostgreSQL only: %v", err)
 		// Create hybrid repo that will always fallback to PostgreSQL
 		redisRepo := repository.NewRedisRepository(redisClient)
 		hybridRepo := repository.NewHybridRepository(redisRepo, postgresRepo)
+		hybridRepo.SetNegativeCacheTTL(cfg.NegativeCacheTTL)
 		hV2 = handler.NewHandlerV2(hybridRepo)
 	} else {
 		log.Println("Successfully connected to Redis")
@@ -122,6 +119,7 @@ func main() {
 
 		// Initialize Hybrid repository
 		hybridRepo := repository.NewHybridRepository(redisRepo, postgresRepo)
+		hybridRepo.SetNegativeCacheTTL(cfg.NegativeCacheTTL)
 
 		// Warm cache from PostgreSQL at startup
 		go func() {
@@ -134,12 +132,18 @@ func main() {
 		hV2 = handler.NewHandlerV2(hybridRepo)
 	}
 
-	apiV2 := r.PathPrefix("/v2").Subrouter()
-	apiV2.Use(middleware.MetricsMiddleware)
+	hV2.SetCacheMaxAge(cfg.LeaderboardCacheMaxAge)
 
-	apiV2.HandleFunc("/scores", hV2.UpdateScore).Methods("POST")
-	apiV2.HandleFunc("/scores", hV2.GetLeaderboard).Methods("GET")
-	apiV2.HandleFunc("/scores/{user_id}", hV2.GetUserRank).Methods("GET")
+	apiV2 := r.PathPrefix("/v2").Subrouter()
+	apiV2.Use(middleware.MetricsMiddleware, gameMiddleware)
+
+	for _, prefix := range []string{"", "/games/{game_id}"} {
+		apiV2.HandleFunc(prefix+"/scores", hV2.UpdateScore).Methods("POST")
+		apiV2.HandleFunc(prefix+"/scores", hV2.GetLeaderboard).Methods("GET")
+		// Registered before /scores/{user_id} so "histogram" isn't taken as a user ID
+		apiV2.HandleFunc(prefix+"/scores/histogram", hV2.GetScoreHistogram).Methods("GET")
+		apiV2.HandleFunc(prefix+"/scores/{user_id}", hV2.GetUserRank).Methods("GET")
+	}
 
 	// Health check
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
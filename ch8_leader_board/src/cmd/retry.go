@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"leader_board/internal/config"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// sleep and randFloat are replaced in tests
+var (
+	sleep     = time.Sleep
+	randFloat = rand.Float64
+)
+
+// waitForDependency calls probe until it succeeds or cfg.Attempts is used up.
+// Between attempts it waits BaseDelay, doubling each time up to MaxDelay, with
+// up to Jitter of each delay taken off at random so that replicas restarting
+// together do not reconnect in lockstep. Returns the last probe error.
+func waitForDependency(name string, probe func() error, cfg config.RetryConfig) error {
+	attempts := cfg.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = probe(); err == nil {
+			return nil
+		}
+		if i == attempts-1 {
+			break
+		}
+		delay := backoffDelay(i, cfg)
+		log.Printf("Waiting for %s... (attempt %d/%d, retrying in %s): %v", name, i+1, attempts, delay, err)
+		sleep(delay)
+	}
+	return fmt.Errorf("%s not available after %d attempts: %w", name, attempts, err)
+}
+
+// backoffDelay returns how long to wait after the given failed attempt (0-based)
+func backoffDelay(attempt int, cfg config.RetryConfig) time.Duration {
+	delay := cfg.BaseDelay
+	for i := 0; i < attempt && (cfg.MaxDelay == 0 || delay < cfg.MaxDelay); i++ {
+		delay *= 2
+	}
+	if cfg.MaxDelay > 0 && delay > cfg.MaxDelay {
+		delay = cfg.MaxDelay
+	}
+	if cfg.Jitter > 0 {
+		delay -= time.Duration(float64(delay) * cfg.Jitter * randFloat())
+	}
+	return delay
+}
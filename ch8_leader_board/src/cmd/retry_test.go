@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"leader_board/internal/config"
+	"testing"
+	"time"
+)
+
+// fakeSleep records requested waits instead of sleeping
+func fakeSleep(t *testing.T, jitterRoll float64) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	origSleep, origRand := sleep, randFloat
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	randFloat = func() float64 { return jitterRoll }
+	t.Cleanup(func() { sleep, randFloat = origSleep, origRand })
+	return &waits
+}
+
+// flakyProbe fails the first n calls
+func flakyProbe(n int) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= n {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, &calls
+}
+
+func TestWaitForDependency_BacksOffThenSucceeds(t *testing.T) {
+	waits := fakeSleep(t, 0)
+	probe, calls := flakyProbe(4)
+
+	cfg := config.RetryConfig{Attempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: 500 * time.Millisecond}
+	if err := waitForDependency("database", probe, cfg); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if *calls != 5 {
+		t.Errorf("expected 5 probes, got %d", *calls)
+	}
+
+	// Doubles from the base delay and is capped at the max
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond}
+	if len(*waits) != len(want) {
+		t.Fatalf("expected waits %v, got %v", want, *waits)
+	}
+	for i := range want {
+		if (*waits)[i] != want[i] {
+			t.Errorf("wait %d: expected %s, got %s", i, want[i], (*waits)[i])
+		}
+	}
+}
+
+func TestWaitForDependency_ExhaustsRetries(t *testing.T) {
+	waits := fakeSleep(t, 0)
+	probe, calls := flakyProbe(100)
+
+	cfg := config.RetryConfig{Attempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}
+	err := waitForDependency("Redis", probe, cfg)
+	if err == nil {
+		t.Fatal("expected an error once attempts are used up")
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 probes, got %d", *calls)
+	}
+	// No wait after the final attempt
+	if len(*waits) != 2 {
+		t.Errorf("expected 2 waits, got %v", *waits)
+	}
+	if got := err.Error(); got != "Redis not available after 3 attempts: connection refused" {
+		t.Errorf("unexpected error %q", got)
+	}
+}
+
+func TestWaitForDependency_Jitter(t *testing.T) {
+	// The largest possible roll takes the full jitter fraction off
+	waits := fakeSleep(t, 1)
+	probe, _ := flakyProbe(2)
+
+	cfg := config.RetryConfig{Attempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second, Jitter: 0.5}
+	if err := waitForDependency("database", probe, cfg); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	want := []time.Duration{500 * time.Millisecond, time.Second}
+	for i := range want {
+		if (*waits)[i] != want[i] {
+			t.Errorf("wait %d: expected %s, got %s", i, want[i], (*waits)[i])
+		}
+	}
+}
+
+func TestBackoffDelay_NoMaxDelay(t *testing.T) {
+	cfg := config.RetryConfig{BaseDelay: time.Second}
+	if got := backoffDelay(3, cfg); got != 8*time.Second {
+		t.Errorf("expected 8s without a cap, got %s", got)
+	}
+}
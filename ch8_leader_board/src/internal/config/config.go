@@ -3,12 +3,33 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
 	UseRedis bool
 	DB       DBConfig
 	Redis    RedisConfig
+	// NegativeCacheTTL is how long the v2 repository remembers unknown users (0 disables)
+	NegativeCacheTTL time.Duration
+	// GameID is the leaderboard namespace used when a request names no game
+	GameID string
+	// LeaderboardCacheMaxAge is the Cache-Control max-age of top-N responses (0 = always revalidate)
+	LeaderboardCacheMaxAge time.Duration
+	// DependencyRetry controls how startup waits for PostgreSQL and Redis
+	DependencyRetry RetryConfig
+}
+
+// RetryConfig is an exponential backoff policy
+type RetryConfig struct {
+	// Attempts is how many times the dependency is probed in total
+	Attempts int
+	// BaseDelay is the wait after the first failure; it doubles after each one
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts
+	MaxDelay time.Duration
+	// Jitter is the fraction (0-1) of each wait that may be taken off at random
+	Jitter float64
 }
 
 type DBConfig struct {
@@ -23,6 +44,29 @@ type RedisConfig struct {
 
 func Load() *Config {
 	useRedis, _ := strconv.ParseBool(getEnv("USE_REDIS", "false"))
+	negativeCacheTTL, err := time.ParseDuration(getEnv("NEGATIVE_CACHE_TTL", "5s"))
+	if err != nil {
+		negativeCacheTTL = 5 * time.Second
+	}
+
+	leaderboardCacheMaxAge, err := time.ParseDuration(getEnv("LEADERBOARD_CACHE_MAX_AGE", "0s"))
+	if err != nil {
+		leaderboardCacheMaxAge = 0
+	}
+
+	retry := RetryConfig{Attempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second, Jitter: 0.2}
+	if n, err := strconv.Atoi(getEnv("DEPENDENCY_RETRY_ATTEMPTS", "")); err == nil && n > 0 {
+		retry.Attempts = n
+	}
+	if d, err := time.ParseDuration(getEnv("DEPENDENCY_RETRY_BASE_DELAY", "")); err == nil && d >= 0 {
+		retry.BaseDelay = d
+	}
+	if d, err := time.ParseDuration(getEnv("DEPENDENCY_RETRY_MAX_DELAY", "")); err == nil && d >= 0 {
+		retry.MaxDelay = d
+	}
+	if j, err := strconv.ParseFloat(getEnv("DEPENDENCY_RETRY_JITTER", ""), 64); err == nil && j >= 0 && j <= 1 {
+		retry.Jitter = j
+	}
 
 	return &Config{
 		UseRedis: useRedis,
@@ -34,6 +78,11 @@ func Load() *Config {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       0,
 		},
+		NegativeCacheTTL: negativeCacheTTL,
+		GameID:           getEnv("GAME_ID", "default"),
+
+		LeaderboardCacheMaxAge: leaderboardCacheMaxAge,
+		DependencyRetry:        retry,
 	}
 }
 
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// writeCachedJSON writes v as JSON with a weak ETag derived from the encoded
+// body, answering 304 Not Modified when the client's If-None-Match already
+// names it. Hashing the response rather than counting updates keeps the tag
+// correct across replicas and games. maxAge sets Cache-Control; 0 lets
+// clients keep the body but makes them revalidate on every request.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v any, maxAge time.Duration) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h := fnv.New64a()
+	h.Write(body)
+	etag := fmt.Sprintf(`W/"%x"`, h.Sum64())
+
+	w.Header().Set("ETag", etag)
+	if maxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// etagMatches applies the weak comparison If-None-Match calls for: any listed
+// tag (or *) matching etag, ignoring the W/ prefix.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"leader_board/internal/repository"
+	"leader_board/internal/tracing"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func init() {
+	tracing.Tracer = noop.NewTracerProvider().Tracer("test")
+}
+
+// fakeRepo is an in-memory Repository
+type fakeRepo struct {
+	mu     sync.Mutex
+	scores map[string]int
+}
+
+func (f *fakeRepo) UpdateScore(_ context.Context, userID string, points int, _ string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scores[userID] += points
+	return f.scores[userID], nil
+}
+
+func (f *fakeRepo) GetTopN(_ context.Context, n int) ([]repository.LeaderboardEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries := make([]repository.LeaderboardEntry, 0, len(f.scores))
+	for user, score := range f.scores {
+		entries = append(entries, repository.LeaderboardEntry{UserID: user, Score: score})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries, nil
+}
+
+func (f *fakeRepo) GetUserRank(context.Context, string, int) (*repository.LeaderboardEntry, []repository.LeaderboardEntry, error) {
+	return nil, nil, repository.ErrUserNotFound
+}
+
+func (f *fakeRepo) GetScoreHistogram(context.Context, int) ([]repository.ScoreBucket, error) {
+	return nil, nil
+}
+
+func TestGetLeaderboard_ETag(t *testing.T) {
+	h := NewHandler(&fakeRepo{scores: map[string]int{"alice": 5, "bob": 3}})
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/scores", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h.GetLeaderboard(w, req)
+		return w
+	}
+
+	first := get("")
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", first.Code)
+	}
+	etag := first.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected a weak ETag, got %q", etag)
+	}
+	if cc := first.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected Cache-Control no-cache by default, got %q", cc)
+	}
+	if !strings.Contains(first.Body.String(), `"alice"`) {
+		t.Errorf("expected leaderboard body, got %s", first.Body.String())
+	}
+
+	// Unchanged leaderboard: the client's copy is still good
+	notModified := get(etag)
+	if notModified.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", notModified.Code)
+	}
+	if notModified.Body.Len() != 0 {
+		t.Errorf("expected empty 304 body, got %q", notModified.Body.String())
+	}
+	if got := get(`"other", ` + strings.TrimPrefix(etag, "W/")).Code; got != http.StatusNotModified {
+		t.Errorf("expected a strong form of the tag in a list to match, got %d", got)
+	}
+
+	// A score update changes the leaderboard and invalidates the tag
+	post := httptest.NewRequest(http.MethodPost, "/v1/scores",
+		strings.NewReader(`{"user_id":"bob","points":10,"match_id":"m1"}`))
+	h.UpdateScore(httptest.NewRecorder(), post)
+
+	changed := get(etag)
+	if changed.Code != http.StatusOK {
+		t.Fatalf("expected 200 after a score update, got %d", changed.Code)
+	}
+	if newTag := changed.Header().Get("ETag"); newTag == etag || newTag == "" {
+		t.Errorf("expected a new ETag after the update, got %q", newTag)
+	}
+	if get(changed.Header().Get("ETag")).Code != http.StatusNotModified {
+		t.Errorf("expected the new ETag to validate")
+	}
+}
+
+func TestGetLeaderboard_CacheMaxAge(t *testing.T) {
+	h := NewHandler(&fakeRepo{scores: map[string]int{"alice": 1}})
+	h.SetCacheMaxAge(30 * time.Second)
+
+	w := httptest.NewRecorder()
+	h.GetLeaderboard(w, httptest.NewRequest(http.MethodGet, "/v1/scores", nil))
+	if cc := w.Header().Get("Cache-Control"); cc != "private, max-age=30" {
+		t.Errorf("expected max-age=30, got %q", cc)
+	}
+}
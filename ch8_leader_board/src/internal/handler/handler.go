@@ -5,6 +5,7 @@ import (
 	"leader_board/internal/repository"
 	"leader_board/internal/tracing"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel/attribute"
@@ -13,13 +14,20 @@ import (
 )
 
 type Handler struct {
-	repo repository.Repository
+	repo        repository.Repository
+	cacheMaxAge time.Duration
 }
 
 func NewHandler(repo repository.Repository) *Handler {
 	return &Handler{repo: repo}
 }
 
+// SetCacheMaxAge sets how long clients may reuse a leaderboard response
+// before revalidating it with its ETag (0 = revalidate every time)
+func (h *Handler) SetCacheMaxAge(d time.Duration) {
+	h.cacheMaxAge = d
+}
+
 // UpdateScoreRequest represents the request body for updating scores
 type UpdateScoreRequest struct {
 	UserID  string `json:"user_id"`
@@ -127,14 +135,13 @@ func (h *Handler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
 	span.SetAttributes(attribute.Int("result_count", len(entries)))
 	span.SetStatus(codes.Ok, "")
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(LeaderboardResponse{
+	writeCachedJSON(w, r, LeaderboardResponse{
 		Status: "success",
 		Data: LeaderboardData{
 			Leaderboard: entries,
 			Count:       len(entries),
 		},
-	})
+	}, h.cacheMaxAge)
 }
 
 // GetUserRank handles GET /v1/scores/{user_id} or /v2/scores/{user_id}
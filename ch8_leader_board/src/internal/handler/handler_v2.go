@@ -2,9 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"leader_board/internal/repository"
 	"leader_board/internal/tracing"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel/attribute"
@@ -14,13 +17,20 @@ import (
 
 // HandlerV2 uses HybridRepository (Redis + PostgreSQL fallback)
 type HandlerV2 struct {
-	repo *repository.HybridRepository
+	repo        *repository.HybridRepository
+	cacheMaxAge time.Duration
 }
 
 func NewHandlerV2(repo *repository.HybridRepository) *HandlerV2 {
 	return &HandlerV2{repo: repo}
 }
 
+// SetCacheMaxAge sets how long clients may reuse a leaderboard response
+// before revalidating it with its ETag (0 = revalidate every time)
+func (h *HandlerV2) SetCacheMaxAge(d time.Duration) {
+	h.cacheMaxAge = d
+}
+
 // UpdateScore handles POST /v2/scores
 func (h *HandlerV2) UpdateScore(w http.ResponseWriter, r *http.Request) {
 	ctx, span := tracing.Tracer.Start(r.Context(), "handler.v2.UpdateScore",
@@ -96,14 +106,13 @@ func (h *HandlerV2) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
 	span.SetAttributes(attribute.Int("result_count", len(entries)))
 	span.SetStatus(codes.Ok, "")
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(LeaderboardResponse{
+	writeCachedJSON(w, r, LeaderboardResponse{
 		Status: "success",
 		Data: LeaderboardData{
 			Leaderboard: entries,
 			Count:       len(entries),
 		},
-	})
+	}, h.cacheMaxAge)
 }
 
 // GetUserRank handles GET /v2/scores/{user_id}
@@ -156,3 +165,64 @@ func (h *HandlerV2) GetUserRank(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// DefaultHistogramBucketSize is the bucket width used when ?bucket= is omitted
+const DefaultHistogramBucketSize = 10
+
+// HistogramResponse represents the response for the score distribution query
+type HistogramResponse struct {
+	Status string        `json:"status"`
+	Data   HistogramData `json:"data"`
+}
+
+type HistogramData struct {
+	BucketSize int                      `json:"bucket_size"`
+	Buckets    []repository.ScoreBucket `json:"buckets"`
+}
+
+// GetScoreHistogram handles GET /v2/scores/histogram?bucket=
+func (h *HandlerV2) GetScoreHistogram(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Tracer.Start(r.Context(), "handler.v2.GetScoreHistogram",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("api_version", "v2"),
+		),
+	)
+	defer span.End()
+
+	bucketSize := DefaultHistogramBucketSize
+	if raw := r.URL.Query().Get("bucket"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			span.SetStatus(codes.Error, "invalid bucket")
+			http.Error(w, "bucket must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		bucketSize = n
+	}
+	span.SetAttributes(attribute.Int("bucket_size", bucketSize))
+
+	buckets, err := h.repo.GetScoreHistogram(ctx, bucketSize)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, repository.ErrTooManyBuckets) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("bucket_count", len(buckets)))
+	span.SetStatus(codes.Ok, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HistogramResponse{
+		Status: "success",
+		Data: HistogramData{
+			BucketSize: bucketSize,
+			Buckets:    buckets,
+		},
+	})
+}
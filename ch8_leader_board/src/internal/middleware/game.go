@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"leader_board/internal/repository"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// GameHeader lets clients select a game's leaderboard on the un-prefixed routes
+const GameHeader = "X-Game-ID"
+
+// GameMiddleware scopes each request to one game's leaderboard.
+// The game is taken from the {game_id} path segment, then the X-Game-ID
+// header, and falls back to defaultGame.
+func GameMiddleware(defaultGame string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gameID := mux.Vars(r)["game_id"]
+			if gameID == "" {
+				gameID = r.Header.Get(GameHeader)
+			}
+			if gameID == "" {
+				gameID = defaultGame
+			}
+			if err := repository.ValidateGameID(gameID); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(repository.WithGame(r.Context(), gameID)))
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -72,6 +73,12 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 		// Record metrics
 		duration := time.Since(start).Seconds()
 		endpoint := normalizePathForMetrics(r.URL.Path)
+		if route := mux.CurrentRoute(r); route != nil {
+			// Use the route template so per-game paths don't explode label cardinality
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				endpoint = tmpl
+			}
+		}
 		scenario := r.Header.Get("X-Scenario")
 		if scenario == "" {
 			scenario = "unknown"
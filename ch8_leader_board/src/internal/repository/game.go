@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// DefaultGameID is the namespace used when no game is specified.
+// It keeps the original un-prefixed Redis key so existing data stays visible.
+const DefaultGameID = "default"
+
+var gameIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+
+type gameKey struct{}
+
+// WithGame returns a context scoped to the given game's leaderboard
+func WithGame(ctx context.Context, gameID string) context.Context {
+	return context.WithValue(ctx, gameKey{}, gameID)
+}
+
+// GameFromContext returns the game ID carried by ctx, or DefaultGameID
+func GameFromContext(ctx context.Context) string {
+	if gameID, ok := ctx.Value(gameKey{}).(string); ok && gameID != "" {
+		return gameID
+	}
+	return DefaultGameID
+}
+
+// ValidateGameID checks that a game ID is safe to embed in Redis keys
+func ValidateGameID(gameID string) error {
+	if !gameIDPattern.MatchString(gameID) {
+		return fmt.Errorf("invalid game id %q: use 1-50 letters, digits, '_' or '-'", gameID)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxHistogramBuckets bounds the size of a score histogram response
+const MaxHistogramBuckets = 1000
+
+// ErrTooManyBuckets is returned when the score range split by the bucket size
+// would produce more than MaxHistogramBuckets buckets
+var ErrTooManyBuckets = errors.New("too many histogram buckets")
+
+// ScoreBucket counts the players whose score falls in [Min, Max]
+type ScoreBucket struct {
+	Min   int   `json:"min"`
+	Max   int   `json:"max"`
+	Count int64 `json:"count"`
+}
+
+// newHistogram returns empty buckets of bucketSize covering minScore..maxScore.
+// Buckets are aligned to multiples of bucketSize, so bucket boundaries are the
+// same whichever backend computes the counts.
+func newHistogram(minScore, maxScore, bucketSize int) ([]ScoreBucket, error) {
+	if bucketSize < 1 {
+		return nil, fmt.Errorf("bucket size must be positive, got %d", bucketSize)
+	}
+
+	first := floorDiv(minScore, bucketSize)
+	count := floorDiv(maxScore, bucketSize) - first + 1
+	if count > MaxHistogramBuckets {
+		return nil, fmt.Errorf("%w: %d buckets of size %d needed, limit is %d",
+			ErrTooManyBuckets, count, bucketSize, MaxHistogramBuckets)
+	}
+
+	buckets := make([]ScoreBucket, count)
+	for i := range buckets {
+		low := (first + i) * bucketSize
+		buckets[i] = ScoreBucket{Min: low, Max: low + bucketSize - 1}
+	}
+	return buckets, nil
+}
+
+// bucketIndex returns the position in buckets of the bucket holding score
+func bucketIndex(buckets []ScoreBucket, score, bucketSize int) int {
+	return floorDiv(score, bucketSize) - floorDiv(buckets[0].Min, bucketSize)
+}
+
+// floorDiv divides rounding towards negative infinity, matching FLOOR() in SQL
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"leader_board/internal/tracing"
 	"log"
 	"time"
@@ -12,21 +13,40 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultNegativeCacheTTL is how long a confirmed-absent user ID is remembered
+const DefaultNegativeCacheTTL = 5 * time.Second
+
+// scoreCache is the subset of RedisRepository used by HybridRepository
+type scoreCache interface {
+	GetTopN(ctx context.Context, n int) ([]LeaderboardEntry, error)
+	GetUserRank(ctx context.Context, userID string, neighborCount int) (*LeaderboardEntry, []LeaderboardEntry, error)
+	SetScore(ctx context.Context, userID string, score int) error
+	GetScoreHistogram(ctx context.Context, bucketSize int) ([]ScoreBucket, error)
+}
+
 // HybridRepository implements cache-aside pattern:
 // - Read: Redis first, fallback to PostgreSQL on cache miss
 // - Write: Write to both Redis and PostgreSQL (write-through)
+// - Unknown users: short-TTL negative cache, so repeated lookups skip PostgreSQL
 type HybridRepository struct {
-	redis    *RedisRepository
-	postgres *PostgresRepository
+	redis    scoreCache
+	postgres Repository
+	negative *negativeCache
 }
 
 func NewHybridRepository(redis *RedisRepository, postgres *PostgresRepository) *HybridRepository {
 	return &HybridRepository{
 		redis:    redis,
 		postgres: postgres,
+		negative: newNegativeCache(DefaultNegativeCacheTTL),
 	}
 }
 
+// SetNegativeCacheTTL sets how long unknown user IDs are cached; zero disables the negative cache
+func (h *HybridRepository) SetNegativeCacheTTL(ttl time.Duration) {
+	h.negative.setTTL(ttl)
+}
+
 // UpdateScore updates score in both Redis and PostgreSQL
 // Write-through: ensures data consistency
 func (h *HybridRepository) UpdateScore(ctx context.Context, userID string, points int, matchID string) (int, error) {
@@ -53,6 +73,9 @@ func (h *HybridRepository) UpdateScore(ctx context.Context, userID string, point
 		return 0, err
 	}
 
+	// The user now exists, so any cached "not found" is stale
+	h.negative.remove(negativeKey(ctx, userID))
+
 	// 2. Update Redis cache (best effort, don't fail if Redis is down)
 	if err := h.redis.SetScore(ctx, userID, newScore); err != nil {
 		span.AddEvent("redis_cache_update_failed", trace.WithAttributes(
@@ -130,7 +153,7 @@ func (h *HybridRepository) GetTopN(ctx context.Context, n int) ([]LeaderboardEnt
 	))
 
 	// 3. Warm cache asynchronously (best effort)
-	go h.warmCacheFromEntries(entries)
+	go h.warmCacheFromEntries(GameFromContext(ctx), entries)
 
 	span.SetStatus(codes.Ok, "")
 	return entries, nil
@@ -153,6 +176,17 @@ func (h *HybridRepository) GetUserRank(ctx context.Context, userID string, neigh
 		attribute.Int("neighbor_count", neighborCount),
 	))
 
+	// 0. Fail fast for users recently confirmed absent
+	if h.negative.contains(negativeKey(ctx, userID)) {
+		span.SetAttributes(
+			attribute.Bool("cache.hit", true),
+			attribute.String("data_source", "negative_cache"),
+		)
+		span.AddEvent("negative_cache_hit")
+		span.SetStatus(codes.Error, "user not found in leaderboard")
+		return nil, nil, ErrUserNotFound
+	}
+
 	// 1. Try Redis first
 	userEntry, neighbors, err := h.redis.GetUserRank(ctx, userID, neighborCount)
 	if err == nil {
@@ -179,6 +213,10 @@ func (h *HybridRepository) GetUserRank(ctx context.Context, userID string, neigh
 	// 2. Fallback to PostgreSQL
 	userEntry, neighbors, err = h.postgres.GetUserRank(ctx, userID, neighborCount)
 	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			h.negative.add(negativeKey(ctx, userID))
+			span.AddEvent("negative_cache_stored")
+		}
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "postgres fallback failed")
 		return nil, nil, err
@@ -195,9 +233,10 @@ func (h *HybridRepository) GetUserRank(ctx context.Context, userID string, neigh
 	))
 
 	// 3. Warm cache for this user (best effort)
+	gameCtx := WithGame(context.Background(), GameFromContext(ctx))
 	go func() {
 		if userEntry != nil {
-			if err := h.redis.SetScore(context.Background(), userEntry.UserID, userEntry.Score); err != nil {
+			if err := h.redis.SetScore(gameCtx, userEntry.UserID, userEntry.Score); err != nil {
 				log.Printf("Failed to warm cache for user %s: %v", userEntry.UserID, err)
 			}
 		}
@@ -207,9 +246,69 @@ func (h *HybridRepository) GetUserRank(ctx context.Context, userID string, neigh
 	return userEntry, neighbors, nil
 }
 
-// warmCacheFromEntries populates Redis cache from PostgreSQL results
-func (h *HybridRepository) warmCacheFromEntries(entries []LeaderboardEntry) {
-	ctx := context.Background()
+// GetScoreHistogram counts players per score bucket
+// Cache-aside: Try Redis first, fallback to PostgreSQL
+func (h *HybridRepository) GetScoreHistogram(ctx context.Context, bucketSize int) ([]ScoreBucket, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "hybrid.GetScoreHistogram",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("strategy", "cache-aside"),
+			attribute.Int("bucket_size", bucketSize),
+		),
+	)
+	defer span.End()
+
+	// 1. Try Redis first
+	buckets, err := h.redis.GetScoreHistogram(ctx, bucketSize)
+	if err == nil && len(buckets) > 0 {
+		span.SetAttributes(
+			attribute.Bool("cache.hit", true),
+			attribute.String("data_source", "redis"),
+			attribute.Int("bucket_count", len(buckets)),
+		)
+		span.SetStatus(codes.Ok, "")
+		return buckets, nil
+	}
+
+	// The bucket limit holds for either backend, so don't retry against PostgreSQL
+	if errors.Is(err, ErrTooManyBuckets) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	if err != nil {
+		span.AddEvent("redis_fallback", trace.WithAttributes(
+			attribute.String("error", err.Error()),
+		))
+		log.Printf("Redis GetScoreHistogram failed, falling back to PostgreSQL: %v", err)
+	} else {
+		span.AddEvent("redis_fallback", trace.WithAttributes(
+			attribute.String("reason", "empty_result"),
+		))
+	}
+
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
+	// 2. Fallback to PostgreSQL
+	buckets, err = h.postgres.GetScoreHistogram(ctx, bucketSize)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "postgres fallback failed")
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.String("data_source", "postgresql"),
+		attribute.Int("bucket_count", len(buckets)),
+	)
+	span.SetStatus(codes.Ok, "")
+	return buckets, nil
+}
+
+// warmCacheFromEntries populates a game's Redis cache from PostgreSQL results
+func (h *HybridRepository) warmCacheFromEntries(gameID string, entries []LeaderboardEntry) {
+	ctx := WithGame(context.Background(), gameID)
 	for _, entry := range entries {
 		if err := h.redis.SetScore(ctx, entry.UserID, entry.Score); err != nil {
 			log.Printf("Failed to warm cache for user %s: %v", entry.UserID, err)
@@ -231,7 +330,7 @@ func (h *HybridRepository) WarmCache(db *sql.DB) error {
 	start := time.Now()
 
 	rows, err := db.QueryContext(ctx, `
-		SELECT user_id, score
+		SELECT game_id, user_id, score
 		FROM monthly_leaderboard
 		WHERE month = $1
 	`, currentMonth)
@@ -245,15 +344,15 @@ func (h *HybridRepository) WarmCache(db *sql.DB) error {
 	count := 0
 	errors := 0
 	for rows.Next() {
-		var userID string
+		var gameID, userID string
 		var score int
-		if err := rows.Scan(&userID, &score); err != nil {
+		if err := rows.Scan(&gameID, &userID, &score); err != nil {
 			log.Printf("Error scanning row during cache warm: %v", err)
 			errors++
 			continue
 		}
 
-		if err := h.redis.SetScore(ctx, userID, score); err != nil {
+		if err := h.redis.SetScore(WithGame(ctx, gameID), userID, score); err != nil {
 			log.Printf("Error setting score in Redis during cache warm: %v", err)
 			errors++
 			continue
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"leader_board/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func init() {
+	tracing.Tracer = noop.NewTracerProvider().Tracer("test")
+}
+
+// fakeStore is an in-memory stand-in for PostgresRepository that counts rank lookups
+type fakeStore struct {
+	mu          sync.Mutex
+	scores      map[string]int
+	rankLookups int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{scores: make(map[string]int)}
+}
+
+func (s *fakeStore) UpdateScore(ctx context.Context, userID string, points int, matchID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scores[userID] += points
+	return s.scores[userID], nil
+}
+
+func (s *fakeStore) GetTopN(ctx context.Context, n int) ([]LeaderboardEntry, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) GetUserRank(ctx context.Context, userID string, neighborCount int) (*LeaderboardEntry, []LeaderboardEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rankLookups++
+	score, ok := s.scores[userID]
+	if !ok {
+		return nil, nil, ErrUserNotFound
+	}
+	return &LeaderboardEntry{UserID: userID, Score: score, Rank: 1}, nil, nil
+}
+
+func (s *fakeStore) GetScoreHistogram(ctx context.Context, bucketSize int) ([]ScoreBucket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.scores) == 0 {
+		return []ScoreBucket{}, nil
+	}
+	lo, hi := int(^uint(0)>>1), 0
+	for _, score := range s.scores {
+		lo, hi = min(lo, score), max(hi, score)
+	}
+	buckets, err := newHistogram(lo, hi, bucketSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, score := range s.scores {
+		buckets[bucketIndex(buckets, score, bucketSize)].Count++
+	}
+	return buckets, nil
+}
+
+func (s *fakeStore) lookups() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rankLookups
+}
+
+// missCache behaves like a cold Redis: every rank lookup misses
+type missCache struct{}
+
+func (missCache) GetTopN(ctx context.Context, n int) ([]LeaderboardEntry, error) {
+	return nil, nil
+}
+
+func (missCache) GetUserRank(ctx context.Context, userID string, neighborCount int) (*LeaderboardEntry, []LeaderboardEntry, error) {
+	return nil, nil, ErrUserNotFound
+}
+
+func (missCache) SetScore(ctx context.Context, userID string, score int) error {
+	return nil
+}
+
+func (missCache) GetScoreHistogram(ctx context.Context, bucketSize int) ([]ScoreBucket, error) {
+	return []ScoreBucket{}, nil
+}
+
+func newTestHybrid(store *fakeStore, ttl time.Duration) *HybridRepository {
+	return &HybridRepository{
+		redis:    missCache{},
+		postgres: store,
+		negative: newNegativeCache(ttl),
+	}
+}
+
+func TestHybridGetUserRank_NegativeCacheHitsPostgresOnce(t *testing.T) {
+	store := newFakeStore()
+	h := newTestHybrid(store, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, _, err := h.GetUserRank(ctx, "ghost", 4)
+		if !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("lookup %d: expected ErrUserNotFound, got %v", i, err)
+		}
+	}
+
+	if got := store.lookups(); got != 1 {
+		t.Fatalf("expected 1 postgres lookup, got %d", got)
+	}
+}
+
+func TestHybridGetUserRank_NegativeEntryExpires(t *testing.T) {
+	store := newFakeStore()
+	h := newTestHybrid(store, time.Second)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.negative.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	h.GetUserRank(ctx, "ghost", 4)
+	h.GetUserRank(ctx, "ghost", 4)
+	if got := store.lookups(); got != 1 {
+		t.Fatalf("expected 1 postgres lookup within TTL, got %d", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	h.GetUserRank(ctx, "ghost", 4)
+	if got := store.lookups(); got != 2 {
+		t.Fatalf("expected expired entry to fall through to postgres, got %d lookups", got)
+	}
+}
+
+func TestHybridUpdateScore_ClearsNegativeEntry(t *testing.T) {
+	store := newFakeStore()
+	h := newTestHybrid(store, time.Minute)
+	ctx := context.Background()
+
+	if _, _, err := h.GetUserRank(ctx, "newbie", 4); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+	if !h.negative.contains(negativeKey(ctx, "newbie")) {
+		t.Fatal("expected unknown user to be negatively cached")
+	}
+
+	if _, err := h.UpdateScore(ctx, "newbie", 3, "match-1"); err != nil {
+		t.Fatalf("UpdateScore failed: %v", err)
+	}
+	if h.negative.contains(negativeKey(ctx, "newbie")) {
+		t.Fatal("expected UpdateScore to clear the negative entry")
+	}
+
+	entry, _, err := h.GetUserRank(ctx, "newbie", 4)
+	if err != nil {
+		t.Fatalf("expected user to be found after scoring, got %v", err)
+	}
+	if entry.Score != 3 {
+		t.Fatalf("expected score 3, got %d", entry.Score)
+	}
+	if got := store.lookups(); got != 2 {
+		t.Fatalf("expected 2 postgres lookups, got %d", got)
+	}
+}
+
+func TestHybridGetUserRank_NegativeCacheDisabled(t *testing.T) {
+	store := newFakeStore()
+	h := newTestHybrid(store, time.Minute)
+	h.SetNegativeCacheTTL(0)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		h.GetUserRank(ctx, "ghost", 4)
+	}
+	if got := store.lookups(); got != 3 {
+		t.Fatalf("expected every lookup to reach postgres when disabled, got %d", got)
+	}
+}
+
+func TestHybridNegativeCache_ScopedPerGame(t *testing.T) {
+	store := newFakeStore()
+	h := newTestHybrid(store, time.Minute)
+	chess := WithGame(context.Background(), "chess")
+	poker := WithGame(context.Background(), "poker")
+
+	h.GetUserRank(chess, "ghost", 4)
+	if _, err := h.UpdateScore(poker, "ghost", 1, "m-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Scoring in poker must not clear chess's negative entry
+	if !h.negative.contains(negativeKey(chess, "ghost")) {
+		t.Fatal("expected chess negative entry to survive a poker score update")
+	}
+}
+
+func TestHybridGetScoreHistogram_FallsBackToPostgres(t *testing.T) {
+	store := newFakeStore()
+	h := newTestHybrid(store, time.Minute)
+	for user, score := range map[string]int{"a": 3, "b": 7, "c": 12, "d": 35} {
+		store.UpdateScore(context.Background(), user, score, "m")
+	}
+
+	buckets, err := h.GetScoreHistogram(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ScoreBucket{{0, 9, 2}, {10, 19, 1}, {20, 29, 0}, {30, 39, 1}}
+	if len(buckets) != len(want) {
+		t.Fatalf("expected %d buckets, got %v", len(want), buckets)
+	}
+	for i := range want {
+		if buckets[i] != want[i] {
+			t.Errorf("bucket %d: expected %+v, got %+v", i, want[i], buckets[i])
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package repository
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrUserNotFound is returned when a user has no entry in the current month's leaderboard
+var ErrUserNotFound = errors.New("user not found in leaderboard")
 
 // Repository defines the interface for leaderboard operations
 // This allows switching between PostgreSQL-only and Redis+PostgreSQL implementations
@@ -14,4 +20,7 @@ type Repository interface {
 
 	// GetUserRank retrieves a specific user's rank and nearby players
 	GetUserRank(ctx context.Context, userID string, neighborCount int) (*LeaderboardEntry, []LeaderboardEntry, error)
+
+	// GetScoreHistogram counts the current month's players per score bucket of bucketSize
+	GetScoreHistogram(ctx context.Context, bucketSize int) ([]ScoreBucket, error)
 }
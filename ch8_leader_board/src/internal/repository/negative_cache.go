@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// negativeCacheMaxEntries bounds memory use when many distinct unknown IDs are queried
+const negativeCacheMaxEntries = 100000
+
+// negativeCache remembers user IDs confirmed absent from the leaderboard for a short TTL,
+// so repeated lookups for unknown users fail fast instead of hitting PostgreSQL every time
+type negativeCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]time.Time // userID -> expiry
+}
+
+func newNegativeCache(ttl time.Duration) *negativeCache {
+	return &negativeCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]time.Time),
+	}
+}
+
+// setTTL changes the TTL; zero or negative disables the cache and drops all entries
+func (c *negativeCache) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+	if ttl <= 0 {
+		c.entries = make(map[string]time.Time)
+	}
+}
+
+// contains reports whether userID is a live negative entry
+func (c *negativeCache) contains(userID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiry, ok := c.entries[userID]
+	if !ok {
+		return false
+	}
+	if !c.now().Before(expiry) {
+		delete(c.entries, userID)
+		return false
+	}
+	return true
+}
+
+// add records userID as absent until the TTL elapses
+func (c *negativeCache) add(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return
+	}
+	now := c.now()
+	if len(c.entries) >= negativeCacheMaxEntries {
+		for id, expiry := range c.entries {
+			if !now.Before(expiry) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= negativeCacheMaxEntries {
+			c.entries = make(map[string]time.Time)
+		}
+	}
+	c.entries[userID] = now.Add(c.ttl)
+}
+
+// remove drops the negative entry for userID, e.g. after the user scores
+func (c *negativeCache) remove(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+// negativeKey scopes a negative entry to the game in ctx
+func negativeKey(ctx context.Context, userID string) string {
+	return GameFromContext(ctx) + ":" + userID
+}
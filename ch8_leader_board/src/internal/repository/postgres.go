@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"leader_board/internal/tracing"
 	"time"
 
@@ -48,6 +47,7 @@ func (r *PostgresRepository) UpdateScore(ctx context.Context, userID string, poi
 	))
 
 	currentMonth := time.Now().Format("2006-01")
+	gameID := GameFromContext(ctx)
 
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -91,7 +91,7 @@ func (r *PostgresRepository) UpdateScore(ctx context.Context, userID string, poi
 		),
 	)
 	var exists bool
-	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM score_history WHERE match_id = $1)`, matchID).Scan(&exists)
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM score_history WHERE game_id = $1 AND match_id = $2)`, gameID, matchID).Scan(&exists)
 	if err != nil {
 		checkSpan.RecordError(err)
 		checkSpan.SetStatus(codes.Error, err.Error())
@@ -113,8 +113,8 @@ func (r *PostgresRepository) UpdateScore(ctx context.Context, userID string, poi
 		err = tx.QueryRowContext(ctx, `
 			SELECT COALESCE(score, 0)
 			FROM monthly_leaderboard
-			WHERE user_id = $1 AND month = $2
-		`, userID, currentMonth).Scan(&currentScore)
+			WHERE user_id = $1 AND month = $2 AND game_id = $3
+		`, userID, currentMonth, gameID).Scan(&currentScore)
 		if err != nil && err != sql.ErrNoRows {
 			span.RecordError(err)
 			return 0, err
@@ -133,9 +133,9 @@ func (r *PostgresRepository) UpdateScore(ctx context.Context, userID string, poi
 		),
 	)
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO score_history (user_id, match_id, points)
-		VALUES ($1, $2, $3)
-	`, userID, matchID, points)
+		INSERT INTO score_history (user_id, match_id, points, game_id)
+		VALUES ($1, $2, $3, $4)
+	`, userID, matchID, points, gameID)
 	if err != nil {
 		historySpan.RecordError(err)
 		historySpan.SetStatus(codes.Error, err.Error())
@@ -157,14 +157,14 @@ func (r *PostgresRepository) UpdateScore(ctx context.Context, userID string, poi
 	)
 	var newScore int
 	err = tx.QueryRowContext(ctx, `
-		INSERT INTO monthly_leaderboard (user_id, score, month)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, month)
+		INSERT INTO monthly_leaderboard (user_id, score, month, game_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (game_id, user_id, month)
 		DO UPDATE SET
 			score = monthly_leaderboard.score + $2,
 			updated_at = CURRENT_TIMESTAMP
 		RETURNING score
-	`, userID, points, currentMonth).Scan(&newScore)
+	`, userID, points, currentMonth, gameID).Scan(&newScore)
 	if err != nil {
 		updateSpan.RecordError(err)
 		updateSpan.SetStatus(codes.Error, err.Error())
@@ -210,10 +210,10 @@ func (r *PostgresRepository) GetTopN(ctx context.Context, n int) ([]LeaderboardE
 			score,
 			RANK() OVER (ORDER BY score DESC) as rank
 		FROM monthly_leaderboard
-		WHERE month = $1
+		WHERE month = $1 AND game_id = $3
 		ORDER BY score DESC
 		LIMIT $2
-	`, currentMonth, n)
+	`, currentMonth, n, GameFromContext(ctx))
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -255,6 +255,7 @@ func (r *PostgresRepository) GetUserRank(ctx context.Context, userID string, nei
 	))
 
 	currentMonth := time.Now().Format("2006-01")
+	gameID := GameFromContext(ctx)
 
 	// This is extremely slow query - requires counting all rows with score >= user's score
 	_, rankSpan := tracing.Tracer.Start(ctx, "postgres.SelectUserRank",
@@ -272,16 +273,16 @@ func (r *PostgresRepository) GetUserRank(ctx context.Context, userID string, nei
 			lb1.user_id,
 			lb1.score,
 			(SELECT COUNT(*) FROM monthly_leaderboard lb2
-			 WHERE lb2.month = $2 AND lb2.score >= lb1.score) AS rank
+			 WHERE lb2.month = $2 AND lb2.game_id = $3 AND lb2.score >= lb1.score) AS rank
 		FROM monthly_leaderboard lb1
-		WHERE lb1.user_id = $1 AND lb1.month = $2
-	`, userID, currentMonth).Scan(&userEntry.UserID, &userEntry.Score, &userEntry.Rank)
+		WHERE lb1.user_id = $1 AND lb1.month = $2 AND lb1.game_id = $3
+	`, userID, currentMonth, gameID).Scan(&userEntry.UserID, &userEntry.Score, &userEntry.Rank)
 
 	if err == sql.ErrNoRows {
 		rankSpan.SetStatus(codes.Error, "user not found")
 		rankSpan.End()
 		span.SetStatus(codes.Error, "user not found in leaderboard")
-		return nil, nil, fmt.Errorf("user not found in leaderboard")
+		return nil, nil, ErrUserNotFound
 	}
 	if err != nil {
 		rankSpan.RecordError(err)
@@ -329,13 +330,13 @@ func (r *PostgresRepository) GetUserRank(ctx context.Context, userID string, nei
 					score,
 					RANK() OVER (ORDER BY score DESC) as rank
 				FROM monthly_leaderboard
-				WHERE month = $1
+				WHERE month = $1 AND game_id = $4
 			)
 			SELECT user_id, score, rank
 			FROM ranked
 			WHERE rank BETWEEN $2 AND $3
 			ORDER BY rank
-		`, currentMonth, startRank, endRank)
+		`, currentMonth, startRank, endRank, gameID)
 		if err != nil {
 			neighborSpan.RecordError(err)
 			neighborSpan.SetStatus(codes.Error, err.Error())
@@ -367,3 +368,78 @@ func (r *PostgresRepository) GetUserRank(ctx context.Context, userID string, nei
 	span.SetStatus(codes.Ok, "")
 	return &userEntry, neighbors, nil
 }
+
+// GetScoreHistogram counts the current month's players per score bucket.
+// Empty buckets between the lowest and highest score are included.
+func (r *PostgresRepository) GetScoreHistogram(ctx context.Context, bucketSize int) ([]ScoreBucket, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "postgres.GetScoreHistogram",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", "SELECT"),
+			attribute.String("db.table", "monthly_leaderboard"),
+			attribute.Int("bucket_size", bucketSize),
+		),
+	)
+	defer span.End()
+
+	currentMonth := time.Now().Format("2006-01")
+	gameID := GameFromContext(ctx)
+
+	// Check the score range first so an oversized histogram is rejected
+	// before grouping the whole table
+	var minScore, maxScore sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MIN(score), MAX(score)
+		FROM monthly_leaderboard
+		WHERE month = $1 AND game_id = $2
+	`, currentMonth, gameID).Scan(&minScore, &maxScore)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if !minScore.Valid {
+		span.SetStatus(codes.Ok, "")
+		return []ScoreBucket{}, nil
+	}
+
+	buckets, err := newHistogram(int(minScore.Int64), int(maxScore.Int64), bucketSize)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT FLOOR(score::numeric / $3)::bigint AS bucket, COUNT(*)
+		FROM monthly_leaderboard
+		WHERE month = $1 AND game_id = $2
+		GROUP BY bucket
+	`, currentMonth, gameID, bucketSize)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	first := floorDiv(buckets[0].Min, bucketSize)
+	for rows.Next() {
+		var bucket int
+		var count int64
+		if err := rows.Scan(&bucket, &count); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		// Scores may have moved since the range query; drop what no longer fits
+		if i := bucket - first; i >= 0 && i < len(buckets) {
+			buckets[i].Count = count
+		}
+	}
+
+	span.SetAttributes(attribute.Int("bucket_count", len(buckets)))
+	span.SetStatus(codes.Ok, "")
+	return buckets, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"leader_board/internal/tracing"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -20,9 +21,14 @@ func NewRedisRepository(client *redis.Client) *RedisRepository {
 	return &RedisRepository{client: client}
 }
 
-// leaderboardKey returns the Redis key for the current month's leaderboard
-func (r *RedisRepository) leaderboardKey() string {
-	return fmt.Sprintf("leaderboard_%s", time.Now().Format("2006_01"))
+// leaderboardKey returns the Redis key for the current month's leaderboard of the
+// game in ctx: <game>_leaderboard_<month>, or leaderboard_<month> for the default game
+func (r *RedisRepository) leaderboardKey(ctx context.Context) string {
+	key := fmt.Sprintf("leaderboard_%s", time.Now().Format("2006_01"))
+	if game := GameFromContext(ctx); game != DefaultGameID {
+		key = game + "_" + key
+	}
+	return key
 }
 
 // UpdateScore increments user's score using ZINCRBY
@@ -43,7 +49,7 @@ func (r *RedisRepository) UpdateScore(ctx context.Context, userID string, points
 		attribute.Int("points", points),
 	))
 
-	key := r.leaderboardKey()
+	key := r.leaderboardKey(ctx)
 
 	// ZINCRBY leaderboard_2024_01 1 "user123"
 	newScore, err := r.client.ZIncrBy(ctx, key, float64(points), userID).Result()
@@ -71,7 +77,7 @@ func (r *RedisRepository) GetTopN(ctx context.Context, n int) ([]LeaderboardEntr
 	)
 	defer span.End()
 
-	key := r.leaderboardKey()
+	key := r.leaderboardKey(ctx)
 
 	// ZREVRANGE leaderboard_2024_01 0 9 WITHSCORES
 	results, err := r.client.ZRevRangeWithScores(ctx, key, 0, int64(n-1)).Result()
@@ -129,7 +135,7 @@ func (r *RedisRepository) GetUserRank(ctx context.Context, userID string, neighb
 		attribute.Int("neighbor_count", neighborCount),
 	))
 
-	key := r.leaderboardKey()
+	key := r.leaderboardKey(ctx)
 
 	// Get user's rank: ZREVRANK leaderboard_2024_01 "user123"
 	_, rankSpan := tracing.Tracer.Start(ctx, "redis.ZREVRANK",
@@ -150,7 +156,7 @@ func (r *RedisRepository) GetUserRank(ctx context.Context, userID string, neighb
 		rankSpan.End()
 		span.SetAttributes(attribute.Bool("cache.hit", false))
 		span.SetStatus(codes.Error, "user not found in leaderboard")
-		return nil, nil, fmt.Errorf("user not found in leaderboard")
+		return nil, nil, ErrUserNotFound
 	}
 	if err != nil {
 		rankSpan.RecordError(err)
@@ -275,7 +281,7 @@ func (r *RedisRepository) Exists(ctx context.Context, userID string) (bool, erro
 		attribute.String("user_id", userID),
 	))
 
-	key := r.leaderboardKey()
+	key := r.leaderboardKey(ctx)
 	_, err := r.client.ZScore(ctx, key, userID).Result()
 	if err == redis.Nil {
 		span.SetAttributes(attribute.Bool("exists", false))
@@ -309,7 +315,7 @@ func (r *RedisRepository) SetScore(ctx context.Context, userID string, score int
 		attribute.Int("score", score),
 	))
 
-	key := r.leaderboardKey()
+	key := r.leaderboardKey(ctx)
 	err := r.client.ZAdd(ctx, key, redis.Z{
 		Score:  float64(score),
 		Member: userID,
@@ -336,7 +342,7 @@ func (r *RedisRepository) GetLeaderboardSize(ctx context.Context) (int64, error)
 	)
 	defer span.End()
 
-	key := r.leaderboardKey()
+	key := r.leaderboardKey(ctx)
 	size, err := r.client.ZCard(ctx, key).Result()
 	if err != nil {
 		span.RecordError(err)
@@ -348,3 +354,70 @@ func (r *RedisRepository) GetLeaderboardSize(ctx context.Context) (int64, error)
 	span.SetStatus(codes.Ok, "")
 	return size, nil
 }
+
+// GetScoreHistogram counts players per score bucket with one ZCOUNT per bucket
+// Time complexity: O(B log N) for B buckets, pipelined into a single round trip
+func (r *RedisRepository) GetScoreHistogram(ctx context.Context, bucketSize int) ([]ScoreBucket, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "redis.GetScoreHistogram",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", "ZCOUNT"),
+			attribute.Int("bucket_size", bucketSize),
+		),
+	)
+	defer span.End()
+
+	key := r.leaderboardKey(ctx)
+
+	// The lowest and highest scores bound the buckets to count
+	lowest, err := r.client.ZRangeWithScores(ctx, key, 0, 0).Result()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get lowest score from redis: %w", err)
+	}
+	if len(lowest) == 0 {
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+		span.SetStatus(codes.Ok, "")
+		return []ScoreBucket{}, nil
+	}
+	highest, err := r.client.ZRevRangeWithScores(ctx, key, 0, 0).Result()
+	if err != nil || len(highest) == 0 {
+		if err == nil {
+			err = fmt.Errorf("leaderboard emptied during histogram")
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to get highest score from redis: %w", err)
+	}
+
+	buckets, err := newHistogram(int(lowest[0].Score), int(highest[0].Score), bucketSize)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	// ZCOUNT leaderboard_2024_01 0 9, ZCOUNT leaderboard_2024_01 10 19, ...
+	pipe := r.client.Pipeline()
+	counts := make([]*redis.IntCmd, len(buckets))
+	for i, b := range buckets {
+		counts[i] = pipe.ZCount(ctx, key, strconv.Itoa(b.Min), strconv.Itoa(b.Max))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to count scores in redis: %w", err)
+	}
+	for i, cmd := range counts {
+		buckets[i].Count = cmd.Val()
+	}
+
+	span.SetAttributes(
+		attribute.Bool("cache.hit", true),
+		attribute.Int("bucket_count", len(buckets)),
+	)
+	span.SetStatus(codes.Ok, "")
+	return buckets, nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisRepository(t *testing.T) (*RedisRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisRepository(client), mr
+}
+
+func TestRedisLeaderboardKey_GameNamespace(t *testing.T) {
+	r, _ := newTestRedisRepository(t)
+	month := time.Now().Format("2006_01")
+
+	if got := r.leaderboardKey(context.Background()); got != "leaderboard_"+month {
+		t.Fatalf("default game should keep the legacy key, got %q", got)
+	}
+	if got := r.leaderboardKey(WithGame(context.Background(), "chess")); got != "chess_leaderboard_"+month {
+		t.Fatalf("expected game-prefixed key, got %q", got)
+	}
+}
+
+func TestRedisRepository_GamesAreIsolated(t *testing.T) {
+	r, mr := newTestRedisRepository(t)
+	chess := WithGame(context.Background(), "chess")
+	poker := WithGame(context.Background(), "poker")
+
+	for i, score := range []int{30, 20, 10} {
+		if _, err := r.UpdateScore(chess, fmt.Sprintf("chess_player_%d", i), score); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.UpdateScore(poker, "poker_player", 5); err != nil {
+		t.Fatal(err)
+	}
+	// The same user in both games keeps independent scores
+	if _, err := r.UpdateScore(chess, "shared", 15); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.UpdateScore(poker, "shared", 50); err != nil {
+		t.Fatal(err)
+	}
+
+	chessTop, err := r.GetTopN(chess, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chessTop) != 4 {
+		t.Fatalf("expected 4 chess entries, got %d: %v", len(chessTop), chessTop)
+	}
+	for _, e := range chessTop {
+		if e.UserID == "poker_player" {
+			t.Fatal("poker player leaked into chess leaderboard")
+		}
+	}
+
+	pokerTop, err := r.GetTopN(poker, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pokerTop) != 2 || pokerTop[0].UserID != "shared" || pokerTop[0].Score != 50 {
+		t.Fatalf("unexpected poker leaderboard: %v", pokerTop)
+	}
+
+	entry, _, err := r.GetUserRank(chess, "shared", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Score != 15 || entry.Rank != 3 {
+		t.Fatalf("expected chess score 15 at rank 3, got %+v", entry)
+	}
+
+	entry, _, err = r.GetUserRank(poker, "shared", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Score != 50 || entry.Rank != 1 {
+		t.Fatalf("expected poker score 50 at rank 1, got %+v", entry)
+	}
+
+	if _, _, err := r.GetUserRank(poker, "chess_player_0", 1); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected chess player to be unknown in poker, got %v", err)
+	}
+
+	// Nothing was written to the default game's key
+	for _, key := range mr.Keys() {
+		if strings.HasPrefix(key, "leaderboard_") {
+			t.Fatalf("unexpected write to default key %q", key)
+		}
+	}
+}
+
+func TestValidateGameID(t *testing.T) {
+	for _, id := range []string{"chess", "game-1", "TEAM_A"} {
+		if err := ValidateGameID(id); err != nil {
+			t.Errorf("expected %q to be valid: %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "a b", "x:y", strings.Repeat("a", 51)} {
+		if err := ValidateGameID(id); err == nil {
+			t.Errorf("expected %q to be rejected", id)
+		}
+	}
+}
+
+func TestRedisGetScoreHistogram(t *testing.T) {
+	r, _ := newTestRedisRepository(t)
+	ctx := context.Background()
+
+	// 0-9: 3 players, 10-19: 2, 20-29: none, 30-39: 1
+	for i, score := range []int{1, 5, 9, 10, 19, 30} {
+		if err := r.SetScore(ctx, fmt.Sprintf("player_%d", i), score); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buckets, err := r.GetScoreHistogram(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ScoreBucket{{0, 9, 3}, {10, 19, 2}, {20, 29, 0}, {30, 39, 1}}
+	if len(buckets) != len(want) {
+		t.Fatalf("expected %d buckets, got %v", len(want), buckets)
+	}
+	for i := range want {
+		if buckets[i] != want[i] {
+			t.Errorf("bucket %d: expected %+v, got %+v", i, want[i], buckets[i])
+		}
+	}
+
+	// Buckets start at the lowest score's bucket, not at zero
+	buckets, err = r.GetScoreHistogram(ctx, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buckets[0].Min != 0 || buckets[len(buckets)-1].Max != 31 || len(buckets) != 8 {
+		t.Fatalf("unexpected bucket layout: %v", buckets)
+	}
+}
+
+func TestRedisGetScoreHistogram_BoundsBuckets(t *testing.T) {
+	r, _ := newTestRedisRepository(t)
+	ctx := context.Background()
+
+	if buckets, err := r.GetScoreHistogram(ctx, 10); err != nil || len(buckets) != 0 {
+		t.Fatalf("expected empty histogram for empty leaderboard, got %v, %v", buckets, err)
+	}
+
+	r.SetScore(ctx, "low", 0)
+	r.SetScore(ctx, "high", MaxHistogramBuckets*10)
+
+	if _, err := r.GetScoreHistogram(ctx, 1); !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("expected ErrTooManyBuckets, got %v", err)
+	}
+	if _, err := r.GetScoreHistogram(ctx, 0); err == nil {
+		t.Fatal("expected a zero bucket size to be rejected")
+	}
+	if _, err := r.GetScoreHistogram(ctx, 100); err != nil {
+		t.Fatalf("expected %d buckets to fit, got %v", MaxHistogramBuckets/10+1, err)
+	}
+}
@@ -33,8 +33,13 @@ func NewValkeyRepository(rdb *redis.Client, db *sql.DB) *ValkeyRepository {
 }
 
 // getLeaderboardKey returns the Redis key for the current month's leaderboard
-func (r *ValkeyRepository) getLeaderboardKey() string {
-	return fmt.Sprintf("leaderboard_%s", time.Now().Format("2006_01"))
+// of the game in ctx, named like RedisRepository's keys
+func (r *ValkeyRepository) getLeaderboardKey(ctx context.Context) string {
+	key := fmt.Sprintf("leaderboard_%s", time.Now().Format("2006_01"))
+	if game := GameFromContext(ctx); game != DefaultGameID {
+		key = game + "_" + key
+	}
+	return key
 }
 
 // UpdateScore updates a user's score using ZINCRBY - O(log n)
@@ -45,6 +50,7 @@ func (r *ValkeyRepository) UpdateScore(userID string, points int, matchID string
 // UpdateScoreWithContext updates score with context for tracing
 func (r *ValkeyRepository) UpdateScoreWithContext(ctx context.Context, userID string, points int, matchID string) (int, error) {
 	currentMonth := time.Now().Format("2006-01")
+	gameID := GameFromContext(ctx)
 
 	// Start PostgreSQL transaction span
 	ctx, txSpan := pgTracer.Start(ctx, "postgres.transaction",
@@ -90,7 +96,7 @@ func (r *ValkeyRepository) UpdateScoreWithContext(ctx context.Context, userID st
 			attribute.String("db.sql.table", "score_history"),
 		))
 	var exists bool
-	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM score_history WHERE match_id = $1)`, matchID).Scan(&exists)
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM score_history WHERE game_id = $1 AND match_id = $2)`, gameID, matchID).Scan(&exists)
 	if err != nil {
 		checkSpan.RecordError(err)
 		checkSpan.End()
@@ -107,7 +113,7 @@ func (r *ValkeyRepository) UpdateScoreWithContext(ctx context.Context, userID st
 				attribute.String("db.system", "redis"),
 				attribute.String("db.operation", "ZSCORE"),
 			))
-		score, err := r.rdb.ZScore(ctx, r.getLeaderboardKey(), userID).Result()
+		score, err := r.rdb.ZScore(ctx, r.getLeaderboardKey(ctx), userID).Result()
 		if err == redis.Nil {
 			redisSpan.End()
 			return 0, nil
@@ -130,9 +136,9 @@ func (r *ValkeyRepository) UpdateScoreWithContext(ctx context.Context, userID st
 			attribute.String("db.sql.table", "score_history"),
 		))
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO score_history (user_id, match_id, points)
-		VALUES ($1, $2, $3)
-	`, userID, matchID, points)
+		INSERT INTO score_history (game_id, user_id, match_id, points)
+		VALUES ($1, $2, $3, $4)
+	`, gameID, userID, matchID, points)
 	if err != nil {
 		historySpan.RecordError(err)
 		historySpan.End()
@@ -148,13 +154,13 @@ func (r *ValkeyRepository) UpdateScoreWithContext(ctx context.Context, userID st
 			attribute.String("db.sql.table", "monthly_leaderboard"),
 		))
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO monthly_leaderboard (user_id, score, month)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, month)
+		INSERT INTO monthly_leaderboard (game_id, user_id, score, month)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (game_id, user_id, month)
 		DO UPDATE SET
-			score = monthly_leaderboard.score + $2,
+			score = monthly_leaderboard.score + $3,
 			updated_at = CURRENT_TIMESTAMP
-	`, userID, points, currentMonth)
+	`, gameID, userID, points, currentMonth)
 	if err != nil {
 		updateSpan.RecordError(err)
 		updateSpan.End()
@@ -168,10 +174,10 @@ func (r *ValkeyRepository) UpdateScoreWithContext(ctx context.Context, userID st
 		trace.WithAttributes(
 			attribute.String("db.system", "redis"),
 			attribute.String("db.operation", "ZINCRBY"),
-			attribute.String("key", r.getLeaderboardKey()),
+			attribute.String("key", r.getLeaderboardKey(ctx)),
 			attribute.Int("increment", points),
 		))
-	newScore, err := r.rdb.ZIncrBy(ctx, r.getLeaderboardKey(), float64(points), userID).Result()
+	newScore, err := r.rdb.ZIncrBy(ctx, r.getLeaderboardKey(ctx), float64(points), userID).Result()
 	if err != nil {
 		redisSpan.RecordError(err)
 		redisSpan.End()
@@ -202,13 +208,13 @@ func (r *ValkeyRepository) GetTopNWithContext(ctx context.Context, n int) ([]Lea
 		trace.WithAttributes(
 			attribute.String("db.system", "redis"),
 			attribute.String("db.operation", "ZREVRANGE"),
-			attribute.String("key", r.getLeaderboardKey()),
+			attribute.String("key", r.getLeaderboardKey(ctx)),
 			attribute.Int("limit", n),
 		))
 	defer span.End()
 
 	// ZREVRANGE with WITHSCORES returns members sorted by score descending
-	results, err := r.rdb.ZRevRangeWithScores(ctx, r.getLeaderboardKey(), 0, int64(n-1)).Result()
+	results, err := r.rdb.ZRevRangeWithScores(ctx, r.getLeaderboardKey(ctx), 0, int64(n-1)).Result()
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -240,7 +246,7 @@ func (r *ValkeyRepository) GetUserRank(userID string, neighborCount int) (*Leade
 
 // GetUserRankWithContext retrieves user rank with context for tracing
 func (r *ValkeyRepository) GetUserRankWithContext(ctx context.Context, userID string, neighborCount int) (*LeaderboardEntry, []LeaderboardEntry, error) {
-	key := r.getLeaderboardKey()
+	key := r.getLeaderboardKey(ctx)
 
 	ctx, span := redisTracer.Start(ctx, "valkey.get_user_rank",
 		trace.WithSpanKind(trace.SpanKindClient),
@@ -265,7 +271,7 @@ func (r *ValkeyRepository) GetUserRankWithContext(ctx context.Context, userID st
 		))
 		rankSpan.End()
 		span.SetAttributes(attribute.Bool("cache.hit", false))
-		return nil, nil, fmt.Errorf("user not found in leaderboard")
+		return nil, nil, ErrUserNotFound
 	}
 	if err != nil {
 		rankSpan.RecordError(err)
@@ -353,10 +359,11 @@ func (r *ValkeyRepository) GetUserRankWithContext(ctx context.Context, userID st
 	return userEntry, neighbors, nil
 }
 
-// SyncFromPostgres rebuilds the Redis leaderboard from PostgreSQL data
+// SyncFromPostgres rebuilds the Redis leaderboard of the game in ctx from
+// PostgreSQL data
 func (r *ValkeyRepository) SyncFromPostgres(ctx context.Context) error {
 	currentMonth := time.Now().Format("2006-01")
-	key := r.getLeaderboardKey()
+	key := r.getLeaderboardKey(ctx)
 
 	// Clear existing data
 	if err := r.rdb.Del(ctx, key).Err(); err != nil {
@@ -367,8 +374,8 @@ func (r *ValkeyRepository) SyncFromPostgres(ctx context.Context) error {
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT user_id, score
 		FROM monthly_leaderboard
-		WHERE month = $1
-	`, currentMonth)
+		WHERE month = $1 AND game_id = $2
+	`, currentMonth, GameFromContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -408,12 +415,12 @@ func (r *ValkeyRepository) SyncFromPostgres(ctx context.Context) error {
 
 // GetLeaderboardSize returns the total number of users in the leaderboard
 func (r *ValkeyRepository) GetLeaderboardSize(ctx context.Context) (int64, error) {
-	return r.rdb.ZCard(ctx, r.getLeaderboardKey()).Result()
+	return r.rdb.ZCard(ctx, r.getLeaderboardKey(ctx)).Result()
 }
 
 // GetScoreRange returns users within a specific score range
 func (r *ValkeyRepository) GetScoreRange(ctx context.Context, minScore, maxScore int, offset, count int64) ([]LeaderboardEntry, error) {
-	results, err := r.rdb.ZRevRangeByScoreWithScores(ctx, r.getLeaderboardKey(), &redis.ZRangeBy{
+	results, err := r.rdb.ZRevRangeByScoreWithScores(ctx, r.getLeaderboardKey(ctx), &redis.ZRangeBy{
 		Min:    strconv.Itoa(minScore),
 		Max:    strconv.Itoa(maxScore),
 		Offset: offset,